@@ -0,0 +1,98 @@
+package access
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 凭证类型
+const (
+	CredentialCard   = "card"    // 门禁卡，凭证号为卡号
+	CredentialFace   = "face"    // 人脸，凭证号为人脸平台返回的人脸ID
+	CredentialAppKey = "app_key" // 手机开门密钥
+)
+
+// 下发操作
+const (
+	ActionGrant   = "grant"   // 授权：发放新凭证或恢复已暂停的凭证
+	ActionSuspend = "suspend" // 暂停：挂失等场景，凭证保留可恢复
+	ActionRevoke  = "revoke"  // 注销：凭证永久失效
+)
+
+// Credential 表示下发给门禁控制器的凭证信息
+type Credential struct {
+	ID          int
+	ResidentID  int
+	BlockNumber string // 住户所在楼栋，控制器据此开通对应楼栋的门
+	Type        string
+	Value       string // 卡号、人脸ID或开门密钥
+	HolderName  string
+	ValidUntil  *time.Time // 为空表示长期有效
+}
+
+// Change 表示一次凭证变更
+type Change struct {
+	Action     string
+	Credential Credential
+}
+
+// Controller 表示一类门禁控制器的适配器
+type Controller interface {
+	// Name 返回控制器名称
+	Name() string
+	// Apply 将凭证变更下发到控制器，失败时返回错误
+	Apply(change Change) error
+}
+
+// Registry 保存已注册的门禁控制器
+type Registry struct {
+	mu          sync.RWMutex
+	controllers map[string]Controller
+}
+
+// NewRegistry 创建新的控制器注册表
+func NewRegistry(controllers ...Controller) *Registry {
+	r := &Registry{controllers: make(map[string]Controller)}
+	for _, ctl := range controllers {
+		r.Register(ctl)
+	}
+	return r
+}
+
+// Register 注册控制器，同名控制器会被替换
+func (r *Registry) Register(ctl Controller) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.controllers[ctl.Name()] = ctl
+}
+
+// Get 获取指定名称的控制器
+func (r *Registry) Get(name string) (Controller, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ctl, ok := r.controllers[name]
+	return ctl, ok
+}
+
+// Names 返回已注册的控制器名称，按名称排序
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.controllers))
+	for name := range r.controllers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply 将凭证变更下发到指定控制器
+func (r *Registry) Apply(controller string, change Change) error {
+	ctl, ok := r.Get(controller)
+	if !ok {
+		return fmt.Errorf("未注册的门禁控制器: %s", controller)
+	}
+	return ctl.Apply(change)
+}
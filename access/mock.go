@@ -0,0 +1,82 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// MockController 模拟门禁控制器，在内存中维护有效凭证并记录收到的变更，
+// 用于尚未对接门禁厂商时的联调和测试
+type MockController struct {
+	name string
+
+	mu      sync.Mutex
+	active  map[string]Credential
+	changes []Change
+	failErr error
+}
+
+// NewMockController 创建新的MockController
+func NewMockController(name string) *MockController {
+	return &MockController{
+		name:   name,
+		active: make(map[string]Credential),
+	}
+}
+
+// Name 返回控制器名称
+func (m *MockController) Name() string {
+	return m.name
+}
+
+// Apply 记录凭证变更并更新内存中的有效凭证
+func (m *MockController) Apply(change Change) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.failErr != nil {
+		return m.failErr
+	}
+	cred := change.Credential
+	if cred.Type == "" || cred.Value == "" {
+		return errors.New("凭证类型和凭证号不能为空")
+	}
+
+	key := cred.Type + ":" + cred.Value
+	switch change.Action {
+	case ActionGrant:
+		m.active[key] = cred
+	case ActionSuspend, ActionRevoke:
+		delete(m.active, key)
+	default:
+		return fmt.Errorf("不支持的下发操作: %s", change.Action)
+	}
+	m.changes = append(m.changes, change)
+
+	log.Printf("[%s] %s 住户%d %s凭证 %s", m.name, change.Action, cred.ResidentID, cred.Type, cred.Value)
+	return nil
+}
+
+// SetFailure 设置后续下发返回的错误，传nil恢复正常，用于模拟控制器离线
+func (m *MockController) SetFailure(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failErr = err
+}
+
+// IsActive 判断凭证在控制器上是否有效
+func (m *MockController) IsActive(credentialType, value string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.active[credentialType+":"+value]
+	return ok
+}
+
+// Changes 返回已收到的凭证变更
+func (m *MockController) Changes() []Change {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Change(nil), m.changes...)
+}
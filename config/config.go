@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +14,53 @@ type Config struct {
 	Port      string
 	JWTSecret []byte
 	GinMode   string
+
+	// 催缴任务执行间隔
+	DunningInterval time.Duration
+	// 同一级别催缴提醒的重发间隔
+	DunningRepeatInterval time.Duration
+
+	// 滞纳金日费率和账单到期后的宽限天数
+	LateFeeDailyRate float64
+	LateFeeGraceDays int
+	// 分期到期后的宽限天数
+	PlanGraceDays int
+
+	// 投诉的答复时限，超期未答复自动升级
+	ComplaintResponseTime time.Duration
+
+	// 快递到件后超过该天数仍未取走视为超期
+	ParcelOverdueDays int
+
+	// 门禁凭证同一操作的最大下发次数，超过后不再自动重试
+	AccessSyncMaxAttempts int
+
+	// 租约到期前多少天发送提醒
+	LeaseReminderDays int
+	// 登记租约未指定时的物业费缴纳方：owner或tenant
+	LeaseDefaultFeePayer string
+
+	// 每条通知的最大发送次数
+	NotifyMaxAttempts int
+	// SMTP邮件服务器，未设置SMTPHost时邮件仅写日志
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	// HTTP短信网关，未设置SMSGatewayURL时短信仅写日志
+	SMSGatewayURL   string
+	SMSGatewayToken string
+	SMSSign         string
+
+	// 访客通行码的签名密钥，未设置时使用JWT密钥
+	VisitorPassSecret []byte
+
+	// 上传文件（工单照片等）的保存目录
+	UploadDir string
+
+	// 启动时是否自动执行未执行的数据库迁移，默认关闭，避免升级时对没有迁移记录的已有数据库执行迁移
+	MigrateOnStartup bool
 }
 
 // LoadConfig 加载配置
@@ -33,15 +82,86 @@ func LoadConfig() *Config {
 		jwtSecret = "your_jwt_secret_key" // 默认密钥，生产环境应该更改
 	}
 
+	// 获取访客通行码签名密钥
+	visitorPassSecret := os.Getenv("VISITOR_PASS_SECRET")
+	if visitorPassSecret == "" {
+		visitorPassSecret = jwtSecret
+	}
+
 	// 获取Gin模式
 	ginMode := os.Getenv("GIN_MODE")
 	if ginMode == "" {
 		ginMode = "release"
 	}
 
+	// 获取上传目录
+	uploadDir := os.Getenv("UPLOAD_DIR")
+	if uploadDir == "" {
+		uploadDir = "uploads"
+	}
+
+	// 获取租约默认缴费方
+	leaseFeePayer := os.Getenv("LEASE_DEFAULT_FEE_PAYER")
+	if leaseFeePayer != "tenant" {
+		leaseFeePayer = "owner"
+	}
+
+	// 获取催缴配置，单位为小时
+	dunningInterval := getEnvHours("DUNNING_INTERVAL_HOURS", 24)
+	dunningRepeatInterval := getEnvHours("DUNNING_REPEAT_HOURS", 7*24)
+
 	return &Config{
-		Port:      port,
-		JWTSecret: []byte(jwtSecret),
-		GinMode:   ginMode,
+		Port:                  port,
+		JWTSecret:             []byte(jwtSecret),
+		GinMode:               ginMode,
+		DunningInterval:       dunningInterval,
+		DunningRepeatInterval: dunningRepeatInterval,
+		LateFeeDailyRate:      getEnvFloat("LATE_FEE_DAILY_RATE", 0.0005),
+		LateFeeGraceDays:      getEnvInt("LATE_FEE_GRACE_DAYS", 15),
+		PlanGraceDays:         getEnvInt("PAYMENT_PLAN_GRACE_DAYS", 3),
+		ComplaintResponseTime: getEnvHours("COMPLAINT_RESPONSE_HOURS", 48),
+		ParcelOverdueDays:     getEnvInt("PARCEL_OVERDUE_DAYS", 7),
+		AccessSyncMaxAttempts: getEnvInt("ACCESS_SYNC_MAX_ATTEMPTS", 10),
+		LeaseReminderDays:     getEnvInt("LEASE_REMINDER_DAYS", 30),
+		LeaseDefaultFeePayer:  leaseFeePayer,
+		NotifyMaxAttempts:     getEnvInt("NOTIFY_MAX_ATTEMPTS", 5),
+		SMTPHost:              os.Getenv("SMTP_HOST"),
+		SMTPPort:              getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:          os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:          os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:              os.Getenv("SMTP_FROM"),
+		SMSGatewayURL:         os.Getenv("SMS_GATEWAY_URL"),
+		SMSGatewayToken:       os.Getenv("SMS_GATEWAY_TOKEN"),
+		SMSSign:               os.Getenv("SMS_SIGN"),
+		VisitorPassSecret:     []byte(visitorPassSecret),
+		UploadDir:             uploadDir,
+		MigrateOnStartup:      os.Getenv("MIGRATE_ON_STARTUP") == "true",
+	}
+}
+
+// getEnvHours 读取以小时为单位的环境变量，未设置或无效时返回默认值
+func getEnvHours(key string, defaultHours int) time.Duration {
+	hours, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || hours <= 0 {
+		hours = defaultHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// getEnvInt 读取非负整数环境变量，未设置或无效时返回默认值
+func getEnvInt(key string, defaultValue int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || v < 0 {
+		return defaultValue
+	}
+	return v
+}
+
+// getEnvFloat 读取非负小数环境变量，未设置或无效时返回默认值
+func getEnvFloat(key string, defaultValue float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || v < 0 {
+		return defaultValue
 	}
+	return v
 }
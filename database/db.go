@@ -48,9 +48,6 @@ func InitDB() error {
 		return fmt.Errorf("数据库连接测试失败: %v", err)
 	}
 
-	// 检查数据库表是否存在
-	checkTables()
-
 	log.Println("数据库连接成功")
 	return nil
 }
@@ -71,33 +68,3 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
-
-// checkTables 检查数据库表是否存在
-func checkTables() {
-	// 检查residents表
-	var residentCount int
-	err := DB.QueryRow("SELECT COUNT(*) FROM residents").Scan(&residentCount)
-	if err != nil {
-		log.Printf("检查residents表失败: %v", err)
-	} else {
-		log.Printf("residents表中有 %d 条记录", residentCount)
-	}
-
-	// 检查property_fees表
-	var propertyFeeCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM property_fees").Scan(&propertyFeeCount)
-	if err != nil {
-		log.Printf("检查property_fees表失败: %v", err)
-	} else {
-		log.Printf("property_fees表中有 %d 条记录", propertyFeeCount)
-	}
-
-	// 检查property_fee_monthly_stats表
-	var statsCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM property_fee_monthly_stats").Scan(&statsCount)
-	if err != nil {
-		log.Printf("检查property_fee_monthly_stats表失败: %v", err)
-	} else {
-		log.Printf("property_fee_monthly_stats表中有 %d 条记录", statsCount)
-	}
-}
@@ -0,0 +1,411 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFS 内嵌的迁移文件，命名为<版本>_<名称>.up.sql和<版本>_<名称>.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migrationFilePattern 匹配迁移文件名
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var (
+	// ErrMigrationDirty 上次迁移执行中断，需人工修复后用force标记版本
+	ErrMigrationDirty = errors.New("数据库迁移处于中断状态")
+	// ErrUnversionedSchema 数据库已有表但没有迁移记录
+	ErrUnversionedSchema = errors.New("数据库已有表但没有迁移记录")
+)
+
+// Migration 表示一个版本的数据库迁移
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus 表示一个迁移的执行状态
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// appliedMigration 表示schema_migrations中的一条记录
+type appliedMigration struct {
+	dirty     bool
+	appliedAt time.Time
+}
+
+// LoadMigrations 读取内嵌的迁移文件，按版本升序返回，每个版本必须同时有up和down文件
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("无效的迁移文件名: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+
+		content, err := migrationFS.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("迁移版本%d的名称不一致: %s, %s", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("迁移%04d_%s缺少up或down文件", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// ensureMigrationTable 创建迁移记录表
+func ensureMigrationTable() error {
+	_, err := DB.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		    version INT PRIMARY KEY,
+		    name VARCHAR(100) NOT NULL,
+		    dirty TINYINT NOT NULL DEFAULT 0,
+		    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`)
+	return err
+}
+
+// getAppliedMigrations 获取已执行的迁移记录
+func getAppliedMigrations() (map[int]appliedMigration, error) {
+	rows, err := DB.Query(`SELECT version, dirty, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.dirty, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+
+	return applied, rows.Err()
+}
+
+// loadMigrationState 读取迁移文件和已执行记录，存在中断的迁移时返回ErrMigrationDirty
+func loadMigrationState() ([]Migration, map[int]appliedMigration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ensureMigrationTable(); err != nil {
+		return nil, nil, err
+	}
+	applied, err := getAppliedMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+	for version, a := range applied {
+		if a.dirty {
+			return nil, nil, fmt.Errorf("%w: 版本%d，请人工修复后执行 migrate force <版本>", ErrMigrationDirty, version)
+		}
+	}
+	return migrations, applied, nil
+}
+
+// checkUnversionedSchema 没有迁移记录但数据库中已有表（手工执行过建表脚本）时返回ErrUnversionedSchema，
+// 避免对已有表重复执行ALTER
+func checkUnversionedSchema(applied map[int]appliedMigration) error {
+	if len(applied) > 0 {
+		return nil
+	}
+
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_name = 'residents'`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w，请确认当前结构对应的版本后执行 migrate force <版本>", ErrUnversionedSchema)
+	}
+	return nil
+}
+
+// PendingMigrations 返回尚未执行的迁移
+func PendingMigrations() ([]Migration, error) {
+	migrations, applied, err := loadMigrationState()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// MigrateUp 按版本顺序执行尚未执行的迁移，target为0时执行到最新版本，返回本次执行的迁移
+func MigrateUp(target int) ([]Migration, error) {
+	migrations, applied, err := loadMigrationState()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnversionedSchema(applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if target > 0 && m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		log.Printf("执行数据库迁移 %04d_%s", m.Version, m.Name)
+		if err := runMigration(m, true); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+// MigrateDown 按版本倒序回滚最近执行的steps个迁移，返回本次回滚的迁移
+func MigrateDown(steps int) ([]Migration, error) {
+	migrations, applied, err := loadMigrationState()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		log.Printf("回滚数据库迁移 %04d_%s", m.Version, m.Name)
+		if err := runMigration(m, false); err != nil {
+			return done, err
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+// runMigration 逐条执行迁移的up或down脚本，执行前将版本标记为中断状态，成功后更新记录
+// MySQL的DDL不能回滚，执行失败时版本保持中断状态，需人工修复
+func runMigration(m Migration, up bool) error {
+	script := m.Down
+	if up {
+		script = m.Up
+		if _, err := DB.Exec(`INSERT INTO schema_migrations (version, name, dirty) VALUES (?, ?, 1)`, m.Version, m.Name); err != nil {
+			return err
+		}
+	} else {
+		if _, err := DB.Exec(`UPDATE schema_migrations SET dirty = 1 WHERE version = ?`, m.Version); err != nil {
+			return err
+		}
+	}
+
+	for i, stmt := range splitStatements(script) {
+		if _, err := DB.Exec(stmt); err != nil {
+			return fmt.Errorf("迁移%04d_%s第%d条语句执行失败: %w", m.Version, m.Name, i+1, err)
+		}
+	}
+
+	var err error
+	if up {
+		_, err = DB.Exec(`UPDATE schema_migrations SET dirty = 0, applied_at = CURRENT_TIMESTAMP WHERE version = ?`, m.Version)
+	} else {
+		_, err = DB.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+	}
+	return err
+}
+
+// ForceMigrationVersion 将迁移记录设为已执行到version（不执行任何脚本），并清除中断状态
+// 用于接管手工建表的数据库或人工修复中断的迁移之后，version为0时清空迁移记录
+func ForceMigrationVersion(version int) error {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return err
+	}
+	if err := ensureMigrationTable(); err != nil {
+		return err
+	}
+
+	if version > 0 {
+		found := false
+		for _, m := range migrations {
+			if m.Version == version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("迁移版本%d不存在", version)
+		}
+	}
+
+	if _, err := DB.Exec(`DELETE FROM schema_migrations WHERE version > ?`, version); err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.Version > version {
+			break
+		}
+		_, err := DB.Exec(`
+			INSERT INTO schema_migrations (version, name, dirty) VALUES (?, ?, 0)
+			ON DUPLICATE KEY UPDATE dirty = 0`,
+			m.Version, m.Name,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMigrationStatuses 获取所有迁移的执行状态
+func GetMigrationStatuses() ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationTable(); err != nil {
+		return nil, err
+	}
+	applied, err := getAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			s.Applied = true
+			s.Dirty = a.dirty
+			s.AppliedAt = a.appliedAt
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// splitStatements 将迁移脚本按分号拆分为单条语句，忽略注释和引号内的分号
+// 优化器提示/*+ ... */原样保留
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); i++ {
+		ch := script[i]
+		switch {
+		case ch == '-' && i+1 < len(script) && script[i+1] == '-', ch == '#':
+			// 行注释，跳到行尾
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			current.WriteByte('\n')
+		case ch == '/' && i+2 < len(script) && script[i+1] == '*' && script[i+2] == '+':
+			// 优化器提示
+			end := strings.Index(script[i+3:], "*/")
+			if end < 0 {
+				end = len(script) - i - 5
+			}
+			current.WriteString(script[i : i+end+5])
+			i += end + 4
+		case ch == '/' && i+1 < len(script) && script[i+1] == '*':
+			// 块注释
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+			}
+			current.WriteByte(' ')
+		case ch == '\'' || ch == '"' || ch == '`':
+			// 字符串或标识符，原样保留，支持反斜杠转义和连续两个引号
+			current.WriteByte(ch)
+			for i++; i < len(script); i++ {
+				current.WriteByte(script[i])
+				if script[i] == '\\' && ch != '`' && i+1 < len(script) {
+					i++
+					current.WriteByte(script[i])
+					continue
+				}
+				if script[i] == ch {
+					if i+1 < len(script) && script[i+1] == ch {
+						i++
+						current.WriteByte(script[i])
+						continue
+					}
+					break
+				}
+			}
+		case ch == ';':
+			flush()
+		default:
+			current.WriteByte(ch)
+		}
+	}
+	flush()
+
+	return statements
+}
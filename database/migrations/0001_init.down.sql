@@ -0,0 +1,6 @@
+-- 回滚初始表结构
+
+DROP TABLE IF EXISTS property_fee_monthly_stats;
+DROP TABLE IF EXISTS property_fees;
+DROP TABLE IF EXISTS residents;
+DROP TABLE IF EXISTS users;
@@ -1,8 +1,5 @@
--- 创建数据库（如果不存在）
-CREATE DATABASE IF NOT EXISTS community CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
-
--- 使用数据库
-USE community;
+-- 初始表结构：用户、住户、缴费记录和月度统计
+-- 数据库需预先创建（库名见DB_NAME），迁移在当前连接的数据库中执行
 
 -- 创建用户表
 CREATE TABLE IF NOT EXISTS users (
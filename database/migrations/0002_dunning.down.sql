@@ -0,0 +1,10 @@
+-- 回滚催缴相关表结构
+
+DROP TABLE IF EXISTS in_app_messages;
+DROP TABLE IF EXISTS dunning_records;
+DROP TABLE IF EXISTS dunning_templates;
+DROP TABLE IF EXISTS bills;
+
+ALTER TABLE residents
+    DROP COLUMN email,
+    DROP COLUMN phone;
@@ -0,0 +1,89 @@
+-- 催缴相关表结构
+
+-- 为住户表增加联系方式，用于短信和邮件催缴
+ALTER TABLE residents
+    ADD COLUMN phone VARCHAR(20) NOT NULL DEFAULT '' AFTER fare_sum,
+    ADD COLUMN email VARCHAR(100) NOT NULL DEFAULT '' AFTER phone;
+
+-- 创建账单表（应收），欠费金额 = 已到期账单 - 已缴物业费
+CREATE TABLE IF NOT EXISTS bills (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    year INT NOT NULL,
+    month INT NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    due_date DATE NOT NULL,
+    remark VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY resident_period_idx (resident_id, year, month),
+    KEY due_date_idx (due_date),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建催缴模板表，按催缴级别和渠道配置
+-- 模板支持占位符：{{name}} {{room}} {{amount}} {{days}}
+CREATE TABLE IF NOT EXISTS dunning_templates (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    level INT NOT NULL,
+    min_overdue_days INT NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content VARCHAR(500) NOT NULL,
+    enabled TINYINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY level_channel_idx (level, channel)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建催缴记录表，记录每一次发送的催缴提醒
+CREATE TABLE IF NOT EXISTS dunning_records (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    level INT NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    recipient VARCHAR(100) NOT NULL DEFAULT '',
+    content VARCHAR(500) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    overdue_days INT NOT NULL,
+    status TINYINT NOT NULL DEFAULT 1, -- 1表示发送成功，0表示发送失败
+    error VARCHAR(255),
+    sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    KEY resident_sent_idx (resident_id, sent_at),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建站内信表
+CREATE TABLE IF NOT EXISTS in_app_messages (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content VARCHAR(1000) NOT NULL,
+    is_read TINYINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP NULL,
+    KEY resident_idx (resident_id),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 插入默认催缴模板：逾期1天站内提醒，逾期30天短信，逾期60天短信+邮件正式催缴
+INSERT IGNORE INTO dunning_templates (level, min_overdue_days, channel, title, content) VALUES
+(1, 1, 'inapp', '物业费缴费提醒', '{{name}}您好，您的房屋{{room}}物业费{{amount}}元已逾期{{days}}天，请尽快缴纳。'),
+(2, 30, 'sms', '物业费催缴通知', '【物业服务中心】{{name}}您好，您的房屋{{room}}物业费{{amount}}元已逾期{{days}}天，请及时缴纳，如已缴纳请忽略。'),
+(3, 60, 'sms', '物业费正式催缴', '【物业服务中心】{{name}}您好，您的房屋{{room}}物业费{{amount}}元已逾期{{days}}天，请于7日内缴清，逾期将依约处理。'),
+(3, 60, 'email', '物业费正式催缴函', '{{name}}您好：\n您的房屋{{room}}物业费{{amount}}元已逾期{{days}}天。请于收到本函后7日内到物业服务中心或通过线上渠道缴清欠款。\n物业服务中心');
+
+-- 插入示例账单（王五、钱七欠费）
+INSERT IGNORE INTO bills (resident_id, year, month, amount, due_date, remark) VALUES
+(3, 2024, 1, 475.00, '2024-01-31', '2024年1月物业费'),
+(3, 2024, 2, 475.00, '2024-02-29', '2024年2月物业费'),
+(3, 2024, 3, 475.00, '2024-03-31', '2024年3月物业费'),
+(3, 2024, 4, 475.00, '2024-04-30', '2024年4月物业费'),
+(3, 2024, 5, 475.00, '2024-05-31', '2024年5月物业费'),
+(3, 2024, 6, 475.00, '2024-06-30', '2024年6月物业费'),
+(5, 2024, 1, 552.50, '2024-01-31', '2024年1月物业费'),
+(5, 2024, 2, 552.50, '2024-02-29', '2024年2月物业费'),
+(5, 2024, 3, 552.50, '2024-03-31', '2024年3月物业费'),
+(5, 2024, 4, 552.50, '2024-04-30', '2024年4月物业费'),
+(5, 2024, 5, 552.50, '2024-05-31', '2024年5月物业费'),
+(5, 2024, 6, 552.50, '2024-06-30', '2024年6月物业费');
@@ -0,0 +1,8 @@
+-- 回滚费用减免与优惠相关表结构
+
+DROP TABLE IF EXISTS fee_adjustments;
+
+DELETE FROM users WHERE username = 'finance';
+
+ALTER TABLE users
+    DROP COLUMN role;
@@ -0,0 +1,31 @@
+-- 费用减免与优惠相关表结构
+
+-- 为用户表增加角色：admin 管理员，finance 财务，staff 普通工作人员
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'staff' AFTER email;
+
+UPDATE users SET role = 'admin' WHERE username = 'admin';
+
+-- 创建费用调整表（减免、优惠），审批通过后自动冲抵对应账单
+CREATE TABLE IF NOT EXISTS fee_adjustments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    bill_id INT NOT NULL,
+    type VARCHAR(20) NOT NULL,         -- waiver 困难减免，discount 提前缴费优惠
+    amount DECIMAL(10,2) NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending 待审批，approved 已通过，rejected 已驳回
+    requested_by INT NOT NULL,
+    reviewed_by INT,
+    review_remark VARCHAR(255),
+    reviewed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY bill_status_idx (bill_id, status),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE,
+    FOREIGN KEY (bill_id) REFERENCES bills(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 插入财务示例用户（密码为123456）
+INSERT IGNORE INTO users (username, password, email, role) VALUES
+('finance', 'e10adc3949ba59abbe56e057f20f883e', 'finance@example.com', 'finance');
@@ -0,0 +1,30 @@
+-- 回滚收费项目相关表结构
+
+-- 只保留物业费的账单和月度统计，其他收费项目的账单会被删除
+DELETE FROM property_fee_monthly_stats WHERE fee_type_id <> 1;
+
+ALTER TABLE property_fee_monthly_stats
+    DROP FOREIGN KEY fk_monthly_stats_fee_type;
+
+ALTER TABLE property_fee_monthly_stats
+    DROP INDEX year_month_type_idx,
+    ADD UNIQUE KEY year_month_idx (year, month),
+    DROP COLUMN fee_type_id;
+
+ALTER TABLE property_fees
+    DROP FOREIGN KEY fk_property_fees_fee_type;
+
+ALTER TABLE property_fees
+    DROP COLUMN fee_type_id;
+
+DELETE FROM bills WHERE fee_type_id <> 1;
+
+ALTER TABLE bills
+    DROP FOREIGN KEY fk_bills_fee_type;
+
+ALTER TABLE bills
+    ADD UNIQUE KEY resident_period_idx (resident_id, year, month),
+    DROP INDEX resident_type_period_idx,
+    DROP COLUMN fee_type_id;
+
+DROP TABLE IF EXISTS fee_types;
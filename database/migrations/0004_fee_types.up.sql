@@ -0,0 +1,42 @@
+-- 收费项目相关表结构
+
+-- 创建收费项目表
+-- pricing_rule: per_area 按建筑面积计费，fixed 按户固定收费，per_usage 按用量计费
+CREATE TABLE IF NOT EXISTS fee_types (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(30) NOT NULL UNIQUE,
+    name VARCHAR(50) NOT NULL,
+    pricing_rule VARCHAR(20) NOT NULL,
+    unit_price DECIMAL(10,4) NOT NULL DEFAULT 0.0000,
+    unit VARCHAR(20) NOT NULL DEFAULT '',
+    accounting_category VARCHAR(50) NOT NULL,
+    enabled TINYINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 插入默认收费项目，物业费ID固定为1，已有账单和缴费记录默认归入物业费
+INSERT IGNORE INTO fee_types (id, code, name, pricing_rule, unit_price, unit, accounting_category) VALUES
+(1, 'property', '物业费', 'per_area', 2.5000, '元/平方米·月', '物业服务收入'),
+(2, 'water', '水费', 'per_usage', 3.5000, '元/吨', '代收水费'),
+(3, 'electricity', '电费', 'per_usage', 0.5600, '元/度', '代收电费'),
+(4, 'parking', '停车费', 'fixed', 300.0000, '元/月', '停车服务收入'),
+(5, 'garbage', '垃圾处理费', 'fixed', 15.0000, '元/户·月', '代收垃圾处理费'),
+(6, 'maintenance_fund', '专项维修资金', 'per_area', 0.2500, '元/平方米·月', '专项维修资金');
+
+-- 账单、缴费记录和月度统计按收费项目区分
+ALTER TABLE bills
+    ADD COLUMN fee_type_id INT NOT NULL DEFAULT 1 AFTER resident_id,
+    DROP INDEX resident_period_idx,
+    ADD UNIQUE KEY resident_type_period_idx (resident_id, fee_type_id, year, month),
+    ADD CONSTRAINT fk_bills_fee_type FOREIGN KEY (fee_type_id) REFERENCES fee_types(id);
+
+ALTER TABLE property_fees
+    ADD COLUMN fee_type_id INT NOT NULL DEFAULT 1 AFTER resident_id,
+    ADD CONSTRAINT fk_property_fees_fee_type FOREIGN KEY (fee_type_id) REFERENCES fee_types(id);
+
+ALTER TABLE property_fee_monthly_stats
+    ADD COLUMN fee_type_id INT NOT NULL DEFAULT 1 AFTER month,
+    DROP INDEX year_month_idx,
+    ADD UNIQUE KEY year_month_type_idx (year, month, fee_type_id),
+    ADD CONSTRAINT fk_monthly_stats_fee_type FOREIGN KEY (fee_type_id) REFERENCES fee_types(id);
@@ -0,0 +1,5 @@
+-- 回滚抄表计费相关表结构
+
+DROP TABLE IF EXISTS tariff_tiers;
+DROP TABLE IF EXISTS meter_readings;
+DROP TABLE IF EXISTS meters;
@@ -0,0 +1,58 @@
+-- 抄表计费相关表结构
+
+-- 创建计量表（水表、电表）
+CREATE TABLE IF NOT EXISTS meters (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    fee_type_id INT NOT NULL,
+    meter_no VARCHAR(50) NOT NULL UNIQUE,
+    multiplier DECIMAL(10,2) NOT NULL DEFAULT 1.00, -- 倍率
+    max_reading DECIMAL(12,2) NOT NULL DEFAULT 0.00, -- 表盘最大读数，用于判断翻转，0表示不翻转
+    initial_reading DECIMAL(12,2) NOT NULL DEFAULT 0.00,
+    installed_at DATE NOT NULL,
+    status TINYINT NOT NULL DEFAULT 1, -- 1表示在用，0表示停用
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY resident_idx (resident_id),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE,
+    FOREIGN KEY (fee_type_id) REFERENCES fee_types(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建抄表记录表
+CREATE TABLE IF NOT EXISTS meter_readings (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    meter_id INT NOT NULL,
+    year INT NOT NULL,
+    month INT NOT NULL,
+    reading DECIMAL(12,2) NOT NULL,
+    consumption DECIMAL(12,2) NOT NULL,
+    read_at DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'normal', -- normal 正常，rollover 表盘翻转，abnormal 异常突增（已确认）
+    remark VARCHAR(255),
+    entered_by INT NOT NULL,
+    bill_id INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY meter_period_idx (meter_id, year, month),
+    FOREIGN KEY (meter_id) REFERENCES meters(id) ON DELETE CASCADE,
+    FOREIGN KEY (bill_id) REFERENCES bills(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建阶梯价格表，upper_limit为该档月用量上限，NULL表示不封顶
+CREATE TABLE IF NOT EXISTS tariff_tiers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    fee_type_id INT NOT NULL,
+    tier INT NOT NULL,
+    upper_limit DECIMAL(12,2),
+    unit_price DECIMAL(10,4) NOT NULL,
+    UNIQUE KEY fee_type_tier_idx (fee_type_id, tier),
+    FOREIGN KEY (fee_type_id) REFERENCES fee_types(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 插入默认阶梯价格（按月）
+INSERT IGNORE INTO tariff_tiers (fee_type_id, tier, upper_limit, unit_price) VALUES
+(2, 1, 15.00, 3.5000),
+(2, 2, 25.00, 4.9000),
+(2, 3, NULL, 7.0000),
+(3, 1, 200.00, 0.5600),
+(3, 2, 400.00, 0.6100),
+(3, 3, NULL, 0.8600);
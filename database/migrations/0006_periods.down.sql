@@ -0,0 +1,4 @@
+-- 回滚账期结账与审计日志相关表结构
+
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS accounting_periods;
@@ -0,0 +1,30 @@
+-- 账期结账与审计日志相关表结构
+
+-- 创建会计账期表，按月结账，未记录的账期视为未结账
+CREATE TABLE IF NOT EXISTS accounting_periods (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    year INT NOT NULL,
+    month INT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'closed', -- closed 已结账，open 已反结账
+    closed_by INT,
+    closed_at TIMESTAMP NULL,
+    reopened_by INT,
+    reopened_at TIMESTAMP NULL,
+    reopen_reason VARCHAR(255),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY year_month_idx (year, month)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建审计日志表
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    entity VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(50) NOT NULL DEFAULT '',
+    detail VARCHAR(1000),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    KEY entity_idx (entity, entity_id),
+    KEY created_at_idx (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
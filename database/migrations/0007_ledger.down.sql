@@ -0,0 +1,12 @@
+-- 回滚总账（复式记账）相关表结构
+
+ALTER TABLE property_fees
+    DROP COLUMN refunded_at;
+
+DROP TABLE IF EXISTS journal_lines;
+DROP TABLE IF EXISTS journal_entries;
+
+ALTER TABLE fee_types
+    DROP COLUMN account_code;
+
+DROP TABLE IF EXISTS gl_accounts;
@@ -0,0 +1,72 @@
+-- 总账（复式记账）相关表结构
+
+-- 创建会计科目表
+-- type: asset 资产，liability 负债，equity 所有者权益，income 收入，expense 费用
+CREATE TABLE IF NOT EXISTS gl_accounts (
+    code VARCHAR(20) PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    parent_code VARCHAR(20),
+    enabled TINYINT NOT NULL DEFAULT 1
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT IGNORE INTO gl_accounts (code, name, type, parent_code) VALUES
+('1001', '库存现金', 'asset', NULL),
+('1002', '银行存款', 'asset', NULL),
+('1012', '其他货币资金', 'asset', NULL),
+('1122', '应收账款', 'asset', NULL),
+('2203', '预收账款', 'liability', NULL),
+('2241', '其他应付款', 'liability', NULL),
+('2241.01', '代收水费', 'liability', '2241'),
+('2241.02', '代收电费', 'liability', '2241'),
+('2241.03', '代收垃圾处理费', 'liability', '2241'),
+('2241.04', '专项维修资金', 'liability', '2241'),
+('6001', '主营业务收入', 'income', NULL),
+('6001.01', '物业服务收入', 'income', '6001'),
+('6001.02', '停车服务收入', 'income', '6001'),
+('6051', '其他业务收入', 'income', NULL),
+('6051.01', '滞纳金收入', 'income', '6051'),
+('6401', '主营业务成本', 'expense', NULL);
+
+-- 收费项目对应的贷方科目（收入或代收款）
+ALTER TABLE fee_types
+    ADD COLUMN account_code VARCHAR(20) NOT NULL DEFAULT '6001.01' AFTER accounting_category;
+
+UPDATE fee_types SET account_code = '6001.01' WHERE code = 'property';
+UPDATE fee_types SET account_code = '2241.01' WHERE code = 'water';
+UPDATE fee_types SET account_code = '2241.02' WHERE code = 'electricity';
+UPDATE fee_types SET account_code = '6001.02' WHERE code = 'parking';
+UPDATE fee_types SET account_code = '2241.03' WHERE code = 'garbage';
+UPDATE fee_types SET account_code = '2241.04' WHERE code = 'maintenance_fund';
+
+-- 创建记账凭证表，每个业务单据只生成一张凭证
+-- source_type: bill 账单，payment 收款，refund 退款，adjustment 减免
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    entry_date DATE NOT NULL,
+    source_type VARCHAR(20) NOT NULL,
+    source_id INT NOT NULL,
+    memo VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY source_idx (source_type, source_id),
+    KEY entry_date_idx (entry_date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建凭证分录表
+CREATE TABLE IF NOT EXISTS journal_lines (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    entry_id INT NOT NULL,
+    account_code VARCHAR(20) NOT NULL,
+    resident_id INT,
+    debit DECIMAL(12,2) NOT NULL DEFAULT 0.00,
+    credit DECIMAL(12,2) NOT NULL DEFAULT 0.00,
+    memo VARCHAR(255) NOT NULL,
+    KEY account_idx (account_code),
+    KEY resident_idx (resident_id),
+    FOREIGN KEY (entry_id) REFERENCES journal_entries(id) ON DELETE CASCADE,
+    FOREIGN KEY (account_code) REFERENCES gl_accounts(code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 缴费状态：1 已缴，2 已退款
+ALTER TABLE property_fees
+    ADD COLUMN refunded_at DATE NULL AFTER payment_status;
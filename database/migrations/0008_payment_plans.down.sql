@@ -0,0 +1,5 @@
+-- 回滚分期还款与滞纳金相关表结构
+
+-- 滞纳金收费项目保留，已出账的滞纳金账单仍引用该项目
+DROP TABLE IF EXISTS payment_plan_instalments;
+DROP TABLE IF EXISTS payment_plans;
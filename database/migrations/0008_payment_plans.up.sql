@@ -0,0 +1,40 @@
+-- 分期还款与滞纳金相关表结构
+
+-- 滞纳金作为独立收费项目按月出账，记入滞纳金收入
+INSERT IGNORE INTO fee_types (code, name, pricing_rule, unit_price, unit, accounting_category, account_code) VALUES
+('late_fee', '滞纳金', 'fixed', 0.0000, '元', '滞纳金收入', '6051.01');
+
+-- 创建分期还款计划表，total_amount为计划生效日的欠费余额
+-- status: active 执行中，completed 已还清，cancelled 已取消
+CREATE TABLE IF NOT EXISTS payment_plans (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    total_amount DECIMAL(10,2) NOT NULL,
+    start_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    remark VARCHAR(255),
+    created_by INT NOT NULL,
+    cancelled_by INT,
+    cancelled_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY resident_status_idx (resident_id, status),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建分期明细表
+-- status: pending 待还，paid 已还，missed 逾期未还
+CREATE TABLE IF NOT EXISTS payment_plan_instalments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    plan_id INT NOT NULL,
+    seq INT NOT NULL,
+    due_date DATE NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    paid_amount DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    paid_at DATE NULL,
+    alerted_at TIMESTAMP NULL, -- 逾期提醒发送时间
+    UNIQUE KEY plan_seq_idx (plan_id, seq),
+    KEY status_idx (status),
+    FOREIGN KEY (plan_id) REFERENCES payment_plans(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,8 @@
+-- 回滚报修工单相关表结构
+
+DROP TABLE IF EXISTS work_order_photos;
+DROP TABLE IF EXISTS work_order_comments;
+DROP TABLE IF EXISTS work_orders;
+DROP TABLE IF EXISTS work_order_categories;
+
+DELETE FROM users WHERE username = 'repair01';
@@ -0,0 +1,86 @@
+-- 报修工单相关表结构
+
+-- 插入维修人员示例用户（密码为123456），工单只能指派给maintenance角色
+INSERT IGNORE INTO users (username, password, email, role) VALUES
+('repair01', 'e10adc3949ba59abbe56e057f20f883e', 'repair01@example.com', 'maintenance');
+
+-- 创建工单分类表
+CREATE TABLE IF NOT EXISTS work_order_categories (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(30) NOT NULL UNIQUE,
+    name VARCHAR(50) NOT NULL,
+    enabled TINYINT NOT NULL DEFAULT 1
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT IGNORE INTO work_order_categories (code, name) VALUES
+('plumbing', '给排水'),
+('electrical', '电路照明'),
+('door_window', '门窗锁具'),
+('elevator', '电梯'),
+('public_facility', '公共设施'),
+('cleaning', '绿化保洁'),
+('other', '其他');
+
+-- 创建工单表
+-- location_type: house 住户房屋（resident_id必填），common 公共区域（location必填）
+-- priority: low 低，normal 普通，high 高，urgent 紧急
+-- status: pending 待指派，assigned 已指派，in_progress 处理中，resolved 已完成，closed 已关闭，cancelled 已取消
+-- source: staff 工作人员登记，resident 住户报修
+CREATE TABLE IF NOT EXISTS work_orders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_no VARCHAR(20) UNIQUE, -- 创建后按 WO+日期+ID 生成
+    category_id INT NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    description VARCHAR(1000) NOT NULL DEFAULT '',
+    location_type VARCHAR(20) NOT NULL,
+    resident_id INT,
+    location VARCHAR(100) NOT NULL DEFAULT '',
+    priority VARCHAR(20) NOT NULL DEFAULT 'normal',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    source VARCHAR(20) NOT NULL DEFAULT 'staff',
+    contact_name VARCHAR(50) NOT NULL DEFAULT '',
+    contact_phone VARCHAR(20) NOT NULL DEFAULT '',
+    created_by INT NOT NULL,
+    assignee_id INT,
+    assigned_at TIMESTAMP NULL,
+    started_at TIMESTAMP NULL,
+    resolved_at TIMESTAMP NULL,
+    closed_at TIMESTAMP NULL,
+    resolution VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY status_priority_idx (status, priority),
+    KEY assignee_status_idx (assignee_id, status),
+    KEY resident_idx (resident_id),
+    FOREIGN KEY (category_id) REFERENCES work_order_categories(id),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE SET NULL,
+    FOREIGN KEY (assignee_id) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建工单处理记录表，状态变更会自动记录一条status类型的记录
+-- kind: comment 评论，status 状态变更
+CREATE TABLE IF NOT EXISTS work_order_comments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    user_id INT NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    kind VARCHAR(20) NOT NULL DEFAULT 'comment',
+    content VARCHAR(1000) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    KEY order_idx (order_id),
+    FOREIGN KEY (order_id) REFERENCES work_orders(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建工单照片表，文件保存在UPLOAD_DIR下
+CREATE TABLE IF NOT EXISTS work_order_photos (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    file_path VARCHAR(255) NOT NULL,
+    original_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    size INT NOT NULL,
+    uploaded_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    KEY order_idx (order_id),
+    FOREIGN KEY (order_id) REFERENCES work_orders(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
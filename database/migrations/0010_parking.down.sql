@@ -0,0 +1,7 @@
+-- 回滚车位与车辆相关表结构
+
+DROP TABLE IF EXISTS vehicles;
+DROP TABLE IF EXISTS parking_contracts;
+DROP TABLE IF EXISTS parking_spaces;
+
+UPDATE fee_types SET pricing_rule = 'fixed', unit = '元/月' WHERE code = 'parking';
@@ -0,0 +1,67 @@
+-- 车位与车辆相关表结构
+
+-- 停车费改为按车位租赁合同出账，月租金取自合同
+UPDATE fee_types SET pricing_rule = 'per_contract', unit = '元/车位·月' WHERE code = 'parking';
+
+-- 创建车位表
+-- type: owned 产权车位（resident_id为业主），rented 租赁车位（按合同出租），visitor 访客车位
+-- occupied 仅用于访客车位，表示当前是否有车停放
+CREATE TABLE IF NOT EXISTS parking_spaces (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    space_no VARCHAR(20) NOT NULL UNIQUE,
+    zone VARCHAR(50) NOT NULL DEFAULT '',
+    type VARCHAR(20) NOT NULL,
+    resident_id INT,
+    occupied TINYINT NOT NULL DEFAULT 0,
+    enabled TINYINT NOT NULL DEFAULT 1,
+    remark VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY type_idx (type),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建车位租赁合同表
+-- status: active 生效中（到期后视为已到期），terminated 已提前终止
+CREATE TABLE IF NOT EXISTS parking_contracts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    space_id INT NOT NULL,
+    resident_id INT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    monthly_rent DECIMAL(10,2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    remark VARCHAR(255),
+    created_by INT NOT NULL,
+    terminated_at DATE NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY space_period_idx (space_id, start_date, end_date),
+    KEY resident_idx (resident_id),
+    FOREIGN KEY (space_id) REFERENCES parking_spaces(id),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建车辆表，车牌号唯一
+CREATE TABLE IF NOT EXISTS vehicles (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    plate_no VARCHAR(10) NOT NULL UNIQUE,
+    brand VARCHAR(50) NOT NULL DEFAULT '',
+    color VARCHAR(20) NOT NULL DEFAULT '',
+    space_id INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY resident_idx (resident_id),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE,
+    FOREIGN KEY (space_id) REFERENCES parking_spaces(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 插入示例车位
+INSERT IGNORE INTO parking_spaces (space_no, zone, type, resident_id) VALUES
+('B1-001', '地下一层', 'owned', 1),
+('B1-002', '地下一层', 'owned', NULL),
+('B1-003', '地下一层', 'rented', NULL),
+('B1-004', '地下一层', 'rented', NULL),
+('G-001', '地面', 'visitor', NULL),
+('G-002', '地面', 'visitor', NULL);
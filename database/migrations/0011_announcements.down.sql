@@ -0,0 +1,14 @@
+-- 回滚社区公告相关表结构
+
+DROP TABLE IF EXISTS announcement_reads;
+DROP TABLE IF EXISTS announcement_targets;
+DROP TABLE IF EXISTS announcements;
+
+-- 住户账号随住户关联一并删除
+DELETE FROM users WHERE role = 'resident';
+
+ALTER TABLE users
+    DROP FOREIGN KEY fk_users_resident;
+
+ALTER TABLE users
+    DROP COLUMN resident_id;
@@ -0,0 +1,52 @@
+-- 社区公告相关表结构
+
+-- 为用户表增加住户账号关联，role为resident的用户只能访问住户端接口
+ALTER TABLE users
+    ADD COLUMN resident_id INT NULL AFTER role,
+    ADD UNIQUE KEY resident_idx (resident_id),
+    ADD CONSTRAINT fk_users_resident FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE;
+
+-- 创建公告表
+-- status: draft 草稿，published 已发布（publish_at之后对住户可见，expire_at之后不再显示），withdrawn 已撤回
+-- audience: all 全体住户，targeted 指定楼栋或单元
+CREATE TABLE IF NOT EXISTS announcements (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    title VARCHAR(100) NOT NULL,
+    content MEDIUMTEXT NOT NULL,
+    audience VARCHAR(20) NOT NULL DEFAULT 'all',
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    publish_at DATETIME NULL,
+    expire_at DATETIME NULL,
+    pinned TINYINT NOT NULL DEFAULT 0,
+    created_by INT NOT NULL,
+    published_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY status_publish_idx (status, publish_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建公告发布范围表，unit_number为空表示整栋楼
+CREATE TABLE IF NOT EXISTS announcement_targets (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    announcement_id INT NOT NULL,
+    block_number VARCHAR(10) NOT NULL,
+    unit_number VARCHAR(10) NOT NULL DEFAULT '',
+    UNIQUE KEY target_idx (announcement_id, block_number, unit_number),
+    FOREIGN KEY (announcement_id) REFERENCES announcements(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建公告阅读回执表，每个住户账号只记录首次阅读时间
+CREATE TABLE IF NOT EXISTS announcement_reads (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    announcement_id INT NOT NULL,
+    user_id INT NOT NULL,
+    resident_id INT NOT NULL,
+    read_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY announcement_user_idx (announcement_id, user_id),
+    FOREIGN KEY (announcement_id) REFERENCES announcements(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 插入住户示例账号（张三，密码为123456）
+INSERT IGNORE INTO users (username, password, email, role, resident_id) VALUES
+('zhangsan', 'e10adc3949ba59abbe56e057f20f883e', '', 'resident', 1);
@@ -0,0 +1,4 @@
+-- 回滚投诉建议相关表结构
+
+DROP TABLE IF EXISTS complaint_logs;
+DROP TABLE IF EXISTS complaints;
@@ -0,0 +1,52 @@
+-- 投诉建议相关表结构
+
+-- 创建投诉建议表
+-- type: complaint 投诉，suggestion 建议
+-- status: pending 待受理，processing 处理中，responded 已答复，closed 已关闭
+-- anonymous为1时仅管理员可以看到住户和联系人信息
+-- deadline为答复期限，超期未答复时自动升级（escalation_level加1）并顺延期限
+CREATE TABLE IF NOT EXISTS complaints (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    complaint_no VARCHAR(20) NULL UNIQUE,
+    type VARCHAR(20) NOT NULL DEFAULT 'complaint',
+    category VARCHAR(20) NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content VARCHAR(2000) NOT NULL,
+    resident_id INT NULL,
+    anonymous TINYINT NOT NULL DEFAULT 0,
+    channel VARCHAR(20) NOT NULL DEFAULT 'phone',
+    contact_name VARCHAR(50) NOT NULL DEFAULT '',
+    contact_phone VARCHAR(20) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    assignee_id INT NULL,
+    deadline DATETIME NOT NULL,
+    escalation_level INT NOT NULL DEFAULT 0,
+    escalated_at DATETIME NULL,
+    response VARCHAR(2000) NOT NULL DEFAULT '',
+    responded_at DATETIME NULL,
+    closed_at DATETIME NULL,
+    satisfaction TINYINT NULL,
+    feedback VARCHAR(500) NOT NULL DEFAULT '',
+    created_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY status_deadline_idx (status, deadline),
+    KEY created_idx (created_at),
+    KEY resident_idx (resident_id),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE SET NULL,
+    FOREIGN KEY (assignee_id) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建投诉处理记录表
+-- kind: comment 备注，status 状态变更，escalate 超期升级（user_id为0表示系统）
+CREATE TABLE IF NOT EXISTS complaint_logs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    complaint_id INT NOT NULL,
+    user_id INT NOT NULL DEFAULT 0,
+    username VARCHAR(50) NOT NULL DEFAULT '',
+    kind VARCHAR(20) NOT NULL,
+    content VARCHAR(1000) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    KEY complaint_idx (complaint_id),
+    FOREIGN KEY (complaint_id) REFERENCES complaints(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,4 @@
+-- 回滚访客通行相关表结构
+
+DROP TABLE IF EXISTS visitor_logs;
+DROP TABLE IF EXISTS visitor_passes;
@@ -0,0 +1,38 @@
+-- 访客通行相关表结构
+
+-- 创建访客通行证表
+-- 通行码由通行证ID和失效时间签名生成，不在数据库中保存
+-- status: active 有效，revoked 已作废；是否过期由valid_until判断
+CREATE TABLE IF NOT EXISTS visitor_passes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    visitor_name VARCHAR(50) NOT NULL,
+    visitor_phone VARCHAR(20) NOT NULL DEFAULT '',
+    plate_no VARCHAR(10) NOT NULL DEFAULT '',
+    visitors INT NOT NULL DEFAULT 1,
+    purpose VARCHAR(100) NOT NULL DEFAULT '',
+    valid_from DATETIME NOT NULL,
+    valid_until DATETIME NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    source VARCHAR(20) NOT NULL DEFAULT 'staff',
+    created_by INT NOT NULL,
+    revoked_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY resident_idx (resident_id),
+    KEY valid_idx (valid_from, valid_until),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建访客进出记录表，exited_at为空表示访客仍在小区内
+CREATE TABLE IF NOT EXISTS visitor_logs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    pass_id INT NOT NULL,
+    entered_at DATETIME NOT NULL,
+    entry_guard INT NOT NULL,
+    exited_at DATETIME NULL,
+    exit_guard INT NULL,
+    KEY pass_idx (pass_id),
+    KEY entered_idx (entered_at),
+    FOREIGN KEY (pass_id) REFERENCES visitor_passes(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
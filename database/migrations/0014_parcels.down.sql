@@ -0,0 +1,3 @@
+-- 回滚快递代收相关表结构
+
+DROP TABLE IF EXISTS parcels;
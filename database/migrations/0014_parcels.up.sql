@@ -0,0 +1,34 @@
+-- 快递代收相关表结构
+
+-- 创建快递包裹表
+-- status: waiting 待取件，picked_up 已取件，returned 已退回
+-- pickup_code为6位取件码，同一时间待取件的包裹取件码不重复
+-- notified_at为到件通知时间，reminded_at为超期催取提醒时间（每件只提醒一次）
+CREATE TABLE IF NOT EXISTS parcels (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    carrier VARCHAR(30) NOT NULL,
+    tracking_no VARCHAR(50) NOT NULL,
+    recipient_name VARCHAR(50) NOT NULL DEFAULT '',
+    shelf VARCHAR(20) NOT NULL DEFAULT '',
+    pickup_code CHAR(6) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting',
+    note VARCHAR(200) NOT NULL DEFAULT '',
+    received_at DATETIME NOT NULL,
+    received_by INT NOT NULL,
+    notified_at DATETIME NULL,
+    reminded_at DATETIME NULL,
+    picked_up_at DATETIME NULL,
+    picked_up_by INT NULL,
+    picker_name VARCHAR(50) NOT NULL DEFAULT '',
+    returned_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY carrier_tracking_idx (carrier, tracking_no),
+    KEY status_received_idx (status, received_at),
+    KEY pickup_code_idx (pickup_code),
+    KEY resident_idx (resident_id),
+    FOREIGN KEY (resident_id) REFERENCES residents(id),
+    FOREIGN KEY (received_by) REFERENCES users(id),
+    FOREIGN KEY (picked_up_by) REFERENCES users(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,5 @@
+-- 回滚公共设施预约相关表结构
+
+-- 公共设施使用费收费项目和科目保留，已有缴费记录和凭证仍引用
+DROP TABLE IF EXISTS facility_bookings;
+DROP TABLE IF EXISTS facilities;
@@ -0,0 +1,67 @@
+-- 公共设施预约相关表结构
+
+-- 场地使用费按预约收取，收费记录写入property_fees
+INSERT IGNORE INTO gl_accounts (code, name, type, parent_code) VALUES
+('6051.02', '场地使用收入', 'income', '6051');
+
+INSERT IGNORE INTO fee_types (code, name, pricing_rule, unit_price, unit, accounting_category, account_code) VALUES
+('facility', '公共设施使用费', 'per_booking', 0.0000, '元/次', '场地使用收入', '6051.02');
+
+-- 创建公共设施表
+-- 开放时间为每天open_time至close_time，按slot_minutes分时段预约，每次预约1至max_slots个连续时段
+-- advance_days: 最多可提前预约的天数
+-- weekly_quota: 每户每周（周一至周日）可预约的次数，0为不限
+-- fee_per_slot: 每时段收费，0为免费
+-- cancel_hours: 开始前cancel_hours小时之前取消全额退款，之后取消不退费
+CREATE TABLE IF NOT EXISTS facilities (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    location VARCHAR(100) NOT NULL DEFAULT '',
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    open_time TIME NOT NULL,
+    close_time TIME NOT NULL,
+    slot_minutes INT NOT NULL DEFAULT 60,
+    max_slots INT NOT NULL DEFAULT 2,
+    advance_days INT NOT NULL DEFAULT 7,
+    weekly_quota INT NOT NULL DEFAULT 0,
+    fee_per_slot DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    cancel_hours INT NOT NULL DEFAULT 24,
+    enabled TINYINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建设施预约表
+-- status: booked 已预约，cancelled 已取消；是否已结束由end_time判断
+-- fee_id为场地使用费的缴费记录，免费设施为空；refunded为1表示取消时已退款
+CREATE TABLE IF NOT EXISTS facility_bookings (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    facility_id INT NOT NULL,
+    resident_id INT NOT NULL,
+    start_time DATETIME NOT NULL,
+    end_time DATETIME NOT NULL,
+    slots INT NOT NULL,
+    attendees INT NOT NULL DEFAULT 1,
+    purpose VARCHAR(100) NOT NULL DEFAULT '',
+    amount DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    fee_id INT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'booked',
+    source VARCHAR(20) NOT NULL DEFAULT 'staff',
+    created_by INT NOT NULL,
+    cancelled_at DATETIME NULL,
+    cancel_reason VARCHAR(200) NOT NULL DEFAULT '',
+    refunded TINYINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY facility_time_idx (facility_id, status, start_time),
+    KEY resident_idx (resident_id, start_time),
+    FOREIGN KEY (facility_id) REFERENCES facilities(id),
+    FOREIGN KEY (resident_id) REFERENCES residents(id),
+    FOREIGN KEY (fee_id) REFERENCES property_fees(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 插入示例设施
+INSERT IGNORE INTO facilities (id, name, location, open_time, close_time, slot_minutes, max_slots, advance_days, weekly_quota, fee_per_slot, cancel_hours) VALUES
+(1, '会所多功能厅', '会所二楼', '09:00', '21:00', 60, 4, 14, 1, 50.00, 24),
+(2, '会议室', '物业服务中心三楼', '08:00', '20:00', 30, 4, 7, 3, 0.00, 2),
+(3, '网球场', '3栋东侧', '06:00', '22:00', 60, 2, 7, 2, 20.00, 12);
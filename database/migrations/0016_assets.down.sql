@@ -0,0 +1,7 @@
+-- 回滚设备设施维保相关表结构
+
+DROP TABLE IF EXISTS asset_inspection_files;
+DROP TABLE IF EXISTS asset_inspections;
+DROP TABLE IF EXISTS maintenance_tasks;
+DROP TABLE IF EXISTS maintenance_schedules;
+DROP TABLE IF EXISTS assets;
@@ -0,0 +1,109 @@
+-- 设备设施维保相关表结构
+
+-- 创建设备台账表
+-- category: elevator 电梯，pump 水泵，fire 消防设施，electrical 配电设备，other 其他
+-- block_number为所在楼栋，为空表示小区公共设备
+-- status: in_service 在用，out_of_service 停用，retired 报废
+CREATE TABLE IF NOT EXISTS assets (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    asset_no VARCHAR(30) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    category VARCHAR(20) NOT NULL,
+    block_number VARCHAR(20) NOT NULL DEFAULT '',
+    location VARCHAR(100) NOT NULL DEFAULT '',
+    model VARCHAR(50) NOT NULL DEFAULT '',
+    manufacturer VARCHAR(100) NOT NULL DEFAULT '',
+    install_date DATE NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_service',
+    remark VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY category_idx (category),
+    KEY block_idx (block_number)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建维保计划表
+-- kind: maintenance 日常维保，inspection 法定检验
+-- 周期为interval_value个interval_unit（day 天，month 月），next_due为下次到期日
+-- 到期前lead_days天生成维保任务，完成检查后按检查日期顺延下次到期日
+CREATE TABLE IF NOT EXISTS maintenance_schedules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    asset_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL DEFAULT 'maintenance',
+    interval_value INT NOT NULL,
+    interval_unit VARCHAR(10) NOT NULL DEFAULT 'month',
+    lead_days INT NOT NULL DEFAULT 7,
+    next_due DATE NOT NULL,
+    last_done DATE NULL,
+    enabled TINYINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY next_due_idx (enabled, next_due),
+    FOREIGN KEY (asset_id) REFERENCES assets(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建维保任务表，每个计划同一时间只有一个待完成任务
+-- 生成任务时同时创建公共区域工单，便于指派维修人员
+-- status: pending 待完成，completed 已完成
+CREATE TABLE IF NOT EXISTS maintenance_tasks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    schedule_id INT NOT NULL,
+    asset_id INT NOT NULL,
+    due_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    work_order_id INT NULL,
+    inspection_id INT NULL,
+    completed_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY schedule_due_idx (schedule_id, due_date),
+    KEY status_due_idx (status, due_date),
+    FOREIGN KEY (schedule_id) REFERENCES maintenance_schedules(id) ON DELETE CASCADE,
+    FOREIGN KEY (asset_id) REFERENCES assets(id) ON DELETE CASCADE,
+    FOREIGN KEY (work_order_id) REFERENCES work_orders(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建检查记录表
+-- result: pass 合格，rectify 需整改，fail 不合格
+CREATE TABLE IF NOT EXISTS asset_inspections (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    asset_id INT NOT NULL,
+    task_id INT NULL,
+    inspected_on DATE NOT NULL,
+    inspector VARCHAR(50) NOT NULL,
+    agency VARCHAR(100) NOT NULL DEFAULT '',
+    certificate_no VARCHAR(50) NOT NULL DEFAULT '',
+    result VARCHAR(20) NOT NULL,
+    notes VARCHAR(1000) NOT NULL DEFAULT '',
+    created_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    KEY asset_idx (asset_id, inspected_on),
+    FOREIGN KEY (asset_id) REFERENCES assets(id) ON DELETE CASCADE,
+    FOREIGN KEY (task_id) REFERENCES maintenance_tasks(id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建检查记录附件表（检验报告、现场照片），文件保存在UPLOAD_DIR下
+CREATE TABLE IF NOT EXISTS asset_inspection_files (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    inspection_id INT NOT NULL,
+    file_path VARCHAR(255) NOT NULL,
+    original_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    size INT NOT NULL,
+    uploaded_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    KEY inspection_idx (inspection_id),
+    FOREIGN KEY (inspection_id) REFERENCES asset_inspections(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 插入示例设备和维保计划：电梯每半月维保、每年法定检验，消防设施每月巡检
+INSERT IGNORE INTO assets (id, asset_no, name, category, block_number, location, model, manufacturer) VALUES
+(1, 'DT-01-1', '1栋1号电梯', 'elevator', '1栋', '1栋1单元', 'GeN2', '奥的斯'),
+(2, 'SB-01', '生活水泵', 'pump', '', '地下一层水泵房', 'CDL32', '南方泵业'),
+(3, 'XF-01', '消防栓系统', 'fire', '', '小区全域', '', '');
+
+INSERT IGNORE INTO maintenance_schedules (id, asset_id, name, kind, interval_value, interval_unit, lead_days, next_due) VALUES
+(1, 1, '电梯半月维保', 'maintenance', 15, 'day', 3, CURDATE()),
+(2, 1, '电梯年度检验', 'inspection', 12, 'month', 30, DATE_ADD(CURDATE(), INTERVAL 20 DAY)),
+(3, 2, '水泵季度保养', 'maintenance', 3, 'month', 7, DATE_ADD(CURDATE(), INTERVAL 10 DAY)),
+(4, 3, '消防设施月度巡检', 'inspection', 1, 'month', 5, DATE_SUB(CURDATE(), INTERVAL 2 DAY));
@@ -0,0 +1,8 @@
+-- 回滚业主表决和问卷调查相关表结构
+
+DROP TABLE IF EXISTS poll_results;
+DROP TABLE IF EXISTS poll_ballots;
+DROP TABLE IF EXISTS poll_participants;
+DROP TABLE IF EXISTS poll_options;
+DROP TABLE IF EXISTS poll_blocks;
+DROP TABLE IF EXISTS polls;
@@ -0,0 +1,93 @@
+-- 业主表决和问卷调查相关表结构
+
+-- 创建表决/问卷表
+-- kind: vote 业主表决（选项固定为同意、反对、弃权），survey 问卷调查
+-- rule: 业主表决的通过规则，ordinary 一般事项（参与人数和面积均达三分之二，同意过半），special 重大事项（同意达四分之三）
+-- audience: all 全体住户，targeted 指定楼栋
+-- anonymous为1时选票不记录住户，只记录参与情况
+-- status: draft 草稿，open 投票中，closed 已结束，cancelled 已取消
+-- eligible_count和eligible_area为开始投票时范围内的户数和房屋面积
+CREATE TABLE IF NOT EXISTS polls (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    title VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    rule VARCHAR(20) NOT NULL DEFAULT 'ordinary',
+    max_choices INT NOT NULL DEFAULT 1,
+    anonymous TINYINT NOT NULL DEFAULT 0,
+    audience VARCHAR(20) NOT NULL DEFAULT 'all',
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    start_at DATETIME NULL,
+    end_at DATETIME NOT NULL,
+    eligible_count INT NOT NULL DEFAULT 0,
+    eligible_area DECIMAL(12,2) NOT NULL DEFAULT 0,
+    created_by INT NOT NULL,
+    opened_at DATETIME NULL,
+    closed_at DATETIME NULL,
+    closed_by INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY status_idx (status, end_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建表决范围表，指定可参与投票的楼栋
+CREATE TABLE IF NOT EXISTS poll_blocks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    poll_id INT NOT NULL,
+    block_number VARCHAR(10) NOT NULL,
+    UNIQUE KEY poll_block_idx (poll_id, block_number),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建表决选项表
+CREATE TABLE IF NOT EXISTS poll_options (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    poll_id INT NOT NULL,
+    sort_order INT NOT NULL,
+    content VARCHAR(200) NOT NULL,
+    KEY poll_idx (poll_id, sort_order),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建参与记录表，每户只能投一票
+-- source: resident 住户端投票，staff 工作人员代录纸质选票
+CREATE TABLE IF NOT EXISTS poll_participants (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    poll_id INT NOT NULL,
+    resident_id INT NOT NULL,
+    source VARCHAR(20) NOT NULL DEFAULT 'resident',
+    cast_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY poll_resident_idx (poll_id, resident_id),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+    FOREIGN KEY (resident_id) REFERENCES residents(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建选票表，选票按seq顺序组成哈希链，hash = SHA-256(prev_hash|poll_id|seq|resident_id|area|choices)
+-- 匿名表决的resident_id为空；area为投票时的房屋面积；choices为所选选项ID，逗号分隔
+CREATE TABLE IF NOT EXISTS poll_ballots (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    poll_id INT NOT NULL,
+    seq INT NOT NULL,
+    resident_id INT NULL,
+    area DECIMAL(10,2) NOT NULL,
+    choices VARCHAR(255) NOT NULL,
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    UNIQUE KEY poll_seq_idx (poll_id, seq),
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+    FOREIGN KEY (resident_id) REFERENCES residents(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建表决结果表，结束投票时写入，不再修改
+-- tally为计票结果JSON，result_hash = SHA-256(最后一张选票的hash|tally)
+CREATE TABLE IF NOT EXISTS poll_results (
+    poll_id INT PRIMARY KEY,
+    tally TEXT NOT NULL,
+    passed TINYINT NULL,
+    ballot_count INT NOT NULL,
+    last_hash CHAR(64) NOT NULL,
+    result_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,5 @@
+-- 回滚装修申请相关表结构
+
+-- 装修押金收费项目和科目保留，已有缴费记录和凭证仍引用
+DROP TABLE IF EXISTS renovation_inspections;
+DROP TABLE IF EXISTS renovation_applications;
@@ -0,0 +1,66 @@
+-- 装修申请相关表结构
+
+-- 装修押金按申请收取，收款记录写入property_fees，押金计入其他应付款，没收时转入其他业务收入
+INSERT IGNORE INTO gl_accounts (code, name, type, parent_code) VALUES
+('2241.05', '装修押金', 'liability', '2241'),
+('6051.03', '押金没收收入', 'income', '6051');
+
+-- unit_price为默认押金金额
+INSERT IGNORE INTO fee_types (code, name, pricing_rule, unit_price, unit, accounting_category, account_code) VALUES
+('renovation_deposit', '装修押金', 'deposit', 2000.0000, '元/户', '代收押金', '2241.05');
+
+-- 创建装修申请表
+-- status: submitted 待审批，approved 已批准待缴押金，in_progress 施工中，completed 竣工验收通过，
+--         closed 已结案（押金已退还或没收），rejected 已驳回，cancelled 已取消
+-- deposit_status: none 未缴，held 已缴未退，refunded 已退还，forfeited 已没收
+-- source: resident 住户端申请，staff 物业代为登记
+CREATE TABLE IF NOT EXISTS renovation_applications (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    applicant_name VARCHAR(50) NOT NULL,
+    applicant_phone VARCHAR(20) NOT NULL,
+    scope TEXT NOT NULL,
+    contractor_name VARCHAR(100) NOT NULL,
+    contractor_contact VARCHAR(50) NOT NULL,
+    contractor_phone VARCHAR(20) NOT NULL,
+    contractor_license VARCHAR(50) NOT NULL DEFAULT '',
+    workers INT NOT NULL DEFAULT 0,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    deposit_amount DECIMAL(10,2) NOT NULL,
+    deposit_fee_id INT NULL,
+    deposit_status VARCHAR(20) NOT NULL DEFAULT 'none',
+    status VARCHAR(20) NOT NULL DEFAULT 'submitted',
+    source VARCHAR(20) NOT NULL DEFAULT 'resident',
+    created_by INT NOT NULL,
+    reviewed_by INT NULL,
+    reviewed_at DATETIME NULL,
+    review_note VARCHAR(255) NOT NULL DEFAULT '',
+    completed_at DATETIME NULL,
+    closed_at DATETIME NULL,
+    cancel_reason VARCHAR(255) NOT NULL DEFAULT '',
+    forfeited_at DATE NULL,
+    forfeit_reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY resident_idx (resident_id, status),
+    KEY status_idx (status),
+    FOREIGN KEY (resident_id) REFERENCES residents(id),
+    FOREIGN KEY (deposit_fee_id) REFERENCES property_fees(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建装修验收记录表
+-- checkpoint: hidden_works 隐蔽工程验收（水电管线），waterproof 防水闭水试验，final 竣工验收
+-- result: pass 通过，fail 不通过（整改后可再次验收）
+CREATE TABLE IF NOT EXISTS renovation_inspections (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    application_id INT NOT NULL,
+    checkpoint VARCHAR(20) NOT NULL,
+    result VARCHAR(10) NOT NULL,
+    inspector VARCHAR(50) NOT NULL,
+    notes VARCHAR(1000) NOT NULL DEFAULT '',
+    created_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    KEY application_idx (application_id, checkpoint),
+    FOREIGN KEY (application_id) REFERENCES renovation_applications(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,4 @@
+-- 回滚门禁凭证相关表结构
+
+DROP TABLE IF EXISTS access_credential_syncs;
+DROP TABLE IF EXISTS access_credentials;
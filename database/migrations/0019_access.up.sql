@@ -0,0 +1,44 @@
+-- 门禁凭证相关表结构
+
+-- 创建门禁凭证表
+-- type: card 门禁卡，face 人脸，app_key 手机开门密钥
+-- status: active 有效，suspended 已暂停（挂失，可恢复），revoked 已注销
+-- 同一类型的凭证号在未注销的凭证中唯一，由应用层校验
+CREATE TABLE IF NOT EXISTS access_credentials (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    credential_no VARCHAR(64) NOT NULL,
+    holder_name VARCHAR(50) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    valid_until DATE NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    issued_by INT NOT NULL,
+    suspended_at DATETIME NULL,
+    suspend_reason VARCHAR(255) NOT NULL DEFAULT '',
+    revoked_at DATETIME NULL,
+    revoke_reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY resident_idx (resident_id, status),
+    KEY credential_idx (type, credential_no),
+    FOREIGN KEY (resident_id) REFERENCES residents(id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建凭证下发状态表，每个凭证在每个控制器上保留最近一次下发结果
+-- action: grant 授权，suspend 暂停，revoke 注销
+-- status: success 下发成功，failed 下发失败（由定时任务重试）
+CREATE TABLE IF NOT EXISTS access_credential_syncs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    credential_id INT NOT NULL,
+    controller VARCHAR(50) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    error VARCHAR(500) NOT NULL DEFAULT '',
+    attempts INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY credential_controller (credential_id, controller),
+    KEY status_idx (status),
+    FOREIGN KEY (credential_id) REFERENCES access_credentials(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
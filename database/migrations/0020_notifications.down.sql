@@ -0,0 +1,5 @@
+-- 回滚通知中心相关表结构
+
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notification_templates;
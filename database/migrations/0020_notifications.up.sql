@@ -0,0 +1,69 @@
+-- 通知中心相关表结构
+
+-- 创建通知模板表，按事件、渠道和语言配置
+-- channel: inapp 站内信，sms 短信，email 邮件
+-- locale: zh 中文，en 英文，住户偏好语言没有对应模板时使用中文模板
+-- 模板支持{{key}}占位符，{{name}} {{room}}默认为住户姓名和房号，其余由发送方提供
+CREATE TABLE IF NOT EXISTS notification_templates (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    event VARCHAR(50) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    locale VARCHAR(5) NOT NULL DEFAULT 'zh',
+    title VARCHAR(100) NOT NULL DEFAULT '',
+    content VARCHAR(1000) NOT NULL,
+    enabled TINYINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY event_channel_locale (event, channel, locale)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建住户通知偏好表，没有记录的住户使用中文并接收全部渠道
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    resident_id INT PRIMARY KEY,
+    locale VARCHAR(5) NOT NULL DEFAULT 'zh',
+    inapp_enabled TINYINT NOT NULL DEFAULT 1,
+    sms_enabled TINYINT NOT NULL DEFAULT 1,
+    email_enabled TINYINT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建通知发送队列表，每条记录对应一个渠道的一次通知
+-- status: pending 待发送（含等待重试），sending 发送中，sent 已发送，failed 重试次数用尽仍失败
+CREATE TABLE IF NOT EXISTS notifications (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    locale VARCHAR(5) NOT NULL,
+    recipient VARCHAR(100) NOT NULL DEFAULT '',
+    title VARCHAR(100) NOT NULL DEFAULT '',
+    content VARCHAR(1000) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_error VARCHAR(500) NOT NULL DEFAULT '',
+    sent_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY status_next_idx (status, next_attempt_at),
+    KEY resident_idx (resident_id, created_at),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 插入快递通知模板，支持占位符：{{carrier}} {{tracking_no}} {{code}} {{shelf}} {{days}}
+INSERT IGNORE INTO notification_templates (event, channel, locale, title, content) VALUES
+('parcel_arrival', 'inapp', 'zh', '快递到件通知', '{{name}}您好，您的{{carrier}}快递（运单尾号{{tracking_no}}）已到物业前台，取件码{{code}}，请凭取件码领取。'),
+('parcel_arrival', 'sms', 'zh', '快递到件通知', '{{name}}您好，您的{{carrier}}快递（运单尾号{{tracking_no}}）已到物业前台，取件码{{code}}，请凭取件码领取。'),
+('parcel_arrival', 'inapp', 'en', 'Parcel arrived', 'Dear {{name}}, your {{carrier}} parcel (tracking no. ending {{tracking_no}}) has arrived at the front desk. Pickup code: {{code}}.'),
+('parcel_arrival', 'sms', 'en', 'Parcel arrived', 'Dear {{name}}, your {{carrier}} parcel (tracking no. ending {{tracking_no}}) has arrived at the front desk. Pickup code: {{code}}.'),
+('parcel_overdue', 'inapp', 'zh', '快递催取提醒', '{{name}}您好，您的{{carrier}}快递（运单尾号{{tracking_no}}）已在物业前台存放{{days}}天，取件码{{code}}，请尽快领取，长期未取的包裹将退回快递公司。'),
+('parcel_overdue', 'sms', 'zh', '快递催取提醒', '{{name}}您好，您的{{carrier}}快递（运单尾号{{tracking_no}}）已在物业前台存放{{days}}天，取件码{{code}}，请尽快领取，长期未取的包裹将退回快递公司。'),
+('parcel_overdue', 'inapp', 'en', 'Parcel pickup reminder', 'Dear {{name}}, your {{carrier}} parcel (tracking no. ending {{tracking_no}}) has been waiting at the front desk for {{days}} days. Pickup code: {{code}}. Uncollected parcels will be returned to the carrier.'),
+('parcel_overdue', 'sms', 'en', 'Parcel pickup reminder', 'Dear {{name}}, your {{carrier}} parcel (tracking no. ending {{tracking_no}}) has been waiting at the front desk for {{days}} days. Pickup code: {{code}}. Uncollected parcels will be returned to the carrier.');
+
+-- 插入通用通知模板，用于物业人员直接填写标题和正文发送通知
+INSERT IGNORE INTO notification_templates (event, channel, locale, title, content) VALUES
+('general', 'inapp', 'zh', '{{title}}', '{{content}}'),
+('general', 'sms', 'zh', '{{title}}', '{{content}}'),
+('general', 'email', 'zh', '{{title}}', '{{content}}');
@@ -0,0 +1,6 @@
+-- 回滚房屋租赁相关表结构
+
+DROP TABLE IF EXISTS lease_tenants;
+DROP TABLE IF EXISTS leases;
+
+DELETE FROM notification_templates WHERE event IN ('lease_expiring', 'lease_expiring_tenant');
@@ -0,0 +1,50 @@
+-- 房屋租赁相关表结构
+
+-- 创建租约表，residents表的一行代表房屋及业主，租约记录该房屋出租给租户的情况
+-- fee_payer: owner 业主缴纳物业费，tenant 租户缴纳物业费（租期内的账单提醒和催缴发给主租户）
+-- status: active 生效中（结束日期已过时显示为expired 已到期），terminated 已提前解约
+CREATE TABLE IF NOT EXISTS leases (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    resident_id INT NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    monthly_rent DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    deposit DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    fee_payer VARCHAR(10) NOT NULL DEFAULT 'owner',
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_by INT NOT NULL,
+    reminded_at DATETIME NULL,
+    terminated_at DATE NULL,
+    terminate_reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    KEY resident_idx (resident_id, status),
+    KEY end_date_idx (status, end_date),
+    FOREIGN KEY (resident_id) REFERENCES residents(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 创建租户表，每份租约至少一名租户，is_primary为主租户（联系人和缴费人）
+CREATE TABLE IF NOT EXISTS lease_tenants (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    lease_id INT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    phone VARCHAR(20) NOT NULL DEFAULT '',
+    email VARCHAR(100) NOT NULL DEFAULT '',
+    id_number VARCHAR(30) NOT NULL DEFAULT '',
+    is_primary TINYINT NOT NULL DEFAULT 0,
+    KEY lease_idx (lease_id),
+    FOREIGN KEY (lease_id) REFERENCES leases(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 插入租约到期提醒模板，支持占位符：{{tenant}} {{end_date}} {{days}}
+-- lease_expiring 发给业主，lease_expiring_tenant 发给主租户（只支持短信和邮件）
+INSERT IGNORE INTO notification_templates (event, channel, locale, title, content) VALUES
+('lease_expiring', 'inapp', 'zh', '租约到期提醒', '{{name}}您好，您的房屋{{room}}与租户{{tenant}}的租约将于{{end_date}}到期（剩余{{days}}天），如需续租请到物业服务中心更新租约。'),
+('lease_expiring', 'sms', 'zh', '租约到期提醒', '{{name}}您好，您的房屋{{room}}与租户{{tenant}}的租约将于{{end_date}}到期（剩余{{days}}天），如需续租请到物业服务中心更新租约。'),
+('lease_expiring', 'inapp', 'en', 'Lease expiring', 'Dear {{name}}, the lease of {{room}} with {{tenant}} expires on {{end_date}} ({{days}} days left). Please update the lease at the property office if it is renewed.'),
+('lease_expiring', 'sms', 'en', 'Lease expiring', 'Dear {{name}}, the lease of {{room}} with {{tenant}} expires on {{end_date}} ({{days}} days left). Please update the lease at the property office if it is renewed.'),
+('lease_expiring_tenant', 'sms', 'zh', '租约到期提醒', '{{tenant}}您好，您租住的{{room}}租约将于{{end_date}}到期（剩余{{days}}天），请与业主确认续租或按时办理退租。'),
+('lease_expiring_tenant', 'email', 'zh', '租约到期提醒', '{{tenant}}您好：\n您租住的{{room}}租约将于{{end_date}}到期（剩余{{days}}天），请与业主确认续租或按时办理退租手续。\n物业服务中心'),
+('lease_expiring_tenant', 'sms', 'en', 'Lease expiring', 'Dear {{tenant}}, your lease of {{room}} expires on {{end_date}} ({{days}} days left). Please confirm renewal with the owner or arrange move-out.'),
+('lease_expiring_tenant', 'email', 'en', 'Lease expiring', 'Dear {{tenant}},\nYour lease of {{room}} expires on {{end_date}} ({{days}} days left). Please confirm renewal with the owner or arrange move-out.\nProperty Management Office');
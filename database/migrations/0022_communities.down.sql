@@ -0,0 +1,28 @@
+-- 回滚多社区相关表结构
+
+-- 所有社区的数据合并为一个社区，月度统计只保留默认社区，可重新刷新
+DROP TABLE IF EXISTS user_communities;
+
+ALTER TABLE users
+    DROP FOREIGN KEY fk_users_community;
+
+ALTER TABLE users
+    DROP COLUMN community_id;
+
+DELETE FROM property_fee_monthly_stats WHERE community_id <> 1;
+
+ALTER TABLE property_fee_monthly_stats
+    DROP FOREIGN KEY fk_monthly_stats_community;
+
+ALTER TABLE property_fee_monthly_stats
+    ADD UNIQUE KEY year_month_type_idx (year, month, fee_type_id),
+    DROP INDEX community_year_month_type_idx,
+    DROP COLUMN community_id;
+
+ALTER TABLE residents
+    DROP FOREIGN KEY fk_residents_community;
+
+ALTER TABLE residents
+    DROP COLUMN community_id;
+
+DROP TABLE IF EXISTS communities;
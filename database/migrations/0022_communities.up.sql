@@ -0,0 +1,52 @@
+-- 多社区相关表结构
+
+-- 创建社区表，物业公司管理的每个小区为一个社区，已有数据归入默认社区（id为1）
+-- enabled为0表示已停用，停用后不能再登录或切换进入
+CREATE TABLE IF NOT EXISTS communities (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    code VARCHAR(30) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    address VARCHAR(255) NOT NULL DEFAULT '',
+    enabled TINYINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY code_idx (code)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT IGNORE INTO communities (id, code, name) VALUES
+(1, 'default', '默认社区');
+
+-- 住户归属社区，缴费记录和账单通过住户归属社区
+ALTER TABLE residents
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER id,
+    ADD KEY community_idx (community_id),
+    ADD CONSTRAINT fk_residents_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+-- 月度统计按社区分别计算
+ALTER TABLE property_fee_monthly_stats
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER id,
+    DROP INDEX year_month_type_idx,
+    ADD UNIQUE KEY community_year_month_type_idx (community_id, year, month, fee_type_id),
+    ADD CONSTRAINT fk_monthly_stats_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+-- 用户当前所在社区，登录时进入该社区，切换社区后更新
+ALTER TABLE users
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER resident_id,
+    ADD CONSTRAINT fk_users_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+-- 创建用户社区成员表，工作人员可以属于多个社区；管理员可以进入所有社区
+-- 住户账号只属于所住房屋所在的社区，不在此表登记
+CREATE TABLE IF NOT EXISTS user_communities (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    community_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY user_community_idx (user_id, community_id),
+    KEY community_idx (community_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (community_id) REFERENCES communities(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- 已有工作人员归入默认社区
+INSERT IGNORE INTO user_communities (user_id, community_id)
+SELECT id, 1 FROM users WHERE role <> 'resident';
@@ -0,0 +1,10 @@
+-- 回滚表决参与记录的自增ID和登记时间
+
+-- 已有参与记录按住户顺序重新编号，登记时间为回滚时间
+ALTER TABLE poll_participants
+    ADD UNIQUE KEY poll_resident_idx (poll_id, resident_id);
+
+ALTER TABLE poll_participants
+    DROP PRIMARY KEY,
+    ADD COLUMN id INT AUTO_INCREMENT PRIMARY KEY FIRST,
+    ADD COLUMN created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
@@ -0,0 +1,12 @@
+-- 表决参与记录去掉自增ID和登记时间
+
+-- 参与记录的自增ID和登记时间与选票的seq顺序一致，可以据此把匿名选票对应到住户，
+-- 改为以(poll_id, resident_id)为主键，参与记录只表示住户已投票，不保留投票先后顺序
+ALTER TABLE poll_participants
+    DROP PRIMARY KEY,
+    DROP COLUMN id,
+    DROP COLUMN created_at,
+    ADD PRIMARY KEY (poll_id, resident_id);
+
+ALTER TABLE poll_participants
+    DROP INDEX poll_resident_idx;
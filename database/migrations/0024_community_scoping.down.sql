@@ -0,0 +1,17 @@
+-- 回滚工单和车位的所属社区
+
+-- 不同社区的车位号重复时回滚会失败，需先修改车位号
+ALTER TABLE parking_spaces
+    DROP FOREIGN KEY fk_parking_spaces_community;
+
+ALTER TABLE parking_spaces
+    ADD UNIQUE KEY space_no (space_no),
+    DROP INDEX community_space_no_idx,
+    DROP COLUMN community_id;
+
+ALTER TABLE work_orders
+    DROP FOREIGN KEY fk_work_orders_community;
+
+ALTER TABLE work_orders
+    DROP INDEX community_status_idx,
+    DROP COLUMN community_id;
@@ -0,0 +1,22 @@
+-- 工单和车位按社区区分
+
+-- 工单归属社区，房屋工单按住户所在社区归属，公共区域工单归入默认社区
+ALTER TABLE work_orders
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER id,
+    ADD KEY community_status_idx (community_id, status),
+    ADD CONSTRAINT fk_work_orders_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+UPDATE work_orders w JOIN residents r ON w.resident_id = r.id
+SET w.community_id = r.community_id;
+
+-- 车位归属社区，已有车位按分配的住户所在社区归属，未分配的归入默认社区；车位号在社区内唯一
+ALTER TABLE parking_spaces
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER id,
+    ADD CONSTRAINT fk_parking_spaces_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+UPDATE parking_spaces s JOIN residents r ON s.resident_id = r.id
+SET s.community_id = r.community_id;
+
+ALTER TABLE parking_spaces
+    ADD UNIQUE KEY community_space_no_idx (community_id, space_no),
+    DROP INDEX space_no;
@@ -0,0 +1 @@
+-- 删除的押金月度统计不能恢复，回滚后押金类收费项目不再有月度统计，不影响其他数据
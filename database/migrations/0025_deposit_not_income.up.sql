@@ -0,0 +1,6 @@
+-- 押金不计入收入
+
+-- 押金需要在结案时退还，属于负债，删除押金类收费项目已生成的月度统计，之后也不再生成
+DELETE s FROM property_fee_monthly_stats s
+JOIN fee_types t ON s.fee_type_id = t.id
+WHERE t.pricing_rule = 'deposit';
@@ -0,0 +1,8 @@
+-- 回滚匿名表决的面积合计
+
+-- 匿名选票上已清除的面积不能恢复，回滚后匿名表决按选票计票时面积为0
+ALTER TABLE poll_options
+    DROP COLUMN area;
+
+ALTER TABLE polls
+    DROP COLUMN voted_area;
@@ -0,0 +1,52 @@
+-- 匿名选票不保存房屋面积
+
+-- 面积独特的房屋可以凭选票上的面积对应到住户，匿名表决改为在表决和选项上累计面积，选票只保存所选选项
+ALTER TABLE polls
+    ADD COLUMN voted_area DECIMAL(12,2) NOT NULL DEFAULT 0 AFTER eligible_area;
+
+ALTER TABLE poll_options
+    ADD COLUMN area DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+-- 按已有选票累计匿名表决的参与面积和各选项面积
+UPDATE polls p
+JOIN (SELECT poll_id, SUM(area) AS area FROM poll_ballots GROUP BY poll_id) b ON b.poll_id = p.id
+SET p.voted_area = b.area
+WHERE p.anonymous = 1;
+
+UPDATE poll_options o
+JOIN polls p ON o.poll_id = p.id
+JOIN (
+    SELECT o2.id, SUM(b.area) AS area
+    FROM poll_options o2
+    JOIN poll_ballots b ON b.poll_id = o2.poll_id AND FIND_IN_SET(o2.id, b.choices) > 0
+    GROUP BY o2.id
+) t ON t.id = o.id
+SET o.area = t.area
+WHERE p.anonymous = 1;
+
+-- 清除匿名选票的面积，并按选票哈希的计算方式重新串联哈希链：
+-- hash = SHA-256(prev_hash|poll_id|seq||0.00|choices)
+UPDATE /*+ SET_VAR(cte_max_recursion_depth = 4294967295) */ poll_ballots b
+JOIN (
+    WITH RECURSIVE chain (poll_id, seq, prev_hash, hash) AS (
+        SELECT b1.poll_id, b1.seq, CAST(REPEAT('0', 64) AS CHAR(64)),
+               CAST(SHA2(CONCAT(REPEAT('0', 64), '|', b1.poll_id, '|', b1.seq, '||0.00|', b1.choices), 256) AS CHAR(64))
+        FROM poll_ballots b1
+        JOIN polls p ON b1.poll_id = p.id
+        WHERE p.anonymous = 1 AND b1.seq = 1
+        UNION ALL
+        SELECT b2.poll_id, b2.seq, c.hash,
+               SHA2(CONCAT(c.hash, '|', b2.poll_id, '|', b2.seq, '||0.00|', b2.choices), 256)
+        FROM chain c
+        JOIN poll_ballots b2 ON b2.poll_id = c.poll_id AND b2.seq = c.seq + 1
+    )
+    SELECT poll_id, seq, prev_hash, hash FROM chain
+) c ON b.poll_id = c.poll_id AND b.seq = c.seq
+SET b.area = 0, b.prev_hash = c.prev_hash, b.hash = c.hash;
+
+-- 已结束的匿名表决按新的最后一张选票哈希重新计算结果哈希，计票结果不变
+UPDATE poll_results r
+JOIN polls p ON r.poll_id = p.id
+JOIN poll_ballots b ON b.poll_id = r.poll_id AND b.seq = r.ballot_count
+SET r.last_hash = b.hash, r.result_hash = SHA2(CONCAT(b.hash, '|', r.tally), 256)
+WHERE p.anonymous = 1;
@@ -0,0 +1,9 @@
+-- 回滚凭证号
+
+DROP TABLE IF EXISTS voucher_sequences;
+
+ALTER TABLE journal_entries
+    DROP INDEX voucher_idx,
+    DROP COLUMN period_month,
+    DROP COLUMN period_year,
+    DROP COLUMN voucher_no;
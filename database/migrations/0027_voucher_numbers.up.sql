@@ -0,0 +1,29 @@
+-- 凭证号按记账月份连续编号
+
+-- 凭证号在记账时分配并随凭证保存，导出凭证时不再按导出范围重新编号
+ALTER TABLE journal_entries
+    ADD COLUMN voucher_no INT AFTER id,
+    ADD COLUMN period_year SMALLINT AS (YEAR(entry_date)) STORED,
+    ADD COLUMN period_month TINYINT AS (MONTH(entry_date)) STORED,
+    ADD UNIQUE KEY voucher_idx (period_year, period_month, voucher_no);
+
+-- 已有凭证按记账日期和凭证ID在各月内编号，与此前导出的凭证号一致
+UPDATE journal_entries j
+JOIN (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY YEAR(entry_date), MONTH(entry_date) ORDER BY entry_date, id) AS no
+    FROM journal_entries
+) n ON j.id = n.id
+SET j.voucher_no = n.no;
+
+-- 各月已分配的最大凭证号
+CREATE TABLE IF NOT EXISTS voucher_sequences (
+    year SMALLINT NOT NULL,
+    month TINYINT NOT NULL,
+    last_no INT NOT NULL,
+    PRIMARY KEY (year, month)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+INSERT INTO voucher_sequences (year, month, last_no)
+SELECT period_year, period_month, MAX(voucher_no)
+FROM journal_entries
+GROUP BY period_year, period_month;
@@ -0,0 +1,39 @@
+-- 回滚投诉、公告、表决、公共设施和设备的所属社区
+
+-- 不同社区的设备编号或设施名称重复时回滚会失败，需先修改
+ALTER TABLE assets
+    DROP FOREIGN KEY fk_assets_community;
+
+ALTER TABLE assets
+    ADD UNIQUE KEY asset_no (asset_no),
+    DROP INDEX community_asset_no_idx,
+    DROP COLUMN community_id;
+
+ALTER TABLE facilities
+    DROP FOREIGN KEY fk_facilities_community;
+
+ALTER TABLE facilities
+    ADD UNIQUE KEY name (name),
+    DROP INDEX community_name_idx,
+    DROP COLUMN community_id;
+
+ALTER TABLE polls
+    DROP FOREIGN KEY fk_polls_community;
+
+ALTER TABLE polls
+    DROP INDEX community_status_idx,
+    DROP COLUMN community_id;
+
+ALTER TABLE announcements
+    DROP FOREIGN KEY fk_announcements_community;
+
+ALTER TABLE announcements
+    DROP INDEX community_status_idx,
+    DROP COLUMN community_id;
+
+ALTER TABLE complaints
+    DROP FOREIGN KEY fk_complaints_community;
+
+ALTER TABLE complaints
+    DROP INDEX community_status_idx,
+    DROP COLUMN community_id;
@@ -0,0 +1,38 @@
+-- 投诉、公告、表决、公共设施和设备按社区区分
+
+-- 投诉按关联住户所在社区归属，未关联住户的归入默认社区
+ALTER TABLE complaints
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER id,
+    ADD KEY community_status_idx (community_id, status),
+    ADD CONSTRAINT fk_complaints_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+UPDATE complaints c JOIN residents r ON c.resident_id = r.id
+SET c.community_id = r.community_id;
+
+-- 已有公告和表决归入默认社区
+ALTER TABLE announcements
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER id,
+    ADD KEY community_status_idx (community_id, status, publish_at),
+    ADD CONSTRAINT fk_announcements_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+ALTER TABLE polls
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER id,
+    ADD KEY community_status_idx (community_id, status, end_at),
+    ADD CONSTRAINT fk_polls_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+-- 已有公共设施和设备归入默认社区；设施名称和设备编号在社区内唯一
+ALTER TABLE facilities
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER id,
+    ADD CONSTRAINT fk_facilities_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+ALTER TABLE facilities
+    ADD UNIQUE KEY community_name_idx (community_id, name),
+    DROP INDEX name;
+
+ALTER TABLE assets
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER id,
+    ADD CONSTRAINT fk_assets_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+ALTER TABLE assets
+    ADD UNIQUE KEY community_asset_no_idx (community_id, asset_no),
+    DROP INDEX asset_no;
@@ -0,0 +1,36 @@
+-- 回滚账期、凭证和凭证号的所属社区
+
+-- 不同社区同月的凭证号重复时回滚会失败，需先重新编号
+ALTER TABLE journal_entries
+    ADD UNIQUE KEY voucher_idx (period_year, period_month, voucher_no),
+    DROP INDEX community_voucher_idx;
+
+DELETE FROM voucher_sequences;
+
+ALTER TABLE voucher_sequences
+    DROP PRIMARY KEY,
+    DROP COLUMN community_id,
+    ADD PRIMARY KEY (year, month);
+
+INSERT INTO voucher_sequences (year, month, last_no)
+SELECT period_year, period_month, MAX(voucher_no)
+FROM journal_entries
+WHERE voucher_no IS NOT NULL
+GROUP BY period_year, period_month;
+
+ALTER TABLE journal_entries
+    DROP FOREIGN KEY fk_journal_entries_community;
+
+ALTER TABLE journal_entries
+    DROP COLUMN community_id;
+
+-- 回滚后只保留默认社区的账期
+DELETE FROM accounting_periods WHERE community_id <> 1;
+
+ALTER TABLE accounting_periods
+    DROP FOREIGN KEY fk_periods_community;
+
+ALTER TABLE accounting_periods
+    ADD UNIQUE KEY year_month_idx (year, month),
+    DROP INDEX community_year_month_idx,
+    DROP COLUMN community_id;
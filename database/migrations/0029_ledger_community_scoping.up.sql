@@ -0,0 +1,47 @@
+-- 账期、凭证和凭证号按社区区分
+
+-- 各社区分别结账；已有账期此前对所有社区生效，复制到每个社区，已结账的月份在各社区保持结账
+ALTER TABLE accounting_periods
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER id,
+    ADD UNIQUE KEY community_year_month_idx (community_id, year, month),
+    ADD CONSTRAINT fk_periods_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+ALTER TABLE accounting_periods
+    DROP INDEX year_month_idx;
+
+INSERT INTO accounting_periods (community_id, year, month, status, closed_by, closed_at, reopened_by, reopened_at, reopen_reason)
+SELECT c.id, p.year, p.month, p.status, p.closed_by, p.closed_at, p.reopened_by, p.reopened_at, p.reopen_reason
+FROM accounting_periods p CROSS JOIN communities c
+WHERE p.community_id = 1 AND c.id <> 1;
+
+-- 凭证归入分录住户所属社区，住户已删除的凭证归入默认社区
+ALTER TABLE journal_entries
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 AFTER id,
+    ADD CONSTRAINT fk_journal_entries_community FOREIGN KEY (community_id) REFERENCES communities(id);
+
+UPDATE journal_entries e
+JOIN (
+    SELECT entry_id, MIN(resident_id) AS resident_id FROM journal_lines
+    WHERE resident_id IS NOT NULL
+    GROUP BY entry_id
+) l ON l.entry_id = e.id
+JOIN residents r ON l.resident_id = r.id
+SET e.community_id = r.community_id;
+
+-- 凭证号按社区和记账月份连续编号；已分配的凭证号保持不变，各社区新凭证从该社区已用的最大凭证号之后继续编号
+ALTER TABLE journal_entries
+    ADD UNIQUE KEY community_voucher_idx (community_id, period_year, period_month, voucher_no),
+    DROP INDEX voucher_idx;
+
+ALTER TABLE voucher_sequences
+    ADD COLUMN community_id INT NOT NULL DEFAULT 1 FIRST,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (community_id, year, month);
+
+DELETE FROM voucher_sequences;
+
+INSERT INTO voucher_sequences (community_id, year, month, last_no)
+SELECT community_id, period_year, period_month, MAX(voucher_no)
+FROM journal_entries
+WHERE voucher_no IS NOT NULL
+GROUP BY community_id, period_year, period_month;
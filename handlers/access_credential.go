@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/jobs"
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// AccessCredentialHandler 处理门禁凭证相关请求
+type AccessCredentialHandler struct {
+	Credentials repository.AccessCredentialRepository
+	Sync        *jobs.AccessSync
+}
+
+// NewAccessCredentialHandler 创建新的AccessCredentialHandler
+func NewAccessCredentialHandler(credentials repository.AccessCredentialRepository, sync *jobs.AccessSync) *AccessCredentialHandler {
+	return &AccessCredentialHandler{
+		Credentials: credentials,
+		Sync:        sync,
+	}
+}
+
+// credentialStatus 返回门禁凭证写操作失败时的HTTP状态码
+func credentialStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrCredentialExists), errors.Is(err, models.ErrCredentialTransition):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidCredential):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetCredentials 获取当前社区的门禁凭证列表，sync_failed=true时只返回存在下发失败的凭证
+func (h *AccessCredentialHandler) GetCredentials(c *gin.Context) {
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"type", "status", "keyword"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	if c.Query("sync_failed") == "true" {
+		filters["sync_failed"] = true
+	}
+	h.listCredentials(c, filters)
+}
+
+// listCredentials 按条件查询门禁凭证并返回分页结果
+func (h *AccessCredentialHandler) listCredentials(c *gin.Context, filters map[string]interface{}) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	credentials, total, err := h.Credentials.GetAccessCredentials(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取门禁凭证列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  credentials,
+			"total": total,
+		},
+	})
+}
+
+// GetCredential 获取门禁凭证详情及各控制器的下发结果
+func (h *AccessCredentialHandler) GetCredential(c *gin.Context) {
+	credential, ok := h.loadCredential(c, 0)
+	if !ok {
+		return
+	}
+	credential.Mask()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    credential,
+	})
+}
+
+// IssueCredential 为住户发放门禁凭证并下发到门禁控制器
+// 手机开门密钥只在发放时完整返回一次，之后查询均隐藏
+func (h *AccessCredentialHandler) IssueCredential(c *gin.Context) {
+	var req models.AccessCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	id, err := models.IssueAccessCredential(req, currentUserID(c))
+	if err != nil {
+		c.JSON(credentialStatus(err), gin.H{
+			"success": false,
+			"message": "发放门禁凭证失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.pushAndRespond(c, http.StatusCreated, id, "门禁凭证已发放", false)
+}
+
+// SuspendCredential 暂停门禁凭证，用于挂失
+func (h *AccessCredentialHandler) SuspendCredential(c *gin.Context) {
+	h.transition(c, models.CredentialActionSuspend, "门禁凭证已暂停")
+}
+
+// ResumeCredential 恢复已暂停的门禁凭证
+func (h *AccessCredentialHandler) ResumeCredential(c *gin.Context) {
+	h.transition(c, models.CredentialActionResume, "门禁凭证已恢复")
+}
+
+// RevokeCredential 注销门禁凭证
+func (h *AccessCredentialHandler) RevokeCredential(c *gin.Context) {
+	h.transition(c, models.CredentialActionRevoke, "门禁凭证已注销")
+}
+
+// SyncCredential 将凭证当前状态重新下发到所有控制器
+func (h *AccessCredentialHandler) SyncCredential(c *gin.Context) {
+	credential, ok := h.loadCredential(c, 0)
+	if !ok {
+		return
+	}
+	h.pushAndRespond(c, http.StatusOK, credential.ID, "门禁凭证已重新下发", true)
+}
+
+// RetrySync 立即重试下发失败的凭证
+func (h *AccessCredentialHandler) RetrySync(c *gin.Context) {
+	result, err := h.Sync.Run(time.Now())
+	if err != nil {
+		fmt.Println("门禁凭证下发重试失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "门禁凭证下发重试失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("重试下发成功%d个，失败%d个", result.Sent, result.Failed),
+		"data":    result,
+	})
+}
+
+// GetMyCredentials 住户端：获取本户的门禁凭证
+func (h *AccessCredentialHandler) GetMyCredentials(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	filters := map[string]interface{}{"resident_id": residentID}
+	for _, key := range []string{"type", "status"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+	h.listCredentials(c, filters)
+}
+
+// SuspendMyCredential 住户端：挂失本户的门禁凭证，恢复和补办需到物业办理
+func (h *AccessCredentialHandler) SuspendMyCredential(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+	credential, ok := h.loadCredential(c, residentID)
+	if !ok {
+		return
+	}
+
+	var req models.CredentialActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "住户挂失"
+	}
+
+	if err := models.TransitionAccessCredential(credential.ID, models.CredentialActionSuspend, req.Reason); err != nil {
+		c.JSON(credentialStatus(err), gin.H{
+			"success": false,
+			"message": "挂失失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.pushAndRespond(c, http.StatusOK, credential.ID, "挂失成功", true)
+}
+
+// transition 执行凭证状态变更并下发到门禁控制器
+func (h *AccessCredentialHandler) transition(c *gin.Context, action, message string) {
+	credential, ok := h.loadCredential(c, 0)
+	if !ok {
+		return
+	}
+
+	var req models.CredentialActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.TransitionAccessCredential(credential.ID, action, req.Reason); err != nil {
+		c.JSON(credentialStatus(err), gin.H{
+			"success": false,
+			"message": "操作失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.pushAndRespond(c, http.StatusOK, credential.ID, message, true)
+}
+
+// pushAndRespond 将凭证下发到门禁控制器并返回最新的凭证详情
+// 下发失败不回滚凭证变更，失败记录由定时任务重试，并在提示中说明
+func (h *AccessCredentialHandler) pushAndRespond(c *gin.Context, status, id int, message string, mask bool) {
+	credential, err := h.Credentials.GetAccessCredentialByID(id)
+	if err != nil || credential == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取门禁凭证详情失败",
+		})
+		return
+	}
+
+	if _, err := h.Sync.Push(credential); err != nil {
+		fmt.Println("下发门禁凭证失败:", err)
+		message += "，但下发到门禁控制器失败，系统将自动重试: " + err.Error()
+	}
+
+	// 重新加载以返回最新的下发结果
+	if credential, err = h.Credentials.GetAccessCredentialByID(id); err != nil || credential == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取门禁凭证详情失败",
+		})
+		return
+	}
+	if mask {
+		credential.Mask()
+	}
+
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": message,
+		"data":    credential,
+	})
+}
+
+// loadCredential 根据路径参数id加载当前社区的门禁凭证，residentID大于0时只允许加载该住户的凭证，
+// 加载失败时直接返回错误响应并返回false
+func (h *AccessCredentialHandler) loadCredential(c *gin.Context, residentID int) (*models.AccessCredential, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的凭证ID",
+		})
+		return nil, false
+	}
+
+	credential, err := h.Credentials.GetAccessCredentialByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取门禁凭证详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if credential == nil || credential.CommunityID != currentCommunityID(c) || (residentID > 0 && credential.ResidentID != residentID) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "门禁凭证不存在",
+		})
+		return nil, false
+	}
+
+	return credential, true
+}
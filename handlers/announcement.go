@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// AnnouncementHandler 处理社区公告相关请求，包括住户端的公告查看
+type AnnouncementHandler struct {
+	Announcements repository.AnnouncementRepository
+}
+
+// NewAnnouncementHandler 创建新的AnnouncementHandler
+func NewAnnouncementHandler(announcements repository.AnnouncementRepository) *AnnouncementHandler {
+	return &AnnouncementHandler{
+		Announcements: announcements,
+	}
+}
+
+// announcementStatus 返回公告写操作失败时的HTTP状态码
+func announcementStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrAnnouncementNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrAnnouncementState):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidAnnouncement):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetAnnouncements 获取当前社区的公告列表
+func (h *AnnouncementHandler) GetAnnouncements(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	if keyword := c.Query("keyword"); keyword != "" {
+		filters["keyword"] = keyword
+	}
+	if pinned := c.Query("pinned"); pinned != "" {
+		filters["pinned"] = pinned == "true"
+	}
+
+	list, total, err := h.Announcements.GetAnnouncements(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取公告列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  list,
+			"total": total,
+		},
+	})
+}
+
+// GetAnnouncement 获取公告详情，含发布范围和阅读统计
+func (h *AnnouncementHandler) GetAnnouncement(c *gin.Context) {
+	announcement, ok := h.loadAnnouncement(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    announcement,
+	})
+}
+
+// CreateAnnouncement 在当前社区创建公告草稿
+func (h *AnnouncementHandler) CreateAnnouncement(c *gin.Context) {
+	var req models.AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := models.CreateAnnouncement(req, currentUserID(c), currentCommunityID(c))
+	if err != nil {
+		c.JSON(announcementStatus(err), gin.H{
+			"success": false,
+			"message": "创建公告失败: " + err.Error(),
+		})
+		return
+	}
+
+	announcement, err := h.Announcements.GetAnnouncementByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取新创建的公告失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "公告创建成功",
+		"data":    announcement,
+	})
+}
+
+// UpdateAnnouncement 修改公告
+func (h *AnnouncementHandler) UpdateAnnouncement(c *gin.Context) {
+	announcement, ok := h.loadAnnouncement(c)
+	if !ok {
+		return
+	}
+	id := announcement.ID
+
+	var req models.AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.UpdateAnnouncement(id, req); err != nil {
+		c.JSON(announcementStatus(err), gin.H{
+			"success": false,
+			"message": "修改公告失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "公告修改成功",
+	})
+}
+
+// PublishAnnouncement 发布公告
+func (h *AnnouncementHandler) PublishAnnouncement(c *gin.Context) {
+	announcement, ok := h.loadAnnouncement(c)
+	if !ok {
+		return
+	}
+	id := announcement.ID
+
+	if err := models.PublishAnnouncement(id, currentUserID(c)); err != nil {
+		c.JSON(announcementStatus(err), gin.H{
+			"success": false,
+			"message": "发布公告失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.audit(c, "publish", id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "公告发布成功",
+	})
+}
+
+// WithdrawAnnouncement 撤回公告
+func (h *AnnouncementHandler) WithdrawAnnouncement(c *gin.Context) {
+	announcement, ok := h.loadAnnouncement(c)
+	if !ok {
+		return
+	}
+	id := announcement.ID
+
+	if err := models.WithdrawAnnouncement(id); err != nil {
+		c.JSON(announcementStatus(err), gin.H{
+			"success": false,
+			"message": "撤回公告失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.audit(c, "withdraw", id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "公告已撤回",
+	})
+}
+
+// PinAnnouncement 置顶或取消置顶公告
+func (h *AnnouncementHandler) PinAnnouncement(c *gin.Context) {
+	announcement, ok := h.loadAnnouncement(c)
+	if !ok {
+		return
+	}
+	id := announcement.ID
+
+	var req models.AnnouncementPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.SetAnnouncementPinned(id, req.Pinned); err != nil {
+		c.JSON(announcementStatus(err), gin.H{
+			"success": false,
+			"message": "设置置顶失败: " + err.Error(),
+		})
+		return
+	}
+
+	message := "公告已取消置顶"
+	if req.Pinned {
+		message = "公告已置顶"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+	})
+}
+
+// DeleteAnnouncement 删除公告草稿
+func (h *AnnouncementHandler) DeleteAnnouncement(c *gin.Context) {
+	announcement, ok := h.loadAnnouncement(c)
+	if !ok {
+		return
+	}
+	id := announcement.ID
+
+	if err := models.DeleteAnnouncement(id); err != nil {
+		c.JSON(announcementStatus(err), gin.H{
+			"success": false,
+			"message": "删除公告失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "公告删除成功",
+	})
+}
+
+// GetReceipts 获取公告的阅读回执，read=true只看已读，read=false只看未读
+func (h *AnnouncementHandler) GetReceipts(c *gin.Context) {
+	announcement, ok := h.loadAnnouncement(c)
+	if !ok {
+		return
+	}
+	id := announcement.ID
+
+	var read *bool
+	if v := c.Query("read"); v != "" {
+		b := v == "true"
+		read = &b
+	}
+
+	receipts, err := models.GetAnnouncementReceipts(id, read)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取阅读回执失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    receipts,
+	})
+}
+
+// GetMyAnnouncements 住户端：获取当前可见的公告，unread=true只看未读
+func (h *AnnouncementHandler) GetMyAnnouncements(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	list, total, err := models.GetResidentAnnouncements(currentUserID(c), residentID, page, pageSize, c.Query("unread") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取公告列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  list,
+			"total": total,
+		},
+	})
+}
+
+// GetMyAnnouncement 住户端：查看公告并记录阅读回执
+func (h *AnnouncementHandler) GetMyAnnouncement(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	id, ok := announcementID(c)
+	if !ok {
+		return
+	}
+
+	userID := currentUserID(c)
+	announcement, err := models.GetResidentAnnouncement(userID, residentID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取公告失败: " + err.Error(),
+		})
+		return
+	}
+	if announcement == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "公告不存在",
+		})
+		return
+	}
+
+	if announcement.ReadAt == nil {
+		if err := models.MarkAnnouncementRead(id, userID, residentID); err != nil {
+			fmt.Println("记录公告阅读回执失败:", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    announcement,
+	})
+}
+
+// audit 记录公告发布和撤回的审计日志
+func (h *AnnouncementHandler) audit(c *gin.Context, action string, id int) {
+	if err := models.CreateAuditLog(models.AuditLog{
+		UserID:   int(currentUserID(c)),
+		Username: currentUsername(c),
+		Action:   action,
+		Entity:   "announcement",
+		EntityID: strconv.Itoa(id),
+	}); err != nil {
+		fmt.Println("记录审计日志失败:", err)
+	}
+}
+
+// loadAnnouncement 根据路径参数id加载当前社区的公告，加载失败时直接返回错误响应并返回false
+func (h *AnnouncementHandler) loadAnnouncement(c *gin.Context) (*models.Announcement, bool) {
+	id, ok := announcementID(c)
+	if !ok {
+		return nil, false
+	}
+
+	announcement, err := h.Announcements.GetAnnouncementByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取公告失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if announcement == nil || announcement.CommunityID != currentCommunityID(c) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "公告不存在",
+		})
+		return nil, false
+	}
+
+	return announcement, true
+}
+
+// announcementID 解析路径参数id，失败时直接返回错误响应并返回false
+func announcementID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的公告ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// residentAccount 获取当前住户账号关联的住户ID，失败时直接返回错误响应并返回false
+func residentAccount(c *gin.Context) (int, bool) {
+	residentID := currentResidentID(c)
+	if residentID == 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "当前账号未关联住户",
+		})
+		return 0, false
+	}
+	return residentID, true
+}
@@ -0,0 +1,616 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/jobs"
+	"community-backward/models"
+	"community-backward/repository"
+	"community-backward/utils"
+)
+
+// AssetHandler 处理设备台账和维保相关请求
+type AssetHandler struct {
+	Assets      repository.AssetRepository
+	Maintenance *jobs.Maintenance
+	// UploadDir 检查记录附件的保存目录
+	UploadDir string
+}
+
+// NewAssetHandler 创建新的AssetHandler
+func NewAssetHandler(assets repository.AssetRepository, maintenance *jobs.Maintenance, uploadDir string) *AssetHandler {
+	return &AssetHandler{
+		Assets:      assets,
+		Maintenance: maintenance,
+		UploadDir:   uploadDir,
+	}
+}
+
+// assetStatus 返回设备维保写操作失败时的HTTP状态码
+func assetStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrAssetExists), errors.Is(err, models.ErrTaskNotPending):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidAsset), errors.Is(err, models.ErrInvalidMaintenanceSchedule),
+		errors.Is(err, models.ErrInvalidInspection):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetAssets 获取当前社区的设备台账
+func (h *AssetHandler) GetAssets(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"category", "block_number", "status", "keyword"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+
+	assets, total, err := h.Assets.GetAssets(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取设备台账失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  assets,
+			"total": total,
+		},
+	})
+}
+
+// GetAsset 获取设备详情，含维保计划
+func (h *AssetHandler) GetAsset(c *gin.Context) {
+	asset, ok := h.loadAsset(c, true)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    asset,
+	})
+}
+
+// CreateAsset 在当前社区新增设备
+func (h *AssetHandler) CreateAsset(c *gin.Context) {
+	var req models.AssetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := models.CreateAsset(req, currentCommunityID(c))
+	if err != nil {
+		c.JSON(assetStatus(err), gin.H{
+			"success": false,
+			"message": "新增设备失败: " + err.Error(),
+		})
+		return
+	}
+
+	asset, err := h.Assets.GetAssetByID(id, true)
+	if err != nil || asset == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取设备详情失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "设备新增成功",
+		"data":    asset,
+	})
+}
+
+// UpdateAsset 修改设备
+func (h *AssetHandler) UpdateAsset(c *gin.Context) {
+	asset, ok := h.loadAsset(c, false)
+	if !ok {
+		return
+	}
+
+	var req models.AssetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.UpdateAsset(asset.ID, req); err != nil {
+		c.JSON(assetStatus(err), gin.H{
+			"success": false,
+			"message": "修改设备失败: " + err.Error(),
+		})
+		return
+	}
+
+	asset, err := h.Assets.GetAssetByID(asset.ID, true)
+	if err != nil || asset == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取设备详情失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "设备修改成功",
+		"data":    asset,
+	})
+}
+
+// GetSchedules 获取设备的维保计划
+func (h *AssetHandler) GetSchedules(c *gin.Context) {
+	asset, ok := h.loadAsset(c, true)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    asset.Schedules,
+	})
+}
+
+// CreateSchedule 为设备新增维保计划
+func (h *AssetHandler) CreateSchedule(c *gin.Context) {
+	asset, ok := h.loadAsset(c, false)
+	if !ok {
+		return
+	}
+
+	var req models.MaintenanceScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := models.CreateMaintenanceSchedule(asset.ID, req)
+	if err != nil {
+		c.JSON(assetStatus(err), gin.H{
+			"success": false,
+			"message": "新增维保计划失败: " + err.Error(),
+		})
+		return
+	}
+
+	schedule, err := h.Assets.GetMaintenanceScheduleByID(id)
+	if err != nil || schedule == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取维保计划失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "维保计划新增成功",
+		"data":    schedule,
+	})
+}
+
+// UpdateSchedule 修改维保计划
+func (h *AssetHandler) UpdateSchedule(c *gin.Context) {
+	schedule, ok := h.loadSchedule(c)
+	if !ok {
+		return
+	}
+
+	var req models.MaintenanceScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.UpdateMaintenanceSchedule(schedule.ID, req); err != nil {
+		c.JSON(assetStatus(err), gin.H{
+			"success": false,
+			"message": "修改维保计划失败: " + err.Error(),
+		})
+		return
+	}
+
+	schedule, err := h.Assets.GetMaintenanceScheduleByID(schedule.ID)
+	if err != nil || schedule == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取维保计划失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "维保计划修改成功",
+		"data":    schedule,
+	})
+}
+
+// GetTasks 获取当前社区的维保任务，overdue=true时只返回超期未完成的任务
+func (h *AssetHandler) GetTasks(c *gin.Context) {
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"status", "kind", "block_number"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+	if v := c.Query("asset_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["asset_id"] = id
+		}
+	}
+	if c.Query("overdue") == "true" {
+		filters["overdue"] = true
+	}
+	h.listMaintenanceTasks(c, filters)
+}
+
+// GetOverdueTasks 获取当前社区超期未完成的维保任务，作为逾期检验预警清单
+func (h *AssetHandler) GetOverdueTasks(c *gin.Context) {
+	filters := map[string]interface{}{"community_id": currentCommunityID(c), "overdue": true}
+	if kind := c.Query("kind"); kind != "" {
+		filters["kind"] = kind
+	}
+	h.listMaintenanceTasks(c, filters)
+}
+
+// listMaintenanceTasks 按条件查询维保任务并返回分页结果
+func (h *AssetHandler) listMaintenanceTasks(c *gin.Context, filters map[string]interface{}) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	tasks, total, err := h.Assets.GetMaintenanceTasks(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取维保任务失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  tasks,
+			"total": total,
+		},
+	})
+}
+
+// GenerateTasks 立即按维保计划生成到期任务
+func (h *AssetHandler) GenerateTasks(c *gin.Context) {
+	result, err := h.Maintenance.Run(time.Now())
+	if err != nil {
+		fmt.Println("生成维保任务失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "生成维保任务失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已生成%d项维保任务", result.Created),
+		"data":    result,
+	})
+}
+
+// GetInspections 获取设备的检查记录
+func (h *AssetHandler) GetInspections(c *gin.Context) {
+	asset, ok := h.loadAsset(c, false)
+	if !ok {
+		return
+	}
+
+	inspections, err := models.GetAssetInspections(asset.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取检查记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    inspections,
+	})
+}
+
+// CreateInspection 登记设备检查记录，指定任务时完成该任务并顺延维保计划
+func (h *AssetHandler) CreateInspection(c *gin.Context) {
+	asset, ok := h.loadAsset(c, false)
+	if !ok {
+		return
+	}
+
+	var req models.AssetInspectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := models.CreateAssetInspection(asset.ID, req, currentUserID(c))
+	if err != nil {
+		c.JSON(assetStatus(err), gin.H{
+			"success": false,
+			"message": "登记检查记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	inspection, err := h.Assets.GetAssetInspectionByID(id)
+	if err != nil || inspection == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取检查记录失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "检查记录登记成功",
+		"data":    inspection,
+	})
+}
+
+// GetInspection 获取检查记录详情，含附件
+func (h *AssetHandler) GetInspection(c *gin.Context) {
+	inspection, ok := h.loadInspection(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    inspection,
+	})
+}
+
+// UploadInspectionFile 上传检查记录附件，表单字段为file，支持图片和PDF
+func (h *AssetHandler) UploadInspectionFile(c *gin.Context) {
+	inspection, ok := h.loadInspection(c)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请上传附件",
+		})
+		return
+	}
+
+	path, contentType, err := utils.SaveAttachment(file, h.UploadDir, filepath.Join("inspections", strconv.Itoa(inspection.ID)))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidAttachment) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "保存附件失败: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := models.CreateInspectionFile(models.InspectionFile{
+		InspectionID: inspection.ID,
+		FilePath:     path,
+		OriginalName: file.Filename,
+		ContentType:  contentType,
+		Size:         file.Size,
+		UploadedBy:   int(currentUserID(c)),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "保存附件记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	saved, err := models.GetInspectionFile(inspection.ID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取附件失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "附件上传成功",
+		"data":    saved,
+	})
+}
+
+// GetInspectionFile 下载检查记录附件
+func (h *AssetHandler) GetInspectionFile(c *gin.Context) {
+	inspection, ok := h.loadInspection(c)
+	if !ok {
+		return
+	}
+
+	fileID, err := strconv.Atoi(c.Param("fileId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的附件ID",
+		})
+		return
+	}
+
+	file, err := models.GetInspectionFile(inspection.ID, fileID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取附件失败: " + err.Error(),
+		})
+		return
+	}
+	if file == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "附件不存在",
+		})
+		return
+	}
+
+	c.Header("Content-Type", file.ContentType)
+	c.File(filepath.Join(h.UploadDir, file.FilePath))
+}
+
+// loadAsset 根据路径参数id加载当前社区的设备，加载失败时直接返回错误响应并返回false
+func (h *AssetHandler) loadAsset(c *gin.Context, withSchedules bool) (*models.Asset, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的设备ID",
+		})
+		return nil, false
+	}
+
+	asset, err := h.Assets.GetAssetByID(id, withSchedules)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取设备详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if asset == nil || asset.CommunityID != currentCommunityID(c) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "设备不存在",
+		})
+		return nil, false
+	}
+
+	return asset, true
+}
+
+// loadSchedule 根据路径参数id加载当前社区设备的维保计划，加载失败时直接返回错误响应并返回false
+func (h *AssetHandler) loadSchedule(c *gin.Context) (*models.MaintenanceSchedule, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的维保计划ID",
+		})
+		return nil, false
+	}
+
+	schedule, err := h.Assets.GetMaintenanceScheduleByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取维保计划失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	var asset *models.Asset
+	if schedule != nil {
+		if asset, err = h.Assets.GetAssetByID(schedule.AssetID, false); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取设备详情失败: " + err.Error(),
+			})
+			return nil, false
+		}
+	}
+	if asset == nil || asset.CommunityID != currentCommunityID(c) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "维保计划不存在",
+		})
+		return nil, false
+	}
+
+	return schedule, true
+}
+
+// loadInspection 根据路径参数id加载当前社区设备的检查记录，加载失败时直接返回错误响应并返回false
+func (h *AssetHandler) loadInspection(c *gin.Context) (*models.AssetInspection, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的检查记录ID",
+		})
+		return nil, false
+	}
+
+	inspection, err := h.Assets.GetAssetInspectionByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取检查记录失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if inspection == nil || inspection.CommunityID != currentCommunityID(c) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "检查记录不存在",
+		})
+		return nil, false
+	}
+
+	return inspection, true
+}
@@ -1,21 +1,25 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"github.com/gin-gonic/gin"
 	"community-backward/models"
+	"community-backward/repository"
 	"community-backward/utils"
 )
 
 // AuthHandler 处理认证相关请求
 type AuthHandler struct {
 	JWTUtil *utils.JWTUtil
+	Users   repository.UserRepository
 }
 
 // NewAuthHandler 创建新的AuthHandler
-func NewAuthHandler(jwtUtil *utils.JWTUtil) *AuthHandler {
+func NewAuthHandler(jwtUtil *utils.JWTUtil, users repository.UserRepository) *AuthHandler {
 	return &AuthHandler{
 		JWTUtil: jwtUtil,
+		Users:   users,
 	}
 }
 
@@ -31,7 +35,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// 验证用户
-	user, err := models.FindUser(req.Username, req.Password)
+	user, err := h.Users.FindUser(req.Username, req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -48,8 +52,25 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// 确定登录后进入的社区
+	community, err := h.Users.ResolveUserCommunity(user)
+	if err != nil {
+		if errors.Is(err, models.ErrNotCommunityMember) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "账号未分配可进入的社区",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "服务器错误",
+		})
+		return
+	}
+
 	// 生成JWT
-	tokenString, err := h.JWTUtil.GenerateToken(user.ID, user.Username, req.Remember)
+	tokenString, err := h.JWTUtil.GenerateToken(user.ID, user.Username, user.Role, community.ID, req.Remember)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -60,8 +81,100 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	// 返回token
 	c.JSON(http.StatusOK, models.LoginResponse{
-		Success: true,
-		Token:   tokenString,
-		Message: "登录成功",
+		Success:   true,
+		Token:     tokenString,
+		Message:   "登录成功",
+		Community: community,
+	})
+}
+
+// GetCommunities 获取当前用户可以进入的社区
+func (h *AuthHandler) GetCommunities(c *gin.Context) {
+	user, ok := loadCurrentUser(c, h.Users)
+	if !ok {
+		return
+	}
+
+	communities, err := h.Users.GetUserCommunities(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取社区列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"current": currentCommunityID(c),
+			"list":    communities,
+		},
+	})
+}
+
+// SwitchCommunity 切换到用户所属的另一个社区，返回该社区的新token
+func (h *AuthHandler) SwitchCommunity(c *gin.Context) {
+	var req models.SwitchCommunityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数",
+		})
+		return
+	}
+
+	user, ok := loadCurrentUser(c, h.Users)
+	if !ok {
+		return
+	}
+
+	community, err := h.Users.SwitchUserCommunity(user, req.CommunityID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrNotCommunityMember) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "切换社区失败: " + err.Error(),
+		})
+		return
+	}
+
+	tokenString, err := h.JWTUtil.GenerateToken(user.ID, user.Username, user.Role, community.ID, req.Remember)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "生成token失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LoginResponse{
+		Success:   true,
+		Token:     tokenString,
+		Message:   "已切换到" + community.Name,
+		Community: community,
 	})
 }
+
+// loadCurrentUser 加载当前登录用户，加载失败时直接返回错误响应并返回false
+func loadCurrentUser(c *gin.Context, users repository.UserRepository) (*models.User, bool) {
+	user, err := users.GetUserByID(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取用户信息失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "用户不存在",
+		})
+		return nil, false
+	}
+	return user, true
+}
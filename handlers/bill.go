@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// BillHandler 处理账单相关请求
+type BillHandler struct {
+	Bills     repository.BillRepository
+	Residents repository.ResidentRepository
+	FeeTypes  repository.FeeTypeRepository
+	Dashboard repository.DashboardRepository
+}
+
+// NewBillHandler 创建新的BillHandler
+func NewBillHandler(bills repository.BillRepository, residents repository.ResidentRepository, feeTypes repository.FeeTypeRepository, dashboard repository.DashboardRepository) *BillHandler {
+	return &BillHandler{
+		Bills:     bills,
+		Residents: residents,
+		FeeTypes:  feeTypes,
+		Dashboard: dashboard,
+	}
+}
+
+// GetBills 获取账单列表
+func (h *BillHandler) GetBills(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := make(map[string]interface{})
+	for _, key := range []string{"resident_id", "fee_type_id", "year", "month"} {
+		if v := c.Query(key); v != "" {
+			if n, err := strconv.Atoi(v); err == nil {
+				filters[key] = n
+			}
+		}
+	}
+	filters["community_id"] = currentCommunityID(c)
+
+	bills, total, err := h.Bills.GetBills(page, pageSize, filters)
+	if err != nil {
+		fmt.Println("获取账单列表失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取账单列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  bills,
+			"total": total,
+		},
+	})
+}
+
+// CreateBill 创建账单
+func (h *BillHandler) CreateBill(c *gin.Context) {
+	var req models.BillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := h.Residents.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	id, err := h.Bills.CreateBill(req)
+	if err != nil {
+		fmt.Println("创建账单失败:", err)
+		c.JSON(mutationStatus(err), gin.H{
+			"success": false,
+			"message": "创建账单失败: " + err.Error(),
+		})
+		return
+	}
+
+	bill, err := h.Bills.GetBillByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取新创建的账单失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "账单创建成功",
+		"data":    bill,
+	})
+}
+
+// GenerateBills 按收费项目为当前社区的所有住户批量生成账单
+func (h *BillHandler) GenerateBills(c *gin.Context) {
+	var req models.GenerateBillsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	dueDate, err := time.ParseInLocation("2006-01-02", req.DueDate, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的到期日期: " + req.DueDate,
+		})
+		return
+	}
+
+	feeType, err := h.FeeTypes.GetFeeTypeByID(req.FeeTypeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取收费项目失败: " + err.Error(),
+		})
+		return
+	}
+
+	if feeType == nil || !feeType.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "收费项目不存在或已停用",
+		})
+		return
+	}
+
+	// 按用量计费的项目根据当月抄表记录出账，按合同计费的项目根据车位租赁合同出账
+	var created int
+	switch feeType.PricingRule {
+	case models.PricingPerUsage:
+		created, err = h.Bills.GenerateUsageBills(feeType, req.Year, req.Month, dueDate, currentCommunityID(c))
+	case models.PricingPerContract:
+		created, err = h.Bills.GenerateParkingBills(feeType, req.Year, req.Month, dueDate, currentCommunityID(c))
+	default:
+		created, err = h.Bills.GenerateBills(feeType, req.Year, req.Month, dueDate, currentCommunityID(c))
+	}
+	if err != nil {
+		fmt.Println("批量生成账单失败:", err)
+		c.JSON(mutationStatus(err), gin.H{
+			"success": false,
+			"message": "批量生成账单失败: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.Dashboard.RefreshMonthlyStats(currentCommunityID(c), req.Year, req.Month); err != nil {
+		fmt.Println("刷新月度统计失败:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已生成%d张%s账单", created, feeType.Name),
+		"data": gin.H{
+			"created": created,
+		},
+	})
+}
+
+// DeleteBill 删除账单
+func (h *BillHandler) DeleteBill(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的账单ID",
+		})
+		return
+	}
+
+	bill, err := h.Bills.GetBillByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取账单详情失败: " + err.Error(),
+		})
+		return
+	}
+
+	if bill == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "账单不存在",
+		})
+		return
+	}
+
+	// 其他社区住户的账单按不存在处理
+	resident, err := h.Residents.GetResidentByID(bill.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "账单不存在",
+		})
+		return
+	}
+
+	if err := h.Bills.DeleteBill(id); err != nil {
+		c.JSON(mutationStatus(err), gin.H{
+			"success": false,
+			"message": "删除账单失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "账单删除成功",
+	})
+}
+
+// GetOverdueAccounts 获取当前社区的欠费住户列表
+func (h *BillHandler) GetOverdueAccounts(c *gin.Context) {
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+
+	accounts, err := h.Bills.GetOverdueAccounts(asOf, currentCommunityID(c))
+	if err != nil {
+		fmt.Println("获取欠费住户失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取欠费住户失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  accounts,
+			"total": len(accounts),
+		},
+	})
+}
+
+// parseAsOf 解析asOf查询参数（格式2006-01-02），缺省为当天
+// 解析失败时直接返回400响应，调用方应在ok为false时返回
+func parseAsOf(c *gin.Context) (time.Time, bool) {
+	asOfStr := c.Query("asOf")
+	if asOfStr == "" {
+		return time.Now(), true
+	}
+
+	asOf, err := time.ParseInLocation("2006-01-02", asOfStr, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的日期参数: " + asOfStr,
+		})
+		return time.Time{}, false
+	}
+
+	// 包含当天全天
+	return asOf.Add(24*time.Hour - time.Second), true
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// CommunityHandler 处理社区及总部跨社区汇总相关请求
+type CommunityHandler struct {
+	Users       repository.UserRepository
+	Communities repository.CommunityRepository
+}
+
+// NewCommunityHandler 创建新的CommunityHandler
+func NewCommunityHandler(users repository.UserRepository, communities repository.CommunityRepository) *CommunityHandler {
+	return &CommunityHandler{
+		Users:       users,
+		Communities: communities,
+	}
+}
+
+// communityStatus 返回社区写操作失败时的HTTP状态码
+func communityStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrCommunityCodeExists):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidCommunityMember):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetCommunities 获取社区列表
+func (h *CommunityHandler) GetCommunities(c *gin.Context) {
+	communities, err := h.Communities.GetCommunities(c.Query("enabled") == "1")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取社区列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    communities,
+	})
+}
+
+// CreateCommunity 创建社区
+func (h *CommunityHandler) CreateCommunity(c *gin.Context) {
+	var req models.CommunityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := h.Communities.CreateCommunity(req)
+	if err != nil {
+		c.JSON(communityStatus(err), gin.H{
+			"success": false,
+			"message": "创建社区失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.respondCommunity(c, http.StatusCreated, id, "社区创建成功")
+}
+
+// UpdateCommunity 更新社区
+func (h *CommunityHandler) UpdateCommunity(c *gin.Context) {
+	community, ok := h.loadCommunity(c)
+	if !ok {
+		return
+	}
+
+	var req models.CommunityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.Communities.UpdateCommunity(community.ID, req); err != nil {
+		c.JSON(communityStatus(err), gin.H{
+			"success": false,
+			"message": "更新社区失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.respondCommunity(c, http.StatusOK, community.ID, "社区更新成功")
+}
+
+// GetMembers 获取社区成员
+func (h *CommunityHandler) GetMembers(c *gin.Context) {
+	community, ok := h.loadCommunity(c)
+	if !ok {
+		return
+	}
+
+	users, err := h.Communities.GetCommunityMembers(community.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取社区成员失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    users,
+	})
+}
+
+// AddMember 将工作人员加入社区
+func (h *CommunityHandler) AddMember(c *gin.Context) {
+	community, ok := h.loadCommunity(c)
+	if !ok {
+		return
+	}
+
+	var req models.CommunityMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.Communities.AddCommunityMember(community.ID, req.UserID); err != nil {
+		c.JSON(communityStatus(err), gin.H{
+			"success": false,
+			"message": "添加社区成员失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已加入社区" + community.Name,
+	})
+}
+
+// RemoveMember 将工作人员移出社区
+func (h *CommunityHandler) RemoveMember(c *gin.Context) {
+	community, ok := h.loadCommunity(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的用户ID",
+		})
+		return
+	}
+
+	if err := h.Communities.RemoveCommunityMember(community.ID, uint(userID)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "该用户不是社区成员",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "移出社区成员失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已移出社区" + community.Name,
+	})
+}
+
+// GetRollup 总部跨社区汇总：按社区对比住户、收入和收缴率，只汇总当前用户可以进入的社区
+func (h *CommunityHandler) GetRollup(c *gin.Context) {
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(time.Now().Year())))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的年份参数",
+		})
+		return
+	}
+
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+
+	user, ok := loadCurrentUser(c, h.Users)
+	if !ok {
+		return
+	}
+
+	communities, err := h.Users.GetUserCommunities(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取社区列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	rollups, total, err := h.Communities.GetCommunityRollup(communities, year, asOf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取社区汇总失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"year":        year,
+			"asOf":        asOf.Format("2006-01-02"),
+			"communities": rollups,
+			"total":       total,
+		},
+	})
+}
+
+// respondCommunity 重新加载社区并返回
+func (h *CommunityHandler) respondCommunity(c *gin.Context, status, id int, message string) {
+	community, err := h.Communities.GetCommunityByID(id)
+	if err != nil || community == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取社区详情失败",
+		})
+		return
+	}
+
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": message,
+		"data":    community,
+	})
+}
+
+// loadCommunity 根据路径参数id加载社区，加载失败时直接返回错误响应并返回false
+func (h *CommunityHandler) loadCommunity(c *gin.Context) (*models.Community, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的社区ID",
+		})
+		return nil, false
+	}
+
+	community, err := h.Communities.GetCommunityByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取社区详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if community == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "社区不存在",
+		})
+		return nil, false
+	}
+
+	return community, true
+}
@@ -0,0 +1,507 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/jobs"
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// ComplaintHandler 处理投诉建议相关请求，包括住户端的投诉登记和评价
+type ComplaintHandler struct {
+	Complaints repository.ComplaintRepository
+	Escalation *jobs.Complaints
+}
+
+// NewComplaintHandler 创建新的ComplaintHandler
+func NewComplaintHandler(complaints repository.ComplaintRepository, escalation *jobs.Complaints) *ComplaintHandler {
+	return &ComplaintHandler{
+		Complaints: complaints,
+		Escalation: escalation,
+	}
+}
+
+// complaintStatus 返回投诉写操作失败时的HTTP状态码
+func complaintStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrComplaintNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrComplaintTransition):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidComplaint):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetComplaints 获取投诉建议列表，匿名投诉仅管理员可见住户信息
+func (h *ComplaintHandler) GetComplaints(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"type", "category", "status", "channel", "keyword"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+	for _, key := range []string{"resident_id", "assignee_id"} {
+		if v := c.Query(key); v != "" {
+			if id, err := strconv.Atoi(v); err == nil {
+				filters[key] = id
+			}
+		}
+	}
+	for _, key := range []string{"overdue", "escalated"} {
+		if c.Query(key) == "true" {
+			filters[key] = true
+		}
+	}
+
+	list, total, err := h.Complaints.GetComplaints(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取投诉列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	if currentRole(c) != models.RoleAdmin {
+		for i := range list {
+			list[i].MaskIdentity()
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  list,
+			"total": total,
+		},
+	})
+}
+
+// GetComplaint 获取投诉详情，含处理记录
+func (h *ComplaintHandler) GetComplaint(c *gin.Context) {
+	complaint, ok := h.loadComplaint(c, true)
+	if !ok {
+		return
+	}
+
+	if currentRole(c) != models.RoleAdmin {
+		complaint.MaskIdentity()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    complaint,
+	})
+}
+
+// CreateComplaint 登记投诉建议（电话、前台等渠道）
+func (h *ComplaintHandler) CreateComplaint(c *gin.Context) {
+	var req models.ComplaintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if req.ResidentID > 0 {
+		resident, err := models.GetResidentByID(req.ResidentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取住户详情失败: " + err.Error(),
+			})
+			return
+		}
+		if resident == nil || !inCurrentCommunity(c, resident) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "住户不存在",
+			})
+			return
+		}
+		if req.ContactName == "" {
+			req.ContactName = resident.Name
+		}
+		if req.ContactPhone == "" {
+			req.ContactPhone = resident.Phone
+		}
+	}
+
+	h.create(c, req)
+}
+
+// create 在当前社区创建投诉并返回详情
+func (h *ComplaintHandler) create(c *gin.Context, req models.ComplaintRequest) {
+	id, err := models.CreateComplaint(req, currentUserID(c), currentCommunityID(c), h.Escalation.ResponseTime)
+	if err != nil {
+		c.JSON(complaintStatus(err), gin.H{
+			"success": false,
+			"message": "登记投诉建议失败: " + err.Error(),
+		})
+		return
+	}
+
+	complaint, err := h.Complaints.GetComplaintByID(id, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取新登记的投诉建议失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "投诉建议登记成功",
+		"data":    complaint,
+	})
+}
+
+// Transition 返回执行指定投诉操作的处理函数
+func (h *ComplaintHandler) Transition(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		complaint, ok := h.loadComplaint(c, false)
+		if !ok {
+			return
+		}
+
+		var req models.ComplaintActionRequest
+		if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的请求参数: " + err.Error(),
+			})
+			return
+		}
+
+		h.transition(c, complaint.ID, action, req)
+	}
+}
+
+// transition 执行投诉操作并返回最新详情
+func (h *ComplaintHandler) transition(c *gin.Context, id int, action string, req models.ComplaintActionRequest) {
+	if err := models.TransitionComplaint(id, action, req, currentUserID(c), currentUsername(c)); err != nil {
+		c.JSON(complaintStatus(err), gin.H{
+			"success": false,
+			"message": "投诉操作失败: " + err.Error(),
+		})
+		return
+	}
+
+	complaint, err := h.Complaints.GetComplaintByID(id, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取投诉失败: " + err.Error(),
+		})
+		return
+	}
+	if currentRole(c) != models.RoleAdmin && currentRole(c) != models.RoleResident {
+		complaint.MaskIdentity()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "投诉操作成功",
+		"data":    complaint,
+	})
+}
+
+// CreateLog 添加投诉处理备注
+func (h *ComplaintHandler) CreateLog(c *gin.Context) {
+	complaint, ok := h.loadComplaint(c, false)
+	if !ok {
+		return
+	}
+
+	var req models.ComplaintLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.CreateComplaintLog(complaint.ID, req.Content, currentUserID(c), currentUsername(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "添加备注失败: " + err.Error(),
+		})
+		return
+	}
+
+	logs, err := models.GetComplaintLogs(complaint.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取处理记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "备注添加成功",
+		"data":    logs,
+	})
+}
+
+// Escalate 立即升级超期未答复的投诉
+func (h *ComplaintHandler) Escalate(c *gin.Context) {
+	escalated, err := h.Escalation.Run(time.Now())
+	if err != nil {
+		fmt.Println("投诉超期升级失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "投诉超期升级失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已升级%d件超期投诉", len(escalated)),
+		"data": gin.H{
+			"escalated": len(escalated),
+		},
+	})
+}
+
+// GetStats 获取当前社区指定月份的投诉建议统计，缺省为当月
+func (h *ComplaintHandler) GetStats(c *gin.Context) {
+	now := time.Now()
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(now.Year())))
+	if err != nil {
+		year = now.Year()
+	}
+	month, err := strconv.Atoi(c.DefaultQuery("month", strconv.Itoa(int(now.Month()))))
+	if err != nil || month < 1 || month > 12 {
+		month = int(now.Month())
+	}
+
+	stats, err := models.GetComplaintStats(year, month, currentCommunityID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取投诉统计失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+// GetMyComplaints 住户端：获取本户登记的投诉建议
+func (h *ComplaintHandler) GetMyComplaints(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"resident_id": residentID}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+
+	list, total, err := h.Complaints.GetComplaints(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取投诉列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  list,
+			"total": total,
+		},
+	})
+}
+
+// GetMyComplaint 住户端：获取本户的投诉详情，不含内部备注
+func (h *ComplaintHandler) GetMyComplaint(c *gin.Context) {
+	complaint, ok := h.loadMyComplaint(c, true)
+	if !ok {
+		return
+	}
+
+	logs := []models.ComplaintLog{}
+	for _, l := range complaint.Logs {
+		if l.Kind != models.ComplaintLogComment {
+			logs = append(logs, l)
+		}
+	}
+	complaint.Logs = logs
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    complaint,
+	})
+}
+
+// CreateMyComplaint 住户端：提交投诉建议
+func (h *ComplaintHandler) CreateMyComplaint(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	var req models.ComplaintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(residentID)
+	if err != nil || resident == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败",
+		})
+		return
+	}
+
+	// 住户端提交的投诉只能关联本户，答复期限按配置计算
+	req.ResidentID = residentID
+	req.Channel = "portal"
+	req.Deadline = ""
+	if req.ContactName == "" {
+		req.ContactName = resident.Name
+	}
+	if req.ContactPhone == "" {
+		req.ContactPhone = resident.Phone
+	}
+
+	h.create(c, req)
+}
+
+// RateMyComplaint 住户端：对已答复的投诉评价满意度并关闭
+func (h *ComplaintHandler) RateMyComplaint(c *gin.Context) {
+	complaint, ok := h.loadMyComplaint(c, false)
+	if !ok {
+		return
+	}
+
+	var req models.ComplaintActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Satisfaction == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请评价满意度（1-5分）",
+		})
+		return
+	}
+
+	h.transition(c, complaint.ID, models.ComplaintActionClose, req)
+}
+
+// ReopenMyComplaint 住户端：对答复不满意时要求重新处理
+func (h *ComplaintHandler) ReopenMyComplaint(c *gin.Context) {
+	complaint, ok := h.loadMyComplaint(c, false)
+	if !ok {
+		return
+	}
+
+	var req models.ComplaintActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	h.transition(c, complaint.ID, models.ComplaintActionReopen, models.ComplaintActionRequest{Note: req.Note})
+}
+
+// loadComplaint 根据路径参数id加载当前社区的投诉，加载失败时直接返回错误响应并返回false
+func (h *ComplaintHandler) loadComplaint(c *gin.Context, withLogs bool) (*models.Complaint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的投诉ID",
+		})
+		return nil, false
+	}
+
+	complaint, err := h.Complaints.GetComplaintByID(id, withLogs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取投诉失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if complaint == nil || complaint.CommunityID != currentCommunityID(c) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "投诉建议不存在",
+		})
+		return nil, false
+	}
+
+	return complaint, true
+}
+
+// loadMyComplaint 加载当前住户账号本户的投诉，其他住户的投诉视为不存在
+func (h *ComplaintHandler) loadMyComplaint(c *gin.Context, withLogs bool) (*models.Complaint, bool) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return nil, false
+	}
+
+	complaint, ok := h.loadComplaint(c, withLogs)
+	if !ok {
+		return nil, false
+	}
+	if complaint.ResidentID != residentID {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "投诉建议不存在",
+		})
+		return nil, false
+	}
+
+	return complaint, true
+}
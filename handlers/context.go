@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+)
+
+// currentUserID 获取当前登录用户的ID
+func currentUserID(c *gin.Context) uint {
+	v, _ := c.Get("user_id")
+	// JWT中的数字解析后为float64
+	if id, ok := v.(float64); ok {
+		return uint(id)
+	}
+	return 0
+}
+
+// currentUsername 获取当前登录用户的用户名
+func currentUsername(c *gin.Context) string {
+	v, _ := c.Get("username")
+	username, _ := v.(string)
+	return username
+}
+
+// currentCommunityID 获取当前登录用户所在的社区ID
+// 启用多社区之前签发的令牌没有社区信息，视为默认社区
+func currentCommunityID(c *gin.Context) int {
+	v, _ := c.Get("community_id")
+	// JWT中的数字解析后为float64
+	if id, ok := v.(float64); ok && id > 0 {
+		return int(id)
+	}
+	return models.DefaultCommunityID
+}
+
+// inCurrentCommunity 判断住户是否属于当前登录用户所在的社区
+func inCurrentCommunity(c *gin.Context, resident *models.Resident) bool {
+	return resident.CommunityID == currentCommunityID(c)
+}
+
+// currentRole 获取当前登录用户的角色
+func currentRole(c *gin.Context) string {
+	v, _ := c.Get("role")
+	role, _ := v.(string)
+	return role
+}
+
+// mutationStatus 返回写操作失败时的HTTP状态码，账期已结账时返回409
+func mutationStatus(err error) int {
+	if errors.Is(err, models.ErrPeriodClosed) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// currentResidentID 获取当前住户账号关联的住户ID，由LoadResidentAccount中间件写入，未关联住户时返回0
+func currentResidentID(c *gin.Context) int {
+	v, _ := c.Get("resident_id")
+	residentID, _ := v.(int)
+	return residentID
+}
@@ -8,15 +8,21 @@ import (
 
 	"github.com/gin-gonic/gin"
 
-	"community-backward/models"
+	"community-backward/repository"
 )
 
 // DashboardHandler 处理仪表盘相关请求
-type DashboardHandler struct{}
+type DashboardHandler struct {
+	Dashboard repository.DashboardRepository
+	FeeTypes  repository.FeeTypeRepository
+}
 
 // NewDashboardHandler 创建新的DashboardHandler
-func NewDashboardHandler() *DashboardHandler {
-	return &DashboardHandler{}
+func NewDashboardHandler(dashboard repository.DashboardRepository, feeTypes repository.FeeTypeRepository) *DashboardHandler {
+	return &DashboardHandler{
+		Dashboard: dashboard,
+		FeeTypes:  feeTypes,
+	}
 }
 
 // GetDashboard 获取仪表盘数据
@@ -26,8 +32,8 @@ func (h *DashboardHandler) GetDashboard(c *gin.Context) {
 	username, _ := c.Get("username")
 	fmt.Printf("当前用户: %v\n", username)
 
-	// 获取仪表盘统计数据
-	stats, err := models.GetDashboardStats()
+	// 获取当前社区的仪表盘统计数据
+	stats, err := h.Dashboard.GetDashboardStats(currentCommunityID(c))
 	if err != nil {
 		fmt.Println("获取仪表盘统计数据失败:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -72,8 +78,29 @@ func (h *DashboardHandler) GetIncomeData(c *gin.Context) {
 
 	fmt.Printf("解析后的年份: %d\n", year)
 
+	// 按收费项目过滤，缺省汇总所有项目
+	feeTypeID := 0
+	if code := c.Query("type"); code != "" {
+		feeType, err := h.FeeTypes.GetFeeTypeByCode(code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取收费项目失败: " + err.Error(),
+			})
+			return
+		}
+		if feeType == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的收费项目: " + code,
+			})
+			return
+		}
+		feeTypeID = feeType.ID
+	}
+
 	// 获取物业费收入趋势数据
-	incomeData, err := models.GetIncomeData(year)
+	incomeData, err := h.Dashboard.GetIncomeData(year, feeTypeID, currentCommunityID(c))
 	if err != nil {
 		fmt.Println("获取物业费收入趋势数据失败:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -93,3 +120,91 @@ func (h *DashboardHandler) GetIncomeData(c *gin.Context) {
 	fmt.Printf("返回响应: %+v\n", response)
 	c.JSON(http.StatusOK, response)
 }
+
+// GetIncomeByType 获取按收费项目拆分的月度收入
+func (h *DashboardHandler) GetIncomeByType(c *gin.Context) {
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(time.Now().Year())))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的年份参数",
+		})
+		return
+	}
+
+	incomes, err := h.Dashboard.GetIncomeByType(year, currentCommunityID(c))
+	if err != nil {
+		fmt.Println("获取分项收入失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取分项收入失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    incomes,
+	})
+}
+
+// GetCollectionRate 获取按楼栋或单元统计的收缴率
+func (h *DashboardHandler) GetCollectionRate(c *gin.Context) {
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(time.Now().Year())))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的年份参数",
+		})
+		return
+	}
+
+	month, err := strconv.Atoi(c.DefaultQuery("month", "0"))
+	if err != nil || month < 0 || month > 12 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的月份参数",
+		})
+		return
+	}
+
+	groupBy := c.DefaultQuery("groupBy", "building")
+	if groupBy != "building" && groupBy != "unit" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "groupBy只能为building或unit",
+		})
+		return
+	}
+
+	feeTypeID := 0
+	if v := c.Query("fee_type_id"); v != "" {
+		if feeTypeID, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的收费项目ID",
+			})
+			return
+		}
+	}
+
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.Dashboard.GetCollectionReport(year, month, groupBy, feeTypeID, asOf, currentCommunityID(c))
+	if err != nil {
+		fmt.Println("获取收缴率失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取收缴率失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
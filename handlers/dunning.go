@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/jobs"
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// DunningHandler 处理催缴相关请求
+type DunningHandler struct {
+	Records repository.DunningRepository
+	Dunning *jobs.Dunning
+}
+
+// NewDunningHandler 创建新的DunningHandler
+func NewDunningHandler(records repository.DunningRepository, dunning *jobs.Dunning) *DunningHandler {
+	return &DunningHandler{
+		Records: records,
+		Dunning: dunning,
+	}
+}
+
+// RunDunning 立即对当前社区执行一次催缴
+func (h *DunningHandler) RunDunning(c *gin.Context) {
+	fmt.Println("接收到手动催缴请求")
+
+	result, err := h.Dunning.Run(time.Now(), currentCommunityID(c))
+	if err != nil {
+		fmt.Println("执行催缴失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "执行催缴失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "催缴执行完成",
+		"data":    result,
+	})
+}
+
+// GetDunningRecords 获取当前社区的催缴记录
+func (h *DunningHandler) GetDunningRecords(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	if channel := c.Query("channel"); channel != "" {
+		filters["channel"] = channel
+	}
+	if v := c.Query("status"); v != "" {
+		if status, err := strconv.Atoi(v); err == nil {
+			filters["status"] = status
+		}
+	}
+
+	records, total, err := h.Records.GetDunningRecords(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取催缴记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  records,
+			"total": total,
+		},
+	})
+}
+
+// GetDunningTemplates 获取催缴模板
+func (h *DunningHandler) GetDunningTemplates(c *gin.Context) {
+	templates, err := models.GetDunningTemplates(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取催缴模板失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    templates,
+	})
+}
+
+// UpdateDunningTemplate 更新催缴模板
+func (h *DunningHandler) UpdateDunningTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的模板ID",
+		})
+		return
+	}
+
+	var req models.DunningTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	found, err := models.UpdateDunningTemplate(id, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "更新催缴模板失败: " + err.Error(),
+		})
+		return
+	}
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "催缴模板不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "催缴模板更新成功",
+	})
+}
@@ -0,0 +1,498 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// FacilityHandler 处理公共设施和设施预约相关请求
+type FacilityHandler struct {
+	Facilities repository.FacilityRepository
+}
+
+// NewFacilityHandler 创建新的FacilityHandler
+func NewFacilityHandler(facilities repository.FacilityRepository) *FacilityHandler {
+	return &FacilityHandler{
+		Facilities: facilities,
+	}
+}
+
+// facilityStatus 返回设施和预约写操作失败时的HTTP状态码
+func facilityStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrFacilityNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrFacilityExists), errors.Is(err, models.ErrBookingConflict),
+		errors.Is(err, models.ErrBookingQuota), errors.Is(err, models.ErrBookingNotCancellable),
+		errors.Is(err, models.ErrBookingNotPayable):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidFacility), errors.Is(err, models.ErrInvalidBooking):
+		return http.StatusBadRequest
+	}
+	return mutationStatus(err)
+}
+
+// GetFacilities 获取当前社区的设施列表，enabled=true时只返回启用的设施
+func (h *FacilityHandler) GetFacilities(c *gin.Context) {
+	facilities, err := h.Facilities.GetFacilities(currentCommunityID(c), c.Query("enabled") == "true" || currentRole(c) == models.RoleResident)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取设施列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    facilities,
+	})
+}
+
+// CreateFacility 在当前社区新增设施
+func (h *FacilityHandler) CreateFacility(c *gin.Context) {
+	var req models.FacilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := models.CreateFacility(req, currentCommunityID(c))
+	if err != nil {
+		c.JSON(facilityStatus(err), gin.H{
+			"success": false,
+			"message": "新增设施失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "新增设施成功",
+		"data": gin.H{
+			"id": id,
+		},
+	})
+}
+
+// UpdateFacility 修改设施
+func (h *FacilityHandler) UpdateFacility(c *gin.Context) {
+	facility, ok := h.loadFacility(c)
+	if !ok {
+		return
+	}
+
+	var req models.FacilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.UpdateFacility(facility.ID, req); err != nil {
+		c.JSON(facilityStatus(err), gin.H{
+			"success": false,
+			"message": "修改设施失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "修改设施成功",
+	})
+}
+
+// GetAvailability 获取设施某天各时段的预约情况，date缺省为今天
+func (h *FacilityHandler) GetAvailability(c *gin.Context) {
+	facility, ok := h.loadFacility(c)
+	if !ok {
+		return
+	}
+	if !facility.Enabled && currentRole(c) == models.RoleResident {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": models.ErrFacilityNotFound.Error(),
+		})
+		return
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", c.DefaultQuery("date", time.Now().Format("2006-01-02")), time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "日期格式应为YYYY-MM-DD",
+		})
+		return
+	}
+
+	slots, err := models.GetFacilityAvailability(facility, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取设施预约情况失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"facility": facility,
+			"date":     date.Format("2006-01-02"),
+			"slots":    slots,
+		},
+	})
+}
+
+// GetBookings 获取当前社区的设施预约列表
+func (h *FacilityHandler) GetBookings(c *gin.Context) {
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"facility_id", "resident_id"} {
+		if v := c.Query(key); v != "" {
+			if id, err := strconv.Atoi(v); err == nil {
+				filters[key] = id
+			}
+		}
+	}
+	h.listBookings(c, filters)
+}
+
+// listBookings 按条件查询设施预约并返回分页结果，支持status、date（YYYY-MM-DD）和upcoming=true过滤
+func (h *FacilityHandler) listBookings(c *gin.Context, filters map[string]interface{}) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	if v := c.Query("date"); v != "" {
+		date, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "日期格式应为YYYY-MM-DD",
+			})
+			return
+		}
+		filters["date"] = date
+	}
+	if c.Query("upcoming") == "true" {
+		filters["upcoming"] = true
+	}
+	if c.Query("unpaid") == "true" {
+		filters["unpaid"] = true
+	}
+
+	bookings, total, err := h.Facilities.GetFacilityBookings(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取设施预约失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  bookings,
+			"total": total,
+		},
+	})
+}
+
+// GetBooking 获取设施预约详情
+func (h *FacilityHandler) GetBooking(c *gin.Context) {
+	booking, ok := h.loadBooking(c, 0)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    booking,
+	})
+}
+
+// CreateBooking 前台为住户预约设施
+func (h *FacilityHandler) CreateBooking(c *gin.Context) {
+	var req models.FacilityBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if req.ResidentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请指定预约住户",
+		})
+		return
+	}
+	resident, err := models.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	h.createBooking(c, req, models.BookingSourceStaff)
+}
+
+// createBooking 创建设施预约并返回预约详情
+func (h *FacilityHandler) createBooking(c *gin.Context, req models.FacilityBookingRequest, source string) {
+	id, err := models.CreateFacilityBooking(req, currentUserID(c), source)
+	if err != nil {
+		c.JSON(facilityStatus(err), gin.H{
+			"success": false,
+			"message": "预约失败: " + err.Error(),
+		})
+		return
+	}
+
+	booking, err := h.Facilities.GetFacilityBookingByID(id)
+	if err != nil || booking == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取预约详情失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "预约成功",
+		"data":    booking,
+	})
+}
+
+// PayBooking 前台确认收取住户端预约的费用
+func (h *FacilityHandler) PayBooking(c *gin.Context) {
+	booking, ok := h.loadBooking(c, 0)
+	if !ok {
+		return
+	}
+
+	var req models.FacilityPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.PayFacilityBooking(booking.ID, req); err != nil {
+		c.JSON(facilityStatus(err), gin.H{
+			"success": false,
+			"message": "收取预约费用失败: " + err.Error(),
+		})
+		return
+	}
+
+	booking, err := h.Facilities.GetFacilityBookingByID(booking.ID)
+	if err != nil || booking == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取预约详情失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "预约费用已收取",
+		"data":    booking,
+	})
+}
+
+// CancelBooking 取消设施预约，waive_policy=true时不论何时取消都全额退款
+func (h *FacilityHandler) CancelBooking(c *gin.Context) {
+	booking, ok := h.loadBooking(c, 0)
+	if !ok {
+		return
+	}
+
+	var req models.FacilityCancelRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+	cancelBooking(c, booking, req)
+}
+
+// cancelBooking 按取消规则取消预约并返回是否退款
+func cancelBooking(c *gin.Context, booking *models.FacilityBooking, req models.FacilityCancelRequest) {
+	refunded, err := models.CancelFacilityBooking(booking.ID, req)
+	if err != nil {
+		c.JSON(facilityStatus(err), gin.H{
+			"success": false,
+			"message": "取消预约失败: " + err.Error(),
+		})
+		return
+	}
+
+	message := "预约已取消"
+	if refunded {
+		message = "预约已取消，费用已退回"
+	} else if booking.FeeID != nil {
+		message = "预约已取消，已超过免费取消时限，费用不予退还"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data": gin.H{
+			"refunded": refunded,
+		},
+	})
+}
+
+// GetMyBookings 住户端：获取本户的设施预约
+func (h *FacilityHandler) GetMyBookings(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+	h.listBookings(c, map[string]interface{}{"resident_id": residentID})
+}
+
+// CreateMyBooking 住户端：为本户预约设施，收费设施只记录应付金额，由前台确认收款
+func (h *FacilityHandler) CreateMyBooking(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	var req models.FacilityBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	req.ResidentID = residentID
+	req.PaymentMethod = ""
+	h.createBooking(c, req, models.BookingSourceResident)
+}
+
+// CancelMyBooking 住户端：取消本户的设施预约，按设施的取消规则退款
+func (h *FacilityHandler) CancelMyBooking(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	booking, ok := h.loadBooking(c, residentID)
+	if !ok {
+		return
+	}
+
+	var req models.FacilityCancelRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+	req.WaivePolicy = false
+	cancelBooking(c, booking, req)
+}
+
+// loadFacility 根据路径参数id加载当前社区的设施，加载失败时直接返回错误响应并返回false
+func (h *FacilityHandler) loadFacility(c *gin.Context) (*models.Facility, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的设施ID",
+		})
+		return nil, false
+	}
+
+	facility, err := h.Facilities.GetFacilityByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取设施详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if facility == nil || facility.CommunityID != currentCommunityID(c) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "设施不存在",
+		})
+		return nil, false
+	}
+
+	return facility, true
+}
+
+// loadBooking 根据路径参数id加载当前社区的设施预约，residentID大于0时只能加载该住户的预约
+// 加载失败时直接返回错误响应并返回false
+func (h *FacilityHandler) loadBooking(c *gin.Context, residentID int) (*models.FacilityBooking, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的预约ID",
+		})
+		return nil, false
+	}
+
+	booking, err := h.Facilities.GetFacilityBookingByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取预约详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if booking == nil || booking.CommunityID != currentCommunityID(c) || (residentID > 0 && booking.ResidentID != residentID) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "预约不存在",
+		})
+		return nil, false
+	}
+
+	return booking, true
+}
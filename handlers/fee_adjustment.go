@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+)
+
+// FeeAdjustmentHandler 处理费用减免和优惠相关请求
+type FeeAdjustmentHandler struct{}
+
+// NewFeeAdjustmentHandler 创建新的FeeAdjustmentHandler
+func NewFeeAdjustmentHandler() *FeeAdjustmentHandler {
+	return &FeeAdjustmentHandler{}
+}
+
+// GetFeeAdjustments 获取费用调整列表
+func (h *FeeAdjustmentHandler) GetFeeAdjustments(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	if adjType := c.Query("type"); adjType != "" {
+		filters["type"] = adjType
+	}
+
+	list, total, err := models.GetFeeAdjustments(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取减免申请失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  list,
+			"total": total,
+		},
+	})
+}
+
+// CreateFeeAdjustment 提交减免或优惠申请
+func (h *FeeAdjustmentHandler) CreateFeeAdjustment(c *gin.Context) {
+	var req models.FeeAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	bill, err := models.GetBillByID(req.BillID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取账单详情失败: " + err.Error(),
+		})
+		return
+	}
+
+	if bill == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "账单不存在",
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(bill.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "账单不存在",
+		})
+		return
+	}
+
+	id, err := models.CreateFeeAdjustment(req, currentUserID(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrAdjustmentExceedsBill) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "提交减免申请失败: " + err.Error(),
+		})
+		return
+	}
+
+	fmt.Printf("用户%s提交减免申请, ID: %d\n", currentUsername(c), id)
+
+	adjustment, err := models.GetFeeAdjustmentByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取新创建的减免申请失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "减免申请已提交，等待财务审批",
+		"data":    adjustment,
+	})
+}
+
+// ApproveFeeAdjustment 审批通过
+func (h *FeeAdjustmentHandler) ApproveFeeAdjustment(c *gin.Context) {
+	h.review(c, true)
+}
+
+// RejectFeeAdjustment 审批驳回
+func (h *FeeAdjustmentHandler) RejectFeeAdjustment(c *gin.Context) {
+	h.review(c, false)
+}
+
+// review 审批减免申请，申请人不能审批自己的申请
+func (h *FeeAdjustmentHandler) review(c *gin.Context, approve bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的申请ID",
+		})
+		return
+	}
+
+	var req models.FeeAdjustmentReviewRequest
+	// 审批意见可选，允许空请求体
+	_ = c.ShouldBindJSON(&req)
+
+	adjustment, err := models.GetFeeAdjustmentByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取减免申请失败: " + err.Error(),
+		})
+		return
+	}
+
+	if adjustment == nil || adjustment.CommunityID != currentCommunityID(c) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "减免申请不存在",
+		})
+		return
+	}
+
+	reviewerID := currentUserID(c)
+	if uint(adjustment.RequestedBy) == reviewerID {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "不能审批自己提交的申请",
+		})
+		return
+	}
+
+	if err := models.ReviewFeeAdjustment(id, approve, reviewerID, req.Remark); err != nil {
+		status := mutationStatus(err)
+		if errors.Is(err, models.ErrAdjustmentNotPending) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "审批失败: " + err.Error(),
+		})
+		return
+	}
+
+	message := "已驳回"
+	if approve {
+		message = "审批通过，已冲抵账单"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+	})
+}
+
+// GetFeeAdjustmentReport 获取某期间的减免汇总报表
+func (h *FeeAdjustmentHandler) GetFeeAdjustmentReport(c *gin.Context) {
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(time.Now().Year())))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的年份参数",
+		})
+		return
+	}
+
+	month, err := strconv.Atoi(c.DefaultQuery("month", "0"))
+	if err != nil || month < 0 || month > 12 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的月份参数",
+		})
+		return
+	}
+
+	report, err := models.GetFeeAdjustmentReport(year, month, currentCommunityID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取减免报表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
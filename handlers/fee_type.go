@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// FeeTypeHandler 处理收费项目相关请求
+type FeeTypeHandler struct {
+	FeeTypes repository.FeeTypeRepository
+}
+
+// NewFeeTypeHandler 创建新的FeeTypeHandler
+func NewFeeTypeHandler(feeTypes repository.FeeTypeRepository) *FeeTypeHandler {
+	return &FeeTypeHandler{FeeTypes: feeTypes}
+}
+
+// GetFeeTypes 获取收费项目列表
+func (h *FeeTypeHandler) GetFeeTypes(c *gin.Context) {
+	types, err := h.FeeTypes.GetFeeTypes(c.Query("enabled") == "1")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取收费项目失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    types,
+	})
+}
+
+// CreateFeeType 创建收费项目
+func (h *FeeTypeHandler) CreateFeeType(c *gin.Context) {
+	var req models.FeeTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := h.FeeTypes.CreateFeeType(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建收费项目失败: " + err.Error(),
+		})
+		return
+	}
+
+	feeType, err := h.FeeTypes.GetFeeTypeByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取新创建的收费项目失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "收费项目创建成功",
+		"data":    feeType,
+	})
+}
+
+// UpdateFeeType 更新收费项目
+func (h *FeeTypeHandler) UpdateFeeType(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的收费项目ID",
+		})
+		return
+	}
+
+	feeType, err := h.FeeTypes.GetFeeTypeByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取收费项目失败: " + err.Error(),
+		})
+		return
+	}
+
+	if feeType == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "收费项目不存在",
+		})
+		return
+	}
+
+	var req models.FeeTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.FeeTypes.UpdateFeeType(id, req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "更新收费项目失败: " + err.Error(),
+		})
+		return
+	}
+
+	updated, err := h.FeeTypes.GetFeeTypeByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取更新后的收费项目失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "收费项目更新成功",
+		"data":    updated,
+	})
+}
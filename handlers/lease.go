@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/jobs"
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// LeaseHandler 处理房屋租赁相关请求
+type LeaseHandler struct {
+	Leases    repository.LeaseRepository
+	Reminders *jobs.Leases
+}
+
+// NewLeaseHandler 创建新的LeaseHandler
+func NewLeaseHandler(leases repository.LeaseRepository, reminders *jobs.Leases) *LeaseHandler {
+	return &LeaseHandler{
+		Leases:    leases,
+		Reminders: reminders,
+	}
+}
+
+// leaseStatus 返回租约写操作失败时的HTTP状态码
+func leaseStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrLeaseOverlap), errors.Is(err, models.ErrLeaseNotActive):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidLease):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetLeases 获取当前社区的租约列表，expiring_days=N时只返回N天内到期的生效中租约
+func (h *LeaseHandler) GetLeases(c *gin.Context) {
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"status", "fee_payer", "keyword"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	if v := c.Query("expiring_days"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days >= 0 {
+			filters["expiring_days"] = days
+		}
+	}
+	h.listLeases(c, filters)
+}
+
+// listLeases 按条件查询租约并返回分页结果
+func (h *LeaseHandler) listLeases(c *gin.Context, filters map[string]interface{}) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	leases, total, err := h.Leases.GetLeases(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取租约列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  leases,
+			"total": total,
+		},
+	})
+}
+
+// GetLease 获取租约详情
+func (h *LeaseHandler) GetLease(c *gin.Context) {
+	lease, ok := h.loadLease(c, 0)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    lease,
+	})
+}
+
+// CreateLease 登记租约
+func (h *LeaseHandler) CreateLease(c *gin.Context) {
+	var req models.LeaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	id, err := models.CreateLease(req, h.Reminders.DefaultFeePayer, currentUserID(c))
+	if err != nil {
+		c.JSON(leaseStatus(err), gin.H{
+			"success": false,
+			"message": "登记租约失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.respondLease(c, http.StatusCreated, id, "租约登记成功")
+}
+
+// UpdateLease 更新生效中的租约
+func (h *LeaseHandler) UpdateLease(c *gin.Context) {
+	lease, ok := h.loadLease(c, 0)
+	if !ok {
+		return
+	}
+
+	var req models.LeaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.UpdateLease(lease.ID, req, h.Reminders.DefaultFeePayer); err != nil {
+		c.JSON(leaseStatus(err), gin.H{
+			"success": false,
+			"message": "更新租约失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.respondLease(c, http.StatusOK, lease.ID, "租约更新成功")
+}
+
+// RenewLease 续租
+func (h *LeaseHandler) RenewLease(c *gin.Context) {
+	lease, ok := h.loadLease(c, 0)
+	if !ok {
+		return
+	}
+
+	var req models.LeaseRenewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.RenewLease(lease.ID, req); err != nil {
+		c.JSON(leaseStatus(err), gin.H{
+			"success": false,
+			"message": "续租失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.respondLease(c, http.StatusOK, lease.ID, "续租成功")
+}
+
+// TerminateLease 提前解约
+func (h *LeaseHandler) TerminateLease(c *gin.Context) {
+	lease, ok := h.loadLease(c, 0)
+	if !ok {
+		return
+	}
+
+	var req models.LeaseTerminateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.TerminateLease(lease.ID, req); err != nil {
+		c.JSON(leaseStatus(err), gin.H{
+			"success": false,
+			"message": "解约失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.respondLease(c, http.StatusOK, lease.ID, "租约已解约")
+}
+
+// Remind 立即发送租约到期提醒
+func (h *LeaseHandler) Remind(c *gin.Context) {
+	result, err := h.Reminders.Run(time.Now())
+	if err != nil {
+		fmt.Println("租约到期提醒失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "租约到期提醒失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已提醒%d份即将到期的租约", result.Reminded),
+		"data":    result,
+	})
+}
+
+// GetBillPayer 获取当前社区房屋在指定日期（默认今天）的物业费缴纳人
+func (h *LeaseHandler) GetBillPayer(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的住户ID",
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	date := time.Now()
+	if v := c.Query("date"); v != "" {
+		if date, err = time.ParseInLocation("2006-01-02", v, time.Local); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的日期",
+			})
+			return
+		}
+	}
+
+	payer, err := models.GetBillPayer(id, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取缴费人失败: " + err.Error(),
+		})
+		return
+	}
+	if payer == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    payer,
+	})
+}
+
+// GetMyLeases 住户端：业主查看本户房屋的租约
+func (h *LeaseHandler) GetMyLeases(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	filters := map[string]interface{}{"resident_id": residentID}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	h.listLeases(c, filters)
+}
+
+// GetMyLease 住户端：业主查看本户房屋的租约详情
+func (h *LeaseHandler) GetMyLease(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+	lease, ok := h.loadLease(c, residentID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    lease,
+	})
+}
+
+// respondLease 重新加载租约并返回
+func (h *LeaseHandler) respondLease(c *gin.Context, status, id int, message string) {
+	lease, err := h.Leases.GetLeaseByID(id)
+	if err != nil || lease == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取租约详情失败",
+		})
+		return
+	}
+
+	c.JSON(status, gin.H{
+		"success": true,
+		"message": message,
+		"data":    lease,
+	})
+}
+
+// loadLease 根据路径参数id加载当前社区的租约，residentID大于0时只允许加载该住户房屋的租约，
+// 加载失败时直接返回错误响应并返回false
+func (h *LeaseHandler) loadLease(c *gin.Context, residentID int) (*models.Lease, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的租约ID",
+		})
+		return nil, false
+	}
+
+	lease, err := h.Leases.GetLeaseByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取租约详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if lease == nil || lease.CommunityID != currentCommunityID(c) || (residentID > 0 && lease.ResidentID != residentID) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "租约不存在",
+		})
+		return nil, false
+	}
+
+	return lease, true
+}
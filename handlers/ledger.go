@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+)
+
+// 凭证导出格式
+const (
+	exportKingdee = "kingdee" // 金蝶
+	exportYonyou  = "yonyou"  // 用友
+)
+
+// LedgerHandler 处理总账相关请求
+type LedgerHandler struct{}
+
+// NewLedgerHandler 创建新的LedgerHandler
+func NewLedgerHandler() *LedgerHandler {
+	return &LedgerHandler{}
+}
+
+// GetAccounts 获取会计科目表
+func (h *LedgerHandler) GetAccounts(c *gin.Context) {
+	accounts, err := models.GetGLAccounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取会计科目失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    accounts,
+	})
+}
+
+// GetTrialBalance 获取当前社区的科目余额表，format=csv时导出CSV
+func (h *LedgerHandler) GetTrialBalance(c *gin.Context) {
+	start, end, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	tb, err := models.GetTrialBalance(currentCommunityID(c), start, end)
+	if err != nil {
+		fmt.Println("获取科目余额表失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取科目余额表失败: " + err.Error(),
+		})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		rows := [][]string{{"科目代码", "科目名称", "期初借方", "期初贷方", "本期借方", "本期贷方", "期末借方", "期末贷方"}}
+		for _, r := range append(tb.Rows, tb.Total) {
+			rows = append(rows, []string{
+				r.Code, r.Name,
+				formatAmount(r.OpeningDebit), formatAmount(r.OpeningCredit),
+				formatAmount(r.PeriodDebit), formatAmount(r.PeriodCredit),
+				formatAmount(r.ClosingDebit), formatAmount(r.ClosingCredit),
+			})
+		}
+		writeCSV(c, fmt.Sprintf("trial-balance-%s-%s.csv", tb.Start, tb.End), rows)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tb,
+	})
+}
+
+// GetAccountStatement 获取当前社区的科目明细账，可按住户过滤
+func (h *LedgerHandler) GetAccountStatement(c *gin.Context) {
+	start, end, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	residentID := 0
+	if v := c.Query("resident_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的住户ID",
+			})
+			return
+		}
+		residentID = id
+	}
+
+	statement, err := models.GetAccountStatement(currentCommunityID(c), c.Param("code"), start, end, residentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取科目明细账失败: " + err.Error(),
+		})
+		return
+	}
+
+	if statement == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "会计科目不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    statement,
+	})
+}
+
+// ExportVouchers 按金蝶或用友的凭证导入格式导出当前社区的凭证CSV，凭证号取记账时按社区和月份分配的凭证号
+func (h *LedgerHandler) ExportVouchers(c *gin.Context) {
+	start, end, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", exportKingdee)
+	if format != exportKingdee && format != exportYonyou {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的导出格式: " + format,
+		})
+		return
+	}
+
+	entries, err := models.GetJournalEntries(currentCommunityID(c), start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取凭证失败: " + err.Error(),
+		})
+		return
+	}
+
+	var rows [][]string
+	if format == exportKingdee {
+		rows = [][]string{{"凭证日期", "凭证字", "凭证号", "摘要", "科目代码", "科目名称", "借方金额", "贷方金额"}}
+	} else {
+		rows = [][]string{{"制单日期", "凭证类别", "凭证号", "摘要", "科目编码", "借方金额", "贷方金额", "制单人"}}
+	}
+
+	for _, e := range entries {
+		seq := e.VoucherNo
+		date := e.EntryDate.Format("2006-01-02")
+		for _, l := range e.Lines {
+			if format == exportKingdee {
+				rows = append(rows, []string{date, "记", strconv.Itoa(seq), l.Memo, l.AccountCode, l.AccountName,
+					formatAmount(l.Debit), formatAmount(l.Credit)})
+			} else {
+				rows = append(rows, []string{date, "记账凭证", strconv.Itoa(seq), l.Memo, l.AccountCode,
+					formatAmount(l.Debit), formatAmount(l.Credit), currentUsername(c)})
+			}
+		}
+	}
+
+	writeCSV(c, fmt.Sprintf("vouchers-%s-%s-%s.csv", format, start.Format("20060102"), end.Format("20060102")), rows)
+}
+
+// SyncJournal 为当前社区的历史单据补记凭证
+func (h *LedgerHandler) SyncJournal(c *gin.Context) {
+	posted, err := models.SyncJournal(currentCommunityID(c))
+	if err != nil {
+		fmt.Println("同步总账失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("同步总账失败（已补记%d张凭证）: %s", posted, err.Error()),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已补记%d张凭证", posted),
+		"data":    gin.H{"posted": posted},
+	})
+}
+
+// parseDateRange 解析start和end参数（YYYY-MM-DD），缺省为本年1月1日至今天
+// 解析失败时直接返回400并返回false
+func parseDateRange(c *gin.Context) (time.Time, time.Time, bool) {
+	now := time.Now()
+	start := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	if v := c.Query("start"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的开始日期，格式应为YYYY-MM-DD",
+			})
+			return start, end, false
+		}
+		start = t
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的结束日期，格式应为YYYY-MM-DD",
+			})
+			return start, end, false
+		}
+		end = t
+	}
+
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "结束日期不能早于开始日期",
+		})
+		return start, end, false
+	}
+
+	return start, end, true
+}
+
+// formatAmount 格式化金额，保留两位小数
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
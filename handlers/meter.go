@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// MeterHandler 处理计量表和抄表相关请求
+type MeterHandler struct {
+	Meters repository.MeterRepository
+}
+
+// NewMeterHandler 创建新的MeterHandler
+func NewMeterHandler(meters repository.MeterRepository) *MeterHandler {
+	return &MeterHandler{
+		Meters: meters,
+	}
+}
+
+// readingUploadResult 表示批量导入中单行的处理结果
+type readingUploadResult struct {
+	Line    int                  `json:"line"`
+	MeterNo string               `json:"meter_no"`
+	Success bool                 `json:"success"`
+	Message string               `json:"message,omitempty"`
+	Reading *models.MeterReading `json:"reading,omitempty"`
+}
+
+// GetMeters 获取当前社区的计量表列表
+func (h *MeterHandler) GetMeters(c *gin.Context) {
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"resident_id", "fee_type_id"} {
+		if v := c.Query(key); v != "" {
+			if n, err := strconv.Atoi(v); err == nil {
+				filters[key] = n
+			}
+		}
+	}
+
+	meters, err := h.Meters.GetMeters(filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取计量表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    meters,
+	})
+}
+
+// CreateMeter 登记计量表
+func (h *MeterHandler) CreateMeter(c *gin.Context) {
+	var req models.MeterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	feeType, err := models.GetFeeTypeByID(req.FeeTypeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取收费项目失败: " + err.Error(),
+		})
+		return
+	}
+
+	if feeType == nil || feeType.PricingRule != models.PricingPerUsage {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "计量表只能关联按用量计费的收费项目",
+		})
+		return
+	}
+
+	id, err := models.CreateMeter(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "登记计量表失败: " + err.Error(),
+		})
+		return
+	}
+
+	meter, err := h.Meters.GetMeterByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取新登记的计量表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "计量表登记成功",
+		"data":    meter,
+	})
+}
+
+// DisableMeter 停用计量表
+func (h *MeterHandler) DisableMeter(c *gin.Context) {
+	meter, ok := h.findMeter(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DisableMeter(meter.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "停用计量表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "计量表已停用",
+	})
+}
+
+// GetMeterReadings 获取计量表的抄表记录
+func (h *MeterHandler) GetMeterReadings(c *gin.Context) {
+	meter, ok := h.findMeter(c)
+	if !ok {
+		return
+	}
+
+	readings, err := models.GetMeterReadings(meter.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取抄表记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    readings,
+	})
+}
+
+// CreateMeterReading 录入单条读数
+func (h *MeterHandler) CreateMeterReading(c *gin.Context) {
+	var req models.MeterReadingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	reading, err := models.CreateMeterReading(req, currentUserID(c), currentCommunityID(c))
+	if err != nil {
+		c.JSON(readingErrorStatus(err), gin.H{
+			"success": false,
+			"message": "录入读数失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "读数录入成功",
+		"data":    reading,
+	})
+}
+
+// UploadMeterReadings 通过CSV批量导入读数
+// CSV列依次为：表号,年,月,读数,抄表日期[,备注]，首行为表头
+// 每行独立校验，校验失败的行不影响其他行导入
+func (h *MeterHandler) UploadMeterReadings(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请上传CSV文件",
+		})
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "读取上传文件失败: " + err.Error(),
+		})
+		return
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	enteredBy := currentUserID(c)
+	communityID := currentCommunityID(c)
+	var results []readingUploadResult
+	imported := 0
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			results = append(results, readingUploadResult{Line: line, Message: "CSV格式错误: " + err.Error()})
+			continue
+		}
+		// 跳过表头
+		if line == 1 {
+			continue
+		}
+
+		req, err := parseReadingRecord(record)
+		if err != nil {
+			results = append(results, readingUploadResult{Line: line, Message: err.Error()})
+			continue
+		}
+
+		result := readingUploadResult{Line: line, MeterNo: req.MeterNo}
+		reading, err := models.CreateMeterReading(req, enteredBy, communityID)
+		if err != nil {
+			result.Message = err.Error()
+		} else {
+			result.Success = true
+			result.Reading = reading
+			imported++
+		}
+		results = append(results, result)
+	}
+
+	fmt.Printf("批量导入读数完成: 成功%d条，共%d条\n", imported, len(results))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("成功导入%d条，失败%d条", imported, len(results)-imported),
+		"data": gin.H{
+			"imported": imported,
+			"failed":   len(results) - imported,
+			"results":  results,
+		},
+	})
+}
+
+// GetTariffTiers 获取收费项目的阶梯价格
+func (h *MeterHandler) GetTariffTiers(c *gin.Context) {
+	feeTypeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的收费项目ID",
+		})
+		return
+	}
+
+	tiers, err := models.GetTariffTiers(feeTypeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取阶梯价格失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tiers,
+	})
+}
+
+// UpdateTariffTiers 替换收费项目的阶梯价格
+func (h *MeterHandler) UpdateTariffTiers(c *gin.Context) {
+	feeTypeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的收费项目ID",
+		})
+		return
+	}
+
+	var tiers []models.TariffTier
+	if err := c.ShouldBindJSON(&tiers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	// 档位上限必须递增，只有最后一档可以不封顶
+	var lower float64
+	for i, t := range tiers {
+		if t.UnitPrice < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("第%d档单价不能为负数", i+1),
+			})
+			return
+		}
+		if t.UpperLimit == nil {
+			if i != len(tiers)-1 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "只有最后一档可以不设上限",
+				})
+				return
+			}
+			continue
+		}
+		if *t.UpperLimit <= lower {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("第%d档上限必须大于上一档", i+1),
+			})
+			return
+		}
+		lower = *t.UpperLimit
+	}
+
+	if err := models.ReplaceTariffTiers(feeTypeID, tiers); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "更新阶梯价格失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "阶梯价格更新成功",
+	})
+}
+
+// findMeter 根据路径参数获取当前社区的计量表，失败时直接写入响应
+func (h *MeterHandler) findMeter(c *gin.Context) (*models.Meter, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的计量表ID",
+		})
+		return nil, false
+	}
+
+	meter, err := h.Meters.GetMeterByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取计量表失败: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	if meter == nil || meter.CommunityID != currentCommunityID(c) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "计量表不存在",
+		})
+		return nil, false
+	}
+
+	return meter, true
+}
+
+// parseReadingRecord 解析CSV中的一行读数
+func parseReadingRecord(record []string) (models.MeterReadingRequest, error) {
+	var req models.MeterReadingRequest
+	if len(record) < 5 {
+		return req, fmt.Errorf("列数不足，应为：表号,年,月,读数,抄表日期")
+	}
+
+	// 去除Excel导出文件的BOM
+	req.MeterNo = strings.TrimPrefix(strings.TrimSpace(record[0]), "\ufeff")
+
+	year, err := strconv.Atoi(strings.TrimSpace(record[1]))
+	if err != nil {
+		return req, fmt.Errorf("无效的年份: %s", record[1])
+	}
+	month, err := strconv.Atoi(strings.TrimSpace(record[2]))
+	if err != nil || month < 1 || month > 12 {
+		return req, fmt.Errorf("无效的月份: %s", record[2])
+	}
+	reading, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+	if err != nil || reading < 0 {
+		return req, fmt.Errorf("无效的读数: %s", record[3])
+	}
+
+	req.Year = year
+	req.Month = month
+	req.Reading = reading
+	req.ReadAt = strings.TrimSpace(record[4])
+	if len(record) > 5 {
+		req.Remark = strings.TrimSpace(record[5])
+	}
+
+	return req, nil
+}
+
+// readingErrorStatus 将读数校验错误映射为HTTP状态码
+func readingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrMeterNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrReadingDuplicate), errors.Is(err, models.ErrPeriodClosed), errors.Is(err, models.ErrNextReadingBilled):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrReadingRollback), errors.Is(err, models.ErrReadingAbnormal), errors.Is(err, models.ErrReadingExceedsNext):
+		return http.StatusUnprocessableEntity
+	}
+	if strings.HasPrefix(err.Error(), "无效的") {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,526 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/jobs"
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// NotificationHandler 处理通知中心相关请求
+type NotificationHandler struct {
+	Notifications repository.NotificationRepository
+	Dispatcher    *jobs.Notifications
+}
+
+// NewNotificationHandler 创建新的NotificationHandler
+func NewNotificationHandler(notifications repository.NotificationRepository, dispatcher *jobs.Notifications) *NotificationHandler {
+	return &NotificationHandler{
+		Notifications: notifications,
+		Dispatcher:    dispatcher,
+	}
+}
+
+// notificationStatus 返回通知相关写操作失败时的HTTP状态码
+func notificationStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInAppMessageNotFound), errors.Is(err, models.ErrNotificationTemplateNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrNotificationTemplateExists), errors.Is(err, models.ErrNotificationNotFailed):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidNotificationTemplate):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetTemplates 获取通知模板列表，支持按event、channel、locale过滤
+func (h *NotificationHandler) GetTemplates(c *gin.Context) {
+	filters := make(map[string]interface{})
+	for _, key := range []string{"event", "channel", "locale"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+
+	templates, err := models.GetNotificationTemplates(filters, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取通知模板失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    templates,
+	})
+}
+
+// CreateTemplate 创建通知模板
+func (h *NotificationHandler) CreateTemplate(c *gin.Context) {
+	var req models.NotificationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := models.CreateNotificationTemplate(req)
+	if err != nil {
+		c.JSON(notificationStatus(err), gin.H{
+			"success": false,
+			"message": "创建通知模板失败: " + err.Error(),
+		})
+		return
+	}
+
+	template, err := models.GetNotificationTemplateByID(id)
+	if err != nil || template == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取通知模板失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "通知模板创建成功",
+		"data":    template,
+	})
+}
+
+// UpdateTemplate 更新通知模板
+func (h *NotificationHandler) UpdateTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的模板ID",
+		})
+		return
+	}
+
+	var req models.NotificationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	found, err := models.UpdateNotificationTemplate(id, req)
+	if err != nil {
+		c.JSON(notificationStatus(err), gin.H{
+			"success": false,
+			"message": "更新通知模板失败: " + err.Error(),
+		})
+		return
+	}
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "通知模板不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "通知模板更新成功",
+	})
+}
+
+// GetNotifications 获取当前社区住户的通知发送记录
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"status", "channel", "event"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+
+	notifications, total, err := h.Notifications.GetNotifications(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取通知记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  notifications,
+			"total": total,
+		},
+	})
+}
+
+// GetNotification 获取通知详情
+func (h *NotificationHandler) GetNotification(c *gin.Context) {
+	notification, ok := h.loadNotification(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    notification,
+	})
+}
+
+// SendNotification 按事件模板向当前社区的一个或多个住户发送通知
+// 使用general事件时在vars中填写title和content即可直接发送自定义内容
+func (h *NotificationHandler) SendNotification(c *gin.Context) {
+	var req struct {
+		ResidentIDs []int             `json:"resident_ids" binding:"required,min=1,max=500"`
+		Event       string            `json:"event" binding:"required,max=50"`
+		Vars        map[string]string `json:"vars"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	// 先校验全部住户，避免部分住户已入队后才发现越权
+	for _, residentID := range req.ResidentIDs {
+		if _, ok := loadCommunityResident(c, residentID); !ok {
+			return
+		}
+	}
+
+	queued, failed := 0, 0
+	var lastErr error
+	for _, residentID := range req.ResidentIDs {
+		n, err := h.Dispatcher.Notify(residentID, req.Event, req.Vars)
+		queued += n
+		if err != nil {
+			// 没有模板时所有住户都会失败，直接返回
+			if errors.Is(err, models.ErrNotificationTemplateNotFound) {
+				c.JSON(notificationStatus(err), gin.H{
+					"success": false,
+					"message": "发送通知失败: " + err.Error(),
+				})
+				return
+			}
+			fmt.Println("生成通知失败:", err)
+			failed++
+			lastErr = err
+		}
+	}
+
+	message := fmt.Sprintf("已将%d条通知加入发送队列", queued)
+	if failed > 0 {
+		message += fmt.Sprintf("，%d户生成失败: %v", failed, lastErr)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data": gin.H{
+			"queued": queued,
+			"failed": failed,
+		},
+	})
+}
+
+// Dispatch 立即发送队列中到期的通知
+func (h *NotificationHandler) Dispatch(c *gin.Context) {
+	result, err := h.Dispatcher.Run(time.Now())
+	if err != nil {
+		fmt.Println("发送通知失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "发送通知失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("发送成功%d条，等待重试%d条，失败%d条", result.Sent, result.Retrying, result.Failed),
+		"data":    result,
+	})
+}
+
+// RetryNotification 将发送失败的通知重新放回队列，再发送一次
+func (h *NotificationHandler) RetryNotification(c *gin.Context) {
+	notification, ok := h.loadNotification(c)
+	if !ok {
+		return
+	}
+
+	if err := models.RetryNotification(notification.ID); err != nil {
+		c.JSON(notificationStatus(err), gin.H{
+			"success": false,
+			"message": "重试通知失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "通知已重新加入发送队列",
+	})
+}
+
+// GetResidentPreference 获取当前社区住户的通知偏好
+func (h *NotificationHandler) GetResidentPreference(c *gin.Context) {
+	id, ok := preferenceResidentID(c)
+	if !ok {
+		return
+	}
+	respondPreference(c, id)
+}
+
+// UpdateResidentPreference 更新当前社区住户的通知偏好
+func (h *NotificationHandler) UpdateResidentPreference(c *gin.Context) {
+	id, ok := preferenceResidentID(c)
+	if !ok {
+		return
+	}
+	savePreference(c, id)
+}
+
+// GetMyPreference 住户端：获取本户的通知偏好
+func (h *NotificationHandler) GetMyPreference(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+	respondPreference(c, residentID)
+}
+
+// UpdateMyPreference 住户端：更新本户的通知偏好
+func (h *NotificationHandler) UpdateMyPreference(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+	savePreference(c, residentID)
+}
+
+// GetMyMessages 住户端：获取本户的站内信，unread=true时只返回未读
+func (h *NotificationHandler) GetMyMessages(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	messages, total, unread, err := models.GetInAppMessages(residentID, page, pageSize, c.Query("unread") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取站内信失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":   messages,
+			"total":  total,
+			"unread": unread,
+		},
+	})
+}
+
+// ReadMyMessage 住户端：将站内信标记为已读
+func (h *NotificationHandler) ReadMyMessage(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的站内信ID",
+		})
+		return
+	}
+
+	if err := models.MarkInAppMessagesRead(residentID, id); err != nil {
+		c.JSON(notificationStatus(err), gin.H{
+			"success": false,
+			"message": "标记已读失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已标记为已读",
+	})
+}
+
+// ReadAllMyMessages 住户端：将全部站内信标记为已读
+func (h *NotificationHandler) ReadAllMyMessages(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	if err := models.MarkInAppMessagesRead(residentID, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "标记已读失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已全部标记为已读",
+	})
+}
+
+// respondPreference 返回住户的通知偏好
+func respondPreference(c *gin.Context, residentID int) {
+	pref, err := models.GetNotificationPreference(residentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取通知偏好失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    pref,
+	})
+}
+
+// savePreference 保存住户的通知偏好并返回最新偏好
+func savePreference(c *gin.Context, residentID int) {
+	var req models.NotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.SaveNotificationPreference(residentID, req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "保存通知偏好失败: " + err.Error(),
+		})
+		return
+	}
+
+	respondPreference(c, residentID)
+}
+
+// preferenceResidentID 解析路径参数中的住户ID并确认住户属于当前社区，失败时直接返回错误响应
+func preferenceResidentID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的住户ID",
+		})
+		return 0, false
+	}
+	if _, ok := loadCommunityResident(c, id); !ok {
+		return 0, false
+	}
+	return id, true
+}
+
+// loadCommunityResident 加载当前社区的住户，住户不存在或属于其他社区时直接返回404
+func loadCommunityResident(c *gin.Context, id int) (*models.Resident, bool) {
+	resident, err := models.GetResidentByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("住户%d不存在", id),
+		})
+		return nil, false
+	}
+	return resident, true
+}
+
+// loadNotification 根据路径参数id加载当前社区住户的通知，加载失败时直接返回错误响应并返回false
+func (h *NotificationHandler) loadNotification(c *gin.Context) (*models.Notification, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的通知ID",
+		})
+		return nil, false
+	}
+
+	notification, err := h.Notifications.GetNotificationByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取通知详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if notification == nil || notification.CommunityID != currentCommunityID(c) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "通知不存在",
+		})
+		return nil, false
+	}
+
+	return notification, true
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/jobs"
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// ParcelHandler 处理前台快递代收相关请求
+type ParcelHandler struct {
+	Parcels   repository.ParcelRepository
+	Reminders *jobs.Parcels
+}
+
+// NewParcelHandler 创建新的ParcelHandler
+func NewParcelHandler(parcels repository.ParcelRepository, reminders *jobs.Parcels) *ParcelHandler {
+	return &ParcelHandler{
+		Parcels:   parcels,
+		Reminders: reminders,
+	}
+}
+
+// parcelStatus 返回包裹写操作失败时的HTTP状态码
+func parcelStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidPickupCode):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrParcelExists), errors.Is(err, models.ErrParcelNotWaiting):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidParcel):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetParcels 获取当前社区的包裹列表，overdue=true时只返回超期未取的包裹
+func (h *ParcelHandler) GetParcels(c *gin.Context) {
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"status", "carrier", "keyword"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	if c.Query("overdue") == "true" {
+		filters["received_before"] = h.Reminders.OverdueBefore(time.Now())
+	}
+	h.listParcels(c, filters)
+}
+
+// GetOverdueParcels 获取当前社区超期未取的包裹列表
+func (h *ParcelHandler) GetOverdueParcels(c *gin.Context) {
+	h.listParcels(c, map[string]interface{}{
+		"community_id":    currentCommunityID(c),
+		"received_before": h.Reminders.OverdueBefore(time.Now()),
+	})
+}
+
+// listParcels 按条件查询包裹并返回分页结果
+func (h *ParcelHandler) listParcels(c *gin.Context, filters map[string]interface{}) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	parcels, total, err := h.Parcels.GetParcels(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取包裹列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  parcels,
+			"total": total,
+		},
+	})
+}
+
+// GetParcel 获取包裹详情
+func (h *ParcelHandler) GetParcel(c *gin.Context) {
+	parcel, ok := h.loadParcel(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    parcel,
+	})
+}
+
+// CreateParcel 登记到件包裹，生成取件码并通知住户
+func (h *ParcelHandler) CreateParcel(c *gin.Context) {
+	var req models.ParcelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	id, err := models.CreateParcel(req, currentUserID(c))
+	if err != nil {
+		c.JSON(parcelStatus(err), gin.H{
+			"success": false,
+			"message": "登记包裹失败: " + err.Error(),
+		})
+		return
+	}
+
+	parcel, err := h.Parcels.GetParcelByID(id)
+	if err != nil || parcel == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取包裹详情失败",
+		})
+		return
+	}
+
+	// 通知失败不影响登记，前台可稍后重新通知
+	message := "包裹登记成功，已通知住户"
+	if err := h.Reminders.NotifyArrival(parcel); err != nil {
+		fmt.Println("发送到件通知失败:", err)
+		message = "包裹登记成功，但通知住户失败: " + err.Error()
+	} else if parcel, err = h.Parcels.GetParcelByID(id); err != nil || parcel == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取包裹详情失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": message,
+		"data":    parcel,
+	})
+}
+
+// NotifyParcel 重新发送到件通知
+func (h *ParcelHandler) NotifyParcel(c *gin.Context) {
+	parcel, ok := h.loadParcel(c)
+	if !ok {
+		return
+	}
+	if parcel.Status != models.ParcelWaiting {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": models.ErrParcelNotWaiting.Error(),
+		})
+		return
+	}
+
+	if err := h.Reminders.NotifyArrival(parcel); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "发送到件通知失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已通知住户取件",
+	})
+}
+
+// Pickup 凭取件码确认取件
+func (h *ParcelHandler) Pickup(c *gin.Context) {
+	var req models.ParcelPickupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	parcel, err := models.PickupParcel(req.PickupCode, req.PickerName, currentUserID(c), currentCommunityID(c))
+	if err != nil {
+		c.JSON(parcelStatus(err), gin.H{
+			"success": false,
+			"message": "确认取件失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "取件成功",
+		"data":    parcel,
+	})
+}
+
+// ReturnParcel 将包裹退回快递公司
+func (h *ParcelHandler) ReturnParcel(c *gin.Context) {
+	parcel, ok := h.loadParcel(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Note string `json:"note" binding:"max=200"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.ReturnParcel(parcel.ID, req.Note); err != nil {
+		c.JSON(parcelStatus(err), gin.H{
+			"success": false,
+			"message": "退回包裹失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "包裹已退回",
+	})
+}
+
+// Remind 立即向超期未取包裹的住户发送催取提醒
+func (h *ParcelHandler) Remind(c *gin.Context) {
+	result, err := h.Reminders.Run(time.Now())
+	if err != nil {
+		fmt.Println("快递超期催取失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "快递超期催取失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已将%d条催取提醒加入发送队列", result.Sent),
+		"data":    result,
+	})
+}
+
+// GetMyParcels 住户端：获取本户的包裹，含取件码
+func (h *ParcelHandler) GetMyParcels(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	filters := map[string]interface{}{"resident_id": residentID}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	h.listParcels(c, filters)
+}
+
+// loadParcel 根据路径参数id加载当前社区的包裹，加载失败时直接返回错误响应并返回false
+func (h *ParcelHandler) loadParcel(c *gin.Context) (*models.Parcel, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的包裹ID",
+		})
+		return nil, false
+	}
+
+	parcel, err := h.Parcels.GetParcelByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取包裹详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if parcel == nil || parcel.CommunityID != currentCommunityID(c) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "包裹不存在",
+		})
+		return nil, false
+	}
+
+	return parcel, true
+}
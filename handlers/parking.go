@@ -0,0 +1,522 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+)
+
+// ParkingHandler 处理车位、车辆和车位租赁合同相关请求
+type ParkingHandler struct{}
+
+// NewParkingHandler 创建新的ParkingHandler
+func NewParkingHandler() *ParkingHandler {
+	return &ParkingHandler{}
+}
+
+// parkingStatus 返回车位相关写操作失败时的HTTP状态码
+func parkingStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrSpaceNotFound), errors.Is(err, models.ErrContractNotFound), errors.Is(err, models.ErrVehicleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrSpaceExists), errors.Is(err, models.ErrPlateExists),
+		errors.Is(err, models.ErrContractOverlap), errors.Is(err, models.ErrContractNotActive):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrSpaceType), errors.Is(err, models.ErrInvalidContract),
+		errors.Is(err, models.ErrInvalidPlate), errors.Is(err, models.ErrVehicleSpace):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetSpaces 获取车位列表及当前使用情况
+func (h *ParkingHandler) GetSpaces(c *gin.Context) {
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	if spaceType := c.Query("type"); spaceType != "" {
+		filters["type"] = spaceType
+	}
+	if zone := c.Query("zone"); zone != "" {
+		filters["zone"] = zone
+	}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	for _, key := range []string{"enabled", "occupied"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v == "true"
+		}
+	}
+
+	spaces, err := models.GetParkingSpaces(time.Now(), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取车位列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    spaces,
+	})
+}
+
+// CreateSpace 新增车位
+func (h *ParkingHandler) CreateSpace(c *gin.Context) {
+	var req models.ParkingSpaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := models.CreateParkingSpace(req, currentCommunityID(c))
+	if err != nil {
+		c.JSON(parkingStatus(err), gin.H{
+			"success": false,
+			"message": "新增车位失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "车位新增成功",
+		"data": gin.H{
+			"id": id,
+		},
+	})
+}
+
+// UpdateSpace 修改车位
+func (h *ParkingHandler) UpdateSpace(c *gin.Context) {
+	id, ok := parkingID(c, "无效的车位ID")
+	if !ok {
+		return
+	}
+
+	var req models.ParkingSpaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.UpdateParkingSpace(id, req, currentCommunityID(c)); err != nil {
+		c.JSON(parkingStatus(err), gin.H{
+			"success": false,
+			"message": "修改车位失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "车位修改成功",
+	})
+}
+
+// AssignSpace 登记或解除产权车位的业主
+func (h *ParkingHandler) AssignSpace(c *gin.Context) {
+	id, ok := parkingID(c, "无效的车位ID")
+	if !ok {
+		return
+	}
+
+	var req models.SpaceAssignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if req.ResidentID > 0 {
+		resident, err := models.GetResidentByID(req.ResidentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取住户详情失败: " + err.Error(),
+			})
+			return
+		}
+		if resident == nil || !inCurrentCommunity(c, resident) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "住户不存在",
+			})
+			return
+		}
+	}
+
+	if err := models.AssignParkingSpace(id, req.ResidentID, currentCommunityID(c)); err != nil {
+		c.JSON(parkingStatus(err), gin.H{
+			"success": false,
+			"message": "登记车位业主失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "车位业主登记成功",
+	})
+}
+
+// SetOccupancy 登记访客车位的占用状态
+func (h *ParkingHandler) SetOccupancy(c *gin.Context) {
+	id, ok := parkingID(c, "无效的车位ID")
+	if !ok {
+		return
+	}
+
+	var req models.SpaceOccupancyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.SetSpaceOccupancy(id, req.Occupied, currentCommunityID(c)); err != nil {
+		c.JSON(parkingStatus(err), gin.H{
+			"success": false,
+			"message": "登记车位占用状态失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "车位占用状态已更新",
+	})
+}
+
+// GetOccupancy 获取车位使用率
+func (h *ParkingHandler) GetOccupancy(c *gin.Context) {
+	occupancy, err := models.GetParkingOccupancy(time.Now(), currentCommunityID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取车位使用率失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    occupancy,
+	})
+}
+
+// GetContracts 获取车位租赁合同列表
+func (h *ParkingHandler) GetContracts(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"space_id", "resident_id"} {
+		if v := c.Query(key); v != "" {
+			if id, err := strconv.Atoi(v); err == nil {
+				filters[key] = id
+			}
+		}
+	}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+
+	contracts, total, err := models.GetParkingContracts(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取租赁合同失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  contracts,
+			"total": total,
+		},
+	})
+}
+
+// CreateContract 签订车位租赁合同
+func (h *ParkingHandler) CreateContract(c *gin.Context) {
+	var req models.ParkingContractRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	id, err := models.CreateParkingContract(req, currentUserID(c))
+	if err != nil {
+		c.JSON(parkingStatus(err), gin.H{
+			"success": false,
+			"message": "签订租赁合同失败: " + err.Error(),
+		})
+		return
+	}
+
+	contract, err := models.GetParkingContractByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取新签订的合同失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "租赁合同签订成功",
+		"data":    contract,
+	})
+}
+
+// TerminateContract 提前终止车位租赁合同
+func (h *ParkingHandler) TerminateContract(c *gin.Context) {
+	id, ok := parkingID(c, "无效的合同ID")
+	if !ok {
+		return
+	}
+
+	var req models.TerminateContractRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	endDate, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的终止日期",
+		})
+		return
+	}
+
+	if err := models.TerminateParkingContract(id, endDate, currentCommunityID(c)); err != nil {
+		c.JSON(parkingStatus(err), gin.H{
+			"success": false,
+			"message": "终止租赁合同失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "租赁合同已终止",
+	})
+}
+
+// GetVehicles 获取车辆列表
+func (h *ParkingHandler) GetVehicles(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	if plate := c.Query("plate_no"); plate != "" {
+		filters["plate_no"] = plate
+	}
+
+	vehicles, total, err := models.GetVehicles(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取车辆列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  vehicles,
+			"total": total,
+		},
+	})
+}
+
+// CreateVehicle 登记车辆
+func (h *ParkingHandler) CreateVehicle(c *gin.Context) {
+	var req models.VehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	id, err := models.CreateVehicle(req)
+	if err != nil {
+		c.JSON(parkingStatus(err), gin.H{
+			"success": false,
+			"message": "登记车辆失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "车辆登记成功",
+		"data": gin.H{
+			"id": id,
+		},
+	})
+}
+
+// UpdateVehicle 修改车辆信息
+func (h *ParkingHandler) UpdateVehicle(c *gin.Context) {
+	id, ok := parkingID(c, "无效的车辆ID")
+	if !ok {
+		return
+	}
+
+	var req models.VehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	if err := models.UpdateVehicle(id, req, currentCommunityID(c)); err != nil {
+		c.JSON(parkingStatus(err), gin.H{
+			"success": false,
+			"message": "修改车辆失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "车辆修改成功",
+	})
+}
+
+// DeleteVehicle 删除车辆
+func (h *ParkingHandler) DeleteVehicle(c *gin.Context) {
+	id, ok := parkingID(c, "无效的车辆ID")
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteVehicle(id, currentCommunityID(c)); err != nil {
+		c.JSON(parkingStatus(err), gin.H{
+			"success": false,
+			"message": "删除车辆失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "车辆删除成功",
+	})
+}
+
+// parkingID 解析路径参数id，失败时直接返回错误响应并返回false
+func parkingID(c *gin.Context, message string) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": message,
+		})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/jobs"
+	"community-backward/models"
+)
+
+// PaymentPlanHandler 处理分期还款和滞纳金相关请求
+type PaymentPlanHandler struct {
+	Plans    *jobs.PaymentPlans
+	LateFees *jobs.LateFees
+}
+
+// NewPaymentPlanHandler 创建新的PaymentPlanHandler
+func NewPaymentPlanHandler(plans *jobs.PaymentPlans, lateFees *jobs.LateFees) *PaymentPlanHandler {
+	return &PaymentPlanHandler{
+		Plans:    plans,
+		LateFees: lateFees,
+	}
+}
+
+// GetPaymentPlans 获取分期计划列表
+func (h *PaymentPlanHandler) GetPaymentPlans(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := make(map[string]interface{})
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+
+	plans, total, err := models.GetPaymentPlans(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取分期计划失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  plans,
+			"total": total,
+		},
+	})
+}
+
+// GetPaymentPlan 获取分期计划详情
+func (h *PaymentPlanHandler) GetPaymentPlan(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的分期计划ID",
+		})
+		return
+	}
+
+	plan, err := models.GetPaymentPlanByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取分期计划失败: " + err.Error(),
+		})
+		return
+	}
+
+	if plan == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "分期计划不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    plan,
+	})
+}
+
+// CreatePaymentPlan 为住户当前欠费创建分期计划
+func (h *PaymentPlanHandler) CreatePaymentPlan(c *gin.Context) {
+	var req models.PaymentPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	id, err := models.CreatePaymentPlan(req, currentUserID(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, models.ErrPlanExists):
+			status = http.StatusConflict
+		case errors.Is(err, models.ErrNoArrears), errors.Is(err, models.ErrInvalidSchedule):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "创建分期计划失败: " + err.Error(),
+		})
+		return
+	}
+
+	plan, err := models.GetPaymentPlanByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取新创建的分期计划失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "分期计划创建成功",
+		"data":    plan,
+	})
+}
+
+// CancelPaymentPlan 取消分期计划
+func (h *PaymentPlanHandler) CancelPaymentPlan(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的分期计划ID",
+		})
+		return
+	}
+
+	if err := models.CancelPaymentPlan(id, currentUserID(c)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrPlanNotActive) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "取消分期计划失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "分期计划已取消",
+	})
+}
+
+// GetMissedInstalments 获取执行中计划的逾期分期
+func (h *PaymentPlanHandler) GetMissedInstalments(c *gin.Context) {
+	list, err := models.GetMissedInstalments(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取逾期分期失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    list,
+	})
+}
+
+// CheckPaymentPlans 立即更新分期还款进度并发送逾期提醒
+func (h *PaymentPlanHandler) CheckPaymentPlans(c *gin.Context) {
+	result, err := h.Plans.Run(time.Now())
+	if err != nil {
+		fmt.Println("分期还款检查失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "分期还款检查失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "分期还款检查完成",
+		"data":    result,
+	})
+}
+
+// AssessLateFees 计收当前社区指定月份的滞纳金
+func (h *PaymentPlanHandler) AssessLateFees(c *gin.Context) {
+	var req models.LateFeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := h.LateFees.Run(req.Year, req.Month, currentCommunityID(c))
+	if err != nil {
+		fmt.Println("计收滞纳金失败:", err)
+		c.JSON(mutationStatus(err), gin.H{
+			"success": false,
+			"message": "计收滞纳金失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已生成%d张滞纳金账单", result.Created),
+		"data":    result,
+	})
+}
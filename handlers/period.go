@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// PeriodHandler 处理账期结账相关请求
+type PeriodHandler struct {
+	Periods   repository.PeriodRepository
+	AuditLogs repository.AuditLogRepository
+}
+
+// NewPeriodHandler 创建新的PeriodHandler
+func NewPeriodHandler(periods repository.PeriodRepository, auditLogs repository.AuditLogRepository) *PeriodHandler {
+	return &PeriodHandler{
+		Periods:   periods,
+		AuditLogs: auditLogs,
+	}
+}
+
+// GetPeriods 获取当前社区指定年份的账期状态
+func (h *PeriodHandler) GetPeriods(c *gin.Context) {
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(time.Now().Year())))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的年份参数",
+		})
+		return
+	}
+
+	periods, err := h.Periods.GetAccountingPeriods(currentCommunityID(c), year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取账期失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    periods,
+	})
+}
+
+// ClosePeriod 当前社区月结或年结，month为0时结账全年12个月
+func (h *PeriodHandler) ClosePeriod(c *gin.Context) {
+	var req models.ClosePeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	months := []int{req.Month}
+	if req.Month == 0 {
+		months = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	}
+
+	// 不能结账尚未结束的月份
+	now := time.Now()
+	for _, m := range months {
+		end := time.Date(req.Year, time.Month(m)+1, 1, 0, 0, 0, 0, time.Local)
+		if !now.After(end) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("%d年%d月尚未结束，不能结账", req.Year, m),
+			})
+			return
+		}
+	}
+
+	userID := currentUserID(c)
+	communityID := currentCommunityID(c)
+	for _, m := range months {
+		// 年结时跳过已经结账的月份
+		if req.Month == 0 {
+			closed, err := h.Periods.IsPeriodClosed(communityID, req.Year, m)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"message": "检查账期状态失败: " + err.Error(),
+				})
+				return
+			}
+			if closed {
+				continue
+			}
+		}
+		if err := h.Periods.ClosePeriod(communityID, req.Year, m, userID); err != nil {
+			fmt.Println("结账失败:", err)
+			c.JSON(mutationStatus(err), gin.H{
+				"success": false,
+				"message": fmt.Sprintf("%d年%d月结账失败: %s", req.Year, m, err.Error()),
+			})
+			return
+		}
+	}
+
+	detail := fmt.Sprintf("%d年%d月", req.Year, req.Month)
+	if req.Month == 0 {
+		detail = fmt.Sprintf("%d年全年", req.Year)
+	}
+	h.audit(c, "close", req.Year, req.Month, "结账"+detail)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": detail + "结账成功",
+	})
+}
+
+// ReopenPeriod 当前社区反结账，仅限管理员，操作记录审计日志
+func (h *PeriodHandler) ReopenPeriod(c *gin.Context) {
+	var req models.ReopenPeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	reopened, err := h.Periods.ReopenPeriod(currentCommunityID(c), req.Year, req.Month, currentUserID(c), req.Reason)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrLaterPeriodClosed) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "反结账失败: " + err.Error(),
+		})
+		return
+	}
+
+	if !reopened {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("%d年%d月未结账", req.Year, req.Month),
+		})
+		return
+	}
+
+	h.audit(c, "reopen", req.Year, req.Month, fmt.Sprintf("反结账%d年%d月，原因: %s", req.Year, req.Month, req.Reason))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("%d年%d月已反结账", req.Year, req.Month),
+	})
+}
+
+// audit 记录账期操作的审计日志，实体ID为“社区ID/年-月”，失败时仅打印日志
+func (h *PeriodHandler) audit(c *gin.Context, action string, year, month int, detail string) {
+	err := h.AuditLogs.CreateAuditLog(models.AuditLog{
+		UserID:   int(currentUserID(c)),
+		Username: currentUsername(c),
+		Action:   action,
+		Entity:   "accounting_period",
+		EntityID: fmt.Sprintf("%d/%d-%02d", currentCommunityID(c), year, month),
+		Detail:   detail,
+	})
+	if err != nil {
+		fmt.Println("记录审计日志失败:", err)
+	}
+}
+
+// GetAuditLogs 获取审计日志
+func (h *PeriodHandler) GetAuditLogs(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := make(map[string]interface{})
+	if entity := c.Query("entity"); entity != "" {
+		filters["entity"] = entity
+	}
+	if action := c.Query("action"); action != "" {
+		filters["action"] = action
+	}
+	if v := c.Query("user_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["user_id"] = id
+		}
+	}
+
+	logs, total, err := h.AuditLogs.GetAuditLogs(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取审计日志失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  logs,
+			"total": total,
+		},
+	})
+}
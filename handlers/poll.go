@@ -0,0 +1,543 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// PollHandler 处理业主表决和问卷调查相关请求
+type PollHandler struct {
+	Polls repository.PollRepository
+}
+
+// NewPollHandler 创建新的PollHandler
+func NewPollHandler(polls repository.PollRepository) *PollHandler {
+	return &PollHandler{
+		Polls: polls,
+	}
+}
+
+// pollStatus 返回表决写操作失败时的HTTP状态码
+func pollStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrPollNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrPollState), errors.Is(err, models.ErrAlreadyVoted):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidPoll):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrPollNotEligible):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// GetPolls 获取当前社区的表决列表，支持按kind和status过滤
+func (h *PollHandler) GetPolls(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"kind", "status"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+
+	polls, total, err := h.Polls.GetPolls(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取表决列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  polls,
+			"total": total,
+		},
+	})
+}
+
+// GetPoll 获取表决详情
+func (h *PollHandler) GetPoll(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    poll,
+	})
+}
+
+// CreatePoll 在当前社区创建表决草稿
+func (h *PollHandler) CreatePoll(c *gin.Context) {
+	var req models.PollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := models.CreatePoll(req, currentUserID(c), currentCommunityID(c))
+	if err != nil {
+		c.JSON(pollStatus(err), gin.H{
+			"success": false,
+			"message": "创建表决失败: " + err.Error(),
+		})
+		return
+	}
+
+	poll, err := h.Polls.GetPollByID(id)
+	if err != nil || poll == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取表决详情失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "表决创建成功",
+		"data":    poll,
+	})
+}
+
+// UpdatePoll 修改表决草稿
+func (h *PollHandler) UpdatePoll(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	var req models.PollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.UpdatePoll(poll.ID, req); err != nil {
+		c.JSON(pollStatus(err), gin.H{
+			"success": false,
+			"message": "修改表决失败: " + err.Error(),
+		})
+		return
+	}
+
+	poll, err := h.Polls.GetPollByID(poll.ID)
+	if err != nil || poll == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取表决详情失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "表决修改成功",
+		"data":    poll,
+	})
+}
+
+// OpenPoll 开始投票
+func (h *PollHandler) OpenPoll(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	if err := models.OpenPoll(poll.ID); err != nil {
+		c.JSON(pollStatus(err), gin.H{
+			"success": false,
+			"message": "开始投票失败: " + err.Error(),
+		})
+		return
+	}
+
+	poll, err := h.Polls.GetPollByID(poll.ID)
+	if err != nil || poll == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取表决详情失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已开始投票",
+		"data":    poll,
+	})
+}
+
+// ClosePoll 结束投票并写入表决结果
+func (h *PollHandler) ClosePoll(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	result, err := models.ClosePoll(poll.ID, currentUserID(c))
+	if err != nil {
+		c.JSON(pollStatus(err), gin.H{
+			"success": false,
+			"message": "结束投票失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "投票已结束",
+		"data":    result,
+	})
+}
+
+// CancelPoll 取消表决
+func (h *PollHandler) CancelPoll(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	if err := models.CancelPoll(poll.ID); err != nil {
+		c.JSON(pollStatus(err), gin.H{
+			"success": false,
+			"message": "取消表决失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "表决已取消",
+	})
+}
+
+// GetBallots 获取表决的选票及哈希，匿名表决不返回住户信息
+func (h *PollHandler) GetBallots(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	ballots, total, err := models.GetPollBallots(poll.ID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取选票失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  ballots,
+			"total": total,
+		},
+	})
+}
+
+// CastBallot 工作人员代录住户的纸质选票
+func (h *PollHandler) CastBallot(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	var req models.PollBallotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+	if req.ResidentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请指定投票住户",
+		})
+		return
+	}
+
+	if err := models.CastBallot(poll.ID, req.ResidentID, req.Choices, models.BallotSourceStaff, currentUserID(c)); err != nil {
+		c.JSON(pollStatus(err), gin.H{
+			"success": false,
+			"message": "录入选票失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "选票录入成功",
+	})
+}
+
+// GetResults 获取计票结果，已结束的表决返回结果记录，投票中的表决返回实时计票
+func (h *PollHandler) GetResults(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+	pollResults(c, poll)
+}
+
+// Verify 校验选票哈希链和表决结果是否被篡改
+func (h *PollHandler) Verify(c *gin.Context) {
+	poll, ok := h.loadPoll(c)
+	if !ok {
+		return
+	}
+
+	verification, err := models.VerifyPoll(poll)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "校验表决失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    verification,
+	})
+}
+
+// GetMyPolls 住户端：获取本户可参与的表决
+func (h *PollHandler) GetMyPolls(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	polls, total, err := models.GetResidentPolls(residentID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取表决列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  polls,
+			"total": total,
+		},
+	})
+}
+
+// GetMyPoll 住户端：获取表决详情及本户投票情况
+func (h *PollHandler) GetMyPoll(c *gin.Context) {
+	poll, ok := loadResidentPoll(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    poll,
+	})
+}
+
+// CastMyBallot 住户端：本户投票
+func (h *PollHandler) CastMyBallot(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+	poll, ok := loadResidentPoll(c)
+	if !ok {
+		return
+	}
+
+	var req models.PollBallotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.CastBallot(poll.ID, residentID, req.Choices, models.BallotSourceResident, currentUserID(c)); err != nil {
+		c.JSON(pollStatus(err), gin.H{
+			"success": false,
+			"message": "投票失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "投票成功",
+	})
+}
+
+// GetMyPollResults 住户端：获取已结束表决的结果
+func (h *PollHandler) GetMyPollResults(c *gin.Context) {
+	poll, ok := loadResidentPoll(c)
+	if !ok {
+		return
+	}
+	if poll.Status != models.PollClosed {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "投票结束后才能查看结果",
+		})
+		return
+	}
+	pollResults(c, poll)
+}
+
+// pollResults 返回表决的计票结果
+func pollResults(c *gin.Context, poll *models.Poll) {
+	if poll.Status == models.PollClosed {
+		result, err := models.GetPollResult(poll.ID)
+		if err != nil || result == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取表决结果失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    result,
+		})
+		return
+	}
+
+	tally, err := models.GetPollTally(poll)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "计票失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"poll_id": poll.ID,
+			"tally":   tally,
+		},
+	})
+}
+
+// loadPoll 根据路径参数id加载当前社区的表决，加载失败时直接返回错误响应并返回false
+func (h *PollHandler) loadPoll(c *gin.Context) (*models.Poll, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的表决ID",
+		})
+		return nil, false
+	}
+
+	poll, err := h.Polls.GetPollByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取表决详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if poll == nil || poll.CommunityID != currentCommunityID(c) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "表决不存在",
+		})
+		return nil, false
+	}
+
+	return poll, true
+}
+
+// loadResidentPoll 住户端：加载本户可参与的表决，加载失败时直接返回错误响应并返回false
+func loadResidentPoll(c *gin.Context) (*models.Poll, bool) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return nil, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的表决ID",
+		})
+		return nil, false
+	}
+
+	poll, err := models.GetResidentPoll(id, residentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取表决详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if poll == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "表决不存在",
+		})
+		return nil, false
+	}
+
+	return poll, true
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// PropertyFeeHandler 处理缴费记录相关请求
+type PropertyFeeHandler struct {
+	Fees      repository.FeeRepository
+	FeeTypes  repository.FeeTypeRepository
+	Residents repository.ResidentRepository
+	AuditLogs repository.AuditLogRepository
+}
+
+// NewPropertyFeeHandler 创建新的PropertyFeeHandler
+func NewPropertyFeeHandler(fees repository.FeeRepository, feeTypes repository.FeeTypeRepository, residents repository.ResidentRepository, auditLogs repository.AuditLogRepository) *PropertyFeeHandler {
+	return &PropertyFeeHandler{
+		Fees:      fees,
+		FeeTypes:  feeTypes,
+		Residents: residents,
+		AuditLogs: auditLogs,
+	}
+}
+
+// GetPropertyFees 获取缴费记录列表
+func (h *PropertyFeeHandler) GetPropertyFees(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	if name := c.Query("resident_name"); name != "" {
+		filters["resident_name"] = name
+	}
+	if v := c.Query("fee_type_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["fee_type_id"] = id
+		}
+	}
+	if v := c.Query("start_date"); v != "" {
+		if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+			filters["start_date"] = t
+		}
+	}
+	if v := c.Query("end_date"); v != "" {
+		if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+			filters["end_date"] = t
+		}
+	}
+	if v := c.Query("status"); v != "" {
+		if status, err := strconv.Atoi(v); err == nil {
+			filters["status"] = status
+		}
+	}
+
+	fees, total, err := h.Fees.GetPropertyFees(page, pageSize, filters)
+	if err != nil {
+		fmt.Println("获取缴费记录失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取缴费记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  fees,
+			"total": total,
+		},
+	})
+}
+
+// CreatePropertyFee 登记缴费
+func (h *PropertyFeeHandler) CreatePropertyFee(c *gin.Context) {
+	var req models.PropertyFeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := h.Residents.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	if req.FeeTypeID > 0 {
+		feeType, err := h.FeeTypes.GetFeeTypeByID(req.FeeTypeID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取收费项目失败: " + err.Error(),
+			})
+			return
+		}
+		if feeType == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "收费项目不存在",
+			})
+			return
+		}
+	}
+
+	id, err := h.Fees.CreatePropertyFee(req)
+	if err != nil {
+		fmt.Println("登记缴费失败:", err)
+		c.JSON(mutationStatus(err), gin.H{
+			"success": false,
+			"message": "登记缴费失败: " + err.Error(),
+		})
+		return
+	}
+
+	fee, err := h.Fees.GetPropertyFeeByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取新登记的缴费记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "缴费登记成功",
+		"data":    fee,
+	})
+}
+
+// RefundPropertyFee 缴费退款，记录审计日志
+func (h *PropertyFeeHandler) RefundPropertyFee(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的缴费记录ID",
+		})
+		return
+	}
+
+	var req models.RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	fee, err := h.Fees.GetPropertyFeeByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取缴费记录失败: " + err.Error(),
+		})
+		return
+	}
+	if fee == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "缴费记录不存在",
+		})
+		return
+	}
+
+	// 其他社区住户的缴费记录按不存在处理
+	resident, err := h.Residents.GetResidentByID(fee.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "缴费记录不存在",
+		})
+		return
+	}
+
+	if err := h.Fees.RefundPropertyFee(id, req); err != nil {
+		status := mutationStatus(err)
+		if errors.Is(err, models.ErrPaymentNotRefundable) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "退款失败: " + err.Error(),
+		})
+		return
+	}
+
+	err = h.AuditLogs.CreateAuditLog(models.AuditLog{
+		UserID:   int(currentUserID(c)),
+		Username: currentUsername(c),
+		Action:   "refund",
+		Entity:   "property_fee",
+		EntityID: strconv.Itoa(id),
+		Detail:   fmt.Sprintf("退款%.2f元，原因: %s", fee.Amount, req.Reason),
+	})
+	if err != nil {
+		fmt.Println("记录审计日志失败:", err)
+	}
+
+	fee, err = h.Fees.GetPropertyFeeByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取缴费记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "退款成功",
+		"data":    fee,
+	})
+}
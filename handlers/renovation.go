@@ -0,0 +1,458 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+)
+
+// RenovationHandler 处理装修申请、押金和验收相关请求
+type RenovationHandler struct {
+	Renovations repository.RenovationRepository
+}
+
+// NewRenovationHandler 创建新的RenovationHandler
+func NewRenovationHandler(renovations repository.RenovationRepository) *RenovationHandler {
+	return &RenovationHandler{
+		Renovations: renovations,
+	}
+}
+
+// renovationStatus 返回装修申请写操作失败时的HTTP状态码
+func renovationStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrRenovationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrRenovationExists), errors.Is(err, models.ErrRenovationState),
+		errors.Is(err, models.ErrPaymentNotRefundable):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidRenovation):
+		return http.StatusBadRequest
+	}
+	return mutationStatus(err)
+}
+
+// GetRenovations 获取当前社区的装修申请列表
+func (h *RenovationHandler) GetRenovations(c *gin.Context) {
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	for _, key := range []string{"status", "deposit_status", "keyword"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	h.listRenovations(c, filters)
+}
+
+// listRenovations 按条件查询装修申请并返回分页结果
+func (h *RenovationHandler) listRenovations(c *gin.Context, filters map[string]interface{}) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	renovations, total, err := h.Renovations.GetRenovations(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取装修申请列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  renovations,
+			"total": total,
+		},
+	})
+}
+
+// GetRenovation 获取装修申请详情，含验收记录
+func (h *RenovationHandler) GetRenovation(c *gin.Context) {
+	renovation, ok := h.loadRenovation(c, 0)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    renovation,
+	})
+}
+
+// CreateRenovation 物业代住户登记装修申请
+func (h *RenovationHandler) CreateRenovation(c *gin.Context) {
+	var req models.RenovationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if req.ResidentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请指定装修住户",
+		})
+		return
+	}
+	resident, err := models.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	h.createRenovation(c, req, models.RenovationSourceStaff)
+}
+
+// createRenovation 提交装修申请并返回申请详情
+func (h *RenovationHandler) createRenovation(c *gin.Context, req models.RenovationRequest, source string) {
+	id, err := models.CreateRenovation(req, source, currentUserID(c))
+	if err != nil {
+		c.JSON(renovationStatus(err), gin.H{
+			"success": false,
+			"message": "提交装修申请失败: " + err.Error(),
+		})
+		return
+	}
+
+	renovation, err := h.Renovations.GetRenovationByID(id)
+	if err != nil || renovation == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取装修申请详情失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "装修申请已提交",
+		"data":    renovation,
+	})
+}
+
+// ReviewRenovation 审批装修申请
+func (h *RenovationHandler) ReviewRenovation(c *gin.Context) {
+	renovation, ok := h.loadRenovation(c, 0)
+	if !ok {
+		return
+	}
+
+	var req models.RenovationReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.ReviewRenovation(renovation.ID, req, currentUserID(c)); err != nil {
+		c.JSON(renovationStatus(err), gin.H{
+			"success": false,
+			"message": "审批装修申请失败: " + err.Error(),
+		})
+		return
+	}
+
+	message := "装修申请已驳回"
+	if req.Approved {
+		message = "装修申请已批准"
+	}
+	h.respondRenovation(c, renovation.ID, message)
+}
+
+// CollectDeposit 收取装修押金，收取后进入施工
+func (h *RenovationHandler) CollectDeposit(c *gin.Context) {
+	renovation, ok := h.loadRenovation(c, 0)
+	if !ok {
+		return
+	}
+
+	var req models.RenovationDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.CollectRenovationDeposit(renovation.ID, req); err != nil {
+		c.JSON(renovationStatus(err), gin.H{
+			"success": false,
+			"message": "收取押金失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.respondRenovation(c, renovation.ID, "押金已收取，可以开始施工")
+}
+
+// CreateInspection 登记装修验收，竣工验收通过后待退押金
+func (h *RenovationHandler) CreateInspection(c *gin.Context) {
+	renovation, ok := h.loadRenovation(c, 0)
+	if !ok {
+		return
+	}
+
+	var req models.RenovationInspectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if _, err := models.CreateRenovationInspection(renovation.ID, req, currentUserID(c)); err != nil {
+		c.JSON(renovationStatus(err), gin.H{
+			"success": false,
+			"message": "登记验收失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.respondRenovation(c, renovation.ID, "验收记录已登记")
+}
+
+// RefundDeposit 退还装修押金
+func (h *RenovationHandler) RefundDeposit(c *gin.Context) {
+	renovation, ok := h.loadRenovation(c, 0)
+	if !ok {
+		return
+	}
+
+	var req models.RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.RefundRenovationDeposit(renovation.ID, req); err != nil {
+		c.JSON(renovationStatus(err), gin.H{
+			"success": false,
+			"message": "退还押金失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.respondRenovation(c, renovation.ID, "押金已退还")
+}
+
+// ForfeitDeposit 没收装修押金，需填写原因
+func (h *RenovationHandler) ForfeitDeposit(c *gin.Context) {
+	renovation, ok := h.loadRenovation(c, 0)
+	if !ok {
+		return
+	}
+
+	var req models.RenovationReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请填写没收押金的原因",
+		})
+		return
+	}
+
+	if err := models.ForfeitRenovationDeposit(renovation.ID, req.Reason); err != nil {
+		c.JSON(renovationStatus(err), gin.H{
+			"success": false,
+			"message": "没收押金失败: " + err.Error(),
+		})
+		return
+	}
+
+	h.respondRenovation(c, renovation.ID, "押金已没收")
+}
+
+// CancelRenovation 取消未完工的装修申请
+func (h *RenovationHandler) CancelRenovation(c *gin.Context) {
+	renovation, ok := h.loadRenovation(c, 0)
+	if !ok {
+		return
+	}
+	h.cancelRenovation(c, renovation)
+}
+
+// cancelRenovation 取消装修申请并返回申请详情
+func (h *RenovationHandler) cancelRenovation(c *gin.Context, renovation *models.Renovation) {
+	var req models.RenovationReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if err := models.CancelRenovation(renovation.ID, req.Reason); err != nil {
+		c.JSON(renovationStatus(err), gin.H{
+			"success": false,
+			"message": "取消装修申请失败: " + err.Error(),
+		})
+		return
+	}
+
+	message := "装修申请已取消"
+	if renovation.DepositStatus == models.DepositHeld {
+		message = "装修申请已取消，请到物业办理押金退还"
+	}
+	h.respondRenovation(c, renovation.ID, message)
+}
+
+// GetMyRenovations 住户端：获取本户的装修申请
+func (h *RenovationHandler) GetMyRenovations(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	filters := map[string]interface{}{"resident_id": residentID}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	h.listRenovations(c, filters)
+}
+
+// GetMyRenovation 住户端：获取本户的装修申请详情
+func (h *RenovationHandler) GetMyRenovation(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	renovation, ok := h.loadRenovation(c, residentID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    renovation,
+	})
+}
+
+// CreateMyRenovation 住户端：提交本户的装修申请，押金按物业规定金额收取
+func (h *RenovationHandler) CreateMyRenovation(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	var req models.RenovationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	req.ResidentID = residentID
+	req.DepositAmount = 0
+	h.createRenovation(c, req, models.RenovationSourceResident)
+}
+
+// CancelMyRenovation 住户端：取消本户尚未开工的装修申请
+func (h *RenovationHandler) CancelMyRenovation(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	renovation, ok := h.loadRenovation(c, residentID)
+	if !ok {
+		return
+	}
+	if renovation.Status != models.RenovationSubmitted && renovation.Status != models.RenovationApproved {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "已开工的装修申请请联系物业取消",
+		})
+		return
+	}
+	h.cancelRenovation(c, renovation)
+}
+
+// respondRenovation 返回操作成功的消息和最新的申请详情
+func (h *RenovationHandler) respondRenovation(c *gin.Context, id int, message string) {
+	renovation, err := h.Renovations.GetRenovationByID(id)
+	if err != nil || renovation == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取装修申请详情失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    renovation,
+	})
+}
+
+// loadRenovation 根据路径参数id加载当前社区的装修申请，residentID大于0时只能加载该住户的申请
+// 加载失败时直接返回错误响应并返回false
+func (h *RenovationHandler) loadRenovation(c *gin.Context, residentID int) (*models.Renovation, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的装修申请ID",
+		})
+		return nil, false
+	}
+
+	renovation, err := h.Renovations.GetRenovationByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取装修申请详情失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if renovation == nil || renovation.CommunityID != currentCommunityID(c) || (residentID > 0 && renovation.ResidentID != residentID) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "装修申请不存在",
+		})
+		return nil, false
+	}
+
+	return renovation, true
+}
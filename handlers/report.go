@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+)
+
+// ReportHandler 处理财务报表相关请求
+type ReportHandler struct{}
+
+// NewReportHandler 创建新的ReportHandler
+func NewReportHandler() *ReportHandler {
+	return &ReportHandler{}
+}
+
+// GetAgingReport 获取应收账款账龄报表，format=csv时导出CSV
+func (h *ReportHandler) GetAgingReport(c *gin.Context) {
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+
+	report, err := models.GetAgingReport(asOf, currentCommunityID(c))
+	if err != nil {
+		fmt.Println("获取账龄报表失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取账龄报表失败: " + err.Error(),
+		})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		rows := [][]string{{"楼栋", "欠费户数", "0-30天", "31-60天", "61-90天", "90天以上", "合计"}}
+		for _, b := range report.Buildings {
+			rows = append(rows, append([]string{b.Building, strconv.Itoa(b.Households)}, agingCells(b.AgingBuckets)...))
+		}
+		rows = append(rows, append([]string{"合计", ""}, agingCells(report.Total)...))
+		writeCSV(c, "aging-"+report.AsOf+".csv", rows)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// GetAgingHouseholds 获取账龄明细，可按楼栋和账龄区间下钻，format=csv时导出CSV
+func (h *ReportHandler) GetAgingHouseholds(c *gin.Context) {
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+
+	bucket := c.Query("bucket")
+	switch bucket {
+	case "", models.AgingBucket0To30, models.AgingBucket31To60, models.AgingBucket61To90, models.AgingBucketOver90:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的账龄区间: " + bucket,
+		})
+		return
+	}
+
+	households, err := models.GetAgingHouseholds(asOf, c.Query("building"), bucket, currentCommunityID(c))
+	if err != nil {
+		fmt.Println("获取账龄明细失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取账龄明细失败: " + err.Error(),
+		})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		rows := [][]string{{"住户ID", "姓名", "楼栋", "单元", "房号", "0-30天", "31-60天", "61-90天", "90天以上", "合计"}}
+		for _, hh := range households {
+			rows = append(rows, append([]string{strconv.Itoa(hh.ResidentID), hh.Name, hh.BlockNumber, hh.UnitNumber, hh.HouseNumber},
+				agingCells(hh.AgingBuckets)...))
+		}
+		writeCSV(c, "aging-households-"+asOf.Format("2006-01-02")+".csv", rows)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  households,
+			"total": len(households),
+		},
+	})
+}
+
+// agingCells 将账龄金额格式化为CSV单元格
+func agingCells(b models.AgingBuckets) []string {
+	return []string{
+		strconv.FormatFloat(b.Days0To30, 'f', 2, 64),
+		strconv.FormatFloat(b.Days31To60, 'f', 2, 64),
+		strconv.FormatFloat(b.Days61To90, 'f', 2, 64),
+		strconv.FormatFloat(b.Over90, 'f', 2, 64),
+		strconv.FormatFloat(b.Total, 'f', 2, 64),
+	}
+}
+
+// writeCSV 以附件形式输出CSV，带BOM以便Excel正确识别中文
+func writeCSV(c *gin.Context, filename string, rows [][]string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	c.Writer.WriteString("\ufeff")
+	w := csv.NewWriter(c.Writer)
+	w.WriteAll(rows)
+}
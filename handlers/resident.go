@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,14 +11,21 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"community-backward/models"
+	"community-backward/repository"
 )
 
 // ResidentHandler 处理住户相关请求
-type ResidentHandler struct{}
+type ResidentHandler struct {
+	Residents repository.ResidentRepository
+	Users     repository.UserRepository
+}
 
 // NewResidentHandler 创建新的ResidentHandler
-func NewResidentHandler() *ResidentHandler {
-	return &ResidentHandler{}
+func NewResidentHandler(residents repository.ResidentRepository, users repository.UserRepository) *ResidentHandler {
+	return &ResidentHandler{
+		Residents: residents,
+		Users:     users,
+	}
 }
 
 // GetResidents 获取住户列表
@@ -41,8 +49,8 @@ func (h *ResidentHandler) GetResidents(c *gin.Context) {
 	}
 	fmt.Println("每页条数:", pageSize)
 
-	// 获取过滤参数
-	filters := make(map[string]interface{})
+	// 获取过滤参数，只查询当前社区的住户
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
 
 	// 姓名过滤
 	if name := c.Query("name"); name != "" {
@@ -62,6 +70,17 @@ func (h *ResidentHandler) GetResidents(c *gin.Context) {
 		fmt.Println("单元过滤:", unit)
 	}
 
+	// 状态过滤，支持0/1或"欠费"/"正常"
+	if status := c.Query("status"); status != "" {
+		switch status {
+		case "0", "欠费":
+			filters["status"] = 0
+		case "1", "正常":
+			filters["status"] = 1
+		}
+		fmt.Println("状态过滤:", status)
+	}
+
 	// 面积过滤
 	if area := c.Query("area"); area != "" {
 		if areaFloat, err := strconv.ParseFloat(area, 64); err == nil {
@@ -91,7 +110,7 @@ func (h *ResidentHandler) GetResidents(c *gin.Context) {
 	fmt.Println("最终过滤条件:", filters)
 
 	// 获取住户列表
-	residents, total, err := models.GetResidents(page, pageSize, filters)
+	residents, total, err := h.Residents.GetResidents(page, pageSize, filters)
 	if err != nil {
 		fmt.Println("获取住户列表失败:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -127,7 +146,7 @@ func (h *ResidentHandler) GetResidentByID(c *gin.Context) {
 	}
 
 	// 获取住户
-	resident, err := models.GetResidentByID(id)
+	resident, err := h.Residents.GetResidentByID(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -136,7 +155,7 @@ func (h *ResidentHandler) GetResidentByID(c *gin.Context) {
 		return
 	}
 
-	if resident == nil {
+	if resident == nil || !inCurrentCommunity(c, resident) {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"message": "住户不存在",
@@ -173,7 +192,7 @@ func (h *ResidentHandler) CreateResident(c *gin.Context) {
 	fmt.Println("解析后的请求参数:", req)
 
 	// 创建住户
-	id, err := models.CreateResident(req)
+	id, err := h.Residents.CreateResident(req, currentCommunityID(c))
 	if err != nil {
 		fmt.Println("创建住户失败:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -186,7 +205,7 @@ func (h *ResidentHandler) CreateResident(c *gin.Context) {
 	fmt.Println("住户创建成功，ID:", id)
 
 	// 获取新创建的住户
-	resident, err := models.GetResidentByID(id)
+	resident, err := h.Residents.GetResidentByID(id)
 	if err != nil {
 		fmt.Println("获取新创建的住户失败:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -222,7 +241,7 @@ func (h *ResidentHandler) UpdateResident(c *gin.Context) {
 	}
 
 	// 检查住户是否存在
-	resident, err := models.GetResidentByID(id)
+	resident, err := h.Residents.GetResidentByID(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -231,7 +250,7 @@ func (h *ResidentHandler) UpdateResident(c *gin.Context) {
 		return
 	}
 
-	if resident == nil {
+	if resident == nil || !inCurrentCommunity(c, resident) {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"message": "住户不存在",
@@ -250,7 +269,7 @@ func (h *ResidentHandler) UpdateResident(c *gin.Context) {
 	}
 
 	// 更新住户
-	if err := models.UpdateResident(id, req); err != nil {
+	if err := h.Residents.UpdateResident(id, req); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
 			"message": "更新住户失败: " + err.Error(),
@@ -259,7 +278,7 @@ func (h *ResidentHandler) UpdateResident(c *gin.Context) {
 	}
 
 	// 获取更新后的住户
-	updatedResident, err := models.GetResidentByID(id)
+	updatedResident, err := h.Residents.GetResidentByID(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -289,7 +308,7 @@ func (h *ResidentHandler) DeleteResident(c *gin.Context) {
 	}
 
 	// 检查住户是否存在
-	resident, err := models.GetResidentByID(id)
+	resident, err := h.Residents.GetResidentByID(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -298,7 +317,7 @@ func (h *ResidentHandler) DeleteResident(c *gin.Context) {
 		return
 	}
 
-	if resident == nil {
+	if resident == nil || !inCurrentCommunity(c, resident) {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
 			"message": "住户不存在",
@@ -307,8 +326,8 @@ func (h *ResidentHandler) DeleteResident(c *gin.Context) {
 	}
 
 	// 删除住户
-	if err := models.DeleteResident(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	if err := h.Residents.DeleteResident(id); err != nil {
+		c.JSON(mutationStatus(err), gin.H{
 			"success": false,
 			"message": "删除住户失败: " + err.Error(),
 		})
@@ -321,3 +340,62 @@ func (h *ResidentHandler) DeleteResident(c *gin.Context) {
 		"message": "住户删除成功",
 	})
 }
+
+// CreateAccount 为住户开通住户端登录账号
+func (h *ResidentHandler) CreateAccount(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的住户ID",
+		})
+		return
+	}
+
+	var req models.ResidentAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := h.Residents.GetResidentByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	userID, err := h.Users.CreateResidentAccount(id, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrUsernameExists) || errors.Is(err, models.ErrAccountExists) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "开通住户账号失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "住户账号开通成功",
+		"data": gin.H{
+			"user_id":  userID,
+			"username": req.Username,
+		},
+	})
+}
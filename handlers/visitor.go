@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+	"community-backward/utils"
+)
+
+// VisitorHandler 处理访客登记、通行码核验和进出记录相关请求
+type VisitorHandler struct {
+	Visitors repository.VisitorRepository
+	Signer   *utils.PassSigner
+}
+
+// NewVisitorHandler 创建新的VisitorHandler
+func NewVisitorHandler(visitors repository.VisitorRepository, signer *utils.PassSigner) *VisitorHandler {
+	return &VisitorHandler{
+		Visitors: visitors,
+		Signer:   signer,
+	}
+}
+
+// visitorStatus 返回访客相关操作失败时的HTTP状态码
+func visitorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrPassNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrPassUnusable), errors.Is(err, models.ErrVisitorInside), errors.Is(err, models.ErrVisitorNotInside):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidPass), errors.Is(err, models.ErrInvalidPlate), errors.Is(err, utils.ErrInvalidPassCode):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetPasses 获取当前社区的访客通行证列表
+func (h *VisitorHandler) GetPasses(c *gin.Context) {
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	h.listPasses(c, filters)
+}
+
+// listPasses 按条件查询访客通行证并返回分页结果
+func (h *VisitorHandler) listPasses(c *gin.Context, filters map[string]interface{}) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	if keyword := c.Query("keyword"); keyword != "" {
+		filters["keyword"] = keyword
+	}
+
+	passes, total, err := h.Visitors.GetVisitorPasses(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取访客通行证失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  passes,
+			"total": total,
+		},
+	})
+}
+
+// GetPass 获取访客通行证详情，含通行码
+func (h *VisitorHandler) GetPass(c *gin.Context) {
+	pass, ok := h.loadPass(c, 0)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    pass,
+	})
+}
+
+// CreatePass 前台登记访客，生成通行码
+func (h *VisitorHandler) CreatePass(c *gin.Context) {
+	var req models.VisitorPassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if req.ResidentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请指定被访住户",
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(req.ResidentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败: " + err.Error(),
+		})
+		return
+	}
+	if resident == nil || !inCurrentCommunity(c, resident) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "住户不存在",
+		})
+		return
+	}
+
+	h.create(c, req, "staff")
+}
+
+// create 创建访客通行证并返回带通行码的详情
+func (h *VisitorHandler) create(c *gin.Context, req models.VisitorPassRequest, source string) {
+	id, err := models.CreateVisitorPass(req, currentUserID(c), source)
+	if err != nil {
+		c.JSON(visitorStatus(err), gin.H{
+			"success": false,
+			"message": "登记访客失败: " + err.Error(),
+		})
+		return
+	}
+
+	pass, err := h.Visitors.GetVisitorPassByID(id)
+	if err != nil || pass == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取访客通行证失败",
+		})
+		return
+	}
+	pass.Code = h.Signer.Sign(pass.ID, pass.ValidUntil)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "访客登记成功",
+		"data":    pass,
+	})
+}
+
+// RevokePass 作废访客通行证
+func (h *VisitorHandler) RevokePass(c *gin.Context) {
+	pass, ok := h.loadPass(c, 0)
+	if !ok {
+		return
+	}
+	h.revoke(c, pass.ID)
+}
+
+// revoke 作废通行证并返回结果
+func (h *VisitorHandler) revoke(c *gin.Context, id int) {
+	if err := models.RevokeVisitorPass(id); err != nil {
+		c.JSON(visitorStatus(err), gin.H{
+			"success": false,
+			"message": "作废通行证失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "通行证已作废",
+	})
+}
+
+// Verify 门岗核验通行码，登记访客入园或离开，只认当前社区住户的通行证
+func (h *VisitorHandler) Verify(c *gin.Context) {
+	var req models.VisitorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	passID, validUntil, err := h.Signer.Parse(req.Code)
+	if err != nil {
+		c.JSON(visitorStatus(err), gin.H{
+			"success": false,
+			"message": "核验失败: " + err.Error(),
+		})
+		return
+	}
+
+	log, err := models.VerifyVisitorPass(passID, validUntil, req.Action, currentUserID(c), currentCommunityID(c))
+	if err != nil {
+		c.JSON(visitorStatus(err), gin.H{
+			"success": false,
+			"message": "核验失败: " + err.Error(),
+		})
+		return
+	}
+
+	message := "核验通过，已登记入园"
+	if req.Action == models.VisitorExit {
+		message = "已登记离开"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    log,
+	})
+}
+
+// GetLogs 获取当前社区的访客进出记录，支持keyword、resident_id、start、end（YYYY-MM-DD）和inside=true过滤
+func (h *VisitorHandler) GetLogs(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	if keyword := c.Query("keyword"); keyword != "" {
+		filters["keyword"] = keyword
+	}
+	if v := c.Query("resident_id"); v != "" {
+		if id, err := strconv.Atoi(v); err == nil {
+			filters["resident_id"] = id
+		}
+	}
+	for _, key := range []string{"start", "end"} {
+		if v := c.Query(key); v != "" {
+			t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "日期格式应为YYYY-MM-DD",
+				})
+				return
+			}
+			filters[key] = t
+		}
+	}
+	if c.Query("inside") == "true" {
+		filters["inside"] = true
+	}
+
+	logs, total, err := h.Visitors.GetVisitorLogs(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取访客记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  logs,
+			"total": total,
+		},
+	})
+}
+
+// GetMyPasses 住户端：获取本户登记的访客
+func (h *VisitorHandler) GetMyPasses(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+	h.listPasses(c, map[string]interface{}{"resident_id": residentID})
+}
+
+// GetMyPass 住户端：获取本户访客通行证详情，含通行码
+func (h *VisitorHandler) GetMyPass(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	pass, ok := h.loadPass(c, residentID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    pass,
+	})
+}
+
+// CreateMyPass 住户端：为本户预约访客
+func (h *VisitorHandler) CreateMyPass(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	var req models.VisitorPassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	req.ResidentID = residentID
+	h.create(c, req, "resident")
+}
+
+// RevokeMyPass 住户端：作废本户的访客通行证
+func (h *VisitorHandler) RevokeMyPass(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	pass, ok := h.loadPass(c, residentID)
+	if !ok {
+		return
+	}
+	h.revoke(c, pass.ID)
+}
+
+// loadPass 根据路径参数id加载当前社区的通行证并附上通行码，residentID大于0时只能加载该住户的通行证
+// 加载失败时直接返回错误响应并返回false
+func (h *VisitorHandler) loadPass(c *gin.Context, residentID int) (*models.VisitorPass, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的通行证ID",
+		})
+		return nil, false
+	}
+
+	pass, err := h.Visitors.GetVisitorPassByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取访客通行证失败: " + err.Error(),
+		})
+		return nil, false
+	}
+	if pass == nil || pass.CommunityID != currentCommunityID(c) || (residentID > 0 && pass.ResidentID != residentID) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "访客通行证不存在",
+		})
+		return nil, false
+	}
+
+	pass.Code = h.Signer.Sign(pass.ID, pass.ValidUntil)
+	return pass, true
+}
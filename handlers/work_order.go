@@ -0,0 +1,541 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/utils"
+)
+
+// WorkOrderHandler 处理报修工单相关请求
+type WorkOrderHandler struct {
+	// UploadDir 工单照片的保存目录
+	UploadDir string
+}
+
+// NewWorkOrderHandler 创建新的WorkOrderHandler
+func NewWorkOrderHandler(uploadDir string) *WorkOrderHandler {
+	return &WorkOrderHandler{
+		UploadDir: uploadDir,
+	}
+}
+
+// GetCategories 获取启用的工单分类
+func (h *WorkOrderHandler) GetCategories(c *gin.Context) {
+	categories, err := models.GetWorkOrderCategories(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取工单分类失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    categories,
+	})
+}
+
+// GetAssignees 获取可指派的维修人员
+func (h *WorkOrderHandler) GetAssignees(c *gin.Context) {
+	users, err := models.GetUsersByRole(models.RoleMaintenance)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取维修人员失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    users,
+	})
+}
+
+// GetWorkOrders 获取工单列表，维修人员只能看到指派给自己的工单
+func (h *WorkOrderHandler) GetWorkOrders(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"community_id": currentCommunityID(c)}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	if priority := c.Query("priority"); priority != "" {
+		filters["priority"] = priority
+	}
+	if keyword := c.Query("keyword"); keyword != "" {
+		filters["keyword"] = keyword
+	}
+	if c.Query("open") == "true" {
+		filters["open"] = true
+	}
+	for _, key := range []string{"category_id", "assignee_id", "resident_id"} {
+		if v := c.Query(key); v != "" {
+			if id, err := strconv.Atoi(v); err == nil {
+				filters[key] = id
+			}
+		}
+	}
+	if currentRole(c) == models.RoleMaintenance {
+		filters["assignee_id"] = int(currentUserID(c))
+	}
+
+	orders, total, err := models.GetWorkOrders(page, pageSize, filters)
+	if err != nil {
+		fmt.Println("获取工单列表失败:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取工单列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  orders,
+			"total": total,
+		},
+	})
+}
+
+// GetWorkOrder 获取工单详情，含处理记录和照片
+func (h *WorkOrderHandler) GetWorkOrder(c *gin.Context) {
+	order, ok := h.loadOrder(c, true)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    order,
+	})
+}
+
+// CreateWorkOrder 登记工单，可代住户登记（source为resident）
+func (h *WorkOrderHandler) CreateWorkOrder(c *gin.Context) {
+	var req models.WorkOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if req.LocationType == models.LocationHouse && req.ResidentID > 0 {
+		resident, err := models.GetResidentByID(req.ResidentID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取住户详情失败: " + err.Error(),
+			})
+			return
+		}
+		if resident == nil || !inCurrentCommunity(c, resident) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "住户不存在",
+			})
+			return
+		}
+		// 未填写联系人时默认为住户本人
+		if req.ContactName == "" {
+			req.ContactName = resident.Name
+		}
+		if req.ContactPhone == "" {
+			req.ContactPhone = resident.Phone
+		}
+	}
+
+	h.create(c, req)
+}
+
+// create 创建工单并返回新工单详情
+func (h *WorkOrderHandler) create(c *gin.Context, req models.WorkOrderRequest) {
+	id, err := models.CreateWorkOrder(req, currentUserID(c), currentCommunityID(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrInvalidWorkOrder) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "创建工单失败: " + err.Error(),
+		})
+		return
+	}
+
+	order, err := models.GetWorkOrderByID(id, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取新创建的工单失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "工单创建成功",
+		"data":    order,
+	})
+}
+
+// GetMyWorkOrders 住户端：获取本户的报修工单
+func (h *WorkOrderHandler) GetMyWorkOrders(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 {
+		pageSize = 10
+	}
+
+	filters := map[string]interface{}{"resident_id": residentID}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	if c.Query("open") == "true" {
+		filters["open"] = true
+	}
+
+	orders, total, err := models.GetWorkOrders(page, pageSize, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取工单列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"list":  orders,
+			"total": total,
+		},
+	})
+}
+
+// GetMyWorkOrder 住户端：获取本户的工单详情，含处理记录和照片
+func (h *WorkOrderHandler) GetMyWorkOrder(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	order, ok := h.loadOrder(c, true)
+	if !ok {
+		return
+	}
+	if order.ResidentID != residentID {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "工单不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    order,
+	})
+}
+
+// CreateMyWorkOrder 住户端：为本户房屋提交报修
+func (h *WorkOrderHandler) CreateMyWorkOrder(c *gin.Context) {
+	residentID, ok := residentAccount(c)
+	if !ok {
+		return
+	}
+
+	var req models.WorkOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	resident, err := models.GetResidentByID(residentID)
+	if err != nil || resident == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取住户详情失败",
+		})
+		return
+	}
+
+	// 住户端报修只能针对本户房屋，优先级由物业评估
+	req.LocationType = models.LocationHouse
+	req.ResidentID = residentID
+	req.Location = ""
+	req.Priority = ""
+	req.Source = models.WorkOrderSourceResident
+	if req.ContactName == "" {
+		req.ContactName = resident.Name
+	}
+	if req.ContactPhone == "" {
+		req.ContactPhone = resident.Phone
+	}
+
+	h.create(c, req)
+}
+
+// Transition 返回执行指定工单操作的处理函数
+// 维修人员只能开始处理、完成和重新处理指派给自己的工单
+func (h *WorkOrderHandler) Transition(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		order, ok := h.loadOrder(c, false)
+		if !ok {
+			return
+		}
+
+		if currentRole(c) == models.RoleMaintenance {
+			switch action {
+			case models.WorkOrderActionStart, models.WorkOrderActionResolve, models.WorkOrderActionReopen:
+			default:
+				c.JSON(http.StatusForbidden, gin.H{
+					"success": false,
+					"message": "权限不足",
+				})
+				return
+			}
+		}
+
+		var req models.WorkOrderActionRequest
+		if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "无效的请求参数: " + err.Error(),
+			})
+			return
+		}
+
+		err := models.TransitionWorkOrder(order.ID, action, req, currentUserID(c), currentUsername(c))
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, models.ErrInvalidTransition):
+				status = http.StatusConflict
+			case errors.Is(err, models.ErrInvalidAssignee), errors.Is(err, models.ErrInvalidWorkOrder):
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{
+				"success": false,
+				"message": "工单操作失败: " + err.Error(),
+			})
+			return
+		}
+
+		order, err = models.GetWorkOrderByID(order.ID, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取工单失败: " + err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "工单操作成功",
+			"data":    order,
+		})
+	}
+}
+
+// CreateComment 添加工单评论
+func (h *WorkOrderHandler) CreateComment(c *gin.Context) {
+	order, ok := h.loadOrder(c, false)
+	if !ok {
+		return
+	}
+
+	var req models.WorkOrderCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的请求参数: " + err.Error(),
+		})
+		return
+	}
+
+	if _, err := models.CreateWorkOrderComment(order.ID, req.Content, currentUserID(c), currentUsername(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "添加评论失败: " + err.Error(),
+		})
+		return
+	}
+
+	comments, err := models.GetWorkOrderComments(order.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取处理记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "评论添加成功",
+		"data":    comments,
+	})
+}
+
+// UploadPhoto 上传工单照片，表单字段为file
+func (h *WorkOrderHandler) UploadPhoto(c *gin.Context) {
+	order, ok := h.loadOrder(c, false)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请上传照片",
+		})
+		return
+	}
+
+	path, contentType, err := utils.SaveImage(file, h.UploadDir, filepath.Join("work_orders", strconv.Itoa(order.ID)))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, utils.ErrInvalidImage) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "保存照片失败: " + err.Error(),
+		})
+		return
+	}
+
+	id, err := models.CreateWorkOrderPhoto(models.WorkOrderPhoto{
+		OrderID:      order.ID,
+		FilePath:     path,
+		OriginalName: file.Filename,
+		ContentType:  contentType,
+		Size:         file.Size,
+		UploadedBy:   int(currentUserID(c)),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "保存照片记录失败: " + err.Error(),
+		})
+		return
+	}
+
+	photo, err := models.GetWorkOrderPhoto(order.ID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取照片失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "照片上传成功",
+		"data":    photo,
+	})
+}
+
+// GetPhoto 下载工单照片
+func (h *WorkOrderHandler) GetPhoto(c *gin.Context) {
+	order, ok := h.loadOrder(c, false)
+	if !ok {
+		return
+	}
+
+	photoID, err := strconv.Atoi(c.Param("photoId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的照片ID",
+		})
+		return
+	}
+
+	photo, err := models.GetWorkOrderPhoto(order.ID, photoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取照片失败: " + err.Error(),
+		})
+		return
+	}
+	if photo == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "照片不存在",
+		})
+		return
+	}
+
+	c.Header("Content-Type", photo.ContentType)
+	c.File(filepath.Join(h.UploadDir, photo.FilePath))
+}
+
+// loadOrder 根据路径参数id加载当前社区的工单，维修人员只能访问指派给自己的工单
+// 加载失败时直接返回错误响应并返回false
+func (h *WorkOrderHandler) loadOrder(c *gin.Context, withDetail bool) (*models.WorkOrder, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的工单ID",
+		})
+		return nil, false
+	}
+
+	order, err := models.GetWorkOrderByID(id, withDetail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取工单失败: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	if order == nil || order.CommunityID != currentCommunityID(c) ||
+		(currentRole(c) == models.RoleMaintenance && order.AssigneeID != int(currentUserID(c))) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "工单不存在",
+		})
+		return nil, false
+	}
+
+	return order, true
+}
@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"community-backward/access"
+	"community-backward/models"
+)
+
+// AccessSyncResult 表示一次凭证下发的结果
+type AccessSyncResult struct {
+	Sent   int `json:"sent"`   // 下发成功的控制器数
+	Failed int `json:"failed"` // 下发失败的控制器数
+}
+
+// AccessSync 门禁凭证下发任务：凭证变更后下发到所有控制器，失败的下发由定时任务重试
+type AccessSync struct {
+	Controllers *access.Registry
+	// MaxAttempts 同一操作的最大下发次数，超过后不再自动重试，需人工处理
+	MaxAttempts int
+}
+
+// NewAccessSync 创建新的门禁凭证下发任务
+func NewAccessSync(controllers *access.Registry, maxAttempts int) *AccessSync {
+	return &AccessSync{
+		Controllers: controllers,
+		MaxAttempts: maxAttempts,
+	}
+}
+
+// Job 返回可供Scheduler调度的定时任务
+func (s *AccessSync) Job(interval time.Duration) Job {
+	return Job{
+		Name:     "门禁凭证下发重试",
+		Interval: interval,
+		Run: func() error {
+			result, err := s.Run(time.Now())
+			if err != nil {
+				return err
+			}
+			if result.Sent+result.Failed > 0 {
+				log.Printf("门禁凭证下发重试结果: 成功%d个, 失败%d个", result.Sent, result.Failed)
+			}
+			return nil
+		},
+	}
+}
+
+// Push 将凭证当前状态下发到所有已注册的控制器，并记录每个控制器的下发结果
+func (s *AccessSync) Push(credential *models.AccessCredential) (*AccessSyncResult, error) {
+	result := &AccessSyncResult{}
+	var errs []error
+	for _, name := range s.Controllers.Names() {
+		if err := s.apply(credential, name); err != nil {
+			result.Failed++
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		result.Sent++
+	}
+	return result, errors.Join(errs...)
+}
+
+// Run 重试下发失败的记录，始终按凭证的最新状态下发
+func (s *AccessSync) Run(now time.Time) (*AccessSyncResult, error) {
+	syncs, err := models.GetFailedAccessCredentialSyncs(s.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AccessSyncResult{}
+	for _, sync := range syncs {
+		credential, err := models.GetAccessCredentialByID(sync.CredentialID)
+		if err != nil || credential == nil {
+			log.Printf("获取门禁凭证%d失败: %v", sync.CredentialID, err)
+			continue
+		}
+		if err := s.apply(credential, sync.Controller); err != nil {
+			log.Printf("门禁凭证%d下发到%s失败: %v", credential.ID, sync.Controller, err)
+			result.Failed++
+			continue
+		}
+		result.Sent++
+	}
+
+	return result, nil
+}
+
+// apply 将凭证当前状态下发到指定控制器并记录结果
+func (s *AccessSync) apply(credential *models.AccessCredential, controller string) error {
+	action := credential.Action()
+	err := s.Controllers.Apply(controller, access.Change{
+		Action:     action,
+		Credential: credential.ToAccess(),
+	})
+	if recordErr := models.RecordAccessCredentialSync(credential.ID, controller, action, err); recordErr != nil {
+		log.Printf("记录门禁凭证下发结果失败: %v", recordErr)
+	}
+	return err
+}
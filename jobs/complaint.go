@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"community-backward/models"
+)
+
+// Complaints 投诉升级任务：超过答复期限仍未答复的投诉自动升级一级
+type Complaints struct {
+	// ResponseTime 投诉的答复时限，升级后按此顺延答复期限
+	ResponseTime time.Duration
+}
+
+// NewComplaints 创建新的投诉升级任务
+func NewComplaints(responseTime time.Duration) *Complaints {
+	return &Complaints{ResponseTime: responseTime}
+}
+
+// Job 返回可供Scheduler调度的定时任务
+func (c *Complaints) Job(interval time.Duration) Job {
+	return Job{
+		Name:     "投诉超期升级",
+		Interval: interval,
+		Run: func() error {
+			escalated, err := c.Run(time.Now())
+			if err != nil {
+				return err
+			}
+			log.Printf("投诉超期升级结果: 升级%d件", len(escalated))
+			return nil
+		},
+	}
+}
+
+// Run 升级截至now超期未答复的投诉
+func (c *Complaints) Run(now time.Time) ([]models.Complaint, error) {
+	escalated, err := models.EscalateOverdueComplaints(now, c.ResponseTime)
+	for _, complaint := range escalated {
+		log.Printf("投诉%s超期未答复，已升级至第%d级", complaint.ComplaintNo, complaint.EscalationLevel)
+	}
+	return escalated, err
+}
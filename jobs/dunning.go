@@ -0,0 +1,164 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"community-backward/models"
+	"community-backward/notify"
+)
+
+// DunningResult 表示一次催缴任务的执行结果
+type DunningResult struct {
+	Overdue int `json:"overdue"` // 欠费住户数
+	Sent    int `json:"sent"`    // 发送成功的提醒数
+	Failed  int `json:"failed"`  // 发送失败的提醒数
+	Skipped int `json:"skipped"` // 因未到重发间隔而跳过的住户数
+}
+
+// Dunning 催缴任务：按社区查找欠费住户并按逾期天数逐级发送催缴提醒
+type Dunning struct {
+	Channels *notify.Registry
+	// RepeatInterval 同一级别提醒的重发间隔
+	RepeatInterval time.Duration
+}
+
+// NewDunning 创建新的催缴任务
+func NewDunning(channels *notify.Registry, repeatInterval time.Duration) *Dunning {
+	return &Dunning{
+		Channels:       channels,
+		RepeatInterval: repeatInterval,
+	}
+}
+
+// Job 返回可供Scheduler调度的定时任务
+func (d *Dunning) Job(interval time.Duration) Job {
+	return Job{
+		Name:     "催缴提醒",
+		Interval: interval,
+		Run: func() error {
+			communities, err := models.GetCommunities(true)
+			if err != nil {
+				return fmt.Errorf("获取社区失败: %w", err)
+			}
+			for _, community := range communities {
+				result, err := d.Run(time.Now(), community.ID)
+				if err != nil {
+					return fmt.Errorf("%s催缴失败: %w", community.Name, err)
+				}
+				log.Printf("%s催缴结果: 欠费住户%d户, 发送成功%d条, 失败%d条, 跳过%d户",
+					community.Name, result.Overdue, result.Sent, result.Failed, result.Skipped)
+			}
+			return nil
+		},
+	}
+}
+
+// Run 以now为基准对指定社区执行一次催缴
+// 欠费结清的住户不会出现在欠费列表中，因此不再收到提醒
+func (d *Dunning) Run(now time.Time, communityID int) (*DunningResult, error) {
+	accounts, err := models.GetOverdueAccounts(now, communityID)
+	if err != nil {
+		return nil, fmt.Errorf("获取欠费住户失败: %w", err)
+	}
+
+	templates, err := models.GetDunningTemplates(true)
+	if err != nil {
+		return nil, fmt.Errorf("获取催缴模板失败: %w", err)
+	}
+
+	result := &DunningResult{Overdue: len(accounts)}
+	for _, acc := range accounts {
+		level, levelTemplates := selectLevel(templates, acc.OverdueDays)
+		if level == 0 {
+			continue
+		}
+
+		// 只看本轮欠费开始后的记录，结清后再次欠费时重新从低级别开始
+		// 发送失败的提醒同样按重发间隔重试
+		last, err := models.GetLastDunningRecord(acc.ResidentID, acc.OldestDueDate)
+		if err != nil {
+			return nil, fmt.Errorf("获取住户%d催缴记录失败: %w", acc.ResidentID, err)
+		}
+		if last != nil && last.Level >= level && now.Sub(last.SentAt) < d.RepeatInterval {
+			result.Skipped++
+			continue
+		}
+
+		for _, tpl := range levelTemplates {
+			if err := d.send(acc, tpl); err != nil {
+				log.Printf("向住户%d发送%s催缴失败: %v", acc.ResidentID, tpl.Channel, err)
+				result.Failed++
+			} else {
+				result.Sent++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// send 按模板向住户发送催缴提醒并记录
+func (d *Dunning) send(acc models.OverdueAccount, tpl models.DunningTemplate) error {
+	vars := map[string]string{
+		"name":   acc.Name,
+		"room":   acc.BlockNumber + acc.UnitNumber + acc.HouseNumber,
+		"amount": strconv.FormatFloat(acc.Amount, 'f', 2, 64),
+		"days":   strconv.Itoa(acc.OverdueDays),
+	}
+
+	msg := notify.Message{
+		ResidentID: acc.ResidentID,
+		Title:      notify.Render(tpl.Title, vars),
+		Content:    notify.Render(tpl.Content, vars),
+	}
+	switch tpl.Channel {
+	case notify.ChannelSMS:
+		msg.Recipient = acc.Phone
+	case notify.ChannelEmail:
+		msg.Recipient = acc.Email
+	}
+
+	sendErr := d.Channels.Send(tpl.Channel, msg)
+
+	rec := models.DunningRecord{
+		ResidentID:  acc.ResidentID,
+		Level:       tpl.Level,
+		Channel:     tpl.Channel,
+		Recipient:   msg.Recipient,
+		Content:     msg.Content,
+		Amount:      acc.Amount,
+		OverdueDays: acc.OverdueDays,
+		Status:      1,
+	}
+	if sendErr != nil {
+		rec.Status = 0
+		rec.Error = sendErr.Error()
+	}
+	if err := models.CreateDunningRecord(rec); err != nil {
+		log.Printf("保存催缴记录失败: %v", err)
+	}
+
+	return sendErr
+}
+
+// selectLevel 根据逾期天数选择最高可用的催缴级别及该级别的模板
+func selectLevel(templates []models.DunningTemplate, overdueDays int) (int, []models.DunningTemplate) {
+	level := 0
+	for _, t := range templates {
+		if overdueDays >= t.MinOverdueDays && t.Level > level {
+			level = t.Level
+		}
+	}
+
+	var selected []models.DunningTemplate
+	for _, t := range templates {
+		if t.Level == level {
+			selected = append(selected, t)
+		}
+	}
+
+	return level, selected
+}
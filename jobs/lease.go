@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"community-backward/models"
+)
+
+// 租约到期提醒事件，模板见notification_templates
+const (
+	leaseExpiringEvent       = "lease_expiring"
+	leaseExpiringTenantEvent = "lease_expiring_tenant"
+)
+
+// LeaseResult 表示一次租约到期提醒的结果
+type LeaseResult struct {
+	Expiring int `json:"expiring"` // 新发现的即将到期租约数
+	Reminded int `json:"reminded"` // 已加入发送队列的租约数
+	Failed   int `json:"failed"`   // 加入发送队列失败的租约数
+}
+
+// Leases 租约到期提醒任务：租约到期前向业主和主租户各提醒一次
+type Leases struct {
+	Notifications *Notifications
+	// ReminderDays 到期前多少天发送提醒
+	ReminderDays int
+	// DefaultFeePayer 登记租约未指定时的物业费缴纳方
+	DefaultFeePayer string
+}
+
+// NewLeases 创建新的租约到期提醒任务
+func NewLeases(notifications *Notifications, reminderDays int, defaultFeePayer string) *Leases {
+	return &Leases{
+		Notifications:   notifications,
+		ReminderDays:    reminderDays,
+		DefaultFeePayer: defaultFeePayer,
+	}
+}
+
+// Job 返回可供Scheduler调度的定时任务
+func (l *Leases) Job(interval time.Duration) Job {
+	return Job{
+		Name:     "租约到期提醒",
+		Interval: interval,
+		Run: func() error {
+			result, err := l.Run(time.Now())
+			if err != nil {
+				return err
+			}
+			log.Printf("租约到期提醒结果: 即将到期%d份, 提醒%d份, 失败%d份", result.Expiring, result.Reminded, result.Failed)
+			return nil
+		},
+	}
+}
+
+// Run 对截至now在ReminderDays天内到期且尚未提醒的租约发送到期提醒
+func (l *Leases) Run(now time.Time) (*LeaseResult, error) {
+	leases, err := models.GetUnremindedExpiringLeases(now, now.AddDate(0, 0, l.ReminderDays))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LeaseResult{Expiring: len(leases)}
+	for i := range leases {
+		lease := &leases[i]
+		if err := l.remind(lease); err != nil {
+			log.Printf("发送租约%d到期提醒失败: %v", lease.ID, err)
+			result.Failed++
+			continue
+		}
+
+		result.Reminded++
+		if err := models.MarkLeaseReminded(lease.ID); err != nil {
+			log.Printf("记录租约到期提醒失败: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// remind 向业主和主租户发送租约到期提醒
+func (l *Leases) remind(lease *models.Lease) error {
+	names := make([]string, 0, len(lease.Tenants))
+	for _, t := range lease.Tenants {
+		names = append(names, t.Name)
+	}
+	vars := map[string]string{
+		"tenant":   strings.Join(names, "、"),
+		"end_date": lease.EndDate.Format("2006-01-02"),
+		"days":     strconv.Itoa(lease.DaysLeft),
+	}
+
+	_, err := l.Notifications.Notify(lease.ResidentID, leaseExpiringEvent, vars)
+	if len(lease.Tenants) > 0 {
+		primary := lease.Tenants[0]
+		vars["tenant"] = primary.Name
+		_, tenantErr := l.Notifications.NotifyContact(lease.ResidentID, leaseExpiringTenantEvent, Contact{
+			Name:  primary.Name,
+			Phone: primary.Phone,
+			Email: primary.Email,
+		}, vars)
+		err = errors.Join(err, tenantErr)
+	}
+	return err
+}
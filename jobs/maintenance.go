@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"community-backward/models"
+)
+
+// MaintenanceResult 表示一次维保任务生成的结果
+type MaintenanceResult struct {
+	Created int `json:"created"` // 新生成的维保任务数
+	Overdue int `json:"overdue"` // 当前超期未完成的维保任务数
+}
+
+// Maintenance 设备维保任务：按维保计划在到期前生成任务和工单，并统计超期未完成的任务
+type Maintenance struct{}
+
+// NewMaintenance 创建新的设备维保任务
+func NewMaintenance() *Maintenance {
+	return &Maintenance{}
+}
+
+// Job 返回可供Scheduler调度的定时任务
+func (m *Maintenance) Job(interval time.Duration) Job {
+	return Job{
+		Name:     "设备维保任务生成",
+		Interval: interval,
+		Run: func() error {
+			result, err := m.Run(time.Now())
+			if err != nil {
+				return err
+			}
+			log.Printf("设备维保任务生成结果: 新增%d项, 超期未完成%d项", result.Created, result.Overdue)
+			return nil
+		},
+	}
+}
+
+// Run 生成截至now需要提前安排的维保任务
+func (m *Maintenance) Run(now time.Time) (*MaintenanceResult, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	created, err := models.GenerateMaintenanceTasks(today)
+	if err != nil {
+		return nil, err
+	}
+
+	_, overdue, err := models.GetMaintenanceTasks(1, 1, map[string]interface{}{"overdue": true})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MaintenanceResult{Created: created, Overdue: overdue}, nil
+}
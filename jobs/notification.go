@@ -0,0 +1,232 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"community-backward/models"
+	"community-backward/notify"
+)
+
+// 每次处理的最大通知数
+const notificationBatchSize = 100
+
+// 首次重试的等待时间，之后每次翻倍，最长不超过notificationMaxRetryDelay
+const (
+	notificationRetryDelay    = time.Minute
+	notificationMaxRetryDelay = 6 * time.Hour
+)
+
+// 发送中超过该时长仍未更新的通知视为发送中断，重新放回队列
+const notificationStaleAfter = 10 * time.Minute
+
+// NotificationResult 表示一次发送队列处理的结果
+type NotificationResult struct {
+	Sent     int `json:"sent"`     // 发送成功数
+	Retrying int `json:"retrying"` // 发送失败、等待重试数
+	Failed   int `json:"failed"`   // 重试次数用尽仍失败数
+}
+
+// Notifications 通知中心：按模板和住户偏好生成通知并放入发送队列，由定时任务发送和重试
+type Notifications struct {
+	Channels *notify.Registry
+	// MaxAttempts 每条通知的最大发送次数
+	MaxAttempts int
+}
+
+// NewNotifications 创建新的通知中心
+func NewNotifications(channels *notify.Registry, maxAttempts int) *Notifications {
+	return &Notifications{
+		Channels:    channels,
+		MaxAttempts: maxAttempts,
+	}
+}
+
+// Job 返回可供Scheduler调度的定时任务
+func (n *Notifications) Job(interval time.Duration) Job {
+	return Job{
+		Name:     "通知发送",
+		Interval: interval,
+		Run: func() error {
+			result, err := n.Run(time.Now())
+			if err != nil {
+				return err
+			}
+			if result.Sent+result.Retrying+result.Failed > 0 {
+				log.Printf("通知发送结果: 成功%d条, 等待重试%d条, 失败%d条", result.Sent, result.Retrying, result.Failed)
+			}
+			return nil
+		},
+	}
+}
+
+// Contact 表示通知的接收联系人
+type Contact struct {
+	Name  string
+	Phone string
+	Email string
+}
+
+// Notify 按事件模板向住户发送通知，返回加入队列的通知数
+// 每个渠道按住户偏好语言选择模板，没有对应语言时使用中文模板；住户关闭的渠道和未登记联系方式的渠道会跳过
+// vars中未提供name和room时默认为住户姓名和房号
+func (n *Notifications) Notify(residentID int, event string, vars map[string]string) (int, error) {
+	resident, err := models.GetResidentByID(residentID)
+	if err != nil {
+		return 0, err
+	}
+	if resident == nil {
+		return 0, fmt.Errorf("住户%d不存在", residentID)
+	}
+
+	pref, err := models.GetNotificationPreference(residentID)
+	if err != nil {
+		return 0, err
+	}
+
+	contact := Contact{Name: resident.Name, Phone: resident.Phone, Email: resident.Email}
+	return n.enqueue(resident, event, contact, pref, vars)
+}
+
+// NotifyContact 按事件模板向住户房屋的其他联系人（如租户）发送短信和邮件，返回加入队列的通知数
+// 联系人没有站内信，也不受住户通知偏好影响，使用中文模板；vars中未提供name时默认为联系人姓名
+func (n *Notifications) NotifyContact(residentID int, event string, contact Contact, vars map[string]string) (int, error) {
+	resident, err := models.GetResidentByID(residentID)
+	if err != nil {
+		return 0, err
+	}
+	if resident == nil {
+		return 0, fmt.Errorf("住户%d不存在", residentID)
+	}
+
+	pref := &models.NotificationPreference{
+		ResidentID:   residentID,
+		Locale:       notify.LocaleZH,
+		SMSEnabled:   true,
+		EmailEnabled: true,
+	}
+	return n.enqueue(resident, event, contact, pref, vars)
+}
+
+// enqueue 按模板和偏好为联系人生成各渠道的通知并加入发送队列
+func (n *Notifications) enqueue(resident *models.Resident, event string, contact Contact,
+	pref *models.NotificationPreference, vars map[string]string) (int, error) {
+	templates, err := models.GetNotificationTemplates(map[string]interface{}{"event": event}, true)
+	if err != nil {
+		return 0, err
+	}
+	if len(templates) == 0 {
+		return 0, fmt.Errorf("%w: %s", models.ErrNotificationTemplateNotFound, event)
+	}
+
+	merged := map[string]string{
+		"name": contact.Name,
+		"room": resident.BlockNumber + resident.UnitNumber + resident.HouseNumber,
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+
+	queued := 0
+	for channel, tpl := range selectTemplates(templates, pref.Locale) {
+		if !pref.Enabled(channel) {
+			continue
+		}
+		recipient := ""
+		switch channel {
+		case notify.ChannelSMS:
+			recipient = contact.Phone
+		case notify.ChannelEmail:
+			recipient = contact.Email
+		}
+		if channel != notify.ChannelInApp && recipient == "" {
+			continue
+		}
+
+		_, err := models.CreateNotification(models.Notification{
+			ResidentID: resident.ID,
+			Event:      event,
+			Channel:    channel,
+			Locale:     tpl.Locale,
+			Recipient:  recipient,
+			Title:      notify.Render(tpl.Title, merged),
+			Content:    notify.Render(tpl.Content, merged),
+		})
+		if err != nil {
+			return queued, err
+		}
+		queued++
+	}
+
+	return queued, nil
+}
+
+// selectTemplates 为每个渠道选择模板，优先使用locale语言，其次中文
+func selectTemplates(templates []models.NotificationTemplate, locale string) map[string]models.NotificationTemplate {
+	selected := make(map[string]models.NotificationTemplate)
+	for _, tpl := range templates {
+		current, ok := selected[tpl.Channel]
+		switch {
+		case !ok:
+			selected[tpl.Channel] = tpl
+		case tpl.Locale == locale:
+			selected[tpl.Channel] = tpl
+		case tpl.Locale == notify.LocaleZH && current.Locale != locale:
+			selected[tpl.Channel] = tpl
+		}
+	}
+	return selected
+}
+
+// Run 发送截至now到期的通知，失败的通知按指数退避重试，达到最大发送次数后标记为失败
+func (n *Notifications) Run(now time.Time) (*NotificationResult, error) {
+	due, err := models.ClaimDueNotifications(now, now.Add(-notificationStaleAfter), notificationBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NotificationResult{}
+	for _, item := range due {
+		sendErr := n.Channels.Send(item.Channel, notify.Message{
+			ResidentID: item.ResidentID,
+			Recipient:  item.Recipient,
+			Title:      item.Title,
+			Content:    item.Content,
+		})
+		if sendErr == nil {
+			result.Sent++
+			if err := models.MarkNotificationSent(item.ID); err != nil {
+				log.Printf("记录通知%d发送结果失败: %v", item.ID, err)
+			}
+			continue
+		}
+
+		var retryAt *time.Time
+		if attempts := item.Attempts + 1; attempts < n.MaxAttempts {
+			at := now.Add(retryDelay(attempts))
+			retryAt = &at
+			result.Retrying++
+		} else {
+			log.Printf("通知%d发送失败，已达最大发送次数: %v", item.ID, sendErr)
+			result.Failed++
+		}
+		if err := models.MarkNotificationFailed(item.ID, sendErr, retryAt); err != nil {
+			log.Printf("记录通知%d发送结果失败: %v", item.ID, err)
+		}
+	}
+
+	return result, nil
+}
+
+// retryDelay 返回第attempts次发送失败后的重试等待时间
+func retryDelay(attempts int) time.Duration {
+	delay := notificationRetryDelay
+	for i := 1; i < attempts && delay < notificationMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > notificationMaxRetryDelay {
+		delay = notificationMaxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"community-backward/models"
+)
+
+// 快递通知事件，模板见notification_templates
+const (
+	parcelArrivalEvent = "parcel_arrival"
+	parcelOverdueEvent = "parcel_overdue"
+)
+
+// ParcelResult 表示一次超期包裹催取的结果
+type ParcelResult struct {
+	Overdue int `json:"overdue"` // 新发现的超期包裹数
+	Sent    int `json:"sent"`    // 加入发送队列的提醒数
+	Failed  int `json:"failed"`  // 加入发送队列失败的提醒数
+}
+
+// Parcels 快递通知任务：到件时通知住户，超期未取时催取一次
+type Parcels struct {
+	Notifications *Notifications
+	// OverdueDays 到件后超过该天数仍未取走视为超期
+	OverdueDays int
+}
+
+// NewParcels 创建新的快递通知任务
+func NewParcels(notifications *Notifications, overdueDays int) *Parcels {
+	return &Parcels{
+		Notifications: notifications,
+		OverdueDays:   overdueDays,
+	}
+}
+
+// OverdueBefore 返回以now为基准的超期判定时间，在此之前到件且未取走的包裹为超期包裹
+func (p *Parcels) OverdueBefore(now time.Time) time.Time {
+	return now.AddDate(0, 0, -p.OverdueDays)
+}
+
+// Job 返回可供Scheduler调度的定时任务
+func (p *Parcels) Job(interval time.Duration) Job {
+	return Job{
+		Name:     "快递超期催取",
+		Interval: interval,
+		Run: func() error {
+			result, err := p.Run(time.Now())
+			if err != nil {
+				return err
+			}
+			log.Printf("快递超期催取结果: 超期%d件, 提醒入队%d条, 失败%d条", result.Overdue, result.Sent, result.Failed)
+			return nil
+		},
+	}
+}
+
+// NotifyArrival 发送到件通知并记录通知时间
+func (p *Parcels) NotifyArrival(parcel *models.Parcel) error {
+	if err := p.send(parcel, parcelArrivalEvent); err != nil {
+		return err
+	}
+	return models.MarkParcelNotified(parcel.ID)
+}
+
+// Run 对截至now超期未取的包裹发送催取提醒，每件只提醒一次
+func (p *Parcels) Run(now time.Time) (*ParcelResult, error) {
+	parcels, err := models.GetUnremindedOverdueParcels(p.OverdueBefore(now))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ParcelResult{Overdue: len(parcels)}
+	for i := range parcels {
+		parcel := &parcels[i]
+		if err := p.send(parcel, parcelOverdueEvent); err != nil {
+			log.Printf("向住户%d发送快递催取提醒失败: %v", parcel.ResidentID, err)
+			result.Failed++
+			continue
+		}
+
+		result.Sent++
+		if err := models.MarkParcelReminded(parcel.ID); err != nil {
+			log.Printf("记录快递催取提醒失败: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// send 通过通知中心向包裹所属住户发送通知，渠道和语言由模板及住户偏好决定
+func (p *Parcels) send(parcel *models.Parcel, event string) error {
+	trackingTail := parcel.TrackingNo
+	if len(trackingTail) > 4 {
+		trackingTail = trackingTail[len(trackingTail)-4:]
+	}
+	_, err := p.Notifications.Notify(parcel.ResidentID, event, map[string]string{
+		"name":        parcel.ResidentName,
+		"room":        parcel.Room,
+		"carrier":     parcel.Carrier,
+		"tracking_no": trackingTail,
+		"code":        parcel.PickupCode,
+		"shelf":       parcel.Shelf,
+		"days":        strconv.Itoa(parcel.WaitingDays),
+	})
+	return err
+}
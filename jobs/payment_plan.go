@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"community-backward/models"
+	"community-backward/notify"
+)
+
+// 分期逾期提醒模板，支持占位符：{{name}} {{room}} {{seq}} {{amount}} {{due}}
+const (
+	instalmentAlertTitle   = "分期还款逾期提醒"
+	instalmentAlertContent = "{{name}}您好，您的房屋{{room}}分期还款计划第{{seq}}期（应还{{amount}}元，到期日{{due}}）已逾期，请尽快还款。逾期期间将恢复计收滞纳金。"
+)
+
+// PaymentPlanResult 表示一次分期计划检查的结果
+type PaymentPlanResult struct {
+	Missed int `json:"missed"` // 新发现的逾期分期数
+	Sent   int `json:"sent"`   // 发送成功的提醒数
+	Failed int `json:"failed"` // 发送失败的提醒数
+}
+
+// PaymentPlans 分期计划任务：更新还款进度，并对逾期分期发送提醒
+type PaymentPlans struct {
+	Channels *notify.Registry
+	// GraceDays 分期到期后的宽限天数
+	GraceDays int
+}
+
+// NewPaymentPlans 创建新的分期计划任务
+func NewPaymentPlans(channels *notify.Registry, graceDays int) *PaymentPlans {
+	return &PaymentPlans{
+		Channels:  channels,
+		GraceDays: graceDays,
+	}
+}
+
+// Job 返回可供Scheduler调度的定时任务
+func (p *PaymentPlans) Job(interval time.Duration) Job {
+	return Job{
+		Name:     "分期还款检查",
+		Interval: interval,
+		Run: func() error {
+			result, err := p.Run(time.Now())
+			if err != nil {
+				return err
+			}
+			log.Printf("分期还款检查结果: 新逾期%d期, 提醒成功%d条, 失败%d条", result.Missed, result.Sent, result.Failed)
+			return nil
+		},
+	}
+}
+
+// Run 以now为基准更新所有执行中的分期计划，每期逾期只提醒一次
+func (p *PaymentPlans) Run(now time.Time) (*PaymentPlanResult, error) {
+	if err := models.RefreshPaymentPlans(now, p.GraceDays); err != nil {
+		return nil, fmt.Errorf("更新分期计划失败: %w", err)
+	}
+
+	missed, err := models.GetMissedInstalments(true)
+	if err != nil {
+		return nil, fmt.Errorf("获取逾期分期失败: %w", err)
+	}
+
+	result := &PaymentPlanResult{Missed: len(missed)}
+	for _, m := range missed {
+		vars := map[string]string{
+			"name":   m.Name,
+			"room":   m.Room,
+			"seq":    strconv.Itoa(m.Seq),
+			"amount": strconv.FormatFloat(m.Amount-m.PaidAmount, 'f', 2, 64),
+			"due":    m.DueDate.Format("2006-01-02"),
+		}
+		msg := notify.Message{
+			ResidentID: m.ResidentID,
+			Title:      instalmentAlertTitle,
+			Content:    notify.Render(instalmentAlertContent, vars),
+		}
+
+		// 站内信必发，有手机号时同时发送短信
+		sendErr := p.Channels.Send(notify.ChannelInApp, msg)
+		if m.Phone != "" {
+			msg.Recipient = m.Phone
+			sendErr = errors.Join(sendErr, p.Channels.Send(notify.ChannelSMS, msg))
+		}
+		if sendErr != nil {
+			log.Printf("向住户%d发送分期逾期提醒失败: %v", m.ResidentID, sendErr)
+			result.Failed++
+			continue
+		}
+
+		result.Sent++
+		if err := models.MarkInstalmentAlerted(m.InstalmentID); err != nil {
+			log.Printf("记录分期逾期提醒失败: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// LateFees 滞纳金任务：上月结束后按社区依据月末欠费计收上月滞纳金
+type LateFees struct {
+	Policy models.LateFeePolicy
+	Plans  *PaymentPlans
+}
+
+// NewLateFees 创建新的滞纳金任务，计收前先更新分期计划以确定暂停计收的住户
+func NewLateFees(policy models.LateFeePolicy, plans *PaymentPlans) *LateFees {
+	return &LateFees{Policy: policy, Plans: plans}
+}
+
+// Job 返回可供Scheduler调度的定时任务
+func (l *LateFees) Job(interval time.Duration) Job {
+	return Job{
+		Name:     "计收滞纳金",
+		Interval: interval,
+		Run: func() error {
+			now := time.Now()
+			lastMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, -1, 0)
+			communities, err := models.GetCommunities(true)
+			if err != nil {
+				return fmt.Errorf("获取社区失败: %w", err)
+			}
+			for _, community := range communities {
+				result, err := l.Run(lastMonth.Year(), int(lastMonth.Month()), community.ID)
+				// 已结账的社区不再计收
+				if errors.Is(err, models.ErrPeriodClosed) {
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("%s计收滞纳金失败: %w", community.Name, err)
+				}
+				log.Printf("%s%d年%d月滞纳金: 新生成%d张账单, 合计%.2f元, 分期暂停%d户",
+					community.Name, result.Year, result.Month, result.Created, result.Amount, result.Suspended)
+			}
+			return nil
+		},
+	}
+}
+
+// Run 计收指定社区指定月份的滞纳金，已计收的住户不会重复生成
+func (l *LateFees) Run(year, month, communityID int) (*models.LateFeeResult, error) {
+	if err := models.RefreshPaymentPlans(time.Now(), l.Plans.GraceDays); err != nil {
+		return nil, fmt.Errorf("更新分期计划失败: %w", err)
+	}
+	policy := l.Policy
+	policy.PlanGraceDays = l.Plans.GraceDays
+	return models.GenerateLateFees(year, month, communityID, policy)
+}
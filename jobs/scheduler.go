@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Job 表示一个定时任务
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() error
+}
+
+// Scheduler 按固定间隔执行定时任务
+type Scheduler struct {
+	jobs []Job
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewScheduler 创建新的Scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{stop: make(chan struct{})}
+}
+
+// Add 添加定时任务，需在Start之前调用
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start 启动所有定时任务，每个任务启动后立即执行一次
+func (s *Scheduler) Start() {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+}
+
+// Stop 停止所有定时任务并等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(job Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(job)
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("定时任务[%s]异常: %v", job.Name, r)
+		}
+	}()
+
+	start := time.Now()
+	if err := job.Run(); err != nil {
+		log.Printf("定时任务[%s]执行失败: %v", job.Name, err)
+		return
+	}
+	log.Printf("定时任务[%s]执行完成，耗时 %v", job.Name, time.Since(start))
+}
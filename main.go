@@ -2,9 +2,16 @@ package main
 
 import (
 	"log"
+	"os"
+	"time"
 	"github.com/gin-gonic/gin"
+	"community-backward/access"
 	"community-backward/config"
 	"community-backward/database"
+	"community-backward/jobs"
+	"community-backward/models"
+	"community-backward/notify"
+	"community-backward/repository"
 	"community-backward/routes"
 	"community-backward/utils"
 )
@@ -22,11 +29,98 @@ func main() {
 	}
 	defer database.CloseDB()
 
+	// 执行数据库迁移子命令：community-backward migrate <命令>
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			// log.Fatal不会执行defer，先关闭数据库连接
+			database.CloseDB()
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// 执行数据库迁移，关闭自动迁移时只提示未执行的迁移
+	if cfg.MigrateOnStartup {
+		if _, err := database.MigrateUp(0); err != nil {
+			database.CloseDB()
+			log.Fatalf("数据库迁移失败: %v", err)
+		}
+	} else if pending, err := database.PendingMigrations(); err != nil {
+		log.Printf("检查数据库迁移失败: %v", err)
+	} else if len(pending) > 0 {
+		log.Printf("有%d个数据库迁移未执行，请运行 migrate up", len(pending))
+	}
+
 	// 创建JWT工具
 	jwtUtil := utils.NewJWTUtil(cfg.JWTSecret)
+	passSigner := utils.NewPassSigner(cfg.VisitorPassSecret)
+
+	// 注册通知渠道，短信和邮件未配置服务商时仅写日志
+	channels := notify.NewRegistry(
+		notify.NewLogChannel(notify.ChannelSMS),
+		notify.NewLogChannel(notify.ChannelEmail),
+		notify.NewInAppChannel(),
+	)
+	if cfg.SMTPHost != "" {
+		channels.Register(notify.NewSMTPChannel(notify.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		}))
+	}
+	if cfg.SMSGatewayURL != "" {
+		channels.Register(notify.NewHTTPSMSChannel(notify.HTTPSMSConfig{
+			URL:   cfg.SMSGatewayURL,
+			Token: cfg.SMSGatewayToken,
+			Sign:  cfg.SMSSign,
+		}))
+	}
+
+	// 注册门禁控制器（暂未对接门禁厂商，使用模拟控制器）
+	controllers := access.NewRegistry(access.NewMockController("mock"))
+
+	// 启动定时任务
+	dunning := jobs.NewDunning(channels, cfg.DunningRepeatInterval)
+	plans := jobs.NewPaymentPlans(channels, cfg.PlanGraceDays)
+	lateFees := jobs.NewLateFees(models.LateFeePolicy{
+		DailyRate: cfg.LateFeeDailyRate,
+		GraceDays: cfg.LateFeeGraceDays,
+	}, plans)
+	complaints := jobs.NewComplaints(cfg.ComplaintResponseTime)
+	notifications := jobs.NewNotifications(channels, cfg.NotifyMaxAttempts)
+	parcels := jobs.NewParcels(notifications, cfg.ParcelOverdueDays)
+	leases := jobs.NewLeases(notifications, cfg.LeaseReminderDays, cfg.LeaseDefaultFeePayer)
+	maintenance := jobs.NewMaintenance()
+	accessSync := jobs.NewAccessSync(controllers, cfg.AccessSyncMaxAttempts)
+	scheduler := jobs.NewScheduler()
+	scheduler.Add(dunning.Job(cfg.DunningInterval))
+	scheduler.Add(plans.Job(24 * time.Hour))
+	scheduler.Add(lateFees.Job(24 * time.Hour))
+	scheduler.Add(complaints.Job(time.Hour))
+	scheduler.Add(parcels.Job(24 * time.Hour))
+	scheduler.Add(maintenance.Job(24 * time.Hour))
+	scheduler.Add(accessSync.Job(10 * time.Minute))
+	scheduler.Add(notifications.Job(time.Minute))
+	scheduler.Add(leases.Job(24 * time.Hour))
+	scheduler.Start()
+	defer scheduler.Stop()
 
 	// 设置路由
-	r := routes.SetupRouter(jwtUtil)
+	r := routes.SetupRouter(jwtUtil, repository.NewMySQL(), routes.Deps{
+		Dunning:       dunning,
+		PaymentPlans:  plans,
+		LateFees:      lateFees,
+		Complaints:    complaints,
+		Parcels:       parcels,
+		Maintenance:   maintenance,
+		AccessSync:    accessSync,
+		Notifications: notifications,
+		Leases:        leases,
+		PassSigner:    passSigner,
+		UploadDir:     cfg.UploadDir,
+	})
 
 	// 启动服务器
 	log.Printf("Server running on port %s", cfg.Port)
@@ -5,6 +5,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"community-backward/models"
+	"community-backward/repository"
 	"community-backward/utils"
 )
 
@@ -45,7 +47,78 @@ func AuthMiddleware(jwtUtil *utils.JWTUtil) gin.HandlerFunc {
 		// 将用户信息存储在上下文中
 		c.Set("user_id", claims["user_id"])
 		c.Set("username", claims["username"])
+		c.Set("role", claims["role"])
+		c.Set("community_id", claims["community_id"])
 
 		c.Next()
 	}
 }
+
+// RequireRole 创建角色校验中间件，需在AuthMiddleware之后使用
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "无权执行此操作",
+		})
+	}
+}
+
+// RejectRole 创建角色排除中间件，拒绝指定角色访问，需在AuthMiddleware之后使用
+func RejectRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		for _, r := range roles {
+			if role == r {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"success": false,
+					"message": "无权执行此操作",
+				})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// LoadResidentAccount 创建住户账号加载中间件，将住户账号关联的住户ID存入上下文，需在AuthMiddleware之后使用
+func LoadResidentAccount(users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		if role != models.RoleResident {
+			c.Next()
+			return
+		}
+
+		// JWT中的数字解析后为float64
+		v, _ := c.Get("user_id")
+		userID, _ := v.(float64)
+		user, err := users.GetUserByID(uint(userID))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取住户账号失败: " + err.Error(),
+			})
+			return
+		}
+		if user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "用户不存在",
+			})
+			return
+		}
+
+		c.Set("resident_id", user.ResidentID)
+		c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"community-backward/database"
+)
+
+// migrateUsage migrate子命令的用法
+const migrateUsage = `用法: community-backward migrate <命令>
+  up [版本]       执行未执行的迁移，指定版本时只执行到该版本
+  down [步数]     回滚最近执行的迁移，默认回滚1个
+  status          查看各迁移的执行状态
+  force <版本>    将迁移记录设为已执行到该版本（不执行脚本），用于接管已有数据库或修复中断的迁移`
+
+// runMigrate 执行migrate子命令，失败时返回错误，由调用方关闭数据库连接后退出
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		target, err := migrateArg(args, 0)
+		if err != nil {
+			return err
+		}
+		done, err := database.MigrateUp(target)
+		if err != nil {
+			return fmt.Errorf("数据库迁移失败: %w", err)
+		}
+		log.Printf("已执行%d个迁移", len(done))
+	case "down":
+		steps, err := migrateArg(args, 1)
+		if err != nil {
+			return err
+		}
+		done, err := database.MigrateDown(steps)
+		if err != nil {
+			return fmt.Errorf("数据库迁移回滚失败: %w", err)
+		}
+		log.Printf("已回滚%d个迁移", len(done))
+	case "status":
+		statuses, err := database.GetMigrationStatuses()
+		if err != nil {
+			return fmt.Errorf("获取迁移状态失败: %w", err)
+		}
+		for _, s := range statuses {
+			state := "未执行"
+			if s.Dirty {
+				state = "中断"
+			} else if s.Applied {
+				state = "已执行 " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-20s %s\n", s.Version, s.Name, state)
+		}
+	case "force":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := migrateArg(args, 0)
+		if err != nil {
+			return err
+		}
+		if err := database.ForceMigrationVersion(version); err != nil {
+			return fmt.Errorf("设置迁移版本失败: %w", err)
+		}
+		log.Printf("迁移记录已设为版本%d", version)
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
+
+// migrateArg 读取子命令的非负整数参数，未提供时返回默认值
+func migrateArg(args []string, defaultValue int) (int, error) {
+	if len(args) < 2 {
+		return defaultValue, nil
+	}
+	v, err := strconv.Atoi(args[1])
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("无效的参数: %s\n%s", args[1], migrateUsage)
+	}
+	return v, nil
+}
@@ -0,0 +1,420 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"community-backward/access"
+	"community-backward/database"
+)
+
+// 门禁凭证状态
+const (
+	CredentialActive    = "active"    // 有效
+	CredentialSuspended = "suspended" // 已暂停（挂失）
+	CredentialRevoked   = "revoked"   // 已注销
+)
+
+// 门禁凭证操作
+const (
+	CredentialActionSuspend = "suspend"
+	CredentialActionResume  = "resume"
+	CredentialActionRevoke  = "revoke"
+)
+
+// 凭证下发状态
+const (
+	CredentialSyncSuccess = "success"
+	CredentialSyncFailed  = "failed"
+)
+
+var (
+	// ErrInvalidCredential 门禁凭证信息不完整
+	ErrInvalidCredential = errors.New("无效的门禁凭证")
+	// ErrCredentialExists 同类型凭证号已被未注销的凭证占用
+	ErrCredentialExists = errors.New("该凭证号已在使用")
+	// ErrCredentialTransition 当前凭证状态不允许该操作
+	ErrCredentialTransition = errors.New("当前凭证状态不允许该操作")
+)
+
+// credentialTransitions 门禁凭证状态流转规则
+var credentialTransitions = map[string]workOrderTransition{
+	CredentialActionSuspend: {from: []string{CredentialActive}, to: CredentialSuspended, label: "暂停"},
+	CredentialActionResume:  {from: []string{CredentialSuspended}, to: CredentialActive, label: "恢复"},
+	CredentialActionRevoke:  {from: []string{CredentialActive, CredentialSuspended}, to: CredentialRevoked, label: "注销"},
+}
+
+// AccessCredential 表示住户的门禁凭证
+type AccessCredential struct {
+	ID            int                    `json:"id"`
+	CommunityID   int                    `json:"community_id"` // 住户所在社区
+	ResidentID    int                    `json:"resident_id"`
+	ResidentName  string                 `json:"resident_name"`
+	Room          string                 `json:"room"`
+	BlockNumber   string                 `json:"block_number"`
+	Type          string                 `json:"type"`
+	CredentialNo  string                 `json:"credential_no"`
+	HolderName    string                 `json:"holder_name"`
+	Status        string                 `json:"status"`
+	ValidUntil    *time.Time             `json:"valid_until,omitempty"`
+	Note          string                 `json:"note"`
+	IssuedBy      int                    `json:"issued_by"`
+	SuspendedAt   *time.Time             `json:"suspended_at,omitempty"`
+	SuspendReason string                 `json:"suspend_reason,omitempty"`
+	RevokedAt     *time.Time             `json:"revoked_at,omitempty"`
+	RevokeReason  string                 `json:"revoke_reason,omitempty"`
+	SyncFailed    bool                   `json:"sync_failed"` // 存在下发失败的控制器
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	Syncs         []AccessCredentialSync `json:"syncs,omitempty"`
+}
+
+// AccessCredentialSync 表示凭证在某个门禁控制器上最近一次下发的结果
+type AccessCredentialSync struct {
+	CredentialID int       `json:"credential_id"`
+	Controller   string    `json:"controller"`
+	Action       string    `json:"action"`
+	Status       string    `json:"status"`
+	Error        string    `json:"error,omitempty"`
+	Attempts     int       `json:"attempts"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// AccessCredentialRequest 表示发放门禁凭证请求
+// 手机开门密钥的credential_no留空时由系统生成
+type AccessCredentialRequest struct {
+	ResidentID   int    `json:"resident_id" binding:"required"`
+	Type         string `json:"type" binding:"required,oneof=card face app_key"`
+	CredentialNo string `json:"credential_no" binding:"max=64"`
+	HolderName   string `json:"holder_name" binding:"max=50"`
+	ValidUntil   string `json:"valid_until"` // 格式：2006-01-02，为空表示长期有效
+	Note         string `json:"note" binding:"max=255"`
+}
+
+// CredentialActionRequest 表示暂停、恢复或注销凭证请求
+type CredentialActionRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// Action 返回凭证当前状态对应的控制器下发操作
+func (c *AccessCredential) Action() string {
+	switch c.Status {
+	case CredentialActive:
+		return access.ActionGrant
+	case CredentialSuspended:
+		return access.ActionSuspend
+	}
+	return access.ActionRevoke
+}
+
+// ToAccess 转换为下发给门禁控制器的凭证信息
+func (c *AccessCredential) ToAccess() access.Credential {
+	return access.Credential{
+		ID:          c.ID,
+		ResidentID:  c.ResidentID,
+		BlockNumber: c.BlockNumber,
+		Type:        c.Type,
+		Value:       c.CredentialNo,
+		HolderName:  c.HolderName,
+		ValidUntil:  c.ValidUntil,
+	}
+}
+
+// Mask 隐藏手机开门密钥，仅保留末4位，密钥只在发放时完整返回一次
+func (c *AccessCredential) Mask() {
+	if c.Type != access.CredentialAppKey {
+		return
+	}
+	if n := len(c.CredentialNo); n > 4 {
+		c.CredentialNo = strings.Repeat("*", n-4) + c.CredentialNo[n-4:]
+	}
+}
+
+// newAppKey 生成32位十六进制手机开门密钥
+func newAppKey() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// IssueAccessCredential 为住户发放门禁凭证，返回凭证ID
+func IssueAccessCredential(req AccessCredentialRequest, issuedBy uint) (int, error) {
+	req.CredentialNo = strings.TrimSpace(req.CredentialNo)
+	if req.Type == access.CredentialCard {
+		req.CredentialNo = strings.ToUpper(req.CredentialNo)
+	}
+	if req.CredentialNo == "" {
+		if req.Type != access.CredentialAppKey {
+			return 0, fmt.Errorf("%w: 门禁卡和人脸凭证必须填写凭证号", ErrInvalidCredential)
+		}
+		key, err := newAppKey()
+		if err != nil {
+			return 0, err
+		}
+		req.CredentialNo = key
+	}
+
+	var validUntil interface{}
+	if req.ValidUntil != "" {
+		t, err := time.ParseInLocation("2006-01-02", req.ValidUntil, time.Local)
+		if err != nil {
+			return 0, fmt.Errorf("%w: 无效的有效期%s", ErrInvalidCredential, req.ValidUntil)
+		}
+		if t.Format("2006-01-02") < time.Now().Format("2006-01-02") {
+			return 0, fmt.Errorf("%w: 有效期不能早于今天", ErrInvalidCredential)
+		}
+		validUntil = t.Format("2006-01-02")
+	}
+
+	var exists int
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*) FROM access_credentials WHERE type = ? AND credential_no = ? AND status <> ?`,
+		req.Type, req.CredentialNo, CredentialRevoked).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if exists > 0 {
+		return 0, fmt.Errorf("%w: %s", ErrCredentialExists, req.CredentialNo)
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO access_credentials (resident_id, type, credential_no, holder_name, status, valid_until, note, issued_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.ResidentID, req.Type, req.CredentialNo, req.HolderName, CredentialActive, validUntil, req.Note, issuedBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+const accessCredentialColumns = `
+	a.id, r.community_id, a.resident_id, r.name, CONCAT(r.block_number, r.unit_number, r.house_number), r.block_number,
+	a.type, a.credential_no, a.holder_name, a.status, a.valid_until, a.note, a.issued_by,
+	a.suspended_at, a.suspend_reason, a.revoked_at, a.revoke_reason,
+	EXISTS (SELECT 1 FROM access_credential_syncs s WHERE s.credential_id = a.id AND s.status = 'failed'),
+	a.created_at, a.updated_at
+	FROM access_credentials a
+	JOIN residents r ON a.resident_id = r.id
+`
+
+// scanAccessCredential 解析一行门禁凭证数据
+func scanAccessCredential(scanner interface{ Scan(...interface{}) error }) (*AccessCredential, error) {
+	var a AccessCredential
+	var validUntil, suspendedAt, revokedAt sql.NullTime
+	err := scanner.Scan(&a.ID, &a.CommunityID, &a.ResidentID, &a.ResidentName, &a.Room, &a.BlockNumber,
+		&a.Type, &a.CredentialNo, &a.HolderName, &a.Status, &validUntil, &a.Note, &a.IssuedBy,
+		&suspendedAt, &a.SuspendReason, &revokedAt, &a.RevokeReason,
+		&a.SyncFailed, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	a.ValidUntil = nullTimePtr(validUntil)
+	a.SuspendedAt = nullTimePtr(suspendedAt)
+	a.RevokedAt = nullTimePtr(revokedAt)
+	return &a, nil
+}
+
+// GetAccessCredentials 获取门禁凭证列表，支持按住户、类型、状态、关键字（凭证号、持有人、住户、房号）查询，
+// filters["sync_failed"]为true时只返回存在下发失败的凭证。手机开门密钥已隐藏
+func GetAccessCredentials(page, pageSize int, filters map[string]interface{}) ([]AccessCredential, int, error) {
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	for _, key := range []string{"type", "status"} {
+		if v, ok := filters[key].(string); ok && v != "" {
+			where += ` AND a.` + key + ` = ?`
+			args = append(args, v)
+		}
+	}
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND a.resident_id = ?`
+		args = append(args, residentID)
+	}
+	if keyword, ok := filters["keyword"].(string); ok && keyword != "" {
+		where += ` AND (a.credential_no = ? OR a.holder_name LIKE ? OR r.name LIKE ?
+			OR CONCAT(r.block_number, r.unit_number, r.house_number) LIKE ?)`
+		like := "%" + keyword + "%"
+		args = append(args, keyword, like, like, like)
+	}
+	if failed, ok := filters["sync_failed"].(bool); ok && failed {
+		where += ` AND EXISTS (SELECT 1 FROM access_credential_syncs s WHERE s.credential_id = a.id AND s.status = ?)`
+		args = append(args, CredentialSyncFailed)
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM access_credentials a JOIN residents r ON a.resident_id = r.id` + where
+	if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + accessCredentialColumns + where + `
+		ORDER BY a.status = 'revoked', a.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	credentials := []AccessCredential{}
+	for rows.Next() {
+		a, err := scanAccessCredential(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		a.Mask()
+		credentials = append(credentials, *a)
+	}
+
+	return credentials, total, rows.Err()
+}
+
+// GetAccessCredentialByID 通过ID获取门禁凭证及各控制器的下发结果，凭证号未隐藏
+func GetAccessCredentialByID(id int) (*AccessCredential, error) {
+	a, err := scanAccessCredential(database.DB.QueryRow(`SELECT `+accessCredentialColumns+` WHERE a.id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT credential_id, controller, action, status, error, attempts, updated_at
+		FROM access_credential_syncs WHERE credential_id = ? ORDER BY controller`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanAccessCredentialSync(rows)
+		if err != nil {
+			return nil, err
+		}
+		a.Syncs = append(a.Syncs, *s)
+	}
+
+	return a, rows.Err()
+}
+
+// TransitionAccessCredential 按操作变更凭证状态：暂停、恢复或注销
+func TransitionAccessCredential(id int, action, reason string) error {
+	t, ok := credentialTransitions[action]
+	if !ok {
+		return fmt.Errorf("%w: 未知操作%s", ErrCredentialTransition, action)
+	}
+
+	var status string
+	err := database.DB.QueryRow(`SELECT status FROM access_credentials WHERE id = ?`, id).Scan(&status)
+	if err != nil {
+		return err
+	}
+	allowed := false
+	for _, from := range t.from {
+		if status == from {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s状态的凭证不能%s", ErrCredentialTransition, status, t.label)
+	}
+
+	query := `UPDATE access_credentials SET status = ?`
+	args := []interface{}{t.to}
+	switch t.to {
+	case CredentialSuspended:
+		query += `, suspended_at = NOW(), suspend_reason = ?`
+		args = append(args, reason)
+	case CredentialActive:
+		query += `, suspended_at = NULL, suspend_reason = ''`
+	case CredentialRevoked:
+		query += `, revoked_at = NOW(), revoke_reason = ?`
+		args = append(args, reason)
+	}
+	query += ` WHERE id = ? AND status = ?`
+	args = append(args, id, status)
+
+	result, err := database.DB.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: 凭证状态已被他人修改，请刷新后重试", ErrCredentialTransition)
+	}
+	return nil
+}
+
+// scanAccessCredentialSync 解析一行凭证下发结果
+func scanAccessCredentialSync(scanner interface{ Scan(...interface{}) error }) (*AccessCredentialSync, error) {
+	var s AccessCredentialSync
+	err := scanner.Scan(&s.CredentialID, &s.Controller, &s.Action, &s.Status, &s.Error, &s.Attempts, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// RecordAccessCredentialSync 记录凭证在控制器上的下发结果，
+// 同一操作重复下发时累计尝试次数，操作变化时重新计数
+func RecordAccessCredentialSync(credentialID int, controller, action string, syncErr error) error {
+	status, message := CredentialSyncSuccess, ""
+	if syncErr != nil {
+		status, message = CredentialSyncFailed, syncErr.Error()
+		if len(message) > 500 {
+			message = message[:500]
+		}
+	}
+
+	_, err := database.DB.Exec(`
+		INSERT INTO access_credential_syncs (credential_id, controller, action, status, error, attempts)
+		VALUES (?, ?, ?, ?, ?, 1)
+		ON DUPLICATE KEY UPDATE
+			attempts = IF(action = VALUES(action), attempts + 1, 1),
+			action = VALUES(action), status = VALUES(status), error = VALUES(error)`,
+		credentialID, controller, action, status, message,
+	)
+	return err
+}
+
+// GetFailedAccessCredentialSyncs 获取下发失败且尝试次数少于maxAttempts的记录
+func GetFailedAccessCredentialSyncs(maxAttempts int) ([]AccessCredentialSync, error) {
+	rows, err := database.DB.Query(`
+		SELECT credential_id, controller, action, status, error, attempts, updated_at
+		FROM access_credential_syncs WHERE status = ? AND attempts < ?
+		ORDER BY updated_at`, CredentialSyncFailed, maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	syncs := []AccessCredentialSync{}
+	for rows.Next() {
+		s, err := scanAccessCredentialSync(rows)
+		if err != nil {
+			return nil, err
+		}
+		syncs = append(syncs, *s)
+	}
+
+	return syncs, rows.Err()
+}
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"community-backward/database"
+)
+
+// 账龄区间
+const (
+	AgingBucket0To30  = "0-30"
+	AgingBucket31To60 = "31-60"
+	AgingBucket61To90 = "61-90"
+	AgingBucketOver90 = "90+"
+)
+
+// AgingBuckets 表示各账龄区间的欠费金额
+type AgingBuckets struct {
+	Days0To30  float64 `json:"days0To30"`
+	Days31To60 float64 `json:"days31To60"`
+	Days61To90 float64 `json:"days61To90"`
+	Over90     float64 `json:"over90"`
+	Total      float64 `json:"total"`
+}
+
+// AgingBuildingRow 表示某栋楼的账龄汇总
+type AgingBuildingRow struct {
+	Building   string `json:"building"`
+	Households int    `json:"households"`
+	AgingBuckets
+}
+
+// AgingHousehold 表示某住户的账龄明细
+type AgingHousehold struct {
+	ResidentID  int    `json:"resident_id"`
+	Name        string `json:"name"`
+	BlockNumber string `json:"building"`
+	UnitNumber  string `json:"unit"`
+	HouseNumber string `json:"room"`
+	AgingBuckets
+}
+
+// AgingReport 表示应收账款账龄报表
+type AgingReport struct {
+	AsOf      string             `json:"asOf"`
+	Buildings []AgingBuildingRow `json:"buildings"`
+	Total     AgingBuckets       `json:"total"`
+}
+
+// AgingBucketOf 根据逾期天数返回账龄区间
+func AgingBucketOf(days int) string {
+	switch {
+	case days <= 30:
+		return AgingBucket0To30
+	case days <= 60:
+		return AgingBucket31To60
+	case days <= 90:
+		return AgingBucket61To90
+	}
+	return AgingBucketOver90
+}
+
+// add 将金额计入对应账龄区间
+func (b *AgingBuckets) add(bucket string, amount float64) {
+	switch bucket {
+	case AgingBucket0To30:
+		b.Days0To30 = roundAmount(b.Days0To30 + amount)
+	case AgingBucket31To60:
+		b.Days31To60 = roundAmount(b.Days31To60 + amount)
+	case AgingBucket61To90:
+		b.Days61To90 = roundAmount(b.Days61To90 + amount)
+	case AgingBucketOver90:
+		b.Over90 = roundAmount(b.Over90 + amount)
+	}
+	b.Total = roundAmount(b.Total + amount)
+}
+
+// Amount 返回指定账龄区间的金额，bucket为空时返回合计
+func (b AgingBuckets) Amount(bucket string) float64 {
+	switch bucket {
+	case AgingBucket0To30:
+		return b.Days0To30
+	case AgingBucket31To60:
+		return b.Days31To60
+	case AgingBucket61To90:
+		return b.Days61To90
+	case AgingBucketOver90:
+		return b.Over90
+	}
+	return b.Total
+}
+
+// GetAgingHouseholds 获取截至asOf指定社区各住户的账龄明细
+// building不为空时只返回该楼栋，bucket不为空时只返回在该区间有欠费的住户
+func GetAgingHouseholds(asOf time.Time, building, bucket string, communityID int) ([]AgingHousehold, error) {
+	open, err := GetOpenBills(asOf, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	residents, err := getResidentIndex(communityID)
+	if err != nil {
+		return nil, err
+	}
+
+	households := make(map[int]*AgingHousehold)
+	for _, b := range open {
+		r, ok := residents[b.ResidentID]
+		if !ok || (building != "" && r.BlockNumber != building) {
+			continue
+		}
+		h, ok := households[b.ResidentID]
+		if !ok {
+			h = &AgingHousehold{
+				ResidentID:  r.ID,
+				Name:        r.Name,
+				BlockNumber: r.BlockNumber,
+				UnitNumber:  r.UnitNumber,
+				HouseNumber: r.HouseNumber,
+			}
+			households[b.ResidentID] = h
+		}
+		h.add(AgingBucketOf(daysBetween(b.DueDate, asOf)), b.Outstanding)
+	}
+
+	result := []AgingHousehold{}
+	for _, h := range households {
+		if bucket != "" && h.Amount(bucket) <= 0 {
+			continue
+		}
+		result = append(result, *h)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.BlockNumber != b.BlockNumber {
+			return a.BlockNumber < b.BlockNumber
+		}
+		if a.UnitNumber != b.UnitNumber {
+			return a.UnitNumber < b.UnitNumber
+		}
+		return a.HouseNumber < b.HouseNumber
+	})
+
+	return result, nil
+}
+
+// GetAgingReport 获取截至asOf指定社区按楼栋汇总的应收账款账龄报表
+func GetAgingReport(asOf time.Time, communityID int) (*AgingReport, error) {
+	households, err := GetAgingHouseholds(asOf, "", "", communityID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AgingReport{AsOf: asOf.Format("2006-01-02"), Buildings: []AgingBuildingRow{}}
+	index := make(map[string]int)
+	for _, h := range households {
+		i, ok := index[h.BlockNumber]
+		if !ok {
+			i = len(report.Buildings)
+			index[h.BlockNumber] = i
+			report.Buildings = append(report.Buildings, AgingBuildingRow{Building: h.BlockNumber})
+		}
+		row := &report.Buildings[i]
+		row.Households++
+		for _, bucket := range []string{AgingBucket0To30, AgingBucket31To60, AgingBucket61To90, AgingBucketOver90} {
+			if amount := h.Amount(bucket); amount > 0 {
+				row.add(bucket, amount)
+				report.Total.add(bucket, amount)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// getResidentIndex 获取指定社区的住户，按ID索引，communityID为0时获取所有社区的住户
+func getResidentIndex(communityID int) (map[int]Resident, error) {
+	query := `SELECT id, name, block_number, unit_number, house_number, house_area FROM residents`
+	var args []interface{}
+	if communityID > 0 {
+		query += ` WHERE community_id = ?`
+		args = append(args, communityID)
+	}
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询住户失败: %w", err)
+	}
+	defer rows.Close()
+
+	residents := make(map[int]Resident)
+	for rows.Next() {
+		var r Resident
+		if err := rows.Scan(&r.ID, &r.Name, &r.BlockNumber, &r.UnitNumber, &r.HouseNumber, &r.HouseArea); err != nil {
+			return nil, fmt.Errorf("解析住户失败: %w", err)
+		}
+		residents[r.ID] = r
+	}
+
+	return residents, rows.Err()
+}
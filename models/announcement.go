@@ -0,0 +1,610 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"community-backward/database"
+)
+
+// 公告状态，scheduled、active和expired由已发布公告的发布时间和过期时间计算得出
+const (
+	AnnouncementDraft     = "draft"
+	AnnouncementPublished = "published"
+	AnnouncementWithdrawn = "withdrawn"
+	AnnouncementScheduled = "scheduled" // 已发布，未到发布时间
+	AnnouncementActive    = "active"    // 已发布，住户可见
+	AnnouncementExpired   = "expired"   // 已发布，已过期
+)
+
+// 公告发布范围
+const (
+	AudienceAll      = "all"      // 全体住户
+	AudienceTargeted = "targeted" // 指定楼栋或单元
+)
+
+var (
+	// ErrInvalidAnnouncement 公告参数错误
+	ErrInvalidAnnouncement = errors.New("公告参数错误")
+	// ErrAnnouncementState 公告当前状态不允许该操作
+	ErrAnnouncementState = errors.New("公告当前状态不允许该操作")
+	// ErrAnnouncementNotFound 公告不存在
+	ErrAnnouncementNotFound = errors.New("公告不存在")
+)
+
+// 富文本中不允许出现的标签、事件属性和脚本链接
+var (
+	unsafeBlockPattern = regexp.MustCompile(`(?is)<(script|style|iframe|object|embed|form)\b[^>]*>.*?</\s*(script|style|iframe|object|embed|form)\s*>`)
+	unsafeTagPattern   = regexp.MustCompile(`(?i)</?(script|style|iframe|object|embed|form)\b[^>]*>`)
+	eventAttrPattern   = regexp.MustCompile(`(?i)\s+on[a-z]+\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+	scriptLinkPattern  = regexp.MustCompile(`(?i)(href|src)\s*=\s*("\s*javascript:[^"]*"|'\s*javascript:[^']*'|javascript:[^\s>]+)`)
+)
+
+// AnnouncementTarget 表示公告发布范围中的楼栋或单元，unit为空表示整栋楼
+type AnnouncementTarget struct {
+	BlockNumber string `json:"building" binding:"required"`
+	UnitNumber  string `json:"unit"`
+}
+
+// Announcement 表示社区公告
+type Announcement struct {
+	ID            int                  `json:"id"`
+	CommunityID   int                  `json:"community_id"`
+	Title         string               `json:"title"`
+	Content       string               `json:"content"` // 富文本（HTML）
+	Audience      string               `json:"audience"`
+	Targets       []AnnouncementTarget `json:"targets,omitempty"`
+	Status        string               `json:"status"`
+	PublishAt     *time.Time           `json:"publish_at,omitempty"`
+	ExpireAt      *time.Time           `json:"expire_at,omitempty"`
+	Pinned        bool                 `json:"pinned"`
+	CreatedBy     int                  `json:"created_by"`
+	PublishedBy   int                  `json:"published_by,omitempty"`
+	ReadCount     int                  `json:"read_count"`
+	AudienceCount int                  `json:"audience_count,omitempty"` // 发布范围内已开通的住户账号数
+	ReadAt        *time.Time           `json:"read_at,omitempty"`        // 住户端：当前账号的阅读时间
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+// AnnouncementRequest 表示创建或修改公告请求
+type AnnouncementRequest struct {
+	Title     string               `json:"title" binding:"required,max=100"`
+	Content   string               `json:"content" binding:"required"`
+	Audience  string               `json:"audience" binding:"required,oneof=all targeted"`
+	Targets   []AnnouncementTarget `json:"targets" binding:"dive"`
+	PublishAt string               `json:"publish_at"` // 格式: 2006-01-02 15:04，为空时发布即可见
+	ExpireAt  string               `json:"expire_at"`  // 格式: 2006-01-02 15:04，为空表示长期有效
+	Pinned    bool                 `json:"pinned"`
+}
+
+// AnnouncementPinRequest 表示置顶或取消置顶请求
+type AnnouncementPinRequest struct {
+	Pinned bool `json:"pinned"`
+}
+
+// AnnouncementReceipt 表示发布范围内住户账号的阅读情况
+type AnnouncementReceipt struct {
+	UserID       int        `json:"user_id"`
+	Username     string     `json:"username"`
+	ResidentID   int        `json:"resident_id"`
+	ResidentName string     `json:"resident_name"`
+	BlockNumber  string     `json:"building"`
+	UnitNumber   string     `json:"unit"`
+	HouseNumber  string     `json:"room"`
+	ReadAt       *time.Time `json:"read_at"`
+}
+
+// SanitizeHTML 去除富文本中的脚本、内嵌页面、事件属性和javascript链接
+func SanitizeHTML(content string) string {
+	content = unsafeBlockPattern.ReplaceAllString(content, "")
+	content = unsafeTagPattern.ReplaceAllString(content, "")
+	content = eventAttrPattern.ReplaceAllString(content, "")
+	return scriptLinkPattern.ReplaceAllString(content, `$1="#"`)
+}
+
+// parseDateTime 解析公告时间，支持精确到秒、分钟或日期
+func parseDateTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: 无效的时间%s", ErrInvalidAnnouncement, value)
+}
+
+// validateAnnouncement 校验公告请求，返回解析后的发布时间和过期时间
+func validateAnnouncement(req *AnnouncementRequest) (*time.Time, *time.Time, error) {
+	publishAt, err := parseDateTime(req.PublishAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	expireAt, err := parseDateTime(req.ExpireAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if publishAt != nil && expireAt != nil && !expireAt.After(*publishAt) {
+		return nil, nil, fmt.Errorf("%w: 过期时间必须晚于发布时间", ErrInvalidAnnouncement)
+	}
+
+	req.Content = SanitizeHTML(req.Content)
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, nil, fmt.Errorf("%w: 公告内容不能为空", ErrInvalidAnnouncement)
+	}
+
+	if req.Audience == AudienceAll {
+		req.Targets = nil
+	} else if len(req.Targets) == 0 {
+		return nil, nil, fmt.Errorf("%w: 请指定发布的楼栋或单元", ErrInvalidAnnouncement)
+	}
+
+	return publishAt, expireAt, nil
+}
+
+// saveAnnouncementTargets 替换公告的发布范围
+func saveAnnouncementTargets(tx *sql.Tx, id int, targets []AnnouncementTarget) error {
+	if _, err := tx.Exec(`DELETE FROM announcement_targets WHERE announcement_id = ?`, id); err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if _, err := tx.Exec(`
+			INSERT IGNORE INTO announcement_targets (announcement_id, block_number, unit_number) VALUES (?, ?, ?)`,
+			id, strings.TrimSpace(t.BlockNumber), strings.TrimSpace(t.UnitNumber),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// announcementStatus 根据发布时间和过期时间计算已发布公告的显示状态
+func announcementStatus(status string, publishAt, expireAt *time.Time, now time.Time) string {
+	if status != AnnouncementPublished {
+		return status
+	}
+	if publishAt != nil && publishAt.After(now) {
+		return AnnouncementScheduled
+	}
+	if expireAt != nil && !expireAt.After(now) {
+		return AnnouncementExpired
+	}
+	return AnnouncementActive
+}
+
+const announcementColumns = `
+	a.id, a.community_id, a.title, a.content, a.audience, a.status, a.publish_at, a.expire_at, a.pinned,
+	a.created_by, COALESCE(a.published_by, 0), a.created_at, a.updated_at,
+	(SELECT COUNT(*) FROM announcement_reads ar WHERE ar.announcement_id = a.id)
+`
+
+// scanAnnouncement 解析一行公告数据
+func scanAnnouncement(scanner interface{ Scan(...interface{}) error }, now time.Time) (*Announcement, error) {
+	var a Announcement
+	var publishAt, expireAt sql.NullTime
+	err := scanner.Scan(&a.ID, &a.CommunityID, &a.Title, &a.Content, &a.Audience, &a.Status, &publishAt, &expireAt, &a.Pinned,
+		&a.CreatedBy, &a.PublishedBy, &a.CreatedAt, &a.UpdatedAt, &a.ReadCount)
+	if err != nil {
+		return nil, err
+	}
+
+	a.PublishAt = nullTimePtr(publishAt)
+	a.ExpireAt = nullTimePtr(expireAt)
+	a.Status = announcementStatus(a.Status, a.PublishAt, a.ExpireAt, now)
+	return &a, nil
+}
+
+// GetAnnouncements 获取公告列表，置顶公告在前
+func GetAnnouncements(page, pageSize int, filters map[string]interface{}) ([]Announcement, int, error) {
+	now := time.Now()
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND a.community_id = ?`
+		args = append(args, communityID)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		switch status {
+		case AnnouncementScheduled:
+			where += ` AND a.status = 'published' AND a.publish_at > ?`
+			args = append(args, now)
+		case AnnouncementActive:
+			where += ` AND a.status = 'published' AND a.publish_at <= ? AND (a.expire_at IS NULL OR a.expire_at > ?)`
+			args = append(args, now, now)
+		case AnnouncementExpired:
+			where += ` AND a.status = 'published' AND a.expire_at <= ?`
+			args = append(args, now)
+		default:
+			where += ` AND a.status = ?`
+			args = append(args, status)
+		}
+	}
+	if keyword, ok := filters["keyword"].(string); ok && keyword != "" {
+		where += ` AND a.title LIKE ?`
+		args = append(args, "%"+keyword+"%")
+	}
+	if pinned, ok := filters["pinned"].(bool); ok {
+		where += ` AND a.pinned = ?`
+		args = append(args, pinned)
+	}
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM announcements a`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + announcementColumns + ` FROM announcements a` + where + `
+		ORDER BY a.pinned DESC, COALESCE(a.publish_at, a.created_at) DESC, a.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	list := []Announcement{}
+	for rows.Next() {
+		a, err := scanAnnouncement(rows, now)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *a)
+	}
+
+	return list, total, rows.Err()
+}
+
+// GetAnnouncementByID 获取公告详情，含发布范围和阅读统计
+func GetAnnouncementByID(id int) (*Announcement, error) {
+	row := database.DB.QueryRow(`SELECT `+announcementColumns+` FROM announcements a WHERE a.id = ?`, id)
+	a, err := scanAnnouncement(row, time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if a.Targets, err = getAnnouncementTargets(id); err != nil {
+		return nil, err
+	}
+
+	err = database.DB.QueryRow(`
+		SELECT COUNT(*) FROM users u
+		JOIN residents r ON u.resident_id = r.id
+		JOIN announcements a ON a.id = ?
+		WHERE u.role = ? AND `+audienceCondition, id, RoleResident).Scan(&a.AudienceCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// audienceCondition 判断住户r是否在公告a的发布范围内，发布范围限于公告所在社区
+const audienceCondition = `r.community_id = a.community_id AND (a.audience = 'all' OR EXISTS (
+	SELECT 1 FROM announcement_targets t
+	WHERE t.announcement_id = a.id AND t.block_number = r.block_number AND (t.unit_number = '' OR t.unit_number = r.unit_number)
+))`
+
+// getAnnouncementTargets 获取公告的发布范围
+func getAnnouncementTargets(id int) ([]AnnouncementTarget, error) {
+	rows, err := database.DB.Query(`
+		SELECT block_number, unit_number FROM announcement_targets
+		WHERE announcement_id = ? ORDER BY block_number, unit_number`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := []AnnouncementTarget{}
+	for rows.Next() {
+		var t AnnouncementTarget
+		if err := rows.Scan(&t.BlockNumber, &t.UnitNumber); err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, rows.Err()
+}
+
+// CreateAnnouncement 在指定社区创建公告草稿
+func CreateAnnouncement(req AnnouncementRequest, createdBy uint, communityID int) (int, error) {
+	publishAt, expireAt, err := validateAnnouncement(&req)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO announcements (community_id, title, content, audience, status, publish_at, expire_at, pinned, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		communityID, req.Title, req.Content, req.Audience, AnnouncementDraft, publishAt, expireAt, req.Pinned, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := saveAnnouncementTargets(tx, int(id), req.Targets); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateAnnouncement 修改公告，已撤回的公告不能修改
+func UpdateAnnouncement(id int, req AnnouncementRequest) error {
+	publishAt, expireAt, err := validateAnnouncement(&req)
+	if err != nil {
+		return err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM announcements WHERE id = ? FOR UPDATE`, id).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrAnnouncementNotFound
+		}
+		return err
+	}
+	if status == AnnouncementWithdrawn {
+		return fmt.Errorf("%w: 已撤回的公告不能修改", ErrAnnouncementState)
+	}
+
+	// 已发布的公告未填写发布时间时保留原发布时间
+	if _, err := tx.Exec(`
+		UPDATE announcements SET title = ?, content = ?, audience = ?, publish_at = COALESCE(?, IF(status = 'published', publish_at, NULL)),
+		       expire_at = ?, pinned = ?
+		WHERE id = ?`,
+		req.Title, req.Content, req.Audience, publishAt, expireAt, req.Pinned, id,
+	); err != nil {
+		return err
+	}
+
+	if err := saveAnnouncementTargets(tx, id, req.Targets); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PublishAnnouncement 发布公告草稿，未设置发布时间时立即可见
+func PublishAnnouncement(id int, userID uint) error {
+	a, err := GetAnnouncementByID(id)
+	if err != nil {
+		return err
+	}
+	if a == nil {
+		return ErrAnnouncementNotFound
+	}
+	if a.Status != AnnouncementDraft {
+		return fmt.Errorf("%w: 只有草稿可以发布", ErrAnnouncementState)
+	}
+	if a.ExpireAt != nil && !a.ExpireAt.After(time.Now()) {
+		return fmt.Errorf("%w: 公告已过期，请修改过期时间", ErrInvalidAnnouncement)
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE announcements SET status = ?, publish_at = COALESCE(publish_at, NOW()), published_by = ?
+		WHERE id = ? AND status = ?`,
+		AnnouncementPublished, userID, id, AnnouncementDraft,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: 只有草稿可以发布", ErrAnnouncementState)
+	}
+
+	return nil
+}
+
+// WithdrawAnnouncement 撤回已发布的公告，撤回后住户不可见
+func WithdrawAnnouncement(id int) error {
+	result, err := database.DB.Exec(`
+		UPDATE announcements SET status = ?, pinned = 0 WHERE id = ? AND status = ?`,
+		AnnouncementWithdrawn, id, AnnouncementPublished,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: 只有已发布的公告可以撤回", ErrAnnouncementState)
+	}
+
+	return nil
+}
+
+// SetAnnouncementPinned 置顶或取消置顶公告
+func SetAnnouncementPinned(id int, pinned bool) error {
+	result, err := database.DB.Exec(`
+		UPDATE announcements SET pinned = ? WHERE id = ? AND status <> ?`,
+		pinned, id, AnnouncementWithdrawn,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		a, err := GetAnnouncementByID(id)
+		if err != nil {
+			return err
+		}
+		if a == nil {
+			return ErrAnnouncementNotFound
+		}
+		if a.Status == AnnouncementWithdrawn {
+			return fmt.Errorf("%w: 已撤回的公告不能置顶", ErrAnnouncementState)
+		}
+	}
+
+	return nil
+}
+
+// DeleteAnnouncement 删除公告草稿
+func DeleteAnnouncement(id int) error {
+	result, err := database.DB.Exec(`DELETE FROM announcements WHERE id = ? AND status = ?`, id, AnnouncementDraft)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: 只有草稿可以删除，已发布的公告请撤回", ErrAnnouncementState)
+	}
+
+	return nil
+}
+
+// GetAnnouncementReceipts 获取公告发布范围内住户账号的阅读回执
+// read为true只看已读，为false只看未读，为nil返回全部
+func GetAnnouncementReceipts(id int, read *bool) ([]AnnouncementReceipt, error) {
+	query := `
+		SELECT u.id, u.username, r.id, r.name, r.block_number, r.unit_number, r.house_number, ar.read_at
+		FROM users u
+		JOIN residents r ON u.resident_id = r.id
+		JOIN announcements a ON a.id = ?
+		LEFT JOIN announcement_reads ar ON ar.announcement_id = a.id AND ar.user_id = u.id
+		WHERE u.role = ? AND ` + audienceCondition
+	if read != nil {
+		if *read {
+			query += ` AND ar.id IS NOT NULL`
+		} else {
+			query += ` AND ar.id IS NULL`
+		}
+	}
+	query += ` ORDER BY r.block_number, r.unit_number, r.house_number`
+
+	rows, err := database.DB.Query(query, id, RoleResident)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := []AnnouncementReceipt{}
+	for rows.Next() {
+		var rc AnnouncementReceipt
+		var readAt sql.NullTime
+		if err := rows.Scan(&rc.UserID, &rc.Username, &rc.ResidentID, &rc.ResidentName,
+			&rc.BlockNumber, &rc.UnitNumber, &rc.HouseNumber, &readAt); err != nil {
+			return nil, err
+		}
+		rc.ReadAt = nullTimePtr(readAt)
+		receipts = append(receipts, rc)
+	}
+
+	return receipts, rows.Err()
+}
+
+// residentVisibleCondition 判断公告a当前对住户r可见
+const residentVisibleCondition = `a.status = 'published' AND a.publish_at <= ? AND (a.expire_at IS NULL OR a.expire_at > ?) AND ` + audienceCondition
+
+// GetResidentAnnouncements 获取住户账号当前可见的公告，置顶公告在前
+func GetResidentAnnouncements(userID uint, residentID, page, pageSize int, unreadOnly bool) ([]Announcement, int, error) {
+	now := time.Now()
+	from := `
+		FROM announcements a
+		JOIN residents r ON r.id = ?
+		LEFT JOIN announcement_reads mine ON mine.announcement_id = a.id AND mine.user_id = ?
+		WHERE ` + residentVisibleCondition
+	args := []interface{}{residentID, userID, now, now}
+	if unreadOnly {
+		from += ` AND mine.id IS NULL`
+	}
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*)`+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + announcementColumns + `, mine.read_at` + from + `
+		ORDER BY a.pinned DESC, a.publish_at DESC, a.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	list := []Announcement{}
+	for rows.Next() {
+		a, err := scanResidentAnnouncement(rows, now)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *a)
+	}
+
+	return list, total, rows.Err()
+}
+
+// GetResidentAnnouncement 获取住户账号可见的公告，不可见时返回nil
+func GetResidentAnnouncement(userID uint, residentID, id int) (*Announcement, error) {
+	now := time.Now()
+	row := database.DB.QueryRow(`SELECT `+announcementColumns+`, mine.read_at
+		FROM announcements a
+		JOIN residents r ON r.id = ?
+		LEFT JOIN announcement_reads mine ON mine.announcement_id = a.id AND mine.user_id = ?
+		WHERE a.id = ? AND `+residentVisibleCondition, residentID, userID, id, now, now)
+	a, err := scanResidentAnnouncement(row, now)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// scanResidentAnnouncement 解析住户端公告数据，附带当前账号的阅读时间
+func scanResidentAnnouncement(scanner interface{ Scan(...interface{}) error }, now time.Time) (*Announcement, error) {
+	var readAt sql.NullTime
+	a, err := scanAnnouncement(scanFunc(func(dest ...interface{}) error {
+		return scanner.Scan(append(dest, &readAt)...)
+	}), now)
+	if err != nil {
+		return nil, err
+	}
+	a.ReadAt = nullTimePtr(readAt)
+	return a, nil
+}
+
+// scanFunc 将函数适配为Scan接口
+type scanFunc func(dest ...interface{}) error
+
+// Scan 调用函数本身
+func (f scanFunc) Scan(dest ...interface{}) error {
+	return f(dest...)
+}
+
+// MarkAnnouncementRead 记录住户账号阅读公告，重复阅读只保留首次阅读时间
+func MarkAnnouncementRead(id int, userID uint, residentID int) error {
+	_, err := database.DB.Exec(`
+		INSERT IGNORE INTO announcement_reads (announcement_id, user_id, resident_id) VALUES (?, ?, ?)`,
+		id, userID, residentID,
+	)
+	return err
+}
@@ -0,0 +1,833 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"community-backward/database"
+)
+
+// 设备分类
+const (
+	AssetElevator   = "elevator"   // 电梯
+	AssetPump       = "pump"       // 水泵
+	AssetFire       = "fire"       // 消防设施
+	AssetElectrical = "electrical" // 配电设备
+	AssetOther      = "other"      // 其他
+)
+
+// 设备状态
+const (
+	AssetInService    = "in_service"     // 在用
+	AssetOutOfService = "out_of_service" // 停用
+	AssetRetired      = "retired"        // 报废
+)
+
+// 维保计划类型和周期单位
+const (
+	ScheduleMaintenance = "maintenance" // 日常维保
+	ScheduleInspection  = "inspection"  // 法定检验
+	IntervalDay         = "day"
+	IntervalMonth       = "month"
+)
+
+// 维保任务状态
+const (
+	TaskPending   = "pending"   // 待完成
+	TaskCompleted = "completed" // 已完成
+)
+
+// 检查结果，不合格的检查不会完成维保任务
+const (
+	InspectionPass    = "pass"    // 合格
+	InspectionRectify = "rectify" // 需整改
+	InspectionFail    = "fail"    // 不合格
+)
+
+// assetWorkOrderCategories 设备分类对应的工单分类代码，生成维保任务时据此创建工单
+var assetWorkOrderCategories = map[string]string{
+	AssetElevator:   "elevator",
+	AssetPump:       "plumbing",
+	AssetFire:       "public_facility",
+	AssetElectrical: "electrical",
+	AssetOther:      "public_facility",
+}
+
+var (
+	// ErrInvalidAsset 设备信息不完整
+	ErrInvalidAsset = errors.New("无效的设备信息")
+	// ErrAssetExists 设备编号已存在
+	ErrAssetExists = errors.New("设备编号已存在")
+	// ErrInvalidMaintenanceSchedule 维保计划设置无效
+	ErrInvalidMaintenanceSchedule = errors.New("无效的维保计划")
+	// ErrInvalidInspection 检查记录无效
+	ErrInvalidInspection = errors.New("无效的检查记录")
+	// ErrTaskNotPending 维保任务不存在、已完成或不属于该设备
+	ErrTaskNotPending = errors.New("维保任务不是待完成状态")
+)
+
+// Asset 表示设备台账中的一台设备
+type Asset struct {
+	ID           int                   `json:"id"`
+	CommunityID  int                   `json:"community_id"`
+	AssetNo      string                `json:"asset_no"`
+	Name         string                `json:"name"`
+	Category     string                `json:"category"`
+	BlockNumber  string                `json:"block_number"` // 所在楼栋，为空表示小区公共设备
+	Location     string                `json:"location"`
+	Model        string                `json:"model"`
+	Manufacturer string                `json:"manufacturer"`
+	InstallDate  *time.Time            `json:"install_date,omitempty"`
+	Status       string                `json:"status"`
+	Remark       string                `json:"remark"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+	Schedules    []MaintenanceSchedule `json:"schedules,omitempty"`
+}
+
+// AssetRequest 表示新增或修改设备请求
+type AssetRequest struct {
+	AssetNo      string `json:"asset_no" binding:"required,max=30"`
+	Name         string `json:"name" binding:"required,max=100"`
+	Category     string `json:"category" binding:"required,oneof=elevator pump fire electrical other"`
+	BlockNumber  string `json:"block_number" binding:"max=20"`
+	Location     string `json:"location" binding:"max=100"`
+	Model        string `json:"model" binding:"max=50"`
+	Manufacturer string `json:"manufacturer" binding:"max=100"`
+	InstallDate  string `json:"install_date"` // 格式: 2006-01-02
+	Status       string `json:"status" binding:"omitempty,oneof=in_service out_of_service retired"`
+	Remark       string `json:"remark" binding:"max=255"`
+}
+
+// MaintenanceSchedule 表示设备的维保计划
+type MaintenanceSchedule struct {
+	ID            int        `json:"id"`
+	AssetID       int        `json:"asset_id"`
+	Name          string     `json:"name"`
+	Kind          string     `json:"kind"`
+	IntervalValue int        `json:"interval_value"`
+	IntervalUnit  string     `json:"interval_unit"`
+	LeadDays      int        `json:"lead_days"`
+	NextDue       time.Time  `json:"next_due"`
+	LastDone      *time.Time `json:"last_done,omitempty"`
+	Enabled       bool       `json:"enabled"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// MaintenanceScheduleRequest 表示新增或修改维保计划请求
+type MaintenanceScheduleRequest struct {
+	Name          string `json:"name" binding:"required,max=100"`
+	Kind          string `json:"kind" binding:"required,oneof=maintenance inspection"`
+	IntervalValue int    `json:"interval_value" binding:"required,min=1"`
+	IntervalUnit  string `json:"interval_unit" binding:"required,oneof=day month"`
+	LeadDays      int    `json:"lead_days" binding:"min=0"`
+	NextDue       string `json:"next_due" binding:"required"` // 格式: 2006-01-02
+	Enabled       bool   `json:"enabled"`
+}
+
+// MaintenanceTask 表示按维保计划生成的任务
+type MaintenanceTask struct {
+	ID           int        `json:"id"`
+	CommunityID  int        `json:"community_id"` // 设备所在社区
+	ScheduleID   int        `json:"schedule_id"`
+	ScheduleName string     `json:"schedule_name"`
+	Kind         string     `json:"kind"`
+	AssetID      int        `json:"asset_id"`
+	AssetNo      string     `json:"asset_no"`
+	AssetName    string     `json:"asset_name"`
+	BlockNumber  string     `json:"block_number"`
+	Location     string     `json:"location"`
+	DueDate      time.Time  `json:"due_date"`
+	Status       string     `json:"status"`
+	WorkOrderID  int        `json:"work_order_id,omitempty"`
+	WorkOrderNo  string     `json:"work_order_no,omitempty"`
+	InspectionID int        `json:"inspection_id,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	OverdueDays  int        `json:"overdue_days"` // 待完成任务超过到期日的天数
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// AssetInspection 表示一次设备检查或维保记录
+type AssetInspection struct {
+	ID            int              `json:"id"`
+	CommunityID   int              `json:"community_id"` // 设备所在社区
+	AssetID       int              `json:"asset_id"`
+	AssetName     string           `json:"asset_name"`
+	TaskID        int              `json:"task_id,omitempty"`
+	InspectedOn   time.Time        `json:"inspected_on"`
+	Inspector     string           `json:"inspector"`
+	Agency        string           `json:"agency"` // 检验机构或维保单位
+	CertificateNo string           `json:"certificate_no"`
+	Result        string           `json:"result"`
+	Notes         string           `json:"notes"`
+	CreatedBy     int              `json:"created_by"`
+	CreatedAt     time.Time        `json:"created_at"`
+	Files         []InspectionFile `json:"files,omitempty"`
+}
+
+// AssetInspectionRequest 表示登记检查记录请求
+type AssetInspectionRequest struct {
+	TaskID        int    `json:"task_id"`                         // 完成的维保任务，可为空
+	InspectedOn   string `json:"inspected_on" binding:"required"` // 格式: 2006-01-02
+	Inspector     string `json:"inspector" binding:"required,max=50"`
+	Agency        string `json:"agency" binding:"max=100"`
+	CertificateNo string `json:"certificate_no" binding:"max=50"`
+	Result        string `json:"result" binding:"required,oneof=pass rectify fail"`
+	Notes         string `json:"notes" binding:"max=1000"`
+}
+
+// InspectionFile 表示检查记录的附件
+type InspectionFile struct {
+	ID           int       `json:"id"`
+	InspectionID int       `json:"inspection_id"`
+	FilePath     string    `json:"-"`
+	OriginalName string    `json:"original_name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	UploadedBy   int       `json:"uploaded_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// advance 返回from之后一个维保周期的日期，按月的周期遇到月末时取当月最后一天
+func (s *MaintenanceSchedule) advance(from time.Time) time.Time {
+	if s.IntervalUnit == IntervalDay {
+		return from.AddDate(0, 0, s.IntervalValue)
+	}
+	first := time.Date(from.Year(), from.Month()+time.Month(s.IntervalValue), 1, 0, 0, 0, 0, time.Local)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := from.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.Local)
+}
+
+// checkAssetNo 检查设备编号是否已被同一社区的其他设备使用
+func checkAssetNo(assetNo string, communityID, excludeID int) error {
+	var count int
+	err := database.DB.QueryRow(`SELECT COUNT(*) FROM assets WHERE community_id = ? AND asset_no = ? AND id != ?`,
+		communityID, assetNo, excludeID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrAssetExists, assetNo)
+	}
+	return nil
+}
+
+// assetFields 校验设备请求，返回安装日期和状态
+func assetFields(req AssetRequest) (sql.NullTime, string, error) {
+	var installDate sql.NullTime
+	if req.InstallDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", req.InstallDate, time.Local)
+		if err != nil {
+			return installDate, "", fmt.Errorf("%w: 安装日期格式应为YYYY-MM-DD", ErrInvalidAsset)
+		}
+		installDate = sql.NullTime{Time: t, Valid: true}
+	}
+	status := req.Status
+	if status == "" {
+		status = AssetInService
+	}
+	return installDate, status, nil
+}
+
+const assetColumns = `
+	id, community_id, asset_no, name, category, block_number, location, model, manufacturer, install_date, status, remark,
+	created_at, updated_at
+`
+
+// scanAsset 解析一行设备数据
+func scanAsset(scanner interface{ Scan(...interface{}) error }) (*Asset, error) {
+	var a Asset
+	var installDate sql.NullTime
+	err := scanner.Scan(&a.ID, &a.CommunityID, &a.AssetNo, &a.Name, &a.Category, &a.BlockNumber, &a.Location, &a.Model,
+		&a.Manufacturer, &installDate, &a.Status, &a.Remark, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	a.InstallDate = nullTimePtr(installDate)
+	return &a, nil
+}
+
+// GetAssets 获取设备台账，支持按分类、楼栋、状态和关键字（编号、名称、位置）查询
+func GetAssets(page, pageSize int, filters map[string]interface{}) ([]Asset, int, error) {
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND community_id = ?`
+		args = append(args, communityID)
+	}
+	for _, key := range []string{"category", "block_number", "status"} {
+		if v, ok := filters[key].(string); ok && v != "" {
+			where += ` AND ` + key + ` = ?`
+			args = append(args, v)
+		}
+	}
+	if keyword, ok := filters["keyword"].(string); ok && keyword != "" {
+		where += ` AND (asset_no LIKE ? OR name LIKE ? OR location LIKE ?)`
+		args = append(args, "%"+keyword+"%", "%"+keyword+"%", "%"+keyword+"%")
+	}
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM assets`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + assetColumns + ` FROM assets` + where + ` ORDER BY block_number, asset_no LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	assets := []Asset{}
+	for rows.Next() {
+		a, err := scanAsset(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		assets = append(assets, *a)
+	}
+
+	return assets, total, rows.Err()
+}
+
+// GetAssetByID 通过ID获取设备，withSchedules为true时同时返回维保计划
+func GetAssetByID(id int, withSchedules bool) (*Asset, error) {
+	a, err := scanAsset(database.DB.QueryRow(`SELECT `+assetColumns+` FROM assets WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if withSchedules {
+		if a.Schedules, err = GetMaintenanceSchedules(id); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// CreateAsset 在指定社区新增设备
+func CreateAsset(req AssetRequest, communityID int) (int, error) {
+	installDate, status, err := assetFields(req)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkAssetNo(req.AssetNo, communityID, 0); err != nil {
+		return 0, err
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO assets (community_id, asset_no, name, category, block_number, location, model, manufacturer, install_date, status, remark)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		communityID, req.AssetNo, req.Name, req.Category, req.BlockNumber, req.Location, req.Model, req.Manufacturer,
+		installDate, status, req.Remark,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateAsset 修改设备，停用或报废的设备不再生成维保任务
+func UpdateAsset(id int, req AssetRequest) error {
+	installDate, status, err := assetFields(req)
+	if err != nil {
+		return err
+	}
+	var communityID int
+	if err := database.DB.QueryRow(`SELECT community_id FROM assets WHERE id = ?`, id).Scan(&communityID); err != nil {
+		return err
+	}
+	if err := checkAssetNo(req.AssetNo, communityID, id); err != nil {
+		return err
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE assets
+		SET asset_no = ?, name = ?, category = ?, block_number = ?, location = ?, model = ?, manufacturer = ?,
+		    install_date = ?, status = ?, remark = ?
+		WHERE id = ?`,
+		req.AssetNo, req.Name, req.Category, req.BlockNumber, req.Location, req.Model, req.Manufacturer,
+		installDate, status, req.Remark, id,
+	)
+	return err
+}
+
+const scheduleColumns = `
+	id, asset_id, name, kind, interval_value, interval_unit, lead_days, next_due, last_done, enabled, created_at, updated_at
+`
+
+// scanSchedule 解析一行维保计划数据
+func scanSchedule(scanner interface{ Scan(...interface{}) error }) (*MaintenanceSchedule, error) {
+	var s MaintenanceSchedule
+	var lastDone sql.NullTime
+	err := scanner.Scan(&s.ID, &s.AssetID, &s.Name, &s.Kind, &s.IntervalValue, &s.IntervalUnit, &s.LeadDays,
+		&s.NextDue, &lastDone, &s.Enabled, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.LastDone = nullTimePtr(lastDone)
+	return &s, nil
+}
+
+// GetMaintenanceSchedules 获取设备的维保计划
+func GetMaintenanceSchedules(assetID int) ([]MaintenanceSchedule, error) {
+	rows, err := database.DB.Query(`SELECT `+scheduleColumns+` FROM maintenance_schedules
+		WHERE asset_id = ? ORDER BY next_due, id`, assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []MaintenanceSchedule{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *s)
+	}
+
+	return schedules, rows.Err()
+}
+
+// GetMaintenanceScheduleByID 通过ID获取维保计划
+func GetMaintenanceScheduleByID(id int) (*MaintenanceSchedule, error) {
+	s, err := scanSchedule(database.DB.QueryRow(`SELECT `+scheduleColumns+` FROM maintenance_schedules WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseNextDue 解析维保计划的下次到期日
+func parseNextDue(value string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return t, fmt.Errorf("%w: 下次到期日格式应为YYYY-MM-DD", ErrInvalidMaintenanceSchedule)
+	}
+	return t, nil
+}
+
+// CreateMaintenanceSchedule 为设备新增维保计划
+func CreateMaintenanceSchedule(assetID int, req MaintenanceScheduleRequest) (int, error) {
+	nextDue, err := parseNextDue(req.NextDue)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO maintenance_schedules (asset_id, name, kind, interval_value, interval_unit, lead_days, next_due, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		assetID, req.Name, req.Kind, req.IntervalValue, req.IntervalUnit, req.LeadDays, nextDue, req.Enabled,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateMaintenanceSchedule 修改维保计划，已生成的待完成任务不受影响
+func UpdateMaintenanceSchedule(id int, req MaintenanceScheduleRequest) error {
+	nextDue, err := parseNextDue(req.NextDue)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE maintenance_schedules
+		SET name = ?, kind = ?, interval_value = ?, interval_unit = ?, lead_days = ?, next_due = ?, enabled = ?
+		WHERE id = ?`,
+		req.Name, req.Kind, req.IntervalValue, req.IntervalUnit, req.LeadDays, nextDue, req.Enabled, id,
+	)
+	return err
+}
+
+// GenerateMaintenanceTasks 为在today之后lead_days天内到期、尚无待完成任务的维保计划生成任务
+// 每个任务同时在设备所在社区创建一张公共区域工单，工单创建失败只记录日志，返回新生成的任务数
+func GenerateMaintenanceTasks(today time.Time) (int, error) {
+	rows, err := database.DB.Query(`
+		SELECT s.id, s.asset_id, s.name, s.kind, s.next_due, a.community_id, a.name, a.category, a.block_number, a.location
+		FROM maintenance_schedules s
+		JOIN assets a ON s.asset_id = a.id
+		WHERE s.enabled = 1 AND a.status = ? AND DATE_SUB(s.next_due, INTERVAL s.lead_days DAY) <= ?
+		  AND NOT EXISTS (SELECT 1 FROM maintenance_tasks t WHERE t.schedule_id = s.id AND t.status = ?)`,
+		AssetInService, today, TaskPending,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	type due struct {
+		scheduleID, assetID, communityID     int
+		name, kind                           string
+		nextDue                              time.Time
+		assetName, category, block, location string
+	}
+	var dues []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.scheduleID, &d.assetID, &d.name, &d.kind, &d.nextDue, &d.communityID,
+			&d.assetName, &d.category, &d.block, &d.location); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		dues = append(dues, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, d := range dues {
+		result, err := database.DB.Exec(`
+			INSERT IGNORE INTO maintenance_tasks (schedule_id, asset_id, due_date, status) VALUES (?, ?, ?, ?)`,
+			d.scheduleID, d.assetID, d.nextDue, TaskPending,
+		)
+		if err != nil {
+			return created, err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		taskID, err := result.LastInsertId()
+		if err != nil {
+			return created, err
+		}
+		created++
+
+		var categoryID int
+		err = database.DB.QueryRow(`SELECT id FROM work_order_categories WHERE code = ?`,
+			assetWorkOrderCategories[d.category]).Scan(&categoryID)
+		if err != nil {
+			log.Printf("维保任务%d未找到对应的工单分类: %v", taskID, err)
+			continue
+		}
+
+		location := d.block + d.location
+		if location == "" {
+			location = d.assetName
+		}
+		priority := PriorityNormal
+		if d.kind == ScheduleInspection {
+			priority = PriorityHigh
+		}
+		orderID, err := CreateWorkOrder(WorkOrderRequest{
+			CategoryID:   categoryID,
+			Title:        d.assetName + d.name,
+			Description:  fmt.Sprintf("维保计划自动生成，到期日%s，完成后请登记检查记录", d.nextDue.Format("2006-01-02")),
+			LocationType: LocationCommon,
+			Location:     location,
+			Priority:     priority,
+		}, 0, d.communityID)
+		if err != nil {
+			log.Printf("维保任务%d创建工单失败: %v", taskID, err)
+			continue
+		}
+		if _, err := database.DB.Exec(`UPDATE maintenance_tasks SET work_order_id = ? WHERE id = ?`, orderID, taskID); err != nil {
+			log.Printf("维保任务%d关联工单失败: %v", taskID, err)
+		}
+	}
+
+	return created, nil
+}
+
+const maintenanceTaskColumns = `
+	t.id, a.community_id, t.schedule_id, s.name, s.kind, t.asset_id, a.asset_no, a.name, a.block_number, a.location,
+	t.due_date, t.status, COALESCE(t.work_order_id, 0), COALESCE(w.order_no, ''), COALESCE(t.inspection_id, 0),
+	t.completed_at, t.created_at
+	FROM maintenance_tasks t
+	JOIN maintenance_schedules s ON t.schedule_id = s.id
+	JOIN assets a ON t.asset_id = a.id
+	LEFT JOIN work_orders w ON t.work_order_id = w.id
+`
+
+// scanMaintenanceTask 解析一行维保任务数据
+func scanMaintenanceTask(scanner interface{ Scan(...interface{}) error }, today time.Time) (*MaintenanceTask, error) {
+	var t MaintenanceTask
+	var completedAt sql.NullTime
+	err := scanner.Scan(&t.ID, &t.CommunityID, &t.ScheduleID, &t.ScheduleName, &t.Kind, &t.AssetID, &t.AssetNo, &t.AssetName,
+		&t.BlockNumber, &t.Location, &t.DueDate, &t.Status, &t.WorkOrderID, &t.WorkOrderNo, &t.InspectionID,
+		&completedAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	t.CompletedAt = nullTimePtr(completedAt)
+	if t.Status == TaskPending && t.DueDate.Before(today) {
+		t.OverdueDays = daysBetween(t.DueDate, today)
+	}
+
+	return &t, nil
+}
+
+// GetMaintenanceTasks 获取维保任务，支持按状态、设备、计划类型和楼栋查询
+// overdue为true时只返回超过到期日仍未完成的任务，按超期天数降序排列
+func GetMaintenanceTasks(page, pageSize int, filters map[string]interface{}) ([]MaintenanceTask, int, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND a.community_id = ?`
+		args = append(args, communityID)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		where += ` AND t.status = ?`
+		args = append(args, status)
+	}
+	if assetID, ok := filters["asset_id"].(int); ok && assetID > 0 {
+		where += ` AND t.asset_id = ?`
+		args = append(args, assetID)
+	}
+	if kind, ok := filters["kind"].(string); ok && kind != "" {
+		where += ` AND s.kind = ?`
+		args = append(args, kind)
+	}
+	if block, ok := filters["block_number"].(string); ok && block != "" {
+		where += ` AND a.block_number = ?`
+		args = append(args, block)
+	}
+	if overdue, ok := filters["overdue"].(bool); ok && overdue {
+		where += ` AND t.status = ? AND t.due_date < ?`
+		args = append(args, TaskPending, today)
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM maintenance_tasks t
+		JOIN maintenance_schedules s ON t.schedule_id = s.id
+		JOIN assets a ON t.asset_id = a.id` + where
+	if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + maintenanceTaskColumns + where + `
+		ORDER BY t.status = 'pending' DESC, t.due_date, t.id LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	tasks := []MaintenanceTask{}
+	for rows.Next() {
+		t, err := scanMaintenanceTask(rows, today)
+		if err != nil {
+			return nil, 0, err
+		}
+		tasks = append(tasks, *t)
+	}
+
+	return tasks, total, rows.Err()
+}
+
+// CreateAssetInspection 登记设备检查记录
+// 指定task_id且结果不是不合格时完成该任务，并按检查日期顺延维保计划的下次到期日
+func CreateAssetInspection(assetID int, req AssetInspectionRequest, createdBy uint) (int, error) {
+	inspectedOn, err := time.ParseInLocation("2006-01-02", req.InspectedOn, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("%w: 检查日期格式应为YYYY-MM-DD", ErrInvalidInspection)
+	}
+	if inspectedOn.After(time.Now()) {
+		return 0, fmt.Errorf("%w: 检查日期不能晚于今天", ErrInvalidInspection)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var schedule *MaintenanceSchedule
+	taskID := sql.NullInt64{}
+	if req.TaskID > 0 {
+		var scheduleID int
+		err := tx.QueryRow(`SELECT schedule_id FROM maintenance_tasks WHERE id = ? AND asset_id = ? AND status = ? FOR UPDATE`,
+			req.TaskID, assetID, TaskPending).Scan(&scheduleID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return 0, ErrTaskNotPending
+			}
+			return 0, err
+		}
+		schedule, err = scanSchedule(tx.QueryRow(`SELECT `+scheduleColumns+` FROM maintenance_schedules WHERE id = ?`, scheduleID))
+		if err != nil {
+			return 0, err
+		}
+		taskID = sql.NullInt64{Int64: int64(req.TaskID), Valid: true}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO asset_inspections (asset_id, task_id, inspected_on, inspector, agency, certificate_no, result, notes, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		assetID, taskID, inspectedOn, req.Inspector, req.Agency, req.CertificateNo, req.Result, req.Notes, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	// 不合格时任务保持待完成，复检合格后再完成
+	if schedule != nil && req.Result != InspectionFail {
+		if _, err := tx.Exec(`UPDATE maintenance_tasks SET status = ?, inspection_id = ?, completed_at = NOW() WHERE id = ?`,
+			TaskCompleted, id, req.TaskID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`UPDATE maintenance_schedules SET last_done = ?, next_due = ? WHERE id = ?`,
+			inspectedOn, schedule.advance(inspectedOn), schedule.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+const assetInspectionColumns = `
+	i.id, a.community_id, i.asset_id, a.name, COALESCE(i.task_id, 0), i.inspected_on, i.inspector, i.agency, i.certificate_no,
+	i.result, i.notes, i.created_by, i.created_at
+	FROM asset_inspections i
+	JOIN assets a ON i.asset_id = a.id
+`
+
+// scanAssetInspection 解析一行检查记录数据
+func scanAssetInspection(scanner interface{ Scan(...interface{}) error }) (*AssetInspection, error) {
+	var i AssetInspection
+	err := scanner.Scan(&i.ID, &i.CommunityID, &i.AssetID, &i.AssetName, &i.TaskID, &i.InspectedOn, &i.Inspector, &i.Agency,
+		&i.CertificateNo, &i.Result, &i.Notes, &i.CreatedBy, &i.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// GetAssetInspections 获取设备的检查记录，按检查日期倒序排列
+func GetAssetInspections(assetID int) ([]AssetInspection, error) {
+	rows, err := database.DB.Query(`SELECT `+assetInspectionColumns+`
+		WHERE i.asset_id = ? ORDER BY i.inspected_on DESC, i.id DESC`, assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inspections := []AssetInspection{}
+	for rows.Next() {
+		i, err := scanAssetInspection(rows)
+		if err != nil {
+			return nil, err
+		}
+		inspections = append(inspections, *i)
+	}
+
+	return inspections, rows.Err()
+}
+
+// GetAssetInspectionByID 通过ID获取检查记录及附件
+func GetAssetInspectionByID(id int) (*AssetInspection, error) {
+	i, err := scanAssetInspection(database.DB.QueryRow(`SELECT `+assetInspectionColumns+` WHERE i.id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if i.Files, err = GetInspectionFiles(id); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// CreateInspectionFile 保存检查记录附件
+func CreateInspectionFile(file InspectionFile) (int, error) {
+	result, err := database.DB.Exec(`
+		INSERT INTO asset_inspection_files (inspection_id, file_path, original_name, content_type, size, uploaded_by)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		file.InspectionID, file.FilePath, file.OriginalName, file.ContentType, file.Size, file.UploadedBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetInspectionFiles 获取检查记录的附件
+func GetInspectionFiles(inspectionID int) ([]InspectionFile, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, inspection_id, file_path, original_name, content_type, size, uploaded_by, created_at
+		FROM asset_inspection_files WHERE inspection_id = ? ORDER BY id`, inspectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []InspectionFile{}
+	for rows.Next() {
+		var f InspectionFile
+		if err := rows.Scan(&f.ID, &f.InspectionID, &f.FilePath, &f.OriginalName, &f.ContentType, &f.Size, &f.UploadedBy, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	return files, rows.Err()
+}
+
+// GetInspectionFile 获取检查记录的某个附件
+func GetInspectionFile(inspectionID, fileID int) (*InspectionFile, error) {
+	var f InspectionFile
+	err := database.DB.QueryRow(`
+		SELECT id, inspection_id, file_path, original_name, content_type, size, uploaded_by, created_at
+		FROM asset_inspection_files WHERE id = ? AND inspection_id = ?`, fileID, inspectionID).
+		Scan(&f.ID, &f.InspectionID, &f.FilePath, &f.OriginalName, &f.ContentType, &f.Size, &f.UploadedBy, &f.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &f, nil
+}
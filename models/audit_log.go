@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"community-backward/database"
+)
+
+// AuditLog 表示一条审计日志
+type AuditLog struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Action    string    `json:"action"`
+	Entity    string    `json:"entity"`
+	EntityID  string    `json:"entity_id"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateAuditLog 记录审计日志
+func CreateAuditLog(log AuditLog) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO audit_logs (user_id, username, action, entity, entity_id, detail)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		log.UserID, log.Username, log.Action, log.Entity, log.EntityID, log.Detail,
+	)
+	return err
+}
+
+// GetAuditLogs 获取审计日志列表
+func GetAuditLogs(page, pageSize int, filters map[string]interface{}) ([]AuditLog, int, error) {
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if entity, ok := filters["entity"].(string); ok && entity != "" {
+		where += ` AND entity = ?`
+		args = append(args, entity)
+	}
+
+	if action, ok := filters["action"].(string); ok && action != "" {
+		where += ` AND action = ?`
+		args = append(args, action)
+	}
+
+	if userID, ok := filters["user_id"].(int); ok && userID > 0 {
+		where += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM audit_logs`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, user_id, username, action, entity, entity_id, detail, created_at FROM audit_logs` +
+		where + ` ORDER BY id DESC LIMIT ? OFFSET ?`
+	args = append(args, pageSize, (page-1)*pageSize)
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var logs []AuditLog
+	for rows.Next() {
+		var l AuditLog
+		var detail sql.NullString
+		if err := rows.Scan(&l.ID, &l.UserID, &l.Username, &l.Action, &l.Entity, &l.EntityID, &detail, &l.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		l.Detail = detail.String
+		logs = append(logs, l)
+	}
+
+	return logs, total, nil
+}
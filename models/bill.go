@@ -0,0 +1,586 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"community-backward/database"
+)
+
+// Bill 表示账单（应收）模型
+type Bill struct {
+	ID           int       `json:"id"`
+	ResidentID   int       `json:"resident_id"`
+	ResidentName string    `json:"resident_name,omitempty"`
+	FeeTypeID    int       `json:"fee_type_id"`
+	FeeTypeName  string    `json:"fee_type_name,omitempty"`
+	Year         int       `json:"year"`
+	Month        int       `json:"month"`
+	Amount       float64   `json:"amount"`
+	DueDate      time.Time `json:"due_date"`
+	Remark       string    `json:"remark,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at,omitempty"`
+}
+
+// BillRequest 表示创建账单请求
+type BillRequest struct {
+	ResidentID int     `json:"resident_id" binding:"required"`
+	FeeTypeID  int     `json:"fee_type_id"` // 缺省为物业费
+	Year       int     `json:"year" binding:"required"`
+	Month      int     `json:"month" binding:"required,min=1,max=12"`
+	Amount     float64 `json:"amount" binding:"required"`
+	DueDate    string  `json:"due_date" binding:"required"` // 格式: 2006-01-02
+	Remark     string  `json:"remark"`
+}
+
+// GenerateBillsRequest 表示批量生成账单请求
+type GenerateBillsRequest struct {
+	FeeTypeID int    `json:"fee_type_id" binding:"required"`
+	Year      int    `json:"year" binding:"required"`
+	Month     int    `json:"month" binding:"required,min=1,max=12"`
+	DueDate   string `json:"due_date" binding:"required"` // 格式: 2006-01-02
+}
+
+// OpenBill 表示截至某日尚未结清的已到期账单
+type OpenBill struct {
+	BillID      int       `json:"bill_id"`
+	ResidentID  int       `json:"resident_id"`
+	FeeTypeID   int       `json:"fee_type_id"`
+	Year        int       `json:"year"`
+	Month       int       `json:"month"`
+	DueDate     time.Time `json:"due_date"`
+	Amount      float64   `json:"amount"`
+	Outstanding float64   `json:"outstanding"`
+}
+
+// OverdueAccount 表示欠费住户及其欠费情况，出租且约定由租户缴费时联系人为主租户
+type OverdueAccount struct {
+	ResidentID    int       `json:"resident_id"`
+	CommunityID   int       `json:"community_id"`
+	Payer         string    `json:"payer"` // owner或tenant
+	Name          string    `json:"name"`
+	BlockNumber   string    `json:"building"`
+	UnitNumber    string    `json:"unit"`
+	HouseNumber   string    `json:"room"`
+	Phone         string    `json:"phone"`
+	Email         string    `json:"email"`
+	Amount        float64   `json:"amount"`
+	OldestDueDate time.Time `json:"oldest_due_date"`
+	OverdueDays   int       `json:"overdue_days"`
+}
+
+// GetBills 获取账单列表
+func GetBills(page, pageSize int, filters map[string]interface{}) ([]Bill, int, error) {
+	query := `
+		SELECT b.id, b.resident_id, r.name, b.fee_type_id, t.name, b.year, b.month, b.amount, b.due_date,
+		       b.remark, b.created_at, b.updated_at
+		FROM bills b
+		JOIN residents r ON b.resident_id = r.id
+		JOIN fee_types t ON b.fee_type_id = t.id
+		WHERE 1=1
+	`
+	countQuery := `SELECT COUNT(*) FROM bills b JOIN residents r ON b.resident_id = r.id WHERE 1=1`
+
+	var args []interface{}
+
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		query += ` AND b.resident_id = ?`
+		countQuery += ` AND b.resident_id = ?`
+		args = append(args, residentID)
+	}
+
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		query += ` AND r.community_id = ?`
+		countQuery += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+
+	if feeTypeID, ok := filters["fee_type_id"].(int); ok && feeTypeID > 0 {
+		query += ` AND b.fee_type_id = ?`
+		countQuery += ` AND b.fee_type_id = ?`
+		args = append(args, feeTypeID)
+	}
+
+	if year, ok := filters["year"].(int); ok && year > 0 {
+		query += ` AND b.year = ?`
+		countQuery += ` AND b.year = ?`
+		args = append(args, year)
+	}
+
+	if month, ok := filters["month"].(int); ok && month > 0 {
+		query += ` AND b.month = ?`
+		countQuery += ` AND b.month = ?`
+		args = append(args, month)
+	}
+
+	var total int
+	if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query += ` ORDER BY b.due_date DESC, b.id DESC LIMIT ? OFFSET ?`
+	args = append(args, pageSize, (page-1)*pageSize)
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var bills []Bill
+	for rows.Next() {
+		var b Bill
+		var remark sql.NullString
+		if err := rows.Scan(&b.ID, &b.ResidentID, &b.ResidentName, &b.FeeTypeID, &b.FeeTypeName, &b.Year, &b.Month, &b.Amount,
+			&b.DueDate, &remark, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		if remark.Valid {
+			b.Remark = remark.String
+		}
+		bills = append(bills, b)
+	}
+
+	return bills, total, nil
+}
+
+// GetBillByID 通过ID获取账单
+func GetBillByID(id int) (*Bill, error) {
+	query := `
+		SELECT b.id, b.resident_id, r.name, b.fee_type_id, t.name, b.year, b.month, b.amount, b.due_date,
+		       b.remark, b.created_at, b.updated_at
+		FROM bills b
+		JOIN residents r ON b.resident_id = r.id
+		JOIN fee_types t ON b.fee_type_id = t.id
+		WHERE b.id = ?
+	`
+
+	var b Bill
+	var remark sql.NullString
+	err := database.DB.QueryRow(query, id).Scan(&b.ID, &b.ResidentID, &b.ResidentName, &b.FeeTypeID, &b.FeeTypeName, &b.Year, &b.Month,
+		&b.Amount, &b.DueDate, &remark, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // 账单不存在
+		}
+		return nil, err
+	}
+	if remark.Valid {
+		b.Remark = remark.String
+	}
+
+	return &b, nil
+}
+
+// CreateBill 创建账单
+func CreateBill(req BillRequest) (int, error) {
+	dueDate, err := time.ParseInLocation("2006-01-02", req.DueDate, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("无效的到期日期: %s", req.DueDate)
+	}
+
+	feeTypeID := req.FeeTypeID
+	if feeTypeID == 0 {
+		feeTypeID = DefaultFeeTypeID
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	communityID, err := residentCommunityTx(tx, req.ResidentID)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkPeriodOpenTx(tx, communityID, req.Year, req.Month); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO bills (resident_id, fee_type_id, year, month, amount, due_date, remark) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.Exec(query, req.ResidentID, feeTypeID, req.Year, req.Month, req.Amount, dueDate, req.Remark)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := postBillTx(tx, int(id)); err != nil {
+		return 0, fmt.Errorf("账单记账失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GenerateBills 按收费项目的计费规则为指定社区的所有住户生成指定月份的账单
+// 已存在的账单会被跳过，返回新生成的账单数量
+func GenerateBills(feeType *FeeType, year, month int, dueDate time.Time, communityID int) (int, error) {
+	if err := feeType.CheckBatchBilling(); err != nil {
+		return 0, err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := checkPeriodOpenTx(tx, communityID, year, month); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(`SELECT id, house_area FROM residents WHERE community_id = ? ORDER BY id`, communityID)
+	if err != nil {
+		return 0, err
+	}
+
+	var residents []Resident
+	for rows.Next() {
+		var r Resident
+		if err := rows.Scan(&r.ID, &r.HouseArea); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		residents = append(residents, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	remark := fmt.Sprintf("%d年%d月%s", year, month, feeType.Name)
+	created := 0
+	for i := range residents {
+		amount := feeType.CalculateFee(&residents[i], 0)
+		if amount <= 0 {
+			continue
+		}
+
+		id, err := insertBillIgnore(tx, residents[i].ID, feeType.ID, year, month, amount, dueDate, remark)
+		if err != nil {
+			return 0, fmt.Errorf("生成住户%d账单失败: %w", residents[i].ID, err)
+		}
+		if id == 0 {
+			continue
+		}
+		if err := postBillTx(tx, id); err != nil {
+			return 0, fmt.Errorf("住户%d账单记账失败: %w", residents[i].ID, err)
+		}
+		created++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return created, nil
+}
+
+// insertBillIgnore 在事务中插入账单，同一住户同一收费项目同一月份的账单已存在时跳过并返回0
+func insertBillIgnore(tx *sql.Tx, residentID, feeTypeID, year, month int, amount float64, dueDate time.Time, remark string) (int, error) {
+	result, err := tx.Exec(`
+		INSERT IGNORE INTO bills (resident_id, fee_type_id, year, month, amount, due_date, remark)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		residentID, feeTypeID, year, month, amount, dueDate, remark,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return 0, nil
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// DeleteBill 删除账单，已结账账期的账单不能删除
+// 账单及其减免已生成的凭证保留在总账中，删除时以删除当天为记账日期生成红字冲销凭证
+func DeleteBill(id int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var communityID, year, month int
+	err = tx.QueryRow(`
+		SELECT r.community_id, b.year, b.month FROM bills b JOIN residents r ON b.resident_id = r.id
+		WHERE b.id = ? FOR UPDATE OF b`, id).Scan(&communityID, &year, &month)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	if err := checkPeriodOpenTx(tx, communityID, year, month); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := checkDateOpenTx(tx, communityID, now); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT id FROM fee_adjustments WHERE bill_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	var adjustmentIDs []int
+	for rows.Next() {
+		var adjustmentID int
+		if err := rows.Scan(&adjustmentID); err != nil {
+			rows.Close()
+			return err
+		}
+		adjustmentIDs = append(adjustmentIDs, adjustmentID)
+	}
+	rows.Close()
+
+	if err := reverseJournal(tx, JournalSourceBill, id, JournalSourceBillVoid, now); err != nil {
+		return err
+	}
+	for _, adjustmentID := range adjustmentIDs {
+		if err := reverseJournal(tx, JournalSourceAdjustment, adjustmentID, JournalSourceAdjustmentVoid, now); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM bills WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetOpenBills 获取截至asOf尚未结清的已到期账单
+// 已审批的减免先冲抵对应账单，已缴物业费再按到期日先后冲抵（先进先出）
+// residentID为0时返回所有住户
+func GetOpenBills(asOf time.Time, residentID int) ([]OpenBill, error) {
+	return getOpenBills(asOf, residentID, 0)
+}
+
+// getOpenBills 获取截至asOf尚未结清的已到期账单，communityID不为0时只返回该社区住户的账单
+func getOpenBills(asOf time.Time, residentID, communityID int) ([]OpenBill, error) {
+	query := `SELECT id, resident_id, fee_type_id, year, month, amount, due_date FROM bills WHERE due_date <= ?`
+	args := []interface{}{asOf}
+	if residentID > 0 {
+		query += ` AND resident_id = ?`
+		args = append(args, residentID)
+	}
+	if communityID > 0 {
+		query += ` AND resident_id IN (SELECT id FROM residents WHERE community_id = ?)`
+		args = append(args, communityID)
+	}
+	query += ` ORDER BY resident_id, due_date, id`
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询账单失败: %w", err)
+	}
+	defer rows.Close()
+
+	var bills []OpenBill
+	for rows.Next() {
+		var b OpenBill
+		if err := rows.Scan(&b.BillID, &b.ResidentID, &b.FeeTypeID, &b.Year, &b.Month, &b.Amount, &b.DueDate); err != nil {
+			return nil, fmt.Errorf("解析账单失败: %w", err)
+		}
+		b.Outstanding = b.Amount
+		bills = append(bills, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 已审批的减免和优惠直接冲抵对应账单
+	adjustments, err := getApprovedAdjustments(asOf, residentID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bills {
+		bills[i].Outstanding = roundAmount(math.Max(bills[i].Outstanding-adjustments[bills[i].BillID], 0))
+	}
+
+	credits, err := getCredits(asOf, residentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按住户和收费项目先进先出冲抵
+	var open []OpenBill
+	for i := range bills {
+		b := &bills[i]
+		key := creditKey{ResidentID: b.ResidentID, FeeTypeID: b.FeeTypeID}
+		credit := credits[key]
+		applied := math.Min(credit, b.Outstanding)
+		b.Outstanding = roundAmount(b.Outstanding - applied)
+		credits[key] = credit - applied
+		if b.Outstanding > 0 {
+			open = append(open, *b)
+		}
+	}
+
+	return open, nil
+}
+
+// creditKey 缴费只冲抵同一住户同一收费项目的账单
+type creditKey struct {
+	ResidentID int
+	FeeTypeID  int
+}
+
+// getCredits 获取截至asOf每个住户各收费项目可用于冲抵账单的金额
+// asOf之后才退款的缴费在asOf时仍然有效，历史日期的统计结果不因之后的退款而改变
+func getCredits(asOf time.Time, residentID int) (map[creditKey]float64, error) {
+	query := `
+		SELECT resident_id, fee_type_id, COALESCE(SUM(amount), 0)
+		FROM property_fees
+		WHERE payment_date <= ? AND (payment_status = ? OR (payment_status = ? AND refunded_at > ?))
+	`
+	args := []interface{}{asOf, PaymentPaid, PaymentRefunded, asOf}
+	if residentID > 0 {
+		query += ` AND resident_id = ?`
+		args = append(args, residentID)
+	}
+	query += ` GROUP BY resident_id, fee_type_id`
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询已缴物业费失败: %w", err)
+	}
+	defer rows.Close()
+
+	credits := make(map[creditKey]float64)
+	for rows.Next() {
+		var key creditKey
+		var amount float64
+		if err := rows.Scan(&key.ResidentID, &key.FeeTypeID, &amount); err != nil {
+			return nil, fmt.Errorf("解析已缴物业费失败: %w", err)
+		}
+		credits[key] += amount
+	}
+
+	return credits, rows.Err()
+}
+
+// GetResidentBalance 获取住户截至asOf的欠费金额
+func GetResidentBalance(residentID int, asOf time.Time) (float64, error) {
+	open, err := GetOpenBills(asOf, residentID)
+	if err != nil {
+		return 0, err
+	}
+
+	var balance float64
+	for _, b := range open {
+		balance += b.Outstanding
+	}
+
+	return roundAmount(balance), nil
+}
+
+// GetOverdueAccounts 获取指定社区截至asOf的欠费住户，按逾期天数降序排列
+// 住户信息和缴费人在一次查询中取得：当天有生效租约且约定由租户缴费时为主租户，否则为业主
+func GetOverdueAccounts(asOf time.Time, communityID int) ([]OverdueAccount, error) {
+	open, err := getOpenBills(asOf, 0, communityID)
+	if err != nil {
+		return nil, err
+	}
+	if len(open) == 0 {
+		return []OverdueAccount{}, nil
+	}
+
+	accounts := make(map[int]*OverdueAccount)
+	for _, b := range open {
+		acc, ok := accounts[b.ResidentID]
+		if !ok {
+			acc = &OverdueAccount{ResidentID: b.ResidentID, OldestDueDate: b.DueDate}
+			accounts[b.ResidentID] = acc
+		}
+		acc.Amount = roundAmount(acc.Amount + b.Outstanding)
+		if b.DueDate.Before(acc.OldestDueDate) {
+			acc.OldestDueDate = b.DueDate
+		}
+	}
+
+	date := asOf.Format("2006-01-02")
+	rows, err := database.DB.Query(`
+		SELECT r.id, r.community_id, r.block_number, r.unit_number, r.house_number,
+		       COALESCE(t.name, r.name), COALESCE(t.phone, r.phone), COALESCE(t.email, r.email), t.id IS NOT NULL
+		FROM residents r
+		LEFT JOIN lease_tenants t ON t.is_primary = 1 AND t.lease_id = (
+			SELECT l.id FROM leases l
+			JOIN lease_tenants pt ON pt.lease_id = l.id AND pt.is_primary = 1
+			WHERE l.resident_id = r.id AND l.status = ? AND l.fee_payer = ? AND l.start_date <= ? AND l.end_date >= ?
+			ORDER BY l.start_date DESC LIMIT 1)
+		WHERE r.community_id = ?`,
+		LeaseActive, FeePayerTenant, date, date, communityID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []OverdueAccount{}
+	for rows.Next() {
+		var id, community int
+		var block, unit, house, name, phone, email string
+		var tenant bool
+		if err := rows.Scan(&id, &community, &block, &unit, &house, &name, &phone, &email, &tenant); err != nil {
+			return nil, err
+		}
+		acc, ok := accounts[id]
+		if !ok {
+			continue
+		}
+		acc.Payer = FeePayerOwner
+		if tenant {
+			acc.Payer = FeePayerTenant
+		}
+		acc.Name = name
+		acc.CommunityID = community
+		acc.BlockNumber = block
+		acc.UnitNumber = unit
+		acc.HouseNumber = house
+		acc.Phone = phone
+		acc.Email = email
+		acc.OverdueDays = daysBetween(acc.OldestDueDate, asOf)
+		result = append(result, *acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].OverdueDays != result[j].OverdueDays {
+			return result[i].OverdueDays > result[j].OverdueDays
+		}
+		return result[i].ResidentID < result[j].ResidentID
+	})
+
+	return result, nil
+}
+
+// daysBetween 计算两个日期之间相差的自然日
+func daysBetween(from, to time.Time) int {
+	f := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	t := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.Local)
+	return int(t.Sub(f).Hours() / 24)
+}
+
+// roundAmount 金额保留两位小数
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
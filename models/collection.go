@@ -0,0 +1,178 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"community-backward/database"
+)
+
+// CollectionRate 表示某一范围内的收缴率
+type CollectionRate struct {
+	Building        string  `json:"building,omitempty"`
+	Unit            string  `json:"unit,omitempty"`
+	AmountDue       float64 `json:"amountDue"`       // 应收金额（已扣除减免）
+	AmountCollected float64 `json:"amountCollected"` // 实收金额
+	AmountRate      float64 `json:"amountRate"`      // 金额收缴率（百分比）
+	Households      int     `json:"households"`      // 应缴户数
+	PaidHouseholds  int     `json:"paidHouseholds"`  // 已缴清户数
+	HouseholdRate   float64 `json:"householdRate"`   // 户数收缴率（百分比）
+}
+
+// CollectionReport 表示收缴率报表
+type CollectionReport struct {
+	Year    int              `json:"year"`
+	Month   int              `json:"month,omitempty"`
+	GroupBy string           `json:"groupBy"`
+	AsOf    string           `json:"asOf"`
+	Total   CollectionRate   `json:"total"`
+	Groups  []CollectionRate `json:"groups"`
+}
+
+// collectionAccumulator 按住户累计应收和未收金额
+type collectionAccumulator struct {
+	rate     CollectionRate
+	unpaid   map[int]bool
+	resident map[int]bool
+}
+
+func newCollectionAccumulator(building, unit string) *collectionAccumulator {
+	return &collectionAccumulator{
+		rate:     CollectionRate{Building: building, Unit: unit},
+		unpaid:   make(map[int]bool),
+		resident: make(map[int]bool),
+	}
+}
+
+func (a *collectionAccumulator) add(residentID int, due, outstanding float64) {
+	a.rate.AmountDue += due
+	a.rate.AmountCollected += due - outstanding
+	a.resident[residentID] = true
+	if outstanding > 0 {
+		a.unpaid[residentID] = true
+	}
+}
+
+func (a *collectionAccumulator) result() CollectionRate {
+	r := a.rate
+	r.AmountDue = roundAmount(r.AmountDue)
+	r.AmountCollected = roundAmount(r.AmountCollected)
+	r.Households = len(a.resident)
+	r.PaidHouseholds = r.Households - len(a.unpaid)
+	if r.AmountDue > 0 {
+		r.AmountRate = math.Round(r.AmountCollected/r.AmountDue*10000) / 100
+	}
+	if r.Households > 0 {
+		r.HouseholdRate = math.Round(float64(r.PaidHouseholds)/float64(r.Households)*10000) / 100
+	}
+	return r
+}
+
+// GetCollectionReport 获取指定账期截至asOf的收缴率
+// month为0时统计全年，groupBy为building或unit，feeTypeID为0时统计所有收费项目
+// 只统计截至asOf已到期的账单，communityID为0时统计所有社区
+func GetCollectionReport(year, month int, groupBy string, feeTypeID int, asOf time.Time, communityID int) (*CollectionReport, error) {
+	query := `SELECT id, resident_id, amount FROM bills WHERE year = ? AND due_date <= ?`
+	args := []interface{}{year, asOf}
+	if month > 0 {
+		query += ` AND month = ?`
+		args = append(args, month)
+	}
+	if feeTypeID > 0 {
+		query += ` AND fee_type_id = ?`
+		args = append(args, feeTypeID)
+	}
+	if communityID > 0 {
+		query += ` AND resident_id IN (SELECT id FROM residents WHERE community_id = ?)`
+		args = append(args, communityID)
+	}
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询账单失败: %w", err)
+	}
+
+	type periodBill struct {
+		id, residentID int
+		amount         float64
+	}
+	var bills []periodBill
+	for rows.Next() {
+		var b periodBill
+		if err := rows.Scan(&b.id, &b.residentID, &b.amount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("解析账单失败: %w", err)
+		}
+		bills = append(bills, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	adjustments, err := getApprovedAdjustments(asOf, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	open, err := GetOpenBills(asOf, 0)
+	if err != nil {
+		return nil, err
+	}
+	outstanding := make(map[int]float64, len(open))
+	for _, b := range open {
+		outstanding[b.BillID] = b.Outstanding
+	}
+
+	residents, err := getResidentIndex(communityID)
+	if err != nil {
+		return nil, err
+	}
+
+	total := newCollectionAccumulator("", "")
+	groups := make(map[string]*collectionAccumulator)
+	for _, b := range bills {
+		r, ok := residents[b.residentID]
+		if !ok {
+			continue
+		}
+
+		due := math.Max(b.amount-adjustments[b.id], 0)
+		total.add(b.residentID, due, outstanding[b.id])
+
+		key, unit := r.BlockNumber, ""
+		if groupBy == "unit" {
+			unit = r.UnitNumber
+			key += "/" + unit
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = newCollectionAccumulator(r.BlockNumber, unit)
+			groups[key] = g
+		}
+		g.add(b.residentID, due, outstanding[b.id])
+	}
+
+	report := &CollectionReport{
+		Year:    year,
+		Month:   month,
+		GroupBy: groupBy,
+		AsOf:    asOf.Format("2006-01-02"),
+		Total:   total.result(),
+		Groups:  []CollectionRate{},
+	}
+	for _, g := range groups {
+		report.Groups = append(report.Groups, g.result())
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.Building != b.Building {
+			return a.Building < b.Building
+		}
+		return a.Unit < b.Unit
+	})
+
+	return report, nil
+}
@@ -0,0 +1,340 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"community-backward/database"
+)
+
+// DefaultCommunityID 默认社区ID，启用多社区之前的数据都归入该社区
+const DefaultCommunityID = 1
+
+var (
+	// ErrCommunityCodeExists 社区编码已存在
+	ErrCommunityCodeExists = errors.New("社区编码已存在")
+	// ErrNotCommunityMember 用户不属于该社区或社区已停用
+	ErrNotCommunityMember = errors.New("无权进入该社区")
+	// ErrInvalidCommunityMember 住户账号不能登记为社区成员
+	ErrInvalidCommunityMember = errors.New("住户账号只属于所住房屋所在的社区")
+)
+
+// Community 表示物业公司管理的一个社区（小区）
+type Community struct {
+	ID        int       `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// CommunityRequest 表示社区请求
+type CommunityRequest struct {
+	Code    string `json:"code" binding:"required,max=30"`
+	Name    string `json:"name" binding:"required,max=100"`
+	Address string `json:"address" binding:"max=255"`
+	Enabled bool   `json:"enabled"`
+}
+
+// CommunityMemberRequest 表示添加社区成员请求
+type CommunityMemberRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// SwitchCommunityRequest 表示切换社区请求
+type SwitchCommunityRequest struct {
+	CommunityID int  `json:"community_id" binding:"required"`
+	Remember    bool `json:"remember"`
+}
+
+// CommunityRollup 表示一个社区的汇总数据，供总部跨社区对比
+type CommunityRollup struct {
+	CommunityID       int     `json:"community_id,omitempty"`
+	Code              string  `json:"code,omitempty"`
+	Name              string  `json:"name"`
+	Residents         int     `json:"residents"`         // 住户数
+	ArrearsHouseholds int     `json:"arrearsHouseholds"` // 欠费户数
+	YearlyIncome      float64 `json:"yearlyIncome"`      // 本年实收
+	AmountDue         float64 `json:"amountDue"`         // 截至asOf已到期的应收金额
+	AmountCollected   float64 `json:"amountCollected"`   // 已到期账单的实收金额
+	AmountRate        float64 `json:"amountRate"`        // 金额收缴率（百分比）
+}
+
+const communityColumns = `id, code, name, address, enabled, created_at, updated_at`
+
+// scanCommunity 解析一行社区数据
+func scanCommunity(scanner interface{ Scan(...interface{}) error }) (*Community, error) {
+	var c Community
+	if err := scanner.Scan(&c.ID, &c.Code, &c.Name, &c.Address, &c.Enabled, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// queryCommunities 执行查询并解析社区列表
+func queryCommunities(query string, args ...interface{}) ([]Community, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	communities := []Community{}
+	for rows.Next() {
+		c, err := scanCommunity(rows)
+		if err != nil {
+			return nil, err
+		}
+		communities = append(communities, *c)
+	}
+
+	return communities, rows.Err()
+}
+
+// GetCommunities 获取社区列表
+func GetCommunities(onlyEnabled bool) ([]Community, error) {
+	query := `SELECT ` + communityColumns + ` FROM communities`
+	if onlyEnabled {
+		query += ` WHERE enabled = 1`
+	}
+	query += ` ORDER BY id ASC`
+	return queryCommunities(query)
+}
+
+// GetCommunityByID 通过ID获取社区
+func GetCommunityByID(id int) (*Community, error) {
+	c, err := scanCommunity(database.DB.QueryRow(`SELECT `+communityColumns+` FROM communities WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkCommunityCode 检查社区编码是否已被其他社区使用
+func checkCommunityCode(code string, excludeID int) error {
+	var count int
+	err := database.DB.QueryRow(`SELECT COUNT(*) FROM communities WHERE code = ? AND id <> ?`, code, excludeID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrCommunityCodeExists, code)
+	}
+	return nil
+}
+
+// CreateCommunity 创建社区
+func CreateCommunity(req CommunityRequest) (int, error) {
+	if err := checkCommunityCode(req.Code, 0); err != nil {
+		return 0, err
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO communities (code, name, address, enabled) VALUES (?, ?, ?, ?)`,
+		req.Code, req.Name, req.Address, req.Enabled,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateCommunity 更新社区
+func UpdateCommunity(id int, req CommunityRequest) error {
+	if err := checkCommunityCode(req.Code, id); err != nil {
+		return err
+	}
+
+	_, err := database.DB.Exec(`
+		UPDATE communities SET code = ?, name = ?, address = ?, enabled = ? WHERE id = ?`,
+		req.Code, req.Name, req.Address, req.Enabled, id,
+	)
+	return err
+}
+
+// GetUserCommunities 获取用户可以进入的已启用社区
+// 管理员可以进入所有社区，住户账号只能进入所住房屋所在的社区，其他用户按社区成员登记
+func GetUserCommunities(user *User) ([]Community, error) {
+	switch user.Role {
+	case RoleAdmin:
+		return GetCommunities(true)
+	case RoleResident:
+		return queryCommunities(`
+			SELECT c.id, c.code, c.name, c.address, c.enabled, c.created_at, c.updated_at
+			FROM communities c
+			JOIN residents r ON r.community_id = c.id
+			WHERE r.id = ? AND c.enabled = 1`, user.ResidentID)
+	}
+
+	return queryCommunities(`
+		SELECT c.id, c.code, c.name, c.address, c.enabled, c.created_at, c.updated_at
+		FROM communities c
+		JOIN user_communities uc ON uc.community_id = c.id
+		WHERE uc.user_id = ? AND c.enabled = 1
+		ORDER BY c.id ASC`, user.ID)
+}
+
+// ResolveUserCommunity 确定用户登录后进入的社区：优先进入上次所在的社区，
+// 该社区已不可进入时进入第一个可进入的社区，没有可进入的社区时返回ErrNotCommunityMember
+func ResolveUserCommunity(user *User) (*Community, error) {
+	communities, err := GetUserCommunities(user)
+	if err != nil {
+		return nil, err
+	}
+	if len(communities) == 0 {
+		return nil, ErrNotCommunityMember
+	}
+
+	for i := range communities {
+		if communities[i].ID == user.CommunityID {
+			return &communities[i], nil
+		}
+	}
+	return &communities[0], nil
+}
+
+// SwitchUserCommunity 切换用户所在的社区，下次登录时进入该社区
+// 用户无权进入该社区时返回ErrNotCommunityMember
+func SwitchUserCommunity(user *User, communityID int) (*Community, error) {
+	communities, err := GetUserCommunities(user)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range communities {
+		if communities[i].ID != communityID {
+			continue
+		}
+		if _, err := database.DB.Exec(`UPDATE users SET community_id = ? WHERE id = ?`, communityID, user.ID); err != nil {
+			return nil, err
+		}
+		return &communities[i], nil
+	}
+
+	return nil, ErrNotCommunityMember
+}
+
+// GetCommunityMembers 获取登记为社区成员的用户
+func GetCommunityMembers(communityID int) ([]User, error) {
+	rows, err := database.DB.Query(`
+		SELECT u.id, u.username, u.email, u.role, u.created_at, u.updated_at
+		FROM users u
+		JOIN user_communities uc ON uc.user_id = u.id
+		WHERE uc.community_id = ?
+		ORDER BY u.id`, communityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// AddCommunityMember 将用户登记为社区成员，已是成员时不做处理
+func AddCommunityMember(communityID int, userID uint) error {
+	user, err := GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("%w: 用户%d不存在", sql.ErrNoRows, userID)
+	}
+	if user.Role == RoleResident {
+		return ErrInvalidCommunityMember
+	}
+
+	_, err = database.DB.Exec(`
+		INSERT IGNORE INTO user_communities (user_id, community_id) VALUES (?, ?)`,
+		userID, communityID,
+	)
+	return err
+}
+
+// RemoveCommunityMember 将用户移出社区，用户不是该社区成员时返回sql.ErrNoRows
+func RemoveCommunityMember(communityID int, userID uint) error {
+	result, err := database.DB.Exec(`
+		DELETE FROM user_communities WHERE user_id = ? AND community_id = ?`,
+		userID, communityID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetCommunityRollup 汇总各社区指定年份的住户、收入和收缴情况，并返回合计
+// 收缴率只统计截至asOf已到期的账单
+func GetCommunityRollup(communities []Community, year int, asOf time.Time) ([]CommunityRollup, *CommunityRollup, error) {
+	rollups := make([]CommunityRollup, 0, len(communities))
+	total := &CommunityRollup{Name: "合计"}
+
+	for _, c := range communities {
+		r := CommunityRollup{CommunityID: c.ID, Code: c.Code, Name: c.Name}
+
+		err := database.DB.QueryRow(`
+			SELECT COUNT(*), COALESCE(SUM(status = 0), 0) FROM residents WHERE community_id = ?`, c.ID,
+		).Scan(&r.Residents, &r.ArrearsHouseholds)
+		if err != nil {
+			return nil, nil, fmt.Errorf("统计社区%s住户失败: %w", c.Name, err)
+		}
+
+		err = database.DB.QueryRow(`
+			SELECT COALESCE(SUM(pf.amount), 0)
+			FROM property_fees pf
+			JOIN residents r ON pf.resident_id = r.id
+			JOIN fee_types t ON pf.fee_type_id = t.id
+			WHERE r.community_id = ? AND pf.payment_status = 1 AND YEAR(pf.payment_date) = ?
+			  AND t.pricing_rule <> 'deposit'`, c.ID, year,
+		).Scan(&r.YearlyIncome)
+		if err != nil {
+			return nil, nil, fmt.Errorf("统计社区%s收入失败: %w", c.Name, err)
+		}
+
+		report, err := GetCollectionReport(year, 0, "building", 0, asOf, c.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("统计社区%s收缴率失败: %w", c.Name, err)
+		}
+		r.AmountDue = report.Total.AmountDue
+		r.AmountCollected = report.Total.AmountCollected
+		r.AmountRate = report.Total.AmountRate
+
+		total.Residents += r.Residents
+		total.ArrearsHouseholds += r.ArrearsHouseholds
+		total.YearlyIncome = roundAmount(total.YearlyIncome + r.YearlyIncome)
+		total.AmountDue = roundAmount(total.AmountDue + r.AmountDue)
+		total.AmountCollected = roundAmount(total.AmountCollected + r.AmountCollected)
+
+		rollups = append(rollups, r)
+	}
+
+	if total.AmountDue > 0 {
+		total.AmountRate = roundAmount(total.AmountCollected / total.AmountDue * 100)
+	}
+
+	return rollups, total, nil
+}
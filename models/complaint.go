@@ -0,0 +1,596 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"community-backward/database"
+)
+
+// 投诉建议类型
+const (
+	ComplaintTypeComplaint  = "complaint"  // 投诉
+	ComplaintTypeSuggestion = "suggestion" // 建议
+)
+
+// 投诉建议状态
+const (
+	ComplaintPending    = "pending"    // 待受理
+	ComplaintProcessing = "processing" // 处理中
+	ComplaintResponded  = "responded"  // 已答复，待住户评价
+	ComplaintClosed     = "closed"     // 已关闭
+)
+
+// 投诉建议操作
+const (
+	ComplaintActionAccept  = "accept"
+	ComplaintActionRespond = "respond"
+	ComplaintActionReopen  = "reopen"
+	ComplaintActionClose   = "close"
+)
+
+// 投诉处理记录类型
+const (
+	ComplaintLogComment  = "comment"
+	ComplaintLogStatus   = "status"
+	ComplaintLogEscalate = "escalate"
+)
+
+var (
+	// ErrInvalidComplaint 投诉建议内容不完整
+	ErrInvalidComplaint = errors.New("无效的投诉建议")
+	// ErrComplaintNotFound 投诉建议不存在
+	ErrComplaintNotFound = errors.New("投诉建议不存在")
+	// ErrComplaintTransition 当前状态不允许该操作
+	ErrComplaintTransition = errors.New("当前投诉状态不允许该操作")
+)
+
+// complaintTransitions 投诉建议状态流转规则
+var complaintTransitions = map[string]workOrderTransition{
+	ComplaintActionAccept:  {from: []string{ComplaintPending}, to: ComplaintProcessing, label: "受理"},
+	ComplaintActionRespond: {from: []string{ComplaintProcessing}, to: ComplaintResponded, label: "答复"},
+	ComplaintActionReopen:  {from: []string{ComplaintResponded}, to: ComplaintProcessing, label: "重新处理"},
+	ComplaintActionClose:   {from: []string{ComplaintResponded}, to: ComplaintClosed, label: "关闭"},
+}
+
+// Complaint 表示投诉或建议
+type Complaint struct {
+	ID              int            `json:"id"`
+	CommunityID     int            `json:"community_id"`
+	ComplaintNo     string         `json:"complaint_no"`
+	Type            string         `json:"type"`
+	Category        string         `json:"category"`
+	Title           string         `json:"title"`
+	Content         string         `json:"content"`
+	ResidentID      int            `json:"resident_id,omitempty"`
+	ResidentName    string         `json:"resident_name,omitempty"`
+	Room            string         `json:"room,omitempty"`
+	Anonymous       bool           `json:"anonymous"`
+	Channel         string         `json:"channel"`
+	ContactName     string         `json:"contact_name,omitempty"`
+	ContactPhone    string         `json:"contact_phone,omitempty"`
+	Status          string         `json:"status"`
+	AssigneeID      int            `json:"assignee_id,omitempty"`
+	AssigneeName    string         `json:"assignee_name,omitempty"`
+	Deadline        time.Time      `json:"deadline"`
+	Overdue         bool           `json:"overdue"` // 未答复且已超过答复期限
+	EscalationLevel int            `json:"escalation_level"`
+	EscalatedAt     *time.Time     `json:"escalated_at,omitempty"`
+	Response        string         `json:"response,omitempty"`
+	RespondedAt     *time.Time     `json:"responded_at,omitempty"`
+	ClosedAt        *time.Time     `json:"closed_at,omitempty"`
+	Satisfaction    int            `json:"satisfaction,omitempty"` // 1-5分，0表示未评价
+	Feedback        string         `json:"feedback,omitempty"`
+	CreatedBy       int            `json:"created_by"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	Logs            []ComplaintLog `json:"logs,omitempty"`
+}
+
+// ComplaintRequest 表示登记投诉建议请求
+type ComplaintRequest struct {
+	Type         string `json:"type" binding:"omitempty,oneof=complaint suggestion"`
+	Category     string `json:"category" binding:"required,oneof=service environment security facility noise parking fee other"`
+	Title        string `json:"title" binding:"required,max=100"`
+	Content      string `json:"content" binding:"required,max=2000"`
+	ResidentID   int    `json:"resident_id"`
+	Anonymous    bool   `json:"anonymous"`
+	Channel      string `json:"channel" binding:"omitempty,oneof=phone counter portal other"`
+	ContactName  string `json:"contact_name"`
+	ContactPhone string `json:"contact_phone"`
+	Deadline     string `json:"deadline"` // 答复期限，格式: 2006-01-02 15:04，缺省按配置的答复时限计算
+}
+
+// ComplaintActionRequest 表示投诉状态操作请求
+type ComplaintActionRequest struct {
+	AssigneeID   int    `json:"assignee_id"`                                  // 受理时指定处理人，缺省为当前用户
+	Note         string `json:"note" binding:"max=2000"`                      // 答复时为答复内容，重新处理时为原因
+	Satisfaction int    `json:"satisfaction" binding:"omitempty,min=1,max=5"` // 关闭时的住户满意度评分
+	Feedback     string `json:"feedback" binding:"max=500"`
+}
+
+// ComplaintLog 表示投诉处理记录
+type ComplaintLog struct {
+	ID          int       `json:"id"`
+	ComplaintID int       `json:"complaint_id"`
+	UserID      int       `json:"user_id"`
+	Username    string    `json:"username"`
+	Kind        string    `json:"kind"`
+	Content     string    `json:"content"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ComplaintLogRequest 表示添加备注请求
+type ComplaintLogRequest struct {
+	Content string `json:"content" binding:"required,max=1000"`
+}
+
+// ComplaintCount 表示按某一维度的投诉数量
+type ComplaintCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// ComplaintStats 表示某月投诉建议的汇总统计
+type ComplaintStats struct {
+	Year             int              `json:"year"`
+	Month            int              `json:"month"`
+	Total            int              `json:"total"`
+	ByType           []ComplaintCount `json:"byType"`
+	ByCategory       []ComplaintCount `json:"byCategory"`
+	ByStatus         []ComplaintCount `json:"byStatus"`
+	Responded        int              `json:"responded"`
+	RespondedOnTime  int              `json:"respondedOnTime"`  // 未升级且在期限内答复
+	OnTimeRate       float64          `json:"onTimeRate"`       // 百分比
+	AvgResponseHours float64          `json:"avgResponseHours"` // 从登记到答复的平均小时数
+	Escalated        int              `json:"escalated"`
+	Overdue          int              `json:"overdue"` // 当前仍未答复且已超期
+	Rated            int              `json:"rated"`
+	AvgSatisfaction  float64          `json:"avgSatisfaction"`
+	Satisfaction     [5]int           `json:"satisfaction"` // 1-5分各自的评价数
+}
+
+// MaskIdentity 隐去匿名投诉的住户和联系人信息
+func (c *Complaint) MaskIdentity() {
+	if !c.Anonymous {
+		return
+	}
+	c.ResidentID = 0
+	c.ResidentName = ""
+	c.Room = ""
+	c.ContactName = ""
+	c.ContactPhone = ""
+}
+
+// CreateComplaint 在指定社区登记投诉建议，编号按 TS+日期+ID 生成
+func CreateComplaint(req ComplaintRequest, createdBy uint, communityID int, responseTime time.Duration) (int, error) {
+	if req.Type == "" {
+		req.Type = ComplaintTypeComplaint
+	}
+	if req.Channel == "" {
+		req.Channel = "phone"
+	}
+
+	now := time.Now()
+	deadline := now.Add(responseTime)
+	if req.Deadline != "" {
+		t, err := parseDateTime(req.Deadline)
+		if err != nil || !t.After(now) {
+			return 0, fmt.Errorf("%w: 答复期限应晚于当前时间", ErrInvalidComplaint)
+		}
+		deadline = *t
+	}
+
+	var residentID sql.NullInt64
+	if req.ResidentID > 0 {
+		residentID = sql.NullInt64{Int64: int64(req.ResidentID), Valid: true}
+	} else if req.Type == ComplaintTypeComplaint && !req.Anonymous && req.ContactPhone == "" {
+		return 0, fmt.Errorf("%w: 非匿名投诉需关联住户或填写联系电话", ErrInvalidComplaint)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO complaints (community_id, type, category, title, content, resident_id, anonymous, channel,
+		                        contact_name, contact_phone, status, deadline, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		communityID, req.Type, req.Category, req.Title, req.Content, residentID, req.Anonymous, req.Channel,
+		req.ContactName, req.ContactPhone, ComplaintPending, deadline, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	complaintNo := fmt.Sprintf("TS%s%06d", now.Format("20060102"), id)
+	if _, err := tx.Exec(`UPDATE complaints SET complaint_no = ? WHERE id = ?`, complaintNo, id); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+const complaintColumns = `
+	c.id, c.community_id, COALESCE(c.complaint_no, ''), c.type, c.category, c.title, c.content,
+	COALESCE(c.resident_id, 0), COALESCE(r.name, ''), COALESCE(CONCAT(r.block_number, r.unit_number, r.house_number), ''),
+	c.anonymous, c.channel, c.contact_name, c.contact_phone, c.status,
+	COALESCE(c.assignee_id, 0), COALESCE(u.username, ''), c.deadline, c.escalation_level, c.escalated_at,
+	c.response, c.responded_at, c.closed_at, COALESCE(c.satisfaction, 0), c.feedback, c.created_by, c.created_at, c.updated_at
+`
+
+const complaintJoins = `
+	FROM complaints c
+	LEFT JOIN residents r ON c.resident_id = r.id
+	LEFT JOIN users u ON c.assignee_id = u.id
+`
+
+// scanComplaint 解析一行投诉建议数据
+func scanComplaint(scanner interface{ Scan(...interface{}) error }, now time.Time) (*Complaint, error) {
+	var c Complaint
+	var escalatedAt, respondedAt, closedAt sql.NullTime
+	err := scanner.Scan(&c.ID, &c.CommunityID, &c.ComplaintNo, &c.Type, &c.Category, &c.Title, &c.Content,
+		&c.ResidentID, &c.ResidentName, &c.Room,
+		&c.Anonymous, &c.Channel, &c.ContactName, &c.ContactPhone, &c.Status,
+		&c.AssigneeID, &c.AssigneeName, &c.Deadline, &c.EscalationLevel, &escalatedAt,
+		&c.Response, &respondedAt, &closedAt, &c.Satisfaction, &c.Feedback, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	c.EscalatedAt = nullTimePtr(escalatedAt)
+	c.RespondedAt = nullTimePtr(respondedAt)
+	c.ClosedAt = nullTimePtr(closedAt)
+	c.Overdue = (c.Status == ComplaintPending || c.Status == ComplaintProcessing) && c.Deadline.Before(now)
+
+	return &c, nil
+}
+
+// GetComplaints 获取投诉建议列表，超期和已升级的排在前面
+func GetComplaints(page, pageSize int, filters map[string]interface{}) ([]Complaint, int, error) {
+	now := time.Now()
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	for _, key := range []string{"type", "category", "status", "channel"} {
+		if v, ok := filters[key].(string); ok && v != "" {
+			where += ` AND c.` + key + ` = ?`
+			args = append(args, v)
+		}
+	}
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND c.community_id = ?`
+		args = append(args, communityID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND c.resident_id = ?`
+		args = append(args, residentID)
+	}
+	if assigneeID, ok := filters["assignee_id"].(int); ok && assigneeID > 0 {
+		where += ` AND c.assignee_id = ?`
+		args = append(args, assigneeID)
+	}
+	if keyword, ok := filters["keyword"].(string); ok && keyword != "" {
+		where += ` AND (c.complaint_no LIKE ? OR c.title LIKE ?)`
+		args = append(args, "%"+keyword+"%", "%"+keyword+"%")
+	}
+	if overdue, ok := filters["overdue"].(bool); ok && overdue {
+		where += ` AND c.status IN ('pending', 'processing') AND c.deadline < ?`
+		args = append(args, now)
+	}
+	if escalated, ok := filters["escalated"].(bool); ok && escalated {
+		where += ` AND c.escalation_level > 0`
+	}
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM complaints c`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + complaintColumns + complaintJoins + where + `
+		ORDER BY c.status IN ('pending', 'processing') DESC, c.escalation_level DESC, c.deadline, c.id DESC
+		LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	list := []Complaint{}
+	for rows.Next() {
+		c, err := scanComplaint(rows, now)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *c)
+	}
+
+	return list, total, rows.Err()
+}
+
+// GetComplaintByID 通过ID获取投诉建议，withLogs为true时同时返回处理记录
+func GetComplaintByID(id int, withLogs bool) (*Complaint, error) {
+	c, err := scanComplaint(database.DB.QueryRow(`SELECT `+complaintColumns+complaintJoins+` WHERE c.id = ?`, id), time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if withLogs {
+		if c.Logs, err = GetComplaintLogs(id); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// TransitionComplaint 按操作变更投诉状态，并自动记录一条状态变更记录
+func TransitionComplaint(id int, action string, req ComplaintActionRequest, userID uint, username string) error {
+	t, ok := complaintTransitions[action]
+	if !ok {
+		return fmt.Errorf("未知的投诉操作: %s", action)
+	}
+
+	complaint, err := GetComplaintByID(id, false)
+	if err != nil {
+		return err
+	}
+	if complaint == nil {
+		return ErrComplaintNotFound
+	}
+
+	allowed := false
+	for _, s := range t.from {
+		if complaint.Status == s {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s状态的投诉不能%s", ErrComplaintTransition, complaint.Status, t.label)
+	}
+
+	set := `status = ?`
+	args := []interface{}{t.to}
+	note := t.label
+
+	switch action {
+	case ComplaintActionAccept:
+		assigneeID := userID
+		if req.AssigneeID > 0 {
+			assigneeID = uint(req.AssigneeID)
+		}
+		assignee, err := GetUserByID(assigneeID)
+		if err != nil {
+			return err
+		}
+		if assignee == nil || assignee.Role == RoleResident {
+			return fmt.Errorf("%w: 处理人必须是物业工作人员", ErrInvalidComplaint)
+		}
+		set += `, assignee_id = ?`
+		args = append(args, assignee.ID)
+		note += "，处理人" + assignee.Username
+	case ComplaintActionRespond:
+		if req.Note == "" {
+			return fmt.Errorf("%w: 请填写答复内容", ErrInvalidComplaint)
+		}
+		set += `, response = ?, responded_at = NOW()`
+		args = append(args, req.Note)
+	case ComplaintActionReopen:
+		set += `, responded_at = NULL`
+	case ComplaintActionClose:
+		set += `, closed_at = NOW(), satisfaction = ?, feedback = ?`
+		args = append(args, sql.NullInt64{Int64: int64(req.Satisfaction), Valid: req.Satisfaction > 0}, req.Feedback)
+		if req.Satisfaction > 0 {
+			note += fmt.Sprintf("，满意度%d分", req.Satisfaction)
+		}
+	}
+	if req.Note != "" && action != ComplaintActionRespond {
+		note += ": " + req.Note
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 以当前状态作为条件，避免并发操作互相覆盖
+	args = append(args, id, complaint.Status)
+	result, err := tx.Exec(`UPDATE complaints SET `+set+` WHERE id = ? AND status = ?`, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: 投诉状态已被他人修改，请刷新后重试", ErrComplaintTransition)
+	}
+
+	if err := insertComplaintLog(tx, id, int(userID), username, ComplaintLogStatus, note); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertComplaintLog 写入一条投诉处理记录
+func insertComplaintLog(tx *sql.Tx, complaintID, userID int, username, kind, content string) error {
+	_, err := tx.Exec(`
+		INSERT INTO complaint_logs (complaint_id, user_id, username, kind, content) VALUES (?, ?, ?, ?, ?)`,
+		complaintID, userID, username, kind, content,
+	)
+	return err
+}
+
+// CreateComplaintLog 添加投诉处理备注
+func CreateComplaintLog(complaintID int, content string, userID uint, username string) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO complaint_logs (complaint_id, user_id, username, kind, content) VALUES (?, ?, ?, ?, ?)`,
+		complaintID, userID, username, ComplaintLogComment, content,
+	)
+	return err
+}
+
+// GetComplaintLogs 获取投诉处理记录，按时间先后排序
+func GetComplaintLogs(complaintID int) ([]ComplaintLog, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, complaint_id, user_id, username, kind, content, created_at
+		FROM complaint_logs WHERE complaint_id = ? ORDER BY id`, complaintID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []ComplaintLog{}
+	for rows.Next() {
+		var l ComplaintLog
+		if err := rows.Scan(&l.ID, &l.ComplaintID, &l.UserID, &l.Username, &l.Kind, &l.Content, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+
+	return logs, rows.Err()
+}
+
+// EscalateOverdueComplaints 将截至now超期未答复的投诉升级一级，并按responseTime顺延答复期限
+// 返回本次升级的投诉
+func EscalateOverdueComplaints(now time.Time, responseTime time.Duration) ([]Complaint, error) {
+	list, _, err := GetComplaints(1, 1000, map[string]interface{}{"overdue": true})
+	if err != nil {
+		return nil, err
+	}
+
+	escalated := []Complaint{}
+	for _, c := range list {
+		if !c.Deadline.Before(now) {
+			continue
+		}
+
+		tx, err := database.DB.Begin()
+		if err != nil {
+			return escalated, err
+		}
+
+		result, err := tx.Exec(`
+			UPDATE complaints SET escalation_level = escalation_level + 1, escalated_at = ?, deadline = ?
+			WHERE id = ? AND escalation_level = ? AND status IN ('pending', 'processing')`,
+			now, now.Add(responseTime), c.ID, c.EscalationLevel,
+		)
+		if err != nil {
+			tx.Rollback()
+			return escalated, err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			tx.Rollback()
+			continue
+		}
+
+		content := fmt.Sprintf("超过答复期限%s未答复，升级至第%d级", c.Deadline.Format("2006-01-02 15:04"), c.EscalationLevel+1)
+		if err := insertComplaintLog(tx, c.ID, 0, "", ComplaintLogEscalate, content); err != nil {
+			tx.Rollback()
+			return escalated, err
+		}
+		if err := tx.Commit(); err != nil {
+			return escalated, err
+		}
+
+		c.EscalationLevel++
+		escalated = append(escalated, c)
+	}
+
+	return escalated, nil
+}
+
+// GetComplaintStats 获取指定社区某月登记的投诉建议汇总统计
+func GetComplaintStats(year, month, communityID int) (*ComplaintStats, error) {
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 1, 0)
+	now := time.Now()
+	stats := &ComplaintStats{Year: year, Month: month}
+
+	var avgResponse, avgSatisfaction sql.NullFloat64
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*),
+		       COALESCE(SUM(responded_at IS NOT NULL), 0),
+		       COALESCE(SUM(responded_at IS NOT NULL AND escalation_level = 0 AND responded_at <= deadline), 0),
+		       AVG(CASE WHEN responded_at IS NOT NULL THEN TIMESTAMPDIFF(MINUTE, created_at, responded_at) / 60 END),
+		       COALESCE(SUM(escalation_level > 0), 0),
+		       COALESCE(SUM(status IN ('pending', 'processing') AND deadline < ?), 0),
+		       COALESCE(SUM(satisfaction IS NOT NULL), 0),
+		       AVG(satisfaction)
+		FROM complaints WHERE community_id = ? AND created_at >= ? AND created_at < ?`, now, communityID, start, end).
+		Scan(&stats.Total, &stats.Responded, &stats.RespondedOnTime, &avgResponse,
+			&stats.Escalated, &stats.Overdue, &stats.Rated, &avgSatisfaction)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.AvgResponseHours = roundAmount(avgResponse.Float64)
+	stats.AvgSatisfaction = roundAmount(avgSatisfaction.Float64)
+	stats.OnTimeRate = percentRate(stats.RespondedOnTime, stats.Responded)
+
+	for key, dest := range map[string]*[]ComplaintCount{"type": &stats.ByType, "category": &stats.ByCategory, "status": &stats.ByStatus} {
+		counts, err := countComplaintsBy(key, communityID, start, end)
+		if err != nil {
+			return nil, err
+		}
+		*dest = counts
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT satisfaction, COUNT(*) FROM complaints
+		WHERE community_id = ? AND created_at >= ? AND created_at < ? AND satisfaction BETWEEN 1 AND 5
+		GROUP BY satisfaction`, communityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var score, count int
+		if err := rows.Scan(&score, &count); err != nil {
+			return nil, err
+		}
+		stats.Satisfaction[score-1] = count
+	}
+
+	return stats, rows.Err()
+}
+
+// countComplaintsBy 按指定字段统计社区在时间段内登记的投诉数量，column只能是内部传入的字段名
+func countComplaintsBy(column string, communityID int, start, end time.Time) ([]ComplaintCount, error) {
+	rows, err := database.DB.Query(`
+		SELECT `+column+`, COUNT(*) FROM complaints
+		WHERE community_id = ? AND created_at >= ? AND created_at < ?
+		GROUP BY `+column+` ORDER BY COUNT(*) DESC`, communityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []ComplaintCount{}
+	for rows.Next() {
+		var c ComplaintCount
+		if err := rows.Scan(&c.Key, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"community-backward/database"
+)
+
+// DunningTemplate 表示催缴模板
+type DunningTemplate struct {
+	ID             int       `json:"id"`
+	Level          int       `json:"level"`
+	MinOverdueDays int       `json:"min_overdue_days"`
+	Channel        string    `json:"channel"`
+	Title          string    `json:"title"`
+	Content        string    `json:"content"`
+	Enabled        bool      `json:"enabled"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at,omitempty"`
+}
+
+// DunningTemplateRequest 表示更新催缴模板请求
+type DunningTemplateRequest struct {
+	MinOverdueDays int    `json:"min_overdue_days" binding:"min=0"`
+	Title          string `json:"title" binding:"required"`
+	Content        string `json:"content" binding:"required"`
+	Enabled        bool   `json:"enabled"`
+}
+
+// DunningRecord 表示一次催缴提醒记录
+type DunningRecord struct {
+	ID           int       `json:"id"`
+	ResidentID   int       `json:"resident_id"`
+	ResidentName string    `json:"resident_name,omitempty"`
+	Level        int       `json:"level"`
+	Channel      string    `json:"channel"`
+	Recipient    string    `json:"recipient"`
+	Content      string    `json:"content"`
+	Amount       float64   `json:"amount"`
+	OverdueDays  int       `json:"overdue_days"`
+	Status       int       `json:"status"` // 1表示发送成功，0表示发送失败
+	Error        string    `json:"error,omitempty"`
+	SentAt       time.Time `json:"sent_at"`
+}
+
+// GetDunningTemplates 获取催缴模板，onlyEnabled为true时只返回启用的模板
+func GetDunningTemplates(onlyEnabled bool) ([]DunningTemplate, error) {
+	query := `SELECT id, level, min_overdue_days, channel, title, content, enabled, created_at, updated_at FROM dunning_templates`
+	if onlyEnabled {
+		query += ` WHERE enabled = 1`
+	}
+	query += ` ORDER BY level ASC, channel ASC`
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []DunningTemplate
+	for rows.Next() {
+		var t DunningTemplate
+		if err := rows.Scan(&t.ID, &t.Level, &t.MinOverdueDays, &t.Channel, &t.Title, &t.Content,
+			&t.Enabled, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	return templates, rows.Err()
+}
+
+// UpdateDunningTemplate 更新催缴模板，返回是否找到该模板
+func UpdateDunningTemplate(id int, req DunningTemplateRequest) (bool, error) {
+	result, err := database.DB.Exec(
+		`UPDATE dunning_templates SET min_overdue_days = ?, title = ?, content = ?, enabled = ? WHERE id = ?`,
+		req.MinOverdueDays, req.Title, req.Content, req.Enabled, id,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	// 内容未变化时RowsAffected为0，需再确认模板是否存在
+	if n, _ := result.RowsAffected(); n > 0 {
+		return true, nil
+	}
+	var count int
+	err = database.DB.QueryRow(`SELECT COUNT(*) FROM dunning_templates WHERE id = ?`, id).Scan(&count)
+	return count > 0, err
+}
+
+// CreateDunningRecord 记录一次催缴提醒
+func CreateDunningRecord(rec DunningRecord) error {
+	var errMsg sql.NullString
+	if rec.Error != "" {
+		errMsg = sql.NullString{String: rec.Error, Valid: true}
+	}
+
+	_, err := database.DB.Exec(`
+		INSERT INTO dunning_records (resident_id, level, channel, recipient, content, amount, overdue_days, status, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.ResidentID, rec.Level, rec.Channel, rec.Recipient, rec.Content, rec.Amount, rec.OverdueDays, rec.Status, errMsg,
+	)
+	return err
+}
+
+// GetLastDunningRecord 获取住户自since以来级别最高的最近一次催缴记录，包括发送失败的记录，
+// 发送失败同样要等到重发间隔之后再重试，避免渠道故障时每次执行都重复发送
+func GetLastDunningRecord(residentID int, since time.Time) (*DunningRecord, error) {
+	query := `
+		SELECT id, resident_id, level, channel, recipient, content, amount, overdue_days, status, sent_at
+		FROM dunning_records
+		WHERE resident_id = ? AND sent_at >= ?
+		ORDER BY level DESC, sent_at DESC
+		LIMIT 1
+	`
+
+	var rec DunningRecord
+	err := database.DB.QueryRow(query, residentID, since).Scan(&rec.ID, &rec.ResidentID, &rec.Level, &rec.Channel,
+		&rec.Recipient, &rec.Content, &rec.Amount, &rec.OverdueDays, &rec.Status, &rec.SentAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &rec, nil
+}
+
+// GetDunningRecords 获取催缴记录列表
+func GetDunningRecords(page, pageSize int, filters map[string]interface{}) ([]DunningRecord, int, error) {
+	query := `
+		SELECT d.id, d.resident_id, r.name, d.level, d.channel, d.recipient, d.content, d.amount,
+		       d.overdue_days, d.status, d.error, d.sent_at
+		FROM dunning_records d
+		JOIN residents r ON d.resident_id = r.id
+		WHERE 1=1
+	`
+	countQuery := `SELECT COUNT(*) FROM dunning_records d JOIN residents r ON d.resident_id = r.id WHERE 1=1`
+
+	var args []interface{}
+
+	// 住户不会在社区间转移，催缴记录按住户所属社区区分
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		query += ` AND r.community_id = ?`
+		countQuery += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		query += ` AND d.resident_id = ?`
+		countQuery += ` AND d.resident_id = ?`
+		args = append(args, residentID)
+	}
+
+	if channel, ok := filters["channel"].(string); ok && channel != "" {
+		query += ` AND d.channel = ?`
+		countQuery += ` AND d.channel = ?`
+		args = append(args, channel)
+	}
+
+	if status, ok := filters["status"].(int); ok {
+		query += ` AND d.status = ?`
+		countQuery += ` AND d.status = ?`
+		args = append(args, status)
+	}
+
+	var total int
+	if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query += ` ORDER BY d.sent_at DESC, d.id DESC LIMIT ? OFFSET ?`
+	args = append(args, pageSize, (page-1)*pageSize)
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var records []DunningRecord
+	for rows.Next() {
+		var rec DunningRecord
+		var errMsg sql.NullString
+		if err := rows.Scan(&rec.ID, &rec.ResidentID, &rec.ResidentName, &rec.Level, &rec.Channel, &rec.Recipient,
+			&rec.Content, &rec.Amount, &rec.OverdueDays, &rec.Status, &errMsg, &rec.SentAt); err != nil {
+			return nil, 0, err
+		}
+		if errMsg.Valid {
+			rec.Error = errMsg.String
+		}
+		records = append(records, rec)
+	}
+
+	return records, total, nil
+}
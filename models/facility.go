@@ -0,0 +1,758 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"community-backward/database"
+)
+
+// FacilityFeeCode 公共设施使用费的收费项目代码
+const FacilityFeeCode = "facility"
+
+// 设施预约状态，finished只用于显示，表示已预约且已结束
+const (
+	BookingBooked    = "booked"    // 已预约
+	BookingCancelled = "cancelled" // 已取消
+	BookingFinished  = "finished"  // 已结束
+)
+
+// 设施预约来源
+const (
+	BookingSourceStaff    = "staff"    // 前台代约
+	BookingSourceResident = "resident" // 住户端自助预约
+)
+
+var (
+	// ErrInvalidFacility 设施的开放时间或预约规则无效
+	ErrInvalidFacility = errors.New("无效的设施设置")
+	// ErrFacilityExists 设施名称已存在
+	ErrFacilityExists = errors.New("设施名称已存在")
+	// ErrFacilityNotFound 设施不存在或已停用
+	ErrFacilityNotFound = errors.New("设施不存在或已停用")
+	// ErrInvalidBooking 预约时段不符合设施的预约规则
+	ErrInvalidBooking = errors.New("无效的预约时段")
+	// ErrBookingConflict 预约时段已被占用
+	ErrBookingConflict = errors.New("该时段已被预约")
+	// ErrBookingQuota 超过每户每周的预约次数
+	ErrBookingQuota = errors.New("超过本周预约次数上限")
+	// ErrBookingNotCancellable 预约已取消或已开始
+	ErrBookingNotCancellable = errors.New("预约已取消或已开始，不能取消")
+	// ErrBookingNotPayable 预约不收费、已缴费或已取消
+	ErrBookingNotPayable = errors.New("预约不收费、已缴费或已取消")
+)
+
+// Facility 表示可预约的公共设施
+type Facility struct {
+	ID          int       `json:"id"`
+	CommunityID int       `json:"community_id"`
+	Name        string    `json:"name"`
+	Location    string    `json:"location"`
+	Description string    `json:"description"`
+	OpenTime    string    `json:"open_time"`  // 格式: 15:04
+	CloseTime   string    `json:"close_time"` // 格式: 15:04
+	SlotMinutes int       `json:"slot_minutes"`
+	MaxSlots    int       `json:"max_slots"`
+	AdvanceDays int       `json:"advance_days"`
+	WeeklyQuota int       `json:"weekly_quota"` // 0为不限
+	FeePerSlot  float64   `json:"fee_per_slot"`
+	CancelHours int       `json:"cancel_hours"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// FacilityRequest 表示新增或修改设施请求
+type FacilityRequest struct {
+	Name        string  `json:"name" binding:"required,max=50"`
+	Location    string  `json:"location" binding:"max=100"`
+	Description string  `json:"description" binding:"max=500"`
+	OpenTime    string  `json:"open_time" binding:"required"`  // 格式: 15:04
+	CloseTime   string  `json:"close_time" binding:"required"` // 格式: 15:04
+	SlotMinutes int     `json:"slot_minutes" binding:"required,min=15,max=240"`
+	MaxSlots    int     `json:"max_slots" binding:"required,min=1,max=24"`
+	AdvanceDays int     `json:"advance_days" binding:"required,min=1,max=90"`
+	WeeklyQuota int     `json:"weekly_quota" binding:"min=0"`
+	FeePerSlot  float64 `json:"fee_per_slot" binding:"min=0"`
+	CancelHours int     `json:"cancel_hours" binding:"min=0"`
+	Enabled     bool    `json:"enabled"`
+}
+
+// FacilitySlot 表示设施某天的一个预约时段
+type FacilitySlot struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Available bool      `json:"available"`
+}
+
+// FacilityBooking 表示设施预约
+type FacilityBooking struct {
+	ID           int        `json:"id"`
+	CommunityID  int        `json:"community_id"` // 设施所在社区
+	FacilityID   int        `json:"facility_id"`
+	FacilityName string     `json:"facility_name"`
+	ResidentID   int        `json:"resident_id"`
+	ResidentName string     `json:"resident_name"`
+	Room         string     `json:"room"`
+	StartTime    time.Time  `json:"start_time"`
+	EndTime      time.Time  `json:"end_time"`
+	Slots        int        `json:"slots"`
+	Attendees    int        `json:"attendees"`
+	Purpose      string     `json:"purpose"`
+	Amount       float64    `json:"amount"`
+	FeeID        *int       `json:"fee_id,omitempty"`
+	Status       string     `json:"status"`
+	Source       string     `json:"source"`
+	CreatedBy    int        `json:"created_by"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+	CancelReason string     `json:"cancel_reason,omitempty"`
+	Refunded     bool       `json:"refunded"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// FacilityBookingRequest 表示预约设施请求
+type FacilityBookingRequest struct {
+	ResidentID    int    `json:"resident_id"` // 前台预约时必填，住户端为本户
+	FacilityID    int    `json:"facility_id" binding:"required"`
+	Date          string `json:"date" binding:"required"`       // 格式: 2006-01-02
+	StartTime     string `json:"start_time" binding:"required"` // 格式: 15:04
+	Slots         int    `json:"slots" binding:"omitempty,min=1"`
+	Attendees     int    `json:"attendees" binding:"omitempty,min=1"`
+	Purpose       string `json:"purpose" binding:"max=100"`
+	PaymentMethod string `json:"payment_method"` // 收费设施的缴费方式，住户端预约不使用
+}
+
+// FacilityPaymentRequest 表示收取预约费用请求
+type FacilityPaymentRequest struct {
+	PaymentMethod string `json:"payment_method" binding:"required"`
+}
+
+// FacilityCancelRequest 表示取消预约请求
+type FacilityCancelRequest struct {
+	Reason string `json:"reason" binding:"max=200"`
+	// WaivePolicy 为true时不论何时取消都全额退款，用于设施临时关闭等物业原因
+	WaivePolicy bool `json:"waive_policy"`
+}
+
+// clock 解析15:04格式的时刻，返回距零点的时长
+func clock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// validateFacility 检查设施的开放时间，至少能容纳一个时段
+func validateFacility(req FacilityRequest) error {
+	opens, err := clock(req.OpenTime)
+	if err != nil {
+		return fmt.Errorf("%w: 开放时间格式应为HH:MM", ErrInvalidFacility)
+	}
+	closes, err := clock(req.CloseTime)
+	if err != nil {
+		return fmt.Errorf("%w: 关闭时间格式应为HH:MM", ErrInvalidFacility)
+	}
+	if closes-opens < time.Duration(req.SlotMinutes)*time.Minute {
+		return fmt.Errorf("%w: 开放时间内至少应有一个时段", ErrInvalidFacility)
+	}
+	return nil
+}
+
+// checkFacilityName 检查设施名称是否已被同一社区的其他设施使用
+func checkFacilityName(name string, communityID, excludeID int) error {
+	var count int
+	err := database.DB.QueryRow(`SELECT COUNT(*) FROM facilities WHERE community_id = ? AND name = ? AND id != ?`,
+		communityID, name, excludeID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrFacilityExists, name)
+	}
+	return nil
+}
+
+// slotsOn 返回设施在date当天的所有时段，最后一个不完整的时段不可预约
+func (f *Facility) slotsOn(date time.Time) []FacilitySlot {
+	opens, _ := clock(f.OpenTime)
+	closes, _ := clock(f.CloseTime)
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	slot := time.Duration(f.SlotMinutes) * time.Minute
+
+	var slots []FacilitySlot
+	for start := opens; start+slot <= closes; start += slot {
+		slots = append(slots, FacilitySlot{Start: day.Add(start), End: day.Add(start + slot), Available: true})
+	}
+	return slots
+}
+
+const facilityColumns = `
+	id, community_id, name, location, description, TIME_FORMAT(open_time, '%H:%i'), TIME_FORMAT(close_time, '%H:%i'),
+	slot_minutes, max_slots, advance_days, weekly_quota, fee_per_slot, cancel_hours, enabled, created_at, updated_at
+`
+
+// scanFacility 解析一行设施数据
+func scanFacility(scanner interface{ Scan(...interface{}) error }) (*Facility, error) {
+	var f Facility
+	err := scanner.Scan(&f.ID, &f.CommunityID, &f.Name, &f.Location, &f.Description, &f.OpenTime, &f.CloseTime,
+		&f.SlotMinutes, &f.MaxSlots, &f.AdvanceDays, &f.WeeklyQuota, &f.FeePerSlot, &f.CancelHours,
+		&f.Enabled, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// GetFacilities 获取社区的设施列表，onlyEnabled为true时只返回启用的设施
+func GetFacilities(communityID int, onlyEnabled bool) ([]Facility, error) {
+	query := `SELECT ` + facilityColumns + ` FROM facilities WHERE community_id = ?`
+	if onlyEnabled {
+		query += ` AND enabled = 1`
+	}
+	query += ` ORDER BY id`
+
+	rows, err := database.DB.Query(query, communityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facilities := []Facility{}
+	for rows.Next() {
+		f, err := scanFacility(rows)
+		if err != nil {
+			return nil, err
+		}
+		facilities = append(facilities, *f)
+	}
+
+	return facilities, rows.Err()
+}
+
+// GetFacilityByID 通过ID获取设施
+func GetFacilityByID(id int) (*Facility, error) {
+	f, err := scanFacility(database.DB.QueryRow(`SELECT `+facilityColumns+` FROM facilities WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// CreateFacility 在指定社区新增设施
+func CreateFacility(req FacilityRequest, communityID int) (int, error) {
+	if err := validateFacility(req); err != nil {
+		return 0, err
+	}
+	if err := checkFacilityName(req.Name, communityID, 0); err != nil {
+		return 0, err
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO facilities (community_id, name, location, description, open_time, close_time, slot_minutes, max_slots,
+		                        advance_days, weekly_quota, fee_per_slot, cancel_hours, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		communityID, req.Name, req.Location, req.Description, req.OpenTime, req.CloseTime, req.SlotMinutes, req.MaxSlots,
+		req.AdvanceDays, req.WeeklyQuota, req.FeePerSlot, req.CancelHours, req.Enabled,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateFacility 修改设施，已有预约不受影响
+func UpdateFacility(id int, req FacilityRequest) error {
+	if err := validateFacility(req); err != nil {
+		return err
+	}
+	var communityID int
+	if err := database.DB.QueryRow(`SELECT community_id FROM facilities WHERE id = ?`, id).Scan(&communityID); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrFacilityNotFound
+		}
+		return err
+	}
+	if err := checkFacilityName(req.Name, communityID, id); err != nil {
+		return err
+	}
+
+	_, err := database.DB.Exec(`
+		UPDATE facilities
+		SET name = ?, location = ?, description = ?, open_time = ?, close_time = ?, slot_minutes = ?, max_slots = ?,
+		    advance_days = ?, weekly_quota = ?, fee_per_slot = ?, cancel_hours = ?, enabled = ?
+		WHERE id = ?`,
+		req.Name, req.Location, req.Description, req.OpenTime, req.CloseTime, req.SlotMinutes, req.MaxSlots,
+		req.AdvanceDays, req.WeeklyQuota, req.FeePerSlot, req.CancelHours, req.Enabled, id,
+	)
+	return err
+}
+
+// GetFacilityAvailability 获取设施在date当天各时段的预约情况，已过去的时段不可预约
+func GetFacilityAvailability(facility *Facility, date time.Time) ([]FacilitySlot, error) {
+	slots := facility.slotsOn(date)
+	if len(slots) == 0 {
+		return slots, nil
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT start_time, end_time FROM facility_bookings
+		WHERE facility_id = ? AND status = ? AND start_time < ? AND end_time > ?`,
+		facility.ID, BookingBooked, slots[len(slots)-1].End, slots[0].Start,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	for i := range slots {
+		slots[i].Available = slots[i].Start.After(now)
+	}
+	for rows.Next() {
+		var start, end time.Time
+		if err := rows.Scan(&start, &end); err != nil {
+			return nil, err
+		}
+		for i := range slots {
+			if slots[i].Start.Before(end) && slots[i].End.After(start) {
+				slots[i].Available = false
+			}
+		}
+	}
+
+	return slots, rows.Err()
+}
+
+// weekStart 返回t所在周的周一零点
+func weekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// CreateFacilityBooking 预约设施，source为staff或resident
+// 同一设施的预约逐个处理，保证并发预约时不会重复占用同一时段；收费设施同时登记缴费
+func CreateFacilityBooking(req FacilityBookingRequest, createdBy uint, source string) (int, error) {
+	now := time.Now()
+	date, err := time.ParseInLocation("2006-01-02", req.Date, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("%w: 日期格式应为YYYY-MM-DD", ErrInvalidBooking)
+	}
+	startClock, err := clock(req.StartTime)
+	if err != nil {
+		return 0, fmt.Errorf("%w: 开始时间格式应为HH:MM", ErrInvalidBooking)
+	}
+	if req.Slots == 0 {
+		req.Slots = 1
+	}
+	if req.Attendees == 0 {
+		req.Attendees = 1
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// 锁定设施，同一设施的预约串行处理
+	facility, err := scanFacility(tx.QueryRow(`SELECT `+facilityColumns+` FROM facilities WHERE id = ? FOR UPDATE`, req.FacilityID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrFacilityNotFound
+		}
+		return 0, err
+	}
+	if !facility.Enabled {
+		return 0, ErrFacilityNotFound
+	}
+
+	// 只能预约住户所在社区的设施
+	var residentCommunityID int
+	if err := tx.QueryRow(`SELECT community_id FROM residents WHERE id = ?`, req.ResidentID).Scan(&residentCommunityID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("%w: 住户不存在", ErrInvalidBooking)
+		}
+		return 0, err
+	}
+	if residentCommunityID != facility.CommunityID {
+		return 0, ErrFacilityNotFound
+	}
+
+	// 开始时间须对齐时段，且全部时段都在开放时间内
+	start := date.Add(startClock)
+	end := start.Add(time.Duration(req.Slots*facility.SlotMinutes) * time.Minute)
+	if req.Slots > facility.MaxSlots {
+		return 0, fmt.Errorf("%w: 每次最多预约%d个时段", ErrInvalidBooking, facility.MaxSlots)
+	}
+	aligned := false
+	slots := facility.slotsOn(date)
+	for _, slot := range slots {
+		if slot.Start.Equal(start) {
+			aligned = true
+			break
+		}
+	}
+	if !aligned || len(slots) == 0 || end.After(slots[len(slots)-1].End) {
+		return 0, fmt.Errorf("%w: %s开放时间为%s-%s，每%d分钟一个时段",
+			ErrInvalidBooking, facility.Name, facility.OpenTime, facility.CloseTime, facility.SlotMinutes)
+	}
+	if !start.After(now) {
+		return 0, fmt.Errorf("%w: 不能预约已开始的时段", ErrInvalidBooking)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if date.After(today.AddDate(0, 0, facility.AdvanceDays)) {
+		return 0, fmt.Errorf("%w: 最多提前%d天预约", ErrInvalidBooking, facility.AdvanceDays)
+	}
+
+	var conflicts int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM facility_bookings
+		WHERE facility_id = ? AND status = ? AND start_time < ? AND end_time > ?`,
+		facility.ID, BookingBooked, end, start,
+	).Scan(&conflicts)
+	if err != nil {
+		return 0, err
+	}
+	if conflicts > 0 {
+		return 0, ErrBookingConflict
+	}
+
+	if facility.WeeklyQuota > 0 {
+		monday := weekStart(start)
+		var used int
+		err = tx.QueryRow(`
+			SELECT COUNT(*) FROM facility_bookings
+			WHERE facility_id = ? AND resident_id = ? AND status = ? AND start_time >= ? AND start_time < ?`,
+			facility.ID, req.ResidentID, BookingBooked, monday, monday.AddDate(0, 0, 7),
+		).Scan(&used)
+		if err != nil {
+			return 0, err
+		}
+		if used >= facility.WeeklyQuota {
+			return 0, fmt.Errorf("%w: %s每户每周最多预约%d次", ErrBookingQuota, facility.Name, facility.WeeklyQuota)
+		}
+	}
+
+	// 前台预约收费设施时当场收费；住户端预约只记录应付金额，待前台确认收款后再登记缴费
+	amount := roundAmount(facility.FeePerSlot * float64(req.Slots))
+	var feeID sql.NullInt64
+	if amount > 0 && source != BookingSourceResident {
+		if req.PaymentMethod == "" {
+			return 0, fmt.Errorf("%w: 收费设施需填写缴费方式", ErrInvalidBooking)
+		}
+		if err := checkDateOpenTx(tx, facility.CommunityID, now); err != nil {
+			return 0, err
+		}
+		id, err := insertBookingFee(tx, facility.Name, req.ResidentID, amount, today, req.PaymentMethod, start, req.Slots)
+		if err != nil {
+			return 0, err
+		}
+		feeID = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO facility_bookings (facility_id, resident_id, start_time, end_time, slots, attendees, purpose,
+		                               amount, fee_id, status, source, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		facility.ID, req.ResidentID, start, end, req.Slots, req.Attendees, req.Purpose,
+		amount, feeID, BookingBooked, source, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if feeID.Valid {
+		if err := postBookingPaymentTx(tx, int(id), int(feeID.Int64)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if feeID.Valid {
+		if err := RefreshMonthlyStats(facility.CommunityID, today.Year(), int(today.Month())); err != nil {
+			fmt.Printf("刷新月度统计失败: %v\n", err)
+		}
+	}
+
+	return int(id), nil
+}
+
+// insertBookingFee 为设施预约登记一笔已缴费的缴费记录，返回缴费记录ID
+func insertBookingFee(tx *sql.Tx, facilityName string, residentID int, amount float64, paymentDate time.Time, paymentMethod string, start time.Time, slots int) (int64, error) {
+	feeType, err := GetFeeTypeByCode(FacilityFeeCode)
+	if err != nil {
+		return 0, err
+	}
+	if feeType == nil {
+		return 0, errors.New("未配置公共设施使用费收费项目")
+	}
+
+	remark := fmt.Sprintf("%s %s %d个时段", facilityName, start.Format("2006-01-02 15:04"), slots)
+	result, err := tx.Exec(`
+		INSERT INTO property_fees (resident_id, fee_type_id, amount, payment_date, payment_method, payment_status, remark)
+		VALUES (?, ?, ?, ?, ?, 1, ?)`,
+		residentID, feeType.ID, amount, paymentDate, paymentMethod, remark,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// postBookingPaymentTx 在事务中为设施预约的收款和场地使用收入记账
+func postBookingPaymentTx(tx *sql.Tx, bookingID, feeID int) error {
+	if err := postPaymentTx(tx, feeID); err != nil {
+		return fmt.Errorf("缴费记账失败: %w", err)
+	}
+	if err := postBookingEntry(tx, bookingID, false); err != nil {
+		return fmt.Errorf("设施预约记账失败: %w", err)
+	}
+	return nil
+}
+
+// PayFacilityBooking 前台确认收取住户端预约的费用，登记缴费并确认场地使用收入
+func PayFacilityBooking(id int, req FacilityPaymentRequest) error {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var residentID, communityID, slots int
+	var amount float64
+	var start time.Time
+	var facilityName, status string
+	var feeID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT b.resident_id, f.community_id, b.slots, b.amount, b.start_time, f.name, b.status, b.fee_id
+		FROM facility_bookings b JOIN facilities f ON b.facility_id = f.id
+		WHERE b.id = ? FOR UPDATE OF b`, id).
+		Scan(&residentID, &communityID, &slots, &amount, &start, &facilityName, &status, &feeID)
+	if err != nil {
+		return err
+	}
+	if err := checkDateOpenTx(tx, communityID, today); err != nil {
+		return err
+	}
+	if status != BookingBooked || amount <= 0 || feeID.Valid {
+		return ErrBookingNotPayable
+	}
+
+	fee, err := insertBookingFee(tx, facilityName, residentID, amount, today, req.PaymentMethod, start, slots)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE facility_bookings SET fee_id = ? WHERE id = ?`, fee, id); err != nil {
+		return err
+	}
+	if err := postBookingPaymentTx(tx, id, int(fee)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := RefreshMonthlyStats(communityID, today.Year(), int(today.Month())); err != nil {
+		fmt.Printf("刷新月度统计失败: %v\n", err)
+	}
+	return nil
+}
+
+const facilityBookingColumns = `
+	b.id, f.community_id, b.facility_id, f.name, b.resident_id, r.name, CONCAT(r.block_number, r.unit_number, r.house_number),
+	b.start_time, b.end_time, b.slots, b.attendees, b.purpose, b.amount, b.fee_id, b.status, b.source,
+	b.created_by, b.cancelled_at, b.cancel_reason, b.refunded, b.created_at
+	FROM facility_bookings b
+	JOIN facilities f ON b.facility_id = f.id
+	JOIN residents r ON b.resident_id = r.id
+`
+
+// scanFacilityBooking 解析一行设施预约数据
+func scanFacilityBooking(scanner interface{ Scan(...interface{}) error }, now time.Time) (*FacilityBooking, error) {
+	var b FacilityBooking
+	var feeID sql.NullInt64
+	var cancelledAt sql.NullTime
+	err := scanner.Scan(&b.ID, &b.CommunityID, &b.FacilityID, &b.FacilityName, &b.ResidentID, &b.ResidentName, &b.Room,
+		&b.StartTime, &b.EndTime, &b.Slots, &b.Attendees, &b.Purpose, &b.Amount, &feeID, &b.Status, &b.Source,
+		&b.CreatedBy, &cancelledAt, &b.CancelReason, &b.Refunded, &b.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if feeID.Valid {
+		id := int(feeID.Int64)
+		b.FeeID = &id
+	}
+	b.CancelledAt = nullTimePtr(cancelledAt)
+	if b.Status == BookingBooked && !b.EndTime.After(now) {
+		b.Status = BookingFinished
+	}
+
+	return &b, nil
+}
+
+// GetFacilityBookings 获取设施预约列表，支持按设施、住户、状态和日期查询，upcoming为true时只返回未开始的预约
+func GetFacilityBookings(page, pageSize int, filters map[string]interface{}) ([]FacilityBooking, int, error) {
+	now := time.Now()
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND b.facility_id IN (SELECT id FROM facilities WHERE community_id = ?)`
+		args = append(args, communityID)
+	}
+	if facilityID, ok := filters["facility_id"].(int); ok && facilityID > 0 {
+		where += ` AND b.facility_id = ?`
+		args = append(args, facilityID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND b.resident_id = ?`
+		args = append(args, residentID)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		switch status {
+		case BookingBooked:
+			where += ` AND b.status = 'booked' AND b.end_time > ?`
+			args = append(args, now)
+		case BookingFinished:
+			where += ` AND b.status = 'booked' AND b.end_time <= ?`
+			args = append(args, now)
+		default:
+			where += ` AND b.status = ?`
+			args = append(args, status)
+		}
+	}
+	if date, ok := filters["date"].(time.Time); ok {
+		where += ` AND b.start_time >= ? AND b.start_time < ?`
+		args = append(args, date, date.AddDate(0, 0, 1))
+	}
+	if upcoming, ok := filters["upcoming"].(bool); ok && upcoming {
+		where += ` AND b.status = 'booked' AND b.start_time > ?`
+		args = append(args, now)
+	}
+	if unpaid, ok := filters["unpaid"].(bool); ok && unpaid {
+		where += ` AND b.status = 'booked' AND b.amount > 0 AND b.fee_id IS NULL`
+	}
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM facility_bookings b`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + facilityBookingColumns + where + ` ORDER BY b.start_time DESC, b.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	bookings := []FacilityBooking{}
+	for rows.Next() {
+		b, err := scanFacilityBooking(rows, now)
+		if err != nil {
+			return nil, 0, err
+		}
+		bookings = append(bookings, *b)
+	}
+
+	return bookings, total, rows.Err()
+}
+
+// GetFacilityBookingByID 通过ID获取设施预约
+func GetFacilityBookingByID(id int) (*FacilityBooking, error) {
+	b, err := scanFacilityBooking(database.DB.QueryRow(`SELECT `+facilityBookingColumns+` WHERE b.id = ?`, id), time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// CancelFacilityBooking 取消尚未开始的预约
+// 收费预约在开始前cancel_hours小时之前取消，或waivePolicy为true时全额退款，否则不退费
+// 退款与取消在同一事务中完成，返回是否已退款
+func CancelFacilityBooking(id int, req FacilityCancelRequest) (bool, error) {
+	now := time.Now()
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var start time.Time
+	var cancelHours int
+	var status string
+	var feeID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT b.start_time, f.cancel_hours, b.status, b.fee_id
+		FROM facility_bookings b JOIN facilities f ON b.facility_id = f.id
+		WHERE b.id = ? FOR UPDATE`, id).Scan(&start, &cancelHours, &status, &feeID)
+	if err != nil {
+		return false, err
+	}
+	if status != BookingBooked || !start.After(now) {
+		return false, ErrBookingNotCancellable
+	}
+
+	refund := feeID.Valid && (req.WaivePolicy || !now.After(start.Add(-time.Duration(cancelHours)*time.Hour)))
+	if _, err := tx.Exec(`
+		UPDATE facility_bookings SET status = ?, cancelled_at = ?, cancel_reason = ?, refunded = ?
+		WHERE id = ?`,
+		BookingCancelled, now, req.Reason, refund, id,
+	); err != nil {
+		return false, err
+	}
+
+	var paymentDate time.Time
+	var communityID int
+	if refund {
+		reason := "取消设施预约"
+		if req.Reason != "" {
+			reason += ": " + req.Reason
+		}
+		refundDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		paymentDate, communityID, err = refundPropertyFeeTx(tx, int(feeID.Int64), refundDate, reason)
+		if err != nil {
+			return false, fmt.Errorf("退款失败: %w", err)
+		}
+		if err := postBookingEntry(tx, id, true); err != nil {
+			return false, fmt.Errorf("设施预约退订记账失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	if !refund {
+		return false, nil
+	}
+
+	refreshRefundStats(communityID, paymentDate)
+	return true, nil
+}
@@ -0,0 +1,350 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"community-backward/database"
+)
+
+// 费用调整类型
+const (
+	AdjustmentWaiver   = "waiver"   // 困难减免
+	AdjustmentDiscount = "discount" // 提前缴费优惠
+)
+
+// 费用调整状态
+const (
+	AdjustmentPending  = "pending"
+	AdjustmentApproved = "approved"
+	AdjustmentRejected = "rejected"
+)
+
+var (
+	// ErrAdjustmentExceedsBill 调整金额超过账单剩余可调整金额
+	ErrAdjustmentExceedsBill = errors.New("调整金额超过账单可减免金额")
+	// ErrAdjustmentNotPending 费用调整已审批，不能重复审批
+	ErrAdjustmentNotPending = errors.New("该申请已审批")
+)
+
+// FeeAdjustment 表示一次费用减免或优惠
+type FeeAdjustment struct {
+	ID           int        `json:"id"`
+	ResidentID   int        `json:"resident_id"`
+	ResidentName string     `json:"resident_name,omitempty"`
+	CommunityID  int        `json:"community_id"`
+	BillID       int        `json:"bill_id"`
+	BillYear     int        `json:"bill_year"`
+	BillMonth    int        `json:"bill_month"`
+	Type         string     `json:"type"`
+	Amount       float64    `json:"amount"`
+	Reason       string     `json:"reason"`
+	Status       string     `json:"status"`
+	RequestedBy  int        `json:"requested_by"`
+	ReviewedBy   int        `json:"reviewed_by,omitempty"`
+	ReviewRemark string     `json:"review_remark,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at,omitempty"`
+}
+
+// FeeAdjustmentRequest 表示费用调整申请
+type FeeAdjustmentRequest struct {
+	BillID int     `json:"bill_id" binding:"required"`
+	Type   string  `json:"type" binding:"required,oneof=waiver discount"`
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+// FeeAdjustmentReviewRequest 表示审批请求
+type FeeAdjustmentReviewRequest struct {
+	Remark string `json:"remark"`
+}
+
+// FeeAdjustmentReport 表示某期间的减免汇总
+type FeeAdjustmentReport struct {
+	Year          int             `json:"year"`
+	Month         int             `json:"month,omitempty"`
+	WaiverTotal   float64         `json:"waiverTotal"`
+	DiscountTotal float64         `json:"discountTotal"`
+	Count         int             `json:"count"`
+	List          []FeeAdjustment `json:"list"`
+}
+
+const feeAdjustmentColumns = `
+	a.id, a.resident_id, r.name, r.community_id, a.bill_id, b.year, b.month, a.type, a.amount, a.reason, a.status,
+	a.requested_by, a.reviewed_by, a.review_remark, a.reviewed_at, a.created_at
+`
+
+// scanFeeAdjustment 解析一行费用调整数据
+func scanFeeAdjustment(scanner interface{ Scan(...interface{}) error }) (*FeeAdjustment, error) {
+	var a FeeAdjustment
+	var reviewedBy sql.NullInt64
+	var remark sql.NullString
+	var reviewedAt sql.NullTime
+	err := scanner.Scan(&a.ID, &a.ResidentID, &a.ResidentName, &a.CommunityID, &a.BillID, &a.BillYear, &a.BillMonth, &a.Type,
+		&a.Amount, &a.Reason, &a.Status, &a.RequestedBy, &reviewedBy, &remark, &reviewedAt, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	a.ReviewedBy = int(reviewedBy.Int64)
+	a.ReviewRemark = remark.String
+	if reviewedAt.Valid {
+		a.ReviewedAt = &reviewedAt.Time
+	}
+	return &a, nil
+}
+
+// CreateFeeAdjustment 提交费用调整申请
+// 同一账单上待审批和已通过的调整合计不能超过账单金额，检查时锁定账单，避免并发申请合计超额
+func CreateFeeAdjustment(req FeeAdjustmentRequest, requestedBy uint) (int, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var residentID int
+	var billAmount float64
+	err = tx.QueryRow(`SELECT resident_id, amount FROM bills WHERE id = ? FOR UPDATE`, req.BillID).Scan(&residentID, &billAmount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("账单不存在: %d", req.BillID)
+		}
+		return 0, err
+	}
+
+	var adjusted float64
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(amount), 0) FROM fee_adjustments
+		WHERE bill_id = ? AND status IN (?, ?)`,
+		req.BillID, AdjustmentPending, AdjustmentApproved,
+	).Scan(&adjusted)
+	if err != nil {
+		return 0, err
+	}
+	if roundAmount(adjusted+req.Amount) > billAmount {
+		return 0, fmt.Errorf("%w: 账单金额%.2f，已申请%.2f", ErrAdjustmentExceedsBill, billAmount, adjusted)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO fee_adjustments (resident_id, bill_id, type, amount, reason, status, requested_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		residentID, req.BillID, req.Type, req.Amount, req.Reason, AdjustmentPending, requestedBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetFeeAdjustmentByID 通过ID获取费用调整
+func GetFeeAdjustmentByID(id int) (*FeeAdjustment, error) {
+	query := `SELECT ` + feeAdjustmentColumns + `
+		FROM fee_adjustments a
+		JOIN residents r ON a.resident_id = r.id
+		JOIN bills b ON a.bill_id = b.id
+		WHERE a.id = ?`
+
+	a, err := scanFeeAdjustment(database.DB.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// GetFeeAdjustments 获取费用调整列表
+func GetFeeAdjustments(page, pageSize int, filters map[string]interface{}) ([]FeeAdjustment, int, error) {
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND a.resident_id = ?`
+		args = append(args, residentID)
+	}
+
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+
+	if status, ok := filters["status"].(string); ok && status != "" {
+		where += ` AND a.status = ?`
+		args = append(args, status)
+	}
+
+	if adjType, ok := filters["type"].(string); ok && adjType != "" {
+		where += ` AND a.type = ?`
+		args = append(args, adjType)
+	}
+
+	from := `
+		FROM fee_adjustments a
+		JOIN residents r ON a.resident_id = r.id
+		JOIN bills b ON a.bill_id = b.id`
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*)`+from+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + feeAdjustmentColumns + from + where + ` ORDER BY a.created_at DESC, a.id DESC LIMIT ? OFFSET ?`
+	args = append(args, pageSize, (page-1)*pageSize)
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var list []FeeAdjustment
+	for rows.Next() {
+		a, err := scanFeeAdjustment(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *a)
+	}
+
+	return list, total, nil
+}
+
+// ReviewFeeAdjustment 审批费用调整，approve为true表示通过
+// 账单所在账期已结账时不能审批通过
+func ReviewFeeAdjustment(id int, approve bool, reviewerID uint, remark string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var communityID, year, month int
+	err = tx.QueryRow(`
+		SELECT a.status, r.community_id, b.year, b.month
+		FROM fee_adjustments a
+		JOIN bills b ON a.bill_id = b.id
+		JOIN residents r ON b.resident_id = r.id
+		WHERE a.id = ? FOR UPDATE OF a`, id).Scan(&status, &communityID, &year, &month)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrAdjustmentNotPending
+		}
+		return err
+	}
+	if status != AdjustmentPending {
+		return ErrAdjustmentNotPending
+	}
+
+	status = AdjustmentRejected
+	if approve {
+		status = AdjustmentApproved
+		if err := checkPeriodOpenTx(tx, communityID, year, month); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE fee_adjustments
+		SET status = ?, reviewed_by = ?, review_remark = ?, reviewed_at = NOW()
+		WHERE id = ?`,
+		status, reviewerID, remark, id,
+	); err != nil {
+		return err
+	}
+
+	if approve {
+		if err := postAdjustmentTx(tx, id); err != nil {
+			return fmt.Errorf("费用调整记账失败: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetFeeAdjustmentReport 获取指定社区指定账期内已通过的减免和优惠，month为0时统计全年
+func GetFeeAdjustmentReport(year, month, communityID int) (*FeeAdjustmentReport, error) {
+	query := `SELECT ` + feeAdjustmentColumns + `
+		FROM fee_adjustments a
+		JOIN residents r ON a.resident_id = r.id
+		JOIN bills b ON a.bill_id = b.id
+		WHERE a.status = ? AND b.year = ? AND r.community_id = ?`
+	args := []interface{}{AdjustmentApproved, year, communityID}
+	if month > 0 {
+		query += ` AND b.month = ?`
+		args = append(args, month)
+	}
+	query += ` ORDER BY b.month ASC, a.id ASC`
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &FeeAdjustmentReport{Year: year, Month: month, List: []FeeAdjustment{}}
+	for rows.Next() {
+		a, err := scanFeeAdjustment(rows)
+		if err != nil {
+			return nil, err
+		}
+		switch a.Type {
+		case AdjustmentWaiver:
+			report.WaiverTotal = roundAmount(report.WaiverTotal + a.Amount)
+		case AdjustmentDiscount:
+			report.DiscountTotal = roundAmount(report.DiscountTotal + a.Amount)
+		}
+		report.List = append(report.List, *a)
+	}
+	report.Count = len(report.List)
+
+	return report, rows.Err()
+}
+
+// getApprovedAdjustments 获取截至asOf各账单已通过的调整金额
+func getApprovedAdjustments(asOf time.Time, residentID int) (map[int]float64, error) {
+	query := `
+		SELECT bill_id, COALESCE(SUM(amount), 0)
+		FROM fee_adjustments
+		WHERE status = ? AND reviewed_at <= ?
+	`
+	args := []interface{}{AdjustmentApproved, asOf}
+	if residentID > 0 {
+		query += ` AND resident_id = ?`
+		args = append(args, residentID)
+	}
+	query += ` GROUP BY bill_id`
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询费用调整失败: %w", err)
+	}
+	defer rows.Close()
+
+	adjustments := make(map[int]float64)
+	for rows.Next() {
+		var billID int
+		var amount float64
+		if err := rows.Scan(&billID, &amount); err != nil {
+			return nil, fmt.Errorf("解析费用调整失败: %w", err)
+		}
+		adjustments[billID] = amount
+	}
+
+	return adjustments, rows.Err()
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"community-backward/database"
+)
+
+// 计费规则
+const (
+	PricingPerArea     = "per_area"     // 按建筑面积计费
+	PricingFixed       = "fixed"        // 按户固定收费
+	PricingPerUsage    = "per_usage"    // 按用量计费
+	PricingPerContract = "per_contract" // 按租赁合同计费（车位租金）
+	PricingPerBooking  = "per_booking"  // 按预约计费（公共设施使用费）
+	PricingDeposit     = "deposit"      // 押金，按申请收取并在结案时退还（装修押金）
+)
+
+// DefaultFeeTypeID 物业费的收费项目ID，未指定收费项目时使用
+const DefaultFeeTypeID = 1
+
+// FeeType 表示收费项目
+type FeeType struct {
+	ID                 int       `json:"id"`
+	Code               string    `json:"code"`
+	Name               string    `json:"name"`
+	PricingRule        string    `json:"pricing_rule"`
+	UnitPrice          float64   `json:"unit_price"`
+	Unit               string    `json:"unit"`
+	AccountingCategory string    `json:"accounting_category"`
+	AccountCode        string    `json:"account_code"` // 总账贷方科目
+	Enabled            bool      `json:"enabled"`
+	CreatedAt          time.Time `json:"created_at,omitempty"`
+	UpdatedAt          time.Time `json:"updated_at,omitempty"`
+}
+
+// FeeTypeRequest 表示收费项目请求
+type FeeTypeRequest struct {
+	Code               string  `json:"code" binding:"required"`
+	Name               string  `json:"name" binding:"required"`
+	PricingRule        string  `json:"pricing_rule" binding:"required,oneof=per_area fixed per_usage per_contract per_booking deposit"`
+	UnitPrice          float64 `json:"unit_price" binding:"min=0"`
+	Unit               string  `json:"unit"`
+	AccountingCategory string  `json:"accounting_category" binding:"required"`
+	AccountCode        string  `json:"account_code"` // 缺省为物业服务收入
+	Enabled            bool    `json:"enabled"`
+}
+
+const feeTypeColumns = `id, code, name, pricing_rule, unit_price, unit, accounting_category, account_code, enabled, created_at, updated_at`
+
+// defaultFeeAccountCode 未指定科目的收费项目记入物业服务收入
+const defaultFeeAccountCode = "6001.01"
+
+// scanFeeType 解析一行收费项目数据
+func scanFeeType(scanner interface{ Scan(...interface{}) error }) (*FeeType, error) {
+	var t FeeType
+	err := scanner.Scan(&t.ID, &t.Code, &t.Name, &t.PricingRule, &t.UnitPrice, &t.Unit,
+		&t.AccountingCategory, &t.AccountCode, &t.Enabled, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetFeeTypes 获取收费项目列表
+func GetFeeTypes(onlyEnabled bool) ([]FeeType, error) {
+	query := `SELECT ` + feeTypeColumns + ` FROM fee_types`
+	if onlyEnabled {
+		query += ` WHERE enabled = 1`
+	}
+	query += ` ORDER BY id ASC`
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var types []FeeType
+	for rows.Next() {
+		t, err := scanFeeType(rows)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, *t)
+	}
+
+	return types, rows.Err()
+}
+
+// GetFeeTypeByID 通过ID获取收费项目
+func GetFeeTypeByID(id int) (*FeeType, error) {
+	t, err := scanFeeType(database.DB.QueryRow(`SELECT `+feeTypeColumns+` FROM fee_types WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetFeeTypeByCode 通过编码获取收费项目
+func GetFeeTypeByCode(code string) (*FeeType, error) {
+	t, err := scanFeeType(database.DB.QueryRow(`SELECT `+feeTypeColumns+` FROM fee_types WHERE code = ?`, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// CreateFeeType 创建收费项目
+func CreateFeeType(req FeeTypeRequest) (int, error) {
+	if req.AccountCode == "" {
+		req.AccountCode = defaultFeeAccountCode
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO fee_types (code, name, pricing_rule, unit_price, unit, accounting_category, account_code, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.Code, req.Name, req.PricingRule, req.UnitPrice, req.Unit, req.AccountingCategory, req.AccountCode, req.Enabled,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateFeeType 更新收费项目
+func UpdateFeeType(id int, req FeeTypeRequest) error {
+	if req.AccountCode == "" {
+		req.AccountCode = defaultFeeAccountCode
+	}
+
+	_, err := database.DB.Exec(`
+		UPDATE fee_types
+		SET code = ?, name = ?, pricing_rule = ?, unit_price = ?, unit = ?, accounting_category = ?, account_code = ?, enabled = ?
+		WHERE id = ?`,
+		req.Code, req.Name, req.PricingRule, req.UnitPrice, req.Unit, req.AccountingCategory, req.AccountCode, req.Enabled, id,
+	)
+	return err
+}
+
+// CalculateFee 按收费项目的计费规则计算住户的应收金额
+// usage仅用于按用量计费的项目
+func (t *FeeType) CalculateFee(r *Resident, usage float64) float64 {
+	switch t.PricingRule {
+	case PricingPerArea:
+		return roundAmount(r.HouseArea * t.UnitPrice)
+	case PricingFixed:
+		return roundAmount(t.UnitPrice)
+	case PricingPerUsage:
+		return roundAmount(usage * t.UnitPrice)
+	}
+	return 0
+}
+
+// CheckBatchBilling 检查收费项目能否按计费规则为社区住户批量生成账单
+func (t *FeeType) CheckBatchBilling() error {
+	switch {
+	case t.PricingRule == PricingPerUsage:
+		return fmt.Errorf("%s按用量计费，需通过抄表生成账单", t.Name)
+	case t.PricingRule == PricingPerContract:
+		return fmt.Errorf("%s按租赁合同计费，需根据车位租赁合同生成账单", t.Name)
+	case t.PricingRule == PricingPerBooking:
+		return fmt.Errorf("%s按预约收取，不能批量生成账单", t.Name)
+	case t.PricingRule == PricingDeposit:
+		return fmt.Errorf("%s为押金，需在办理业务时收取", t.Name)
+	case t.Code == LateFeeCode:
+		return fmt.Errorf("%s按欠费情况计收，不能批量生成", t.Name)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"community-backward/database"
+)
+
+// LateFeeCode 滞纳金收费项目代码
+const LateFeeCode = "late_fee"
+
+// LateFeePolicy 表示滞纳金计收规则
+type LateFeePolicy struct {
+	DailyRate     float64 // 日费率，按未缴金额计收
+	GraceDays     int     // 账单到期后的宽限天数
+	PlanGraceDays int     // 分期到期后的宽限天数，用于判断计收月份的分期计划是否正常执行
+}
+
+// LateFeeRequest 表示计收滞纳金请求
+type LateFeeRequest struct {
+	Year  int `json:"year" binding:"required"`
+	Month int `json:"month" binding:"required,min=1,max=12"`
+}
+
+// LateFeeResult 表示一次计收滞纳金的结果
+type LateFeeResult struct {
+	Year      int     `json:"year"`
+	Month     int     `json:"month"`
+	Created   int     `json:"created"`   // 新生成的滞纳金账单数
+	Suspended int     `json:"suspended"` // 因分期计划正常执行而暂停计收部分账单的住户数
+	Amount    float64 `json:"amount"`    // 新生成的滞纳金合计
+}
+
+// GenerateLateFees 按月末欠费计收指定社区指定月份的滞纳金，每户每月生成一张滞纳金账单
+// 逾期天数按当月内超过宽限期的天数计算，滞纳金本身不再计收滞纳金；
+// 当月月末分期计划正常执行的住户，计划覆盖的账单（计划生效日前到期）暂停计收，其他账单照常计收；
+// 已生成的月份会被跳过
+func GenerateLateFees(year, month, communityID int, policy LateFeePolicy) (*LateFeeResult, error) {
+	// 已结账的月份不再计算欠费，写入前在事务中再次检查
+	if err := CheckPeriodOpen(communityID, year, month); err != nil {
+		return nil, err
+	}
+
+	feeType, err := GetFeeTypeByCode(LateFeeCode)
+	if err != nil {
+		return nil, err
+	}
+	if feeType == nil || !feeType.Enabled {
+		return nil, fmt.Errorf("未启用滞纳金收费项目（%s）", LateFeeCode)
+	}
+
+	monthStart := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+	monthEnd := monthStart.AddDate(0, 1, -1)
+	if !time.Now().After(endOfDay(monthEnd)) {
+		return nil, fmt.Errorf("%d年%d月尚未结束，不能计收滞纳金", year, month)
+	}
+
+	open, err := getOpenBills(endOfDay(monthEnd), 0, communityID)
+	if err != nil {
+		return nil, err
+	}
+
+	honoured, err := getHonouredPlanCoverage(endOfDay(monthEnd), policy.PlanGraceDays)
+	if err != nil {
+		return nil, err
+	}
+
+	fees := make(map[int]float64)
+	suspended := make(map[int]bool)
+	for _, b := range open {
+		if b.FeeTypeID == feeType.ID {
+			continue
+		}
+
+		chargeFrom := b.DueDate.AddDate(0, 0, policy.GraceDays+1)
+		if chargeFrom.Before(monthStart) {
+			chargeFrom = monthStart
+		}
+		days := daysBetween(chargeFrom, monthEnd) + 1
+		if days <= 0 {
+			continue
+		}
+
+		if start, ok := honoured[b.ResidentID]; ok && !b.DueDate.After(start) {
+			suspended[b.ResidentID] = true
+			continue
+		}
+		fees[b.ResidentID] += b.Outstanding * policy.DailyRate * float64(days)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := checkPeriodOpenTx(tx, communityID, year, month); err != nil {
+		return nil, err
+	}
+
+	result := &LateFeeResult{Year: year, Month: month, Suspended: len(suspended)}
+	dueDate := monthEnd.AddDate(0, 1, 0)
+	remark := fmt.Sprintf("%d年%d月滞纳金", year, month)
+	for residentID, fee := range fees {
+		amount := roundAmount(fee)
+		if amount < 0.01 {
+			continue
+		}
+
+		id, err := insertBillIgnore(tx, residentID, feeType.ID, year, month, amount, dueDate, remark)
+		if err != nil {
+			return nil, fmt.Errorf("生成住户%d滞纳金失败: %w", residentID, err)
+		}
+		if id == 0 {
+			continue
+		}
+		if err := postBillTx(tx, id); err != nil {
+			return nil, fmt.Errorf("住户%d滞纳金记账失败: %w", residentID, err)
+		}
+
+		result.Created++
+		result.Amount = roundAmount(result.Amount + amount)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,563 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"community-backward/database"
+)
+
+// 租约状态
+const (
+	LeaseActive     = "active"     // 生效中
+	LeaseExpired    = "expired"    // 已到期，仅用于显示和查询，数据库中仍为active
+	LeaseTerminated = "terminated" // 已提前解约
+)
+
+// 物业费缴纳方
+const (
+	FeePayerOwner  = "owner"  // 业主
+	FeePayerTenant = "tenant" // 租户
+)
+
+var (
+	// ErrInvalidLease 租约信息不完整
+	ErrInvalidLease = errors.New("无效的租约")
+	// ErrLeaseOverlap 同一房屋的租期重叠
+	ErrLeaseOverlap = errors.New("该房屋在此期间已有生效的租约")
+	// ErrLeaseNotActive 租约已解约
+	ErrLeaseNotActive = errors.New("租约已解约")
+)
+
+// Lease 表示房屋租约
+type Lease struct {
+	ID              int           `json:"id"`
+	CommunityID     int           `json:"community_id"` // 房屋所在社区
+	ResidentID      int           `json:"resident_id"`
+	OwnerName       string        `json:"owner_name"`
+	Room            string        `json:"room"`
+	StartDate       time.Time     `json:"start_date"`
+	EndDate         time.Time     `json:"end_date"`
+	MonthlyRent     float64       `json:"monthly_rent"`
+	Deposit         float64       `json:"deposit"`
+	FeePayer        string        `json:"fee_payer"`
+	Status          string        `json:"status"` // 生效中但结束日期已过时为expired
+	DaysLeft        int           `json:"days_left"`
+	Note            string        `json:"note"`
+	CreatedBy       int           `json:"created_by"`
+	RemindedAt      *time.Time    `json:"reminded_at,omitempty"`
+	TerminatedAt    *time.Time    `json:"terminated_at,omitempty"`
+	TerminateReason string        `json:"terminate_reason,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+	Tenants         []LeaseTenant `json:"tenants"`
+}
+
+// LeaseTenant 表示租约中的租户
+type LeaseTenant struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name" binding:"required,max=50"`
+	Phone     string `json:"phone" binding:"max=20"`
+	Email     string `json:"email" binding:"omitempty,email,max=100"`
+	IDNumber  string `json:"id_number" binding:"max=30"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+// LeaseRequest 表示创建或更新租约请求，tenants中第一名租户为主租户
+// fee_payer为空时使用系统默认的缴费方
+type LeaseRequest struct {
+	ResidentID  int           `json:"resident_id" binding:"required"`
+	StartDate   string        `json:"start_date" binding:"required"`
+	EndDate     string        `json:"end_date" binding:"required"`
+	MonthlyRent float64       `json:"monthly_rent" binding:"gte=0"`
+	Deposit     float64       `json:"deposit" binding:"gte=0"`
+	FeePayer    string        `json:"fee_payer" binding:"omitempty,oneof=owner tenant"`
+	Note        string        `json:"note" binding:"max=255"`
+	Tenants     []LeaseTenant `json:"tenants" binding:"required,min=1,max=10,dive"`
+}
+
+// LeaseRenewRequest 表示续租请求
+type LeaseRenewRequest struct {
+	EndDate     string   `json:"end_date" binding:"required"`
+	MonthlyRent *float64 `json:"monthly_rent" binding:"omitempty,gte=0"`
+}
+
+// LeaseTerminateRequest 表示提前解约请求
+type LeaseTerminateRequest struct {
+	Date   string `json:"date" binding:"required"`
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// BillPayer 表示某房屋某日的物业费缴纳人
+type BillPayer struct {
+	Payer   string `json:"payer"` // owner或tenant
+	LeaseID int    `json:"lease_id,omitempty"`
+	Name    string `json:"name"`
+	Phone   string `json:"phone"`
+	Email   string `json:"email"`
+}
+
+// parseLeaseDate 解析租约日期
+func parseLeaseDate(value string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: 无效的日期%s", ErrInvalidLease, value)
+	}
+	return t, nil
+}
+
+// checkLeaseOverlap 检查房屋在[start, end]期间是否已有其他生效的租约
+func checkLeaseOverlap(tx *sql.Tx, residentID, excludeID int, start, end time.Time) error {
+	var count int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM leases
+		WHERE resident_id = ? AND id <> ? AND status = ? AND start_date <= ? AND end_date >= ?
+		FOR UPDATE`,
+		residentID, excludeID, LeaseActive, end.Format("2006-01-02"), start.Format("2006-01-02"),
+	).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrLeaseOverlap
+	}
+	return nil
+}
+
+// saveLeaseTenants 替换租约的租户，第一名租户为主租户
+func saveLeaseTenants(tx *sql.Tx, leaseID int, tenants []LeaseTenant) error {
+	if _, err := tx.Exec(`DELETE FROM lease_tenants WHERE lease_id = ?`, leaseID); err != nil {
+		return err
+	}
+	for i, t := range tenants {
+		_, err := tx.Exec(`
+			INSERT INTO lease_tenants (lease_id, name, phone, email, id_number, is_primary) VALUES (?, ?, ?, ?, ?, ?)`,
+			leaseID, strings.TrimSpace(t.Name), strings.TrimSpace(t.Phone), strings.TrimSpace(t.Email),
+			strings.TrimSpace(t.IDNumber), i == 0,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateLease 校验租约请求，返回解析后的起止日期
+func validateLease(req *LeaseRequest, defaultPayer string) (time.Time, time.Time, error) {
+	start, err := parseLeaseDate(req.StartDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := parseLeaseDate(req.EndDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: 结束日期不能早于开始日期", ErrInvalidLease)
+	}
+	for _, t := range req.Tenants {
+		if strings.TrimSpace(t.Name) == "" {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: 租户姓名不能为空", ErrInvalidLease)
+		}
+	}
+	if req.FeePayer == "" {
+		req.FeePayer = defaultPayer
+	}
+	return start, end, nil
+}
+
+// CreateLease 登记租约，同一房屋生效中的租约租期不能重叠
+func CreateLease(req LeaseRequest, defaultPayer string, createdBy uint) (int, error) {
+	start, end, err := validateLease(&req, defaultPayer)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := checkLeaseOverlap(tx, req.ResidentID, 0, start, end); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO leases (resident_id, start_date, end_date, monthly_rent, deposit, fee_payer, status, note, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.ResidentID, start.Format("2006-01-02"), end.Format("2006-01-02"), roundAmount(req.MonthlyRent),
+		roundAmount(req.Deposit), req.FeePayer, LeaseActive, req.Note, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := saveLeaseTenants(tx, int(id), req.Tenants); err != nil {
+		return 0, err
+	}
+
+	return int(id), tx.Commit()
+}
+
+// UpdateLease 更新生效中的租约，不能更换房屋
+func UpdateLease(id int, req LeaseRequest, defaultPayer string) error {
+	start, end, err := validateLease(&req, defaultPayer)
+	if err != nil {
+		return err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var residentID int
+	var status string
+	err = tx.QueryRow(`SELECT resident_id, status FROM leases WHERE id = ? FOR UPDATE`, id).Scan(&residentID, &status)
+	if err != nil {
+		return err
+	}
+	if status != LeaseActive {
+		return ErrLeaseNotActive
+	}
+	if residentID != req.ResidentID {
+		return fmt.Errorf("%w: 不能更换租约的房屋", ErrInvalidLease)
+	}
+	if err := checkLeaseOverlap(tx, residentID, id, start, end); err != nil {
+		return err
+	}
+
+	// 结束日期变化后重新发送到期提醒
+	_, err = tx.Exec(`
+		UPDATE leases SET reminded_at = IF(end_date = ?, reminded_at, NULL),
+			start_date = ?, end_date = ?, monthly_rent = ?, deposit = ?, fee_payer = ?, note = ?
+		WHERE id = ?`,
+		end.Format("2006-01-02"), start.Format("2006-01-02"), end.Format("2006-01-02"),
+		roundAmount(req.MonthlyRent), roundAmount(req.Deposit), req.FeePayer, req.Note, id,
+	)
+	if err != nil {
+		return err
+	}
+	if err := saveLeaseTenants(tx, id, req.Tenants); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RenewLease 续租：延长结束日期，可同时调整月租金，并重新发送到期提醒
+func RenewLease(id int, req LeaseRenewRequest) error {
+	end, err := parseLeaseDate(req.EndDate)
+	if err != nil {
+		return err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var residentID int
+	var status string
+	var start, oldEnd time.Time
+	var rent float64
+	err = tx.QueryRow(`SELECT resident_id, status, start_date, end_date, monthly_rent FROM leases WHERE id = ? FOR UPDATE`, id).
+		Scan(&residentID, &status, &start, &oldEnd, &rent)
+	if err != nil {
+		return err
+	}
+	if status != LeaseActive {
+		return ErrLeaseNotActive
+	}
+	if !end.After(oldEnd) {
+		return fmt.Errorf("%w: 续租后的结束日期必须晚于%s", ErrInvalidLease, oldEnd.Format("2006-01-02"))
+	}
+	if err := checkLeaseOverlap(tx, residentID, id, start, end); err != nil {
+		return err
+	}
+	if req.MonthlyRent != nil {
+		rent = roundAmount(*req.MonthlyRent)
+	}
+
+	_, err = tx.Exec(`UPDATE leases SET end_date = ?, monthly_rent = ?, reminded_at = NULL WHERE id = ?`,
+		end.Format("2006-01-02"), rent, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// TerminateLease 提前解约，解约日期不能早于租约开始日期
+func TerminateLease(id int, req LeaseTerminateRequest) error {
+	date, err := parseLeaseDate(req.Date)
+	if err != nil {
+		return err
+	}
+
+	var status string
+	var start time.Time
+	err = database.DB.QueryRow(`SELECT status, start_date FROM leases WHERE id = ?`, id).Scan(&status, &start)
+	if err != nil {
+		return err
+	}
+	if status != LeaseActive {
+		return ErrLeaseNotActive
+	}
+	if date.Before(start) {
+		return fmt.Errorf("%w: 解约日期不能早于租约开始日期", ErrInvalidLease)
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE leases SET status = ?, terminated_at = ?, terminate_reason = ?, end_date = LEAST(end_date, ?)
+		WHERE id = ? AND status = ?`,
+		LeaseTerminated, date.Format("2006-01-02"), req.Reason, date.Format("2006-01-02"), id, LeaseActive,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrLeaseNotActive
+	}
+	return nil
+}
+
+const leaseColumns = `
+	l.id, r.community_id, l.resident_id, r.name, CONCAT(r.block_number, r.unit_number, r.house_number),
+	l.start_date, l.end_date, l.monthly_rent, l.deposit, l.fee_payer, l.status, l.note, l.created_by,
+	l.reminded_at, l.terminated_at, l.terminate_reason, l.created_at, l.updated_at
+	FROM leases l
+	JOIN residents r ON l.resident_id = r.id
+`
+
+// scanLease 解析一行租约数据，today用于计算剩余天数和到期状态
+func scanLease(scanner interface{ Scan(...interface{}) error }, today time.Time) (*Lease, error) {
+	var l Lease
+	var remindedAt, terminatedAt sql.NullTime
+	err := scanner.Scan(&l.ID, &l.CommunityID, &l.ResidentID, &l.OwnerName, &l.Room,
+		&l.StartDate, &l.EndDate, &l.MonthlyRent, &l.Deposit, &l.FeePayer, &l.Status, &l.Note, &l.CreatedBy,
+		&remindedAt, &terminatedAt, &l.TerminateReason, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	l.RemindedAt = nullTimePtr(remindedAt)
+	l.TerminatedAt = nullTimePtr(terminatedAt)
+	if l.Status == LeaseActive {
+		l.DaysLeft = daysBetween(today, l.EndDate)
+		if l.DaysLeft < 0 {
+			l.Status = LeaseExpired
+			l.DaysLeft = 0
+		}
+	}
+	l.Tenants = []LeaseTenant{}
+	return &l, nil
+}
+
+// loadLeaseTenants 加载租约的租户，主租户排在最前
+func loadLeaseTenants(leases []*Lease) error {
+	if len(leases) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Lease, len(leases))
+	placeholders := make([]string, 0, len(leases))
+	args := make([]interface{}, 0, len(leases))
+	for _, l := range leases {
+		byID[l.ID] = l
+		placeholders = append(placeholders, "?")
+		args = append(args, l.ID)
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, lease_id, name, phone, email, id_number, is_primary FROM lease_tenants
+		WHERE lease_id IN (`+strings.Join(placeholders, ",")+`) ORDER BY lease_id, is_primary DESC, id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t LeaseTenant
+		var leaseID int
+		if err := rows.Scan(&t.ID, &leaseID, &t.Name, &t.Phone, &t.Email, &t.IDNumber, &t.IsPrimary); err != nil {
+			return err
+		}
+		if l, ok := byID[leaseID]; ok {
+			l.Tenants = append(l.Tenants, t)
+		}
+	}
+	return rows.Err()
+}
+
+// GetLeases 获取租约列表，支持按房屋、状态（含expired）、缴费方、关键字（租户姓名、电话、业主、房号）查询
+// filters["expiring_days"]为int时只返回在该天数内到期的生效中租约
+func GetLeases(page, pageSize int, filters map[string]interface{}) ([]Lease, int, error) {
+	today := time.Now()
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	switch status, _ := filters["status"].(string); status {
+	case LeaseActive:
+		where += ` AND l.status = ? AND l.end_date >= CURDATE()`
+		args = append(args, LeaseActive)
+	case LeaseExpired:
+		where += ` AND l.status = ? AND l.end_date < CURDATE()`
+		args = append(args, LeaseActive)
+	case LeaseTerminated:
+		where += ` AND l.status = ?`
+		args = append(args, LeaseTerminated)
+	}
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND l.resident_id = ?`
+		args = append(args, residentID)
+	}
+	if payer, ok := filters["fee_payer"].(string); ok && payer != "" {
+		where += ` AND l.fee_payer = ?`
+		args = append(args, payer)
+	}
+	if days, ok := filters["expiring_days"].(int); ok {
+		where += ` AND l.status = ? AND l.end_date BETWEEN CURDATE() AND DATE_ADD(CURDATE(), INTERVAL ? DAY)`
+		args = append(args, LeaseActive, days)
+	}
+	if keyword, ok := filters["keyword"].(string); ok && keyword != "" {
+		where += ` AND (r.name LIKE ? OR CONCAT(r.block_number, r.unit_number, r.house_number) LIKE ?
+			OR EXISTS (SELECT 1 FROM lease_tenants t WHERE t.lease_id = l.id AND (t.name LIKE ? OR t.phone LIKE ?)))`
+		like := "%" + keyword + "%"
+		args = append(args, like, like, like, like)
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM leases l JOIN residents r ON l.resident_id = r.id` + where
+	if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + leaseColumns + where + ` ORDER BY l.status = 'active' DESC, l.end_date, l.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var ptrs []*Lease
+	for rows.Next() {
+		l, err := scanLease(rows, today)
+		if err != nil {
+			return nil, 0, err
+		}
+		ptrs = append(ptrs, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err := loadLeaseTenants(ptrs); err != nil {
+		return nil, 0, err
+	}
+
+	leases := make([]Lease, 0, len(ptrs))
+	for _, l := range ptrs {
+		leases = append(leases, *l)
+	}
+	return leases, total, nil
+}
+
+// GetLeaseByID 通过ID获取租约及租户
+func GetLeaseByID(id int) (*Lease, error) {
+	l, err := scanLease(database.DB.QueryRow(`SELECT `+leaseColumns+` WHERE l.id = ?`, id), time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := loadLeaseTenants([]*Lease{l}); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// GetUnremindedExpiringLeases 获取在before之前（含）到期、尚未到期且未发送到期提醒的生效中租约
+func GetUnremindedExpiringLeases(today, before time.Time) ([]Lease, error) {
+	rows, err := database.DB.Query(`SELECT `+leaseColumns+`
+		WHERE l.status = ? AND l.reminded_at IS NULL AND l.end_date >= ? AND l.end_date <= ?
+		ORDER BY l.end_date, l.id`,
+		LeaseActive, today.Format("2006-01-02"), before.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ptrs []*Lease
+	for rows.Next() {
+		l, err := scanLease(rows, today)
+		if err != nil {
+			return nil, err
+		}
+		ptrs = append(ptrs, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := loadLeaseTenants(ptrs); err != nil {
+		return nil, err
+	}
+
+	leases := make([]Lease, 0, len(ptrs))
+	for _, l := range ptrs {
+		leases = append(leases, *l)
+	}
+	return leases, nil
+}
+
+// MarkLeaseReminded 记录租约到期提醒的发送时间
+func MarkLeaseReminded(id int) error {
+	_, err := database.DB.Exec(`UPDATE leases SET reminded_at = NOW() WHERE id = ?`, id)
+	return err
+}
+
+// GetBillPayer 获取房屋在date当天的物业费缴纳人：
+// 当天有生效租约且约定由租户缴费时为主租户，否则为业主
+func GetBillPayer(residentID int, date time.Time) (*BillPayer, error) {
+	var payer BillPayer
+	err := database.DB.QueryRow(`
+		SELECT l.id, t.name, t.phone, t.email
+		FROM leases l
+		JOIN lease_tenants t ON t.lease_id = l.id AND t.is_primary = 1
+		WHERE l.resident_id = ? AND l.status = ? AND l.fee_payer = ? AND l.start_date <= ? AND l.end_date >= ?
+		ORDER BY l.start_date DESC LIMIT 1`,
+		residentID, LeaseActive, FeePayerTenant, date.Format("2006-01-02"), date.Format("2006-01-02"),
+	).Scan(&payer.LeaseID, &payer.Name, &payer.Phone, &payer.Email)
+	if err == nil {
+		payer.Payer = FeePayerTenant
+		return &payer, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	resident, err := GetResidentByID(residentID)
+	if err != nil || resident == nil {
+		return nil, err
+	}
+	return &BillPayer{
+		Payer: FeePayerOwner,
+		Name:  resident.Name,
+		Phone: resident.Phone,
+		Email: resident.Email,
+	}, nil
+}
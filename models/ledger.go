@@ -0,0 +1,871 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"community-backward/database"
+)
+
+// 会计科目类型
+const (
+	AccountAsset     = "asset"
+	AccountLiability = "liability"
+	AccountEquity    = "equity"
+	AccountIncome    = "income"
+	AccountExpense   = "expense"
+)
+
+// 凭证来源
+const (
+	JournalSourceBill       = "bill"
+	JournalSourcePayment    = "payment"
+	JournalSourceRefund     = "refund"
+	JournalSourceAdjustment = "adjustment"
+	// 删除账单时冲销账单及其减免的凭证
+	JournalSourceBillVoid       = "bill_void"
+	JournalSourceAdjustmentVoid = "adjustment_void"
+	// 删除住户时冲销随住户级联删除的收款和退款的凭证
+	JournalSourcePaymentVoid = "payment_void"
+	JournalSourceRefundVoid  = "refund_void"
+	// 设施预约的场地使用费在预约时确认收入，取消退款时冲回
+	JournalSourceBooking       = "booking"
+	JournalSourceBookingCancel = "booking_cancel"
+	// 装修押金收取时转入其他应付款，退还时冲回，没收时转入其他业务收入
+	JournalSourceDeposit        = "deposit"
+	JournalSourceDepositRefund  = "deposit_refund"
+	JournalSourceDepositForfeit = "deposit_forfeit"
+)
+
+// 常用科目
+const (
+	AccountCash          = "1001"    // 库存现金
+	AccountBank          = "1002"    // 银行存款
+	AccountOtherMonetary = "1012"    // 其他货币资金（微信、支付宝）
+	AccountReceivable    = "1122"    // 应收账款
+	AccountDepositIncome = "6051.03" // 押金没收收入
+)
+
+// ErrUnbalancedEntry 凭证借贷不平衡
+var ErrUnbalancedEntry = errors.New("凭证借贷不平衡")
+
+// GLAccount 表示会计科目
+type GLAccount struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	ParentCode string `json:"parent_code,omitempty"`
+	Enabled    bool   `json:"enabled"`
+}
+
+// JournalLine 表示凭证分录
+type JournalLine struct {
+	ID          int     `json:"id,omitempty"`
+	EntryID     int     `json:"entry_id,omitempty"`
+	AccountCode string  `json:"account_code"`
+	AccountName string  `json:"account_name,omitempty"`
+	ResidentID  int     `json:"resident_id,omitempty"`
+	Debit       float64 `json:"debit"`
+	Credit      float64 `json:"credit"`
+	Memo        string  `json:"memo"`
+}
+
+// JournalEntry 表示记账凭证
+type JournalEntry struct {
+	ID          int           `json:"id"`
+	CommunityID int           `json:"community_id"` // 凭证所属社区，为0时按分录住户所属社区确定
+	VoucherNo   int           `json:"voucher_no"`   // 凭证号，按社区和记账月份连续编号
+	EntryDate   time.Time     `json:"entry_date"`
+	SourceType  string        `json:"source_type"`
+	SourceID    int           `json:"source_id"`
+	Memo        string        `json:"memo"`
+	Lines       []JournalLine `json:"lines"`
+}
+
+// TrialBalanceRow 表示科目余额表的一行
+type TrialBalanceRow struct {
+	Code          string  `json:"code"`
+	Name          string  `json:"name"`
+	Type          string  `json:"type"`
+	OpeningDebit  float64 `json:"openingDebit"`
+	OpeningCredit float64 `json:"openingCredit"`
+	PeriodDebit   float64 `json:"periodDebit"`
+	PeriodCredit  float64 `json:"periodCredit"`
+	ClosingDebit  float64 `json:"closingDebit"`
+	ClosingCredit float64 `json:"closingCredit"`
+}
+
+// TrialBalance 表示科目余额表（试算平衡表）
+type TrialBalance struct {
+	Start    string            `json:"start"`
+	End      string            `json:"end"`
+	Rows     []TrialBalanceRow `json:"rows"`
+	Total    TrialBalanceRow   `json:"total"`
+	Balanced bool              `json:"balanced"`
+}
+
+// StatementLine 表示科目明细账的一行
+type StatementLine struct {
+	EntryID    int       `json:"entry_id"`
+	EntryDate  time.Time `json:"entry_date"`
+	SourceType string    `json:"source_type"`
+	SourceID   int       `json:"source_id"`
+	ResidentID int       `json:"resident_id,omitempty"`
+	Memo       string    `json:"memo"`
+	Debit      float64   `json:"debit"`
+	Credit     float64   `json:"credit"`
+	Balance    float64   `json:"balance"` // 按科目余额方向计算的余额
+}
+
+// AccountStatement 表示科目明细账
+type AccountStatement struct {
+	Account        GLAccount       `json:"account"`
+	Start          string          `json:"start"`
+	End            string          `json:"end"`
+	OpeningBalance float64         `json:"openingBalance"`
+	ClosingBalance float64         `json:"closingBalance"`
+	Lines          []StatementLine `json:"lines"`
+}
+
+// debitNormal 判断科目余额方向是否在借方
+func debitNormal(accountType string) bool {
+	return accountType == AccountAsset || accountType == AccountExpense
+}
+
+// GetGLAccounts 获取会计科目表
+func GetGLAccounts() ([]GLAccount, error) {
+	rows, err := database.DB.Query(`SELECT code, name, type, parent_code, enabled FROM gl_accounts ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []GLAccount
+	for rows.Next() {
+		var a GLAccount
+		var parent sql.NullString
+		if err := rows.Scan(&a.Code, &a.Name, &a.Type, &parent, &a.Enabled); err != nil {
+			return nil, err
+		}
+		a.ParentCode = parent.String
+		accounts = append(accounts, a)
+	}
+
+	return accounts, rows.Err()
+}
+
+// GetGLAccount 通过科目代码获取会计科目
+func GetGLAccount(code string) (*GLAccount, error) {
+	var a GLAccount
+	var parent sql.NullString
+	err := database.DB.QueryRow(`SELECT code, name, type, parent_code, enabled FROM gl_accounts WHERE code = ?`, code).
+		Scan(&a.Code, &a.Name, &a.Type, &parent, &a.Enabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	a.ParentCode = parent.String
+	return &a, nil
+}
+
+// PostJournal 保存记账凭证，同一来源单据已记账时直接返回
+func PostJournal(entry JournalEntry) error {
+	return postInTx(func(tx *sql.Tx) error {
+		return postJournalTx(tx, entry)
+	})
+}
+
+// postInTx 在单独的事务中记账，供补记凭证使用；业务单据应在自身的事务中调用postXxxTx
+func postInTx(post func(tx *sql.Tx) error) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := post(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// postJournalTx 在事务中保存记账凭证，同一来源单据已记账时直接返回
+// 未指定社区时凭证归入分录住户所属社区，新凭证按社区和记账日期所在月份分配凭证号
+func postJournalTx(tx *sql.Tx, entry JournalEntry) error {
+	var debit, credit float64
+	for _, l := range entry.Lines {
+		debit += l.Debit
+		credit += l.Credit
+	}
+	if math.Abs(debit-credit) > 0.005 || debit == 0 {
+		return fmt.Errorf("%w: 借方%.2f，贷方%.2f", ErrUnbalancedEntry, debit, credit)
+	}
+
+	if entry.CommunityID == 0 {
+		for _, l := range entry.Lines {
+			if l.ResidentID > 0 {
+				communityID, err := residentCommunityTx(tx, l.ResidentID)
+				if err != nil {
+					return err
+				}
+				entry.CommunityID = communityID
+				break
+			}
+		}
+		if entry.CommunityID == 0 {
+			return fmt.Errorf("凭证%s未关联社区", entry.Memo)
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT IGNORE INTO journal_entries (community_id, entry_date, source_type, source_id, memo)
+		VALUES (?, ?, ?, ?, ?)`,
+		entry.CommunityID, entry.EntryDate, entry.SourceType, entry.SourceID, entry.Memo,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil // 已记账
+	}
+
+	entryID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	voucherNo, err := nextVoucherNo(tx, entry.CommunityID, entry.EntryDate)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE journal_entries SET voucher_no = ? WHERE id = ?`, voucherNo, entryID); err != nil {
+		return err
+	}
+
+	for _, l := range entry.Lines {
+		var residentID sql.NullInt64
+		if l.ResidentID > 0 {
+			residentID = sql.NullInt64{Int64: int64(l.ResidentID), Valid: true}
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO journal_lines (entry_id, account_code, resident_id, debit, credit, memo)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			entryID, l.AccountCode, residentID, roundAmount(l.Debit), roundAmount(l.Credit), l.Memo,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nextVoucherNo 在事务中分配指定社区记账日期所在月份的下一个凭证号
+// 序号行在事务提交前保持锁定，回滚的凭证不占用凭证号
+func nextVoucherNo(tx *sql.Tx, communityID int, entryDate time.Time) (int, error) {
+	if _, err := tx.Exec(`
+		INSERT INTO voucher_sequences (community_id, year, month, last_no) VALUES (?, ?, ?, LAST_INSERT_ID(1))
+		ON DUPLICATE KEY UPDATE last_no = LAST_INSERT_ID(last_no + 1)`,
+		communityID, entryDate.Year(), int(entryDate.Month()),
+	); err != nil {
+		return 0, err
+	}
+
+	var no int
+	if err := tx.QueryRow(`SELECT LAST_INSERT_ID()`).Scan(&no); err != nil {
+		return 0, err
+	}
+	return no, nil
+}
+
+// reverseJournal 在事务中为来源单据的凭证生成借贷方向相反的冲销凭证，来源单据未记账时不做处理
+// 冲销凭证与原凭证属于同一社区
+func reverseJournal(tx *sql.Tx, sourceType string, sourceID int, reverseType string, date time.Time) error {
+	var communityID int
+	var memo string
+	err := tx.QueryRow(`SELECT community_id, memo FROM journal_entries WHERE source_type = ? AND source_id = ?`,
+		sourceType, sourceID).Scan(&communityID, &memo)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT l.account_code, COALESCE(l.resident_id, 0), l.debit, l.credit, l.memo
+		FROM journal_lines l
+		JOIN journal_entries j ON l.entry_id = j.id
+		WHERE j.source_type = ? AND j.source_id = ?
+		ORDER BY l.id`, sourceType, sourceID)
+	if err != nil {
+		return err
+	}
+	reversal := JournalEntry{
+		CommunityID: communityID,
+		EntryDate:   date,
+		SourceType:  reverseType,
+		SourceID:    sourceID,
+		Memo:        "冲销" + memo,
+	}
+	for rows.Next() {
+		var l JournalLine
+		if err := rows.Scan(&l.AccountCode, &l.ResidentID, &l.Credit, &l.Debit, &l.Memo); err != nil {
+			rows.Close()
+			return err
+		}
+		l.Memo = "冲销" + l.Memo
+		reversal.Lines = append(reversal.Lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return postJournalTx(tx, reversal)
+}
+
+// PaymentAccount 根据缴费方式返回收款科目
+func PaymentAccount(method string) string {
+	switch method {
+	case "现金":
+		return AccountCash
+	case "银行转账":
+		return AccountBank
+	}
+	return AccountOtherMonetary
+}
+
+// PostBill 账单记账：借 应收账款，贷 收费项目对应科目
+func PostBill(billID int) error {
+	return postInTx(func(tx *sql.Tx) error { return postBillTx(tx, billID) })
+}
+
+// postBillTx 在事务中为账单记账
+func postBillTx(tx *sql.Tx, billID int) error {
+	var residentID, year, month int
+	var amount float64
+	var accountCode, feeName string
+	err := tx.QueryRow(`
+		SELECT b.resident_id, b.year, b.month, b.amount, t.account_code, t.name
+		FROM bills b JOIN fee_types t ON b.fee_type_id = t.id
+		WHERE b.id = ?`, billID).Scan(&residentID, &year, &month, &amount, &accountCode, &feeName)
+	if err != nil {
+		return fmt.Errorf("获取账单%d失败: %w", billID, err)
+	}
+
+	// 收入按账单所属月份确认，记账日期为该月最后一天
+	memo := fmt.Sprintf("应收%d年%d月%s", year, month, feeName)
+	return postJournalTx(tx, JournalEntry{
+		EntryDate:  time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.Local),
+		SourceType: JournalSourceBill,
+		SourceID:   billID,
+		Memo:       memo,
+		Lines: []JournalLine{
+			{AccountCode: AccountReceivable, ResidentID: residentID, Debit: amount, Memo: memo},
+			{AccountCode: accountCode, ResidentID: residentID, Credit: amount, Memo: memo},
+		},
+	})
+}
+
+// PostPayment 收款记账：借 现金/银行存款/其他货币资金，贷 应收账款
+func PostPayment(feeID int) error {
+	return postInTx(func(tx *sql.Tx) error { return postPaymentTx(tx, feeID) })
+}
+
+// postPaymentTx 在事务中为收款记账
+func postPaymentTx(tx *sql.Tx, feeID int) error {
+	fee, err := getPropertyFeeByID(tx, feeID)
+	if err != nil {
+		return err
+	}
+	if fee == nil {
+		return fmt.Errorf("缴费记录不存在: %d", feeID)
+	}
+
+	memo := fmt.Sprintf("收%s%s", fee.ResidentName, fee.FeeTypeName)
+	return postJournalTx(tx, JournalEntry{
+		EntryDate:  fee.PaymentDate,
+		SourceType: JournalSourcePayment,
+		SourceID:   feeID,
+		Memo:       memo,
+		Lines: []JournalLine{
+			{AccountCode: PaymentAccount(fee.PaymentMethod), ResidentID: fee.ResidentID, Debit: fee.Amount, Memo: memo},
+			{AccountCode: AccountReceivable, ResidentID: fee.ResidentID, Credit: fee.Amount, Memo: memo},
+		},
+	})
+}
+
+// PostRefund 退款记账：借 应收账款，贷 现金/银行存款/其他货币资金
+func PostRefund(feeID int, refundDate time.Time) error {
+	return postInTx(func(tx *sql.Tx) error { return postRefundTx(tx, feeID, refundDate) })
+}
+
+// postRefundTx 在事务中为退款记账
+func postRefundTx(tx *sql.Tx, feeID int, refundDate time.Time) error {
+	fee, err := getPropertyFeeByID(tx, feeID)
+	if err != nil {
+		return err
+	}
+	if fee == nil {
+		return fmt.Errorf("缴费记录不存在: %d", feeID)
+	}
+
+	memo := fmt.Sprintf("退%s%s", fee.ResidentName, fee.FeeTypeName)
+	return postJournalTx(tx, JournalEntry{
+		EntryDate:  refundDate,
+		SourceType: JournalSourceRefund,
+		SourceID:   feeID,
+		Memo:       memo,
+		Lines: []JournalLine{
+			{AccountCode: AccountReceivable, ResidentID: fee.ResidentID, Debit: fee.Amount, Memo: memo},
+			{AccountCode: PaymentAccount(fee.PaymentMethod), ResidentID: fee.ResidentID, Credit: fee.Amount, Memo: memo},
+		},
+	})
+}
+
+// PostAdjustment 减免记账：借 收费项目对应科目（冲减），贷 应收账款
+func PostAdjustment(adjustmentID int) error {
+	return postInTx(func(tx *sql.Tx) error { return postAdjustmentTx(tx, adjustmentID) })
+}
+
+// postAdjustmentTx 在事务中为已审批的减免记账
+func postAdjustmentTx(tx *sql.Tx, adjustmentID int) error {
+	var residentID int
+	var amount float64
+	var reviewedAt time.Time
+	var adjType, accountCode, feeName string
+	err := tx.QueryRow(`
+		SELECT a.resident_id, a.amount, a.reviewed_at, a.type, t.account_code, t.name
+		FROM fee_adjustments a
+		JOIN bills b ON a.bill_id = b.id
+		JOIN fee_types t ON b.fee_type_id = t.id
+		WHERE a.id = ? AND a.status = ?`, adjustmentID, AdjustmentApproved).
+		Scan(&residentID, &amount, &reviewedAt, &adjType, &accountCode, &feeName)
+	if err != nil {
+		return fmt.Errorf("获取已审批的费用调整%d失败: %w", adjustmentID, err)
+	}
+
+	memo := feeName + "减免"
+	if adjType == AdjustmentDiscount {
+		memo = feeName + "优惠"
+	}
+	return postJournalTx(tx, JournalEntry{
+		EntryDate:  reviewedAt,
+		SourceType: JournalSourceAdjustment,
+		SourceID:   adjustmentID,
+		Memo:       memo,
+		Lines: []JournalLine{
+			{AccountCode: accountCode, ResidentID: residentID, Debit: amount, Memo: memo},
+			{AccountCode: AccountReceivable, ResidentID: residentID, Credit: amount, Memo: memo},
+		},
+	})
+}
+
+// PostBooking 设施预约收入记账：借 应收账款，贷 场地使用费对应科目
+// 收款记录冲减应收账款，免费预约不记账
+func PostBooking(bookingID int) error {
+	return postInTx(func(tx *sql.Tx) error { return postBookingEntry(tx, bookingID, false) })
+}
+
+// PostBookingCancel 取消退款的设施预约冲回收入：借 场地使用费对应科目，贷 应收账款
+func PostBookingCancel(bookingID int) error {
+	return postInTx(func(tx *sql.Tx) error { return postBookingEntry(tx, bookingID, true) })
+}
+
+// postBookingEntry 在事务中生成设施预约的收入凭证或冲回凭证
+func postBookingEntry(tx *sql.Tx, bookingID int, cancel bool) error {
+	var residentID int
+	var amount float64
+	var paymentDate time.Time
+	var cancelledAt sql.NullTime
+	var accountCode, facilityName string
+	err := tx.QueryRow(`
+		SELECT b.resident_id, b.amount, pf.payment_date, b.cancelled_at, t.account_code, f.name
+		FROM facility_bookings b
+		JOIN facilities f ON b.facility_id = f.id
+		JOIN property_fees pf ON b.fee_id = pf.id
+		JOIN fee_types t ON pf.fee_type_id = t.id
+		WHERE b.id = ?`, bookingID).
+		Scan(&residentID, &amount, &paymentDate, &cancelledAt, &accountCode, &facilityName)
+	if err != nil {
+		return fmt.Errorf("获取设施预约%d失败: %w", bookingID, err)
+	}
+
+	entry := JournalEntry{
+		EntryDate:  paymentDate,
+		SourceType: JournalSourceBooking,
+		SourceID:   bookingID,
+		Memo:       facilityName + "场地使用费",
+		Lines: []JournalLine{
+			{AccountCode: AccountReceivable, ResidentID: residentID, Debit: amount},
+			{AccountCode: accountCode, ResidentID: residentID, Credit: amount},
+		},
+	}
+	if cancel {
+		if !cancelledAt.Valid {
+			return fmt.Errorf("设施预约%d未取消", bookingID)
+		}
+		entry.EntryDate = cancelledAt.Time
+		entry.SourceType = JournalSourceBookingCancel
+		entry.Memo = facilityName + "场地使用费退订"
+		entry.Lines[0].Debit, entry.Lines[0].Credit = 0, amount
+		entry.Lines[1].Debit, entry.Lines[1].Credit = amount, 0
+	}
+	for i := range entry.Lines {
+		entry.Lines[i].Memo = entry.Memo
+	}
+
+	return postJournalTx(tx, entry)
+}
+
+// PostDeposit 装修押金记账：借 应收账款，贷 押金对应科目
+// 收款记录冲减应收账款，押金在结案前作为负债挂账
+func PostDeposit(applicationID int) error {
+	return postInTx(func(tx *sql.Tx) error { return postDepositEntry(tx, applicationID, JournalSourceDeposit) })
+}
+
+// PostDepositRefund 退还装修押金冲回负债：借 押金对应科目，贷 应收账款
+func PostDepositRefund(applicationID int) error {
+	return postInTx(func(tx *sql.Tx) error { return postDepositEntry(tx, applicationID, JournalSourceDepositRefund) })
+}
+
+// PostDepositForfeit 没收装修押金：借 押金对应科目，贷 押金没收收入
+func PostDepositForfeit(applicationID int) error {
+	return postInTx(func(tx *sql.Tx) error { return postDepositEntry(tx, applicationID, JournalSourceDepositForfeit) })
+}
+
+// postDepositEntry 在事务中生成装修押金的挂账、退还或没收凭证
+func postDepositEntry(tx *sql.Tx, applicationID int, source string) error {
+	var residentID int
+	var amount float64
+	var paymentDate time.Time
+	var refundedAt, forfeitedAt sql.NullTime
+	var accountCode, room string
+	err := tx.QueryRow(`
+		SELECT a.resident_id, pf.amount, pf.payment_date, pf.refunded_at, a.forfeited_at, t.account_code,
+		       CONCAT(r.block_number, r.unit_number, r.house_number)
+		FROM renovation_applications a
+		JOIN residents r ON a.resident_id = r.id
+		JOIN property_fees pf ON a.deposit_fee_id = pf.id
+		JOIN fee_types t ON pf.fee_type_id = t.id
+		WHERE a.id = ?`, applicationID).
+		Scan(&residentID, &amount, &paymentDate, &refundedAt, &forfeitedAt, &accountCode, &room)
+	if err != nil {
+		return fmt.Errorf("获取装修申请%d失败: %w", applicationID, err)
+	}
+
+	entry := JournalEntry{
+		EntryDate:  paymentDate,
+		SourceType: source,
+		SourceID:   applicationID,
+		Memo:       room + "装修押金",
+		Lines: []JournalLine{
+			{AccountCode: AccountReceivable, ResidentID: residentID, Debit: amount},
+			{AccountCode: accountCode, ResidentID: residentID, Credit: amount},
+		},
+	}
+	switch source {
+	case JournalSourceDepositRefund:
+		if !refundedAt.Valid {
+			return fmt.Errorf("装修申请%d的押金未退还", applicationID)
+		}
+		entry.EntryDate = refundedAt.Time
+		entry.Memo = room + "装修押金退还"
+		entry.Lines = []JournalLine{
+			{AccountCode: accountCode, ResidentID: residentID, Debit: amount},
+			{AccountCode: AccountReceivable, ResidentID: residentID, Credit: amount},
+		}
+	case JournalSourceDepositForfeit:
+		if !forfeitedAt.Valid {
+			return fmt.Errorf("装修申请%d的押金未没收", applicationID)
+		}
+		entry.EntryDate = forfeitedAt.Time
+		entry.Memo = room + "装修押金没收"
+		entry.Lines = []JournalLine{
+			{AccountCode: accountCode, ResidentID: residentID, Debit: amount},
+			{AccountCode: AccountDepositIncome, ResidentID: residentID, Credit: amount},
+		}
+	}
+	for i := range entry.Lines {
+		entry.Lines[i].Memo = entry.Memo
+	}
+
+	return postJournalTx(tx, entry)
+}
+
+// SyncJournal 为指定社区尚未记账的账单、收款、退款、已审批减免、收费的设施预约和装修押金补记凭证，返回补记的凭证数
+func SyncJournal(communityID int) (int, error) {
+	type pending struct {
+		query string
+		post  func(id int, date time.Time) error
+	}
+	sources := []pending{
+		{
+			query: `SELECT b.id, b.created_at FROM bills b
+				LEFT JOIN journal_entries j ON j.source_type = 'bill' AND j.source_id = b.id
+				WHERE j.id IS NULL
+				  AND b.resident_id IN (SELECT id FROM residents WHERE community_id = ?)`,
+			post: func(id int, _ time.Time) error { return PostBill(id) },
+		},
+		{
+			query: `SELECT pf.id, pf.payment_date FROM property_fees pf
+				LEFT JOIN journal_entries j ON j.source_type = 'payment' AND j.source_id = pf.id
+				WHERE j.id IS NULL AND pf.payment_status IN (1, 2)
+				  AND pf.resident_id IN (SELECT id FROM residents WHERE community_id = ?)`,
+			post: func(id int, _ time.Time) error { return PostPayment(id) },
+		},
+		{
+			query: `SELECT pf.id, pf.refunded_at FROM property_fees pf
+				LEFT JOIN journal_entries j ON j.source_type = 'refund' AND j.source_id = pf.id
+				WHERE j.id IS NULL AND pf.payment_status = 2 AND pf.refunded_at IS NOT NULL
+				  AND pf.resident_id IN (SELECT id FROM residents WHERE community_id = ?)`,
+			post: PostRefund,
+		},
+		{
+			query: `SELECT a.id, a.reviewed_at FROM fee_adjustments a
+				LEFT JOIN journal_entries j ON j.source_type = 'adjustment' AND j.source_id = a.id
+				WHERE j.id IS NULL AND a.status = 'approved'
+				  AND a.resident_id IN (SELECT id FROM residents WHERE community_id = ?)`,
+			post: func(id int, _ time.Time) error { return PostAdjustment(id) },
+		},
+		{
+			query: `SELECT b.id, b.created_at FROM facility_bookings b
+				LEFT JOIN journal_entries j ON j.source_type = 'booking' AND j.source_id = b.id
+				WHERE j.id IS NULL AND b.fee_id IS NOT NULL
+				  AND b.resident_id IN (SELECT id FROM residents WHERE community_id = ?)`,
+			post: func(id int, _ time.Time) error { return PostBooking(id) },
+		},
+		{
+			query: `SELECT b.id, b.cancelled_at FROM facility_bookings b
+				LEFT JOIN journal_entries j ON j.source_type = 'booking_cancel' AND j.source_id = b.id
+				WHERE j.id IS NULL AND b.fee_id IS NOT NULL AND b.refunded = 1
+				  AND b.resident_id IN (SELECT id FROM residents WHERE community_id = ?)`,
+			post: func(id int, _ time.Time) error { return PostBookingCancel(id) },
+		},
+		{
+			query: `SELECT a.id, a.created_at FROM renovation_applications a
+				LEFT JOIN journal_entries j ON j.source_type = 'deposit' AND j.source_id = a.id
+				WHERE j.id IS NULL AND a.deposit_fee_id IS NOT NULL
+				  AND a.resident_id IN (SELECT id FROM residents WHERE community_id = ?)`,
+			post: func(id int, _ time.Time) error { return PostDeposit(id) },
+		},
+		{
+			query: `SELECT a.id, a.created_at FROM renovation_applications a
+				LEFT JOIN journal_entries j ON j.source_type = 'deposit_refund' AND j.source_id = a.id
+				WHERE j.id IS NULL AND a.deposit_status = 'refunded'
+				  AND a.resident_id IN (SELECT id FROM residents WHERE community_id = ?)`,
+			post: func(id int, _ time.Time) error { return PostDepositRefund(id) },
+		},
+		{
+			query: `SELECT a.id, a.created_at FROM renovation_applications a
+				LEFT JOIN journal_entries j ON j.source_type = 'deposit_forfeit' AND j.source_id = a.id
+				WHERE j.id IS NULL AND a.deposit_status = 'forfeited'
+				  AND a.resident_id IN (SELECT id FROM residents WHERE community_id = ?)`,
+			post: func(id int, _ time.Time) error { return PostDepositForfeit(id) },
+		},
+	}
+
+	posted := 0
+	for _, src := range sources {
+		rows, err := database.DB.Query(src.query, communityID)
+		if err != nil {
+			return posted, err
+		}
+		type item struct {
+			id   int
+			date time.Time
+		}
+		var items []item
+		for rows.Next() {
+			var it item
+			if err := rows.Scan(&it.id, &it.date); err != nil {
+				rows.Close()
+				return posted, err
+			}
+			items = append(items, it)
+		}
+		rows.Close()
+
+		for _, it := range items {
+			if err := src.post(it.id, it.date); err != nil {
+				return posted, err
+			}
+			posted++
+		}
+	}
+
+	return posted, nil
+}
+
+// GetJournalEntries 获取指定社区指定日期范围内的凭证及分录
+func GetJournalEntries(communityID int, start, end time.Time) ([]JournalEntry, error) {
+	rows, err := database.DB.Query(`
+		SELECT e.id, e.community_id, COALESCE(e.voucher_no, 0), e.entry_date, e.source_type, e.source_id, e.memo,
+		       l.id, l.account_code, a.name, COALESCE(l.resident_id, 0), l.debit, l.credit, l.memo
+		FROM journal_entries e
+		JOIN journal_lines l ON l.entry_id = e.id
+		JOIN gl_accounts a ON l.account_code = a.code
+		WHERE e.community_id = ? AND e.entry_date BETWEEN ? AND ?
+		ORDER BY e.entry_date, e.voucher_no, e.id, l.id`, communityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []JournalEntry
+	for rows.Next() {
+		var e JournalEntry
+		var l JournalLine
+		if err := rows.Scan(&e.ID, &e.CommunityID, &e.VoucherNo, &e.EntryDate, &e.SourceType, &e.SourceID, &e.Memo,
+			&l.ID, &l.AccountCode, &l.AccountName, &l.ResidentID, &l.Debit, &l.Credit, &l.Memo); err != nil {
+			return nil, err
+		}
+		l.EntryID = e.ID
+		if n := len(entries); n > 0 && entries[n-1].ID == e.ID {
+			entries[n-1].Lines = append(entries[n-1].Lines, l)
+			continue
+		}
+		e.Lines = []JournalLine{l}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetTrialBalance 获取指定社区指定日期范围的科目余额表
+func GetTrialBalance(communityID int, start, end time.Time) (*TrialBalance, error) {
+	rows, err := database.DB.Query(`
+		SELECT a.code, a.name, a.type,
+		       COALESCE(SUM(CASE WHEN e.entry_date < ? THEN l.debit END), 0),
+		       COALESCE(SUM(CASE WHEN e.entry_date < ? THEN l.credit END), 0),
+		       COALESCE(SUM(CASE WHEN e.entry_date BETWEEN ? AND ? THEN l.debit END), 0),
+		       COALESCE(SUM(CASE WHEN e.entry_date BETWEEN ? AND ? THEN l.credit END), 0)
+		FROM gl_accounts a
+		LEFT JOIN journal_lines l ON l.account_code = a.code
+		LEFT JOIN journal_entries e ON l.entry_id = e.id AND e.community_id = ? AND e.entry_date <= ?
+		GROUP BY a.code, a.name, a.type
+		ORDER BY a.code`, start, start, start, end, start, end, communityID, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tb := &TrialBalance{Start: start.Format("2006-01-02"), End: end.Format("2006-01-02"), Rows: []TrialBalanceRow{}}
+	for rows.Next() {
+		var r TrialBalanceRow
+		var openDebit, openCredit float64
+		if err := rows.Scan(&r.Code, &r.Name, &r.Type, &openDebit, &openCredit, &r.PeriodDebit, &r.PeriodCredit); err != nil {
+			return nil, err
+		}
+		if openDebit == 0 && openCredit == 0 && r.PeriodDebit == 0 && r.PeriodCredit == 0 {
+			continue
+		}
+
+		// 期初和期末余额按净额列示在借方或贷方
+		r.OpeningDebit, r.OpeningCredit = netBalance(openDebit, openCredit)
+		r.ClosingDebit, r.ClosingCredit = netBalance(openDebit+r.PeriodDebit, openCredit+r.PeriodCredit)
+
+		tb.Total.OpeningDebit += r.OpeningDebit
+		tb.Total.OpeningCredit += r.OpeningCredit
+		tb.Total.PeriodDebit += r.PeriodDebit
+		tb.Total.PeriodCredit += r.PeriodCredit
+		tb.Total.ClosingDebit += r.ClosingDebit
+		tb.Total.ClosingCredit += r.ClosingCredit
+		tb.Rows = append(tb.Rows, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	t := &tb.Total
+	t.Name = "合计"
+	t.OpeningDebit, t.OpeningCredit = roundAmount(t.OpeningDebit), roundAmount(t.OpeningCredit)
+	t.PeriodDebit, t.PeriodCredit = roundAmount(t.PeriodDebit), roundAmount(t.PeriodCredit)
+	t.ClosingDebit, t.ClosingCredit = roundAmount(t.ClosingDebit), roundAmount(t.ClosingCredit)
+	tb.Balanced = t.OpeningDebit == t.OpeningCredit && t.PeriodDebit == t.PeriodCredit && t.ClosingDebit == t.ClosingCredit
+
+	return tb, nil
+}
+
+// netBalance 将借贷发生额轧差为借方或贷方余额
+func netBalance(debit, credit float64) (float64, float64) {
+	net := roundAmount(debit - credit)
+	if net >= 0 {
+		return net, 0
+	}
+	return 0, -net
+}
+
+// GetAccountStatement 获取指定社区的科目明细账，residentID大于0时只统计该住户的分录
+func GetAccountStatement(communityID int, code string, start, end time.Time, residentID int) (*AccountStatement, error) {
+	account, err := GetGLAccount(code)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, nil
+	}
+
+	sign := 1.0
+	if !debitNormal(account.Type) {
+		sign = -1
+	}
+
+	residentFilter := ``
+	args := []interface{}{code, communityID, start}
+	if residentID > 0 {
+		residentFilter = ` AND l.resident_id = ?`
+		args = append(args, residentID)
+	}
+
+	var openDebit, openCredit float64
+	err = database.DB.QueryRow(`
+		SELECT COALESCE(SUM(l.debit), 0), COALESCE(SUM(l.credit), 0)
+		FROM journal_lines l JOIN journal_entries e ON l.entry_id = e.id
+		WHERE l.account_code = ? AND e.community_id = ? AND e.entry_date < ?`+residentFilter, args...).Scan(&openDebit, &openCredit)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &AccountStatement{
+		Account:        *account,
+		Start:          start.Format("2006-01-02"),
+		End:            end.Format("2006-01-02"),
+		OpeningBalance: roundAmount(sign * (openDebit - openCredit)),
+		Lines:          []StatementLine{},
+	}
+
+	args = []interface{}{code, communityID, start, end}
+	if residentID > 0 {
+		args = append(args, residentID)
+	}
+	rows, err := database.DB.Query(`
+		SELECT e.id, e.entry_date, e.source_type, e.source_id, COALESCE(l.resident_id, 0), l.memo, l.debit, l.credit
+		FROM journal_lines l JOIN journal_entries e ON l.entry_id = e.id
+		WHERE l.account_code = ? AND e.community_id = ? AND e.entry_date BETWEEN ? AND ?`+residentFilter+`
+		ORDER BY e.entry_date, e.id, l.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balance := statement.OpeningBalance
+	for rows.Next() {
+		var l StatementLine
+		if err := rows.Scan(&l.EntryID, &l.EntryDate, &l.SourceType, &l.SourceID, &l.ResidentID, &l.Memo, &l.Debit, &l.Credit); err != nil {
+			return nil, err
+		}
+		balance = roundAmount(balance + sign*(l.Debit-l.Credit))
+		l.Balance = balance
+		statement.Lines = append(statement.Lines, l)
+	}
+	statement.ClosingBalance = balance
+
+	return statement, rows.Err()
+}
@@ -0,0 +1,614 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"community-backward/database"
+)
+
+// 抄表记录状态
+const (
+	ReadingNormal   = "normal"   // 正常
+	ReadingRollover = "rollover" // 表盘翻转
+	ReadingAbnormal = "abnormal" // 用量异常突增，已人工确认
+)
+
+// 用量异常判断参数：本期用量超过近3期平均用量的AbnormalJumpFactor倍，且差值大于AbnormalJumpMin
+const (
+	AbnormalJumpFactor = 3.0
+	AbnormalJumpMin    = 10.0
+)
+
+var (
+	// ErrReadingRollback 读数小于上期读数
+	ErrReadingRollback = errors.New("读数小于上期读数")
+	// ErrReadingAbnormal 用量异常突增，需确认后才能录入
+	ErrReadingAbnormal = errors.New("用量异常突增")
+	// ErrReadingDuplicate 该计量表本期已抄表
+	ErrReadingDuplicate = errors.New("该计量表本期已抄表")
+	// ErrReadingExceedsNext 补录的读数大于下期读数
+	ErrReadingExceedsNext = errors.New("读数大于下期读数")
+	// ErrNextReadingBilled 下期读数已出账或所在账期已结账，不能在其之前补录读数
+	ErrNextReadingBilled = errors.New("下期读数已出账，不能补录")
+	// ErrMeterNotFound 计量表不存在或已停用
+	ErrMeterNotFound = errors.New("计量表不存在或已停用")
+)
+
+// Meter 表示计量表（水表、电表）
+type Meter struct {
+	ID             int       `json:"id"`
+	CommunityID    int       `json:"community_id"` // 住户所在社区
+	ResidentID     int       `json:"resident_id"`
+	ResidentName   string    `json:"resident_name,omitempty"`
+	FeeTypeID      int       `json:"fee_type_id"`
+	FeeTypeName    string    `json:"fee_type_name,omitempty"`
+	MeterNo        string    `json:"meter_no"`
+	Multiplier     float64   `json:"multiplier"`
+	MaxReading     float64   `json:"max_reading"`
+	InitialReading float64   `json:"initial_reading"`
+	InstalledAt    time.Time `json:"installed_at"`
+	Status         int       `json:"status"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+}
+
+// MeterRequest 表示登记计量表请求
+type MeterRequest struct {
+	ResidentID     int     `json:"resident_id" binding:"required"`
+	FeeTypeID      int     `json:"fee_type_id" binding:"required"`
+	MeterNo        string  `json:"meter_no" binding:"required"`
+	Multiplier     float64 `json:"multiplier"`
+	MaxReading     float64 `json:"max_reading"`
+	InitialReading float64 `json:"initial_reading"`
+	InstalledAt    string  `json:"installed_at" binding:"required"` // 格式: 2006-01-02
+}
+
+// MeterReading 表示一次抄表记录
+type MeterReading struct {
+	ID          int       `json:"id"`
+	MeterID     int       `json:"meter_id"`
+	MeterNo     string    `json:"meter_no,omitempty"`
+	Year        int       `json:"year"`
+	Month       int       `json:"month"`
+	Reading     float64   `json:"reading"`
+	Consumption float64   `json:"consumption"`
+	ReadAt      time.Time `json:"read_at"`
+	Status      string    `json:"status"`
+	Remark      string    `json:"remark,omitempty"`
+	EnteredBy   int       `json:"entered_by"`
+	BillID      int       `json:"bill_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+}
+
+// MeterReadingRequest 表示录入读数请求
+type MeterReadingRequest struct {
+	MeterNo  string  `json:"meter_no" binding:"required"`
+	Year     int     `json:"year" binding:"required"`
+	Month    int     `json:"month" binding:"required,min=1,max=12"`
+	Reading  float64 `json:"reading" binding:"min=0"`
+	ReadAt   string  `json:"read_at" binding:"required"` // 格式: 2006-01-02
+	Rollover bool    `json:"rollover"`                   // 确认表盘已翻转
+	Confirm  bool    `json:"confirm"`                    // 确认异常用量属实
+	Remark   string  `json:"remark"`
+}
+
+// TariffTier 表示阶梯价格的一档
+type TariffTier struct {
+	Tier       int      `json:"tier"`
+	UpperLimit *float64 `json:"upper_limit"` // nil表示不封顶
+	UnitPrice  float64  `json:"unit_price"`
+}
+
+const meterColumns = `
+	m.id, r.community_id, m.resident_id, r.name, m.fee_type_id, t.name, m.meter_no, m.multiplier, m.max_reading,
+	m.initial_reading, m.installed_at, m.status, m.created_at
+`
+
+const meterFrom = `
+	FROM meters m
+	JOIN residents r ON m.resident_id = r.id
+	JOIN fee_types t ON m.fee_type_id = t.id
+`
+
+// scanMeter 解析一行计量表数据
+func scanMeter(scanner interface{ Scan(...interface{}) error }) (*Meter, error) {
+	var m Meter
+	err := scanner.Scan(&m.ID, &m.CommunityID, &m.ResidentID, &m.ResidentName, &m.FeeTypeID, &m.FeeTypeName, &m.MeterNo,
+		&m.Multiplier, &m.MaxReading, &m.InitialReading, &m.InstalledAt, &m.Status, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetMeters 获取计量表列表
+func GetMeters(filters map[string]interface{}) ([]Meter, error) {
+	query := `SELECT ` + meterColumns + meterFrom + ` WHERE 1=1`
+	var args []interface{}
+
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		query += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		query += ` AND m.resident_id = ?`
+		args = append(args, residentID)
+	}
+
+	if feeTypeID, ok := filters["fee_type_id"].(int); ok && feeTypeID > 0 {
+		query += ` AND m.fee_type_id = ?`
+		args = append(args, feeTypeID)
+	}
+
+	query += ` ORDER BY m.resident_id, m.fee_type_id, m.id`
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var meters []Meter
+	for rows.Next() {
+		m, err := scanMeter(rows)
+		if err != nil {
+			return nil, err
+		}
+		meters = append(meters, *m)
+	}
+
+	return meters, rows.Err()
+}
+
+// GetMeterByID 通过ID获取计量表
+func GetMeterByID(id int) (*Meter, error) {
+	m, err := scanMeter(database.DB.QueryRow(`SELECT `+meterColumns+meterFrom+` WHERE m.id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+// GetMeterByNo 通过表号获取指定社区住户的计量表，表号属于其他社区时返回nil
+func GetMeterByNo(meterNo string, communityID int) (*Meter, error) {
+	m, err := scanMeter(database.DB.QueryRow(`SELECT `+meterColumns+meterFrom+` WHERE m.meter_no = ? AND r.community_id = ?`,
+		meterNo, communityID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+// CreateMeter 登记计量表
+func CreateMeter(req MeterRequest) (int, error) {
+	installedAt, err := time.ParseInLocation("2006-01-02", req.InstalledAt, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("无效的安装日期: %s", req.InstalledAt)
+	}
+
+	multiplier := req.Multiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO meters (resident_id, fee_type_id, meter_no, multiplier, max_reading, initial_reading, installed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		req.ResidentID, req.FeeTypeID, req.MeterNo, multiplier, req.MaxReading, req.InitialReading, installedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// DisableMeter 停用计量表（换表时使用）
+func DisableMeter(id int) error {
+	_, err := database.DB.Exec(`UPDATE meters SET status = 0 WHERE id = ?`, id)
+	return err
+}
+
+// GetMeterReadings 获取计量表的抄表记录，按期间倒序
+func GetMeterReadings(meterID int) ([]MeterReading, error) {
+	rows, err := database.DB.Query(`
+		SELECT mr.id, mr.meter_id, m.meter_no, mr.year, mr.month, mr.reading, mr.consumption, mr.read_at,
+		       mr.status, mr.remark, mr.entered_by, mr.bill_id, mr.created_at
+		FROM meter_readings mr
+		JOIN meters m ON mr.meter_id = m.id
+		WHERE mr.meter_id = ?
+		ORDER BY mr.year DESC, mr.month DESC`, meterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var readings []MeterReading
+	for rows.Next() {
+		var mr MeterReading
+		var remark sql.NullString
+		var billID sql.NullInt64
+		if err := rows.Scan(&mr.ID, &mr.MeterID, &mr.MeterNo, &mr.Year, &mr.Month, &mr.Reading, &mr.Consumption,
+			&mr.ReadAt, &mr.Status, &remark, &mr.EnteredBy, &billID, &mr.CreatedAt); err != nil {
+			return nil, err
+		}
+		mr.Remark = remark.String
+		mr.BillID = int(billID.Int64)
+		readings = append(readings, mr)
+	}
+
+	return readings, rows.Err()
+}
+
+// CreateMeterReading 校验并录入读数
+// 读数小于上期时，除非确认表盘翻转，否则返回ErrReadingRollback；
+// 用量较近3期平均值异常突增时，除非确认属实，否则返回ErrReadingAbnormal；
+// 补录早于已有读数的月份时，读数不能大于下期读数，并按补录的读数重新计算下期用量，
+// 下期读数已出账或已结账时返回ErrNextReadingBilled
+// 表号按communityID所在社区查找
+func CreateMeterReading(req MeterReadingRequest, enteredBy uint, communityID int) (*MeterReading, error) {
+	readAt, err := time.ParseInLocation("2006-01-02", req.ReadAt, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("无效的抄表日期: %s", req.ReadAt)
+	}
+
+	// 预先校验以便尽早提示，写入前在事务中再次检查
+	if err := CheckPeriodOpen(communityID, req.Year, req.Month); err != nil {
+		return nil, err
+	}
+
+	meter, err := GetMeterByNo(req.MeterNo, communityID)
+	if err != nil {
+		return nil, err
+	}
+	if meter == nil || meter.Status != 1 {
+		return nil, fmt.Errorf("%w: %s", ErrMeterNotFound, req.MeterNo)
+	}
+
+	var exists int
+	err = database.DB.QueryRow(`SELECT COUNT(*) FROM meter_readings WHERE meter_id = ? AND year = ? AND month = ?`,
+		meter.ID, req.Year, req.Month).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if exists > 0 {
+		return nil, fmt.Errorf("%w: %s %d年%d月", ErrReadingDuplicate, req.MeterNo, req.Year, req.Month)
+	}
+
+	// 上期读数及近3期用量
+	previous := meter.InitialReading
+	var history []float64
+	rows, err := database.DB.Query(`
+		SELECT reading, consumption FROM meter_readings
+		WHERE meter_id = ? AND (year < ? OR (year = ? AND month < ?))
+		ORDER BY year DESC, month DESC
+		LIMIT 3`, meter.ID, req.Year, req.Year, req.Month)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var reading, consumption float64
+		if err := rows.Scan(&reading, &consumption); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if len(history) == 0 {
+			previous = reading
+		}
+		history = append(history, consumption)
+	}
+	rows.Close()
+
+	status := ReadingNormal
+	delta := req.Reading - previous
+	if delta < 0 {
+		if !req.Rollover || meter.MaxReading <= 0 {
+			return nil, fmt.Errorf("%w: 上期%.2f，本期%.2f", ErrReadingRollback, previous, req.Reading)
+		}
+		delta = meter.MaxReading - previous + req.Reading
+		status = ReadingRollover
+	}
+	consumption := roundAmount(delta * meter.Multiplier)
+
+	if len(history) > 0 {
+		var sum float64
+		for _, h := range history {
+			sum += h
+		}
+		avg := sum / float64(len(history))
+		if avg > 0 && consumption > avg*AbnormalJumpFactor && consumption-avg > AbnormalJumpMin {
+			if !req.Confirm {
+				return nil, fmt.Errorf("%w: 本期用量%.2f，近%d期平均%.2f", ErrReadingAbnormal, consumption, len(history), avg)
+			}
+			status = ReadingAbnormal
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := checkPeriodOpenTx(tx, communityID, req.Year, req.Month); err != nil {
+		return nil, err
+	}
+
+	// 补录时下期读数的用量原本按更早的读数计算，需要改为按本期读数计算
+	var next MeterReading
+	var nextBillID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT id, year, month, reading, status, bill_id FROM meter_readings
+		WHERE meter_id = ? AND (year > ? OR (year = ? AND month > ?))
+		ORDER BY year ASC, month ASC
+		LIMIT 1
+		FOR UPDATE`, meter.ID, req.Year, req.Year, req.Month,
+	).Scan(&next.ID, &next.Year, &next.Month, &next.Reading, &next.Status, &nextBillID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil {
+		if nextBillID.Valid {
+			return nil, fmt.Errorf("%w: %d年%d月", ErrNextReadingBilled, next.Year, next.Month)
+		}
+		if err := checkPeriodOpenTx(tx, communityID, next.Year, next.Month); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNextReadingBilled, err)
+		}
+
+		nextDelta, nextStatus, err := rebaseNextReading(meter.MaxReading, req.Reading, next)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`UPDATE meter_readings SET consumption = ?, status = ? WHERE id = ?`,
+			roundAmount(nextDelta*meter.Multiplier), nextStatus, next.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO meter_readings (meter_id, year, month, reading, consumption, read_at, status, remark, entered_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		meter.ID, req.Year, req.Month, req.Reading, consumption, readAt, status, req.Remark, enteredBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &MeterReading{
+		ID:          int(id),
+		MeterID:     meter.ID,
+		MeterNo:     meter.MeterNo,
+		Year:        req.Year,
+		Month:       req.Month,
+		Reading:     req.Reading,
+		Consumption: consumption,
+		ReadAt:      readAt,
+		Status:      status,
+		Remark:      req.Remark,
+		EnteredBy:   int(enteredBy),
+	}, nil
+}
+
+// rebaseNextReading 按补录的读数重新计算下期读数的表盘用量和状态
+// 下期读数不小于补录读数时按差值计算，原先的翻转已在补录读数之前发生，翻转状态改为正常；
+// 下期读数小于补录读数时，只有下期原本就确认为表盘翻转才按翻转计算，否则返回ErrReadingExceedsNext
+func rebaseNextReading(maxReading, reading float64, next MeterReading) (float64, string, error) {
+	if next.Reading >= reading {
+		status := next.Status
+		if status == ReadingRollover {
+			status = ReadingNormal
+		}
+		return next.Reading - reading, status, nil
+	}
+	if next.Status != ReadingRollover || maxReading <= 0 {
+		return 0, "", fmt.Errorf("%w: 下期%.2f，本期%.2f", ErrReadingExceedsNext, next.Reading, reading)
+	}
+	return maxReading - reading + next.Reading, ReadingRollover, nil
+}
+
+// GetTariffTiers 获取收费项目的阶梯价格，按档位升序
+func GetTariffTiers(feeTypeID int) ([]TariffTier, error) {
+	rows, err := database.DB.Query(`
+		SELECT tier, upper_limit, unit_price FROM tariff_tiers
+		WHERE fee_type_id = ?
+		ORDER BY tier ASC`, feeTypeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []TariffTier
+	for rows.Next() {
+		var t TariffTier
+		var upper sql.NullFloat64
+		if err := rows.Scan(&t.Tier, &upper, &t.UnitPrice); err != nil {
+			return nil, err
+		}
+		if upper.Valid {
+			limit := upper.Float64
+			t.UpperLimit = &limit
+		}
+		tiers = append(tiers, t)
+	}
+
+	return tiers, rows.Err()
+}
+
+// ReplaceTariffTiers 替换收费项目的全部阶梯价格
+func ReplaceTariffTiers(feeTypeID int, tiers []TariffTier) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM tariff_tiers WHERE fee_type_id = ?`, feeTypeID); err != nil {
+		return err
+	}
+	for i, t := range tiers {
+		if _, err := tx.Exec(`INSERT INTO tariff_tiers (fee_type_id, tier, upper_limit, unit_price) VALUES (?, ?, ?, ?)`,
+			feeTypeID, i+1, t.UpperLimit, t.UnitPrice); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CalculateTieredAmount 按阶梯价格计算用量对应的金额，未配置阶梯时按统一单价计算
+func CalculateTieredAmount(tiers []TariffTier, unitPrice, consumption float64) float64 {
+	if len(tiers) == 0 {
+		return roundAmount(consumption * unitPrice)
+	}
+
+	var amount, lower float64
+	for _, t := range tiers {
+		if consumption <= lower {
+			break
+		}
+		upper := consumption
+		if t.UpperLimit != nil {
+			upper = math.Min(consumption, *t.UpperLimit)
+		}
+		if upper > lower {
+			amount += (upper - lower) * t.UnitPrice
+		}
+		if t.UpperLimit == nil {
+			lower = consumption
+			break
+		}
+		lower = *t.UpperLimit
+	}
+
+	// 最后一档封顶时，超出部分按最后一档单价计算
+	if consumption > lower {
+		amount += (consumption - lower) * tiers[len(tiers)-1].UnitPrice
+	}
+
+	return roundAmount(amount)
+}
+
+// GenerateUsageBills 根据指定社区指定月份未出账的抄表记录生成按用量计费的账单
+// 同一住户同一收费项目的多块表合并计量，返回新生成的账单数量
+func GenerateUsageBills(feeType *FeeType, year, month int, dueDate time.Time, communityID int) (int, error) {
+	tiers, err := GetTariffTiers(feeType.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := checkPeriodOpenTx(tx, communityID, year, month); err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT m.resident_id, mr.id, mr.consumption
+		FROM meter_readings mr
+		JOIN meters m ON mr.meter_id = m.id
+		JOIN residents r ON m.resident_id = r.id
+		WHERE m.fee_type_id = ? AND mr.year = ? AND mr.month = ? AND mr.bill_id IS NULL AND r.community_id = ?
+		ORDER BY m.resident_id
+		FOR UPDATE OF mr`, feeType.ID, year, month, communityID)
+	if err != nil {
+		return 0, err
+	}
+
+	type usage struct {
+		consumption float64
+		readingIDs  []int
+	}
+	usages := make(map[int]*usage)
+	var residentIDs []int
+	for rows.Next() {
+		var residentID, readingID int
+		var consumption float64
+		if err := rows.Scan(&residentID, &readingID, &consumption); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		u, ok := usages[residentID]
+		if !ok {
+			u = &usage{}
+			usages[residentID] = u
+			residentIDs = append(residentIDs, residentID)
+		}
+		u.consumption += consumption
+		u.readingIDs = append(u.readingIDs, readingID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, residentID := range residentIDs {
+		u := usages[residentID]
+		amount := CalculateTieredAmount(tiers, feeType.UnitPrice, u.consumption)
+		remark := fmt.Sprintf("%d年%d月%s，用量%.2f%s", year, month, feeType.Name, u.consumption, feeType.Unit)
+
+		if err := createUsageBill(tx, residentID, feeType.ID, year, month, amount, dueDate, remark, u.readingIDs); err != nil {
+			return 0, fmt.Errorf("生成住户%d账单失败: %w", residentID, err)
+		}
+		created++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return created, nil
+}
+
+// createUsageBill 在事务中创建账单、关联抄表记录并记账
+func createUsageBill(tx *sql.Tx, residentID, feeTypeID, year, month int, amount float64, dueDate time.Time, remark string, readingIDs []int) error {
+	result, err := tx.Exec(`
+		INSERT INTO bills (resident_id, fee_type_id, year, month, amount, due_date, remark)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		residentID, feeTypeID, year, month, amount, dueDate, remark,
+	)
+	if err != nil {
+		return err
+	}
+
+	billID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range readingIDs {
+		if _, err := tx.Exec(`UPDATE meter_readings SET bill_id = ? WHERE id = ?`, billID, id); err != nil {
+			return err
+		}
+	}
+
+	return postBillTx(tx, int(billID))
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRebaseNextReading(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxReading float64
+		reading    float64
+		next       MeterReading
+		delta      float64
+		status     string
+		err        error
+	}{
+		{
+			name:    "正常读数按差值重算",
+			reading: 100,
+			next:    MeterReading{Reading: 130, Status: ReadingNormal},
+			delta:   30,
+			status:  ReadingNormal,
+		},
+		{
+			name:    "保留已确认的异常状态",
+			reading: 100,
+			next:    MeterReading{Reading: 180, Status: ReadingAbnormal},
+			delta:   80,
+			status:  ReadingAbnormal,
+		},
+		{
+			name:    "补录读数大于下期读数",
+			reading: 150,
+			next:    MeterReading{Reading: 130, Status: ReadingNormal},
+			err:     ErrReadingExceedsNext,
+		},
+		{
+			name:       "翻转发生在补录读数之后",
+			maxReading: 9999,
+			reading:    9990,
+			next:       MeterReading{Reading: 20, Status: ReadingRollover},
+			delta:      29,
+			status:     ReadingRollover,
+		},
+		{
+			// 上期9980，下期20确认翻转；补录的5已经越过翻转，下期改为正常，用量15
+			name:       "补录读数已越过翻转",
+			maxReading: 9999,
+			reading:    5,
+			next:       MeterReading{Reading: 20, Status: ReadingRollover},
+			delta:      15,
+			status:     ReadingNormal,
+		},
+		{
+			name:       "不翻转的表不按翻转计算",
+			maxReading: 0,
+			reading:    50,
+			next:       MeterReading{Reading: 20, Status: ReadingRollover},
+			err:        ErrReadingExceedsNext,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta, status, err := rebaseNextReading(tt.maxReading, tt.reading, tt.next)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("期望错误%v，实际%v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("意外的错误: %v", err)
+			}
+			if delta != tt.delta || status != tt.status {
+				t.Fatalf("期望用量%.2f状态%s，实际用量%.2f状态%s", tt.delta, tt.status, delta, status)
+			}
+		})
+	}
+}
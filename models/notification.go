@@ -0,0 +1,539 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"community-backward/database"
+	"community-backward/notify"
+)
+
+// 通知发送状态
+const (
+	NotificationPending = "pending" // 待发送，含等待重试
+	NotificationSending = "sending" // 发送中
+	NotificationSent    = "sent"    // 已发送
+	NotificationFailed  = "failed"  // 重试次数用尽仍失败
+)
+
+var (
+	// ErrInvalidNotificationTemplate 通知模板信息不完整
+	ErrInvalidNotificationTemplate = errors.New("无效的通知模板")
+	// ErrNotificationTemplateExists 同一事件、渠道和语言的模板已存在
+	ErrNotificationTemplateExists = errors.New("该事件、渠道和语言的模板已存在")
+	// ErrNotificationTemplateNotFound 事件没有可用的通知模板
+	ErrNotificationTemplateNotFound = errors.New("没有可用的通知模板")
+	// ErrNotificationNotFailed 只有发送失败的通知可以重试
+	ErrNotificationNotFailed = errors.New("通知不是发送失败状态")
+	// ErrInAppMessageNotFound 站内信不存在
+	ErrInAppMessageNotFound = errors.New("站内信不存在")
+)
+
+// NotificationTemplate 表示通知模板
+type NotificationTemplate struct {
+	ID        int       `json:"id"`
+	Event     string    `json:"event"`
+	Channel   string    `json:"channel"`
+	Locale    string    `json:"locale"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NotificationTemplateRequest 表示创建或更新通知模板请求
+type NotificationTemplateRequest struct {
+	Event   string `json:"event" binding:"required,max=50"`
+	Channel string `json:"channel" binding:"required,oneof=inapp sms email"`
+	Locale  string `json:"locale" binding:"required,oneof=zh en"`
+	Title   string `json:"title" binding:"max=100"`
+	Content string `json:"content" binding:"required,max=1000"`
+	Enabled bool   `json:"enabled"`
+}
+
+// NotificationPreference 表示住户的通知偏好
+type NotificationPreference struct {
+	ResidentID   int        `json:"resident_id"`
+	Locale       string     `json:"locale"`
+	InAppEnabled bool       `json:"inapp_enabled"`
+	SMSEnabled   bool       `json:"sms_enabled"`
+	EmailEnabled bool       `json:"email_enabled"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"` // 为空表示使用默认偏好
+}
+
+// NotificationPreferenceRequest 表示更新通知偏好请求
+type NotificationPreferenceRequest struct {
+	Locale       string `json:"locale" binding:"required,oneof=zh en"`
+	InAppEnabled bool   `json:"inapp_enabled"`
+	SMSEnabled   bool   `json:"sms_enabled"`
+	EmailEnabled bool   `json:"email_enabled"`
+}
+
+// Notification 表示发送队列中的一条通知
+type Notification struct {
+	ID            int        `json:"id"`
+	CommunityID   int        `json:"community_id"` // 住户所在社区
+	ResidentID    int        `json:"resident_id"`
+	ResidentName  string     `json:"resident_name"`
+	Room          string     `json:"room"`
+	Event         string     `json:"event"`
+	Channel       string     `json:"channel"`
+	Locale        string     `json:"locale"`
+	Recipient     string     `json:"recipient"`
+	Title         string     `json:"title"`
+	Content       string     `json:"content"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// InAppMessage 表示住户收到的站内信
+type InAppMessage struct {
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	IsRead    bool       `json:"is_read"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+// Enabled 判断住户是否接收指定渠道的通知
+func (p *NotificationPreference) Enabled(channel string) bool {
+	switch channel {
+	case notify.ChannelInApp:
+		return p.InAppEnabled
+	case notify.ChannelSMS:
+		return p.SMSEnabled
+	case notify.ChannelEmail:
+		return p.EmailEnabled
+	}
+	return false
+}
+
+const notificationTemplateColumns = `id, event, channel, locale, title, content, enabled, created_at, updated_at FROM notification_templates`
+
+// scanNotificationTemplate 解析一行通知模板数据
+func scanNotificationTemplate(scanner interface{ Scan(...interface{}) error }) (*NotificationTemplate, error) {
+	var t NotificationTemplate
+	err := scanner.Scan(&t.ID, &t.Event, &t.Channel, &t.Locale, &t.Title, &t.Content, &t.Enabled, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetNotificationTemplates 获取通知模板列表，支持按事件、渠道、语言过滤，onlyEnabled为true时只返回启用的模板
+func GetNotificationTemplates(filters map[string]interface{}, onlyEnabled bool) ([]NotificationTemplate, error) {
+	query := `SELECT ` + notificationTemplateColumns + ` WHERE 1=1`
+	var args []interface{}
+	for _, key := range []string{"event", "channel", "locale"} {
+		if v, ok := filters[key].(string); ok && v != "" {
+			query += ` AND ` + key + ` = ?`
+			args = append(args, v)
+		}
+	}
+	if onlyEnabled {
+		query += ` AND enabled = 1`
+	}
+	query += ` ORDER BY event, channel, locale`
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []NotificationTemplate{}
+	for rows.Next() {
+		t, err := scanNotificationTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+
+	return templates, rows.Err()
+}
+
+// GetNotificationTemplateByID 通过ID获取通知模板
+func GetNotificationTemplateByID(id int) (*NotificationTemplate, error) {
+	t, err := scanNotificationTemplate(database.DB.QueryRow(`SELECT `+notificationTemplateColumns+` WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// validateNotificationTemplate 校验模板请求，短信不需要标题，其余渠道必须填写标题
+func validateNotificationTemplate(req *NotificationTemplateRequest) error {
+	req.Event = strings.TrimSpace(req.Event)
+	if req.Event == "" {
+		return fmt.Errorf("%w: 事件不能为空", ErrInvalidNotificationTemplate)
+	}
+	if req.Channel != notify.ChannelSMS && strings.TrimSpace(req.Title) == "" {
+		return fmt.Errorf("%w: 站内信和邮件模板必须填写标题", ErrInvalidNotificationTemplate)
+	}
+	return nil
+}
+
+// CreateNotificationTemplate 创建通知模板
+func CreateNotificationTemplate(req NotificationTemplateRequest) (int, error) {
+	if err := validateNotificationTemplate(&req); err != nil {
+		return 0, err
+	}
+
+	var exists int
+	err := database.DB.QueryRow(`SELECT COUNT(*) FROM notification_templates WHERE event = ? AND channel = ? AND locale = ?`,
+		req.Event, req.Channel, req.Locale).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if exists > 0 {
+		return 0, fmt.Errorf("%w: %s/%s/%s", ErrNotificationTemplateExists, req.Event, req.Channel, req.Locale)
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO notification_templates (event, channel, locale, title, content, enabled) VALUES (?, ?, ?, ?, ?, ?)`,
+		req.Event, req.Channel, req.Locale, req.Title, req.Content, req.Enabled,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateNotificationTemplate 更新通知模板，返回是否找到该模板
+func UpdateNotificationTemplate(id int, req NotificationTemplateRequest) (bool, error) {
+	if err := validateNotificationTemplate(&req); err != nil {
+		return false, err
+	}
+
+	var exists int
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*) FROM notification_templates WHERE event = ? AND channel = ? AND locale = ? AND id <> ?`,
+		req.Event, req.Channel, req.Locale, id).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	if exists > 0 {
+		return false, fmt.Errorf("%w: %s/%s/%s", ErrNotificationTemplateExists, req.Event, req.Channel, req.Locale)
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE notification_templates SET event = ?, channel = ?, locale = ?, title = ?, content = ?, enabled = ?
+		WHERE id = ?`,
+		req.Event, req.Channel, req.Locale, req.Title, req.Content, req.Enabled, id,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	// 内容未变化时RowsAffected为0，需再确认模板是否存在
+	if n, _ := result.RowsAffected(); n > 0 {
+		return true, nil
+	}
+	var count int
+	err = database.DB.QueryRow(`SELECT COUNT(*) FROM notification_templates WHERE id = ?`, id).Scan(&count)
+	return count > 0, err
+}
+
+// GetNotificationPreference 获取住户的通知偏好，未设置时返回默认偏好：中文、接收全部渠道
+func GetNotificationPreference(residentID int) (*NotificationPreference, error) {
+	p := NotificationPreference{ResidentID: residentID}
+	var updatedAt time.Time
+	err := database.DB.QueryRow(`
+		SELECT locale, inapp_enabled, sms_enabled, email_enabled, updated_at
+		FROM notification_preferences WHERE resident_id = ?`, residentID,
+	).Scan(&p.Locale, &p.InAppEnabled, &p.SMSEnabled, &p.EmailEnabled, &updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			p.Locale = notify.LocaleZH
+			p.InAppEnabled, p.SMSEnabled, p.EmailEnabled = true, true, true
+			return &p, nil
+		}
+		return nil, err
+	}
+
+	p.UpdatedAt = &updatedAt
+	return &p, nil
+}
+
+// SaveNotificationPreference 保存住户的通知偏好
+func SaveNotificationPreference(residentID int, req NotificationPreferenceRequest) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO notification_preferences (resident_id, locale, inapp_enabled, sms_enabled, email_enabled)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE locale = VALUES(locale), inapp_enabled = VALUES(inapp_enabled),
+			sms_enabled = VALUES(sms_enabled), email_enabled = VALUES(email_enabled)`,
+		residentID, req.Locale, req.InAppEnabled, req.SMSEnabled, req.EmailEnabled,
+	)
+	return err
+}
+
+// CreateNotification 将通知加入发送队列，立即可发送
+func CreateNotification(n Notification) (int, error) {
+	result, err := database.DB.Exec(`
+		INSERT INTO notifications (resident_id, event, channel, locale, recipient, title, content, status, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())`,
+		n.ResidentID, n.Event, n.Channel, n.Locale, n.Recipient, n.Title, n.Content, NotificationPending,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+const notificationColumns = `
+	n.id, r.community_id, n.resident_id, r.name, CONCAT(r.block_number, r.unit_number, r.house_number),
+	n.event, n.channel, n.locale, n.recipient, n.title, n.content, n.status, n.attempts,
+	n.next_attempt_at, n.last_error, n.sent_at, n.created_at, n.updated_at
+	FROM notifications n
+	JOIN residents r ON n.resident_id = r.id
+`
+
+// scanNotification 解析一行通知数据
+func scanNotification(scanner interface{ Scan(...interface{}) error }) (*Notification, error) {
+	var n Notification
+	var sentAt sql.NullTime
+	err := scanner.Scan(&n.ID, &n.CommunityID, &n.ResidentID, &n.ResidentName, &n.Room,
+		&n.Event, &n.Channel, &n.Locale, &n.Recipient, &n.Title, &n.Content, &n.Status, &n.Attempts,
+		&n.NextAttemptAt, &n.LastError, &sentAt, &n.CreatedAt, &n.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	n.SentAt = nullTimePtr(sentAt)
+	return &n, nil
+}
+
+// GetNotifications 获取通知发送记录，支持按社区、状态、渠道、事件、住户过滤
+func GetNotifications(page, pageSize int, filters map[string]interface{}) ([]Notification, int, error) {
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	for _, key := range []string{"status", "channel", "event"} {
+		if v, ok := filters[key].(string); ok && v != "" {
+			where += ` AND n.` + key + ` = ?`
+			args = append(args, v)
+		}
+	}
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND n.resident_id = ?`
+		args = append(args, residentID)
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM notifications n JOIN residents r ON n.resident_id = r.id` + where
+	if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + notificationColumns + where + ` ORDER BY n.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		notifications = append(notifications, *n)
+	}
+
+	return notifications, total, rows.Err()
+}
+
+// GetNotificationByID 通过ID获取通知
+func GetNotificationByID(id int) (*Notification, error) {
+	n, err := scanNotification(database.DB.QueryRow(`SELECT `+notificationColumns+` WHERE n.id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return n, nil
+}
+
+// ClaimDueNotifications 领取截至now应发送的通知并标记为发送中，最多limit条
+// 发送中超过staleBefore仍未更新的通知视为发送进程中断，重新放回队列
+func ClaimDueNotifications(now, staleBefore time.Time, limit int) ([]Notification, error) {
+	_, err := database.DB.Exec(`UPDATE notifications SET status = ? WHERE status = ? AND updated_at < ?`,
+		NotificationPending, NotificationSending, staleBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.DB.Query(`SELECT `+notificationColumns+`
+		WHERE n.status = ? AND n.next_attempt_at <= ? ORDER BY n.next_attempt_at, n.id LIMIT ?`,
+		NotificationPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	var due []Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, *n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 逐条抢占，避免手动触发与定时任务同时执行时重复发送
+	claimed := []Notification{}
+	for _, n := range due {
+		result, err := database.DB.Exec(`UPDATE notifications SET status = ? WHERE id = ? AND status = ?`,
+			NotificationSending, n.ID, NotificationPending)
+		if err != nil {
+			return nil, err
+		}
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			n.Status = NotificationSending
+			claimed = append(claimed, n)
+		}
+	}
+
+	return claimed, nil
+}
+
+// MarkNotificationSent 记录通知发送成功
+func MarkNotificationSent(id int) error {
+	_, err := database.DB.Exec(`
+		UPDATE notifications SET status = ?, attempts = attempts + 1, last_error = '', sent_at = NOW()
+		WHERE id = ?`, NotificationSent, id)
+	return err
+}
+
+// MarkNotificationFailed 记录通知发送失败，retryAt为空时不再重试
+func MarkNotificationFailed(id int, sendErr error, retryAt *time.Time) error {
+	message := sendErr.Error()
+	if len(message) > 500 {
+		message = message[:500]
+	}
+
+	status, next := NotificationFailed, time.Now()
+	if retryAt != nil {
+		status, next = NotificationPending, *retryAt
+	}
+	_, err := database.DB.Exec(`
+		UPDATE notifications SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+		WHERE id = ?`, status, message, next, id)
+	return err
+}
+
+// RetryNotification 将发送失败的通知重新放回队列
+func RetryNotification(id int) error {
+	result, err := database.DB.Exec(`
+		UPDATE notifications SET status = ?, next_attempt_at = NOW() WHERE id = ? AND status = ?`,
+		NotificationPending, id, NotificationFailed)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotificationNotFailed
+	}
+	return nil
+}
+
+// GetInAppMessages 获取住户的站内信，返回列表、总数和未读数
+func GetInAppMessages(residentID, page, pageSize int, unreadOnly bool) ([]InAppMessage, int, int, error) {
+	where := ` WHERE resident_id = ?`
+	args := []interface{}{residentID}
+	if unreadOnly {
+		where += ` AND is_read = 0`
+	}
+
+	var total, unread int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM in_app_messages`+where, args...).Scan(&total); err != nil {
+		return nil, 0, 0, err
+	}
+	err := database.DB.QueryRow(`SELECT COUNT(*) FROM in_app_messages WHERE resident_id = ? AND is_read = 0`,
+		residentID).Scan(&unread)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	rows, err := database.DB.Query(`SELECT id, title, content, is_read, created_at, read_at FROM in_app_messages`+
+		where+` ORDER BY id DESC LIMIT ? OFFSET ?`, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer rows.Close()
+
+	messages := []InAppMessage{}
+	for rows.Next() {
+		var m InAppMessage
+		var readAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.Title, &m.Content, &m.IsRead, &m.CreatedAt, &readAt); err != nil {
+			return nil, 0, 0, err
+		}
+		m.ReadAt = nullTimePtr(readAt)
+		messages = append(messages, m)
+	}
+
+	return messages, total, unread, rows.Err()
+}
+
+// MarkInAppMessagesRead 将住户的站内信标记为已读，id为0时标记全部
+func MarkInAppMessagesRead(residentID, id int) error {
+	query := `UPDATE in_app_messages SET is_read = 1, read_at = NOW() WHERE resident_id = ? AND is_read = 0`
+	args := []interface{}{residentID}
+	if id > 0 {
+		query += ` AND id = ?`
+		args = append(args, id)
+	}
+	if _, err := database.DB.Exec(query, args...); err != nil {
+		return err
+	}
+	if id == 0 {
+		return nil
+	}
+
+	// 已读的站内信不会被更新，需再确认站内信是否存在
+	var count int
+	err := database.DB.QueryRow(`SELECT COUNT(*) FROM in_app_messages WHERE id = ? AND resident_id = ?`,
+		id, residentID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrInAppMessageNotFound
+	}
+	return nil
+}
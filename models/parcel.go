@@ -0,0 +1,331 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"community-backward/database"
+)
+
+// 包裹状态
+const (
+	ParcelWaiting  = "waiting"   // 待取件
+	ParcelPickedUp = "picked_up" // 已取件
+	ParcelReturned = "returned"  // 已退回
+)
+
+var (
+	// ErrInvalidParcel 包裹信息不完整
+	ErrInvalidParcel = errors.New("无效的包裹信息")
+	// ErrParcelExists 同一快递公司的运单号已登记
+	ErrParcelExists = errors.New("该运单已登记")
+	// ErrParcelNotWaiting 包裹已取件或已退回
+	ErrParcelNotWaiting = errors.New("包裹不是待取件状态")
+	// ErrInvalidPickupCode 取件码不存在或对应包裹已取走
+	ErrInvalidPickupCode = errors.New("取件码无效")
+)
+
+// Parcel 表示前台代收的快递包裹
+type Parcel struct {
+	ID            int        `json:"id"`
+	CommunityID   int        `json:"community_id"` // 收件住户所在社区
+	ResidentID    int        `json:"resident_id"`
+	ResidentName  string     `json:"resident_name"`
+	Room          string     `json:"room"`
+	Phone         string     `json:"phone"`
+	Carrier       string     `json:"carrier"`
+	TrackingNo    string     `json:"tracking_no"`
+	RecipientName string     `json:"recipient_name"`
+	Shelf         string     `json:"shelf"` // 存放货架
+	PickupCode    string     `json:"pickup_code"`
+	Status        string     `json:"status"`
+	Note          string     `json:"note"`
+	ReceivedAt    time.Time  `json:"received_at"`
+	ReceivedBy    int        `json:"received_by"`
+	NotifiedAt    *time.Time `json:"notified_at,omitempty"`
+	RemindedAt    *time.Time `json:"reminded_at,omitempty"`
+	PickedUpAt    *time.Time `json:"picked_up_at,omitempty"`
+	PickedUpBy    *int       `json:"picked_up_by,omitempty"`
+	PickerName    string     `json:"picker_name,omitempty"`
+	ReturnedAt    *time.Time `json:"returned_at,omitempty"`
+	WaitingDays   int        `json:"waiting_days"` // 待取件的已存放天数
+}
+
+// ParcelRequest 表示登记包裹请求
+type ParcelRequest struct {
+	ResidentID    int    `json:"resident_id" binding:"required"`
+	Carrier       string `json:"carrier" binding:"required,max=30"`
+	TrackingNo    string `json:"tracking_no" binding:"required,max=50"`
+	RecipientName string `json:"recipient_name" binding:"max=50"`
+	Shelf         string `json:"shelf" binding:"max=20"`
+	Note          string `json:"note" binding:"max=200"`
+}
+
+// ParcelPickupRequest 表示凭取件码取件请求
+type ParcelPickupRequest struct {
+	PickupCode string `json:"pickup_code" binding:"required,len=6"`
+	PickerName string `json:"picker_name" binding:"max=50"` // 代取时填写取件人
+}
+
+// pickupCodeAttempts 生成不重复取件码的最大尝试次数
+const pickupCodeAttempts = 10
+
+// newPickupCode 生成6位数字取件码
+func newPickupCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// CreateParcel 登记到件包裹并生成取件码，取件码在待取件包裹中不重复
+func CreateParcel(req ParcelRequest, receivedBy uint) (int, error) {
+	req.Carrier = strings.TrimSpace(req.Carrier)
+	req.TrackingNo = strings.ToUpper(strings.TrimSpace(req.TrackingNo))
+	if req.Carrier == "" || req.TrackingNo == "" {
+		return 0, fmt.Errorf("%w: 快递公司和运单号不能为空", ErrInvalidParcel)
+	}
+
+	var exists int
+	err := database.DB.QueryRow(`SELECT COUNT(*) FROM parcels WHERE carrier = ? AND tracking_no = ?`,
+		req.Carrier, req.TrackingNo).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if exists > 0 {
+		return 0, fmt.Errorf("%w: %s %s", ErrParcelExists, req.Carrier, req.TrackingNo)
+	}
+
+	code := ""
+	for i := 0; i < pickupCodeAttempts && code == ""; i++ {
+		candidate, err := newPickupCode()
+		if err != nil {
+			return 0, err
+		}
+		var used int
+		err = database.DB.QueryRow(`SELECT COUNT(*) FROM parcels WHERE pickup_code = ? AND status = ?`,
+			candidate, ParcelWaiting).Scan(&used)
+		if err != nil {
+			return 0, err
+		}
+		if used == 0 {
+			code = candidate
+		}
+	}
+	if code == "" {
+		return 0, errors.New("生成取件码失败，请重试")
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO parcels (resident_id, carrier, tracking_no, recipient_name, shelf, pickup_code,
+		                     status, note, received_at, received_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW(), ?)`,
+		req.ResidentID, req.Carrier, req.TrackingNo, req.RecipientName, req.Shelf, code,
+		ParcelWaiting, req.Note, receivedBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+const parcelColumns = `
+	p.id, r.community_id, p.resident_id, r.name, CONCAT(r.block_number, r.unit_number, r.house_number), r.phone,
+	p.carrier, p.tracking_no, p.recipient_name, p.shelf, p.pickup_code, p.status, p.note,
+	p.received_at, p.received_by, p.notified_at, p.reminded_at,
+	p.picked_up_at, p.picked_up_by, p.picker_name, p.returned_at
+	FROM parcels p
+	JOIN residents r ON p.resident_id = r.id
+`
+
+// scanParcel 解析一行包裹数据
+func scanParcel(scanner interface{ Scan(...interface{}) error }, now time.Time) (*Parcel, error) {
+	var p Parcel
+	var notifiedAt, remindedAt, pickedUpAt, returnedAt sql.NullTime
+	var pickedUpBy sql.NullInt64
+	err := scanner.Scan(&p.ID, &p.CommunityID, &p.ResidentID, &p.ResidentName, &p.Room, &p.Phone,
+		&p.Carrier, &p.TrackingNo, &p.RecipientName, &p.Shelf, &p.PickupCode, &p.Status, &p.Note,
+		&p.ReceivedAt, &p.ReceivedBy, &notifiedAt, &remindedAt,
+		&pickedUpAt, &pickedUpBy, &p.PickerName, &returnedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	p.NotifiedAt = nullTimePtr(notifiedAt)
+	p.RemindedAt = nullTimePtr(remindedAt)
+	p.PickedUpAt = nullTimePtr(pickedUpAt)
+	p.ReturnedAt = nullTimePtr(returnedAt)
+	if pickedUpBy.Valid {
+		by := int(pickedUpBy.Int64)
+		p.PickedUpBy = &by
+	}
+	if p.Status == ParcelWaiting {
+		p.WaitingDays = int(now.Sub(p.ReceivedAt).Hours() / 24)
+	}
+
+	return &p, nil
+}
+
+// GetParcels 获取包裹列表，支持按状态、住户、快递公司、关键字（运单号、取件码、收件人、房号）查询
+// filters["received_before"]为time.Time时只返回在该时间之前到件且仍未取走的包裹，用于超期包裹列表
+func GetParcels(page, pageSize int, filters map[string]interface{}) ([]Parcel, int, error) {
+	now := time.Now()
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	for _, key := range []string{"status", "carrier"} {
+		if v, ok := filters[key].(string); ok && v != "" {
+			where += ` AND p.` + key + ` = ?`
+			args = append(args, v)
+		}
+	}
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND p.resident_id = ?`
+		args = append(args, residentID)
+	}
+	if keyword, ok := filters["keyword"].(string); ok && keyword != "" {
+		where += ` AND (p.tracking_no LIKE ? OR p.pickup_code = ? OR p.recipient_name LIKE ? OR r.name LIKE ?
+			OR CONCAT(r.block_number, r.unit_number, r.house_number) LIKE ?)`
+		like := "%" + keyword + "%"
+		args = append(args, like, keyword, like, like, like)
+	}
+	if before, ok := filters["received_before"].(time.Time); ok {
+		where += ` AND p.status = ? AND p.received_at < ?`
+		args = append(args, ParcelWaiting, before)
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM parcels p JOIN residents r ON p.resident_id = r.id` + where
+	if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + parcelColumns + where + `
+		ORDER BY p.status = 'waiting' DESC, p.received_at DESC, p.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	parcels := []Parcel{}
+	for rows.Next() {
+		p, err := scanParcel(rows, now)
+		if err != nil {
+			return nil, 0, err
+		}
+		parcels = append(parcels, *p)
+	}
+
+	return parcels, total, rows.Err()
+}
+
+// GetParcelByID 通过ID获取包裹
+func GetParcelByID(id int) (*Parcel, error) {
+	p, err := scanParcel(database.DB.QueryRow(`SELECT `+parcelColumns+` WHERE p.id = ?`, id), time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// PickupParcel 凭取件码确认取件，pickerName为空时视为住户本人取件
+// 只认communityID社区住户的包裹
+func PickupParcel(code, pickerName string, staffID uint, communityID int) (*Parcel, error) {
+	var id int
+	err := database.DB.QueryRow(`
+		SELECT p.id FROM parcels p
+		JOIN residents r ON p.resident_id = r.id
+		WHERE p.pickup_code = ? AND p.status = ? AND r.community_id = ?`,
+		strings.TrimSpace(code), ParcelWaiting, communityID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidPickupCode
+		}
+		return nil, err
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE parcels SET status = ?, picked_up_at = NOW(), picked_up_by = ?, picker_name = ?
+		WHERE id = ? AND status = ?`,
+		ParcelPickedUp, staffID, pickerName, id, ParcelWaiting,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrInvalidPickupCode
+	}
+
+	return GetParcelByID(id)
+}
+
+// ReturnParcel 将长期无人领取的包裹退回快递公司
+func ReturnParcel(id int, note string) error {
+	result, err := database.DB.Exec(`
+		UPDATE parcels SET status = ?, returned_at = NOW(), note = IF(? = '', note, ?)
+		WHERE id = ? AND status = ?`,
+		ParcelReturned, note, note, id, ParcelWaiting,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrParcelNotWaiting
+	}
+	return nil
+}
+
+// MarkParcelNotified 记录到件通知的发送时间
+func MarkParcelNotified(id int) error {
+	_, err := database.DB.Exec(`UPDATE parcels SET notified_at = NOW() WHERE id = ?`, id)
+	return err
+}
+
+// MarkParcelReminded 记录超期催取提醒的发送时间
+func MarkParcelReminded(id int) error {
+	_, err := database.DB.Exec(`UPDATE parcels SET reminded_at = NOW() WHERE id = ?`, id)
+	return err
+}
+
+// GetUnremindedOverdueParcels 获取在before之前到件、仍未取走且尚未催取的包裹
+func GetUnremindedOverdueParcels(before time.Time) ([]Parcel, error) {
+	now := time.Now()
+	rows, err := database.DB.Query(`SELECT `+parcelColumns+`
+		WHERE p.status = ? AND p.received_at < ? AND p.reminded_at IS NULL
+		ORDER BY p.received_at`, ParcelWaiting, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parcels := []Parcel{}
+	for rows.Next() {
+		p, err := scanParcel(rows, now)
+		if err != nil {
+			return nil, err
+		}
+		parcels = append(parcels, *p)
+	}
+
+	return parcels, rows.Err()
+}
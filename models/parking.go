@@ -0,0 +1,871 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"community-backward/database"
+)
+
+// 车位类型
+const (
+	SpaceOwned   = "owned"   // 产权车位
+	SpaceRented  = "rented"  // 租赁车位
+	SpaceVisitor = "visitor" // 访客车位
+)
+
+// 车位租赁合同状态
+const (
+	ContractActive     = "active"
+	ContractExpired    = "expired" // 仅用于展示，生效中的合同过了结束日期即为已到期
+	ContractTerminated = "terminated"
+)
+
+var (
+	// ErrSpaceExists 车位编号已存在
+	ErrSpaceExists = errors.New("车位编号已存在")
+	// ErrSpaceNotFound 车位不存在
+	ErrSpaceNotFound = errors.New("车位不存在")
+	// ErrContractNotFound 租赁合同不存在
+	ErrContractNotFound = errors.New("租赁合同不存在")
+	// ErrVehicleNotFound 车辆不存在
+	ErrVehicleNotFound = errors.New("车辆不存在")
+	// ErrSpaceType 车位类型不支持该操作
+	ErrSpaceType = errors.New("车位类型不支持该操作")
+	// ErrContractOverlap 车位在合同期内已出租
+	ErrContractOverlap = errors.New("该车位在合同期内已有租赁合同")
+	// ErrInvalidContract 合同参数错误
+	ErrInvalidContract = errors.New("合同参数错误")
+	// ErrContractNotActive 合同不是生效中状态
+	ErrContractNotActive = errors.New("合同不是生效中状态")
+	// ErrInvalidPlate 车牌号格式错误
+	ErrInvalidPlate = errors.New("车牌号格式错误")
+	// ErrPlateExists 车牌号已登记
+	ErrPlateExists = errors.New("车牌号已登记")
+	// ErrVehicleSpace 车辆只能绑定本户的产权车位或租赁车位
+	ErrVehicleSpace = errors.New("车辆只能绑定本户的产权车位或在租车位")
+)
+
+// platePattern 普通车牌7位，新能源车牌8位
+var platePattern = regexp.MustCompile(`^[京津沪渝冀豫云辽黑湘皖鲁新苏浙赣鄂桂甘晋蒙陕吉闽贵粤青藏川宁琼][A-HJ-NP-Z][A-HJ-NP-Z0-9]{4,5}[A-HJ-NP-Z0-9挂学警港澳]$`)
+
+// ParkingSpace 表示车位
+type ParkingSpace struct {
+	ID           int       `json:"id"`
+	CommunityID  int       `json:"community_id"`
+	SpaceNo      string    `json:"space_no"`
+	Zone         string    `json:"zone"`
+	Type         string    `json:"type"`
+	ResidentID   int       `json:"resident_id,omitempty"` // 产权车位为业主，租赁车位为当前承租住户
+	ResidentName string    `json:"resident_name,omitempty"`
+	ContractID   int       `json:"contract_id,omitempty"` // 当前生效的租赁合同
+	Occupied     bool      `json:"occupied"`
+	Enabled      bool      `json:"enabled"`
+	Remark       string    `json:"remark,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ParkingSpaceRequest 表示创建或修改车位请求
+type ParkingSpaceRequest struct {
+	SpaceNo string `json:"space_no" binding:"required,max=20"`
+	Zone    string `json:"zone"`
+	Type    string `json:"type" binding:"required,oneof=owned rented visitor"`
+	Enabled bool   `json:"enabled"`
+	Remark  string `json:"remark"`
+}
+
+// SpaceAssignRequest 表示产权车位登记业主请求，resident_id为0表示解除
+type SpaceAssignRequest struct {
+	ResidentID int `json:"resident_id"`
+}
+
+// SpaceOccupancyRequest 表示登记访客车位占用状态请求
+type SpaceOccupancyRequest struct {
+	Occupied bool `json:"occupied"`
+}
+
+// ParkingContract 表示车位租赁合同
+type ParkingContract struct {
+	ID           int        `json:"id"`
+	CommunityID  int        `json:"community_id"`
+	SpaceID      int        `json:"space_id"`
+	SpaceNo      string     `json:"space_no"`
+	ResidentID   int        `json:"resident_id"`
+	ResidentName string     `json:"resident_name"`
+	StartDate    time.Time  `json:"start_date"`
+	EndDate      time.Time  `json:"end_date"`
+	MonthlyRent  float64    `json:"monthly_rent"`
+	Status       string     `json:"status"`
+	Remark       string     `json:"remark,omitempty"`
+	CreatedBy    int        `json:"created_by"`
+	TerminatedAt *time.Time `json:"terminated_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ParkingContractRequest 表示签订车位租赁合同请求
+type ParkingContractRequest struct {
+	SpaceID     int     `json:"space_id" binding:"required"`
+	ResidentID  int     `json:"resident_id" binding:"required"`
+	StartDate   string  `json:"start_date" binding:"required"` // 格式: 2006-01-02
+	EndDate     string  `json:"end_date" binding:"required"`   // 格式: 2006-01-02
+	MonthlyRent float64 `json:"monthly_rent"`                  // 缺省为停车费收费项目的单价
+	Remark      string  `json:"remark"`
+}
+
+// TerminateContractRequest 表示提前终止合同请求
+type TerminateContractRequest struct {
+	EndDate string `json:"end_date" binding:"required"` // 最后计租日，格式: 2006-01-02
+}
+
+// Vehicle 表示住户登记的车辆
+type Vehicle struct {
+	ID           int       `json:"id"`
+	ResidentID   int       `json:"resident_id"`
+	ResidentName string    `json:"resident_name"`
+	PlateNo      string    `json:"plate_no"`
+	Brand        string    `json:"brand"`
+	Color        string    `json:"color"`
+	SpaceID      int       `json:"space_id,omitempty"`
+	SpaceNo      string    `json:"space_no,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// VehicleRequest 表示登记或修改车辆请求
+type VehicleRequest struct {
+	ResidentID int    `json:"resident_id" binding:"required"`
+	PlateNo    string `json:"plate_no" binding:"required"`
+	Brand      string `json:"brand"`
+	Color      string `json:"color"`
+	SpaceID    int    `json:"space_id"`
+}
+
+// ParkingTypeStats 表示某类车位的占用情况
+type ParkingTypeStats struct {
+	Type     string  `json:"type"`
+	Total    int     `json:"total"`
+	Occupied int     `json:"occupied"`
+	Rate     float64 `json:"rate"` // 百分比
+}
+
+// ParkingOccupancy 表示车位使用率
+type ParkingOccupancy struct {
+	AsOf     string             `json:"asOf"`
+	Total    int                `json:"total"`
+	Occupied int                `json:"occupied"`
+	Rate     float64            `json:"rate"` // 百分比
+	ByType   []ParkingTypeStats `json:"byType"`
+}
+
+// GetParkingSpaces 获取截至asOf的车位列表，包含当前业主或承租住户
+func GetParkingSpaces(asOf time.Time, filters map[string]interface{}) ([]ParkingSpace, error) {
+	query := `
+		SELECT s.id, s.community_id, s.space_no, s.zone, s.type, COALESCE(s.resident_id, ct.resident_id, 0), COALESCE(r.name, ''),
+		       COALESCE(ct.id, 0), s.occupied, s.enabled, s.remark, s.created_at
+		FROM parking_spaces s
+		LEFT JOIN parking_contracts ct
+		       ON ct.space_id = s.id AND ct.status = 'active' AND ct.start_date <= ? AND ct.end_date >= ?
+		LEFT JOIN residents r ON r.id = COALESCE(s.resident_id, ct.resident_id)
+		WHERE 1=1`
+	args := []interface{}{asOf, asOf}
+
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		query += ` AND s.community_id = ?`
+		args = append(args, communityID)
+	}
+	if spaceType, ok := filters["type"].(string); ok && spaceType != "" {
+		query += ` AND s.type = ?`
+		args = append(args, spaceType)
+	}
+	if zone, ok := filters["zone"].(string); ok && zone != "" {
+		query += ` AND s.zone = ?`
+		args = append(args, zone)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		query += ` AND COALESCE(s.resident_id, ct.resident_id) = ?`
+		args = append(args, residentID)
+	}
+	if enabled, ok := filters["enabled"].(bool); ok {
+		query += ` AND s.enabled = ?`
+		args = append(args, enabled)
+	}
+	query += ` ORDER BY s.space_no`
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spaces := []ParkingSpace{}
+	for rows.Next() {
+		var s ParkingSpace
+		var remark sql.NullString
+		if err := rows.Scan(&s.ID, &s.CommunityID, &s.SpaceNo, &s.Zone, &s.Type, &s.ResidentID, &s.ResidentName,
+			&s.ContractID, &s.Occupied, &s.Enabled, &remark, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.Remark = remark.String
+
+		// 产权车位有业主、租赁车位有生效合同即视为占用，访客车位按登记的占用状态
+		switch s.Type {
+		case SpaceOwned:
+			s.Occupied = s.ResidentID > 0
+		case SpaceRented:
+			s.Occupied = s.ContractID > 0
+		}
+
+		if occupied, ok := filters["occupied"].(bool); ok && s.Occupied != occupied {
+			continue
+		}
+		spaces = append(spaces, s)
+	}
+
+	return spaces, rows.Err()
+}
+
+// getParkingSpace 获取指定社区车位的基本信息，车位不存在或属于其他社区时返回nil
+func getParkingSpace(id, communityID int) (*ParkingSpace, error) {
+	var s ParkingSpace
+	var residentID sql.NullInt64
+	var remark sql.NullString
+	err := database.DB.QueryRow(`
+		SELECT id, community_id, space_no, zone, type, resident_id, occupied, enabled, remark, created_at
+		FROM parking_spaces WHERE id = ? AND community_id = ?`, id, communityID).
+		Scan(&s.ID, &s.CommunityID, &s.SpaceNo, &s.Zone, &s.Type, &residentID, &s.Occupied, &s.Enabled, &remark, &s.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	s.ResidentID = int(residentID.Int64)
+	s.Remark = remark.String
+	return &s, nil
+}
+
+// CreateParkingSpace 在指定社区新增车位，车位编号在社区内唯一
+func CreateParkingSpace(req ParkingSpaceRequest, communityID int) (int, error) {
+	var exists int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM parking_spaces WHERE community_id = ? AND space_no = ?`,
+		communityID, req.SpaceNo).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists > 0 {
+		return 0, fmt.Errorf("%w: %s", ErrSpaceExists, req.SpaceNo)
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO parking_spaces (community_id, space_no, zone, type, enabled, remark) VALUES (?, ?, ?, ?, ?, ?)`,
+		communityID, req.SpaceNo, req.Zone, req.Type, req.Enabled, req.Remark,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdateParkingSpace 修改指定社区的车位，车位类型变更后原类型的业主和占用状态会被清除
+func UpdateParkingSpace(id int, req ParkingSpaceRequest, communityID int) error {
+	space, err := getParkingSpace(id, communityID)
+	if err != nil {
+		return err
+	}
+	if space == nil {
+		return ErrSpaceNotFound
+	}
+
+	var exists int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM parking_spaces WHERE community_id = ? AND space_no = ? AND id <> ?`,
+		communityID, req.SpaceNo, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return fmt.Errorf("%w: %s", ErrSpaceExists, req.SpaceNo)
+	}
+
+	query := `UPDATE parking_spaces SET space_no = ?, zone = ?, type = ?, enabled = ?, remark = ?`
+	if req.Type != space.Type {
+		query += `, resident_id = NULL, occupied = 0`
+	}
+	_, err = database.DB.Exec(query+` WHERE id = ?`, req.SpaceNo, req.Zone, req.Type, req.Enabled, req.Remark, id)
+	return err
+}
+
+// AssignParkingSpace 登记指定社区产权车位的业主，residentID为0时解除
+func AssignParkingSpace(id, residentID, communityID int) error {
+	space, err := getParkingSpace(id, communityID)
+	if err != nil {
+		return err
+	}
+	if space == nil {
+		return ErrSpaceNotFound
+	}
+	if space.Type != SpaceOwned {
+		return fmt.Errorf("%w: 只有产权车位可以登记业主，租赁车位请签订租赁合同", ErrSpaceType)
+	}
+
+	var owner sql.NullInt64
+	if residentID > 0 {
+		owner = sql.NullInt64{Int64: int64(residentID), Valid: true}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE parking_spaces SET resident_id = ? WHERE id = ?`, owner, id); err != nil {
+		return err
+	}
+	// 业主变更后原业主车辆不再绑定该车位
+	if _, err := tx.Exec(`UPDATE vehicles SET space_id = NULL WHERE space_id = ? AND resident_id <> ?`, id, residentID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetSpaceOccupancy 登记指定社区访客车位的占用状态
+func SetSpaceOccupancy(id int, occupied bool, communityID int) error {
+	space, err := getParkingSpace(id, communityID)
+	if err != nil {
+		return err
+	}
+	if space == nil {
+		return ErrSpaceNotFound
+	}
+	if space.Type != SpaceVisitor {
+		return fmt.Errorf("%w: 只有访客车位需要登记占用状态", ErrSpaceType)
+	}
+
+	_, err = database.DB.Exec(`UPDATE parking_spaces SET occupied = ? WHERE id = ?`, occupied, id)
+	return err
+}
+
+// GetParkingOccupancy 获取截至asOf指定社区已启用车位的使用率，按车位类型分别统计
+func GetParkingOccupancy(asOf time.Time, communityID int) (*ParkingOccupancy, error) {
+	spaces, err := GetParkingSpaces(asOf, map[string]interface{}{"enabled": true, "community_id": communityID})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ParkingOccupancy{AsOf: asOf.Format("2006-01-02")}
+	byType := make(map[string]*ParkingTypeStats)
+	for _, t := range []string{SpaceOwned, SpaceRented, SpaceVisitor} {
+		byType[t] = &ParkingTypeStats{Type: t}
+	}
+
+	for _, s := range spaces {
+		stats, ok := byType[s.Type]
+		if !ok {
+			continue
+		}
+		stats.Total++
+		result.Total++
+		if s.Occupied {
+			stats.Occupied++
+			result.Occupied++
+		}
+	}
+
+	for _, t := range []string{SpaceOwned, SpaceRented, SpaceVisitor} {
+		stats := byType[t]
+		stats.Rate = percentRate(stats.Occupied, stats.Total)
+		result.ByType = append(result.ByType, *stats)
+	}
+	result.Rate = percentRate(result.Occupied, result.Total)
+
+	return result, nil
+}
+
+// percentRate 计算百分比，保留一位小数
+func percentRate(occupied, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(int(float64(occupied)*1000/float64(total)+0.5)) / 10
+}
+
+const parkingContractColumns = `
+	ct.id, r.community_id, ct.space_id, s.space_no, ct.resident_id, r.name, ct.start_date, ct.end_date, ct.monthly_rent,
+	ct.status, ct.remark, ct.created_by, ct.terminated_at, ct.created_at
+`
+
+// scanParkingContract 解析一行车位租赁合同数据，过了结束日期的生效合同显示为已到期
+func scanParkingContract(scanner interface{ Scan(...interface{}) error }, today time.Time) (*ParkingContract, error) {
+	var ct ParkingContract
+	var remark sql.NullString
+	var terminatedAt sql.NullTime
+	err := scanner.Scan(&ct.ID, &ct.CommunityID, &ct.SpaceID, &ct.SpaceNo, &ct.ResidentID, &ct.ResidentName, &ct.StartDate, &ct.EndDate,
+		&ct.MonthlyRent, &ct.Status, &remark, &ct.CreatedBy, &terminatedAt, &ct.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	ct.Remark = remark.String
+	ct.TerminatedAt = nullTimePtr(terminatedAt)
+	if ct.Status == ContractActive && ct.EndDate.Before(today) {
+		ct.Status = ContractExpired
+	}
+
+	return &ct, nil
+}
+
+// GetParkingContracts 获取车位租赁合同列表
+func GetParkingContracts(page, pageSize int, filters map[string]interface{}) ([]ParkingContract, int, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	where := ` WHERE 1=1`
+	var args []interface{}
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+	if spaceID, ok := filters["space_id"].(int); ok && spaceID > 0 {
+		where += ` AND ct.space_id = ?`
+		args = append(args, spaceID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND ct.resident_id = ?`
+		args = append(args, residentID)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		switch status {
+		case ContractActive:
+			where += ` AND ct.status = 'active' AND ct.end_date >= ?`
+			args = append(args, today)
+		case ContractExpired:
+			where += ` AND ct.status = 'active' AND ct.end_date < ?`
+			args = append(args, today)
+		default:
+			where += ` AND ct.status = ?`
+			args = append(args, status)
+		}
+	}
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM parking_contracts ct JOIN residents r ON ct.resident_id = r.id`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + parkingContractColumns + `
+		FROM parking_contracts ct
+		JOIN parking_spaces s ON ct.space_id = s.id
+		JOIN residents r ON ct.resident_id = r.id` + where + `
+		ORDER BY ct.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	contracts := []ParkingContract{}
+	for rows.Next() {
+		ct, err := scanParkingContract(rows, today)
+		if err != nil {
+			return nil, 0, err
+		}
+		contracts = append(contracts, *ct)
+	}
+
+	return contracts, total, rows.Err()
+}
+
+// GetParkingContractByID 通过ID获取车位租赁合同
+func GetParkingContractByID(id int) (*ParkingContract, error) {
+	now := time.Now()
+	row := database.DB.QueryRow(`SELECT `+parkingContractColumns+`
+		FROM parking_contracts ct
+		JOIN parking_spaces s ON ct.space_id = s.id
+		JOIN residents r ON ct.resident_id = r.id
+		WHERE ct.id = ?`, id)
+	ct, err := scanParkingContract(row, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ct, nil
+}
+
+// CreateParkingContract 签订车位租赁合同，车位须与住户在同一社区，同一车位的合同期不能重叠
+func CreateParkingContract(req ParkingContractRequest, createdBy uint) (int, error) {
+	startDate, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("%w: 无效的开始日期%s", ErrInvalidContract, req.StartDate)
+	}
+	endDate, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("%w: 无效的结束日期%s", ErrInvalidContract, req.EndDate)
+	}
+	if endDate.Before(startDate) {
+		return 0, fmt.Errorf("%w: 结束日期不能早于开始日期", ErrInvalidContract)
+	}
+
+	if req.MonthlyRent <= 0 {
+		feeType, err := GetFeeTypeByCode("parking")
+		if err != nil {
+			return 0, err
+		}
+		if feeType == nil {
+			return 0, fmt.Errorf("%w: 请填写月租金", ErrInvalidContract)
+		}
+		req.MonthlyRent = feeType.UnitPrice
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// 锁定车位，避免并发签订重叠的合同
+	var spaceType string
+	var enabled bool
+	err = tx.QueryRow(`
+		SELECT s.type, s.enabled FROM parking_spaces s JOIN residents r ON r.id = ?
+		WHERE s.id = ? AND s.community_id = r.community_id FOR UPDATE`, req.ResidentID, req.SpaceID).Scan(&spaceType, &enabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrSpaceNotFound
+		}
+		return 0, err
+	}
+	if spaceType != SpaceRented || !enabled {
+		return 0, fmt.Errorf("%w: 只有已启用的租赁车位可以出租", ErrSpaceType)
+	}
+
+	var overlap int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM parking_contracts
+		WHERE space_id = ? AND status IN ('active', 'terminated') AND start_date <= ? AND end_date >= ?`,
+		req.SpaceID, endDate, startDate).Scan(&overlap)
+	if err != nil {
+		return 0, err
+	}
+	if overlap > 0 {
+		return 0, ErrContractOverlap
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO parking_contracts (space_id, resident_id, start_date, end_date, monthly_rent, status, remark, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.SpaceID, req.ResidentID, startDate, endDate, roundAmount(req.MonthlyRent), ContractActive, req.Remark, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// TerminateParkingContract 提前终止指定社区的合同，endDate为最后计租日
+func TerminateParkingContract(id int, endDate time.Time, communityID int) error {
+	ct, err := GetParkingContractByID(id)
+	if err != nil {
+		return err
+	}
+	if ct == nil || ct.CommunityID != communityID {
+		return ErrContractNotFound
+	}
+	if ct.Status != ContractActive {
+		return ErrContractNotActive
+	}
+	if endDate.Before(ct.StartDate) || endDate.After(ct.EndDate) {
+		return fmt.Errorf("%w: 终止日期应在合同期%s至%s之间", ErrInvalidContract, ct.StartDate.Format("2006-01-02"), ct.EndDate.Format("2006-01-02"))
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE parking_contracts SET status = ?, end_date = ?, terminated_at = CURDATE() WHERE id = ?`,
+		ContractTerminated, endDate, id,
+	); err != nil {
+		return err
+	}
+	// 承租住户的车辆不再绑定该车位
+	if _, err := tx.Exec(`UPDATE vehicles SET space_id = NULL WHERE space_id = ? AND resident_id = ?`, ct.SpaceID, ct.ResidentID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GenerateParkingBills 按指定社区住户的车位租赁合同生成指定月份的停车费账单
+// 合同只覆盖当月部分日期时按天折算，同一住户的多个车位合并为一张账单
+func GenerateParkingBills(feeType *FeeType, year, month int, dueDate time.Time, communityID int) (int, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := checkPeriodOpenTx(tx, communityID, year, month); err != nil {
+		return 0, err
+	}
+
+	monthStart := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+	monthEnd := monthStart.AddDate(0, 1, -1)
+	daysInMonth := monthEnd.Day()
+
+	rows, err := tx.Query(`
+		SELECT resident_id, start_date, end_date, monthly_rent FROM parking_contracts
+		WHERE status IN ('active', 'terminated') AND start_date <= ? AND end_date >= ?
+		  AND resident_id IN (SELECT id FROM residents WHERE community_id = ?)
+		ORDER BY resident_id, id`, monthEnd, monthStart, communityID)
+	if err != nil {
+		return 0, err
+	}
+
+	rents := make(map[int]float64)
+	var residentIDs []int
+	for rows.Next() {
+		var residentID int
+		var start, end time.Time
+		var rent float64
+		if err := rows.Scan(&residentID, &start, &end, &rent); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if start.Before(monthStart) {
+			start = monthStart
+		}
+		if end.After(monthEnd) {
+			end = monthEnd
+		}
+		if _, ok := rents[residentID]; !ok {
+			residentIDs = append(residentIDs, residentID)
+		}
+		rents[residentID] += rent * float64(daysBetween(start, end)+1) / float64(daysInMonth)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	remark := fmt.Sprintf("%d年%d月%s", year, month, feeType.Name)
+	created := 0
+	for _, residentID := range residentIDs {
+		amount := roundAmount(rents[residentID])
+		if amount <= 0 {
+			continue
+		}
+
+		id, err := insertBillIgnore(tx, residentID, feeType.ID, year, month, amount, dueDate, remark)
+		if err != nil {
+			return 0, fmt.Errorf("生成住户%d停车费账单失败: %w", residentID, err)
+		}
+		if id == 0 {
+			continue
+		}
+		if err := postBillTx(tx, id); err != nil {
+			return 0, fmt.Errorf("住户%d停车费账单记账失败: %w", residentID, err)
+		}
+		created++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return created, nil
+}
+
+// normalizePlate 去除空格和分隔符并转为大写
+func normalizePlate(plate string) string {
+	plate = strings.ToUpper(strings.TrimSpace(plate))
+	plate = strings.ReplaceAll(plate, " ", "")
+	plate = strings.ReplaceAll(plate, "·", "")
+	return strings.ReplaceAll(plate, "-", "")
+}
+
+// validateVehicle 校验车牌号和绑定的车位，返回规范化后的车牌号
+func validateVehicle(id int, req VehicleRequest) (string, error) {
+	plate := normalizePlate(req.PlateNo)
+	if !platePattern.MatchString(plate) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidPlate, req.PlateNo)
+	}
+
+	var exists int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM vehicles WHERE plate_no = ? AND id <> ?`, plate, id).Scan(&exists); err != nil {
+		return "", err
+	}
+	if exists > 0 {
+		return "", fmt.Errorf("%w: %s", ErrPlateExists, plate)
+	}
+
+	if req.SpaceID > 0 {
+		spaces, err := GetParkingSpaces(time.Now(), map[string]interface{}{"resident_id": req.ResidentID})
+		if err != nil {
+			return "", err
+		}
+		found := false
+		for _, s := range spaces {
+			if s.ID == req.SpaceID && s.Type != SpaceVisitor {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", ErrVehicleSpace
+		}
+	}
+
+	return plate, nil
+}
+
+// GetVehicles 获取车辆列表
+func GetVehicles(page, pageSize int, filters map[string]interface{}) ([]Vehicle, int, error) {
+	where := ` WHERE 1=1`
+	var args []interface{}
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND v.resident_id = ?`
+		args = append(args, residentID)
+	}
+	if plate, ok := filters["plate_no"].(string); ok && plate != "" {
+		where += ` AND v.plate_no LIKE ?`
+		args = append(args, "%"+normalizePlate(plate)+"%")
+	}
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM vehicles v JOIN residents r ON v.resident_id = r.id`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT v.id, v.resident_id, r.name, v.plate_no, v.brand, v.color, COALESCE(v.space_id, 0), COALESCE(s.space_no, ''), v.created_at
+		FROM vehicles v
+		JOIN residents r ON v.resident_id = r.id
+		LEFT JOIN parking_spaces s ON v.space_id = s.id` + where + `
+		ORDER BY v.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	vehicles := []Vehicle{}
+	for rows.Next() {
+		var v Vehicle
+		if err := rows.Scan(&v.ID, &v.ResidentID, &v.ResidentName, &v.PlateNo, &v.Brand, &v.Color, &v.SpaceID, &v.SpaceNo, &v.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		vehicles = append(vehicles, v)
+	}
+
+	return vehicles, total, rows.Err()
+}
+
+// GetVehicleByPlate 通过车牌号获取车辆，不存在时返回nil
+func GetVehicleByPlate(plate string) (*Vehicle, error) {
+	list, _, err := GetVehicles(1, 1, map[string]interface{}{"plate_no": plate})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 || list[0].PlateNo != normalizePlate(plate) {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+// CreateVehicle 登记车辆
+func CreateVehicle(req VehicleRequest) (int, error) {
+	plate, err := validateVehicle(0, req)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO vehicles (resident_id, plate_no, brand, color, space_id) VALUES (?, ?, ?, ?, ?)`,
+		req.ResidentID, plate, req.Brand, req.Color, nullableID(req.SpaceID),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// checkVehicleCommunity 检查车辆是否属于指定社区的住户，不存在或属于其他社区时返回ErrVehicleNotFound
+func checkVehicleCommunity(id, communityID int) error {
+	var count int
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*) FROM vehicles v JOIN residents r ON v.resident_id = r.id
+		WHERE v.id = ? AND r.community_id = ?`, id, communityID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrVehicleNotFound
+	}
+	return nil
+}
+
+// UpdateVehicle 修改指定社区住户的车辆信息
+func UpdateVehicle(id int, req VehicleRequest, communityID int) error {
+	if err := checkVehicleCommunity(id, communityID); err != nil {
+		return err
+	}
+
+	plate, err := validateVehicle(id, req)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE vehicles SET resident_id = ?, plate_no = ?, brand = ?, color = ?, space_id = ? WHERE id = ?`,
+		req.ResidentID, plate, req.Brand, req.Color, nullableID(req.SpaceID), id,
+	)
+	return err
+}
+
+// DeleteVehicle 删除指定社区住户的车辆
+func DeleteVehicle(id, communityID int) error {
+	if err := checkVehicleCommunity(id, communityID); err != nil {
+		return err
+	}
+
+	_, err := database.DB.Exec(`DELETE FROM vehicles WHERE id = ?`, id)
+	return err
+}
+
+// nullableID 将0转换为NULL
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id > 0}
+}
@@ -0,0 +1,541 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"community-backward/database"
+)
+
+// 分期计划状态
+const (
+	PlanActive    = "active"
+	PlanCompleted = "completed"
+	PlanCancelled = "cancelled"
+)
+
+// 分期状态
+const (
+	InstalmentPending = "pending"
+	InstalmentPaid    = "paid"
+	InstalmentMissed  = "missed"
+)
+
+var (
+	// ErrPlanExists 住户已有执行中的分期计划
+	ErrPlanExists = errors.New("该住户已有执行中的分期计划")
+	// ErrPlanNotActive 分期计划不是执行中状态
+	ErrPlanNotActive = errors.New("分期计划不是执行中状态")
+	// ErrNoArrears 住户没有欠费
+	ErrNoArrears = errors.New("该住户没有欠费，无需分期")
+	// ErrInvalidSchedule 分期安排不合法
+	ErrInvalidSchedule = errors.New("无效的分期安排")
+)
+
+// PaymentPlan 表示分期还款计划
+type PaymentPlan struct {
+	ID           int                     `json:"id"`
+	ResidentID   int                     `json:"resident_id"`
+	ResidentName string                  `json:"resident_name,omitempty"`
+	TotalAmount  float64                 `json:"total_amount"`
+	PaidAmount   float64                 `json:"paid_amount"`
+	StartDate    time.Time               `json:"start_date"`
+	Status       string                  `json:"status"`
+	Honoured     bool                    `json:"honoured"` // 执行中且没有逾期分期，期间暂停计收滞纳金
+	Remark       string                  `json:"remark,omitempty"`
+	CreatedBy    int                     `json:"created_by"`
+	CancelledBy  int                     `json:"cancelled_by,omitempty"`
+	CancelledAt  *time.Time              `json:"cancelled_at,omitempty"`
+	CreatedAt    time.Time               `json:"created_at,omitempty"`
+	Instalments  []PaymentPlanInstalment `json:"instalments,omitempty"`
+}
+
+// PaymentPlanInstalment 表示分期还款计划中的一期
+type PaymentPlanInstalment struct {
+	ID         int        `json:"id"`
+	PlanID     int        `json:"plan_id"`
+	Seq        int        `json:"seq"`
+	DueDate    time.Time  `json:"due_date"`
+	Amount     float64    `json:"amount"`
+	PaidAmount float64    `json:"paid_amount"`
+	Status     string     `json:"status"`
+	PaidAt     *time.Time `json:"paid_at,omitempty"`
+	AlertedAt  *time.Time `json:"alerted_at,omitempty"`
+}
+
+// InstalmentRequest 表示自定义的一期安排
+type InstalmentRequest struct {
+	DueDate string  `json:"due_date" binding:"required"` // 格式: 2006-01-02
+	Amount  float64 `json:"amount" binding:"required,gt=0"`
+}
+
+// PaymentPlanRequest 表示创建分期计划请求
+// 提供schedule时按自定义安排分期，否则从first_due_date起按月平均分为instalments期
+type PaymentPlanRequest struct {
+	ResidentID   int                 `json:"resident_id" binding:"required"`
+	Instalments  int                 `json:"instalments"`
+	FirstDueDate string              `json:"first_due_date"` // 格式: 2006-01-02
+	Schedule     []InstalmentRequest `json:"schedule"`
+	Remark       string              `json:"remark"`
+}
+
+// MissedInstalment 表示逾期未还的分期及住户联系方式
+type MissedInstalment struct {
+	InstalmentID int        `json:"instalment_id"`
+	PlanID       int        `json:"plan_id"`
+	Seq          int        `json:"seq"`
+	DueDate      time.Time  `json:"due_date"`
+	Amount       float64    `json:"amount"`
+	PaidAmount   float64    `json:"paid_amount"`
+	ResidentID   int        `json:"resident_id"`
+	Name         string     `json:"name"`
+	Room         string     `json:"room"`
+	Phone        string     `json:"phone"`
+	Email        string     `json:"email"`
+	AlertedAt    *time.Time `json:"alerted_at,omitempty"`
+}
+
+// buildSchedule 根据请求生成分期安排，各期金额之和必须等于欠费总额
+func buildSchedule(req PaymentPlanRequest, total float64) ([]PaymentPlanInstalment, error) {
+	var schedule []PaymentPlanInstalment
+
+	if len(req.Schedule) > 0 {
+		var sum float64
+		var last time.Time
+		for i, s := range req.Schedule {
+			due, err := time.ParseInLocation("2006-01-02", s.DueDate, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("%w: 第%d期到期日期无效", ErrInvalidSchedule, i+1)
+			}
+			if !due.After(last) {
+				return nil, fmt.Errorf("%w: 到期日期必须逐期递增", ErrInvalidSchedule)
+			}
+			last = due
+			sum += s.Amount
+			schedule = append(schedule, PaymentPlanInstalment{Seq: i + 1, DueDate: due, Amount: roundAmount(s.Amount)})
+		}
+		if roundAmount(sum) != total {
+			return nil, fmt.Errorf("%w: 各期合计%.2f元，与欠费总额%.2f元不一致", ErrInvalidSchedule, sum, total)
+		}
+		return schedule, nil
+	}
+
+	if req.Instalments < 2 || req.Instalments > 36 {
+		return nil, fmt.Errorf("%w: 分期数应在2到36之间", ErrInvalidSchedule)
+	}
+	first, err := time.ParseInLocation("2006-01-02", req.FirstDueDate, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 首期到期日期无效", ErrInvalidSchedule)
+	}
+
+	// 平均分期，分摊不尽的零头计入最后一期
+	each := math.Floor(total/float64(req.Instalments)*100) / 100
+	for i := 0; i < req.Instalments; i++ {
+		amount := each
+		if i == req.Instalments-1 {
+			amount = roundAmount(total - each*float64(req.Instalments-1))
+		}
+		schedule = append(schedule, PaymentPlanInstalment{
+			Seq:     i + 1,
+			DueDate: first.AddDate(0, i, 0),
+			Amount:  amount,
+		})
+	}
+
+	return schedule, nil
+}
+
+// CreatePaymentPlan 按住户当前欠费余额创建分期计划
+// 锁定住户后再检查是否已有执行中的计划，同一住户的并发请求只有一个能创建成功
+func CreatePaymentPlan(req PaymentPlanRequest, createdBy uint) (int, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var residentID int
+	if err := tx.QueryRow(`SELECT id FROM residents WHERE id = ? FOR UPDATE`, req.ResidentID).Scan(&residentID); err != nil {
+		return 0, err
+	}
+
+	var existing int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM payment_plans WHERE resident_id = ? AND status = ?`,
+		req.ResidentID, PlanActive).Scan(&existing)
+	if err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 0, ErrPlanExists
+	}
+
+	now := time.Now()
+	startDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	total, err := GetResidentBalance(req.ResidentID, endOfDay(startDate))
+	if err != nil {
+		return 0, err
+	}
+	if total <= 0 {
+		return 0, ErrNoArrears
+	}
+
+	schedule, err := buildSchedule(req, total)
+	if err != nil {
+		return 0, err
+	}
+	if schedule[0].DueDate.Before(startDate) {
+		return 0, fmt.Errorf("%w: 首期到期日期不能早于今天", ErrInvalidSchedule)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO payment_plans (resident_id, total_amount, start_date, status, remark, created_by)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		req.ResidentID, total, startDate, PlanActive, req.Remark, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	planID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, s := range schedule {
+		if _, err := tx.Exec(`
+			INSERT INTO payment_plan_instalments (plan_id, seq, due_date, amount, status)
+			VALUES (?, ?, ?, ?, ?)`,
+			planID, s.Seq, s.DueDate, s.Amount, InstalmentPending,
+		); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(planID), nil
+}
+
+// endOfDay 返回指定日期当天的最后时刻
+func endOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, time.Local)
+}
+
+const paymentPlanColumns = `
+	p.id, p.resident_id, r.name, p.total_amount, p.start_date, p.status, p.remark,
+	p.created_by, p.cancelled_by, p.cancelled_at, p.created_at,
+	COALESCE((SELECT SUM(i.paid_amount) FROM payment_plan_instalments i WHERE i.plan_id = p.id), 0),
+	NOT EXISTS (SELECT 1 FROM payment_plan_instalments i WHERE i.plan_id = p.id AND i.status = 'missed')
+`
+
+// scanPaymentPlan 解析一行分期计划数据
+func scanPaymentPlan(scanner interface{ Scan(...interface{}) error }) (*PaymentPlan, error) {
+	var p PaymentPlan
+	var remark sql.NullString
+	var cancelledBy sql.NullInt64
+	var cancelledAt sql.NullTime
+	var noMissed bool
+	err := scanner.Scan(&p.ID, &p.ResidentID, &p.ResidentName, &p.TotalAmount, &p.StartDate, &p.Status, &remark,
+		&p.CreatedBy, &cancelledBy, &cancelledAt, &p.CreatedAt, &p.PaidAmount, &noMissed)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Remark = remark.String
+	p.CancelledBy = int(cancelledBy.Int64)
+	if cancelledAt.Valid {
+		p.CancelledAt = &cancelledAt.Time
+	}
+	p.Honoured = p.Status == PlanActive && noMissed
+
+	return &p, nil
+}
+
+// GetPaymentPlans 获取分期计划列表
+func GetPaymentPlans(page, pageSize int, filters map[string]interface{}) ([]PaymentPlan, int, error) {
+	query := `SELECT ` + paymentPlanColumns + ` FROM payment_plans p JOIN residents r ON p.resident_id = r.id WHERE 1=1`
+	countQuery := `SELECT COUNT(*) FROM payment_plans p WHERE 1=1`
+	var args []interface{}
+
+	if v, ok := filters["resident_id"]; ok {
+		query += ` AND p.resident_id = ?`
+		countQuery += ` AND p.resident_id = ?`
+		args = append(args, v)
+	}
+	if v, ok := filters["status"]; ok {
+		query += ` AND p.status = ?`
+		countQuery += ` AND p.status = ?`
+		args = append(args, v)
+	}
+
+	var total int
+	if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query += ` ORDER BY p.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	plans := []PaymentPlan{}
+	for rows.Next() {
+		p, err := scanPaymentPlan(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		plans = append(plans, *p)
+	}
+
+	return plans, total, rows.Err()
+}
+
+// GetPaymentPlanByID 通过ID获取分期计划及各期明细
+func GetPaymentPlanByID(id int) (*PaymentPlan, error) {
+	row := database.DB.QueryRow(`SELECT `+paymentPlanColumns+`
+		FROM payment_plans p JOIN residents r ON p.resident_id = r.id WHERE p.id = ?`, id)
+	p, err := scanPaymentPlan(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	p.Instalments, err = getInstalments(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// getInstalments 获取分期计划的各期明细
+func getInstalments(planID int) ([]PaymentPlanInstalment, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, plan_id, seq, due_date, amount, paid_amount, status, paid_at, alerted_at
+		FROM payment_plan_instalments WHERE plan_id = ? ORDER BY seq`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []PaymentPlanInstalment
+	for rows.Next() {
+		var i PaymentPlanInstalment
+		var paidAt, alertedAt sql.NullTime
+		if err := rows.Scan(&i.ID, &i.PlanID, &i.Seq, &i.DueDate, &i.Amount, &i.PaidAmount, &i.Status, &paidAt, &alertedAt); err != nil {
+			return nil, err
+		}
+		if paidAt.Valid {
+			i.PaidAt = &paidAt.Time
+		}
+		if alertedAt.Valid {
+			i.AlertedAt = &alertedAt.Time
+		}
+		list = append(list, i)
+	}
+
+	return list, rows.Err()
+}
+
+// CancelPaymentPlan 取消执行中的分期计划，取消后恢复计收滞纳金
+func CancelPaymentPlan(id int, cancelledBy uint) error {
+	result, err := database.DB.Exec(`
+		UPDATE payment_plans SET status = ?, cancelled_by = ?, cancelled_at = NOW()
+		WHERE id = ? AND status = ?`,
+		PlanCancelled, cancelledBy, id, PlanActive,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrPlanNotActive
+	}
+
+	return nil
+}
+
+// RefreshPaymentPlans 以now为基准重新计算所有执行中分期计划的还款进度
+// 计划生效日前到期账单的已还金额（含减免）按期次先后计入各期；
+// 到期超过graceDays天仍未还清的分期标记为逾期，全部还清后计划完成
+func RefreshPaymentPlans(now time.Time, graceDays int) error {
+	rows, err := database.DB.Query(`SELECT id, resident_id, total_amount, start_date FROM payment_plans WHERE status = ?`, PlanActive)
+	if err != nil {
+		return err
+	}
+
+	type activePlan struct {
+		id, residentID int
+		total          float64
+		startDate      time.Time
+	}
+	var plans []activePlan
+	for rows.Next() {
+		var p activePlan
+		if err := rows.Scan(&p.id, &p.residentID, &p.total, &p.startDate); err != nil {
+			rows.Close()
+			return err
+		}
+		plans = append(plans, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range plans {
+		if err := refreshPaymentPlan(p.id, p.residentID, p.total, p.startDate, now, graceDays); err != nil {
+			return fmt.Errorf("刷新分期计划%d失败: %w", p.id, err)
+		}
+	}
+
+	return nil
+}
+
+// refreshPaymentPlan 重新计算单个分期计划的还款进度
+func refreshPaymentPlan(planID, residentID int, total float64, startDate, now time.Time, graceDays int) error {
+	open, err := GetOpenBills(now, residentID)
+	if err != nil {
+		return err
+	}
+
+	// 计划覆盖生效日前已到期的账单，先进先出冲抵保证缴费先还这部分欠款
+	var remaining float64
+	for _, b := range open {
+		if !b.DueDate.After(startDate) {
+			remaining += b.Outstanding
+		}
+	}
+	paid := roundAmount(math.Max(total-remaining, 0))
+
+	instalments, err := getInstalments(planID)
+	if err != nil {
+		return err
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	allPaid := true
+	for _, inst := range instalments {
+		covered := roundAmount(math.Min(paid, inst.Amount))
+		paid = roundAmount(paid - covered)
+
+		status := InstalmentPending
+		var paidAt interface{} = nil
+		switch {
+		case covered >= inst.Amount:
+			status = InstalmentPaid
+			paidAt = today
+			if inst.PaidAt != nil {
+				paidAt = *inst.PaidAt
+			}
+		case today.After(inst.DueDate.AddDate(0, 0, graceDays)):
+			status = InstalmentMissed
+		}
+		if status != InstalmentPaid {
+			allPaid = false
+		}
+
+		if status == inst.Status && covered == inst.PaidAmount {
+			continue
+		}
+		if _, err := database.DB.Exec(`
+			UPDATE payment_plan_instalments SET paid_amount = ?, status = ?, paid_at = ? WHERE id = ?`,
+			covered, status, paidAt, inst.ID,
+		); err != nil {
+			return err
+		}
+	}
+
+	if allPaid {
+		_, err = database.DB.Exec(`UPDATE payment_plans SET status = ? WHERE id = ?`, PlanCompleted, planID)
+		return err
+	}
+
+	return nil
+}
+
+// GetMissedInstalments 获取执行中计划的逾期分期，unalertedOnly为true时只返回尚未提醒的
+func GetMissedInstalments(unalertedOnly bool) ([]MissedInstalment, error) {
+	query := `
+		SELECT i.id, i.plan_id, i.seq, i.due_date, i.amount, i.paid_amount, i.alerted_at,
+		       r.id, r.name, CONCAT(r.block_number, r.unit_number, r.house_number), r.phone, r.email
+		FROM payment_plan_instalments i
+		JOIN payment_plans p ON i.plan_id = p.id
+		JOIN residents r ON p.resident_id = r.id
+		WHERE p.status = ? AND i.status = ?`
+	if unalertedOnly {
+		query += ` AND i.alerted_at IS NULL`
+	}
+	query += ` ORDER BY i.due_date, i.id`
+
+	rows, err := database.DB.Query(query, PlanActive, InstalmentMissed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []MissedInstalment{}
+	for rows.Next() {
+		var m MissedInstalment
+		var alertedAt sql.NullTime
+		if err := rows.Scan(&m.InstalmentID, &m.PlanID, &m.Seq, &m.DueDate, &m.Amount, &m.PaidAmount, &alertedAt,
+			&m.ResidentID, &m.Name, &m.Room, &m.Phone, &m.Email); err != nil {
+			return nil, err
+		}
+		if alertedAt.Valid {
+			m.AlertedAt = &alertedAt.Time
+		}
+		list = append(list, m)
+	}
+
+	return list, rows.Err()
+}
+
+// MarkInstalmentAlerted 记录逾期分期已发送提醒
+func MarkInstalmentAlerted(id int) error {
+	_, err := database.DB.Exec(`UPDATE payment_plan_instalments SET alerted_at = NOW() WHERE id = ?`, id)
+	return err
+}
+
+// getHonouredPlanCoverage 获取asOf时分期计划正常执行的住户及计划生效日，计划覆盖生效日前已到期的账单
+// asOf时计划已生效且尚未取消，并且没有到期超过graceDays天仍未还清的分期，才算正常执行；
+// 按asOf而不是当前状态判断，补算历史月份时不受之后取消或逾期的影响
+func getHonouredPlanCoverage(asOf time.Time, graceDays int) (map[int]time.Time, error) {
+	rows, err := database.DB.Query(`
+		SELECT p.resident_id, p.start_date FROM payment_plans p
+		WHERE p.start_date <= ?
+		  AND (p.status <> ? OR p.cancelled_at > ?)
+		  AND NOT EXISTS (
+		      SELECT 1 FROM payment_plan_instalments i
+		      WHERE i.plan_id = p.id AND DATE_ADD(i.due_date, INTERVAL ? DAY) < DATE(?)
+		        AND (i.paid_at IS NULL OR i.paid_at > ?))`,
+		asOf, PlanCancelled, asOf, graceDays, asOf, asOf,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	coverage := make(map[int]time.Time)
+	for rows.Next() {
+		var residentID int
+		var startDate time.Time
+		if err := rows.Scan(&residentID, &startDate); err != nil {
+			return nil, err
+		}
+		if startDate.After(coverage[residentID]) {
+			coverage[residentID] = startDate
+		}
+	}
+
+	return coverage, rows.Err()
+}
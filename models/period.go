@@ -0,0 +1,231 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"community-backward/database"
+)
+
+// 账期状态
+const (
+	PeriodClosed = "closed"
+	PeriodOpen   = "open"
+)
+
+var (
+	// ErrPeriodClosed 账期已结账，不能新增或修改该账期内的账单、缴费和统计
+	ErrPeriodClosed = errors.New("账期已结账")
+	// ErrLaterPeriodClosed 之后的账期已结账，需先反结账之后的账期
+	ErrLaterPeriodClosed = errors.New("之后的账期已结账，请先反结账之后的账期")
+)
+
+// AccountingPeriod 表示一个社区的会计账期（月），各社区分别结账
+type AccountingPeriod struct {
+	ID           int        `json:"id"`
+	CommunityID  int        `json:"community_id"`
+	Year         int        `json:"year"`
+	Month        int        `json:"month"`
+	Status       string     `json:"status"`
+	ClosedBy     int        `json:"closed_by,omitempty"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	ReopenedBy   int        `json:"reopened_by,omitempty"`
+	ReopenedAt   *time.Time `json:"reopened_at,omitempty"`
+	ReopenReason string     `json:"reopen_reason,omitempty"`
+}
+
+// ClosePeriodRequest 表示结账请求，month为0时结账全年
+type ClosePeriodRequest struct {
+	Year  int `json:"year" binding:"required"`
+	Month int `json:"month" binding:"min=0,max=12"`
+}
+
+// ReopenPeriodRequest 表示反结账请求
+type ReopenPeriodRequest struct {
+	Year   int    `json:"year" binding:"required"`
+	Month  int    `json:"month" binding:"required,min=1,max=12"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// GetAccountingPeriods 获取指定社区指定年份已记录的账期
+func GetAccountingPeriods(communityID, year int) ([]AccountingPeriod, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, community_id, year, month, status, closed_by, closed_at, reopened_by, reopened_at, reopen_reason
+		FROM accounting_periods
+		WHERE community_id = ? AND year = ?
+		ORDER BY month ASC`, communityID, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periods := []AccountingPeriod{}
+	for rows.Next() {
+		var p AccountingPeriod
+		var closedBy, reopenedBy sql.NullInt64
+		var closedAt, reopenedAt sql.NullTime
+		var reason sql.NullString
+		if err := rows.Scan(&p.ID, &p.CommunityID, &p.Year, &p.Month, &p.Status, &closedBy, &closedAt,
+			&reopenedBy, &reopenedAt, &reason); err != nil {
+			return nil, err
+		}
+		p.ClosedBy = int(closedBy.Int64)
+		p.ReopenedBy = int(reopenedBy.Int64)
+		p.ReopenReason = reason.String
+		if closedAt.Valid {
+			p.ClosedAt = &closedAt.Time
+		}
+		if reopenedAt.Valid {
+			p.ReopenedAt = &reopenedAt.Time
+		}
+		periods = append(periods, p)
+	}
+
+	return periods, rows.Err()
+}
+
+// GetLastClosedPeriod 获取指定社区最近一个已结账的账期，没有已结账的账期时返回0, 0
+func GetLastClosedPeriod(communityID int) (int, int, error) {
+	var year, month int
+	err := database.DB.QueryRow(`
+		SELECT year, month FROM accounting_periods
+		WHERE community_id = ? AND status = ?
+		ORDER BY year DESC, month DESC
+		LIMIT 1`, communityID, PeriodClosed).Scan(&year, &month)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	return year, month, nil
+}
+
+// IsPeriodClosed 判断指定社区的指定账期是否已结账
+// 结账冻结该社区截至该月的全部数据，最近一个已结账账期及之前的账期都视为已结账
+func IsPeriodClosed(communityID, year, month int) (bool, error) {
+	closedYear, closedMonth, err := GetLastClosedPeriod(communityID)
+	if err != nil {
+		return false, err
+	}
+	return closedYear > year || (closedYear == year && closedMonth >= month), nil
+}
+
+// CheckPeriodOpen 检查指定社区的指定账期是否允许修改，已结账时返回ErrPeriodClosed
+// 只用于写入前的预先校验，写入账单、缴费等数据时应在事务内调用checkPeriodOpenTx
+func CheckPeriodOpen(communityID, year, month int) error {
+	closed, err := IsPeriodClosed(communityID, year, month)
+	if err != nil {
+		return fmt.Errorf("检查账期状态失败: %w", err)
+	}
+	if closed {
+		return fmt.Errorf("%w: %d年%d月已结账，不能修改", ErrPeriodClosed, year, month)
+	}
+	return nil
+}
+
+// CheckDateOpen 检查指定社区日期所在账期是否允许修改
+func CheckDateOpen(communityID int, t time.Time) error {
+	return CheckPeriodOpen(communityID, t.Year(), int(t.Month()))
+}
+
+// checkPeriodOpenTx 在写入事务中检查指定社区的指定账期是否允许修改，已结账时返回ErrPeriodClosed
+// 以共享锁读取该社区该月及之后的账期记录，结账需等待事务提交，避免检查通过后账期被结账
+func checkPeriodOpenTx(tx *sql.Tx, communityID, year, month int) error {
+	var closed int
+	err := tx.QueryRow(`
+		SELECT COUNT(CASE WHEN status = ? THEN 1 END) FROM accounting_periods
+		WHERE community_id = ? AND (year > ? OR (year = ? AND month >= ?))
+		FOR SHARE`,
+		PeriodClosed, communityID, year, year, month,
+	).Scan(&closed)
+	if err != nil {
+		return fmt.Errorf("检查账期状态失败: %w", err)
+	}
+	if closed > 0 {
+		return fmt.Errorf("%w: %d年%d月已结账，不能修改", ErrPeriodClosed, year, month)
+	}
+	return nil
+}
+
+// checkDateOpenTx 在写入事务中检查指定社区日期所在账期是否允许修改
+func checkDateOpenTx(tx *sql.Tx, communityID int, t time.Time) error {
+	return checkPeriodOpenTx(tx, communityID, t.Year(), int(t.Month()))
+}
+
+// residentCommunityTx 在事务中获取住户所属社区，账单、缴费等按住户所属社区检查账期和记账
+func residentCommunityTx(tx *sql.Tx, residentID int) (int, error) {
+	var communityID int
+	err := tx.QueryRow(`SELECT community_id FROM residents WHERE id = ?`, residentID).Scan(&communityID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("住户%d不存在", residentID)
+	}
+	return communityID, err
+}
+
+// ClosePeriod 结账指定社区的指定月份，结账前刷新该社区该月的月度统计
+// 先锁定账期记录，等待正在写入该月的事务提交后再刷新统计，结账后的写入会被账期检查拒绝
+func ClosePeriod(communityID, year, month int, userID uint) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO accounting_periods (community_id, year, month, status) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE status = status`,
+		communityID, year, month, PeriodOpen,
+	); err != nil {
+		return err
+	}
+	if err := checkPeriodOpenTx(tx, communityID, year, month); err != nil {
+		return err
+	}
+
+	if err := refreshMonthlyStats(tx, communityID, year, month); err != nil {
+		return fmt.Errorf("刷新%d年%d月统计失败: %w", year, month, err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE accounting_periods SET status = ?, closed_by = ?, closed_at = NOW()
+		WHERE community_id = ? AND year = ? AND month = ?`,
+		PeriodClosed, userID, communityID, year, month,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReopenPeriod 反结账指定社区的指定月份，返回该月此前是否处于结账状态
+// 只能从该社区最近一个已结账的账期开始倒序反结账，之后的账期已结账时返回ErrLaterPeriodClosed
+func ReopenPeriod(communityID, year, month int, userID uint, reason string) (bool, error) {
+	var later int
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*) FROM accounting_periods
+		WHERE community_id = ? AND status = ? AND (year > ? OR (year = ? AND month > ?))`,
+		communityID, PeriodClosed, year, year, month,
+	).Scan(&later)
+	if err != nil {
+		return false, err
+	}
+	if later > 0 {
+		return false, fmt.Errorf("%w: %d年%d月", ErrLaterPeriodClosed, year, month)
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE accounting_periods
+		SET status = ?, reopened_by = ?, reopened_at = NOW(), reopen_reason = ?
+		WHERE community_id = ? AND year = ? AND month = ? AND status = ?`,
+		PeriodOpen, userID, reason, communityID, year, month, PeriodClosed,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
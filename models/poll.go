@@ -0,0 +1,1076 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"community-backward/database"
+)
+
+// 表决类型
+const (
+	PollVote   = "vote"   // 业主表决
+	PollSurvey = "survey" // 问卷调查
+)
+
+// 业主表决的通过规则
+const (
+	PollRuleOrdinary = "ordinary" // 一般事项：同意过半
+	PollRuleSpecial  = "special"  // 重大事项：同意达四分之三
+)
+
+// 表决状态，ended由投票中表决的截止时间计算得出
+const (
+	PollDraft     = "draft"
+	PollOpen      = "open"
+	PollClosed    = "closed"
+	PollCancelled = "cancelled"
+	PollEnded     = "ended" // 投票中，已过截止时间，等待结束计票
+)
+
+// 选票来源
+const (
+	BallotSourceResident = "resident" // 住户端投票
+	BallotSourceStaff    = "staff"    // 工作人员代录纸质选票
+)
+
+// voteOptions 业主表决的固定选项，第一项为同意
+var voteOptions = []string{"同意", "反对", "弃权"}
+
+// genesisHash 每个表决第一张选票的prev_hash
+var genesisHash = strings.Repeat("0", 64)
+
+var (
+	// ErrInvalidPoll 表决参数错误
+	ErrInvalidPoll = errors.New("表决参数错误")
+	// ErrPollState 表决当前状态不允许该操作
+	ErrPollState = errors.New("表决当前状态不允许该操作")
+	// ErrPollNotFound 表决不存在
+	ErrPollNotFound = errors.New("表决不存在")
+	// ErrPollNotEligible 住户不在表决范围内
+	ErrPollNotEligible = errors.New("住户不在表决范围内")
+	// ErrAlreadyVoted 本户已投票
+	ErrAlreadyVoted = errors.New("本户已投票")
+)
+
+// PollOption 表示表决选项
+type PollOption struct {
+	ID        int    `json:"id"`
+	SortOrder int    `json:"sort_order"`
+	Content   string `json:"content"`
+}
+
+// Poll 表示业主表决或问卷调查
+type Poll struct {
+	ID            int          `json:"id"`
+	CommunityID   int          `json:"community_id"`
+	Title         string       `json:"title"`
+	Description   string       `json:"description"`
+	Kind          string       `json:"kind"`
+	Rule          string       `json:"rule,omitempty"`
+	MaxChoices    int          `json:"max_choices"`
+	Anonymous     bool         `json:"anonymous"`
+	Audience      string       `json:"audience"`
+	Blocks        []string     `json:"buildings,omitempty"`
+	Status        string       `json:"status"`
+	StartAt       *time.Time   `json:"start_at,omitempty"`
+	EndAt         time.Time    `json:"end_at"`
+	EligibleCount int          `json:"eligible_count"`
+	EligibleArea  float64      `json:"eligible_area"`
+	VotedCount    int          `json:"voted_count"`
+	CreatedBy     int          `json:"created_by"`
+	OpenedAt      *time.Time   `json:"opened_at,omitempty"`
+	ClosedAt      *time.Time   `json:"closed_at,omitempty"`
+	ClosedBy      int          `json:"closed_by,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+	Options       []PollOption `json:"options,omitempty"`
+	Voted         *bool        `json:"voted,omitempty"`      // 住户端：本户是否已投票
+	MyChoices     []int        `json:"my_choices,omitempty"` // 住户端：本户的选择，匿名表决不返回
+}
+
+// PollRequest 表示创建或修改表决请求
+type PollRequest struct {
+	Title       string   `json:"title" binding:"required,max=100"`
+	Description string   `json:"description"`
+	Kind        string   `json:"kind" binding:"required,oneof=vote survey"`
+	Rule        string   `json:"rule" binding:"omitempty,oneof=ordinary special"` // 业主表决必填，缺省为一般事项
+	MaxChoices  int      `json:"max_choices" binding:"min=0"`                     // 问卷每户最多可选项数，缺省为1
+	Anonymous   bool     `json:"anonymous"`
+	Audience    string   `json:"audience" binding:"required,oneof=all targeted"`
+	Blocks      []string `json:"buildings"`                               // 指定楼栋时必填
+	Options     []string `json:"options" binding:"dive,required,max=200"` // 问卷选项，业主表决固定为同意、反对、弃权
+	StartAt     string   `json:"start_at"`                                // 格式: 2006-01-02 15:04，为空时开始投票即可投
+	EndAt       string   `json:"end_at" binding:"required"`               // 格式: 2006-01-02 15:04
+}
+
+// PollBallotRequest 表示投票请求，工作人员代录时需指定住户
+type PollBallotRequest struct {
+	ResidentID int   `json:"resident_id"`
+	Choices    []int `json:"choices" binding:"required,min=1"`
+}
+
+// PollOptionTally 表示单个选项的计票结果
+type PollOptionTally struct {
+	OptionID int     `json:"option_id"`
+	Content  string  `json:"content"`
+	Count    int     `json:"count"`
+	Area     float64 `json:"area"`
+}
+
+// PollTally 表示表决的计票结果，按户数和房屋面积分别统计
+type PollTally struct {
+	EligibleCount int               `json:"eligible_count"`
+	EligibleArea  float64           `json:"eligible_area"`
+	VotedCount    int               `json:"voted_count"`
+	VotedArea     float64           `json:"voted_area"`
+	Options       []PollOptionTally `json:"options"`
+	Passed        *bool             `json:"passed,omitempty"` // 仅业主表决
+	Reason        string            `json:"reason,omitempty"`
+}
+
+// PollResult 表示结束投票时写入的表决结果
+type PollResult struct {
+	PollID      int       `json:"poll_id"`
+	Tally       PollTally `json:"tally"`
+	Passed      *bool     `json:"passed,omitempty"`
+	BallotCount int       `json:"ballot_count"`
+	LastHash    string    `json:"last_hash"`
+	ResultHash  string    `json:"result_hash"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PollBallot 表示一张选票，匿名表决不返回住户信息，面积为0
+type PollBallot struct {
+	Seq          int     `json:"seq"`
+	ResidentID   int     `json:"resident_id,omitempty"`
+	ResidentName string  `json:"resident_name,omitempty"`
+	BlockNumber  string  `json:"building,omitempty"`
+	UnitNumber   string  `json:"unit,omitempty"`
+	HouseNumber  string  `json:"room,omitempty"`
+	Area         float64 `json:"area"`
+	Choices      []int   `json:"choices"`
+	PrevHash     string  `json:"prev_hash"`
+	Hash         string  `json:"hash"`
+}
+
+// PollVerification 表示选票哈希链和表决结果的校验结果
+type PollVerification struct {
+	Valid       bool   `json:"valid"`
+	BallotCount int    `json:"ballot_count"`
+	BrokenSeq   int    `json:"broken_seq,omitempty"` // 第一张校验失败的选票序号
+	Message     string `json:"message"`
+}
+
+// ballotHash 计算选票哈希，voter在匿名表决中为空，匿名表决的area为0
+func ballotHash(prevHash string, pollID, seq int, voter string, area float64, choices string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d|%s|%.2f|%s", prevHash, pollID, seq, voter, area, choices)))
+	return hex.EncodeToString(sum[:])
+}
+
+// resultHash 计算表决结果哈希
+func resultHash(lastHash, tally string) string {
+	sum := sha256.Sum256([]byte(lastHash + "|" + tally))
+	return hex.EncodeToString(sum[:])
+}
+
+// joinChoices 将所选选项ID排序去重后以逗号连接
+func joinChoices(choices []int) string {
+	sorted := append([]int(nil), choices...)
+	sort.Ints(sorted)
+	parts := make([]string, 0, len(sorted))
+	for i, id := range sorted {
+		if i > 0 && id == sorted[i-1] {
+			continue
+		}
+		parts = append(parts, strconv.Itoa(id))
+	}
+	return strings.Join(parts, ",")
+}
+
+// splitChoices 解析逗号分隔的选项ID
+func splitChoices(choices string) []int {
+	ids := []int{}
+	for _, part := range strings.Split(choices, ",") {
+		if id, err := strconv.Atoi(part); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// pollStatus 根据截止时间计算投票中表决的显示状态
+func pollStatus(status string, endAt, now time.Time) string {
+	if status == PollOpen && !endAt.After(now) {
+		return PollEnded
+	}
+	return status
+}
+
+// validatePoll 校验表决请求，返回解析后的开始时间和截止时间
+func validatePoll(req *PollRequest) (*time.Time, time.Time, error) {
+	var endAt time.Time
+	startAt, err := parseDateTime(req.StartAt)
+	if err != nil {
+		return nil, endAt, fmt.Errorf("%w: 无效的开始时间%s", ErrInvalidPoll, req.StartAt)
+	}
+	end, err := parseDateTime(req.EndAt)
+	if err != nil || end == nil {
+		return nil, endAt, fmt.Errorf("%w: 无效的截止时间%s", ErrInvalidPoll, req.EndAt)
+	}
+	endAt = *end
+	if startAt != nil && !endAt.After(*startAt) {
+		return nil, endAt, fmt.Errorf("%w: 截止时间必须晚于开始时间", ErrInvalidPoll)
+	}
+
+	if req.Kind == PollVote {
+		if req.Rule == "" {
+			req.Rule = PollRuleOrdinary
+		}
+		req.Options = voteOptions
+		req.MaxChoices = 1
+	} else {
+		req.Rule = ""
+		if len(req.Options) < 2 {
+			return nil, endAt, fmt.Errorf("%w: 问卷至少需要两个选项", ErrInvalidPoll)
+		}
+		if req.MaxChoices == 0 {
+			req.MaxChoices = 1
+		}
+		if req.MaxChoices > len(req.Options) {
+			return nil, endAt, fmt.Errorf("%w: 最多可选项数不能超过选项数", ErrInvalidPoll)
+		}
+	}
+
+	if req.Audience == AudienceAll {
+		req.Blocks = nil
+	} else if len(req.Blocks) == 0 {
+		return nil, endAt, fmt.Errorf("%w: 请指定参与表决的楼栋", ErrInvalidPoll)
+	}
+
+	return startAt, endAt, nil
+}
+
+// savePollDetail 替换表决的选项和楼栋范围
+func savePollDetail(tx *sql.Tx, id int, req PollRequest) error {
+	if _, err := tx.Exec(`DELETE FROM poll_options WHERE poll_id = ?`, id); err != nil {
+		return err
+	}
+	for i, content := range req.Options {
+		if _, err := tx.Exec(`INSERT INTO poll_options (poll_id, sort_order, content) VALUES (?, ?, ?)`,
+			id, i+1, strings.TrimSpace(content)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM poll_blocks WHERE poll_id = ?`, id); err != nil {
+		return err
+	}
+	for _, block := range req.Blocks {
+		if _, err := tx.Exec(`INSERT IGNORE INTO poll_blocks (poll_id, block_number) VALUES (?, ?)`,
+			id, strings.TrimSpace(block)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreatePoll 创建表决草稿
+func CreatePoll(req PollRequest, createdBy uint, communityID int) (int, error) {
+	startAt, endAt, err := validatePoll(&req)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO polls (community_id, title, description, kind, rule, max_choices, anonymous, audience, status, start_at, end_at, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		communityID, req.Title, req.Description, req.Kind, req.Rule, req.MaxChoices, req.Anonymous, req.Audience,
+		PollDraft, startAt, endAt, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := savePollDetail(tx, int(id), req); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// UpdatePoll 修改表决草稿，开始投票后不能修改
+func UpdatePoll(id int, req PollRequest) error {
+	startAt, endAt, err := validatePoll(&req)
+	if err != nil {
+		return err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow(`SELECT status FROM polls WHERE id = ? FOR UPDATE`, id).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrPollNotFound
+		}
+		return err
+	}
+	if status != PollDraft {
+		return fmt.Errorf("%w: 只有草稿可以修改", ErrPollState)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE polls SET title = ?, description = ?, kind = ?, rule = ?, max_choices = ?, anonymous = ?, audience = ?,
+		                 start_at = ?, end_at = ?
+		WHERE id = ?`,
+		req.Title, req.Description, req.Kind, req.Rule, req.MaxChoices, req.Anonymous, req.Audience, startAt, endAt, id,
+	); err != nil {
+		return err
+	}
+	if err := savePollDetail(tx, id, req); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const pollColumns = `
+	p.id, p.community_id, p.title, p.description, p.kind, p.rule, p.max_choices, p.anonymous, p.audience, p.status, p.start_at, p.end_at,
+	p.eligible_count, p.eligible_area, (SELECT COUNT(*) FROM poll_participants pp WHERE pp.poll_id = p.id),
+	p.created_by, p.opened_at, p.closed_at, COALESCE(p.closed_by, 0), p.created_at, p.updated_at
+`
+
+// scanPoll 解析一行表决数据
+func scanPoll(scanner interface{ Scan(...interface{}) error }, now time.Time) (*Poll, error) {
+	var p Poll
+	var startAt, openedAt, closedAt sql.NullTime
+	err := scanner.Scan(&p.ID, &p.CommunityID, &p.Title, &p.Description, &p.Kind, &p.Rule, &p.MaxChoices, &p.Anonymous, &p.Audience,
+		&p.Status, &startAt, &p.EndAt, &p.EligibleCount, &p.EligibleArea, &p.VotedCount,
+		&p.CreatedBy, &openedAt, &closedAt, &p.ClosedBy, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	p.StartAt = nullTimePtr(startAt)
+	p.OpenedAt = nullTimePtr(openedAt)
+	p.ClosedAt = nullTimePtr(closedAt)
+	p.Status = pollStatus(p.Status, p.EndAt, now)
+
+	return &p, nil
+}
+
+// pollCondition 判断住户r是否在表决p的范围内，表决范围限于表决所在社区
+const pollCondition = `r.community_id = p.community_id AND (p.audience = 'all' OR EXISTS (
+	SELECT 1 FROM poll_blocks b WHERE b.poll_id = p.id AND b.block_number = r.block_number
+))`
+
+// GetPolls 获取表决列表，支持按类型和状态查询，status为ended时返回已过截止时间待结束的表决
+func GetPolls(page, pageSize int, filters map[string]interface{}) ([]Poll, int, error) {
+	now := time.Now()
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND p.community_id = ?`
+		args = append(args, communityID)
+	}
+	if kind, ok := filters["kind"].(string); ok && kind != "" {
+		where += ` AND p.kind = ?`
+		args = append(args, kind)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		switch status {
+		case PollEnded:
+			where += ` AND p.status = ? AND p.end_at <= ?`
+			args = append(args, PollOpen, now)
+		case PollOpen:
+			where += ` AND p.status = ? AND p.end_at > ?`
+			args = append(args, PollOpen, now)
+		default:
+			where += ` AND p.status = ?`
+			args = append(args, status)
+		}
+	}
+
+	return queryPolls(`SELECT COUNT(*) FROM polls p`+where, `SELECT `+pollColumns+` FROM polls p`+where+`
+		ORDER BY p.created_at DESC, p.id DESC LIMIT ? OFFSET ?`, args, page, pageSize, now)
+}
+
+// GetResidentPolls 住户端：获取本户可参与的、已开始投票的表决，并标记本户是否已投票
+func GetResidentPolls(residentID, page, pageSize int) ([]Poll, int, error) {
+	now := time.Now()
+	where := ` FROM polls p JOIN residents r ON r.id = ?
+		WHERE p.status IN (?, ?) AND ` + pollCondition
+	args := []interface{}{residentID, PollOpen, PollClosed}
+
+	polls, total, err := queryPolls(`SELECT COUNT(*)`+where, `SELECT `+pollColumns+where+`
+		ORDER BY p.status = 'open' DESC, p.end_at DESC LIMIT ? OFFSET ?`, args, page, pageSize, now)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range polls {
+		if err := fillResidentBallot(&polls[i], residentID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return polls, total, nil
+}
+
+// queryPolls 执行表决列表查询
+func queryPolls(countQuery, query string, args []interface{}, page, pageSize int, now time.Time) ([]Poll, int, error) {
+	var total int
+	if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	polls := []Poll{}
+	for rows.Next() {
+		p, err := scanPoll(rows, now)
+		if err != nil {
+			return nil, 0, err
+		}
+		polls = append(polls, *p)
+	}
+
+	return polls, total, rows.Err()
+}
+
+// GetPollByID 通过ID获取表决，含选项和楼栋范围
+func GetPollByID(id int) (*Poll, error) {
+	p, err := scanPoll(database.DB.QueryRow(`SELECT `+pollColumns+` FROM polls p WHERE p.id = ?`, id), time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if p.Options, err = getPollOptions(id); err != nil {
+		return nil, err
+	}
+
+	rows, err := database.DB.Query(`SELECT block_number FROM poll_blocks WHERE poll_id = ? ORDER BY block_number`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var block string
+		if err := rows.Scan(&block); err != nil {
+			return nil, err
+		}
+		p.Blocks = append(p.Blocks, block)
+	}
+
+	return p, rows.Err()
+}
+
+// GetResidentPoll 住户端：获取本户可参与的表决详情，不在范围内或未开始投票时返回nil
+func GetResidentPoll(id, residentID int) (*Poll, error) {
+	var count int
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*) FROM polls p JOIN residents r ON r.id = ?
+		WHERE p.id = ? AND p.status IN (?, ?) AND `+pollCondition,
+		residentID, id, PollOpen, PollClosed,
+	).Scan(&count)
+	if err != nil || count == 0 {
+		return nil, err
+	}
+
+	p, err := GetPollByID(id)
+	if err != nil || p == nil {
+		return p, err
+	}
+	if err := fillResidentBallot(p, residentID); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// fillResidentBallot 填写本户是否已投票，实名表决同时返回本户的选择
+func fillResidentBallot(p *Poll, residentID int) error {
+	var count int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM poll_participants WHERE poll_id = ? AND resident_id = ?`,
+		p.ID, residentID).Scan(&count); err != nil {
+		return err
+	}
+	voted := count > 0
+	p.Voted = &voted
+
+	if voted && !p.Anonymous {
+		var choices string
+		err := database.DB.QueryRow(`SELECT choices FROM poll_ballots WHERE poll_id = ? AND resident_id = ?`,
+			p.ID, residentID).Scan(&choices)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		p.MyChoices = splitChoices(choices)
+	}
+
+	return nil
+}
+
+// getPollOptions 获取表决选项
+func getPollOptions(pollID int) ([]PollOption, error) {
+	rows, err := database.DB.Query(`SELECT id, sort_order, content FROM poll_options WHERE poll_id = ? ORDER BY sort_order`, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := []PollOption{}
+	for rows.Next() {
+		var o PollOption
+		if err := rows.Scan(&o.ID, &o.SortOrder, &o.Content); err != nil {
+			return nil, err
+		}
+		options = append(options, o)
+	}
+
+	return options, rows.Err()
+}
+
+// OpenPoll 开始投票，记录此时范围内的户数和房屋面积作为计票基数
+func OpenPoll(id int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var endAt time.Time
+	if err := tx.QueryRow(`SELECT status, end_at FROM polls WHERE id = ? FOR UPDATE`, id).Scan(&status, &endAt); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrPollNotFound
+		}
+		return err
+	}
+	if status != PollDraft {
+		return fmt.Errorf("%w: 只有草稿可以开始投票", ErrPollState)
+	}
+	if !endAt.After(time.Now()) {
+		return fmt.Errorf("%w: 截止时间已过，请修改截止时间", ErrInvalidPoll)
+	}
+
+	var eligibleCount int
+	var eligibleArea float64
+	if err := tx.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(r.house_area), 0) FROM residents r JOIN polls p ON p.id = ?
+		WHERE `+pollCondition, id).Scan(&eligibleCount, &eligibleArea); err != nil {
+		return err
+	}
+	if eligibleCount == 0 {
+		return fmt.Errorf("%w: 表决范围内没有住户", ErrInvalidPoll)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE polls SET status = ?, eligible_count = ?, eligible_area = ?, opened_at = NOW()
+		WHERE id = ?`,
+		PollOpen, eligibleCount, roundAmount(eligibleArea), id,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CancelPoll 取消草稿或投票中的表决，已投的选票保留但不再计票
+func CancelPoll(id int) error {
+	result, err := database.DB.Exec(`UPDATE polls SET status = ? WHERE id = ? AND status IN (?, ?)`,
+		PollCancelled, id, PollDraft, PollOpen)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		p, err := GetPollByID(id)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return ErrPollNotFound
+		}
+		return fmt.Errorf("%w: 已结束或已取消的表决不能取消", ErrPollState)
+	}
+
+	return nil
+}
+
+// CastBallot 为住户投票，每户一票，选票追加到表决的哈希链末尾
+// 同一表决的投票逐个处理，保证哈希链的顺序
+func CastBallot(pollID, residentID int, choices []int, source string, castBy uint) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var maxChoices int
+	var anonymous bool
+	var startAt sql.NullTime
+	var endAt time.Time
+	err = tx.QueryRow(`SELECT status, max_choices, anonymous, start_at, end_at FROM polls WHERE id = ? FOR UPDATE`, pollID).
+		Scan(&status, &maxChoices, &anonymous, &startAt, &endAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrPollNotFound
+		}
+		return err
+	}
+	now := time.Now()
+	if status != PollOpen {
+		return fmt.Errorf("%w: 表决不在投票中", ErrPollState)
+	}
+	if startAt.Valid && startAt.Time.After(now) {
+		return fmt.Errorf("%w: 尚未到开始投票时间", ErrPollState)
+	}
+	if !endAt.After(now) {
+		return fmt.Errorf("%w: 已过投票截止时间", ErrPollState)
+	}
+
+	var area float64
+	err = tx.QueryRow(`
+		SELECT r.house_area FROM residents r JOIN polls p ON p.id = ?
+		WHERE r.id = ? AND `+pollCondition, pollID, residentID).Scan(&area)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrPollNotEligible
+		}
+		return err
+	}
+
+	var voted int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM poll_participants WHERE poll_id = ? AND resident_id = ?`,
+		pollID, residentID).Scan(&voted); err != nil {
+		return err
+	}
+	if voted > 0 {
+		return ErrAlreadyVoted
+	}
+
+	joined := joinChoices(choices)
+	selected := splitChoices(joined)
+	if len(selected) > maxChoices {
+		return fmt.Errorf("%w: 最多可选%d项", ErrInvalidPoll, maxChoices)
+	}
+	var valid int
+	query := `SELECT COUNT(*) FROM poll_options WHERE poll_id = ? AND id IN (?` + strings.Repeat(", ?", len(selected)-1) + `)`
+	args := []interface{}{pollID}
+	for _, id := range selected {
+		args = append(args, id)
+	}
+	if err := tx.QueryRow(query, args...).Scan(&valid); err != nil {
+		return err
+	}
+	if valid != len(selected) {
+		return fmt.Errorf("%w: 无效的选项", ErrInvalidPoll)
+	}
+
+	// 参与记录不保存登记顺序，匿名选票无法按先后顺序对应到住户
+	if _, err := tx.Exec(`INSERT INTO poll_participants (poll_id, resident_id, source, cast_by) VALUES (?, ?, ?, ?)`,
+		pollID, residentID, source, castBy); err != nil {
+		return err
+	}
+
+	seq, prevHash := 0, genesisHash
+	err = tx.QueryRow(`SELECT seq, hash FROM poll_ballots WHERE poll_id = ? ORDER BY seq DESC LIMIT 1`, pollID).Scan(&seq, &prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	seq++
+
+	area = roundAmount(area)
+	voter := sql.NullInt64{}
+	ballotArea := area
+	if anonymous {
+		// 面积独特的房屋可以凭面积对应到选票，匿名选票不保存面积，只累加到表决和所选选项的面积合计中
+		ballotArea = 0
+		if _, err := tx.Exec(`UPDATE polls SET voted_area = voted_area + ? WHERE id = ?`, area, pollID); err != nil {
+			return err
+		}
+		query := `UPDATE poll_options SET area = area + ? WHERE poll_id = ? AND id IN (?` + strings.Repeat(", ?", len(selected)-1) + `)`
+		if _, err := tx.Exec(query, append([]interface{}{area}, args...)...); err != nil {
+			return err
+		}
+	} else {
+		voter = sql.NullInt64{Int64: int64(residentID), Valid: true}
+	}
+
+	hash := ballotHash(prevHash, pollID, seq, voterKey(voter), ballotArea, joined)
+	if _, err := tx.Exec(`
+		INSERT INTO poll_ballots (poll_id, seq, resident_id, area, choices, prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		pollID, seq, voter, ballotArea, joined, prevHash, hash,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// voterKey 返回选票哈希中的住户标识，匿名选票为空
+func voterKey(residentID sql.NullInt64) string {
+	if !residentID.Valid {
+		return ""
+	}
+	return strconv.FormatInt(residentID.Int64, 10)
+}
+
+// ballotRow 表示参与计票和校验的一行选票
+type ballotRow struct {
+	seq        int
+	residentID sql.NullInt64
+	area       float64
+	choices    string
+	prevHash   string
+	hash       string
+}
+
+// loadBallots 按顺序读取表决的全部选票
+func loadBallots(q interface {
+	Query(string, ...interface{}) (*sql.Rows, error)
+}, pollID int) ([]ballotRow, error) {
+	rows, err := q.Query(`
+		SELECT seq, resident_id, area, choices, prev_hash, hash FROM poll_ballots WHERE poll_id = ? ORDER BY seq`, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ballots []ballotRow
+	for rows.Next() {
+		var b ballotRow
+		if err := rows.Scan(&b.seq, &b.residentID, &b.area, &b.choices, &b.prevHash, &b.hash); err != nil {
+			return nil, err
+		}
+		ballots = append(ballots, b)
+	}
+
+	return ballots, rows.Err()
+}
+
+// anonymousAreas 表示匿名表决累计的参与面积和各选项面积
+type anonymousAreas struct {
+	voted   float64
+	options map[int]float64
+}
+
+// loadAnonymousAreas 读取匿名表决的面积合计，实名表决返回nil，按选票上的面积计票
+func loadAnonymousAreas(q interface {
+	Query(string, ...interface{}) (*sql.Rows, error)
+}, p *Poll) (*anonymousAreas, error) {
+	if !p.Anonymous {
+		return nil, nil
+	}
+
+	rows, err := q.Query(`
+		SELECT 0, voted_area FROM polls WHERE id = ?
+		UNION ALL
+		SELECT id, area FROM poll_options WHERE poll_id = ?`, p.ID, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	areas := &anonymousAreas{options: make(map[int]float64)}
+	for rows.Next() {
+		var id int
+		var area float64
+		if err := rows.Scan(&id, &area); err != nil {
+			return nil, err
+		}
+		if id == 0 {
+			areas.voted = area
+		} else {
+			areas.options[id] = area
+		}
+	}
+
+	return areas, rows.Err()
+}
+
+// computeTally 按户数和面积计票，业主表决按通过规则判断是否通过
+// 匿名表决的选票不含面积，面积取自areas中的合计
+// 参与表决的户数和面积均需达到三分之二；一般事项同意需超过参与户数和面积的一半，重大事项需达到四分之三
+func computeTally(p *Poll, options []PollOption, ballots []ballotRow, areas *anonymousAreas) PollTally {
+	tally := PollTally{
+		EligibleCount: p.EligibleCount,
+		EligibleArea:  p.EligibleArea,
+		Options:       make([]PollOptionTally, len(options)),
+	}
+	index := make(map[int]int, len(options))
+	for i, o := range options {
+		tally.Options[i] = PollOptionTally{OptionID: o.ID, Content: o.Content}
+		index[o.ID] = i
+	}
+
+	for _, b := range ballots {
+		tally.VotedCount++
+		tally.VotedArea += b.area
+		for _, id := range splitChoices(b.choices) {
+			if i, ok := index[id]; ok {
+				tally.Options[i].Count++
+				tally.Options[i].Area = roundAmount(tally.Options[i].Area + b.area)
+			}
+		}
+	}
+	tally.VotedArea = roundAmount(tally.VotedArea)
+	if areas != nil {
+		tally.VotedArea = roundAmount(areas.voted)
+		for i := range tally.Options {
+			tally.Options[i].Area = roundAmount(areas.options[tally.Options[i].OptionID])
+		}
+	}
+
+	if p.Kind != PollVote || len(tally.Options) == 0 {
+		return tally
+	}
+
+	agree := tally.Options[0]
+	passed := false
+	switch {
+	case tally.VotedCount*3 < tally.EligibleCount*2 || tally.VotedArea*3 < tally.EligibleArea*2:
+		tally.Reason = "参与表决的户数或专有面积未达到三分之二"
+	case p.Rule == PollRuleSpecial && (agree.Count*4 < tally.VotedCount*3 || agree.Area*4 < tally.VotedArea*3):
+		tally.Reason = "同意的户数或面积未达到参与表决的四分之三"
+	case p.Rule != PollRuleSpecial && (agree.Count*2 <= tally.VotedCount || agree.Area*2 <= tally.VotedArea):
+		tally.Reason = "同意的户数或面积未超过参与表决的一半"
+	default:
+		passed = true
+		tally.Reason = "同意的户数和面积均达到通过标准"
+	}
+	tally.Passed = &passed
+
+	return tally
+}
+
+// GetPollTally 获取投票中表决的实时计票结果
+func GetPollTally(p *Poll) (*PollTally, error) {
+	ballots, err := loadBallots(database.DB, p.ID)
+	if err != nil {
+		return nil, err
+	}
+	areas, err := loadAnonymousAreas(database.DB, p)
+	if err != nil {
+		return nil, err
+	}
+	tally := computeTally(p, p.Options, ballots, areas)
+	return &tally, nil
+}
+
+// ClosePoll 结束投票并写入表决结果，结果哈希串联最后一张选票的哈希，写入后不再修改
+func ClosePoll(id int, userID uint) (*PollResult, error) {
+	p, err := GetPollByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrPollNotFound
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow(`SELECT status FROM polls WHERE id = ? FOR UPDATE`, id).Scan(&status); err != nil {
+		return nil, err
+	}
+	if status != PollOpen {
+		return nil, fmt.Errorf("%w: 只有投票中的表决可以结束", ErrPollState)
+	}
+
+	ballots, err := loadBallots(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	areas, err := loadAnonymousAreas(tx, p)
+	if err != nil {
+		return nil, err
+	}
+	tally := computeTally(p, p.Options, ballots, areas)
+	data, err := json.Marshal(tally)
+	if err != nil {
+		return nil, err
+	}
+
+	lastHash := genesisHash
+	if len(ballots) > 0 {
+		lastHash = ballots[len(ballots)-1].hash
+	}
+	var passed sql.NullBool
+	if tally.Passed != nil {
+		passed = sql.NullBool{Bool: *tally.Passed, Valid: true}
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO poll_results (poll_id, tally, passed, ballot_count, last_hash, result_hash)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		id, string(data), passed, len(ballots), lastHash, resultHash(lastHash, string(data)),
+	); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE polls SET status = ?, closed_at = NOW(), closed_by = ? WHERE id = ?`,
+		PollClosed, userID, id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return GetPollResult(id)
+}
+
+// GetPollResult 获取已结束表决的结果
+func GetPollResult(pollID int) (*PollResult, error) {
+	var r PollResult
+	var tally string
+	var passed sql.NullBool
+	err := database.DB.QueryRow(`
+		SELECT poll_id, tally, passed, ballot_count, last_hash, result_hash, created_at
+		FROM poll_results WHERE poll_id = ?`, pollID).
+		Scan(&r.PollID, &tally, &passed, &r.BallotCount, &r.LastHash, &r.ResultHash, &r.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(tally), &r.Tally); err != nil {
+		return nil, err
+	}
+	if passed.Valid {
+		r.Passed = &passed.Bool
+	}
+
+	return &r, nil
+}
+
+// GetPollBallots 获取表决的选票，匿名表决不返回住户信息
+func GetPollBallots(pollID, page, pageSize int) ([]PollBallot, int, error) {
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM poll_ballots WHERE poll_id = ?`, pollID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT b.seq, COALESCE(b.resident_id, 0), COALESCE(r.name, ''), COALESCE(r.block_number, ''),
+		       COALESCE(r.unit_number, ''), COALESCE(r.house_number, ''), b.area, b.choices, b.prev_hash, b.hash
+		FROM poll_ballots b
+		LEFT JOIN residents r ON b.resident_id = r.id
+		WHERE b.poll_id = ?
+		ORDER BY b.seq LIMIT ? OFFSET ?`, pollID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	ballots := []PollBallot{}
+	for rows.Next() {
+		var b PollBallot
+		var choices string
+		if err := rows.Scan(&b.Seq, &b.ResidentID, &b.ResidentName, &b.BlockNumber, &b.UnitNumber, &b.HouseNumber,
+			&b.Area, &choices, &b.PrevHash, &b.Hash); err != nil {
+			return nil, 0, err
+		}
+		b.Choices = splitChoices(choices)
+		ballots = append(ballots, b)
+	}
+
+	return ballots, total, rows.Err()
+}
+
+// VerifyPoll 重新计算选票哈希链，已结束的表决同时重新计票并校验结果哈希
+func VerifyPoll(p *Poll) (*PollVerification, error) {
+	ballots, err := loadBallots(database.DB, p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &PollVerification{BallotCount: len(ballots)}
+	prevHash := genesisHash
+	for i, b := range ballots {
+		if b.seq != i+1 || b.prevHash != prevHash ||
+			b.hash != ballotHash(prevHash, p.ID, b.seq, voterKey(b.residentID), b.area, b.choices) {
+			v.BrokenSeq = i + 1
+			v.Message = fmt.Sprintf("第%d张选票校验失败，选票可能被篡改", i+1)
+			return v, nil
+		}
+		prevHash = b.hash
+	}
+
+	var participants int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM poll_participants WHERE poll_id = ?`, p.ID).Scan(&participants); err != nil {
+		return nil, err
+	}
+	if participants != len(ballots) {
+		v.Message = fmt.Sprintf("参与记录%d户与选票%d张不一致", participants, len(ballots))
+		return v, nil
+	}
+
+	if p.Status == PollClosed {
+		result, err := GetPollResult(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			v.Message = "已结束的表决缺少结果记录"
+			return v, nil
+		}
+		areas, err := loadAnonymousAreas(database.DB, p)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(computeTally(p, p.Options, ballots, areas))
+		if err != nil {
+			return nil, err
+		}
+		if result.BallotCount != len(ballots) || result.LastHash != prevHash ||
+			result.ResultHash != resultHash(prevHash, string(data)) {
+			v.Message = "表决结果与选票不一致，结果记录可能被篡改"
+			return v, nil
+		}
+	}
+
+	v.Valid = true
+	v.Message = "选票哈希链和表决结果校验通过"
+	return v, nil
+}
@@ -2,31 +2,61 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"community-backward/database"
 )
 
+// 缴费状态
+const (
+	PaymentPaid     = 1 // 已缴
+	PaymentRefunded = 2 // 已退款
+)
+
+// ErrPaymentNotRefundable 缴费记录不是已缴状态，不能退款
+var ErrPaymentNotRefundable = errors.New("只有已缴状态的缴费记录可以退款")
+
 // PropertyFee 表示物业费模型
 type PropertyFee struct {
 	ID            int       `json:"id"`
 	ResidentID    int       `json:"resident_id"`
 	ResidentName  string    `json:"resident_name,omitempty"`
+	FeeTypeID     int       `json:"fee_type_id"`
+	FeeTypeName   string    `json:"fee_type_name,omitempty"`
 	Amount        float64   `json:"amount"`
 	PaymentDate   time.Time `json:"payment_date"`
 	PaymentMethod string    `json:"payment_method"`
 	PaymentStatus int       `json:"payment_status"`
+	RefundedAt    string    `json:"refunded_at,omitempty"`
 	Remark        string    `json:"remark,omitempty"`
 	CreatedAt     time.Time `json:"created_at,omitempty"`
 	UpdatedAt     time.Time `json:"updated_at,omitempty"`
 }
 
+// PropertyFeeRequest 表示登记缴费请求
+type PropertyFeeRequest struct {
+	ResidentID    int     `json:"resident_id" binding:"required"`
+	FeeTypeID     int     `json:"fee_type_id"` // 缺省为物业费
+	Amount        float64 `json:"amount" binding:"required,gt=0"`
+	PaymentDate   string  `json:"payment_date" binding:"required"` // 格式: 2006-01-02
+	PaymentMethod string  `json:"payment_method" binding:"required"`
+	Remark        string  `json:"remark"`
+}
+
+// RefundRequest 表示缴费退款请求
+type RefundRequest struct {
+	RefundDate string `json:"refund_date" binding:"required"` // 格式: 2006-01-02
+	Reason     string `json:"reason" binding:"required"`
+}
+
 // PropertyFeeMonthlyStats 表示物业费月度统计模型
 type PropertyFeeMonthlyStats struct {
 	ID            int       `json:"id"`
 	Year          int       `json:"year"`
 	Month         int       `json:"month"`
+	FeeTypeID     int       `json:"fee_type_id"`
 	ActualIncome  float64   `json:"actual_income"`
 	PlannedIncome float64   `json:"planned_income"`
 	CreatedAt     time.Time `json:"created_at,omitempty"`
@@ -35,10 +65,11 @@ type PropertyFeeMonthlyStats struct {
 
 // DashboardStats 表示仪表盘统计数据
 type DashboardStats struct {
-	ResidentCount  int     `json:"residentCount"`
-	YearlyIncome   float64 `json:"yearlyIncome"`
-	PendingTickets int     `json:"pendingTickets"`
-	ParkingRate    string  `json:"parkingRate"`
+	ResidentCount  int             `json:"residentCount"`
+	YearlyIncome   float64         `json:"yearlyIncome"`
+	IncomeByType   []FeeTypeIncome `json:"incomeByType"`
+	PendingTickets int             `json:"pendingTickets"`
+	ParkingRate    string          `json:"parkingRate"`
 }
 
 // IncomeData 表示收入趋势数据
@@ -47,13 +78,24 @@ type IncomeData struct {
 	Planned []float64 `json:"planned"`
 }
 
-// GetDashboardStats 获取仪表盘统计数据
-func GetDashboardStats() (*DashboardStats, error) {
-	fmt.Println("开始获取仪表盘统计数据")
+// FeeTypeIncome 表示某一收费项目的收入
+type FeeTypeIncome struct {
+	FeeTypeID          int       `json:"feeTypeId"`
+	Code               string    `json:"code"`
+	Name               string    `json:"name"`
+	AccountingCategory string    `json:"accountingCategory"`
+	Total              float64   `json:"total"`
+	Actual             []float64 `json:"actual,omitempty"`
+	Planned            []float64 `json:"planned,omitempty"`
+}
+
+// GetDashboardStats 获取指定社区的仪表盘统计数据
+func GetDashboardStats(communityID int) (*DashboardStats, error) {
+	fmt.Printf("开始获取社区%d的仪表盘统计数据\n", communityID)
 
 	// 获取住户总数
 	var residentCount int
-	err := database.DB.QueryRow("SELECT COUNT(*) FROM residents").Scan(&residentCount)
+	err := database.DB.QueryRow("SELECT COUNT(*) FROM residents WHERE community_id = ?", communityID).Scan(&residentCount)
 	if err != nil {
 		fmt.Printf("获取住户总数失败: %v\n", err)
 		return nil, fmt.Errorf("获取住户总数失败: %w", err)
@@ -69,7 +111,7 @@ func GetDashboardStats() (*DashboardStats, error) {
 	err = database.DB.QueryRow(`
 		SELECT COUNT(*)
 		FROM information_schema.tables
-		WHERE table_schema = 'community' AND table_name = 'property_fees'
+		WHERE table_schema = DATABASE() AND table_name = 'property_fees'
 	`).Scan(&tableExists)
 
 	if err != nil {
@@ -78,15 +120,17 @@ func GetDashboardStats() (*DashboardStats, error) {
 		fmt.Println("property_fees表不存在，使用默认物业费收入")
 		yearlyIncome = 89540.00
 	} else {
-		// 表存在，查询物业费收入
+		// 表存在，查询物业费收入，押金需要退还，不计入收入
 		query := `
-			SELECT COALESCE(SUM(amount), 0)
-			FROM property_fees
-			WHERE YEAR(payment_date) = ? AND payment_status = 1
+			SELECT COALESCE(SUM(pf.amount), 0)
+			FROM property_fees pf
+			JOIN residents r ON pf.resident_id = r.id
+			JOIN fee_types t ON pf.fee_type_id = t.id
+			WHERE YEAR(pf.payment_date) = ? AND pf.payment_status = 1 AND r.community_id = ? AND t.pricing_rule <> 'deposit'
 		`
-		fmt.Printf("执行查询: %s, 参数: %d\n", query, currentYear)
+		fmt.Printf("执行查询: %s, 参数: %d, %d\n", query, currentYear, communityID)
 
-		err = database.DB.QueryRow(query, currentYear).Scan(&yearlyIncome)
+		err = database.DB.QueryRow(query, currentYear, communityID).Scan(&yearlyIncome)
 		if err != nil {
 			fmt.Printf("获取本年物业费收入失败: %v\n", err)
 			// 使用默认值
@@ -95,18 +139,36 @@ func GetDashboardStats() (*DashboardStats, error) {
 	}
 	fmt.Printf("本年物业费收入: %.2f\n", yearlyIncome)
 
-	// 获取待处理工单数量（这里使用模拟数据）
-	pendingTickets := 15
+	// 按收费项目拆分本年收入
+	incomeByType, err := getYearlyIncomeByType(currentYear, communityID)
+	if err != nil {
+		fmt.Printf("获取本年分项收入失败: %v\n", err)
+		incomeByType = []FeeTypeIncome{}
+	}
+
+	// 获取待处理工单数量（待指派、已指派和处理中）
+	pendingTickets, err := CountPendingWorkOrders(communityID)
+	if err != nil {
+		fmt.Printf("获取待处理工单数量失败: %v\n", err)
+		pendingTickets = 0
+	}
 	fmt.Printf("待处理工单数量: %d\n", pendingTickets)
 
-	// 获取车位使用率（这里使用模拟数据）
+	// 获取车位使用率，没有车位时显示为--
 	parkingRate := "--"
+	occupancy, err := GetParkingOccupancy(time.Now(), communityID)
+	if err != nil {
+		fmt.Printf("获取车位使用率失败: %v\n", err)
+	} else if occupancy.Total > 0 {
+		parkingRate = fmt.Sprintf("%.1f", occupancy.Rate)
+	}
 	fmt.Printf("车位使用率: %s%%\n", parkingRate)
 
 	// 构建返回数据
 	stats := &DashboardStats{
 		ResidentCount:  residentCount,
 		YearlyIncome:   yearlyIncome,
+		IncomeByType:   incomeByType,
 		PendingTickets: pendingTickets,
 		ParkingRate:    parkingRate,
 	}
@@ -115,8 +177,8 @@ func GetDashboardStats() (*DashboardStats, error) {
 	return stats, nil
 }
 
-// GetIncomeData 获取物业费收入趋势数据
-func GetIncomeData(year int) (*IncomeData, error) {
+// GetIncomeData 获取物业费收入趋势数据，feeTypeID为0时汇总所有收费项目，communityID为0时汇总所有社区
+func GetIncomeData(year, feeTypeID, communityID int) (*IncomeData, error) {
 	fmt.Printf("开始获取%d年物业费收入趋势数据\n", year)
 
 	// 首先检查property_fee_monthly_stats表是否存在
@@ -124,7 +186,7 @@ func GetIncomeData(year int) (*IncomeData, error) {
 	err := database.DB.QueryRow(`
 		SELECT COUNT(*)
 		FROM information_schema.tables
-		WHERE table_schema = 'community' AND table_name = 'property_fee_monthly_stats'
+		WHERE table_schema = DATABASE() AND table_name = 'property_fee_monthly_stats'
 	`).Scan(&tableExists)
 
 	// 初始化12个月的数据
@@ -144,14 +206,23 @@ func GetIncomeData(year int) (*IncomeData, error) {
 
 	// 查询指定年份的月度统计数据
 	query := `
-		SELECT month, actual_income, planned_income
+		SELECT month, SUM(actual_income), SUM(planned_income)
 		FROM property_fee_monthly_stats
 		WHERE year = ?
-		ORDER BY month ASC
 	`
-	fmt.Printf("执行查询: %s, 参数: %d\n", query, year)
+	args := []interface{}{year}
+	if feeTypeID > 0 {
+		query += ` AND fee_type_id = ?`
+		args = append(args, feeTypeID)
+	}
+	if communityID > 0 {
+		query += ` AND community_id = ?`
+		args = append(args, communityID)
+	}
+	query += ` GROUP BY month ORDER BY month ASC`
+	fmt.Printf("执行查询: %s, 参数: %v\n", query, args)
 
-	rows, err := database.DB.Query(query, year)
+	rows, err := database.DB.Query(query, args...)
 	if err != nil {
 		fmt.Printf("获取物业费收入趋势数据失败: %v\n", err)
 		// 使用默认数据
@@ -212,10 +283,11 @@ func useDefaultData(actual, planned []float64) {
 func GetPropertyFees(page, pageSize int, filters map[string]interface{}) ([]PropertyFee, int, error) {
 	// 构建查询
 	query := `
-		SELECT pf.id, pf.resident_id, r.name, pf.amount, pf.payment_date,
+		SELECT pf.id, pf.resident_id, r.name, pf.fee_type_id, t.name, pf.amount, pf.payment_date,
 		       pf.payment_method, pf.payment_status, pf.remark, pf.created_at, pf.updated_at
 		FROM property_fees pf
 		JOIN residents r ON pf.resident_id = r.id
+		JOIN fee_types t ON pf.fee_type_id = t.id
 		WHERE 1=1
 	`
 	countQuery := `SELECT COUNT(*) FROM property_fees pf JOIN residents r ON pf.resident_id = r.id WHERE 1=1`
@@ -232,6 +304,13 @@ func GetPropertyFees(page, pageSize int, filters map[string]interface{}) ([]Prop
 		countArgs = append(countArgs, residentID)
 	}
 
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		query += ` AND r.community_id = ?`
+		countQuery += ` AND r.community_id = ?`
+		args = append(args, communityID)
+		countArgs = append(countArgs, communityID)
+	}
+
 	if residentName, ok := filters["resident_name"].(string); ok && residentName != "" {
 		query += ` AND r.name LIKE ?`
 		countQuery += ` AND r.name LIKE ?`
@@ -239,6 +318,13 @@ func GetPropertyFees(page, pageSize int, filters map[string]interface{}) ([]Prop
 		countArgs = append(countArgs, "%"+residentName+"%")
 	}
 
+	if feeTypeID, ok := filters["fee_type_id"].(int); ok && feeTypeID > 0 {
+		query += ` AND pf.fee_type_id = ?`
+		countQuery += ` AND pf.fee_type_id = ?`
+		args = append(args, feeTypeID)
+		countArgs = append(countArgs, feeTypeID)
+	}
+
 	if startDate, ok := filters["start_date"].(time.Time); ok {
 		query += ` AND pf.payment_date >= ?`
 		countQuery += ` AND pf.payment_date >= ?`
@@ -292,6 +378,8 @@ func GetPropertyFees(page, pageSize int, filters map[string]interface{}) ([]Prop
 			&fee.ID,
 			&fee.ResidentID,
 			&fee.ResidentName,
+			&fee.FeeTypeID,
+			&fee.FeeTypeName,
 			&fee.Amount,
 			&paymentDate,
 			&fee.PaymentMethod,
@@ -317,3 +405,312 @@ func GetPropertyFees(page, pageSize int, filters map[string]interface{}) ([]Prop
 
 	return fees, total, nil
 }
+
+// GetPropertyFeeByID 通过ID获取缴费记录
+func GetPropertyFeeByID(id int) (*PropertyFee, error) {
+	return getPropertyFeeByID(database.DB, id)
+}
+
+// getPropertyFeeByID 通过ID获取缴费记录，q为*sql.DB或*sql.Tx，记账时在业务事务内读取
+func getPropertyFeeByID(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, id int) (*PropertyFee, error) {
+	query := `
+		SELECT pf.id, pf.resident_id, r.name, pf.fee_type_id, t.name, pf.amount, pf.payment_date,
+		       pf.payment_method, pf.payment_status, pf.refunded_at, pf.remark, pf.created_at, pf.updated_at
+		FROM property_fees pf
+		JOIN residents r ON pf.resident_id = r.id
+		JOIN fee_types t ON pf.fee_type_id = t.id
+		WHERE pf.id = ?
+	`
+
+	var fee PropertyFee
+	var refundedAt sql.NullTime
+	var remark sql.NullString
+	err := q.QueryRow(query, id).Scan(
+		&fee.ID,
+		&fee.ResidentID,
+		&fee.ResidentName,
+		&fee.FeeTypeID,
+		&fee.FeeTypeName,
+		&fee.Amount,
+		&fee.PaymentDate,
+		&fee.PaymentMethod,
+		&fee.PaymentStatus,
+		&refundedAt,
+		&remark,
+		&fee.CreatedAt,
+		&fee.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // 缴费记录不存在
+		}
+		return nil, err
+	}
+
+	if refundedAt.Valid {
+		fee.RefundedAt = refundedAt.Time.Format("2006-01-02")
+	}
+	if remark.Valid {
+		fee.Remark = remark.String
+	}
+
+	return &fee, nil
+}
+
+// CreatePropertyFee 登记缴费，并刷新该月的月度统计
+func CreatePropertyFee(req PropertyFeeRequest) (int, error) {
+	paymentDate, err := time.ParseInLocation("2006-01-02", req.PaymentDate, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("无效的缴费日期: %s", req.PaymentDate)
+	}
+
+	feeTypeID := req.FeeTypeID
+	if feeTypeID == 0 {
+		feeTypeID = DefaultFeeTypeID
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// 不允许补录到住户所属社区已结账的账期
+	communityID, err := residentCommunityTx(tx, req.ResidentID)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkDateOpenTx(tx, communityID, paymentDate); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO property_fees (resident_id, fee_type_id, amount, payment_date, payment_method, payment_status, remark)
+		VALUES (?, ?, ?, ?, ?, 1, ?)`,
+		req.ResidentID, feeTypeID, req.Amount, paymentDate, req.PaymentMethod, req.Remark,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := postPaymentTx(tx, int(id)); err != nil {
+		return 0, fmt.Errorf("缴费记账失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if err := RefreshMonthlyStats(communityID, paymentDate.Year(), int(paymentDate.Month())); err != nil {
+		fmt.Printf("刷新月度统计失败: %v\n", err)
+	}
+
+	return int(id), nil
+}
+
+// RefundPropertyFee 缴费退款，退款后该笔缴费不再冲抵账单，并冲回收款凭证
+// 退款日期所在账期已结账时返回ErrPeriodClosed
+func RefundPropertyFee(id int, req RefundRequest) error {
+	refundDate, err := time.ParseInLocation("2006-01-02", req.RefundDate, time.Local)
+	if err != nil {
+		return fmt.Errorf("无效的退款日期: %s", req.RefundDate)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	paymentDate, communityID, err := refundPropertyFeeTx(tx, id, refundDate, req.Reason)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	refreshRefundStats(communityID, paymentDate)
+	return nil
+}
+
+// refundPropertyFeeTx 在事务中退款并冲回收款凭证，返回原缴费日期和缴费住户所属社区
+// 缴费记录不是已缴状态时返回ErrPaymentNotRefundable，记账失败时整笔退款失败
+func refundPropertyFeeTx(tx *sql.Tx, id int, refundDate time.Time, reason string) (time.Time, int, error) {
+	var paymentDate time.Time
+	var status, communityID int
+	err := tx.QueryRow(`
+		SELECT pf.payment_date, pf.payment_status, r.community_id
+		FROM property_fees pf JOIN residents r ON pf.resident_id = r.id
+		WHERE pf.id = ? FOR UPDATE OF pf`, id).
+		Scan(&paymentDate, &status, &communityID)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if err := checkDateOpenTx(tx, communityID, refundDate); err != nil {
+		return time.Time{}, 0, err
+	}
+	if status != PaymentPaid {
+		return time.Time{}, 0, ErrPaymentNotRefundable
+	}
+	if refundDate.Before(paymentDate) {
+		return time.Time{}, 0, fmt.Errorf("退款日期不能早于缴费日期")
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE property_fees
+		SET payment_status = ?, refunded_at = ?, remark = CONCAT(COALESCE(remark, ''), ?)
+		WHERE id = ?`,
+		PaymentRefunded, refundDate, " 退款原因: "+reason, id,
+	); err != nil {
+		return time.Time{}, 0, err
+	}
+
+	if err := postRefundTx(tx, id, refundDate); err != nil {
+		return time.Time{}, 0, fmt.Errorf("退款记账失败: %w", err)
+	}
+
+	return paymentDate, communityID, nil
+}
+
+// refreshRefundStats 退款提交后刷新缴费住户所属社区原缴费所在月份的统计
+func refreshRefundStats(communityID int, paymentDate time.Time) {
+	// 缴费所在月份已结账时统计保持冻结
+	if err := RefreshMonthlyStats(communityID, paymentDate.Year(), int(paymentDate.Month())); err != nil && !errors.Is(err, ErrPeriodClosed) {
+		fmt.Printf("刷新月度统计失败: %v\n", err)
+	}
+}
+
+// RefreshMonthlyStats 按收费项目重新计算指定社区指定月份的月度统计
+// 实际收入取自当月缴费记录，计划收入取自当月账单，均按住户所属社区归集
+// 押金需要退还，不是收入，押金类收费项目不生成统计
+// 该社区已结账账期的统计已冻结，返回ErrPeriodClosed
+func RefreshMonthlyStats(communityID, year, month int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkPeriodOpenTx(tx, communityID, year, month); err != nil {
+		return err
+	}
+	if err := refreshMonthlyStats(tx, communityID, year, month); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// refreshMonthlyStats 在事务中重新计算指定社区指定月份的月度统计，调用方负责检查账期
+func refreshMonthlyStats(tx *sql.Tx, communityID, year, month int) error {
+	_, err := tx.Exec(`
+		INSERT INTO property_fee_monthly_stats (community_id, year, month, fee_type_id, actual_income, planned_income)
+		SELECT c.id, ?, ?, t.id,
+		       (SELECT COALESCE(SUM(pf.amount), 0) FROM property_fees pf JOIN residents r ON pf.resident_id = r.id
+		        WHERE r.community_id = c.id AND pf.fee_type_id = t.id AND pf.payment_status = 1
+		          AND YEAR(pf.payment_date) = ? AND MONTH(pf.payment_date) = ?),
+		       (SELECT COALESCE(SUM(b.amount), 0) FROM bills b JOIN residents r ON b.resident_id = r.id
+		        WHERE r.community_id = c.id AND b.fee_type_id = t.id AND b.year = ? AND b.month = ?)
+		FROM communities c CROSS JOIN fee_types t
+		WHERE c.id = ? AND t.pricing_rule <> 'deposit'
+		ON DUPLICATE KEY UPDATE actual_income = VALUES(actual_income), planned_income = VALUES(planned_income)`,
+		year, month, year, month, year, month, communityID,
+	)
+	return err
+}
+
+// GetIncomeByType 获取指定年份各收费项目的月度收入，communityID为0时汇总所有社区，不含押金类收费项目
+func GetIncomeByType(year, communityID int) ([]FeeTypeIncome, error) {
+	feeTypes, err := GetFeeTypes(false)
+	if err != nil {
+		return nil, err
+	}
+
+	incomes := make([]FeeTypeIncome, 0, len(feeTypes))
+	index := make(map[int]int)
+	for _, t := range feeTypes {
+		if t.PricingRule == PricingDeposit {
+			continue
+		}
+		index[t.ID] = len(incomes)
+		incomes = append(incomes, FeeTypeIncome{
+			FeeTypeID:          t.ID,
+			Code:               t.Code,
+			Name:               t.Name,
+			AccountingCategory: t.AccountingCategory,
+			Actual:             make([]float64, 12),
+			Planned:            make([]float64, 12),
+		})
+	}
+
+	query := `
+		SELECT fee_type_id, month, SUM(actual_income), SUM(planned_income)
+		FROM property_fee_monthly_stats
+		WHERE year = ?`
+	args := []interface{}{year}
+	if communityID > 0 {
+		query += ` AND community_id = ?`
+		args = append(args, communityID)
+	}
+	query += ` GROUP BY fee_type_id, month`
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var feeTypeID, month int
+		var actual, planned float64
+		if err := rows.Scan(&feeTypeID, &month, &actual, &planned); err != nil {
+			return nil, err
+		}
+		i, ok := index[feeTypeID]
+		if !ok || month < 1 || month > 12 {
+			continue
+		}
+		incomes[i].Actual[month-1] = actual
+		incomes[i].Planned[month-1] = planned
+		incomes[i].Total = roundAmount(incomes[i].Total + actual)
+	}
+
+	return incomes, rows.Err()
+}
+
+// getYearlyIncomeByType 按收费项目汇总指定社区指定年份的实收金额，不含押金类收费项目
+func getYearlyIncomeByType(year, communityID int) ([]FeeTypeIncome, error) {
+	rows, err := database.DB.Query(`
+		SELECT t.id, t.code, t.name, t.accounting_category, COALESCE(SUM(pf.amount), 0)
+		FROM fee_types t
+		LEFT JOIN property_fees pf
+		       ON pf.fee_type_id = t.id AND pf.payment_status = 1 AND YEAR(pf.payment_date) = ?
+		      AND pf.resident_id IN (SELECT id FROM residents WHERE community_id = ?)
+		WHERE t.pricing_rule <> 'deposit'
+		GROUP BY t.id, t.code, t.name, t.accounting_category
+		ORDER BY t.id`, year, communityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	incomes := []FeeTypeIncome{}
+	for rows.Next() {
+		var income FeeTypeIncome
+		if err := rows.Scan(&income.FeeTypeID, &income.Code, &income.Name, &income.AccountingCategory, &income.Total); err != nil {
+			return nil, err
+		}
+		incomes = append(incomes, income)
+	}
+
+	return incomes, rows.Err()
+}
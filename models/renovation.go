@@ -0,0 +1,620 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"community-backward/database"
+)
+
+// RenovationDepositCode 装修押金的收费项目代码
+const RenovationDepositCode = "renovation_deposit"
+
+// 装修申请状态
+const (
+	RenovationSubmitted  = "submitted"   // 待审批
+	RenovationApproved   = "approved"    // 已批准，待缴押金
+	RenovationInProgress = "in_progress" // 施工中
+	RenovationCompleted  = "completed"   // 竣工验收通过，待退押金
+	RenovationClosed     = "closed"      // 已结案
+	RenovationRejected   = "rejected"    // 已驳回
+	RenovationCancelled  = "cancelled"   // 已取消
+)
+
+// 装修押金状态
+const (
+	DepositNone      = "none"      // 未缴
+	DepositHeld      = "held"      // 已缴未退
+	DepositRefunded  = "refunded"  // 已退还
+	DepositForfeited = "forfeited" // 已没收
+)
+
+// 装修验收节点，竣工验收前隐蔽工程和防水均需验收通过
+const (
+	CheckpointHiddenWorks = "hidden_works" // 隐蔽工程验收（水电管线）
+	CheckpointWaterproof  = "waterproof"   // 防水闭水试验
+	CheckpointFinal       = "final"        // 竣工验收
+)
+
+// 装修申请来源
+const (
+	RenovationSourceResident = "resident"
+	RenovationSourceStaff    = "staff"
+)
+
+// renovationCheckpointNames 验收节点名称
+var renovationCheckpointNames = map[string]string{
+	CheckpointHiddenWorks: "隐蔽工程验收",
+	CheckpointWaterproof:  "防水闭水试验",
+	CheckpointFinal:       "竣工验收",
+}
+
+var (
+	// ErrInvalidRenovation 装修申请参数错误
+	ErrInvalidRenovation = errors.New("装修申请参数错误")
+	// ErrRenovationExists 该房屋已有未结束的装修申请
+	ErrRenovationExists = errors.New("该房屋已有未结束的装修申请")
+	// ErrRenovationState 装修申请当前状态不允许该操作
+	ErrRenovationState = errors.New("装修申请当前状态不允许该操作")
+	// ErrRenovationNotFound 装修申请不存在
+	ErrRenovationNotFound = errors.New("装修申请不存在")
+)
+
+// Renovation 表示装修申请
+type Renovation struct {
+	ID                int                    `json:"id"`
+	CommunityID       int                    `json:"community_id"` // 住户所在社区
+	ResidentID        int                    `json:"resident_id"`
+	ResidentName      string                 `json:"resident_name"`
+	Room              string                 `json:"room"`
+	ApplicantName     string                 `json:"applicant_name"`
+	ApplicantPhone    string                 `json:"applicant_phone"`
+	Scope             string                 `json:"scope"` // 施工内容
+	ContractorName    string                 `json:"contractor_name"`
+	ContractorContact string                 `json:"contractor_contact"`
+	ContractorPhone   string                 `json:"contractor_phone"`
+	ContractorLicense string                 `json:"contractor_license"`
+	Workers           int                    `json:"workers"`
+	StartDate         time.Time              `json:"start_date"`
+	EndDate           time.Time              `json:"end_date"`
+	DepositAmount     float64                `json:"deposit_amount"`
+	DepositFeeID      *int                   `json:"deposit_fee_id,omitempty"`
+	DepositStatus     string                 `json:"deposit_status"`
+	Status            string                 `json:"status"`
+	Source            string                 `json:"source"`
+	CreatedBy         int                    `json:"created_by"`
+	ReviewedBy        int                    `json:"reviewed_by,omitempty"`
+	ReviewedAt        *time.Time             `json:"reviewed_at,omitempty"`
+	ReviewNote        string                 `json:"review_note,omitempty"`
+	CompletedAt       *time.Time             `json:"completed_at,omitempty"`
+	ClosedAt          *time.Time             `json:"closed_at,omitempty"`
+	CancelReason      string                 `json:"cancel_reason,omitempty"`
+	ForfeitedAt       *time.Time             `json:"forfeited_at,omitempty"`
+	ForfeitReason     string                 `json:"forfeit_reason,omitempty"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+	Inspections       []RenovationInspection `json:"inspections,omitempty"`
+}
+
+// RenovationRequest 表示装修申请请求
+type RenovationRequest struct {
+	ResidentID        int     `json:"resident_id"` // 物业代为登记时必填，住户端为本户
+	ApplicantName     string  `json:"applicant_name" binding:"required,max=50"`
+	ApplicantPhone    string  `json:"applicant_phone" binding:"required,max=20"`
+	Scope             string  `json:"scope" binding:"required,max=2000"`
+	ContractorName    string  `json:"contractor_name" binding:"required,max=100"`
+	ContractorContact string  `json:"contractor_contact" binding:"required,max=50"`
+	ContractorPhone   string  `json:"contractor_phone" binding:"required,max=20"`
+	ContractorLicense string  `json:"contractor_license" binding:"max=50"`
+	Workers           int     `json:"workers" binding:"min=0"`
+	StartDate         string  `json:"start_date" binding:"required"`  // 格式: 2006-01-02
+	EndDate           string  `json:"end_date" binding:"required"`    // 格式: 2006-01-02
+	DepositAmount     float64 `json:"deposit_amount" binding:"min=0"` // 物业登记时可调整，缺省按装修押金收费项目的单价
+}
+
+// RenovationReviewRequest 表示审批装修申请请求
+type RenovationReviewRequest struct {
+	Approved bool   `json:"approved"`
+	Note     string `json:"note" binding:"max=255"`
+}
+
+// RenovationDepositRequest 表示收取装修押金请求
+type RenovationDepositRequest struct {
+	PaymentMethod string `json:"payment_method" binding:"required"`
+	PaymentDate   string `json:"payment_date"` // 格式: 2006-01-02，缺省为当天
+}
+
+// RenovationInspectionRequest 表示登记装修验收请求
+type RenovationInspectionRequest struct {
+	Checkpoint string `json:"checkpoint" binding:"required,oneof=hidden_works waterproof final"`
+	Result     string `json:"result" binding:"required,oneof=pass fail"`
+	Inspector  string `json:"inspector" binding:"required,max=50"`
+	Notes      string `json:"notes" binding:"max=1000"`
+}
+
+// RenovationReasonRequest 表示取消申请或没收押金请求
+type RenovationReasonRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// RenovationInspection 表示一次装修验收记录
+type RenovationInspection struct {
+	ID             int       `json:"id"`
+	ApplicationID  int       `json:"application_id"`
+	Checkpoint     string    `json:"checkpoint"`
+	CheckpointName string    `json:"checkpoint_name"`
+	Result         string    `json:"result"`
+	Inspector      string    `json:"inspector"`
+	Notes          string    `json:"notes"`
+	CreatedBy      int       `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// activeRenovationStatuses 未结束的装修申请状态，每户同时只能有一个
+var activeRenovationStatuses = []interface{}{RenovationSubmitted, RenovationApproved, RenovationInProgress}
+
+// CreateRenovation 提交装修申请，未指定押金时按装修押金收费项目的单价收取
+func CreateRenovation(req RenovationRequest, source string, createdBy uint) (int, error) {
+	start, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("%w: 开工日期格式应为YYYY-MM-DD", ErrInvalidRenovation)
+	}
+	end, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("%w: 完工日期格式应为YYYY-MM-DD", ErrInvalidRenovation)
+	}
+	if end.Before(start) {
+		return 0, fmt.Errorf("%w: 完工日期不能早于开工日期", ErrInvalidRenovation)
+	}
+
+	deposit := roundAmount(req.DepositAmount)
+	if deposit == 0 {
+		feeType, err := GetFeeTypeByCode(RenovationDepositCode)
+		if err != nil {
+			return 0, err
+		}
+		if feeType == nil {
+			return 0, errors.New("未配置装修押金收费项目")
+		}
+		deposit = roundAmount(feeType.UnitPrice)
+	}
+
+	var count int
+	err = database.DB.QueryRow(`
+		SELECT COUNT(*) FROM renovation_applications WHERE resident_id = ? AND status IN (?, ?, ?)`,
+		append([]interface{}{req.ResidentID}, activeRenovationStatuses...)...,
+	).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, ErrRenovationExists
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO renovation_applications (resident_id, applicant_name, applicant_phone, scope, contractor_name,
+		                                     contractor_contact, contractor_phone, contractor_license, workers,
+		                                     start_date, end_date, deposit_amount, deposit_status, status, source, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.ResidentID, req.ApplicantName, req.ApplicantPhone, req.Scope, req.ContractorName,
+		req.ContractorContact, req.ContractorPhone, req.ContractorLicense, req.Workers,
+		start, end, deposit, DepositNone, RenovationSubmitted, source, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+const renovationColumns = `
+	a.id, r.community_id, a.resident_id, r.name, CONCAT(r.block_number, r.unit_number, r.house_number), a.applicant_name,
+	a.applicant_phone, a.scope, a.contractor_name, a.contractor_contact, a.contractor_phone, a.contractor_license,
+	a.workers, a.start_date, a.end_date, a.deposit_amount, a.deposit_fee_id, a.deposit_status, a.status, a.source,
+	a.created_by, COALESCE(a.reviewed_by, 0), a.reviewed_at, a.review_note, a.completed_at, a.closed_at,
+	a.cancel_reason, a.forfeited_at, a.forfeit_reason, a.created_at, a.updated_at
+	FROM renovation_applications a
+	JOIN residents r ON a.resident_id = r.id
+`
+
+// scanRenovation 解析一行装修申请数据
+func scanRenovation(scanner interface{ Scan(...interface{}) error }) (*Renovation, error) {
+	var a Renovation
+	var feeID sql.NullInt64
+	var reviewedAt, completedAt, closedAt, forfeitedAt sql.NullTime
+	err := scanner.Scan(&a.ID, &a.CommunityID, &a.ResidentID, &a.ResidentName, &a.Room, &a.ApplicantName,
+		&a.ApplicantPhone, &a.Scope, &a.ContractorName, &a.ContractorContact, &a.ContractorPhone, &a.ContractorLicense,
+		&a.Workers, &a.StartDate, &a.EndDate, &a.DepositAmount, &feeID, &a.DepositStatus, &a.Status, &a.Source,
+		&a.CreatedBy, &a.ReviewedBy, &reviewedAt, &a.ReviewNote, &completedAt, &closedAt,
+		&a.CancelReason, &forfeitedAt, &a.ForfeitReason, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if feeID.Valid {
+		id := int(feeID.Int64)
+		a.DepositFeeID = &id
+	}
+	a.ReviewedAt = nullTimePtr(reviewedAt)
+	a.CompletedAt = nullTimePtr(completedAt)
+	a.ClosedAt = nullTimePtr(closedAt)
+	a.ForfeitedAt = nullTimePtr(forfeitedAt)
+
+	return &a, nil
+}
+
+// GetRenovations 获取装修申请列表，支持按状态、押金状态、住户和关键字（申请人、施工单位）查询
+func GetRenovations(page, pageSize int, filters map[string]interface{}) ([]Renovation, int, error) {
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if status, ok := filters["status"].(string); ok && status != "" {
+		where += ` AND a.status = ?`
+		args = append(args, status)
+	}
+	if depositStatus, ok := filters["deposit_status"].(string); ok && depositStatus != "" {
+		where += ` AND a.deposit_status = ?`
+		args = append(args, depositStatus)
+	}
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND a.resident_id = ?`
+		args = append(args, residentID)
+	}
+	if keyword, ok := filters["keyword"].(string); ok && keyword != "" {
+		where += ` AND (a.applicant_name LIKE ? OR a.contractor_name LIKE ?)`
+		args = append(args, "%"+keyword+"%", "%"+keyword+"%")
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM renovation_applications a JOIN residents r ON a.resident_id = r.id` + where
+	if err := database.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + renovationColumns + where + ` ORDER BY a.created_at DESC, a.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	renovations := []Renovation{}
+	for rows.Next() {
+		a, err := scanRenovation(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		renovations = append(renovations, *a)
+	}
+
+	return renovations, total, rows.Err()
+}
+
+// GetRenovationByID 通过ID获取装修申请，含验收记录
+func GetRenovationByID(id int) (*Renovation, error) {
+	a, err := scanRenovation(database.DB.QueryRow(`SELECT `+renovationColumns+` WHERE a.id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, application_id, checkpoint, result, inspector, notes, created_by, created_at
+		FROM renovation_inspections WHERE application_id = ? ORDER BY created_at, id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	a.Inspections = []RenovationInspection{}
+	for rows.Next() {
+		var i RenovationInspection
+		if err := rows.Scan(&i.ID, &i.ApplicationID, &i.Checkpoint, &i.Result, &i.Inspector, &i.Notes,
+			&i.CreatedBy, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		i.CheckpointName = renovationCheckpointNames[i.Checkpoint]
+		a.Inspections = append(a.Inspections, i)
+	}
+
+	return a, rows.Err()
+}
+
+// ReviewRenovation 审批装修申请，批准后待缴押金，无需押金的申请直接进入施工
+func ReviewRenovation(id int, req RenovationReviewRequest, userID uint) error {
+	status := RenovationRejected
+	if req.Approved {
+		status = RenovationApproved
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE renovation_applications
+		SET status = IF(? AND deposit_amount = 0, ?, ?), reviewed_by = ?, reviewed_at = NOW(), review_note = ?
+		WHERE id = ? AND status = ?`,
+		req.Approved, RenovationInProgress, status, userID, req.Note, id, RenovationSubmitted,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: 只有待审批的申请可以审批", ErrRenovationState)
+	}
+
+	return nil
+}
+
+// CollectRenovationDeposit 收取装修押金，收款记录写入property_fees，收取后进入施工
+func CollectRenovationDeposit(id int, req RenovationDepositRequest) error {
+	paymentDate := time.Now()
+	if req.PaymentDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", req.PaymentDate, time.Local)
+		if err != nil {
+			return fmt.Errorf("%w: 缴费日期格式应为YYYY-MM-DD", ErrInvalidRenovation)
+		}
+		paymentDate = t
+	}
+	paymentDate = time.Date(paymentDate.Year(), paymentDate.Month(), paymentDate.Day(), 0, 0, 0, 0, time.Local)
+
+	feeType, err := GetFeeTypeByCode(RenovationDepositCode)
+	if err != nil {
+		return err
+	}
+	if feeType == nil {
+		return errors.New("未配置装修押金收费项目")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var residentID, communityID int
+	var status string
+	var amount float64
+	err = tx.QueryRow(`
+		SELECT a.resident_id, r.community_id, a.status, a.deposit_amount
+		FROM renovation_applications a JOIN residents r ON a.resident_id = r.id
+		WHERE a.id = ? FOR UPDATE OF a`, id).
+		Scan(&residentID, &communityID, &status, &amount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRenovationNotFound
+		}
+		return err
+	}
+	if err := checkDateOpenTx(tx, communityID, paymentDate); err != nil {
+		return err
+	}
+	if status != RenovationApproved {
+		return fmt.Errorf("%w: 只有已批准待缴押金的申请可以收取押金", ErrRenovationState)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO property_fees (resident_id, fee_type_id, amount, payment_date, payment_method, payment_status, remark)
+		VALUES (?, ?, ?, ?, ?, 1, ?)`,
+		residentID, feeType.ID, amount, paymentDate, req.PaymentMethod, fmt.Sprintf("装修申请%d押金", id),
+	)
+	if err != nil {
+		return err
+	}
+	feeID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE renovation_applications SET deposit_fee_id = ?, deposit_status = ?, status = ? WHERE id = ?`,
+		feeID, DepositHeld, RenovationInProgress, id,
+	); err != nil {
+		return err
+	}
+
+	if err := postPaymentTx(tx, int(feeID)); err != nil {
+		return fmt.Errorf("缴费记账失败: %w", err)
+	}
+	if err := postDepositEntry(tx, id, JournalSourceDeposit); err != nil {
+		return fmt.Errorf("装修押金记账失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := RefreshMonthlyStats(communityID, paymentDate.Year(), int(paymentDate.Month())); err != nil {
+		fmt.Printf("刷新月度统计失败: %v\n", err)
+	}
+
+	return nil
+}
+
+// CreateRenovationInspection 登记施工中装修的验收记录
+// 竣工验收通过前，隐蔽工程验收和防水闭水试验的最近一次结果均需为通过，竣工验收通过后申请进入待退押金
+func CreateRenovationInspection(id int, req RenovationInspectionRequest, createdBy uint) (int, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM renovation_applications WHERE id = ? FOR UPDATE`, id).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrRenovationNotFound
+		}
+		return 0, err
+	}
+	if status != RenovationInProgress {
+		return 0, fmt.Errorf("%w: 只有施工中的装修可以登记验收", ErrRenovationState)
+	}
+
+	if req.Checkpoint == CheckpointFinal && req.Result == InspectionPass {
+		for _, checkpoint := range []string{CheckpointHiddenWorks, CheckpointWaterproof} {
+			var last string
+			err := tx.QueryRow(`
+				SELECT result FROM renovation_inspections WHERE application_id = ? AND checkpoint = ?
+				ORDER BY created_at DESC, id DESC LIMIT 1`, id, checkpoint).Scan(&last)
+			if err != nil && err != sql.ErrNoRows {
+				return 0, err
+			}
+			if last != InspectionPass {
+				return 0, fmt.Errorf("%w: %s未通过，不能竣工验收", ErrInvalidRenovation, renovationCheckpointNames[checkpoint])
+			}
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO renovation_inspections (application_id, checkpoint, result, inspector, notes, created_by)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		id, req.Checkpoint, req.Result, req.Inspector, req.Notes, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+	inspectionID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if req.Checkpoint == CheckpointFinal && req.Result == InspectionPass {
+		if _, err := tx.Exec(`UPDATE renovation_applications SET status = ?, completed_at = NOW() WHERE id = ?`,
+			RenovationCompleted, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(inspectionID), nil
+}
+
+// CancelRenovation 取消未完工的装修申请，已缴押金在取消后退还或没收
+func CancelRenovation(id int, reason string) error {
+	result, err := database.DB.Exec(`
+		UPDATE renovation_applications SET status = ?, cancel_reason = ?, closed_at = IF(deposit_status = ?, NOW(), NULL)
+		WHERE id = ? AND status IN (?, ?, ?)`,
+		append([]interface{}{RenovationCancelled, reason, DepositNone, id}, activeRenovationStatuses...)...,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: 只有未完工的申请可以取消", ErrRenovationState)
+	}
+
+	return nil
+}
+
+// RefundRenovationDeposit 退还竣工验收通过或已取消申请的押金，退款走缴费退款，完工的申请随之结案
+func RefundRenovationDeposit(id int, req RefundRequest) error {
+	refundDate, err := time.ParseInLocation("2006-01-02", req.RefundDate, time.Local)
+	if err != nil {
+		return fmt.Errorf("无效的退款日期: %s", req.RefundDate)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status, depositStatus string
+	var feeID sql.NullInt64
+	err = tx.QueryRow(`SELECT status, deposit_status, deposit_fee_id FROM renovation_applications WHERE id = ? FOR UPDATE`, id).
+		Scan(&status, &depositStatus, &feeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRenovationNotFound
+		}
+		return err
+	}
+	if depositStatus != DepositHeld || !feeID.Valid || (status != RenovationCompleted && status != RenovationCancelled) {
+		return fmt.Errorf("%w: 只有竣工验收通过或已取消且押金未退的申请可以退押金", ErrRenovationState)
+	}
+
+	paymentDate, communityID, err := refundPropertyFeeTx(tx, int(feeID.Int64), refundDate, req.Reason)
+	if err != nil {
+		return fmt.Errorf("退还押金失败: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE renovation_applications
+		SET deposit_status = ?, status = IF(status = ?, ?, status), closed_at = NOW()
+		WHERE id = ?`,
+		DepositRefunded, RenovationCompleted, RenovationClosed, id,
+	); err != nil {
+		return err
+	}
+	if err := postDepositEntry(tx, id, JournalSourceDepositRefund); err != nil {
+		return fmt.Errorf("装修押金退还记账失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	refreshRefundStats(communityID, paymentDate)
+	return nil
+}
+
+// ForfeitRenovationDeposit 因违规施工等原因没收押金，押金转入押金没收收入，完工的申请随之结案
+func ForfeitRenovationDeposit(id int, reason string) error {
+	today := time.Now()
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var communityID int
+	err = tx.QueryRow(`
+		SELECT r.community_id FROM renovation_applications a JOIN residents r ON a.resident_id = r.id
+		WHERE a.id = ?`, id).Scan(&communityID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRenovationNotFound
+		}
+		return err
+	}
+	if err := checkDateOpenTx(tx, communityID, today); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE renovation_applications
+		SET deposit_status = ?, forfeited_at = ?, forfeit_reason = ?,
+		    status = IF(status = ?, ?, status), closed_at = NOW()
+		WHERE id = ? AND deposit_status = ? AND status IN (?, ?)`,
+		DepositForfeited, today, reason, RenovationCompleted, RenovationClosed,
+		id, DepositHeld, RenovationCompleted, RenovationCancelled,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: 只有竣工验收通过或已取消且押金未退的申请可以没收押金", ErrRenovationState)
+	}
+	if err := postDepositEntry(tx, id, JournalSourceDepositForfeit); err != nil {
+		return fmt.Errorf("装修押金没收记账失败: %w", err)
+	}
+
+	return tx.Commit()
+}
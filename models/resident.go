@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,12 +13,15 @@ import (
 // Resident 表示住户模型
 type Resident struct {
 	ID          int       `json:"id"`
+	CommunityID int       `json:"community_id"` // 所属社区
 	Name        string    `json:"name"`
 	BlockNumber string    `json:"building"` // 前端使用building字段
 	UnitNumber  string    `json:"unit"`     // 前端使用unit字段
 	HouseNumber string    `json:"room"`     // 前端使用room字段
 	HouseArea   float64   `json:"area"`     // 前端使用area字段
 	FareSum     float64   `json:"fee"`      // 前端使用fee字段
+	Phone       string    `json:"phone"`    // 联系电话，用于短信催缴
+	Email       string    `json:"email"`    // 电子邮箱，用于邮件催缴
 	Status      int       `json:"-"`        // 数据库中的状态值
 	StatusText  string    `json:"status"`   // 前端显示的状态文本
 	CreatedAt   time.Time `json:"created_at,omitempty"`
@@ -32,6 +36,8 @@ type ResidentRequest struct {
 	HouseNumber string  `json:"room" binding:"required"`     // 前端使用room字段
 	HouseArea   float64 `json:"area" binding:"required"`     // 前端使用area字段
 	FareSum     float64 `json:"fee"`                         // 前端使用fee字段
+	Phone       string  `json:"phone"`                       // 联系电话
+	Email       string  `json:"email"`                       // 电子邮箱
 	Status      int     `json:"status"`                      // 0表示欠费，1表示正常
 }
 
@@ -58,7 +64,7 @@ func GetResidents(page, pageSize int, filters map[string]interface{}) ([]Residen
 	fmt.Println("接收到的筛选条件:", filters)
 
 	// 构建查询
-	query := `SELECT id, name, block_number, unit_number, house_number, house_area, fare_sum, status, created_at, updated_at FROM residents WHERE 1=1`
+	query := `SELECT id, community_id, name, block_number, unit_number, house_number, house_area, fare_sum, phone, email, status, created_at, updated_at FROM residents WHERE 1=1`
 	countQuery := `SELECT COUNT(*) FROM residents WHERE 1=1`
 
 	// 参数列表
@@ -66,6 +72,13 @@ func GetResidents(page, pageSize int, filters map[string]interface{}) ([]Residen
 	var countArgs []interface{}
 
 	// 添加过滤条件
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		query += ` AND community_id = ?`
+		countQuery += ` AND community_id = ?`
+		args = append(args, communityID)
+		countArgs = append(countArgs, communityID)
+	}
+
 	if name, ok := filters["name"].(string); ok && name != "" {
 		query += ` AND name LIKE ?`
 		countQuery += ` AND name LIKE ?`
@@ -100,6 +113,14 @@ func GetResidents(page, pageSize int, filters map[string]interface{}) ([]Residen
 		fmt.Println("添加单元过滤条件:", unitStr)
 	}
 
+	if status, ok := filters["status"].(int); ok {
+		query += ` AND status = ?`
+		countQuery += ` AND status = ?`
+		args = append(args, status)
+		countArgs = append(countArgs, status)
+		fmt.Println("添加状态过滤条件:", status)
+	}
+
 	if area, ok := filters["area"].(float64); ok && area > 0 {
 		query += ` AND house_area >= ?`
 		countQuery += ` AND house_area >= ?`
@@ -171,12 +192,15 @@ func GetResidents(page, pageSize int, filters map[string]interface{}) ([]Residen
 		var r Resident
 		err := rows.Scan(
 			&r.ID,
+			&r.CommunityID,
 			&r.Name,
 			&r.BlockNumber,
 			&r.UnitNumber,
 			&r.HouseNumber,
 			&r.HouseArea,
 			&r.FareSum,
+			&r.Phone,
+			&r.Email,
 			&r.Status,
 			&r.CreatedAt,
 			&r.UpdatedAt,
@@ -206,17 +230,20 @@ func GetResidents(page, pageSize int, filters map[string]interface{}) ([]Residen
 
 // GetResidentByID 通过ID获取住户
 func GetResidentByID(id int) (*Resident, error) {
-	query := `SELECT id, name, block_number, unit_number, house_number, house_area, fare_sum, status, created_at, updated_at FROM residents WHERE id = ?`
+	query := `SELECT id, community_id, name, block_number, unit_number, house_number, house_area, fare_sum, phone, email, status, created_at, updated_at FROM residents WHERE id = ?`
 
 	var r Resident
 	err := database.DB.QueryRow(query, id).Scan(
 		&r.ID,
+		&r.CommunityID,
 		&r.Name,
 		&r.BlockNumber,
 		&r.UnitNumber,
 		&r.HouseNumber,
 		&r.HouseArea,
 		&r.FareSum,
+		&r.Phone,
+		&r.Email,
 		&r.Status,
 		&r.CreatedAt,
 		&r.UpdatedAt,
@@ -239,8 +266,8 @@ func GetResidentByID(id int) (*Resident, error) {
 	return &r, nil
 }
 
-// CreateResident 创建住户
-func CreateResident(req ResidentRequest) (int, error) {
+// CreateResident 在指定社区创建住户
+func CreateResident(req ResidentRequest, communityID int) (int, error) {
 	fmt.Println("接收到创建住户请求:", req)
 
 	// 确保楼栋和单元格式正确
@@ -254,18 +281,21 @@ func CreateResident(req ResidentRequest) (int, error) {
 		unitNumber = unitNumber + "单元"
 	}
 
-	query := `INSERT INTO residents (name, block_number, unit_number, house_number, house_area, fare_sum, status) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO residents (community_id, name, block_number, unit_number, house_number, house_area, fare_sum, phone, email, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	fmt.Println("SQL查询:", query)
-	fmt.Println("参数:", req.Name, blockNumber, unitNumber, req.HouseNumber, req.HouseArea, req.FareSum, req.Status)
+	fmt.Println("参数:", communityID, req.Name, blockNumber, unitNumber, req.HouseNumber, req.HouseArea, req.FareSum, req.Phone, req.Email, req.Status)
 
 	result, err := database.DB.Exec(
 		query,
+		communityID,
 		req.Name,
 		blockNumber,
 		unitNumber,
 		req.HouseNumber,
 		req.HouseArea,
 		req.FareSum,
+		req.Phone,
+		req.Email,
 		req.Status,
 	)
 	if err != nil {
@@ -298,9 +328,9 @@ func UpdateResident(id int, req ResidentRequest) error {
 		unitNumber = unitNumber + "单元"
 	}
 
-	query := `UPDATE residents SET name = ?, block_number = ?, unit_number = ?, house_number = ?, house_area = ?, fare_sum = ?, status = ? WHERE id = ?`
+	query := `UPDATE residents SET name = ?, block_number = ?, unit_number = ?, house_number = ?, house_area = ?, fare_sum = ?, phone = ?, email = ?, status = ? WHERE id = ?`
 	fmt.Println("SQL查询:", query)
-	fmt.Println("参数:", req.Name, blockNumber, unitNumber, req.HouseNumber, req.HouseArea, req.FareSum, req.Status, id)
+	fmt.Println("参数:", req.Name, blockNumber, unitNumber, req.HouseNumber, req.HouseArea, req.FareSum, req.Phone, req.Email, req.Status, id)
 
 	_, err := database.DB.Exec(
 		query,
@@ -310,6 +340,8 @@ func UpdateResident(id int, req ResidentRequest) error {
 		req.HouseNumber,
 		req.HouseArea,
 		req.FareSum,
+		req.Phone,
+		req.Email,
 		req.Status,
 		id,
 	)
@@ -323,11 +355,100 @@ func UpdateResident(id int, req ResidentRequest) error {
 	return nil
 }
 
-// DeleteResident 删除住户
+// DeleteResident 删除住户，账单、缴费记录等随住户级联删除
+// 住户在已结账账期内有账单或缴费记录时返回ErrPeriodClosed，避免级联删除绕过结账；
+// 被级联删除的账单、减免、收款和退款已生成的凭证，以删除当天为记账日期生成红字冲销凭证
 func DeleteResident(id int) error {
-	query := `DELETE FROM residents WHERE id = ?`
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 住户最早的账单或缴费所在账期及之后的账期都未结账时才能删除
+	var firstBill sql.NullInt64
+	var firstPayment sql.NullTime
+	err = tx.QueryRow(`
+		SELECT (SELECT MIN(year * 100 + month) FROM bills WHERE resident_id = ?),
+		       (SELECT MIN(payment_date) FROM property_fees WHERE resident_id = ?)`,
+		id, id,
+	).Scan(&firstBill, &firstPayment)
+	if err != nil {
+		return err
+	}
+	if firstBill.Valid || firstPayment.Valid {
+		earliest := firstPayment.Time
+		if firstBill.Valid {
+			billMonth := time.Date(int(firstBill.Int64/100), time.Month(firstBill.Int64%100), 1, 0, 0, 0, 0, time.Local)
+			if !firstPayment.Valid || billMonth.Before(earliest) {
+				earliest = billMonth
+			}
+		}
+		communityID, err := residentCommunityTx(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkDateOpenTx(tx, communityID, earliest); err != nil {
+			if errors.Is(err, ErrPeriodClosed) {
+				return fmt.Errorf("%w: 住户在已结账账期内有账单或缴费记录，不能删除", ErrPeriodClosed)
+			}
+			return err
+		}
 
-	_, err := database.DB.Exec(query, id)
+		now := time.Now()
+		if err := checkDateOpenTx(tx, communityID, now); err != nil {
+			return err
+		}
+		if err := reverseResidentJournal(tx, id, now); err != nil {
+			return fmt.Errorf("冲销住户凭证失败: %w", err)
+		}
+	}
 
-	return err
+	if _, err := tx.Exec(`DELETE FROM residents WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// reverseResidentJournal 在事务中冲销住户账单、减免、收款和退款的凭证
+func reverseResidentJournal(tx *sql.Tx, residentID int, date time.Time) error {
+	sources := []struct {
+		query       string
+		sourceType  string
+		reverseType string
+	}{
+		{`SELECT id FROM bills WHERE resident_id = ? FOR UPDATE`, JournalSourceBill, JournalSourceBillVoid},
+		{`SELECT id FROM fee_adjustments WHERE resident_id = ? FOR UPDATE`, JournalSourceAdjustment, JournalSourceAdjustmentVoid},
+		{`SELECT id FROM property_fees WHERE resident_id = ? FOR UPDATE`, JournalSourcePayment, JournalSourcePaymentVoid},
+		{`SELECT id FROM property_fees WHERE resident_id = ? AND payment_status = 2`, JournalSourceRefund, JournalSourceRefundVoid},
+	}
+
+	for _, src := range sources {
+		rows, err := tx.Query(src.query, residentID)
+		if err != nil {
+			return err
+		}
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if err := reverseJournal(tx, src.sourceType, id, src.reverseType, date); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
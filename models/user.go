@@ -2,19 +2,40 @@ package models
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 	"community-backward/database"
 	"community-backward/utils"
 )
 
+// 用户角色
+const (
+	RoleAdmin       = "admin"       // 管理员
+	RoleFinance     = "finance"     // 财务
+	RoleStaff       = "staff"       // 普通工作人员
+	RoleMaintenance = "maintenance" // 维修人员，只能处理指派给自己的工单
+	RoleResident    = "resident"    // 住户账号，只能访问住户端接口
+)
+
+var (
+	// ErrUsernameExists 用户名已存在
+	ErrUsernameExists = errors.New("用户名已存在")
+	// ErrAccountExists 住户已开通账号
+	ErrAccountExists = errors.New("该住户已开通账号")
+)
+
 // User 表示用户模型
 type User struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Password  string    `json:"-"` // 不在JSON中返回密码
-	Email     string    `json:"email,omitempty"`
-	CreatedAt time.Time `json:"created_at,omitempty"`
-	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	ID          uint      `json:"id"`
+	Username    string    `json:"username"`
+	Password    string    `json:"-"` // 不在JSON中返回密码
+	Email       string    `json:"email,omitempty"`
+	Role        string    `json:"role"`
+	ResidentID  int       `json:"resident_id,omitempty"`  // 住户账号关联的住户
+	CommunityID int       `json:"community_id,omitempty"` // 当前所在社区
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
 }
 
 // LoginRequest 表示登录请求
@@ -26,14 +47,15 @@ type LoginRequest struct {
 
 // LoginResponse 表示登录响应
 type LoginResponse struct {
-	Success bool   `json:"success"`
-	Token   string `json:"token"`
-	Message string `json:"message,omitempty"`
+	Success   bool       `json:"success"`
+	Token     string     `json:"token"`
+	Message   string     `json:"message,omitempty"`
+	Community *Community `json:"community,omitempty"` // 登录后进入的社区
 }
 
 // FindUserByUsername 通过用户名查找用户
 func FindUserByUsername(username string) (*User, error) {
-	query := `SELECT id, username, password, email, created_at, updated_at FROM users WHERE username = ?`
+	query := `SELECT id, username, password, email, role, COALESCE(resident_id, 0), community_id, created_at, updated_at FROM users WHERE username = ?`
 
 	var user User
 	err := database.DB.QueryRow(query, username).Scan(
@@ -41,6 +63,9 @@ func FindUserByUsername(username string) (*User, error) {
 		&user.Username,
 		&user.Password,
 		&user.Email,
+		&user.Role,
+		&user.ResidentID,
+		&user.CommunityID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -76,13 +101,16 @@ func FindUser(username, password string) (*User, error) {
 
 // GetUserByID 通过ID获取用户
 func GetUserByID(id uint) (*User, error) {
-	query := `SELECT id, username, email, created_at, updated_at FROM users WHERE id = ?`
+	query := `SELECT id, username, email, role, COALESCE(resident_id, 0), community_id, created_at, updated_at FROM users WHERE id = ?`
 
 	var user User
 	err := database.DB.QueryRow(query, id).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
+		&user.Role,
+		&user.ResidentID,
+		&user.CommunityID,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -97,4 +125,63 @@ func GetUserByID(id uint) (*User, error) {
 	return &user, nil
 }
 
+// GetUsersByRole 获取指定角色的用户
+func GetUsersByRole(role string) ([]User, error) {
+	rows, err := database.DB.Query(`SELECT id, username, email, role, created_at, updated_at FROM users WHERE role = ? ORDER BY id`, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	users := []User{}
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// ResidentAccountRequest 表示为住户开通账号请求
+type ResidentAccountRequest struct {
+	Username string `json:"username" binding:"required,max=50"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// CreateResidentAccount 为住户开通登录账号，每个住户只能开通一个账号
+func CreateResidentAccount(residentID int, req ResidentAccountRequest) (uint, error) {
+	existing, err := FindUserByUsername(req.Username)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil {
+		return 0, fmt.Errorf("%w: %s", ErrUsernameExists, req.Username)
+	}
+
+	var count int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM users WHERE resident_id = ?`, residentID).Scan(&count); err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return 0, ErrAccountExists
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO users (username, password, email, role, resident_id, community_id)
+		SELECT ?, ?, email, ?, id, community_id FROM residents WHERE id = ?`,
+		req.Username, utils.HashPassword(req.Password), RoleResident, residentID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return uint(id), nil
+}
@@ -0,0 +1,437 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"community-backward/database"
+)
+
+// 访客通行证状态，upcoming和expired由有效期计算得出
+const (
+	PassActive   = "active"
+	PassRevoked  = "revoked"
+	PassUpcoming = "upcoming" // 未到生效时间
+	PassExpired  = "expired"  // 已过失效时间
+)
+
+// 访客核验操作
+const (
+	VisitorEntry = "entry"
+	VisitorExit  = "exit"
+)
+
+// MaxPassDuration 访客通行证的最长有效期
+const MaxPassDuration = 7 * 24 * time.Hour
+
+var (
+	// ErrInvalidPass 通行证参数错误
+	ErrInvalidPass = errors.New("无效的访客通行证")
+	// ErrPassNotFound 通行证不存在
+	ErrPassNotFound = errors.New("访客通行证不存在")
+	// ErrPassUnusable 通行证已作废、未生效或已过期
+	ErrPassUnusable = errors.New("访客通行证不可用")
+	// ErrVisitorInside 访客已入园尚未离开
+	ErrVisitorInside = errors.New("访客已入园，尚未登记离开")
+	// ErrVisitorNotInside 访客没有未离开的入园记录
+	ErrVisitorNotInside = errors.New("访客没有入园记录")
+)
+
+// VisitorPass 表示访客通行证
+type VisitorPass struct {
+	ID           int        `json:"id"`
+	CommunityID  int        `json:"community_id"` // 被访住户所在社区
+	ResidentID   int        `json:"resident_id"`
+	ResidentName string     `json:"resident_name"`
+	Room         string     `json:"room"`
+	VisitorName  string     `json:"visitor_name"`
+	VisitorPhone string     `json:"visitor_phone"`
+	PlateNo      string     `json:"plate_no,omitempty"`
+	Visitors     int        `json:"visitors"` // 同行人数
+	Purpose      string     `json:"purpose"`
+	ValidFrom    time.Time  `json:"valid_from"`
+	ValidUntil   time.Time  `json:"valid_until"`
+	Status       string     `json:"status"`
+	Source       string     `json:"source"`
+	CreatedBy    int        `json:"created_by"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	Inside       bool       `json:"inside"`         // 访客当前是否在小区内
+	Code         string     `json:"code,omitempty"` // 通行码，即二维码内容
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// VisitorPassRequest 表示登记访客请求
+type VisitorPassRequest struct {
+	ResidentID   int    `json:"resident_id"` // 前台登记时必填，住户端为本户
+	VisitorName  string `json:"visitor_name" binding:"required,max=50"`
+	VisitorPhone string `json:"visitor_phone" binding:"max=20"`
+	PlateNo      string `json:"plate_no"`
+	Visitors     int    `json:"visitors" binding:"omitempty,min=1,max=50"`
+	Purpose      string `json:"purpose" binding:"max=100"`
+	ValidFrom    string `json:"valid_from"`  // 格式: 2006-01-02 15:04，缺省为当前时间
+	ValidUntil   string `json:"valid_until"` // 格式: 2006-01-02 15:04，缺省为生效后24小时
+}
+
+// VisitorVerifyRequest 表示门岗核验通行码请求
+type VisitorVerifyRequest struct {
+	Code   string `json:"code" binding:"required"`
+	Action string `json:"action" binding:"required,oneof=entry exit"`
+}
+
+// VisitorLog 表示一条访客进出记录
+type VisitorLog struct {
+	ID           int        `json:"id"`
+	PassID       int        `json:"pass_id"`
+	CommunityID  int        `json:"community_id"`
+	ResidentID   int        `json:"resident_id"`
+	ResidentName string     `json:"resident_name"`
+	Room         string     `json:"room"`
+	VisitorName  string     `json:"visitor_name"`
+	VisitorPhone string     `json:"visitor_phone"`
+	PlateNo      string     `json:"plate_no,omitempty"`
+	Visitors     int        `json:"visitors"`
+	EnteredAt    time.Time  `json:"entered_at"`
+	EntryGuard   string     `json:"entry_guard"`
+	ExitedAt     *time.Time `json:"exited_at,omitempty"`
+	ExitGuard    string     `json:"exit_guard,omitempty"`
+}
+
+// passStatus 根据有效期计算有效通行证的显示状态
+func passStatus(status string, from, until, now time.Time) string {
+	if status != PassActive {
+		return status
+	}
+	if from.After(now) {
+		return PassUpcoming
+	}
+	if !until.After(now) {
+		return PassExpired
+	}
+	return PassActive
+}
+
+// CreateVisitorPass 登记访客并生成通行证，source为staff或resident
+func CreateVisitorPass(req VisitorPassRequest, createdBy uint, source string) (int, error) {
+	now := time.Now()
+	validFrom := now
+	if req.ValidFrom != "" {
+		t, err := parseDateTime(req.ValidFrom)
+		if err != nil {
+			return 0, fmt.Errorf("%w: 无效的生效时间", ErrInvalidPass)
+		}
+		validFrom = *t
+	}
+	validUntil := validFrom.Add(24 * time.Hour)
+	if req.ValidUntil != "" {
+		t, err := parseDateTime(req.ValidUntil)
+		if err != nil {
+			return 0, fmt.Errorf("%w: 无效的失效时间", ErrInvalidPass)
+		}
+		validUntil = *t
+	}
+	if !validUntil.After(validFrom) || !validUntil.After(now) {
+		return 0, fmt.Errorf("%w: 失效时间应晚于生效时间和当前时间", ErrInvalidPass)
+	}
+	if validUntil.Sub(validFrom) > MaxPassDuration {
+		return 0, fmt.Errorf("%w: 有效期不能超过%d天", ErrInvalidPass, int(MaxPassDuration.Hours()/24))
+	}
+
+	plate := ""
+	if req.PlateNo != "" {
+		plate = normalizePlate(req.PlateNo)
+		if !platePattern.MatchString(plate) {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidPlate, req.PlateNo)
+		}
+	}
+	if req.Visitors == 0 {
+		req.Visitors = 1
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO visitor_passes (resident_id, visitor_name, visitor_phone, plate_no, visitors, purpose,
+		                            valid_from, valid_until, status, source, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		req.ResidentID, req.VisitorName, req.VisitorPhone, plate, req.Visitors, req.Purpose,
+		validFrom, validUntil, PassActive, source, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+const visitorPassColumns = `
+	p.id, r.community_id, p.resident_id, r.name, CONCAT(r.block_number, r.unit_number, r.house_number),
+	p.visitor_name, p.visitor_phone, p.plate_no, p.visitors, p.purpose, p.valid_from, p.valid_until,
+	p.status, p.source, p.created_by, p.revoked_at,
+	EXISTS (SELECT 1 FROM visitor_logs l WHERE l.pass_id = p.id AND l.exited_at IS NULL), p.created_at
+`
+
+// scanVisitorPass 解析一行访客通行证数据
+func scanVisitorPass(scanner interface{ Scan(...interface{}) error }, now time.Time) (*VisitorPass, error) {
+	var p VisitorPass
+	var revokedAt sql.NullTime
+	err := scanner.Scan(&p.ID, &p.CommunityID, &p.ResidentID, &p.ResidentName, &p.Room,
+		&p.VisitorName, &p.VisitorPhone, &p.PlateNo, &p.Visitors, &p.Purpose, &p.ValidFrom, &p.ValidUntil,
+		&p.Status, &p.Source, &p.CreatedBy, &revokedAt, &p.Inside, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	p.RevokedAt = nullTimePtr(revokedAt)
+	p.Status = passStatus(p.Status, p.ValidFrom, p.ValidUntil, now)
+	return &p, nil
+}
+
+// GetVisitorPasses 获取访客通行证列表
+func GetVisitorPasses(page, pageSize int, filters map[string]interface{}) ([]VisitorPass, int, error) {
+	now := time.Now()
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND p.resident_id = ?`
+		args = append(args, residentID)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		switch status {
+		case PassActive:
+			where += ` AND p.status = 'active' AND p.valid_from <= ? AND p.valid_until > ?`
+			args = append(args, now, now)
+		case PassUpcoming:
+			where += ` AND p.status = 'active' AND p.valid_from > ?`
+			args = append(args, now)
+		case PassExpired:
+			where += ` AND p.status = 'active' AND p.valid_until <= ?`
+			args = append(args, now)
+		default:
+			where += ` AND p.status = ?`
+			args = append(args, status)
+		}
+	}
+	if keyword, ok := filters["keyword"].(string); ok && keyword != "" {
+		where += ` AND (p.visitor_name LIKE ? OR p.visitor_phone LIKE ? OR p.plate_no LIKE ?)`
+		args = append(args, "%"+keyword+"%", "%"+keyword+"%", "%"+normalizePlate(keyword)+"%")
+	}
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM visitor_passes p JOIN residents r ON p.resident_id = r.id`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + visitorPassColumns + `
+		FROM visitor_passes p
+		JOIN residents r ON p.resident_id = r.id` + where + `
+		ORDER BY p.valid_from DESC, p.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	passes := []VisitorPass{}
+	for rows.Next() {
+		p, err := scanVisitorPass(rows, now)
+		if err != nil {
+			return nil, 0, err
+		}
+		passes = append(passes, *p)
+	}
+
+	return passes, total, rows.Err()
+}
+
+// GetVisitorPassByID 通过ID获取访客通行证
+func GetVisitorPassByID(id int) (*VisitorPass, error) {
+	row := database.DB.QueryRow(`SELECT `+visitorPassColumns+`
+		FROM visitor_passes p
+		JOIN residents r ON p.resident_id = r.id
+		WHERE p.id = ?`, id)
+	p, err := scanVisitorPass(row, time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// RevokeVisitorPass 作废访客通行证，已入园的访客仍可登记离开
+func RevokeVisitorPass(id int) error {
+	result, err := database.DB.Exec(`
+		UPDATE visitor_passes SET status = ?, revoked_at = NOW() WHERE id = ? AND status = ?`,
+		PassRevoked, id, PassActive,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: 通行证不存在或已作废", ErrPassUnusable)
+	}
+	return nil
+}
+
+// VerifyVisitorPass 核验通行证并登记访客进出，validUntil为通行码中签发的失效时间
+// 入园需在有效期内且通行证未作废，离开只需有未离开的入园记录
+// 被访住户不在communityID社区的通行证按不存在处理
+func VerifyVisitorPass(passID int, validUntil time.Time, action string, guardID uint, communityID int) (*VisitorLog, error) {
+	now := time.Now()
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 锁定通行证，避免同一通行码并发入园
+	var status string
+	var from, until time.Time
+	err = tx.QueryRow(`
+		SELECT status, valid_from, valid_until FROM visitor_passes
+		WHERE id = ? AND resident_id IN (SELECT id FROM residents WHERE community_id = ?)
+		FOR UPDATE`, passID, communityID).
+		Scan(&status, &from, &until)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPassNotFound
+		}
+		return nil, err
+	}
+	if until.Unix() != validUntil.Unix() {
+		return nil, fmt.Errorf("%w: 通行码已失效，请使用最新的通行码", ErrPassUnusable)
+	}
+
+	var logID int
+	err = tx.QueryRow(`SELECT id FROM visitor_logs WHERE pass_id = ? AND exited_at IS NULL`, passID).Scan(&logID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	switch action {
+	case VisitorEntry:
+		switch passStatus(status, from, until, now) {
+		case PassRevoked:
+			return nil, fmt.Errorf("%w: 通行证已作废", ErrPassUnusable)
+		case PassUpcoming:
+			return nil, fmt.Errorf("%w: 通行证%s生效", ErrPassUnusable, from.Format("2006-01-02 15:04"))
+		case PassExpired:
+			return nil, fmt.Errorf("%w: 通行证已于%s过期", ErrPassUnusable, until.Format("2006-01-02 15:04"))
+		}
+		if logID > 0 {
+			return nil, ErrVisitorInside
+		}
+		result, err := tx.Exec(`INSERT INTO visitor_logs (pass_id, entered_at, entry_guard) VALUES (?, ?, ?)`, passID, now, guardID)
+		if err != nil {
+			return nil, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		logID = int(id)
+	case VisitorExit:
+		if logID == 0 {
+			return nil, ErrVisitorNotInside
+		}
+		if _, err := tx.Exec(`UPDATE visitor_logs SET exited_at = ?, exit_guard = ? WHERE id = ?`, now, guardID, logID); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("未知的核验操作: %s", action)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logs, _, err := GetVisitorLogs(1, 1, map[string]interface{}{"id": logID})
+	if err != nil || len(logs) == 0 {
+		return nil, err
+	}
+	return &logs[0], nil
+}
+
+// GetVisitorLogs 获取访客进出记录，支持按访客姓名、电话、车牌和入园日期查询
+func GetVisitorLogs(page, pageSize int, filters map[string]interface{}) ([]VisitorLog, int, error) {
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if id, ok := filters["id"].(int); ok && id > 0 {
+		where += ` AND l.id = ?`
+		args = append(args, id)
+	}
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND r.community_id = ?`
+		args = append(args, communityID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND p.resident_id = ?`
+		args = append(args, residentID)
+	}
+	if keyword, ok := filters["keyword"].(string); ok && keyword != "" {
+		where += ` AND (p.visitor_name LIKE ? OR p.visitor_phone LIKE ? OR p.plate_no LIKE ?)`
+		args = append(args, "%"+keyword+"%", "%"+keyword+"%", "%"+normalizePlate(keyword)+"%")
+	}
+	if start, ok := filters["start"].(time.Time); ok {
+		where += ` AND l.entered_at >= ?`
+		args = append(args, start)
+	}
+	if end, ok := filters["end"].(time.Time); ok {
+		where += ` AND l.entered_at < ?`
+		args = append(args, end.AddDate(0, 0, 1))
+	}
+	if inside, ok := filters["inside"].(bool); ok && inside {
+		where += ` AND l.exited_at IS NULL`
+	}
+
+	from := `
+		FROM visitor_logs l
+		JOIN visitor_passes p ON l.pass_id = p.id
+		JOIN residents r ON p.resident_id = r.id
+		LEFT JOIN users eg ON l.entry_guard = eg.id
+		LEFT JOIN users xg ON l.exit_guard = xg.id`
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*)`+from+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT l.id, l.pass_id, r.community_id, p.resident_id, r.name, CONCAT(r.block_number, r.unit_number, r.house_number),
+		       p.visitor_name, p.visitor_phone, p.plate_no, p.visitors,
+		       l.entered_at, COALESCE(eg.username, ''), l.exited_at, COALESCE(xg.username, '')` + from + where + `
+		ORDER BY l.entered_at DESC, l.id DESC LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	logs := []VisitorLog{}
+	for rows.Next() {
+		var l VisitorLog
+		var exitedAt sql.NullTime
+		if err := rows.Scan(&l.ID, &l.PassID, &l.CommunityID, &l.ResidentID, &l.ResidentName, &l.Room,
+			&l.VisitorName, &l.VisitorPhone, &l.PlateNo, &l.Visitors,
+			&l.EnteredAt, &l.EntryGuard, &exitedAt, &l.ExitGuard); err != nil {
+			return nil, 0, err
+		}
+		l.ExitedAt = nullTimePtr(exitedAt)
+		logs = append(logs, l)
+	}
+
+	return logs, total, rows.Err()
+}
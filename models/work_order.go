@@ -0,0 +1,586 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"community-backward/database"
+)
+
+// 工单位置类型
+const (
+	LocationHouse  = "house"  // 住户房屋
+	LocationCommon = "common" // 公共区域
+)
+
+// 工单优先级
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// 工单状态
+const (
+	WorkOrderPending    = "pending"     // 待指派
+	WorkOrderAssigned   = "assigned"    // 已指派
+	WorkOrderInProgress = "in_progress" // 处理中
+	WorkOrderResolved   = "resolved"    // 已完成，待确认关闭
+	WorkOrderClosed     = "closed"      // 已关闭
+	WorkOrderCancelled  = "cancelled"   // 已取消
+)
+
+// 工单来源
+const (
+	WorkOrderSourceStaff    = "staff"
+	WorkOrderSourceResident = "resident"
+)
+
+// 工单操作
+const (
+	WorkOrderActionAssign  = "assign"
+	WorkOrderActionStart   = "start"
+	WorkOrderActionResolve = "resolve"
+	WorkOrderActionClose   = "close"
+	WorkOrderActionReopen  = "reopen"
+	WorkOrderActionCancel  = "cancel"
+)
+
+// 工单处理记录类型
+const (
+	CommentKindComment = "comment"
+	CommentKindStatus  = "status"
+)
+
+var (
+	// ErrInvalidTransition 当前状态不允许该操作
+	ErrInvalidTransition = errors.New("当前工单状态不允许该操作")
+	// ErrInvalidAssignee 被指派人不是维修人员
+	ErrInvalidAssignee = errors.New("工单只能指派给维修人员")
+	// ErrInvalidWorkOrder 工单内容不完整
+	ErrInvalidWorkOrder = errors.New("无效的工单")
+	// ErrWorkOrderNotFound 工单不存在
+	ErrWorkOrderNotFound = errors.New("工单不存在")
+)
+
+// workOrderTransition 表示一种工单操作允许的起始状态和目标状态
+type workOrderTransition struct {
+	from  []string
+	to    string
+	label string
+}
+
+// workOrderTransitions 工单状态流转规则
+// 处理中的工单改派时保持处理中状态，见TransitionWorkOrder
+var workOrderTransitions = map[string]workOrderTransition{
+	WorkOrderActionAssign:  {from: []string{WorkOrderPending, WorkOrderAssigned, WorkOrderInProgress}, to: WorkOrderAssigned, label: "指派"},
+	WorkOrderActionStart:   {from: []string{WorkOrderAssigned}, to: WorkOrderInProgress, label: "开始处理"},
+	WorkOrderActionResolve: {from: []string{WorkOrderInProgress}, to: WorkOrderResolved, label: "处理完成"},
+	WorkOrderActionClose:   {from: []string{WorkOrderResolved}, to: WorkOrderClosed, label: "关闭"},
+	WorkOrderActionReopen:  {from: []string{WorkOrderResolved}, to: WorkOrderInProgress, label: "重新处理"},
+	WorkOrderActionCancel:  {from: []string{WorkOrderPending, WorkOrderAssigned, WorkOrderInProgress}, to: WorkOrderCancelled, label: "取消"},
+}
+
+// WorkOrderCategory 表示工单分类
+type WorkOrderCategory struct {
+	ID      int    `json:"id"`
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// WorkOrder 表示报修工单
+type WorkOrder struct {
+	ID           int                `json:"id"`
+	CommunityID  int                `json:"community_id"`
+	OrderNo      string             `json:"order_no"`
+	CategoryID   int                `json:"category_id"`
+	CategoryName string             `json:"category_name,omitempty"`
+	Title        string             `json:"title"`
+	Description  string             `json:"description"`
+	LocationType string             `json:"location_type"`
+	ResidentID   int                `json:"resident_id,omitempty"`
+	ResidentName string             `json:"resident_name,omitempty"`
+	Location     string             `json:"location"` // 公共区域位置，房屋工单为楼栋单元门牌
+	Priority     string             `json:"priority"`
+	Status       string             `json:"status"`
+	Source       string             `json:"source"`
+	ContactName  string             `json:"contact_name"`
+	ContactPhone string             `json:"contact_phone"`
+	CreatedBy    int                `json:"created_by"`
+	AssigneeID   int                `json:"assignee_id,omitempty"`
+	AssigneeName string             `json:"assignee_name,omitempty"`
+	AssignedAt   *time.Time         `json:"assigned_at,omitempty"`
+	StartedAt    *time.Time         `json:"started_at,omitempty"`
+	ResolvedAt   *time.Time         `json:"resolved_at,omitempty"`
+	ClosedAt     *time.Time         `json:"closed_at,omitempty"`
+	Resolution   string             `json:"resolution,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	Comments     []WorkOrderComment `json:"comments,omitempty"`
+	Photos       []WorkOrderPhoto   `json:"photos,omitempty"`
+}
+
+// WorkOrderRequest 表示创建工单请求
+type WorkOrderRequest struct {
+	CategoryID   int    `json:"category_id" binding:"required"`
+	Title        string `json:"title" binding:"required,max=100"`
+	Description  string `json:"description" binding:"max=1000"`
+	LocationType string `json:"location_type" binding:"required,oneof=house common"`
+	ResidentID   int    `json:"resident_id"` // 房屋工单必填
+	Location     string `json:"location"`    // 公共区域工单必填
+	Priority     string `json:"priority" binding:"omitempty,oneof=low normal high urgent"`
+	Source       string `json:"source" binding:"omitempty,oneof=staff resident"`
+	ContactName  string `json:"contact_name"`
+	ContactPhone string `json:"contact_phone"`
+}
+
+// WorkOrderActionRequest 表示工单状态操作请求
+type WorkOrderActionRequest struct {
+	AssigneeID int    `json:"assignee_id"` // 指派时必填
+	Note       string `json:"note"`        // 完成时为处理结果，取消和重新处理时为原因
+}
+
+// WorkOrderComment 表示工单处理记录
+type WorkOrderComment struct {
+	ID        int       `json:"id"`
+	OrderID   int       `json:"order_id"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Kind      string    `json:"kind"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorkOrderCommentRequest 表示添加评论请求
+type WorkOrderCommentRequest struct {
+	Content string `json:"content" binding:"required,max=1000"`
+}
+
+// WorkOrderPhoto 表示工单照片
+type WorkOrderPhoto struct {
+	ID           int       `json:"id"`
+	OrderID      int       `json:"order_id"`
+	FilePath     string    `json:"-"`
+	OriginalName string    `json:"original_name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	UploadedBy   int       `json:"uploaded_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// GetWorkOrderCategories 获取工单分类，enabledOnly为true时只返回启用的分类
+func GetWorkOrderCategories(enabledOnly bool) ([]WorkOrderCategory, error) {
+	query := `SELECT id, code, name, enabled FROM work_order_categories`
+	if enabledOnly {
+		query += ` WHERE enabled = 1`
+	}
+	query += ` ORDER BY id`
+
+	rows, err := database.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []WorkOrderCategory{}
+	for rows.Next() {
+		var c WorkOrderCategory
+		if err := rows.Scan(&c.ID, &c.Code, &c.Name, &c.Enabled); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+
+	return categories, rows.Err()
+}
+
+// CreateWorkOrder 在指定社区创建工单，工单号按 WO+日期+ID 生成
+func CreateWorkOrder(req WorkOrderRequest, createdBy uint, communityID int) (int, error) {
+	if req.Priority == "" {
+		req.Priority = PriorityNormal
+	}
+	if req.Source == "" {
+		req.Source = WorkOrderSourceStaff
+	}
+
+	var residentID sql.NullInt64
+	switch req.LocationType {
+	case LocationHouse:
+		if req.ResidentID <= 0 {
+			return 0, fmt.Errorf("%w: 房屋报修需指定住户", ErrInvalidWorkOrder)
+		}
+		residentID = sql.NullInt64{Int64: int64(req.ResidentID), Valid: true}
+	case LocationCommon:
+		if req.Location == "" {
+			return 0, fmt.Errorf("%w: 公共区域报修需填写位置", ErrInvalidWorkOrder)
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO work_orders (community_id, category_id, title, description, location_type, resident_id, location,
+		                         priority, status, source, contact_name, contact_phone, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		communityID, req.CategoryID, req.Title, req.Description, req.LocationType, residentID, req.Location,
+		req.Priority, WorkOrderPending, req.Source, req.ContactName, req.ContactPhone, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	orderNo := fmt.Sprintf("WO%s%06d", time.Now().Format("20060102"), id)
+	if _, err := tx.Exec(`UPDATE work_orders SET order_no = ? WHERE id = ?`, orderNo, id); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+const workOrderColumns = `
+	w.id, w.community_id, COALESCE(w.order_no, ''), w.category_id, c.name, w.title, w.description, w.location_type,
+	COALESCE(w.resident_id, 0), COALESCE(r.name, ''),
+	CASE WHEN w.location_type = 'house' THEN COALESCE(CONCAT(r.block_number, r.unit_number, r.house_number), '') ELSE w.location END,
+	w.priority, w.status, w.source, w.contact_name, w.contact_phone, w.created_by,
+	COALESCE(w.assignee_id, 0), COALESCE(u.username, ''),
+	w.assigned_at, w.started_at, w.resolved_at, w.closed_at, w.resolution, w.created_at, w.updated_at
+`
+
+const workOrderJoins = `
+	FROM work_orders w
+	JOIN work_order_categories c ON w.category_id = c.id
+	LEFT JOIN residents r ON w.resident_id = r.id
+	LEFT JOIN users u ON w.assignee_id = u.id
+`
+
+// scanWorkOrder 解析一行工单数据
+func scanWorkOrder(scanner interface{ Scan(...interface{}) error }) (*WorkOrder, error) {
+	var w WorkOrder
+	var assignedAt, startedAt, resolvedAt, closedAt sql.NullTime
+	err := scanner.Scan(&w.ID, &w.CommunityID, &w.OrderNo, &w.CategoryID, &w.CategoryName, &w.Title, &w.Description, &w.LocationType,
+		&w.ResidentID, &w.ResidentName, &w.Location,
+		&w.Priority, &w.Status, &w.Source, &w.ContactName, &w.ContactPhone, &w.CreatedBy,
+		&w.AssigneeID, &w.AssigneeName,
+		&assignedAt, &startedAt, &resolvedAt, &closedAt, &w.Resolution, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	w.AssignedAt = nullTimePtr(assignedAt)
+	w.StartedAt = nullTimePtr(startedAt)
+	w.ResolvedAt = nullTimePtr(resolvedAt)
+	w.ClosedAt = nullTimePtr(closedAt)
+
+	return &w, nil
+}
+
+// nullTimePtr 将可空时间转换为指针，空值返回nil
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// GetWorkOrders 获取工单列表，紧急程度高的排在前面
+func GetWorkOrders(page, pageSize int, filters map[string]interface{}) ([]WorkOrder, int, error) {
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if communityID, ok := filters["community_id"].(int); ok && communityID > 0 {
+		where += ` AND w.community_id = ?`
+		args = append(args, communityID)
+	}
+	if status, ok := filters["status"].(string); ok && status != "" {
+		where += ` AND w.status = ?`
+		args = append(args, status)
+	}
+	if categoryID, ok := filters["category_id"].(int); ok && categoryID > 0 {
+		where += ` AND w.category_id = ?`
+		args = append(args, categoryID)
+	}
+	if priority, ok := filters["priority"].(string); ok && priority != "" {
+		where += ` AND w.priority = ?`
+		args = append(args, priority)
+	}
+	if assigneeID, ok := filters["assignee_id"].(int); ok && assigneeID > 0 {
+		where += ` AND w.assignee_id = ?`
+		args = append(args, assigneeID)
+	}
+	if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 {
+		where += ` AND w.resident_id = ?`
+		args = append(args, residentID)
+	}
+	if keyword, ok := filters["keyword"].(string); ok && keyword != "" {
+		where += ` AND (w.order_no LIKE ? OR w.title LIKE ?)`
+		args = append(args, "%"+keyword+"%", "%"+keyword+"%")
+	}
+	if open, ok := filters["open"].(bool); ok && open {
+		where += ` AND w.status IN ('pending', 'assigned', 'in_progress')`
+	}
+
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM work_orders w`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + workOrderColumns + workOrderJoins + where + `
+		ORDER BY FIELD(w.priority, 'urgent', 'high', 'normal', 'low'), w.id DESC
+		LIMIT ? OFFSET ?`
+	rows, err := database.DB.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	orders := []WorkOrder{}
+	for rows.Next() {
+		w, err := scanWorkOrder(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, *w)
+	}
+
+	return orders, total, rows.Err()
+}
+
+// GetWorkOrderByID 通过ID获取工单，withDetail为true时同时返回处理记录和照片
+func GetWorkOrderByID(id int, withDetail bool) (*WorkOrder, error) {
+	w, err := scanWorkOrder(database.DB.QueryRow(`SELECT `+workOrderColumns+workOrderJoins+` WHERE w.id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !withDetail {
+		return w, nil
+	}
+
+	if w.Comments, err = GetWorkOrderComments(id); err != nil {
+		return nil, err
+	}
+	if w.Photos, err = GetWorkOrderPhotos(id); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// TransitionWorkOrder 按操作变更工单状态，并自动记录一条状态变更记录
+func TransitionWorkOrder(id int, action string, req WorkOrderActionRequest, userID uint, username string) error {
+	t, ok := workOrderTransitions[action]
+	if !ok {
+		return fmt.Errorf("未知的工单操作: %s", action)
+	}
+
+	order, err := GetWorkOrderByID(id, false)
+	if err != nil {
+		return err
+	}
+	if order == nil {
+		return ErrWorkOrderNotFound
+	}
+
+	allowed := false
+	for _, s := range t.from {
+		if order.Status == s {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s状态的工单不能%s", ErrInvalidTransition, order.Status, t.label)
+	}
+
+	to := t.to
+	set := `status = ?`
+	args := []interface{}{to}
+	note := t.label
+
+	switch action {
+	case WorkOrderActionAssign:
+		assignee, err := GetUserByID(uint(req.AssigneeID))
+		if err != nil {
+			return err
+		}
+		if assignee == nil || assignee.Role != RoleMaintenance {
+			return ErrInvalidAssignee
+		}
+		// 处理中的工单改派后仍为处理中
+		if order.Status == WorkOrderInProgress {
+			to = WorkOrderInProgress
+			args[0] = to
+		}
+		set += `, assignee_id = ?, assigned_at = NOW()`
+		args = append(args, assignee.ID)
+		note = "指派给" + assignee.Username
+	case WorkOrderActionStart:
+		set += `, started_at = NOW()`
+	case WorkOrderActionResolve:
+		if req.Note == "" {
+			return fmt.Errorf("%w: 请填写处理结果", ErrInvalidWorkOrder)
+		}
+		set += `, resolution = ?, resolved_at = NOW()`
+		args = append(args, req.Note)
+	case WorkOrderActionReopen:
+		set += `, resolved_at = NULL`
+	case WorkOrderActionClose, WorkOrderActionCancel:
+		set += `, closed_at = NOW()`
+	}
+	if req.Note != "" && action != WorkOrderActionAssign {
+		note += ": " + req.Note
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 以当前状态作为条件，避免并发操作互相覆盖
+	args = append(args, id, order.Status)
+	result, err := tx.Exec(`UPDATE work_orders SET `+set+` WHERE id = ? AND status = ?`, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: 工单状态已被他人修改，请刷新后重试", ErrInvalidTransition)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO work_order_comments (order_id, user_id, username, kind, content) VALUES (?, ?, ?, ?, ?)`,
+		id, userID, username, CommentKindStatus, note,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CreateWorkOrderComment 添加工单评论
+func CreateWorkOrderComment(orderID int, content string, userID uint, username string) (int, error) {
+	result, err := database.DB.Exec(`
+		INSERT INTO work_order_comments (order_id, user_id, username, kind, content) VALUES (?, ?, ?, ?, ?)`,
+		orderID, userID, username, CommentKindComment, content,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetWorkOrderComments 获取工单处理记录，按时间先后排列
+func GetWorkOrderComments(orderID int) ([]WorkOrderComment, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, order_id, user_id, username, kind, content, created_at
+		FROM work_order_comments WHERE order_id = ? ORDER BY id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []WorkOrderComment{}
+	for rows.Next() {
+		var c WorkOrderComment
+		if err := rows.Scan(&c.ID, &c.OrderID, &c.UserID, &c.Username, &c.Kind, &c.Content, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, rows.Err()
+}
+
+// CreateWorkOrderPhoto 保存工单照片记录
+func CreateWorkOrderPhoto(photo WorkOrderPhoto) (int, error) {
+	result, err := database.DB.Exec(`
+		INSERT INTO work_order_photos (order_id, file_path, original_name, content_type, size, uploaded_by)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		photo.OrderID, photo.FilePath, photo.OriginalName, photo.ContentType, photo.Size, photo.UploadedBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// GetWorkOrderPhotos 获取工单照片
+func GetWorkOrderPhotos(orderID int) ([]WorkOrderPhoto, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, order_id, file_path, original_name, content_type, size, uploaded_by, created_at
+		FROM work_order_photos WHERE order_id = ? ORDER BY id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	photos := []WorkOrderPhoto{}
+	for rows.Next() {
+		var p WorkOrderPhoto
+		if err := rows.Scan(&p.ID, &p.OrderID, &p.FilePath, &p.OriginalName, &p.ContentType, &p.Size, &p.UploadedBy, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		photos = append(photos, p)
+	}
+
+	return photos, rows.Err()
+}
+
+// GetWorkOrderPhoto 获取工单的某张照片
+func GetWorkOrderPhoto(orderID, photoID int) (*WorkOrderPhoto, error) {
+	var p WorkOrderPhoto
+	err := database.DB.QueryRow(`
+		SELECT id, order_id, file_path, original_name, content_type, size, uploaded_by, created_at
+		FROM work_order_photos WHERE id = ? AND order_id = ?`, photoID, orderID).
+		Scan(&p.ID, &p.OrderID, &p.FilePath, &p.OriginalName, &p.ContentType, &p.Size, &p.UploadedBy, &p.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// CountPendingWorkOrders 统计指定社区未完成（待指派、已指派、处理中）的工单数量
+func CountPendingWorkOrders(communityID int) (int, error) {
+	var count int
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*) FROM work_orders WHERE community_id = ? AND status IN (?, ?, ?)`,
+		communityID, WorkOrderPending, WorkOrderAssigned, WorkOrderInProgress,
+	).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// 内置渠道名称
+const (
+	ChannelSMS   = "sms"
+	ChannelEmail = "email"
+	ChannelInApp = "inapp"
+)
+
+// Message 表示一条待发送的通知
+type Message struct {
+	ResidentID int    // 接收住户ID
+	Recipient  string // 接收地址：手机号、邮箱等，站内信可为空
+	Title      string
+	Content    string
+}
+
+// Channel 表示一种通知渠道
+type Channel interface {
+	// Name 返回渠道名称
+	Name() string
+	// Send 发送通知，失败时返回错误
+	Send(msg Message) error
+}
+
+// Registry 保存已注册的通知渠道
+type Registry struct {
+	mu       sync.RWMutex
+	channels map[string]Channel
+}
+
+// NewRegistry 创建新的渠道注册表
+func NewRegistry(channels ...Channel) *Registry {
+	r := &Registry{channels: make(map[string]Channel)}
+	for _, ch := range channels {
+		r.Register(ch)
+	}
+	return r
+}
+
+// Register 注册渠道，同名渠道会被替换
+func (r *Registry) Register(ch Channel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.channels[ch.Name()] = ch
+}
+
+// Get 获取指定名称的渠道
+func (r *Registry) Get(name string) (Channel, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ch, ok := r.channels[name]
+	return ch, ok
+}
+
+// Send 通过指定渠道发送通知
+func (r *Registry) Send(channel string, msg Message) error {
+	ch, ok := r.Get(channel)
+	if !ok {
+		return fmt.Errorf("未注册的通知渠道: %s", channel)
+	}
+	return ch.Send(msg)
+}
+
+// LogChannel 仅将通知写入日志，用于尚未对接服务商的渠道
+type LogChannel struct {
+	name string
+}
+
+// NewLogChannel 创建新的LogChannel
+func NewLogChannel(name string) *LogChannel {
+	return &LogChannel{name: name}
+}
+
+// Name 返回渠道名称
+func (c *LogChannel) Name() string {
+	return c.name
+}
+
+// Send 将通知写入日志
+func (c *LogChannel) Send(msg Message) error {
+	if msg.Recipient == "" {
+		return fmt.Errorf("住户%d未登记%s联系方式", msg.ResidentID, c.name)
+	}
+	log.Printf("[%s] 发送至 %s: %s - %s", c.name, msg.Recipient, msg.Title, msg.Content)
+	return nil
+}
@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"community-backward/database"
+)
+
+// InAppChannel 将通知写入站内信表
+type InAppChannel struct{}
+
+// NewInAppChannel 创建新的InAppChannel
+func NewInAppChannel() *InAppChannel {
+	return &InAppChannel{}
+}
+
+// Name 返回渠道名称
+func (c *InAppChannel) Name() string {
+	return ChannelInApp
+}
+
+// Send 写入站内信
+func (c *InAppChannel) Send(msg Message) error {
+	_, err := database.DB.Exec(
+		`INSERT INTO in_app_messages (resident_id, title, content) VALUES (?, ?, ?)`,
+		msg.ResidentID, msg.Title, msg.Content,
+	)
+	return err
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPSMSConfig 表示通用HTTP短信网关配置
+type HTTPSMSConfig struct {
+	URL     string        // 网关地址，以POST方式提交JSON
+	Token   string        // 为空时不携带Authorization头
+	Sign    string        // 短信签名，如“物业服务中心”
+	Timeout time.Duration // 请求超时，默认10秒
+}
+
+// HTTPSMSChannel 通过通用HTTP短信网关发送短信
+// 请求体为{"phone":"...","content":"...","sign":"..."}，网关返回2xx视为发送成功
+type HTTPSMSChannel struct {
+	cfg    HTTPSMSConfig
+	client *http.Client
+}
+
+// NewHTTPSMSChannel 创建新的HTTPSMSChannel
+func NewHTTPSMSChannel(cfg HTTPSMSConfig) *HTTPSMSChannel {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &HTTPSMSChannel{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Name 返回渠道名称
+func (c *HTTPSMSChannel) Name() string {
+	return ChannelSMS
+}
+
+// Send 提交短信到网关
+func (c *HTTPSMSChannel) Send(msg Message) error {
+	if msg.Recipient == "" {
+		return fmt.Errorf("住户%d未登记手机号", msg.ResidentID)
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"phone":   msg.Recipient,
+		"content": msg.Content,
+		"sign":    c.cfg.Sign,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("短信网关返回%d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
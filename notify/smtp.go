@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig 表示SMTP邮件服务器配置
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // 为空时不进行认证
+	Password string
+	From     string        // 发件人地址
+	Timeout  time.Duration // 连接及发送一封邮件的总超时，默认30秒
+}
+
+// SMTPChannel 通过SMTP服务器发送邮件
+// 服务器支持STARTTLS时自动加密，不支持465端口的隐式TLS，请使用587或25端口
+type SMTPChannel struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPChannel 创建新的SMTPChannel
+func NewSMTPChannel(cfg SMTPConfig) *SMTPChannel {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &SMTPChannel{cfg: cfg}
+}
+
+// Name 返回渠道名称
+func (c *SMTPChannel) Name() string {
+	return ChannelEmail
+}
+
+// Send 发送纯文本邮件
+func (c *SMTPChannel) Send(msg Message) error {
+	if msg.Recipient == "" {
+		return fmt.Errorf("住户%d未登记邮箱", msg.ResidentID)
+	}
+	if c.cfg.From == "" {
+		return errors.New("未配置发件人地址")
+	}
+
+	// smtp.SendMail没有超时，服务器无响应时会一直阻塞发送任务，这里自行建立连接并设置截止时间
+	addr := net.JoinHostPort(c.cfg.Host, strconv.Itoa(c.cfg.Port))
+	conn, err := net.DialTimeout("tcp", addr, c.cfg.Timeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(c.cfg.Timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, c.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: c.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if c.cfg.Username != "" {
+		auth := smtp.PlainAuth("", c.cfg.Username, c.cfg.Password, c.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(c.cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.Recipient); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(c.build(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// build 生成UTF-8编码的邮件内容，标题使用RFC 2047编码，正文使用base64编码
+func (c *SMTPChannel) build(msg Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", c.cfg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.Recipient)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	body := base64.StdEncoding.EncodeToString([]byte(msg.Content))
+	for len(body) > 76 {
+		buf.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	buf.WriteString(body + "\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,20 @@
+package notify
+
+import (
+	"strings"
+)
+
+// Render 将模板中的{{key}}占位符替换为对应的值
+func Render(tpl string, vars map[string]string) string {
+	pairs := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		pairs = append(pairs, "{{"+k+"}}", v)
+	}
+	return strings.NewReplacer(pairs...).Replace(tpl)
+}
+
+// 模板语言
+const (
+	LocaleZH = "zh"
+	LocaleEN = "en"
+)
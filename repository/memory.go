@@ -0,0 +1,1560 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"community-backward/models"
+	"community-backward/utils"
+)
+
+// Memory 内存实现的仓储，数据只保存在进程内，不需要MySQL，用于测试和本地演示
+// 账单、缴费、退款和删除住户与MySQL实现一样检查账期并生成记账凭证，结账时刷新并冻结月度统计；
+// 与MySQL实现的差异：没有费用减免、抄表记录和车位租赁合同，按用量和按合同生成账单时返回0；
+// 仪表盘的待处理工单数为0，车位使用率为“--”，收入趋势没有统计数据时返回0而不是示例数据；
+// 投诉、公告、设施、设备、租约、访客、包裹等业务模块只能通过AddX预置数据，只读取主记录，
+// 投诉处理记录、装修验收记录等明细为空，列表按ID倒序且只支持社区、住户和上级记录条件，其余条件忽略
+type Memory struct {
+	mu sync.Mutex
+
+	users           map[uint]*models.User // Password保存密码哈希
+	communities     map[int]*models.Community
+	userCommunities map[uint]map[int]bool
+	residents       map[int]*models.Resident
+	feeTypes        map[int]*models.FeeType
+	fees            map[int]*models.PropertyFee
+	auditLogs       []models.AuditLog
+
+	bills        memoryTable[models.Bill]
+	periods      map[periodKey]*models.AccountingPeriod
+	journal      []models.JournalEntry
+	voucherNos   map[periodKey]int
+	monthlyStats map[statsKey]*models.PropertyFeeMonthlyStats
+
+	complaints     memoryTable[models.Complaint]
+	announcements  memoryTable[models.Announcement]
+	polls          memoryTable[models.Poll]
+	facilities     memoryTable[models.Facility]
+	bookings       memoryTable[models.FacilityBooking]
+	assets         memoryTable[models.Asset]
+	schedules      memoryTable[models.MaintenanceSchedule]
+	tasks          memoryTable[models.MaintenanceTask]
+	inspections    memoryTable[models.AssetInspection]
+	leases         memoryTable[models.Lease]
+	meters         memoryTable[models.Meter]
+	visitorPasses  memoryTable[models.VisitorPass]
+	visitorLogs    memoryTable[models.VisitorLog]
+	parcels        memoryTable[models.Parcel]
+	renovations    memoryTable[models.Renovation]
+	credentials    memoryTable[models.AccessCredential]
+	notifications  memoryTable[models.Notification]
+	dunningRecords memoryTable[models.DunningRecord]
+
+	nextUserID      uint
+	nextCommunityID int
+	nextResidentID  int
+	nextFeeTypeID   int
+	nextFeeID       int
+	nextPeriodID    int
+}
+
+// NewMemory 创建内存仓储，预置默认社区和物业费收费项目
+func NewMemory() *Memory {
+	m := &Memory{
+		users:           make(map[uint]*models.User),
+		communities:     make(map[int]*models.Community),
+		userCommunities: make(map[uint]map[int]bool),
+		residents:       make(map[int]*models.Resident),
+		feeTypes:        make(map[int]*models.FeeType),
+		fees:            make(map[int]*models.PropertyFee),
+		periods:         make(map[periodKey]*models.AccountingPeriod),
+		voucherNos:      make(map[periodKey]int),
+		monthlyStats:    make(map[statsKey]*models.PropertyFeeMonthlyStats),
+	}
+	m.AddCommunity(models.Community{ID: models.DefaultCommunityID, Code: "default", Name: "默认社区", Enabled: true})
+	m.AddFeeType(models.FeeType{
+		ID:                 models.DefaultFeeTypeID,
+		Code:               "property",
+		Name:               "物业费",
+		PricingRule:        models.PricingPerArea,
+		UnitPrice:          2.5,
+		Unit:               "元/平方米·月",
+		AccountingCategory: "物业服务收入",
+		Enabled:            true,
+	})
+	return m
+}
+
+// Repositories 返回以该内存仓储为数据源的各个仓储
+func (m *Memory) Repositories() *Repositories {
+	return &Repositories{
+		Users:             memoryUsers{m},
+		Communities:       memoryCommunities{m},
+		Residents:         memoryResidents{m},
+		FeeTypes:          memoryFeeTypes{m},
+		Fees:              memoryFees{m},
+		Bills:             memoryBills{m},
+		Dashboard:         memoryDashboard{m},
+		Periods:           memoryPeriods{m},
+		AuditLogs:         memoryAuditLogs{m},
+		Complaints:        memoryComplaints{m},
+		Announcements:     memoryAnnouncements{m},
+		Polls:             memoryPolls{m},
+		Facilities:        memoryFacilities{m},
+		Assets:            memoryAssets{m},
+		Leases:            memoryLeases{m},
+		Meters:            memoryMeters{m},
+		Visitors:          memoryVisitors{m},
+		Parcels:           memoryParcels{m},
+		Renovations:       memoryRenovations{m},
+		AccessCredentials: memoryAccessCredentials{m},
+		Notifications:     memoryNotifications{m},
+		Dunning:           memoryDunning{m},
+	}
+}
+
+// AddUser 添加用户，password为明文密码，user.ID为0时自动分配，返回用户ID
+// 未指定社区时归入默认社区
+func (m *Memory) AddUser(user models.User, password string) uint {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user.ID == 0 {
+		user.ID = m.nextUserID + 1
+	}
+	if user.ID > m.nextUserID {
+		m.nextUserID = user.ID
+	}
+	if user.CommunityID == 0 {
+		user.CommunityID = models.DefaultCommunityID
+	}
+	user.Password = utils.HashPassword(password)
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	m.users[user.ID] = &user
+	return user.ID
+}
+
+// AddCommunity 添加社区，c.ID为0时自动分配，返回社区ID
+func (m *Memory) AddCommunity(c models.Community) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c.ID == 0 {
+		c.ID = m.nextCommunityID + 1
+	}
+	if c.ID > m.nextCommunityID {
+		m.nextCommunityID = c.ID
+	}
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	m.communities[c.ID] = &c
+	return c.ID
+}
+
+// AddCommunityMember 将工作人员登记为社区成员
+func (m *Memory) AddCommunityMember(communityID int, userID uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.userCommunities[userID] == nil {
+		m.userCommunities[userID] = make(map[int]bool)
+	}
+	m.userCommunities[userID][communityID] = true
+}
+
+// AddFeeType 添加收费项目，t.ID为0时自动分配，未指定科目时记入物业服务收入，返回收费项目ID
+func (m *Memory) AddFeeType(t models.FeeType) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if t.AccountCode == "" {
+		t.AccountCode = defaultFeeAccountCode
+	}
+	if t.ID == 0 {
+		t.ID = m.nextFeeTypeID + 1
+	}
+	if t.ID > m.nextFeeTypeID {
+		m.nextFeeTypeID = t.ID
+	}
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = t.CreatedAt
+	m.feeTypes[t.ID] = &t
+	return t.ID
+}
+
+// AuditLogs 返回已记录的审计日志
+func (m *Memory) AuditLogs() []models.AuditLog {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]models.AuditLog(nil), m.auditLogs...)
+}
+
+// JournalEntries 返回已生成的记账凭证，按生成先后排列
+func (m *Memory) JournalEntries() []models.JournalEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := make([]models.JournalEntry, len(m.journal))
+	for i, e := range m.journal {
+		e.Lines = append([]models.JournalLine(nil), e.Lines...)
+		entries[i] = e
+	}
+	return entries
+}
+
+// paginate 返回第page页的下标范围
+func paginate(total, page, pageSize int) (int, int) {
+	start := (page - 1) * pageSize
+	if start > total {
+		start = total
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// memoryUsers 内存实现的UserRepository
+type memoryUsers struct{ m *Memory }
+
+// findUserByUsername 通过用户名查找用户，调用方需持有锁
+func (r memoryUsers) findUserByUsername(username string) *models.User {
+	for _, u := range r.m.users {
+		if u.Username == username {
+			return u
+		}
+	}
+	return nil
+}
+
+func (r memoryUsers) FindUser(username, password string) (*models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	u := r.findUserByUsername(username)
+	if u == nil || !utils.CheckPasswordHash(password, u.Password) {
+		return nil, nil
+	}
+	user := *u
+	return &user, nil
+}
+
+func (r memoryUsers) GetUserByID(id uint) (*models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	u, ok := r.m.users[id]
+	if !ok {
+		return nil, nil
+	}
+	user := *u
+	user.Password = ""
+	return &user, nil
+}
+
+func (r memoryUsers) CreateResidentAccount(residentID int, req models.ResidentAccountRequest) (uint, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if r.findUserByUsername(req.Username) != nil {
+		return 0, fmt.Errorf("%w: %s", models.ErrUsernameExists, req.Username)
+	}
+	for _, u := range r.m.users {
+		if u.ResidentID == residentID {
+			return 0, models.ErrAccountExists
+		}
+	}
+	resident, ok := r.m.residents[residentID]
+	if !ok {
+		return 0, fmt.Errorf("%w: 住户%d不存在", sql.ErrNoRows, residentID)
+	}
+
+	r.m.nextUserID++
+	now := time.Now()
+	r.m.users[r.m.nextUserID] = &models.User{
+		ID:          r.m.nextUserID,
+		Username:    req.Username,
+		Password:    utils.HashPassword(req.Password),
+		Email:       resident.Email,
+		Role:        models.RoleResident,
+		ResidentID:  residentID,
+		CommunityID: resident.CommunityID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	return r.m.nextUserID, nil
+}
+
+func (r memoryUsers) GetUserCommunities(user *models.User) ([]models.Community, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.userCommunities(user), nil
+}
+
+// userCommunities 获取用户可以进入的已启用社区，规则与models.GetUserCommunities一致，调用方需持有锁
+func (r memoryUsers) userCommunities(user *models.User) []models.Community {
+	communities := []models.Community{}
+	for _, c := range r.m.communities {
+		if !c.Enabled {
+			continue
+		}
+		switch user.Role {
+		case models.RoleAdmin:
+		case models.RoleResident:
+			if resident, ok := r.m.residents[user.ResidentID]; !ok || resident.CommunityID != c.ID {
+				continue
+			}
+		default:
+			if !r.m.userCommunities[user.ID][c.ID] {
+				continue
+			}
+		}
+		communities = append(communities, *c)
+	}
+	sort.Slice(communities, func(i, j int) bool { return communities[i].ID < communities[j].ID })
+	return communities
+}
+
+func (r memoryUsers) ResolveUserCommunity(user *models.User) (*models.Community, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	communities := r.userCommunities(user)
+	if len(communities) == 0 {
+		return nil, models.ErrNotCommunityMember
+	}
+	for i := range communities {
+		if communities[i].ID == user.CommunityID {
+			return &communities[i], nil
+		}
+	}
+	return &communities[0], nil
+}
+
+func (r memoryUsers) SwitchUserCommunity(user *models.User, communityID int) (*models.Community, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	communities := r.userCommunities(user)
+	for i := range communities {
+		if communities[i].ID != communityID {
+			continue
+		}
+		if u, ok := r.m.users[user.ID]; ok {
+			u.CommunityID = communityID
+		}
+		return &communities[i], nil
+	}
+	return nil, models.ErrNotCommunityMember
+}
+
+// memoryCommunities 内存实现的CommunityRepository
+type memoryCommunities struct{ m *Memory }
+
+func (r memoryCommunities) GetCommunities(onlyEnabled bool) ([]models.Community, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	communities := []models.Community{}
+	for _, c := range r.m.communities {
+		if onlyEnabled && !c.Enabled {
+			continue
+		}
+		communities = append(communities, *c)
+	}
+	sort.Slice(communities, func(i, j int) bool { return communities[i].ID < communities[j].ID })
+	return communities, nil
+}
+
+func (r memoryCommunities) GetCommunityByID(id int) (*models.Community, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	c, ok := r.m.communities[id]
+	if !ok {
+		return nil, nil
+	}
+	community := *c
+	return &community, nil
+}
+
+// checkCode 检查社区编码是否已被其他社区使用，调用方需持有锁
+func (r memoryCommunities) checkCode(code string, excludeID int) error {
+	for _, c := range r.m.communities {
+		if c.Code == code && c.ID != excludeID {
+			return fmt.Errorf("%w: %s", models.ErrCommunityCodeExists, code)
+		}
+	}
+	return nil
+}
+
+func (r memoryCommunities) CreateCommunity(req models.CommunityRequest) (int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if err := r.checkCode(req.Code, 0); err != nil {
+		return 0, err
+	}
+
+	r.m.nextCommunityID++
+	now := time.Now()
+	r.m.communities[r.m.nextCommunityID] = &models.Community{
+		ID:        r.m.nextCommunityID,
+		Code:      req.Code,
+		Name:      req.Name,
+		Address:   req.Address,
+		Enabled:   req.Enabled,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	return r.m.nextCommunityID, nil
+}
+
+func (r memoryCommunities) UpdateCommunity(id int, req models.CommunityRequest) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if err := r.checkCode(req.Code, id); err != nil {
+		return err
+	}
+	if c, ok := r.m.communities[id]; ok {
+		c.Code = req.Code
+		c.Name = req.Name
+		c.Address = req.Address
+		c.Enabled = req.Enabled
+		c.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r memoryCommunities) GetCommunityMembers(communityID int) ([]models.User, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	users := []models.User{}
+	for userID, communities := range r.m.userCommunities {
+		u, ok := r.m.users[userID]
+		if !ok || !communities[communityID] {
+			continue
+		}
+		users = append(users, models.User{
+			ID:        u.ID,
+			Username:  u.Username,
+			Email:     u.Email,
+			Role:      u.Role,
+			CreatedAt: u.CreatedAt,
+			UpdatedAt: u.UpdatedAt,
+		})
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (r memoryCommunities) AddCommunityMember(communityID int, userID uint) error {
+	r.m.mu.Lock()
+	u, ok := r.m.users[userID]
+	r.m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: 用户%d不存在", sql.ErrNoRows, userID)
+	}
+	if u.Role == models.RoleResident {
+		return models.ErrInvalidCommunityMember
+	}
+	r.m.AddCommunityMember(communityID, userID)
+	return nil
+}
+
+func (r memoryCommunities) RemoveCommunityMember(communityID int, userID uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if !r.m.userCommunities[userID][communityID] {
+		return sql.ErrNoRows
+	}
+	delete(r.m.userCommunities[userID], communityID)
+	return nil
+}
+
+func (r memoryCommunities) GetCommunityRollup(communities []models.Community, year int, asOf time.Time) ([]models.CommunityRollup, *models.CommunityRollup, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	rollups := make([]models.CommunityRollup, 0, len(communities))
+	total := &models.CommunityRollup{Name: "合计"}
+	for _, c := range communities {
+		rollup := models.CommunityRollup{CommunityID: c.ID, Code: c.Code, Name: c.Name}
+		for _, res := range r.m.residents {
+			if res.CommunityID != c.ID {
+				continue
+			}
+			rollup.Residents++
+			if res.Status == 0 {
+				rollup.ArrearsHouseholds++
+			}
+		}
+		var income float64
+		for _, fee := range r.m.fees {
+			res, ok := r.m.residents[fee.ResidentID]
+			feeType := r.m.feeTypes[fee.FeeTypeID]
+			if ok && res.CommunityID == c.ID && fee.PaymentStatus == models.PaymentPaid && fee.PaymentDate.Year() == year &&
+				(feeType == nil || feeType.PricingRule != models.PricingDeposit) {
+				income += fee.Amount
+			}
+		}
+		rollup.YearlyIncome = roundAmount(income)
+
+		report := r.m.collectionReport(year, 0, "building", 0, asOf, c.ID)
+		rollup.AmountDue = report.Total.AmountDue
+		rollup.AmountCollected = report.Total.AmountCollected
+		rollup.AmountRate = report.Total.AmountRate
+
+		total.Residents += rollup.Residents
+		total.ArrearsHouseholds += rollup.ArrearsHouseholds
+		total.YearlyIncome = roundAmount(total.YearlyIncome + rollup.YearlyIncome)
+		total.AmountDue = roundAmount(total.AmountDue + rollup.AmountDue)
+		total.AmountCollected = roundAmount(total.AmountCollected + rollup.AmountCollected)
+		rollups = append(rollups, rollup)
+	}
+	if total.AmountDue > 0 {
+		total.AmountRate = roundAmount(total.AmountCollected / total.AmountDue * 100)
+	}
+	return rollups, total, nil
+}
+
+// memoryResidents 内存实现的ResidentRepository
+type memoryResidents struct{ m *Memory }
+
+// normalizeBlock 补全楼栋和单元的后缀，与MySQL实现保存的格式一致
+func normalizeBlock(building, unit string) (string, string) {
+	if building != "" && !strings.HasSuffix(building, "栋") {
+		building += "栋"
+	}
+	if unit != "" && !strings.HasSuffix(unit, "单元") {
+		unit += "单元"
+	}
+	return building, unit
+}
+
+// residentStatusText 返回住户状态的显示文本
+func residentStatusText(status int) string {
+	if status == 0 {
+		return "欠费"
+	}
+	return "正常"
+}
+
+func (r memoryResidents) GetResidents(page, pageSize int, filters map[string]interface{}) ([]models.Resident, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	building, _ := filters["building"].(string)
+	unit, _ := filters["unit"].(string)
+	building, unit = normalizeBlock(building, unit)
+
+	var matched []models.Resident
+	for _, res := range r.m.residents {
+		if communityID, ok := filters["community_id"].(int); ok && communityID > 0 && res.CommunityID != communityID {
+			continue
+		}
+		if name, ok := filters["name"].(string); ok && name != "" && !strings.Contains(res.Name, name) {
+			continue
+		}
+		if building != "" && res.BlockNumber != building {
+			continue
+		}
+		if unit != "" && res.UnitNumber != unit {
+			continue
+		}
+		if status, ok := filters["status"].(int); ok && res.Status != status {
+			continue
+		}
+		if area, ok := filters["area"].(float64); ok && area > 0 && res.HouseArea < area {
+			continue
+		}
+		if areaMax, ok := filters["areaMax"].(float64); ok && areaMax > 0 && res.HouseArea > areaMax {
+			continue
+		}
+		matched = append(matched, *res)
+	}
+
+	sortField, _ := filters["sortField"].(string)
+	desc := filters["sortOrder"] == "desc" && sortField != ""
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if desc {
+			a, b = b, a
+		}
+		switch sortField {
+		case "area":
+			if a.HouseArea != b.HouseArea {
+				return a.HouseArea < b.HouseArea
+			}
+		case "fee":
+			if a.FareSum != b.FareSum {
+				return a.FareSum < b.FareSum
+			}
+		}
+		return a.ID < b.ID
+	})
+
+	start, end := paginate(len(matched), page, pageSize)
+	var residents []models.Resident
+	if start < end {
+		residents = matched[start:end]
+	}
+	return residents, len(matched), nil
+}
+
+func (r memoryResidents) GetResidentByID(id int) (*models.Resident, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	res, ok := r.m.residents[id]
+	if !ok {
+		return nil, nil
+	}
+	resident := *res
+	return &resident, nil
+}
+
+func (r memoryResidents) CreateResident(req models.ResidentRequest, communityID int) (int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if _, ok := r.m.communities[communityID]; !ok {
+		return 0, fmt.Errorf("社区%d不存在", communityID)
+	}
+
+	r.m.nextResidentID++
+	res := &models.Resident{ID: r.m.nextResidentID, CommunityID: communityID, CreatedAt: time.Now()}
+	applyResidentRequest(res, req)
+	r.m.residents[res.ID] = res
+	return res.ID, nil
+}
+
+// applyResidentRequest 将住户请求写入住户
+func applyResidentRequest(res *models.Resident, req models.ResidentRequest) {
+	res.Name = req.Name
+	res.BlockNumber, res.UnitNumber = normalizeBlock(req.BlockNumber, req.UnitNumber)
+	res.HouseNumber = req.HouseNumber
+	res.HouseArea = req.HouseArea
+	res.FareSum = req.FareSum
+	res.Phone = req.Phone
+	res.Email = req.Email
+	res.Status = req.Status
+	res.StatusText = residentStatusText(req.Status)
+	res.UpdatedAt = time.Now()
+}
+
+func (r memoryResidents) UpdateResident(id int, req models.ResidentRequest) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if res, ok := r.m.residents[id]; ok {
+		applyResidentRequest(res, req)
+	}
+	return nil
+}
+
+// DeleteResident 规则与models.DeleteResident一致：住户最早的账单或缴费所在账期及之后的账期都未结账时才能删除，
+// 删除时以当天为记账日期冲销住户账单、收款和退款的凭证
+func (r memoryResidents) DeleteResident(id int) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var billIDs, feeIDs []int
+	var earliest time.Time
+	for _, b := range r.m.bills.rows {
+		if b.ResidentID != id {
+			continue
+		}
+		billIDs = append(billIDs, b.ID)
+		if billMonth := time.Date(b.Year, time.Month(b.Month), 1, 0, 0, 0, 0, time.Local); earliest.IsZero() || billMonth.Before(earliest) {
+			earliest = billMonth
+		}
+	}
+	for _, fee := range r.m.fees {
+		if fee.ResidentID != id {
+			continue
+		}
+		feeIDs = append(feeIDs, fee.ID)
+		if earliest.IsZero() || fee.PaymentDate.Before(earliest) {
+			earliest = fee.PaymentDate
+		}
+	}
+	sort.Ints(billIDs)
+	sort.Ints(feeIDs)
+
+	if !earliest.IsZero() {
+		communityID := r.m.residentCommunity(id)
+		if err := r.m.checkDateOpen(communityID, earliest); err != nil {
+			return fmt.Errorf("%w: 住户在已结账账期内有账单或缴费记录，不能删除", models.ErrPeriodClosed)
+		}
+		now := time.Now()
+		if err := r.m.checkDateOpen(communityID, now); err != nil {
+			return err
+		}
+
+		reversals := []struct {
+			ids         []int
+			sourceType  string
+			reverseType string
+		}{
+			{billIDs, models.JournalSourceBill, models.JournalSourceBillVoid},
+			{feeIDs, models.JournalSourcePayment, models.JournalSourcePaymentVoid},
+			{feeIDs, models.JournalSourceRefund, models.JournalSourceRefundVoid},
+		}
+		for _, rev := range reversals {
+			for _, sourceID := range rev.ids {
+				if err := r.m.reverseJournal(rev.sourceType, sourceID, rev.reverseType, now); err != nil {
+					return fmt.Errorf("冲销住户凭证失败: %w", err)
+				}
+			}
+		}
+	}
+
+	delete(r.m.residents, id)
+	// 与外键级联删除一致，同时删除住户的账单、缴费记录和住户账号
+	for _, billID := range billIDs {
+		r.m.bills.remove(billID)
+	}
+	for _, feeID := range feeIDs {
+		delete(r.m.fees, feeID)
+	}
+	for userID, u := range r.m.users {
+		if u.ResidentID == id {
+			delete(r.m.users, userID)
+		}
+	}
+	return nil
+}
+
+// memoryFees 内存实现的FeeRepository
+type memoryFees struct{ m *Memory }
+
+// propertyFee 返回带住户姓名和收费项目名称的缴费记录副本，调用方需持有锁
+func (r memoryFees) propertyFee(fee *models.PropertyFee) models.PropertyFee {
+	f := *fee
+	if res, ok := r.m.residents[f.ResidentID]; ok {
+		f.ResidentName = res.Name
+	}
+	if t, ok := r.m.feeTypes[f.FeeTypeID]; ok {
+		f.FeeTypeName = t.Name
+	}
+	return f
+}
+
+func (r memoryFees) GetPropertyFees(page, pageSize int, filters map[string]interface{}) ([]models.PropertyFee, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var matched []models.PropertyFee
+	for _, fee := range r.m.fees {
+		res, ok := r.m.residents[fee.ResidentID]
+		if !ok {
+			continue
+		}
+		if residentID, ok := filters["resident_id"].(int); ok && residentID > 0 && fee.ResidentID != residentID {
+			continue
+		}
+		if communityID, ok := filters["community_id"].(int); ok && communityID > 0 && res.CommunityID != communityID {
+			continue
+		}
+		if name, ok := filters["resident_name"].(string); ok && name != "" && !strings.Contains(res.Name, name) {
+			continue
+		}
+		if feeTypeID, ok := filters["fee_type_id"].(int); ok && feeTypeID > 0 && fee.FeeTypeID != feeTypeID {
+			continue
+		}
+		if startDate, ok := filters["start_date"].(time.Time); ok && fee.PaymentDate.Before(startDate) {
+			continue
+		}
+		if endDate, ok := filters["end_date"].(time.Time); ok && fee.PaymentDate.After(endDate) {
+			continue
+		}
+		if status, ok := filters["status"].(int); ok && fee.PaymentStatus != status {
+			continue
+		}
+		matched = append(matched, r.propertyFee(fee))
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].PaymentDate.Equal(matched[j].PaymentDate) {
+			return matched[i].PaymentDate.After(matched[j].PaymentDate)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+
+	start, end := paginate(len(matched), page, pageSize)
+	var fees []models.PropertyFee
+	if start < end {
+		fees = matched[start:end]
+	}
+	return fees, len(matched), nil
+}
+
+func (r memoryFees) GetPropertyFeeByID(id int) (*models.PropertyFee, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	fee, ok := r.m.fees[id]
+	if !ok {
+		return nil, nil
+	}
+	f := r.propertyFee(fee)
+	return &f, nil
+}
+
+func (r memoryFees) CreatePropertyFee(req models.PropertyFeeRequest) (int, error) {
+	paymentDate, err := time.ParseInLocation("2006-01-02", req.PaymentDate, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("无效的缴费日期: %s", req.PaymentDate)
+	}
+
+	feeTypeID := req.FeeTypeID
+	if feeTypeID == 0 {
+		feeTypeID = models.DefaultFeeTypeID
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	res, ok := r.m.residents[req.ResidentID]
+	if !ok {
+		return 0, fmt.Errorf("住户%d不存在", req.ResidentID)
+	}
+	if _, ok := r.m.feeTypes[feeTypeID]; !ok {
+		return 0, fmt.Errorf("收费项目%d不存在", feeTypeID)
+	}
+	// 不允许补录到住户所属社区已结账的账期
+	if err := r.m.checkDateOpen(res.CommunityID, paymentDate); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	fee := &models.PropertyFee{
+		ID:            r.m.nextFeeID + 1,
+		ResidentID:    req.ResidentID,
+		FeeTypeID:     feeTypeID,
+		Amount:        req.Amount,
+		PaymentDate:   paymentDate,
+		PaymentMethod: req.PaymentMethod,
+		PaymentStatus: models.PaymentPaid,
+		Remark:        req.Remark,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := r.m.postPayment(r.propertyFee(fee)); err != nil {
+		return 0, fmt.Errorf("缴费记账失败: %w", err)
+	}
+
+	r.m.nextFeeID = fee.ID
+	r.m.fees[fee.ID] = fee
+	r.m.refreshMonthlyStats(res.CommunityID, paymentDate.Year(), int(paymentDate.Month()))
+	return fee.ID, nil
+}
+
+func (r memoryFees) RefundPropertyFee(id int, req models.RefundRequest) error {
+	refundDate, err := time.ParseInLocation("2006-01-02", req.RefundDate, time.Local)
+	if err != nil {
+		return fmt.Errorf("无效的退款日期: %s", req.RefundDate)
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	fee, ok := r.m.fees[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	communityID := r.m.residentCommunity(fee.ResidentID)
+	if err := r.m.checkDateOpen(communityID, refundDate); err != nil {
+		return err
+	}
+	if fee.PaymentStatus != models.PaymentPaid {
+		return models.ErrPaymentNotRefundable
+	}
+	if refundDate.Before(fee.PaymentDate) {
+		return fmt.Errorf("退款日期不能早于缴费日期")
+	}
+
+	if err := r.m.postRefund(r.propertyFee(fee), refundDate); err != nil {
+		return fmt.Errorf("退款记账失败: %w", err)
+	}
+	fee.PaymentStatus = models.PaymentRefunded
+	fee.RefundedAt = refundDate.Format("2006-01-02")
+	fee.Remark += " 退款原因: " + req.Reason
+	fee.UpdatedAt = time.Now()
+
+	// 缴费所在月份已结账时统计保持冻结
+	if r.m.checkDateOpen(communityID, fee.PaymentDate) == nil {
+		r.m.refreshMonthlyStats(communityID, fee.PaymentDate.Year(), int(fee.PaymentDate.Month()))
+	}
+	return nil
+}
+
+// memoryAuditLogs 内存实现的AuditLogRepository
+type memoryAuditLogs struct{ m *Memory }
+
+func (r memoryAuditLogs) CreateAuditLog(log models.AuditLog) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	log.ID = len(r.m.auditLogs) + 1
+	log.CreatedAt = time.Now()
+	r.m.auditLogs = append(r.m.auditLogs, log)
+	return nil
+}
+
+func (r memoryAuditLogs) GetAuditLogs(page, pageSize int, filters map[string]interface{}) ([]models.AuditLog, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	entity, _ := filters["entity"].(string)
+	action, _ := filters["action"].(string)
+	userID, _ := filters["user_id"].(int)
+
+	var matched []models.AuditLog
+	for i := len(r.m.auditLogs) - 1; i >= 0; i-- {
+		l := r.m.auditLogs[i]
+		if (entity != "" && l.Entity != entity) || (action != "" && l.Action != action) || (userID > 0 && l.UserID != userID) {
+			continue
+		}
+		matched = append(matched, l)
+	}
+
+	start, end := paginate(len(matched), page, pageSize)
+	var logs []models.AuditLog
+	if start < end {
+		logs = matched[start:end]
+	}
+	return logs, len(matched), nil
+}
+
+// memoryTable 按ID保存的一类业务记录，零值可直接使用，调用方需持有Memory的锁
+type memoryTable[T any] struct {
+	rows   map[int]*T
+	lastID int
+}
+
+// allocate 分配记录ID，id为0时取下一个可用ID
+func (t *memoryTable[T]) allocate(id int) int {
+	if id == 0 {
+		id = t.lastID + 1
+	}
+	if id > t.lastID {
+		t.lastID = id
+	}
+	return id
+}
+
+// put 保存记录
+func (t *memoryTable[T]) put(id int, row T) {
+	if t.rows == nil {
+		t.rows = make(map[int]*T)
+	}
+	t.rows[id] = &row
+}
+
+// get 返回记录的副本，不存在时返回nil
+func (t *memoryTable[T]) get(id int) *T {
+	row, ok := t.rows[id]
+	if !ok {
+		return nil
+	}
+	copied := *row
+	return &copied
+}
+
+// list 按ID倒序返回满足match的记录中第page页的副本及总数
+func (t *memoryTable[T]) list(page, pageSize int, match func(*T) bool) ([]T, int) {
+	ids := make([]int, 0, len(t.rows))
+	for id, row := range t.rows {
+		if match(row) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+
+	start, end := paginate(len(ids), page, pageSize)
+	rows := make([]T, 0, end-start)
+	for _, id := range ids[start:end] {
+		rows = append(rows, *t.rows[id])
+	}
+	return rows, len(ids)
+}
+
+// all 按ID倒序返回满足match的全部记录
+func (t *memoryTable[T]) all(match func(*T) bool) []T {
+	rows, _ := t.list(1, len(t.rows)+1, match)
+	return rows
+}
+
+// residentCommunity 返回住户所在社区，住户不存在时返回0，调用方需持有锁
+func (m *Memory) residentCommunity(residentID int) int {
+	if res, ok := m.residents[residentID]; ok {
+		return res.CommunityID
+	}
+	return 0
+}
+
+// matchCommunity 判断记录所在社区是否满足filters中的community_id条件
+func matchCommunity(filters map[string]interface{}, communityID int) bool {
+	id, ok := filters["community_id"].(int)
+	return !ok || id <= 0 || id == communityID
+}
+
+// matchResident 判断记录的住户是否满足filters中的resident_id条件
+func matchResident(filters map[string]interface{}, residentID int) bool {
+	id, ok := filters["resident_id"].(int)
+	return !ok || id <= 0 || id == residentID
+}
+
+// AddComplaint 添加投诉，c.ID为0时自动分配，返回投诉ID
+// 未指定社区时取投诉住户所在社区，匿名投诉归入默认社区
+func (m *Memory) AddComplaint(c models.Complaint) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c.ID = m.complaints.allocate(c.ID)
+	if c.CommunityID == 0 {
+		c.CommunityID = m.residentCommunity(c.ResidentID)
+	}
+	if c.CommunityID == 0 {
+		c.CommunityID = models.DefaultCommunityID
+	}
+	m.complaints.put(c.ID, c)
+	return c.ID
+}
+
+// AddAnnouncement 添加公告，a.ID为0时自动分配，未指定社区时归入默认社区，返回公告ID
+func (m *Memory) AddAnnouncement(a models.Announcement) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a.ID = m.announcements.allocate(a.ID)
+	if a.CommunityID == 0 {
+		a.CommunityID = models.DefaultCommunityID
+	}
+	m.announcements.put(a.ID, a)
+	return a.ID
+}
+
+// AddPoll 添加表决，p.ID为0时自动分配，未指定社区时归入默认社区，返回表决ID
+func (m *Memory) AddPoll(p models.Poll) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p.ID = m.polls.allocate(p.ID)
+	if p.CommunityID == 0 {
+		p.CommunityID = models.DefaultCommunityID
+	}
+	m.polls.put(p.ID, p)
+	return p.ID
+}
+
+// AddFacility 添加公共设施，f.ID为0时自动分配，未指定社区时归入默认社区，返回设施ID
+func (m *Memory) AddFacility(f models.Facility) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f.ID = m.facilities.allocate(f.ID)
+	if f.CommunityID == 0 {
+		f.CommunityID = models.DefaultCommunityID
+	}
+	m.facilities.put(f.ID, f)
+	return f.ID
+}
+
+// AddFacilityBooking 添加设施预约，b.ID为0时自动分配，所在社区取设施所在社区，返回预约ID
+func (m *Memory) AddFacilityBooking(b models.FacilityBooking) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b.ID = m.bookings.allocate(b.ID)
+	if f, ok := m.facilities.rows[b.FacilityID]; ok {
+		b.CommunityID = f.CommunityID
+		b.FacilityName = f.Name
+	}
+	m.bookings.put(b.ID, b)
+	return b.ID
+}
+
+// AddAsset 添加设备，a.ID为0时自动分配，未指定社区时归入默认社区，返回设备ID
+func (m *Memory) AddAsset(a models.Asset) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a.ID = m.assets.allocate(a.ID)
+	if a.CommunityID == 0 {
+		a.CommunityID = models.DefaultCommunityID
+	}
+	a.Schedules = nil
+	m.assets.put(a.ID, a)
+	return a.ID
+}
+
+// AddMaintenanceSchedule 添加设备维保计划，s.ID为0时自动分配，返回计划ID
+func (m *Memory) AddMaintenanceSchedule(s models.MaintenanceSchedule) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s.ID = m.schedules.allocate(s.ID)
+	m.schedules.put(s.ID, s)
+	return s.ID
+}
+
+// AddMaintenanceTask 添加维保任务，t.ID为0时自动分配，所在社区取设备所在社区，返回任务ID
+func (m *Memory) AddMaintenanceTask(t models.MaintenanceTask) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t.ID = m.tasks.allocate(t.ID)
+	if a, ok := m.assets.rows[t.AssetID]; ok {
+		t.CommunityID = a.CommunityID
+		t.AssetName = a.Name
+	}
+	if s, ok := m.schedules.rows[t.ScheduleID]; ok {
+		t.ScheduleName = s.Name
+	}
+	m.tasks.put(t.ID, t)
+	return t.ID
+}
+
+// AddAssetInspection 添加巡检记录，i.ID为0时自动分配，所在社区取设备所在社区，返回记录ID
+func (m *Memory) AddAssetInspection(i models.AssetInspection) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i.ID = m.inspections.allocate(i.ID)
+	if a, ok := m.assets.rows[i.AssetID]; ok {
+		i.CommunityID = a.CommunityID
+		i.AssetName = a.Name
+	}
+	m.inspections.put(i.ID, i)
+	return i.ID
+}
+
+// AddLease 添加租约，l.ID为0时自动分配，所在社区取房屋住户所在社区，返回租约ID
+func (m *Memory) AddLease(l models.Lease) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l.ID = m.leases.allocate(l.ID)
+	l.CommunityID = m.residentCommunity(l.ResidentID)
+	m.leases.put(l.ID, l)
+	return l.ID
+}
+
+// AddMeter 添加计量表，meter.ID为0时自动分配，所在社区取住户所在社区，返回计量表ID
+func (m *Memory) AddMeter(meter models.Meter) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	meter.ID = m.meters.allocate(meter.ID)
+	meter.CommunityID = m.residentCommunity(meter.ResidentID)
+	m.meters.put(meter.ID, meter)
+	return meter.ID
+}
+
+// AddVisitorPass 添加访客通行证，p.ID为0时自动分配，所在社区取被访住户所在社区，返回通行证ID
+func (m *Memory) AddVisitorPass(p models.VisitorPass) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p.ID = m.visitorPasses.allocate(p.ID)
+	p.CommunityID = m.residentCommunity(p.ResidentID)
+	m.visitorPasses.put(p.ID, p)
+	return p.ID
+}
+
+// AddVisitorLog 添加访客进出记录，l.ID为0时自动分配，住户和社区取通行证上的住户，返回记录ID
+func (m *Memory) AddVisitorLog(l models.VisitorLog) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l.ID = m.visitorLogs.allocate(l.ID)
+	if p, ok := m.visitorPasses.rows[l.PassID]; ok {
+		l.ResidentID = p.ResidentID
+		l.CommunityID = p.CommunityID
+		l.VisitorName = p.VisitorName
+	}
+	m.visitorLogs.put(l.ID, l)
+	return l.ID
+}
+
+// AddParcel 添加包裹，p.ID为0时自动分配，所在社区取收件住户所在社区，返回包裹ID
+func (m *Memory) AddParcel(p models.Parcel) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p.ID = m.parcels.allocate(p.ID)
+	p.CommunityID = m.residentCommunity(p.ResidentID)
+	m.parcels.put(p.ID, p)
+	return p.ID
+}
+
+// AddRenovation 添加装修申请，a.ID为0时自动分配，所在社区取住户所在社区，返回申请ID
+func (m *Memory) AddRenovation(a models.Renovation) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a.ID = m.renovations.allocate(a.ID)
+	a.CommunityID = m.residentCommunity(a.ResidentID)
+	m.renovations.put(a.ID, a)
+	return a.ID
+}
+
+// AddAccessCredential 添加门禁凭证，a.ID为0时自动分配，所在社区取住户所在社区，返回凭证ID
+func (m *Memory) AddAccessCredential(a models.AccessCredential) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a.ID = m.credentials.allocate(a.ID)
+	a.CommunityID = m.residentCommunity(a.ResidentID)
+	m.credentials.put(a.ID, a)
+	return a.ID
+}
+
+// AddNotification 添加通知，n.ID为0时自动分配，所在社区取住户所在社区，返回通知ID
+func (m *Memory) AddNotification(n models.Notification) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n.ID = m.notifications.allocate(n.ID)
+	n.CommunityID = m.residentCommunity(n.ResidentID)
+	m.notifications.put(n.ID, n)
+	return n.ID
+}
+
+// AddDunningRecord 添加催缴记录，rec.ID为0时自动分配，返回记录ID
+func (m *Memory) AddDunningRecord(rec models.DunningRecord) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec.ID = m.dunningRecords.allocate(rec.ID)
+	m.dunningRecords.put(rec.ID, rec)
+	return rec.ID
+}
+
+// memoryComplaints 内存实现的ComplaintRepository
+type memoryComplaints struct{ m *Memory }
+
+func (r memoryComplaints) GetComplaints(page, pageSize int, filters map[string]interface{}) ([]models.Complaint, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	complaints, total := r.m.complaints.list(page, pageSize, func(c *models.Complaint) bool {
+		return matchCommunity(filters, c.CommunityID) && matchResident(filters, c.ResidentID)
+	})
+	return complaints, total, nil
+}
+
+func (r memoryComplaints) GetComplaintByID(id int, withLogs bool) (*models.Complaint, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.complaints.get(id), nil
+}
+
+// memoryAnnouncements 内存实现的AnnouncementRepository
+type memoryAnnouncements struct{ m *Memory }
+
+func (r memoryAnnouncements) GetAnnouncements(page, pageSize int, filters map[string]interface{}) ([]models.Announcement, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	announcements, total := r.m.announcements.list(page, pageSize, func(a *models.Announcement) bool {
+		return matchCommunity(filters, a.CommunityID)
+	})
+	return announcements, total, nil
+}
+
+func (r memoryAnnouncements) GetAnnouncementByID(id int) (*models.Announcement, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.announcements.get(id), nil
+}
+
+// memoryPolls 内存实现的PollRepository
+type memoryPolls struct{ m *Memory }
+
+func (r memoryPolls) GetPolls(page, pageSize int, filters map[string]interface{}) ([]models.Poll, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	polls, total := r.m.polls.list(page, pageSize, func(p *models.Poll) bool {
+		return matchCommunity(filters, p.CommunityID)
+	})
+	return polls, total, nil
+}
+
+func (r memoryPolls) GetPollByID(id int) (*models.Poll, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.polls.get(id), nil
+}
+
+// memoryFacilities 内存实现的FacilityRepository
+type memoryFacilities struct{ m *Memory }
+
+func (r memoryFacilities) GetFacilities(communityID int, onlyEnabled bool) ([]models.Facility, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.facilities.all(func(f *models.Facility) bool {
+		return f.CommunityID == communityID && (!onlyEnabled || f.Enabled)
+	}), nil
+}
+
+func (r memoryFacilities) GetFacilityByID(id int) (*models.Facility, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.facilities.get(id), nil
+}
+
+func (r memoryFacilities) GetFacilityBookings(page, pageSize int, filters map[string]interface{}) ([]models.FacilityBooking, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	facilityID, _ := filters["facility_id"].(int)
+	bookings, total := r.m.bookings.list(page, pageSize, func(b *models.FacilityBooking) bool {
+		return matchCommunity(filters, b.CommunityID) && matchResident(filters, b.ResidentID) &&
+			(facilityID <= 0 || b.FacilityID == facilityID)
+	})
+	return bookings, total, nil
+}
+
+func (r memoryFacilities) GetFacilityBookingByID(id int) (*models.FacilityBooking, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.bookings.get(id), nil
+}
+
+// memoryAssets 内存实现的AssetRepository
+type memoryAssets struct{ m *Memory }
+
+func (r memoryAssets) GetAssets(page, pageSize int, filters map[string]interface{}) ([]models.Asset, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	assets, total := r.m.assets.list(page, pageSize, func(a *models.Asset) bool {
+		return matchCommunity(filters, a.CommunityID)
+	})
+	return assets, total, nil
+}
+
+func (r memoryAssets) GetAssetByID(id int, withSchedules bool) (*models.Asset, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	asset := r.m.assets.get(id)
+	if asset != nil && withSchedules {
+		asset.Schedules = r.m.schedules.all(func(s *models.MaintenanceSchedule) bool {
+			return s.AssetID == id
+		})
+		sort.Slice(asset.Schedules, func(i, j int) bool { return asset.Schedules[i].ID < asset.Schedules[j].ID })
+	}
+	return asset, nil
+}
+
+func (r memoryAssets) GetMaintenanceScheduleByID(id int) (*models.MaintenanceSchedule, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.schedules.get(id), nil
+}
+
+func (r memoryAssets) GetMaintenanceTasks(page, pageSize int, filters map[string]interface{}) ([]models.MaintenanceTask, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	assetID, _ := filters["asset_id"].(int)
+	tasks, total := r.m.tasks.list(page, pageSize, func(t *models.MaintenanceTask) bool {
+		return matchCommunity(filters, t.CommunityID) && (assetID <= 0 || t.AssetID == assetID)
+	})
+	return tasks, total, nil
+}
+
+func (r memoryAssets) GetAssetInspectionByID(id int) (*models.AssetInspection, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.inspections.get(id), nil
+}
+
+// memoryLeases 内存实现的LeaseRepository
+type memoryLeases struct{ m *Memory }
+
+func (r memoryLeases) GetLeases(page, pageSize int, filters map[string]interface{}) ([]models.Lease, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	leases, total := r.m.leases.list(page, pageSize, func(l *models.Lease) bool {
+		return matchCommunity(filters, l.CommunityID) && matchResident(filters, l.ResidentID)
+	})
+	return leases, total, nil
+}
+
+func (r memoryLeases) GetLeaseByID(id int) (*models.Lease, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.leases.get(id), nil
+}
+
+// memoryMeters 内存实现的MeterRepository
+type memoryMeters struct{ m *Memory }
+
+func (r memoryMeters) GetMeters(filters map[string]interface{}) ([]models.Meter, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.meters.all(func(meter *models.Meter) bool {
+		return matchCommunity(filters, meter.CommunityID) && matchResident(filters, meter.ResidentID)
+	}), nil
+}
+
+func (r memoryMeters) GetMeterByID(id int) (*models.Meter, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.meters.get(id), nil
+}
+
+// memoryVisitors 内存实现的VisitorRepository
+type memoryVisitors struct{ m *Memory }
+
+func (r memoryVisitors) GetVisitorPasses(page, pageSize int, filters map[string]interface{}) ([]models.VisitorPass, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	passes, total := r.m.visitorPasses.list(page, pageSize, func(p *models.VisitorPass) bool {
+		return matchCommunity(filters, p.CommunityID) && matchResident(filters, p.ResidentID)
+	})
+	return passes, total, nil
+}
+
+func (r memoryVisitors) GetVisitorPassByID(id int) (*models.VisitorPass, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.visitorPasses.get(id), nil
+}
+
+func (r memoryVisitors) GetVisitorLogs(page, pageSize int, filters map[string]interface{}) ([]models.VisitorLog, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	logs, total := r.m.visitorLogs.list(page, pageSize, func(l *models.VisitorLog) bool {
+		return matchCommunity(filters, l.CommunityID) && matchResident(filters, l.ResidentID)
+	})
+	return logs, total, nil
+}
+
+// memoryParcels 内存实现的ParcelRepository
+type memoryParcels struct{ m *Memory }
+
+func (r memoryParcels) GetParcels(page, pageSize int, filters map[string]interface{}) ([]models.Parcel, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	parcels, total := r.m.parcels.list(page, pageSize, func(p *models.Parcel) bool {
+		return matchCommunity(filters, p.CommunityID) && matchResident(filters, p.ResidentID)
+	})
+	return parcels, total, nil
+}
+
+func (r memoryParcels) GetParcelByID(id int) (*models.Parcel, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.parcels.get(id), nil
+}
+
+// memoryRenovations 内存实现的RenovationRepository
+type memoryRenovations struct{ m *Memory }
+
+func (r memoryRenovations) GetRenovations(page, pageSize int, filters map[string]interface{}) ([]models.Renovation, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	renovations, total := r.m.renovations.list(page, pageSize, func(a *models.Renovation) bool {
+		return matchCommunity(filters, a.CommunityID) && matchResident(filters, a.ResidentID)
+	})
+	return renovations, total, nil
+}
+
+func (r memoryRenovations) GetRenovationByID(id int) (*models.Renovation, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.renovations.get(id), nil
+}
+
+// memoryAccessCredentials 内存实现的AccessCredentialRepository
+type memoryAccessCredentials struct{ m *Memory }
+
+func (r memoryAccessCredentials) GetAccessCredentials(page, pageSize int, filters map[string]interface{}) ([]models.AccessCredential, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	credentials, total := r.m.credentials.list(page, pageSize, func(a *models.AccessCredential) bool {
+		return matchCommunity(filters, a.CommunityID) && matchResident(filters, a.ResidentID)
+	})
+	return credentials, total, nil
+}
+
+func (r memoryAccessCredentials) GetAccessCredentialByID(id int) (*models.AccessCredential, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.credentials.get(id), nil
+}
+
+// memoryNotifications 内存实现的NotificationRepository
+type memoryNotifications struct{ m *Memory }
+
+func (r memoryNotifications) GetNotifications(page, pageSize int, filters map[string]interface{}) ([]models.Notification, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	notifications, total := r.m.notifications.list(page, pageSize, func(n *models.Notification) bool {
+		return matchCommunity(filters, n.CommunityID) && matchResident(filters, n.ResidentID)
+	})
+	return notifications, total, nil
+}
+
+func (r memoryNotifications) GetNotificationByID(id int) (*models.Notification, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.notifications.get(id), nil
+}
+
+// memoryDunning 内存实现的DunningRepository
+type memoryDunning struct{ m *Memory }
+
+func (r memoryDunning) GetDunningRecords(page, pageSize int, filters map[string]interface{}) ([]models.DunningRecord, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	// 与MySQL实现一致，催缴记录按住户所属社区区分
+	records, total := r.m.dunningRecords.list(page, pageSize, func(rec *models.DunningRecord) bool {
+		return matchCommunity(filters, r.m.residentCommunity(rec.ResidentID)) && matchResident(filters, rec.ResidentID)
+	})
+	return records, total, nil
+}
@@ -0,0 +1,943 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"community-backward/models"
+)
+
+// defaultFeeAccountCode 未指定科目的收费项目记入物业服务收入，与models一致
+const defaultFeeAccountCode = "6001.01"
+
+// periodKey 社区的一个会计月份
+type periodKey struct {
+	communityID int
+	year        int
+	month       int
+}
+
+// statsKey 月度统计按社区、月份和收费项目保存
+type statsKey struct {
+	periodKey
+	feeTypeID int
+}
+
+// creditKey 缴费只冲抵同一住户同一收费项目的账单
+type creditKey struct {
+	residentID int
+	feeTypeID  int
+}
+
+// roundAmount 金额保留两位小数
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// daysBetween 计算两个日期之间相差的自然日
+func daysBetween(from, to time.Time) int {
+	f := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	t := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.Local)
+	return int(t.Sub(f).Hours() / 24)
+}
+
+// remove 删除记录
+func (t *memoryTable[T]) remove(id int) {
+	delete(t.rows, id)
+}
+
+// sortedFeeTypes 按ID升序返回收费项目，调用方需持有锁
+func (m *Memory) sortedFeeTypes() []models.FeeType {
+	types := make([]models.FeeType, 0, len(m.feeTypes))
+	for _, t := range m.feeTypes {
+		types = append(types, *t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].ID < types[j].ID })
+	return types
+}
+
+// lastClosedPeriod 返回社区最近一个已结账的账期，没有已结账的账期时返回0, 0，调用方需持有锁
+func (m *Memory) lastClosedPeriod(communityID int) (int, int) {
+	var year, month int
+	for k, p := range m.periods {
+		if k.communityID != communityID || p.Status != models.PeriodClosed {
+			continue
+		}
+		if k.year > year || (k.year == year && k.month > month) {
+			year, month = k.year, k.month
+		}
+	}
+	return year, month
+}
+
+// checkPeriodOpen 检查社区的指定账期是否允许修改，规则与models.CheckPeriodOpen一致，调用方需持有锁
+func (m *Memory) checkPeriodOpen(communityID, year, month int) error {
+	closedYear, closedMonth := m.lastClosedPeriod(communityID)
+	if closedYear > year || (closedYear == year && closedMonth >= month) {
+		return fmt.Errorf("%w: %d年%d月已结账，不能修改", models.ErrPeriodClosed, year, month)
+	}
+	return nil
+}
+
+// checkDateOpen 检查社区日期所在账期是否允许修改，调用方需持有锁
+func (m *Memory) checkDateOpen(communityID int, t time.Time) error {
+	return m.checkPeriodOpen(communityID, t.Year(), int(t.Month()))
+}
+
+// findJournal 返回来源单据的凭证，未记账时返回nil，调用方需持有锁
+func (m *Memory) findJournal(sourceType string, sourceID int) *models.JournalEntry {
+	for i := range m.journal {
+		if m.journal[i].SourceType == sourceType && m.journal[i].SourceID == sourceID {
+			return &m.journal[i]
+		}
+	}
+	return nil
+}
+
+// postJournal 保存记账凭证，规则与models.PostJournal一致：同一来源单据只记账一次，
+// 未指定社区时归入分录住户所属社区，凭证号按社区和记账月份连续编号，调用方需持有锁
+func (m *Memory) postJournal(entry models.JournalEntry) error {
+	var debit, credit float64
+	for _, l := range entry.Lines {
+		debit += l.Debit
+		credit += l.Credit
+	}
+	if math.Abs(debit-credit) > 0.005 || debit == 0 {
+		return fmt.Errorf("%w: 借方%.2f，贷方%.2f", models.ErrUnbalancedEntry, debit, credit)
+	}
+
+	if entry.CommunityID == 0 {
+		for _, l := range entry.Lines {
+			if l.ResidentID > 0 {
+				entry.CommunityID = m.residentCommunity(l.ResidentID)
+				if entry.CommunityID == 0 {
+					return fmt.Errorf("住户%d不存在", l.ResidentID)
+				}
+				break
+			}
+		}
+		if entry.CommunityID == 0 {
+			return fmt.Errorf("凭证%s未关联社区", entry.Memo)
+		}
+	}
+
+	if m.findJournal(entry.SourceType, entry.SourceID) != nil {
+		return nil // 已记账
+	}
+
+	key := periodKey{entry.CommunityID, entry.EntryDate.Year(), int(entry.EntryDate.Month())}
+	m.voucherNos[key]++
+	entry.ID = len(m.journal) + 1
+	entry.VoucherNo = m.voucherNos[key]
+	lines := make([]models.JournalLine, len(entry.Lines))
+	for i, l := range entry.Lines {
+		l.ID = i + 1
+		l.EntryID = entry.ID
+		l.Debit = roundAmount(l.Debit)
+		l.Credit = roundAmount(l.Credit)
+		lines[i] = l
+	}
+	entry.Lines = lines
+	m.journal = append(m.journal, entry)
+	return nil
+}
+
+// reverseJournal 为来源单据的凭证生成借贷方向相反的冲销凭证，来源单据未记账时不做处理，调用方需持有锁
+func (m *Memory) reverseJournal(sourceType string, sourceID int, reverseType string, date time.Time) error {
+	original := m.findJournal(sourceType, sourceID)
+	if original == nil {
+		return nil
+	}
+
+	reversal := models.JournalEntry{
+		CommunityID: original.CommunityID,
+		EntryDate:   date,
+		SourceType:  reverseType,
+		SourceID:    sourceID,
+		Memo:        "冲销" + original.Memo,
+	}
+	for _, l := range original.Lines {
+		reversal.Lines = append(reversal.Lines, models.JournalLine{
+			AccountCode: l.AccountCode,
+			ResidentID:  l.ResidentID,
+			Debit:       l.Credit,
+			Credit:      l.Debit,
+			Memo:        "冲销" + l.Memo,
+		})
+	}
+	return m.postJournal(reversal)
+}
+
+// postBill 账单记账：借 应收账款，贷 收费项目对应科目，记账日期为账单所属月份的最后一天，调用方需持有锁
+func (m *Memory) postBill(b *models.Bill) error {
+	feeType, ok := m.feeTypes[b.FeeTypeID]
+	if !ok {
+		return fmt.Errorf("收费项目%d不存在", b.FeeTypeID)
+	}
+
+	memo := fmt.Sprintf("应收%d年%d月%s", b.Year, b.Month, feeType.Name)
+	return m.postJournal(models.JournalEntry{
+		EntryDate:  time.Date(b.Year, time.Month(b.Month)+1, 0, 0, 0, 0, 0, time.Local),
+		SourceType: models.JournalSourceBill,
+		SourceID:   b.ID,
+		Memo:       memo,
+		Lines: []models.JournalLine{
+			{AccountCode: models.AccountReceivable, ResidentID: b.ResidentID, Debit: b.Amount, Memo: memo},
+			{AccountCode: feeType.AccountCode, ResidentID: b.ResidentID, Credit: b.Amount, Memo: memo},
+		},
+	})
+}
+
+// postPayment 收款记账：借 现金/银行存款/其他货币资金，贷 应收账款，fee需带住户姓名和收费项目名称，调用方需持有锁
+func (m *Memory) postPayment(fee models.PropertyFee) error {
+	memo := fmt.Sprintf("收%s%s", fee.ResidentName, fee.FeeTypeName)
+	return m.postJournal(models.JournalEntry{
+		EntryDate:  fee.PaymentDate,
+		SourceType: models.JournalSourcePayment,
+		SourceID:   fee.ID,
+		Memo:       memo,
+		Lines: []models.JournalLine{
+			{AccountCode: models.PaymentAccount(fee.PaymentMethod), ResidentID: fee.ResidentID, Debit: fee.Amount, Memo: memo},
+			{AccountCode: models.AccountReceivable, ResidentID: fee.ResidentID, Credit: fee.Amount, Memo: memo},
+		},
+	})
+}
+
+// postRefund 退款记账：借 应收账款，贷 现金/银行存款/其他货币资金，fee需带住户姓名和收费项目名称，调用方需持有锁
+func (m *Memory) postRefund(fee models.PropertyFee, refundDate time.Time) error {
+	memo := fmt.Sprintf("退%s%s", fee.ResidentName, fee.FeeTypeName)
+	return m.postJournal(models.JournalEntry{
+		EntryDate:  refundDate,
+		SourceType: models.JournalSourceRefund,
+		SourceID:   fee.ID,
+		Memo:       memo,
+		Lines: []models.JournalLine{
+			{AccountCode: models.AccountReceivable, ResidentID: fee.ResidentID, Debit: fee.Amount, Memo: memo},
+			{AccountCode: models.PaymentAccount(fee.PaymentMethod), ResidentID: fee.ResidentID, Credit: fee.Amount, Memo: memo},
+		},
+	})
+}
+
+// refreshMonthlyStats 按收费项目重新计算社区指定月份的月度统计，规则与models.RefreshMonthlyStats一致
+// 调用方需持有锁并检查账期
+func (m *Memory) refreshMonthlyStats(communityID, year, month int) {
+	now := time.Now()
+	for _, t := range m.feeTypes {
+		if t.PricingRule == models.PricingDeposit {
+			continue
+		}
+
+		stats := models.PropertyFeeMonthlyStats{Year: year, Month: month, FeeTypeID: t.ID, UpdatedAt: now}
+		for _, fee := range m.fees {
+			if fee.FeeTypeID == t.ID && fee.PaymentStatus == models.PaymentPaid && m.residentCommunity(fee.ResidentID) == communityID &&
+				fee.PaymentDate.Year() == year && int(fee.PaymentDate.Month()) == month {
+				stats.ActualIncome += fee.Amount
+			}
+		}
+		for _, b := range m.bills.rows {
+			if b.FeeTypeID == t.ID && b.Year == year && b.Month == month && m.residentCommunity(b.ResidentID) == communityID {
+				stats.PlannedIncome += b.Amount
+			}
+		}
+		stats.ActualIncome = roundAmount(stats.ActualIncome)
+		stats.PlannedIncome = roundAmount(stats.PlannedIncome)
+
+		key := statsKey{periodKey{communityID, year, month}, t.ID}
+		if old, ok := m.monthlyStats[key]; ok {
+			stats.ID, stats.CreatedAt = old.ID, old.CreatedAt
+		} else {
+			stats.ID, stats.CreatedAt = len(m.monthlyStats)+1, now
+		}
+		m.monthlyStats[key] = &stats
+	}
+}
+
+// openBills 返回截至asOf尚未结清的已到期账单，已缴金额按住户和收费项目先进先出冲抵，
+// 规则与models.GetOpenBills一致，communityID为0时返回所有社区，调用方需持有锁
+func (m *Memory) openBills(asOf time.Time, communityID int) []models.OpenBill {
+	var bills []models.OpenBill
+	for _, b := range m.bills.rows {
+		if b.DueDate.After(asOf) || (communityID > 0 && m.residentCommunity(b.ResidentID) != communityID) {
+			continue
+		}
+		bills = append(bills, models.OpenBill{
+			BillID:      b.ID,
+			ResidentID:  b.ResidentID,
+			FeeTypeID:   b.FeeTypeID,
+			Year:        b.Year,
+			Month:       b.Month,
+			DueDate:     b.DueDate,
+			Amount:      b.Amount,
+			Outstanding: b.Amount,
+		})
+	}
+	sort.Slice(bills, func(i, j int) bool {
+		a, b := bills[i], bills[j]
+		if a.ResidentID != b.ResidentID {
+			return a.ResidentID < b.ResidentID
+		}
+		if !a.DueDate.Equal(b.DueDate) {
+			return a.DueDate.Before(b.DueDate)
+		}
+		return a.BillID < b.BillID
+	})
+
+	// asOf之后才退款的缴费在asOf时仍然有效
+	credits := make(map[creditKey]float64)
+	for _, fee := range m.fees {
+		if fee.PaymentDate.After(asOf) {
+			continue
+		}
+		refundedAt, _ := time.ParseInLocation("2006-01-02", fee.RefundedAt, time.Local)
+		if fee.PaymentStatus == models.PaymentPaid || (fee.PaymentStatus == models.PaymentRefunded && refundedAt.After(asOf)) {
+			credits[creditKey{fee.ResidentID, fee.FeeTypeID}] += fee.Amount
+		}
+	}
+
+	var open []models.OpenBill
+	for _, b := range bills {
+		key := creditKey{b.ResidentID, b.FeeTypeID}
+		applied := math.Min(credits[key], b.Outstanding)
+		b.Outstanding = roundAmount(b.Outstanding - applied)
+		credits[key] -= applied
+		if b.Outstanding > 0 {
+			open = append(open, b)
+		}
+	}
+	return open
+}
+
+// payingTenant 返回asOf当天有生效租约且约定由租户缴费时的主租户，否则返回nil，调用方需持有锁
+func (m *Memory) payingTenant(residentID int, asOf time.Time) *models.LeaseTenant {
+	date := asOf.Format("2006-01-02")
+	var lease *models.Lease
+	for _, l := range m.leases.rows {
+		if l.ResidentID != residentID || l.Status != models.LeaseActive || l.FeePayer != models.FeePayerTenant ||
+			l.StartDate.Format("2006-01-02") > date || l.EndDate.Format("2006-01-02") < date {
+			continue
+		}
+		if lease == nil || l.StartDate.After(lease.StartDate) {
+			lease = l
+		}
+	}
+	if lease == nil {
+		return nil
+	}
+	for i := range lease.Tenants {
+		if lease.Tenants[i].IsPrimary {
+			return &lease.Tenants[i]
+		}
+	}
+	return nil
+}
+
+// collectionGroup 按住户累计应收和未收金额
+type collectionGroup struct {
+	rate      models.CollectionRate
+	residents map[int]bool
+	unpaid    map[int]bool
+}
+
+func newCollectionGroup(building, unit string) *collectionGroup {
+	return &collectionGroup{
+		rate:      models.CollectionRate{Building: building, Unit: unit},
+		residents: make(map[int]bool),
+		unpaid:    make(map[int]bool),
+	}
+}
+
+func (g *collectionGroup) add(residentID int, due, outstanding float64) {
+	g.rate.AmountDue += due
+	g.rate.AmountCollected += due - outstanding
+	g.residents[residentID] = true
+	if outstanding > 0 {
+		g.unpaid[residentID] = true
+	}
+}
+
+func (g *collectionGroup) result() models.CollectionRate {
+	r := g.rate
+	r.AmountDue = roundAmount(r.AmountDue)
+	r.AmountCollected = roundAmount(r.AmountCollected)
+	r.Households = len(g.residents)
+	r.PaidHouseholds = r.Households - len(g.unpaid)
+	if r.AmountDue > 0 {
+		r.AmountRate = math.Round(r.AmountCollected/r.AmountDue*10000) / 100
+	}
+	if r.Households > 0 {
+		r.HouseholdRate = math.Round(float64(r.PaidHouseholds)/float64(r.Households)*10000) / 100
+	}
+	return r
+}
+
+// collectionReport 计算截至asOf的收缴率，规则与models.GetCollectionReport一致，没有减免，调用方需持有锁
+func (m *Memory) collectionReport(year, month int, groupBy string, feeTypeID int, asOf time.Time, communityID int) *models.CollectionReport {
+	outstanding := make(map[int]float64)
+	for _, b := range m.openBills(asOf, communityID) {
+		outstanding[b.BillID] = b.Outstanding
+	}
+
+	total := newCollectionGroup("", "")
+	groups := make(map[string]*collectionGroup)
+	for _, b := range m.bills.rows {
+		if b.Year != year || b.DueDate.After(asOf) || (month > 0 && b.Month != month) || (feeTypeID > 0 && b.FeeTypeID != feeTypeID) {
+			continue
+		}
+		res, ok := m.residents[b.ResidentID]
+		if !ok || (communityID > 0 && res.CommunityID != communityID) {
+			continue
+		}
+
+		total.add(b.ResidentID, b.Amount, outstanding[b.ID])
+
+		key, unit := res.BlockNumber, ""
+		if groupBy == "unit" {
+			unit = res.UnitNumber
+			key += "/" + unit
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = newCollectionGroup(res.BlockNumber, unit)
+			groups[key] = g
+		}
+		g.add(b.ResidentID, b.Amount, outstanding[b.ID])
+	}
+
+	report := &models.CollectionReport{
+		Year:    year,
+		Month:   month,
+		GroupBy: groupBy,
+		AsOf:    asOf.Format("2006-01-02"),
+		Total:   total.result(),
+		Groups:  []models.CollectionRate{},
+	}
+	for _, g := range groups {
+		report.Groups = append(report.Groups, g.result())
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.Building != b.Building {
+			return a.Building < b.Building
+		}
+		return a.Unit < b.Unit
+	})
+	return report
+}
+
+// memoryFeeTypes 内存实现的FeeTypeRepository
+type memoryFeeTypes struct{ m *Memory }
+
+func (r memoryFeeTypes) GetFeeTypes(onlyEnabled bool) ([]models.FeeType, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var types []models.FeeType
+	for _, t := range r.m.sortedFeeTypes() {
+		if onlyEnabled && !t.Enabled {
+			continue
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+func (r memoryFeeTypes) GetFeeTypeByID(id int) (*models.FeeType, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	t, ok := r.m.feeTypes[id]
+	if !ok {
+		return nil, nil
+	}
+	feeType := *t
+	return &feeType, nil
+}
+
+func (r memoryFeeTypes) GetFeeTypeByCode(code string) (*models.FeeType, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for _, t := range r.m.feeTypes {
+		if t.Code == code {
+			feeType := *t
+			return &feeType, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkCode 检查收费项目编码是否已被其他项目使用，调用方需持有锁
+func (r memoryFeeTypes) checkCode(code string, excludeID int) error {
+	for _, t := range r.m.feeTypes {
+		if t.Code == code && t.ID != excludeID {
+			return fmt.Errorf("收费项目编码%s已存在", code)
+		}
+	}
+	return nil
+}
+
+// applyFeeTypeRequest 将收费项目请求写入收费项目，未指定科目时记入物业服务收入
+func applyFeeTypeRequest(t *models.FeeType, req models.FeeTypeRequest) {
+	if req.AccountCode == "" {
+		req.AccountCode = defaultFeeAccountCode
+	}
+	t.Code = req.Code
+	t.Name = req.Name
+	t.PricingRule = req.PricingRule
+	t.UnitPrice = req.UnitPrice
+	t.Unit = req.Unit
+	t.AccountingCategory = req.AccountingCategory
+	t.AccountCode = req.AccountCode
+	t.Enabled = req.Enabled
+	t.UpdatedAt = time.Now()
+}
+
+func (r memoryFeeTypes) CreateFeeType(req models.FeeTypeRequest) (int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if err := r.checkCode(req.Code, 0); err != nil {
+		return 0, err
+	}
+
+	r.m.nextFeeTypeID++
+	t := &models.FeeType{ID: r.m.nextFeeTypeID, CreatedAt: time.Now()}
+	applyFeeTypeRequest(t, req)
+	r.m.feeTypes[t.ID] = t
+	return t.ID, nil
+}
+
+func (r memoryFeeTypes) UpdateFeeType(id int, req models.FeeTypeRequest) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if err := r.checkCode(req.Code, id); err != nil {
+		return err
+	}
+	if t, ok := r.m.feeTypes[id]; ok {
+		applyFeeTypeRequest(t, req)
+	}
+	return nil
+}
+
+// memoryBills 内存实现的BillRepository
+type memoryBills struct{ m *Memory }
+
+// bill 返回带住户姓名和收费项目名称的账单副本，调用方需持有锁
+func (r memoryBills) bill(b *models.Bill) models.Bill {
+	copied := *b
+	if res, ok := r.m.residents[copied.ResidentID]; ok {
+		copied.ResidentName = res.Name
+	}
+	if t, ok := r.m.feeTypes[copied.FeeTypeID]; ok {
+		copied.FeeTypeName = t.Name
+	}
+	return copied
+}
+
+// hasBill 判断住户指定收费项目指定月份的账单是否已存在，调用方需持有锁
+func (r memoryBills) hasBill(residentID, feeTypeID, year, month int) bool {
+	for _, b := range r.m.bills.rows {
+		if b.ResidentID == residentID && b.FeeTypeID == feeTypeID && b.Year == year && b.Month == month {
+			return true
+		}
+	}
+	return false
+}
+
+// insert 保存账单并记账，调用方需持有锁并检查账期
+func (r memoryBills) insert(b models.Bill) (int, error) {
+	now := time.Now()
+	b.ID = r.m.bills.allocate(0)
+	b.CreatedAt = now
+	b.UpdatedAt = now
+	if err := r.m.postBill(&b); err != nil {
+		return 0, fmt.Errorf("账单记账失败: %w", err)
+	}
+	r.m.bills.put(b.ID, b)
+	return b.ID, nil
+}
+
+func (r memoryBills) GetBills(page, pageSize int, filters map[string]interface{}) ([]models.Bill, int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	var matched []models.Bill
+	for _, b := range r.m.bills.rows {
+		res, ok := r.m.residents[b.ResidentID]
+		if !ok || !matchCommunity(filters, res.CommunityID) || !matchResident(filters, b.ResidentID) {
+			continue
+		}
+		if feeTypeID, ok := filters["fee_type_id"].(int); ok && feeTypeID > 0 && b.FeeTypeID != feeTypeID {
+			continue
+		}
+		if year, ok := filters["year"].(int); ok && year > 0 && b.Year != year {
+			continue
+		}
+		if month, ok := filters["month"].(int); ok && month > 0 && b.Month != month {
+			continue
+		}
+		matched = append(matched, r.bill(b))
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].DueDate.Equal(matched[j].DueDate) {
+			return matched[i].DueDate.After(matched[j].DueDate)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+
+	start, end := paginate(len(matched), page, pageSize)
+	var bills []models.Bill
+	if start < end {
+		bills = matched[start:end]
+	}
+	return bills, len(matched), nil
+}
+
+func (r memoryBills) GetBillByID(id int) (*models.Bill, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	b, ok := r.m.bills.rows[id]
+	if !ok {
+		return nil, nil
+	}
+	bill := r.bill(b)
+	return &bill, nil
+}
+
+func (r memoryBills) CreateBill(req models.BillRequest) (int, error) {
+	dueDate, err := time.ParseInLocation("2006-01-02", req.DueDate, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("无效的到期日期: %s", req.DueDate)
+	}
+
+	feeTypeID := req.FeeTypeID
+	if feeTypeID == 0 {
+		feeTypeID = models.DefaultFeeTypeID
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	communityID := r.m.residentCommunity(req.ResidentID)
+	if communityID == 0 {
+		return 0, fmt.Errorf("住户%d不存在", req.ResidentID)
+	}
+	if err := r.m.checkPeriodOpen(communityID, req.Year, req.Month); err != nil {
+		return 0, err
+	}
+	if r.hasBill(req.ResidentID, feeTypeID, req.Year, req.Month) {
+		return 0, fmt.Errorf("住户%d的%d年%d月账单已存在", req.ResidentID, req.Year, req.Month)
+	}
+
+	return r.insert(models.Bill{
+		ResidentID: req.ResidentID,
+		FeeTypeID:  feeTypeID,
+		Year:       req.Year,
+		Month:      req.Month,
+		Amount:     req.Amount,
+		DueDate:    dueDate,
+		Remark:     req.Remark,
+	})
+}
+
+func (r memoryBills) GenerateBills(feeType *models.FeeType, year, month int, dueDate time.Time, communityID int) (int, error) {
+	if err := feeType.CheckBatchBilling(); err != nil {
+		return 0, err
+	}
+
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if err := r.m.checkPeriodOpen(communityID, year, month); err != nil {
+		return 0, err
+	}
+
+	var residents []*models.Resident
+	for _, res := range r.m.residents {
+		if res.CommunityID == communityID {
+			residents = append(residents, res)
+		}
+	}
+	sort.Slice(residents, func(i, j int) bool { return residents[i].ID < residents[j].ID })
+
+	remark := fmt.Sprintf("%d年%d月%s", year, month, feeType.Name)
+	created := 0
+	for _, res := range residents {
+		amount := feeType.CalculateFee(res, 0)
+		if amount <= 0 || r.hasBill(res.ID, feeType.ID, year, month) {
+			continue
+		}
+		if _, err := r.insert(models.Bill{
+			ResidentID: res.ID,
+			FeeTypeID:  feeType.ID,
+			Year:       year,
+			Month:      month,
+			Amount:     amount,
+			DueDate:    dueDate,
+			Remark:     remark,
+		}); err != nil {
+			return created, fmt.Errorf("生成住户%d账单失败: %w", res.ID, err)
+		}
+		created++
+	}
+	return created, nil
+}
+
+// GenerateUsageBills 内存仓储没有抄表记录，检查账期后返回0
+func (r memoryBills) GenerateUsageBills(feeType *models.FeeType, year, month int, dueDate time.Time, communityID int) (int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return 0, r.m.checkPeriodOpen(communityID, year, month)
+}
+
+// GenerateParkingBills 内存仓储没有车位租赁合同，检查账期后返回0
+func (r memoryBills) GenerateParkingBills(feeType *models.FeeType, year, month int, dueDate time.Time, communityID int) (int, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return 0, r.m.checkPeriodOpen(communityID, year, month)
+}
+
+func (r memoryBills) DeleteBill(id int) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	b, ok := r.m.bills.rows[id]
+	if !ok {
+		return nil
+	}
+
+	communityID := r.m.residentCommunity(b.ResidentID)
+	if err := r.m.checkPeriodOpen(communityID, b.Year, b.Month); err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := r.m.checkDateOpen(communityID, now); err != nil {
+		return err
+	}
+
+	if err := r.m.reverseJournal(models.JournalSourceBill, id, models.JournalSourceBillVoid, now); err != nil {
+		return err
+	}
+	r.m.bills.remove(id)
+	return nil
+}
+
+func (r memoryBills) GetOverdueAccounts(asOf time.Time, communityID int) ([]models.OverdueAccount, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	accounts := make(map[int]*models.OverdueAccount)
+	for _, b := range r.m.openBills(asOf, communityID) {
+		acc, ok := accounts[b.ResidentID]
+		if !ok {
+			acc = &models.OverdueAccount{ResidentID: b.ResidentID, OldestDueDate: b.DueDate}
+			accounts[b.ResidentID] = acc
+		}
+		acc.Amount = roundAmount(acc.Amount + b.Outstanding)
+		if b.DueDate.Before(acc.OldestDueDate) {
+			acc.OldestDueDate = b.DueDate
+		}
+	}
+
+	result := []models.OverdueAccount{}
+	for id, acc := range accounts {
+		res, ok := r.m.residents[id]
+		if !ok {
+			continue
+		}
+		acc.CommunityID = res.CommunityID
+		acc.BlockNumber = res.BlockNumber
+		acc.UnitNumber = res.UnitNumber
+		acc.HouseNumber = res.HouseNumber
+		acc.Payer, acc.Name, acc.Phone, acc.Email = models.FeePayerOwner, res.Name, res.Phone, res.Email
+		if tenant := r.m.payingTenant(id, asOf); tenant != nil {
+			acc.Payer, acc.Name, acc.Phone, acc.Email = models.FeePayerTenant, tenant.Name, tenant.Phone, tenant.Email
+		}
+		acc.OverdueDays = daysBetween(acc.OldestDueDate, asOf)
+		result = append(result, *acc)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].OverdueDays != result[j].OverdueDays {
+			return result[i].OverdueDays > result[j].OverdueDays
+		}
+		return result[i].ResidentID < result[j].ResidentID
+	})
+	return result, nil
+}
+
+// memoryDashboard 内存实现的DashboardRepository
+type memoryDashboard struct{ m *Memory }
+
+func (r memoryDashboard) GetDashboardStats(communityID int) (*models.DashboardStats, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	stats := &models.DashboardStats{IncomeByType: []models.FeeTypeIncome{}, ParkingRate: "--"}
+	for _, res := range r.m.residents {
+		if res.CommunityID == communityID {
+			stats.ResidentCount++
+		}
+	}
+
+	// 押金需要退还，不计入收入
+	year := time.Now().Year()
+	for _, t := range r.m.sortedFeeTypes() {
+		if t.PricingRule == models.PricingDeposit {
+			continue
+		}
+		income := models.FeeTypeIncome{FeeTypeID: t.ID, Code: t.Code, Name: t.Name, AccountingCategory: t.AccountingCategory}
+		for _, fee := range r.m.fees {
+			if fee.FeeTypeID == t.ID && fee.PaymentStatus == models.PaymentPaid && fee.PaymentDate.Year() == year &&
+				r.m.residentCommunity(fee.ResidentID) == communityID {
+				income.Total += fee.Amount
+			}
+		}
+		income.Total = roundAmount(income.Total)
+		stats.YearlyIncome = roundAmount(stats.YearlyIncome + income.Total)
+		stats.IncomeByType = append(stats.IncomeByType, income)
+	}
+	return stats, nil
+}
+
+func (r memoryDashboard) GetIncomeData(year, feeTypeID, communityID int) (*models.IncomeData, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	data := &models.IncomeData{Actual: make([]float64, 12), Planned: make([]float64, 12)}
+	for k, s := range r.m.monthlyStats {
+		if k.year != year || (feeTypeID > 0 && k.feeTypeID != feeTypeID) || (communityID > 0 && k.communityID != communityID) {
+			continue
+		}
+		data.Actual[k.month-1] = roundAmount(data.Actual[k.month-1] + s.ActualIncome)
+		data.Planned[k.month-1] = roundAmount(data.Planned[k.month-1] + s.PlannedIncome)
+	}
+	return data, nil
+}
+
+func (r memoryDashboard) GetIncomeByType(year, communityID int) ([]models.FeeTypeIncome, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	incomes := []models.FeeTypeIncome{}
+	for _, t := range r.m.sortedFeeTypes() {
+		if t.PricingRule == models.PricingDeposit {
+			continue
+		}
+		income := models.FeeTypeIncome{
+			FeeTypeID:          t.ID,
+			Code:               t.Code,
+			Name:               t.Name,
+			AccountingCategory: t.AccountingCategory,
+			Actual:             make([]float64, 12),
+			Planned:            make([]float64, 12),
+		}
+		for k, s := range r.m.monthlyStats {
+			if k.feeTypeID != t.ID || k.year != year || (communityID > 0 && k.communityID != communityID) {
+				continue
+			}
+			income.Actual[k.month-1] = roundAmount(income.Actual[k.month-1] + s.ActualIncome)
+			income.Planned[k.month-1] = roundAmount(income.Planned[k.month-1] + s.PlannedIncome)
+			income.Total = roundAmount(income.Total + s.ActualIncome)
+		}
+		incomes = append(incomes, income)
+	}
+	return incomes, nil
+}
+
+func (r memoryDashboard) GetCollectionReport(year, month int, groupBy string, feeTypeID int, asOf time.Time, communityID int) (*models.CollectionReport, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	return r.m.collectionReport(year, month, groupBy, feeTypeID, asOf, communityID), nil
+}
+
+func (r memoryDashboard) RefreshMonthlyStats(communityID, year, month int) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	if err := r.m.checkPeriodOpen(communityID, year, month); err != nil {
+		return err
+	}
+	r.m.refreshMonthlyStats(communityID, year, month)
+	return nil
+}
+
+// memoryPeriods 内存实现的PeriodRepository
+type memoryPeriods struct{ m *Memory }
+
+func (r memoryPeriods) GetAccountingPeriods(communityID, year int) ([]models.AccountingPeriod, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	periods := []models.AccountingPeriod{}
+	for k, p := range r.m.periods {
+		if k.communityID == communityID && k.year == year {
+			periods = append(periods, *p)
+		}
+	}
+	sort.Slice(periods, func(i, j int) bool { return periods[i].Month < periods[j].Month })
+	return periods, nil
+}
+
+func (r memoryPeriods) IsPeriodClosed(communityID, year, month int) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	closedYear, closedMonth := r.m.lastClosedPeriod(communityID)
+	return closedYear > year || (closedYear == year && closedMonth >= month), nil
+}
+
+// ClosePeriod 结账前刷新该社区该月的月度统计，结账后统计冻结
+func (r memoryPeriods) ClosePeriod(communityID, year, month int, userID uint) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	key := periodKey{communityID, year, month}
+	p, ok := r.m.periods[key]
+	if !ok {
+		r.m.nextPeriodID++
+		p = &models.AccountingPeriod{ID: r.m.nextPeriodID, CommunityID: communityID, Year: year, Month: month, Status: models.PeriodOpen}
+		r.m.periods[key] = p
+	}
+	if err := r.m.checkPeriodOpen(communityID, year, month); err != nil {
+		return err
+	}
+
+	r.m.refreshMonthlyStats(communityID, year, month)
+
+	now := time.Now()
+	p.Status = models.PeriodClosed
+	p.ClosedBy = int(userID)
+	p.ClosedAt = &now
+	return nil
+}
+
+func (r memoryPeriods) ReopenPeriod(communityID, year, month int, userID uint, reason string) (bool, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+
+	for k, p := range r.m.periods {
+		if k.communityID == communityID && p.Status == models.PeriodClosed && (k.year > year || (k.year == year && k.month > month)) {
+			return false, fmt.Errorf("%w: %d年%d月", models.ErrLaterPeriodClosed, year, month)
+		}
+	}
+
+	p, ok := r.m.periods[periodKey{communityID, year, month}]
+	if !ok || p.Status != models.PeriodClosed {
+		return false, nil
+	}
+	now := time.Now()
+	p.Status = models.PeriodOpen
+	p.ReopenedBy = int(userID)
+	p.ReopenedAt = &now
+	p.ReopenReason = reason
+	return true, nil
+}
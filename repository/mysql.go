@@ -0,0 +1,413 @@
+package repository
+
+import (
+	"time"
+
+	"community-backward/models"
+)
+
+// NewMySQL 创建MySQL实现的仓储，数据访问委托给models包，使用database.DB连接
+func NewMySQL() *Repositories {
+	return &Repositories{
+		Users:             mysqlUsers{},
+		Communities:       mysqlCommunities{},
+		Residents:         mysqlResidents{},
+		FeeTypes:          mysqlFeeTypes{},
+		Fees:              mysqlFees{},
+		Bills:             mysqlBills{},
+		Dashboard:         mysqlDashboard{},
+		Periods:           mysqlPeriods{},
+		AuditLogs:         mysqlAuditLogs{},
+		Complaints:        mysqlComplaints{},
+		Announcements:     mysqlAnnouncements{},
+		Polls:             mysqlPolls{},
+		Facilities:        mysqlFacilities{},
+		Assets:            mysqlAssets{},
+		Leases:            mysqlLeases{},
+		Meters:            mysqlMeters{},
+		Visitors:          mysqlVisitors{},
+		Parcels:           mysqlParcels{},
+		Renovations:       mysqlRenovations{},
+		AccessCredentials: mysqlAccessCredentials{},
+		Notifications:     mysqlNotifications{},
+		Dunning:           mysqlDunning{},
+	}
+}
+
+// mysqlUsers MySQL实现的UserRepository
+type mysqlUsers struct{}
+
+func (mysqlUsers) FindUser(username, password string) (*models.User, error) {
+	return models.FindUser(username, password)
+}
+
+func (mysqlUsers) GetUserByID(id uint) (*models.User, error) {
+	return models.GetUserByID(id)
+}
+
+func (mysqlUsers) CreateResidentAccount(residentID int, req models.ResidentAccountRequest) (uint, error) {
+	return models.CreateResidentAccount(residentID, req)
+}
+
+func (mysqlUsers) GetUserCommunities(user *models.User) ([]models.Community, error) {
+	return models.GetUserCommunities(user)
+}
+
+func (mysqlUsers) ResolveUserCommunity(user *models.User) (*models.Community, error) {
+	return models.ResolveUserCommunity(user)
+}
+
+func (mysqlUsers) SwitchUserCommunity(user *models.User, communityID int) (*models.Community, error) {
+	return models.SwitchUserCommunity(user, communityID)
+}
+
+// mysqlCommunities MySQL实现的CommunityRepository
+type mysqlCommunities struct{}
+
+func (mysqlCommunities) GetCommunities(onlyEnabled bool) ([]models.Community, error) {
+	return models.GetCommunities(onlyEnabled)
+}
+
+func (mysqlCommunities) GetCommunityByID(id int) (*models.Community, error) {
+	return models.GetCommunityByID(id)
+}
+
+func (mysqlCommunities) CreateCommunity(req models.CommunityRequest) (int, error) {
+	return models.CreateCommunity(req)
+}
+
+func (mysqlCommunities) UpdateCommunity(id int, req models.CommunityRequest) error {
+	return models.UpdateCommunity(id, req)
+}
+
+func (mysqlCommunities) GetCommunityMembers(communityID int) ([]models.User, error) {
+	return models.GetCommunityMembers(communityID)
+}
+
+func (mysqlCommunities) AddCommunityMember(communityID int, userID uint) error {
+	return models.AddCommunityMember(communityID, userID)
+}
+
+func (mysqlCommunities) RemoveCommunityMember(communityID int, userID uint) error {
+	return models.RemoveCommunityMember(communityID, userID)
+}
+
+func (mysqlCommunities) GetCommunityRollup(communities []models.Community, year int, asOf time.Time) ([]models.CommunityRollup, *models.CommunityRollup, error) {
+	return models.GetCommunityRollup(communities, year, asOf)
+}
+
+// mysqlResidents MySQL实现的ResidentRepository
+type mysqlResidents struct{}
+
+func (mysqlResidents) GetResidents(page, pageSize int, filters map[string]interface{}) ([]models.Resident, int, error) {
+	return models.GetResidents(page, pageSize, filters)
+}
+
+func (mysqlResidents) GetResidentByID(id int) (*models.Resident, error) {
+	return models.GetResidentByID(id)
+}
+
+func (mysqlResidents) CreateResident(req models.ResidentRequest, communityID int) (int, error) {
+	return models.CreateResident(req, communityID)
+}
+
+func (mysqlResidents) UpdateResident(id int, req models.ResidentRequest) error {
+	return models.UpdateResident(id, req)
+}
+
+func (mysqlResidents) DeleteResident(id int) error {
+	return models.DeleteResident(id)
+}
+
+// mysqlFeeTypes MySQL实现的FeeTypeRepository
+type mysqlFeeTypes struct{}
+
+func (mysqlFeeTypes) GetFeeTypes(onlyEnabled bool) ([]models.FeeType, error) {
+	return models.GetFeeTypes(onlyEnabled)
+}
+
+func (mysqlFeeTypes) GetFeeTypeByID(id int) (*models.FeeType, error) {
+	return models.GetFeeTypeByID(id)
+}
+
+func (mysqlFeeTypes) GetFeeTypeByCode(code string) (*models.FeeType, error) {
+	return models.GetFeeTypeByCode(code)
+}
+
+func (mysqlFeeTypes) CreateFeeType(req models.FeeTypeRequest) (int, error) {
+	return models.CreateFeeType(req)
+}
+
+func (mysqlFeeTypes) UpdateFeeType(id int, req models.FeeTypeRequest) error {
+	return models.UpdateFeeType(id, req)
+}
+
+// mysqlFees MySQL实现的FeeRepository
+type mysqlFees struct{}
+
+func (mysqlFees) GetPropertyFees(page, pageSize int, filters map[string]interface{}) ([]models.PropertyFee, int, error) {
+	return models.GetPropertyFees(page, pageSize, filters)
+}
+
+func (mysqlFees) GetPropertyFeeByID(id int) (*models.PropertyFee, error) {
+	return models.GetPropertyFeeByID(id)
+}
+
+func (mysqlFees) CreatePropertyFee(req models.PropertyFeeRequest) (int, error) {
+	return models.CreatePropertyFee(req)
+}
+
+func (mysqlFees) RefundPropertyFee(id int, req models.RefundRequest) error {
+	return models.RefundPropertyFee(id, req)
+}
+
+// mysqlBills MySQL实现的BillRepository
+type mysqlBills struct{}
+
+func (mysqlBills) GetBills(page, pageSize int, filters map[string]interface{}) ([]models.Bill, int, error) {
+	return models.GetBills(page, pageSize, filters)
+}
+
+func (mysqlBills) GetBillByID(id int) (*models.Bill, error) {
+	return models.GetBillByID(id)
+}
+
+func (mysqlBills) CreateBill(req models.BillRequest) (int, error) {
+	return models.CreateBill(req)
+}
+
+func (mysqlBills) GenerateBills(feeType *models.FeeType, year, month int, dueDate time.Time, communityID int) (int, error) {
+	return models.GenerateBills(feeType, year, month, dueDate, communityID)
+}
+
+func (mysqlBills) GenerateUsageBills(feeType *models.FeeType, year, month int, dueDate time.Time, communityID int) (int, error) {
+	return models.GenerateUsageBills(feeType, year, month, dueDate, communityID)
+}
+
+func (mysqlBills) GenerateParkingBills(feeType *models.FeeType, year, month int, dueDate time.Time, communityID int) (int, error) {
+	return models.GenerateParkingBills(feeType, year, month, dueDate, communityID)
+}
+
+func (mysqlBills) DeleteBill(id int) error {
+	return models.DeleteBill(id)
+}
+
+func (mysqlBills) GetOverdueAccounts(asOf time.Time, communityID int) ([]models.OverdueAccount, error) {
+	return models.GetOverdueAccounts(asOf, communityID)
+}
+
+// mysqlDashboard MySQL实现的DashboardRepository
+type mysqlDashboard struct{}
+
+func (mysqlDashboard) GetDashboardStats(communityID int) (*models.DashboardStats, error) {
+	return models.GetDashboardStats(communityID)
+}
+
+func (mysqlDashboard) GetIncomeData(year, feeTypeID, communityID int) (*models.IncomeData, error) {
+	return models.GetIncomeData(year, feeTypeID, communityID)
+}
+
+func (mysqlDashboard) GetIncomeByType(year, communityID int) ([]models.FeeTypeIncome, error) {
+	return models.GetIncomeByType(year, communityID)
+}
+
+func (mysqlDashboard) GetCollectionReport(year, month int, groupBy string, feeTypeID int, asOf time.Time, communityID int) (*models.CollectionReport, error) {
+	return models.GetCollectionReport(year, month, groupBy, feeTypeID, asOf, communityID)
+}
+
+func (mysqlDashboard) RefreshMonthlyStats(communityID, year, month int) error {
+	return models.RefreshMonthlyStats(communityID, year, month)
+}
+
+// mysqlPeriods MySQL实现的PeriodRepository
+type mysqlPeriods struct{}
+
+func (mysqlPeriods) GetAccountingPeriods(communityID, year int) ([]models.AccountingPeriod, error) {
+	return models.GetAccountingPeriods(communityID, year)
+}
+
+func (mysqlPeriods) IsPeriodClosed(communityID, year, month int) (bool, error) {
+	return models.IsPeriodClosed(communityID, year, month)
+}
+
+func (mysqlPeriods) ClosePeriod(communityID, year, month int, userID uint) error {
+	return models.ClosePeriod(communityID, year, month, userID)
+}
+
+func (mysqlPeriods) ReopenPeriod(communityID, year, month int, userID uint, reason string) (bool, error) {
+	return models.ReopenPeriod(communityID, year, month, userID, reason)
+}
+
+// mysqlAuditLogs MySQL实现的AuditLogRepository
+type mysqlAuditLogs struct{}
+
+func (mysqlAuditLogs) CreateAuditLog(log models.AuditLog) error {
+	return models.CreateAuditLog(log)
+}
+
+func (mysqlAuditLogs) GetAuditLogs(page, pageSize int, filters map[string]interface{}) ([]models.AuditLog, int, error) {
+	return models.GetAuditLogs(page, pageSize, filters)
+}
+
+// mysqlComplaints MySQL实现的ComplaintRepository
+type mysqlComplaints struct{}
+
+func (mysqlComplaints) GetComplaints(page, pageSize int, filters map[string]interface{}) ([]models.Complaint, int, error) {
+	return models.GetComplaints(page, pageSize, filters)
+}
+
+func (mysqlComplaints) GetComplaintByID(id int, withLogs bool) (*models.Complaint, error) {
+	return models.GetComplaintByID(id, withLogs)
+}
+
+// mysqlAnnouncements MySQL实现的AnnouncementRepository
+type mysqlAnnouncements struct{}
+
+func (mysqlAnnouncements) GetAnnouncements(page, pageSize int, filters map[string]interface{}) ([]models.Announcement, int, error) {
+	return models.GetAnnouncements(page, pageSize, filters)
+}
+
+func (mysqlAnnouncements) GetAnnouncementByID(id int) (*models.Announcement, error) {
+	return models.GetAnnouncementByID(id)
+}
+
+// mysqlPolls MySQL实现的PollRepository
+type mysqlPolls struct{}
+
+func (mysqlPolls) GetPolls(page, pageSize int, filters map[string]interface{}) ([]models.Poll, int, error) {
+	return models.GetPolls(page, pageSize, filters)
+}
+
+func (mysqlPolls) GetPollByID(id int) (*models.Poll, error) {
+	return models.GetPollByID(id)
+}
+
+// mysqlFacilities MySQL实现的FacilityRepository
+type mysqlFacilities struct{}
+
+func (mysqlFacilities) GetFacilities(communityID int, onlyEnabled bool) ([]models.Facility, error) {
+	return models.GetFacilities(communityID, onlyEnabled)
+}
+
+func (mysqlFacilities) GetFacilityByID(id int) (*models.Facility, error) {
+	return models.GetFacilityByID(id)
+}
+
+func (mysqlFacilities) GetFacilityBookings(page, pageSize int, filters map[string]interface{}) ([]models.FacilityBooking, int, error) {
+	return models.GetFacilityBookings(page, pageSize, filters)
+}
+
+func (mysqlFacilities) GetFacilityBookingByID(id int) (*models.FacilityBooking, error) {
+	return models.GetFacilityBookingByID(id)
+}
+
+// mysqlAssets MySQL实现的AssetRepository
+type mysqlAssets struct{}
+
+func (mysqlAssets) GetAssets(page, pageSize int, filters map[string]interface{}) ([]models.Asset, int, error) {
+	return models.GetAssets(page, pageSize, filters)
+}
+
+func (mysqlAssets) GetAssetByID(id int, withSchedules bool) (*models.Asset, error) {
+	return models.GetAssetByID(id, withSchedules)
+}
+
+func (mysqlAssets) GetMaintenanceScheduleByID(id int) (*models.MaintenanceSchedule, error) {
+	return models.GetMaintenanceScheduleByID(id)
+}
+
+func (mysqlAssets) GetMaintenanceTasks(page, pageSize int, filters map[string]interface{}) ([]models.MaintenanceTask, int, error) {
+	return models.GetMaintenanceTasks(page, pageSize, filters)
+}
+
+func (mysqlAssets) GetAssetInspectionByID(id int) (*models.AssetInspection, error) {
+	return models.GetAssetInspectionByID(id)
+}
+
+// mysqlLeases MySQL实现的LeaseRepository
+type mysqlLeases struct{}
+
+func (mysqlLeases) GetLeases(page, pageSize int, filters map[string]interface{}) ([]models.Lease, int, error) {
+	return models.GetLeases(page, pageSize, filters)
+}
+
+func (mysqlLeases) GetLeaseByID(id int) (*models.Lease, error) {
+	return models.GetLeaseByID(id)
+}
+
+// mysqlMeters MySQL实现的MeterRepository
+type mysqlMeters struct{}
+
+func (mysqlMeters) GetMeters(filters map[string]interface{}) ([]models.Meter, error) {
+	return models.GetMeters(filters)
+}
+
+func (mysqlMeters) GetMeterByID(id int) (*models.Meter, error) {
+	return models.GetMeterByID(id)
+}
+
+// mysqlVisitors MySQL实现的VisitorRepository
+type mysqlVisitors struct{}
+
+func (mysqlVisitors) GetVisitorPasses(page, pageSize int, filters map[string]interface{}) ([]models.VisitorPass, int, error) {
+	return models.GetVisitorPasses(page, pageSize, filters)
+}
+
+func (mysqlVisitors) GetVisitorPassByID(id int) (*models.VisitorPass, error) {
+	return models.GetVisitorPassByID(id)
+}
+
+func (mysqlVisitors) GetVisitorLogs(page, pageSize int, filters map[string]interface{}) ([]models.VisitorLog, int, error) {
+	return models.GetVisitorLogs(page, pageSize, filters)
+}
+
+// mysqlParcels MySQL实现的ParcelRepository
+type mysqlParcels struct{}
+
+func (mysqlParcels) GetParcels(page, pageSize int, filters map[string]interface{}) ([]models.Parcel, int, error) {
+	return models.GetParcels(page, pageSize, filters)
+}
+
+func (mysqlParcels) GetParcelByID(id int) (*models.Parcel, error) {
+	return models.GetParcelByID(id)
+}
+
+// mysqlRenovations MySQL实现的RenovationRepository
+type mysqlRenovations struct{}
+
+func (mysqlRenovations) GetRenovations(page, pageSize int, filters map[string]interface{}) ([]models.Renovation, int, error) {
+	return models.GetRenovations(page, pageSize, filters)
+}
+
+func (mysqlRenovations) GetRenovationByID(id int) (*models.Renovation, error) {
+	return models.GetRenovationByID(id)
+}
+
+// mysqlAccessCredentials MySQL实现的AccessCredentialRepository
+type mysqlAccessCredentials struct{}
+
+func (mysqlAccessCredentials) GetAccessCredentials(page, pageSize int, filters map[string]interface{}) ([]models.AccessCredential, int, error) {
+	return models.GetAccessCredentials(page, pageSize, filters)
+}
+
+func (mysqlAccessCredentials) GetAccessCredentialByID(id int) (*models.AccessCredential, error) {
+	return models.GetAccessCredentialByID(id)
+}
+
+// mysqlNotifications MySQL实现的NotificationRepository
+type mysqlNotifications struct{}
+
+func (mysqlNotifications) GetNotifications(page, pageSize int, filters map[string]interface{}) ([]models.Notification, int, error) {
+	return models.GetNotifications(page, pageSize, filters)
+}
+
+func (mysqlNotifications) GetNotificationByID(id int) (*models.Notification, error) {
+	return models.GetNotificationByID(id)
+}
+
+// mysqlDunning MySQL实现的DunningRepository
+type mysqlDunning struct{}
+
+func (mysqlDunning) GetDunningRecords(page, pageSize int, filters map[string]interface{}) ([]models.DunningRecord, int, error) {
+	return models.GetDunningRecords(page, pageSize, filters)
+}
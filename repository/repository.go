@@ -0,0 +1,281 @@
+// Package repository 定义用户、社区、住户、收费、账单、账期及各业务模块数据的仓储接口，处理器通过接口访问数据，
+// 生产环境使用MySQL实现，测试和本地演示可以使用不依赖数据库的内存实现
+package repository
+
+import (
+	"time"
+
+	"community-backward/models"
+)
+
+// UserRepository 用户及其可进入社区的数据访问
+type UserRepository interface {
+	// FindUser 通过用户名和密码查找用户，用户不存在或密码错误时返回nil
+	FindUser(username, password string) (*models.User, error)
+	// GetUserByID 通过ID获取用户，不存在时返回nil
+	GetUserByID(id uint) (*models.User, error)
+	// CreateResidentAccount 为住户开通登录账号
+	CreateResidentAccount(residentID int, req models.ResidentAccountRequest) (uint, error)
+	// GetUserCommunities 获取用户可以进入的已启用社区
+	GetUserCommunities(user *models.User) ([]models.Community, error)
+	// ResolveUserCommunity 确定用户登录后进入的社区
+	ResolveUserCommunity(user *models.User) (*models.Community, error)
+	// SwitchUserCommunity 切换用户所在的社区
+	SwitchUserCommunity(user *models.User, communityID int) (*models.Community, error)
+}
+
+// CommunityRepository 社区及社区成员的数据访问
+type CommunityRepository interface {
+	// GetCommunities 获取社区列表，onlyEnabled为true时只返回已启用的社区
+	GetCommunities(onlyEnabled bool) ([]models.Community, error)
+	// GetCommunityByID 通过ID获取社区，不存在时返回nil
+	GetCommunityByID(id int) (*models.Community, error)
+	// CreateCommunity 创建社区，编码已存在时返回models.ErrCommunityCodeExists
+	CreateCommunity(req models.CommunityRequest) (int, error)
+	// UpdateCommunity 更新社区，编码已被其他社区使用时返回models.ErrCommunityCodeExists
+	UpdateCommunity(id int, req models.CommunityRequest) error
+	// GetCommunityMembers 获取登记为社区成员的用户
+	GetCommunityMembers(communityID int) ([]models.User, error)
+	// AddCommunityMember 将用户登记为社区成员，住户账号返回models.ErrInvalidCommunityMember
+	AddCommunityMember(communityID int, userID uint) error
+	// RemoveCommunityMember 将用户移出社区，用户不是该社区成员时返回sql.ErrNoRows
+	RemoveCommunityMember(communityID int, userID uint) error
+	// GetCommunityRollup 汇总各社区指定年份的住户、收入和收缴情况，并返回合计
+	GetCommunityRollup(communities []models.Community, year int, asOf time.Time) ([]models.CommunityRollup, *models.CommunityRollup, error)
+}
+
+// ResidentRepository 住户的数据访问
+type ResidentRepository interface {
+	// GetResidents 按条件分页查询住户，返回当前页和总数
+	GetResidents(page, pageSize int, filters map[string]interface{}) ([]models.Resident, int, error)
+	// GetResidentByID 通过ID获取住户，不存在时返回nil
+	GetResidentByID(id int) (*models.Resident, error)
+	// CreateResident 在指定社区创建住户
+	CreateResident(req models.ResidentRequest, communityID int) (int, error)
+	// UpdateResident 更新住户
+	UpdateResident(id int, req models.ResidentRequest) error
+	// DeleteResident 删除住户及其缴费记录
+	DeleteResident(id int) error
+}
+
+// FeeTypeRepository 收费项目的数据访问
+type FeeTypeRepository interface {
+	// GetFeeTypes 获取收费项目列表，onlyEnabled为true时只返回已启用的项目
+	GetFeeTypes(onlyEnabled bool) ([]models.FeeType, error)
+	// GetFeeTypeByID 通过ID获取收费项目，不存在时返回nil
+	GetFeeTypeByID(id int) (*models.FeeType, error)
+	// GetFeeTypeByCode 通过编码获取收费项目，不存在时返回nil
+	GetFeeTypeByCode(code string) (*models.FeeType, error)
+	// CreateFeeType 创建收费项目
+	CreateFeeType(req models.FeeTypeRequest) (int, error)
+	// UpdateFeeType 更新收费项目
+	UpdateFeeType(id int, req models.FeeTypeRequest) error
+}
+
+// FeeRepository 缴费记录的数据访问
+// 登记缴费和退款时检查账期并生成记账凭证，已结账时返回models.ErrPeriodClosed
+type FeeRepository interface {
+	// GetPropertyFees 按条件分页查询缴费记录，返回当前页和总数
+	GetPropertyFees(page, pageSize int, filters map[string]interface{}) ([]models.PropertyFee, int, error)
+	// GetPropertyFeeByID 通过ID获取缴费记录，不存在时返回nil
+	GetPropertyFeeByID(id int) (*models.PropertyFee, error)
+	// CreatePropertyFee 登记缴费
+	CreatePropertyFee(req models.PropertyFeeRequest) (int, error)
+	// RefundPropertyFee 缴费退款，缴费记录不是已缴状态时返回models.ErrPaymentNotRefundable
+	RefundPropertyFee(id int, req models.RefundRequest) error
+}
+
+// BillRepository 账单的数据访问
+// 写入账单时检查住户所属社区的账期并生成记账凭证，已结账时返回models.ErrPeriodClosed
+type BillRepository interface {
+	// GetBills 按条件分页查询账单，返回当前页和总数
+	GetBills(page, pageSize int, filters map[string]interface{}) ([]models.Bill, int, error)
+	// GetBillByID 通过ID获取账单，不存在时返回nil
+	GetBillByID(id int) (*models.Bill, error)
+	// CreateBill 创建账单
+	CreateBill(req models.BillRequest) (int, error)
+	// GenerateBills 按收费项目的计费规则为社区所有住户生成账单，返回新生成的账单数量
+	GenerateBills(feeType *models.FeeType, year, month int, dueDate time.Time, communityID int) (int, error)
+	// GenerateUsageBills 根据社区未出账的抄表记录生成按用量计费的账单
+	GenerateUsageBills(feeType *models.FeeType, year, month int, dueDate time.Time, communityID int) (int, error)
+	// GenerateParkingBills 根据社区的车位租赁合同生成停车费账单
+	GenerateParkingBills(feeType *models.FeeType, year, month int, dueDate time.Time, communityID int) (int, error)
+	// DeleteBill 删除账单并冲销其凭证
+	DeleteBill(id int) error
+	// GetOverdueAccounts 获取社区截至asOf的欠费住户，按逾期天数降序排列
+	GetOverdueAccounts(asOf time.Time, communityID int) ([]models.OverdueAccount, error)
+}
+
+// DashboardRepository 仪表盘统计、月度统计和收缴率的数据访问
+type DashboardRepository interface {
+	// GetDashboardStats 获取社区的仪表盘统计数据
+	GetDashboardStats(communityID int) (*models.DashboardStats, error)
+	// GetIncomeData 获取收入趋势，feeTypeID为0时汇总所有收费项目
+	GetIncomeData(year, feeTypeID, communityID int) (*models.IncomeData, error)
+	// GetIncomeByType 获取各收费项目的月度收入
+	GetIncomeByType(year, communityID int) ([]models.FeeTypeIncome, error)
+	// GetCollectionReport 获取截至asOf的收缴率
+	GetCollectionReport(year, month int, groupBy string, feeTypeID int, asOf time.Time, communityID int) (*models.CollectionReport, error)
+	// RefreshMonthlyStats 重新计算社区指定月份的月度统计，已结账时返回models.ErrPeriodClosed
+	RefreshMonthlyStats(communityID, year, month int) error
+}
+
+// PeriodRepository 会计账期的数据访问，各社区分别结账
+type PeriodRepository interface {
+	// GetAccountingPeriods 获取社区指定年份已记录的账期
+	GetAccountingPeriods(communityID, year int) ([]models.AccountingPeriod, error)
+	// IsPeriodClosed 判断社区的指定账期是否已结账
+	IsPeriodClosed(communityID, year, month int) (bool, error)
+	// ClosePeriod 结账社区的指定月份
+	ClosePeriod(communityID, year, month int, userID uint) error
+	// ReopenPeriod 反结账社区的指定月份，返回该月此前是否处于结账状态，之后的账期已结账时返回models.ErrLaterPeriodClosed
+	ReopenPeriod(communityID, year, month int, userID uint, reason string) (bool, error)
+}
+
+// AuditLogRepository 审计日志的数据访问
+type AuditLogRepository interface {
+	// CreateAuditLog 记录审计日志
+	CreateAuditLog(log models.AuditLog) error
+	// GetAuditLogs 按条件分页查询审计日志，返回当前页和总数
+	GetAuditLogs(page, pageSize int, filters map[string]interface{}) ([]models.AuditLog, int, error)
+}
+
+// ComplaintRepository 投诉的数据访问
+type ComplaintRepository interface {
+	// GetComplaints 按条件分页查询投诉，返回当前页和总数
+	GetComplaints(page, pageSize int, filters map[string]interface{}) ([]models.Complaint, int, error)
+	// GetComplaintByID 通过ID获取投诉，withLogs为true时附带处理记录，不存在时返回nil
+	GetComplaintByID(id int, withLogs bool) (*models.Complaint, error)
+}
+
+// AnnouncementRepository 公告的数据访问
+type AnnouncementRepository interface {
+	// GetAnnouncements 按条件分页查询公告，返回当前页和总数
+	GetAnnouncements(page, pageSize int, filters map[string]interface{}) ([]models.Announcement, int, error)
+	// GetAnnouncementByID 通过ID获取公告，不存在时返回nil
+	GetAnnouncementByID(id int) (*models.Announcement, error)
+}
+
+// PollRepository 业主表决的数据访问
+type PollRepository interface {
+	// GetPolls 按条件分页查询表决，返回当前页和总数
+	GetPolls(page, pageSize int, filters map[string]interface{}) ([]models.Poll, int, error)
+	// GetPollByID 通过ID获取表决，不存在时返回nil
+	GetPollByID(id int) (*models.Poll, error)
+}
+
+// FacilityRepository 公共设施及预约的数据访问
+type FacilityRepository interface {
+	// GetFacilities 获取社区的设施，onlyEnabled为true时只返回已启用的设施
+	GetFacilities(communityID int, onlyEnabled bool) ([]models.Facility, error)
+	// GetFacilityByID 通过ID获取设施，不存在时返回nil
+	GetFacilityByID(id int) (*models.Facility, error)
+	// GetFacilityBookings 按条件分页查询预约，返回当前页和总数
+	GetFacilityBookings(page, pageSize int, filters map[string]interface{}) ([]models.FacilityBooking, int, error)
+	// GetFacilityBookingByID 通过ID获取预约，不存在时返回nil
+	GetFacilityBookingByID(id int) (*models.FacilityBooking, error)
+}
+
+// AssetRepository 设备台账、维保计划、维保任务和巡检记录的数据访问
+type AssetRepository interface {
+	// GetAssets 按条件分页查询设备，返回当前页和总数
+	GetAssets(page, pageSize int, filters map[string]interface{}) ([]models.Asset, int, error)
+	// GetAssetByID 通过ID获取设备，withSchedules为true时附带维保计划，不存在时返回nil
+	GetAssetByID(id int, withSchedules bool) (*models.Asset, error)
+	// GetMaintenanceScheduleByID 通过ID获取维保计划，不存在时返回nil
+	GetMaintenanceScheduleByID(id int) (*models.MaintenanceSchedule, error)
+	// GetMaintenanceTasks 按条件分页查询维保任务，返回当前页和总数
+	GetMaintenanceTasks(page, pageSize int, filters map[string]interface{}) ([]models.MaintenanceTask, int, error)
+	// GetAssetInspectionByID 通过ID获取巡检记录，不存在时返回nil
+	GetAssetInspectionByID(id int) (*models.AssetInspection, error)
+}
+
+// LeaseRepository 租约的数据访问
+type LeaseRepository interface {
+	// GetLeases 按条件分页查询租约，返回当前页和总数
+	GetLeases(page, pageSize int, filters map[string]interface{}) ([]models.Lease, int, error)
+	// GetLeaseByID 通过ID获取租约，不存在时返回nil
+	GetLeaseByID(id int) (*models.Lease, error)
+}
+
+// MeterRepository 计量表的数据访问
+type MeterRepository interface {
+	// GetMeters 按条件查询计量表
+	GetMeters(filters map[string]interface{}) ([]models.Meter, error)
+	// GetMeterByID 通过ID获取计量表，不存在时返回nil
+	GetMeterByID(id int) (*models.Meter, error)
+}
+
+// VisitorRepository 访客通行证和进出记录的数据访问
+type VisitorRepository interface {
+	// GetVisitorPasses 按条件分页查询通行证，返回当前页和总数
+	GetVisitorPasses(page, pageSize int, filters map[string]interface{}) ([]models.VisitorPass, int, error)
+	// GetVisitorPassByID 通过ID获取通行证，不存在时返回nil
+	GetVisitorPassByID(id int) (*models.VisitorPass, error)
+	// GetVisitorLogs 按条件分页查询进出记录，返回当前页和总数
+	GetVisitorLogs(page, pageSize int, filters map[string]interface{}) ([]models.VisitorLog, int, error)
+}
+
+// ParcelRepository 代收包裹的数据访问
+type ParcelRepository interface {
+	// GetParcels 按条件分页查询包裹，返回当前页和总数
+	GetParcels(page, pageSize int, filters map[string]interface{}) ([]models.Parcel, int, error)
+	// GetParcelByID 通过ID获取包裹，不存在时返回nil
+	GetParcelByID(id int) (*models.Parcel, error)
+}
+
+// RenovationRepository 装修申请的数据访问
+type RenovationRepository interface {
+	// GetRenovations 按条件分页查询装修申请，返回当前页和总数
+	GetRenovations(page, pageSize int, filters map[string]interface{}) ([]models.Renovation, int, error)
+	// GetRenovationByID 通过ID获取装修申请，不存在时返回nil
+	GetRenovationByID(id int) (*models.Renovation, error)
+}
+
+// AccessCredentialRepository 门禁凭证的数据访问
+type AccessCredentialRepository interface {
+	// GetAccessCredentials 按条件分页查询门禁凭证，返回当前页和总数
+	GetAccessCredentials(page, pageSize int, filters map[string]interface{}) ([]models.AccessCredential, int, error)
+	// GetAccessCredentialByID 通过ID获取门禁凭证，不存在时返回nil
+	GetAccessCredentialByID(id int) (*models.AccessCredential, error)
+}
+
+// NotificationRepository 通知发送记录的数据访问
+type NotificationRepository interface {
+	// GetNotifications 按条件分页查询通知，返回当前页和总数
+	GetNotifications(page, pageSize int, filters map[string]interface{}) ([]models.Notification, int, error)
+	// GetNotificationByID 通过ID获取通知，不存在时返回nil
+	GetNotificationByID(id int) (*models.Notification, error)
+}
+
+// DunningRepository 催缴记录的数据访问
+type DunningRepository interface {
+	// GetDunningRecords 按条件分页查询催缴记录，返回当前页和总数
+	GetDunningRecords(page, pageSize int, filters map[string]interface{}) ([]models.DunningRecord, int, error)
+}
+
+// Repositories 汇总注入处理器的各个仓储
+type Repositories struct {
+	Users             UserRepository
+	Communities       CommunityRepository
+	Residents         ResidentRepository
+	FeeTypes          FeeTypeRepository
+	Fees              FeeRepository
+	Bills             BillRepository
+	Dashboard         DashboardRepository
+	Periods           PeriodRepository
+	AuditLogs         AuditLogRepository
+	Complaints        ComplaintRepository
+	Announcements     AnnouncementRepository
+	Polls             PollRepository
+	Facilities        FacilityRepository
+	Assets            AssetRepository
+	Leases            LeaseRepository
+	Meters            MeterRepository
+	Visitors          VisitorRepository
+	Parcels           ParcelRepository
+	Renovations       RenovationRepository
+	AccessCredentials AccessCredentialRepository
+	Notifications     NotificationRepository
+	Dunning           DunningRepository
+}
@@ -7,12 +7,30 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"community-backward/handlers"
+	"community-backward/jobs"
 	"community-backward/middleware"
+	"community-backward/models"
+	"community-backward/repository"
 	"community-backward/utils"
 )
 
-// SetupRouter 配置路由
-func SetupRouter(jwtUtil *utils.JWTUtil) *gin.Engine {
+// Deps 路由依赖的定时任务、通行证签名和上传目录
+type Deps struct {
+	Dunning       *jobs.Dunning
+	PaymentPlans  *jobs.PaymentPlans
+	LateFees      *jobs.LateFees
+	Complaints    *jobs.Complaints
+	Parcels       *jobs.Parcels
+	Maintenance   *jobs.Maintenance
+	AccessSync    *jobs.AccessSync
+	Notifications *jobs.Notifications
+	Leases        *jobs.Leases
+	PassSigner    *utils.PassSigner
+	UploadDir     string
+}
+
+// SetupRouter 配置路由，用户、社区、住户、收费、账单、账期等数据通过repos访问
+func SetupRouter(jwtUtil *utils.JWTUtil, repos *repository.Repositories, deps Deps) *gin.Engine {
 	// 创建Gin引擎
 	r := gin.Default()
 
@@ -27,9 +45,33 @@ func SetupRouter(jwtUtil *utils.JWTUtil) *gin.Engine {
 	}))
 
 	// 创建处理器
-	authHandler := handlers.NewAuthHandler(jwtUtil)
-	dashboardHandler := handlers.NewDashboardHandler()
-	residentHandler := handlers.NewResidentHandler()
+	authHandler := handlers.NewAuthHandler(jwtUtil, repos.Users)
+	dashboardHandler := handlers.NewDashboardHandler(repos.Dashboard, repos.FeeTypes)
+	residentHandler := handlers.NewResidentHandler(repos.Residents, repos.Users)
+	billHandler := handlers.NewBillHandler(repos.Bills, repos.Residents, repos.FeeTypes, repos.Dashboard)
+	dunningHandler := handlers.NewDunningHandler(repos.Dunning, deps.Dunning)
+	adjustmentHandler := handlers.NewFeeAdjustmentHandler()
+	feeTypeHandler := handlers.NewFeeTypeHandler(repos.FeeTypes)
+	propertyFeeHandler := handlers.NewPropertyFeeHandler(repos.Fees, repos.FeeTypes, repos.Residents, repos.AuditLogs)
+	meterHandler := handlers.NewMeterHandler(repos.Meters)
+	reportHandler := handlers.NewReportHandler()
+	periodHandler := handlers.NewPeriodHandler(repos.Periods, repos.AuditLogs)
+	ledgerHandler := handlers.NewLedgerHandler()
+	paymentPlanHandler := handlers.NewPaymentPlanHandler(deps.PaymentPlans, deps.LateFees)
+	workOrderHandler := handlers.NewWorkOrderHandler(deps.UploadDir)
+	parkingHandler := handlers.NewParkingHandler()
+	announcementHandler := handlers.NewAnnouncementHandler(repos.Announcements)
+	complaintHandler := handlers.NewComplaintHandler(repos.Complaints, deps.Complaints)
+	visitorHandler := handlers.NewVisitorHandler(repos.Visitors, deps.PassSigner)
+	parcelHandler := handlers.NewParcelHandler(repos.Parcels, deps.Parcels)
+	facilityHandler := handlers.NewFacilityHandler(repos.Facilities)
+	assetHandler := handlers.NewAssetHandler(repos.Assets, deps.Maintenance, deps.UploadDir)
+	pollHandler := handlers.NewPollHandler(repos.Polls)
+	renovationHandler := handlers.NewRenovationHandler(repos.Renovations)
+	credentialHandler := handlers.NewAccessCredentialHandler(repos.AccessCredentials, deps.AccessSync)
+	notificationHandler := handlers.NewNotificationHandler(repos.Notifications, deps.Notifications)
+	leaseHandler := handlers.NewLeaseHandler(repos.Leases, deps.Leases)
+	communityHandler := handlers.NewCommunityHandler(repos.Users, repos.Communities)
 
 	// API路由组
 	api := r.Group("/api")
@@ -38,15 +80,62 @@ func SetupRouter(jwtUtil *utils.JWTUtil) *gin.Engine {
 		auth := api.Group("/auth")
 		{
 			auth.POST("/login", authHandler.Login)
+			auth.GET("/communities", middleware.AuthMiddleware(jwtUtil), authHandler.GetCommunities)
+			auth.POST("/switch-community", middleware.AuthMiddleware(jwtUtil), authHandler.SwitchCommunity)
+		}
+
+		// 住户端路由，仅限住户账号
+		portal := api.Group("/portal")
+		portal.Use(middleware.AuthMiddleware(jwtUtil), middleware.RequireRole(models.RoleResident), middleware.LoadResidentAccount(repos.Users))
+		{
+			portal.GET("/announcements", announcementHandler.GetMyAnnouncements)
+			portal.GET("/announcements/:id", announcementHandler.GetMyAnnouncement)
+			portal.GET("/complaints", complaintHandler.GetMyComplaints)
+			portal.POST("/complaints", complaintHandler.CreateMyComplaint)
+			portal.GET("/complaints/:id", complaintHandler.GetMyComplaint)
+			portal.PUT("/complaints/:id/rate", complaintHandler.RateMyComplaint)
+			portal.PUT("/complaints/:id/reopen", complaintHandler.ReopenMyComplaint)
+			portal.GET("/visitors", visitorHandler.GetMyPasses)
+			portal.POST("/visitors", visitorHandler.CreateMyPass)
+			portal.GET("/visitors/:id", visitorHandler.GetMyPass)
+			portal.PUT("/visitors/:id/revoke", visitorHandler.RevokeMyPass)
+			portal.GET("/parcels", parcelHandler.GetMyParcels)
+			portal.GET("/facilities", facilityHandler.GetFacilities)
+			portal.GET("/facilities/:id/availability", facilityHandler.GetAvailability)
+			portal.GET("/facility-bookings", facilityHandler.GetMyBookings)
+			portal.POST("/facility-bookings", facilityHandler.CreateMyBooking)
+			portal.PUT("/facility-bookings/:id/cancel", facilityHandler.CancelMyBooking)
+			portal.GET("/polls", pollHandler.GetMyPolls)
+			portal.GET("/polls/:id", pollHandler.GetMyPoll)
+			portal.POST("/polls/:id/ballot", pollHandler.CastMyBallot)
+			portal.GET("/polls/:id/results", pollHandler.GetMyPollResults)
+			portal.GET("/renovations", renovationHandler.GetMyRenovations)
+			portal.POST("/renovations", renovationHandler.CreateMyRenovation)
+			portal.GET("/renovations/:id", renovationHandler.GetMyRenovation)
+			portal.PUT("/renovations/:id/cancel", renovationHandler.CancelMyRenovation)
+			portal.GET("/access-credentials", credentialHandler.GetMyCredentials)
+			portal.PUT("/access-credentials/:id/suspend", credentialHandler.SuspendMyCredential)
+			portal.GET("/messages", notificationHandler.GetMyMessages)
+			portal.PUT("/messages/read", notificationHandler.ReadAllMyMessages)
+			portal.PUT("/messages/:id/read", notificationHandler.ReadMyMessage)
+			portal.GET("/notification-preferences", notificationHandler.GetMyPreference)
+			portal.GET("/work-orders", workOrderHandler.GetMyWorkOrders)
+			portal.POST("/work-orders", workOrderHandler.CreateMyWorkOrder)
+			portal.GET("/work-orders/:id", workOrderHandler.GetMyWorkOrder)
+			portal.GET("/leases", leaseHandler.GetMyLeases)
+			portal.GET("/leases/:id", leaseHandler.GetMyLease)
+			portal.PUT("/notification-preferences", notificationHandler.UpdateMyPreference)
 		}
 
-		// 受保护的路由
+		// 受保护的路由，住户账号不能访问
 		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware(jwtUtil))
+		protected.Use(middleware.AuthMiddleware(jwtUtil), middleware.RejectRole(models.RoleResident))
 		{
 			// 仪表盘相关路由
 			protected.GET("/dashboard", dashboardHandler.GetDashboard)
 			protected.GET("/dashboard/income", dashboardHandler.GetIncomeData)
+			protected.GET("/dashboard/income/types", dashboardHandler.GetIncomeByType)
+			protected.GET("/dashboard/collection", dashboardHandler.GetCollectionRate)
 
 			// 住户相关路由
 			residents := protected.Group("/residents")
@@ -56,6 +145,318 @@ func SetupRouter(jwtUtil *utils.JWTUtil) *gin.Engine {
 				residents.POST("", residentHandler.CreateResident)
 				residents.PUT("/:id", residentHandler.UpdateResident)
 				residents.DELETE("/:id", residentHandler.DeleteResident)
+				residents.POST("/:id/account", residentHandler.CreateAccount)
+				residents.GET("/:id/notification-preferences", notificationHandler.GetResidentPreference)
+				residents.PUT("/:id/notification-preferences", notificationHandler.UpdateResidentPreference)
+				residents.GET("/:id/bill-payer", leaseHandler.GetBillPayer)
+			}
+
+			// 账单相关路由
+			bills := protected.Group("/bills")
+			{
+				bills.GET("", billHandler.GetBills)
+				bills.POST("", billHandler.CreateBill)
+				bills.POST("/generate", billHandler.GenerateBills)
+				bills.DELETE("/:id", billHandler.DeleteBill)
+				bills.GET("/overdue", billHandler.GetOverdueAccounts)
+			}
+
+			// 催缴相关路由
+			dunningGroup := protected.Group("/dunning")
+			{
+				dunningGroup.POST("/run", dunningHandler.RunDunning)
+				dunningGroup.GET("/records", dunningHandler.GetDunningRecords)
+				dunningGroup.GET("/templates", dunningHandler.GetDunningTemplates)
+				dunningGroup.PUT("/templates/:id", dunningHandler.UpdateDunningTemplate)
+			}
+
+			// 分期还款相关路由，取消计划和计收滞纳金仅限财务
+			paymentPlans := protected.Group("/payment-plans")
+			{
+				paymentPlans.GET("", paymentPlanHandler.GetPaymentPlans)
+				paymentPlans.POST("", paymentPlanHandler.CreatePaymentPlan)
+				paymentPlans.GET("/missed", paymentPlanHandler.GetMissedInstalments)
+				paymentPlans.POST("/check", paymentPlanHandler.CheckPaymentPlans)
+				paymentPlans.GET("/:id", paymentPlanHandler.GetPaymentPlan)
+				paymentPlans.PUT("/:id/cancel", middleware.RequireRole(models.RoleFinance, models.RoleAdmin), paymentPlanHandler.CancelPaymentPlan)
+			}
+			protected.POST("/late-fees/assess", middleware.RequireRole(models.RoleFinance, models.RoleAdmin), paymentPlanHandler.AssessLateFees)
+
+			// 报修工单相关路由
+			workOrders := protected.Group("/work-orders")
+			{
+				workOrders.GET("/categories", workOrderHandler.GetCategories)
+				workOrders.GET("/assignees", workOrderHandler.GetAssignees)
+				workOrders.GET("", workOrderHandler.GetWorkOrders)
+				workOrders.POST("", workOrderHandler.CreateWorkOrder)
+				workOrders.GET("/:id", workOrderHandler.GetWorkOrder)
+				workOrders.PUT("/:id/assign", workOrderHandler.Transition(models.WorkOrderActionAssign))
+				workOrders.PUT("/:id/start", workOrderHandler.Transition(models.WorkOrderActionStart))
+				workOrders.PUT("/:id/resolve", workOrderHandler.Transition(models.WorkOrderActionResolve))
+				workOrders.PUT("/:id/close", workOrderHandler.Transition(models.WorkOrderActionClose))
+				workOrders.PUT("/:id/reopen", workOrderHandler.Transition(models.WorkOrderActionReopen))
+				workOrders.PUT("/:id/cancel", workOrderHandler.Transition(models.WorkOrderActionCancel))
+				workOrders.POST("/:id/comments", workOrderHandler.CreateComment)
+				workOrders.POST("/:id/photos", workOrderHandler.UploadPhoto)
+				workOrders.GET("/:id/photos/:photoId", workOrderHandler.GetPhoto)
+			}
+
+			// 车位与车辆相关路由
+			parking := protected.Group("/parking")
+			{
+				parking.GET("/occupancy", parkingHandler.GetOccupancy)
+				parking.GET("/spaces", parkingHandler.GetSpaces)
+				parking.POST("/spaces", parkingHandler.CreateSpace)
+				parking.PUT("/spaces/:id", parkingHandler.UpdateSpace)
+				parking.PUT("/spaces/:id/assign", parkingHandler.AssignSpace)
+				parking.PUT("/spaces/:id/occupancy", parkingHandler.SetOccupancy)
+				parking.GET("/contracts", parkingHandler.GetContracts)
+				parking.POST("/contracts", parkingHandler.CreateContract)
+				parking.PUT("/contracts/:id/terminate", parkingHandler.TerminateContract)
+				parking.GET("/vehicles", parkingHandler.GetVehicles)
+				parking.POST("/vehicles", parkingHandler.CreateVehicle)
+				parking.PUT("/vehicles/:id", parkingHandler.UpdateVehicle)
+				parking.DELETE("/vehicles/:id", parkingHandler.DeleteVehicle)
+			}
+
+			// 社区公告相关路由
+			announcements := protected.Group("/announcements")
+			{
+				announcements.GET("", announcementHandler.GetAnnouncements)
+				announcements.POST("", announcementHandler.CreateAnnouncement)
+				announcements.GET("/:id", announcementHandler.GetAnnouncement)
+				announcements.PUT("/:id", announcementHandler.UpdateAnnouncement)
+				announcements.DELETE("/:id", announcementHandler.DeleteAnnouncement)
+				announcements.PUT("/:id/publish", announcementHandler.PublishAnnouncement)
+				announcements.PUT("/:id/withdraw", announcementHandler.WithdrawAnnouncement)
+				announcements.PUT("/:id/pin", announcementHandler.PinAnnouncement)
+				announcements.GET("/:id/receipts", announcementHandler.GetReceipts)
+			}
+
+			// 投诉建议相关路由
+			complaintGroup := protected.Group("/complaints")
+			{
+				complaintGroup.GET("", complaintHandler.GetComplaints)
+				complaintGroup.POST("", complaintHandler.CreateComplaint)
+				complaintGroup.GET("/stats", complaintHandler.GetStats)
+				complaintGroup.POST("/escalate", complaintHandler.Escalate)
+				complaintGroup.GET("/:id", complaintHandler.GetComplaint)
+				complaintGroup.PUT("/:id/accept", complaintHandler.Transition(models.ComplaintActionAccept))
+				complaintGroup.PUT("/:id/respond", complaintHandler.Transition(models.ComplaintActionRespond))
+				complaintGroup.PUT("/:id/reopen", complaintHandler.Transition(models.ComplaintActionReopen))
+				complaintGroup.PUT("/:id/close", complaintHandler.Transition(models.ComplaintActionClose))
+				complaintGroup.POST("/:id/logs", complaintHandler.CreateLog)
+			}
+
+			// 访客通行相关路由
+			visitors := protected.Group("/visitors")
+			{
+				visitors.GET("/passes", visitorHandler.GetPasses)
+				visitors.POST("/passes", visitorHandler.CreatePass)
+				visitors.GET("/passes/:id", visitorHandler.GetPass)
+				visitors.PUT("/passes/:id/revoke", visitorHandler.RevokePass)
+				visitors.POST("/verify", visitorHandler.Verify)
+				visitors.GET("/logs", visitorHandler.GetLogs)
+			}
+
+			// 快递代收相关路由
+			parcelGroup := protected.Group("/parcels")
+			{
+				parcelGroup.GET("", parcelHandler.GetParcels)
+				parcelGroup.POST("", parcelHandler.CreateParcel)
+				parcelGroup.GET("/overdue", parcelHandler.GetOverdueParcels)
+				parcelGroup.POST("/remind", parcelHandler.Remind)
+				parcelGroup.POST("/pickup", parcelHandler.Pickup)
+				parcelGroup.GET("/:id", parcelHandler.GetParcel)
+				parcelGroup.POST("/:id/notify", parcelHandler.NotifyParcel)
+				parcelGroup.PUT("/:id/return", parcelHandler.ReturnParcel)
+			}
+
+			// 公共设施预约相关路由，设施设置限管理员
+			facilities := protected.Group("/facilities")
+			{
+				facilities.GET("", facilityHandler.GetFacilities)
+				facilities.POST("", middleware.RequireRole(models.RoleAdmin), facilityHandler.CreateFacility)
+				facilities.PUT("/:id", middleware.RequireRole(models.RoleAdmin), facilityHandler.UpdateFacility)
+				facilities.GET("/:id/availability", facilityHandler.GetAvailability)
+			}
+			bookings := protected.Group("/facility-bookings")
+			{
+				bookings.GET("", facilityHandler.GetBookings)
+				bookings.POST("", facilityHandler.CreateBooking)
+				bookings.GET("/:id", facilityHandler.GetBooking)
+				bookings.PUT("/:id/pay", facilityHandler.PayBooking)
+				bookings.PUT("/:id/cancel", facilityHandler.CancelBooking)
+			}
+
+			// 设备维保相关路由，设备台账维护限管理员
+			assets := protected.Group("/assets")
+			{
+				assets.GET("", assetHandler.GetAssets)
+				assets.POST("", middleware.RequireRole(models.RoleAdmin), assetHandler.CreateAsset)
+				assets.GET("/:id", assetHandler.GetAsset)
+				assets.PUT("/:id", middleware.RequireRole(models.RoleAdmin), assetHandler.UpdateAsset)
+				assets.GET("/:id/schedules", assetHandler.GetSchedules)
+				assets.POST("/:id/schedules", middleware.RequireRole(models.RoleAdmin), assetHandler.CreateSchedule)
+				assets.GET("/:id/inspections", assetHandler.GetInspections)
+				assets.POST("/:id/inspections", assetHandler.CreateInspection)
+			}
+			maintenanceGroup := protected.Group("/maintenance")
+			{
+				maintenanceGroup.PUT("/schedules/:id", middleware.RequireRole(models.RoleAdmin), assetHandler.UpdateSchedule)
+				maintenanceGroup.GET("/tasks", assetHandler.GetTasks)
+				maintenanceGroup.GET("/tasks/overdue", assetHandler.GetOverdueTasks)
+				maintenanceGroup.POST("/tasks/generate", assetHandler.GenerateTasks)
+				maintenanceGroup.GET("/inspections/:id", assetHandler.GetInspection)
+				maintenanceGroup.POST("/inspections/:id/files", assetHandler.UploadInspectionFile)
+				maintenanceGroup.GET("/inspections/:id/files/:fileId", assetHandler.GetInspectionFile)
+			}
+
+			// 业主表决和问卷调查相关路由，创建和开始、结束表决限管理员
+			polls := protected.Group("/polls")
+			{
+				polls.GET("", pollHandler.GetPolls)
+				polls.POST("", middleware.RequireRole(models.RoleAdmin), pollHandler.CreatePoll)
+				polls.GET("/:id", pollHandler.GetPoll)
+				polls.PUT("/:id", middleware.RequireRole(models.RoleAdmin), pollHandler.UpdatePoll)
+				polls.PUT("/:id/open", middleware.RequireRole(models.RoleAdmin), pollHandler.OpenPoll)
+				polls.PUT("/:id/close", middleware.RequireRole(models.RoleAdmin), pollHandler.ClosePoll)
+				polls.PUT("/:id/cancel", middleware.RequireRole(models.RoleAdmin), pollHandler.CancelPoll)
+				polls.GET("/:id/ballots", pollHandler.GetBallots)
+				polls.POST("/:id/ballots", pollHandler.CastBallot)
+				polls.GET("/:id/results", pollHandler.GetResults)
+				polls.GET("/:id/verify", pollHandler.Verify)
+			}
+
+			// 装修申请相关路由，审批限管理员，退还和没收押金限财务
+			renovations := protected.Group("/renovations")
+			{
+				renovations.GET("", renovationHandler.GetRenovations)
+				renovations.POST("", renovationHandler.CreateRenovation)
+				renovations.GET("/:id", renovationHandler.GetRenovation)
+				renovations.PUT("/:id/review", middleware.RequireRole(models.RoleAdmin), renovationHandler.ReviewRenovation)
+				renovations.POST("/:id/deposit", renovationHandler.CollectDeposit)
+				renovations.POST("/:id/inspections", renovationHandler.CreateInspection)
+				renovations.PUT("/:id/cancel", renovationHandler.CancelRenovation)
+				renovations.POST("/:id/deposit/refund", middleware.RequireRole(models.RoleFinance, models.RoleAdmin), renovationHandler.RefundDeposit)
+				renovations.POST("/:id/deposit/forfeit", middleware.RequireRole(models.RoleFinance, models.RoleAdmin), renovationHandler.ForfeitDeposit)
+			}
+
+			// 门禁凭证相关路由
+			credentials := protected.Group("/access-credentials")
+			{
+				credentials.GET("", credentialHandler.GetCredentials)
+				credentials.POST("", credentialHandler.IssueCredential)
+				credentials.POST("/sync/retry", credentialHandler.RetrySync)
+				credentials.GET("/:id", credentialHandler.GetCredential)
+				credentials.PUT("/:id/suspend", credentialHandler.SuspendCredential)
+				credentials.PUT("/:id/resume", credentialHandler.ResumeCredential)
+				credentials.PUT("/:id/revoke", credentialHandler.RevokeCredential)
+				credentials.POST("/:id/sync", credentialHandler.SyncCredential)
+			}
+
+			// 房屋租赁相关路由
+			leaseGroup := protected.Group("/leases")
+			{
+				leaseGroup.GET("", leaseHandler.GetLeases)
+				leaseGroup.POST("", leaseHandler.CreateLease)
+				leaseGroup.POST("/remind", leaseHandler.Remind)
+				leaseGroup.GET("/:id", leaseHandler.GetLease)
+				leaseGroup.PUT("/:id", leaseHandler.UpdateLease)
+				leaseGroup.PUT("/:id/renew", leaseHandler.RenewLease)
+				leaseGroup.PUT("/:id/terminate", leaseHandler.TerminateLease)
+			}
+
+			// 通知中心相关路由，模板维护和群发限管理员
+			notificationGroup := protected.Group("/notifications")
+			{
+				notificationGroup.GET("", notificationHandler.GetNotifications)
+				notificationGroup.POST("/send", middleware.RequireRole(models.RoleAdmin), notificationHandler.SendNotification)
+				notificationGroup.POST("/dispatch", notificationHandler.Dispatch)
+				notificationGroup.GET("/templates", notificationHandler.GetTemplates)
+				notificationGroup.POST("/templates", middleware.RequireRole(models.RoleAdmin), notificationHandler.CreateTemplate)
+				notificationGroup.PUT("/templates/:id", middleware.RequireRole(models.RoleAdmin), notificationHandler.UpdateTemplate)
+				notificationGroup.GET("/:id", notificationHandler.GetNotification)
+				notificationGroup.PUT("/:id/retry", notificationHandler.RetryNotification)
+			}
+
+			// 社区相关路由，社区维护和成员管理限管理员，跨社区汇总限管理员和财务
+			communities := protected.Group("/communities")
+			{
+				communities.GET("", middleware.RequireRole(models.RoleAdmin), communityHandler.GetCommunities)
+				communities.POST("", middleware.RequireRole(models.RoleAdmin), communityHandler.CreateCommunity)
+				communities.GET("/rollup", middleware.RequireRole(models.RoleFinance, models.RoleAdmin), communityHandler.GetRollup)
+				communities.PUT("/:id", middleware.RequireRole(models.RoleAdmin), communityHandler.UpdateCommunity)
+				communities.GET("/:id/members", middleware.RequireRole(models.RoleAdmin), communityHandler.GetMembers)
+				communities.POST("/:id/members", middleware.RequireRole(models.RoleAdmin), communityHandler.AddMember)
+				communities.DELETE("/:id/members/:userId", middleware.RequireRole(models.RoleAdmin), communityHandler.RemoveMember)
+			}
+
+			// 财务报表相关路由
+			reports := protected.Group("/reports")
+			{
+				reports.GET("/aging", reportHandler.GetAgingReport)
+				reports.GET("/aging/households", reportHandler.GetAgingHouseholds)
+			}
+
+			// 账期结账相关路由，结账限财务，反结账限管理员
+			periods := protected.Group("/periods")
+			{
+				periods.GET("", periodHandler.GetPeriods)
+				periods.POST("/close", middleware.RequireRole(models.RoleFinance, models.RoleAdmin), periodHandler.ClosePeriod)
+				periods.POST("/reopen", middleware.RequireRole(models.RoleAdmin), periodHandler.ReopenPeriod)
+			}
+			protected.GET("/audit-logs", middleware.RequireRole(models.RoleAdmin), periodHandler.GetAuditLogs)
+
+			// 总账相关路由，仅限财务
+			ledger := protected.Group("/ledger", middleware.RequireRole(models.RoleFinance, models.RoleAdmin))
+			{
+				ledger.GET("/accounts", ledgerHandler.GetAccounts)
+				ledger.GET("/trial-balance", ledgerHandler.GetTrialBalance)
+				ledger.GET("/accounts/:code/statement", ledgerHandler.GetAccountStatement)
+				ledger.GET("/vouchers/export", ledgerHandler.ExportVouchers)
+				ledger.POST("/sync", ledgerHandler.SyncJournal)
+			}
+
+			// 收费项目相关路由
+			feeTypes := protected.Group("/fee-types")
+			{
+				feeTypes.GET("", feeTypeHandler.GetFeeTypes)
+				feeTypes.POST("", feeTypeHandler.CreateFeeType)
+				feeTypes.PUT("/:id", feeTypeHandler.UpdateFeeType)
+				feeTypes.GET("/:id/tiers", meterHandler.GetTariffTiers)
+				feeTypes.PUT("/:id/tiers", meterHandler.UpdateTariffTiers)
+			}
+
+			// 计量表与抄表相关路由
+			meters := protected.Group("/meters")
+			{
+				meters.GET("", meterHandler.GetMeters)
+				meters.POST("", meterHandler.CreateMeter)
+				meters.PUT("/:id/disable", meterHandler.DisableMeter)
+				meters.GET("/:id/readings", meterHandler.GetMeterReadings)
+				meters.POST("/readings", meterHandler.CreateMeterReading)
+				meters.POST("/readings/upload", meterHandler.UploadMeterReadings)
+			}
+
+			// 缴费记录相关路由
+			propertyFees := protected.Group("/property-fees")
+			{
+				propertyFees.GET("", propertyFeeHandler.GetPropertyFees)
+				propertyFees.POST("", propertyFeeHandler.CreatePropertyFee)
+				propertyFees.POST("/:id/refund", middleware.RequireRole(models.RoleFinance, models.RoleAdmin), propertyFeeHandler.RefundPropertyFee)
+			}
+
+			// 费用减免与优惠相关路由，审批仅限财务
+			adjustments := protected.Group("/adjustments")
+			{
+				adjustments.GET("", adjustmentHandler.GetFeeAdjustments)
+				adjustments.POST("", adjustmentHandler.CreateFeeAdjustment)
+				adjustments.GET("/report", adjustmentHandler.GetFeeAdjustmentReport)
+
+				finance := adjustments.Group("", middleware.RequireRole(models.RoleFinance, models.RoleAdmin))
+				finance.PUT("/:id/approve", adjustmentHandler.ApproveFeeAdjustment)
+				finance.PUT("/:id/reject", adjustmentHandler.RejectFeeAdjustment)
 			}
 		}
 	}
@@ -0,0 +1,620 @@
+package routes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"community-backward/models"
+	"community-backward/repository"
+	"community-backward/routes"
+	"community-backward/utils"
+)
+
+// testServer 以内存仓储为数据源的路由
+type testServer struct {
+	t       *testing.T
+	mem     *repository.Memory
+	jwtUtil *utils.JWTUtil
+	router  *gin.Engine
+}
+
+func newTestServer(t *testing.T) *testServer {
+	gin.SetMode(gin.TestMode)
+
+	mem := repository.NewMemory()
+	mem.AddUser(models.User{Username: "admin", Role: models.RoleAdmin}, "secret")
+	jwtUtil := utils.NewJWTUtil([]byte("test-secret"))
+	router := routes.SetupRouter(jwtUtil, mem.Repositories(), routes.Deps{})
+
+	return &testServer{t: t, mem: mem, jwtUtil: jwtUtil, router: router}
+}
+
+// do 发送请求，返回状态码和解析后的响应
+func (s *testServer) do(method, path, token string, body interface{}) (int, map[string]interface{}) {
+	s.t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("序列化请求失败: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		s.t.Fatalf("%s %s 响应不是JSON: %s", method, path, w.Body.String())
+	}
+	return w.Code, resp
+}
+
+// expect 发送请求并检查状态码
+func (s *testServer) expect(status int, method, path, token string, body interface{}) map[string]interface{} {
+	s.t.Helper()
+
+	code, resp := s.do(method, path, token, body)
+	if code != status {
+		s.t.Fatalf("%s %s 状态码为%d，期望%d: %v", method, path, code, status, resp)
+	}
+	return resp
+}
+
+func (s *testServer) login(username, password string) string {
+	s.t.Helper()
+
+	resp := s.expect(http.StatusOK, "POST", "/api/auth/login", "", gin.H{"username": username, "password": password})
+	token, _ := resp["token"].(string)
+	if token == "" {
+		s.t.Fatalf("登录未返回令牌: %v", resp)
+	}
+	return token
+}
+
+func (s *testServer) createResident(token, room string) int {
+	s.t.Helper()
+
+	resp := s.expect(http.StatusCreated, "POST", "/api/residents", token, gin.H{
+		"name":     "张三",
+		"building": "1",
+		"unit":     "1",
+		"room":     room,
+		"area":     100,
+		"status":   1,
+	})
+	data, _ := resp["data"].(map[string]interface{})
+	id, _ := data["id"].(float64)
+	if id == 0 {
+		s.t.Fatalf("创建住户未返回ID: %v", resp)
+	}
+	return int(id)
+}
+
+// listTotal 返回列表接口的total
+func listTotal(resp map[string]interface{}) int {
+	data, _ := resp["data"].(map[string]interface{})
+	total, _ := data["total"].(float64)
+	return int(total)
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t)
+
+	s.expect(http.StatusUnauthorized, "POST", "/api/auth/login", "", gin.H{"username": "admin", "password": "wrong"})
+	token := s.login("admin", "secret")
+
+	resp := s.expect(http.StatusOK, "GET", "/api/auth/communities", token, nil)
+	data, _ := resp["data"].(map[string]interface{})
+	communities, _ := data["list"].([]interface{})
+	if len(communities) != 1 {
+		t.Fatalf("期望1个社区，实际%d个", len(communities))
+	}
+
+	s.expect(http.StatusUnauthorized, "GET", "/api/residents", "", nil)
+}
+
+func TestResidentsAndPropertyFees(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login("admin", "secret")
+
+	residentID := s.createResident(token, "101")
+	if total := listTotal(s.expect(http.StatusOK, "GET", "/api/residents", token, nil)); total != 1 {
+		t.Fatalf("期望1个住户，实际%d个", total)
+	}
+
+	s.expect(http.StatusNotFound, "POST", "/api/property-fees", token, gin.H{
+		"resident_id":    residentID + 1,
+		"amount":         300,
+		"payment_date":   "2024-03-01",
+		"payment_method": "现金",
+	})
+	resp := s.expect(http.StatusCreated, "POST", "/api/property-fees", token, gin.H{
+		"resident_id":    residentID,
+		"amount":         300,
+		"payment_date":   "2024-03-01",
+		"payment_method": "现金",
+	})
+	data, _ := resp["data"].(map[string]interface{})
+	feeID, _ := data["id"].(float64)
+	if feeID == 0 {
+		t.Fatalf("登记缴费未返回ID: %v", resp)
+	}
+	if total := listTotal(s.expect(http.StatusOK, "GET", "/api/property-fees", token, nil)); total != 1 {
+		t.Fatalf("期望1条缴费记录，实际%d条", total)
+	}
+
+	refund := gin.H{"refund_date": "2024-03-05", "reason": "重复缴费"}
+	refundPath := "/api/property-fees/" + strconv.Itoa(int(feeID)) + "/refund"
+	s.expect(http.StatusOK, "POST", refundPath, token, refund)
+	s.expect(http.StatusConflict, "POST", refundPath, token, refund)
+
+	if logs := s.mem.AuditLogs(); len(logs) != 1 {
+		t.Fatalf("期望1条审计日志，实际%d条", len(logs))
+	}
+}
+
+func TestResidentPortalAccount(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login("admin", "secret")
+
+	residentID := s.createResident(token, "101")
+	accountPath := "/api/residents/" + strconv.Itoa(residentID) + "/account"
+	s.expect(http.StatusCreated, "POST", accountPath, token, gin.H{"username": "zhangsan", "password": "123456"})
+	s.expect(http.StatusConflict, "POST", accountPath, token, gin.H{"username": "zhangsan2", "password": "123456"})
+
+	residentToken := s.login("zhangsan", "123456")
+	s.expect(http.StatusForbidden, "GET", "/api/residents", residentToken, nil)
+	s.expect(http.StatusForbidden, "GET", "/api/portal/announcements", token, nil)
+
+	// 未关联住户的住户账号在进入处理器之前被拒绝
+	userID := s.mem.AddUser(models.User{Username: "orphan", Role: models.RoleResident}, "123456")
+	orphanToken, err := s.jwtUtil.GenerateToken(userID, "orphan", models.RoleResident, models.DefaultCommunityID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.expect(http.StatusForbidden, "GET", "/api/portal/announcements", orphanToken, nil)
+
+	// 令牌中的用户已不存在
+	staleToken, err := s.jwtUtil.GenerateToken(userID+1, "gone", models.RoleResident, models.DefaultCommunityID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.expect(http.StatusUnauthorized, "GET", "/api/portal/announcements", staleToken, nil)
+}
+
+func TestCommunities(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login("admin", "secret")
+
+	resp := s.expect(http.StatusCreated, "POST", "/api/communities", token, gin.H{"code": "east", "name": "东区", "enabled": true})
+	data, _ := resp["data"].(map[string]interface{})
+	communityID, _ := data["id"].(float64)
+	if communityID == 0 {
+		t.Fatalf("创建社区未返回ID: %v", resp)
+	}
+	communityPath := "/api/communities/" + strconv.Itoa(int(communityID))
+
+	s.expect(http.StatusConflict, "POST", "/api/communities", token, gin.H{"code": "east", "name": "东区二期"})
+	s.expect(http.StatusConflict, "PUT", "/api/communities/1", token, gin.H{"code": "east", "name": "默认社区", "enabled": true})
+	s.expect(http.StatusOK, "PUT", communityPath, token, gin.H{"code": "east", "name": "东区一期", "enabled": true})
+	s.expect(http.StatusNotFound, "PUT", "/api/communities/99", token, gin.H{"code": "x", "name": "x"})
+
+	resp = s.expect(http.StatusOK, "GET", "/api/communities", token, nil)
+	if communities, _ := resp["data"].([]interface{}); len(communities) != 2 {
+		t.Fatalf("期望2个社区，实际%d个", len(communities))
+	}
+
+	financeID := s.mem.AddUser(models.User{Username: "finance", Role: models.RoleFinance}, "secret")
+	membersPath := communityPath + "/members"
+	s.expect(http.StatusOK, "POST", membersPath, token, gin.H{"user_id": financeID})
+	s.expect(http.StatusNotFound, "POST", membersPath, token, gin.H{"user_id": financeID + 1})
+	resp = s.expect(http.StatusOK, "GET", membersPath, token, nil)
+	if members, _ := resp["data"].([]interface{}); len(members) != 1 {
+		t.Fatalf("期望1个社区成员，实际%d个", len(members))
+	}
+
+	financeToken := s.login("finance", "secret")
+	s.expect(http.StatusForbidden, "GET", "/api/communities", financeToken, nil)
+	resp = s.expect(http.StatusOK, "GET", "/api/communities/rollup", financeToken, nil)
+	data, _ = resp["data"].(map[string]interface{})
+	if rollups, _ := data["communities"].([]interface{}); len(rollups) != 1 {
+		t.Fatalf("期望汇总1个社区，实际%d个", len(rollups))
+	}
+
+	s.expect(http.StatusOK, "DELETE", membersPath+"/"+strconv.Itoa(int(financeID)), token, nil)
+	s.expect(http.StatusNotFound, "DELETE", membersPath+"/"+strconv.Itoa(int(financeID)), token, nil)
+}
+
+func TestCommunityScoping(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login("admin", "secret")
+	residentID := s.createResident(token, "101")
+
+	resp := s.expect(http.StatusCreated, "POST", "/api/communities", token, gin.H{"code": "east", "name": "东区", "enabled": true})
+	data, _ := resp["data"].(map[string]interface{})
+	communityID, _ := data["id"].(float64)
+
+	resp = s.expect(http.StatusOK, "POST", "/api/auth/switch-community", token, gin.H{"community_id": communityID})
+	eastToken, _ := resp["token"].(string)
+
+	s.expect(http.StatusNotFound, "GET", "/api/residents/"+strconv.Itoa(residentID), eastToken, nil)
+	if total := listTotal(s.expect(http.StatusOK, "GET", "/api/residents", eastToken, nil)); total != 0 {
+		t.Fatalf("期望东区没有住户，实际%d个", total)
+	}
+	s.expect(http.StatusNotFound, "POST", "/api/property-fees", eastToken, gin.H{
+		"resident_id":    residentID,
+		"amount":         300,
+		"payment_date":   "2024-03-01",
+		"payment_method": "现金",
+	})
+	s.expect(http.StatusOK, "GET", "/api/residents/"+strconv.Itoa(residentID), token, nil)
+}
+
+// switchCommunity 创建新社区并切换进入，返回新社区的令牌
+func (s *testServer) switchCommunity(token, code string) string {
+	s.t.Helper()
+
+	resp := s.expect(http.StatusCreated, "POST", "/api/communities", token, gin.H{"code": code, "name": code, "enabled": true})
+	data, _ := resp["data"].(map[string]interface{})
+	communityID, _ := data["id"].(float64)
+
+	resp = s.expect(http.StatusOK, "POST", "/api/auth/switch-community", token, gin.H{"community_id": communityID})
+	switched, _ := resp["token"].(string)
+	if switched == "" {
+		s.t.Fatalf("切换社区未返回令牌: %v", resp)
+	}
+	return switched
+}
+
+// listCount 返回列表接口的记录数，兼容分页结果和直接返回数组两种格式
+func listCount(resp map[string]interface{}) int {
+	if list, ok := resp["data"].([]interface{}); ok {
+		return len(list)
+	}
+	return listTotal(resp)
+}
+
+func TestModuleCommunityScoping(t *testing.T) {
+	type request struct {
+		method, path string
+		body         interface{}
+	}
+
+	cases := []struct {
+		name   string
+		seed   func(mem *repository.Memory, residentID int) int
+		list   string
+		reads  []string  // 按记录ID格式化的详情路径
+		writes []request // 按记录ID格式化的写操作
+	}{
+		{
+			name: "complaints",
+			seed: func(mem *repository.Memory, residentID int) int {
+				return mem.AddComplaint(models.Complaint{ResidentID: residentID, Title: "噪音", Status: "pending"})
+			},
+			list:   "/api/complaints",
+			reads:  []string{"/api/complaints/%d"},
+			writes: []request{{"PUT", "/api/complaints/%d/close", gin.H{}}, {"POST", "/api/complaints/%d/logs", gin.H{"content": "跟进"}}},
+		},
+		{
+			name: "announcements",
+			seed: func(mem *repository.Memory, residentID int) int {
+				return mem.AddAnnouncement(models.Announcement{Title: "停水通知", Status: "draft"})
+			},
+			list:   "/api/announcements",
+			reads:  []string{"/api/announcements/%d", "/api/announcements/%d/receipts"},
+			writes: []request{{"PUT", "/api/announcements/%d/publish", nil}, {"DELETE", "/api/announcements/%d", nil}},
+		},
+		{
+			name: "polls",
+			seed: func(mem *repository.Memory, residentID int) int {
+				return mem.AddPoll(models.Poll{Title: "电梯改造", Status: "draft"})
+			},
+			list:   "/api/polls",
+			reads:  []string{"/api/polls/%d", "/api/polls/%d/results"},
+			writes: []request{{"PUT", "/api/polls/%d/open", nil}, {"PUT", "/api/polls/%d/cancel", nil}},
+		},
+		{
+			name: "facilities",
+			seed: func(mem *repository.Memory, residentID int) int {
+				facilityID := mem.AddFacility(models.Facility{Name: "羽毛球场", Enabled: true})
+				mem.AddFacilityBooking(models.FacilityBooking{FacilityID: facilityID, ResidentID: residentID, Status: "booked"})
+				return facilityID
+			},
+			list:   "/api/facilities",
+			reads:  []string{"/api/facility-bookings/%d"},
+			writes: []request{{"PUT", "/api/facilities/%d", gin.H{"name": "网球场"}}, {"PUT", "/api/facility-bookings/%d/cancel", nil}},
+		},
+		{
+			name: "facility bookings",
+			seed: func(mem *repository.Memory, residentID int) int {
+				facilityID := mem.AddFacility(models.Facility{Name: "羽毛球场", Enabled: true})
+				return mem.AddFacilityBooking(models.FacilityBooking{FacilityID: facilityID, ResidentID: residentID, Status: "booked"})
+			},
+			list: "/api/facility-bookings",
+		},
+		{
+			name: "assets",
+			seed: func(mem *repository.Memory, residentID int) int {
+				assetID := mem.AddAsset(models.Asset{Name: "1号电梯", Status: "active"})
+				scheduleID := mem.AddMaintenanceSchedule(models.MaintenanceSchedule{AssetID: assetID, Name: "月度保养", Enabled: true})
+				mem.AddMaintenanceTask(models.MaintenanceTask{AssetID: assetID, ScheduleID: scheduleID, Status: "pending"})
+				mem.AddAssetInspection(models.AssetInspection{AssetID: assetID})
+				return assetID
+			},
+			list:  "/api/assets",
+			reads: []string{"/api/assets/%d", "/api/assets/%d/schedules", "/api/maintenance/inspections/%d"},
+			writes: []request{
+				{"PUT", "/api/assets/%d", gin.H{"name": "2号电梯"}},
+				{"PUT", "/api/maintenance/schedules/%d", gin.H{"name": "季度保养"}},
+				{"POST", "/api/assets/%d/inspections", gin.H{}},
+			},
+		},
+		{
+			name: "maintenance tasks",
+			seed: func(mem *repository.Memory, residentID int) int {
+				assetID := mem.AddAsset(models.Asset{Name: "1号电梯", Status: "active"})
+				return mem.AddMaintenanceTask(models.MaintenanceTask{AssetID: assetID, Status: "pending"})
+			},
+			list: "/api/maintenance/tasks",
+		},
+		{
+			name: "leases",
+			seed: func(mem *repository.Memory, residentID int) int {
+				return mem.AddLease(models.Lease{ResidentID: residentID, Status: "active"})
+			},
+			list:   "/api/leases",
+			reads:  []string{"/api/leases/%d"},
+			writes: []request{{"PUT", "/api/leases/%d/terminate", gin.H{"end_date": "2024-06-30"}}},
+		},
+		{
+			name: "meters",
+			seed: func(mem *repository.Memory, residentID int) int {
+				return mem.AddMeter(models.Meter{ResidentID: residentID, FeeTypeID: models.DefaultFeeTypeID, MeterNo: "W-101", Status: 1})
+			},
+			list:   "/api/meters",
+			reads:  []string{"/api/meters/%d/readings"},
+			writes: []request{{"PUT", "/api/meters/%d/disable", nil}},
+		},
+		{
+			name: "visitor passes",
+			seed: func(mem *repository.Memory, residentID int) int {
+				return mem.AddVisitorPass(models.VisitorPass{ResidentID: residentID, VisitorName: "李四", Status: "active"})
+			},
+			list:   "/api/visitors/passes",
+			reads:  []string{"/api/visitors/passes/%d"},
+			writes: []request{{"PUT", "/api/visitors/passes/%d/revoke", nil}},
+		},
+		{
+			name: "visitor logs",
+			seed: func(mem *repository.Memory, residentID int) int {
+				passID := mem.AddVisitorPass(models.VisitorPass{ResidentID: residentID, VisitorName: "李四", Status: "active"})
+				return mem.AddVisitorLog(models.VisitorLog{PassID: passID})
+			},
+			list: "/api/visitors/logs",
+		},
+		{
+			name: "parcels",
+			seed: func(mem *repository.Memory, residentID int) int {
+				return mem.AddParcel(models.Parcel{ResidentID: residentID, Carrier: "顺丰", TrackingNo: "SF001", Status: "waiting"})
+			},
+			list:   "/api/parcels",
+			reads:  []string{"/api/parcels/%d"},
+			writes: []request{{"PUT", "/api/parcels/%d/return", nil}, {"POST", "/api/parcels/%d/notify", nil}},
+		},
+		{
+			name: "renovations",
+			seed: func(mem *repository.Memory, residentID int) int {
+				return mem.AddRenovation(models.Renovation{ResidentID: residentID, ApplicantName: "张三", Status: "pending"})
+			},
+			list:   "/api/renovations",
+			reads:  []string{"/api/renovations/%d"},
+			writes: []request{{"PUT", "/api/renovations/%d/cancel", gin.H{"reason": "取消"}}, {"POST", "/api/renovations/%d/deposit", gin.H{}}},
+		},
+		{
+			name: "access credentials",
+			seed: func(mem *repository.Memory, residentID int) int {
+				return mem.AddAccessCredential(models.AccessCredential{ResidentID: residentID, Type: "card", CredentialNo: "C001", Status: "active"})
+			},
+			list:   "/api/access-credentials",
+			reads:  []string{"/api/access-credentials/%d"},
+			writes: []request{{"PUT", "/api/access-credentials/%d/suspend", nil}, {"POST", "/api/access-credentials/%d/sync", nil}},
+		},
+		{
+			name: "notifications",
+			seed: func(mem *repository.Memory, residentID int) int {
+				return mem.AddNotification(models.Notification{ResidentID: residentID, Event: "general", Channel: "sms", Status: "failed"})
+			},
+			list:   "/api/notifications",
+			reads:  []string{"/api/notifications/%d"},
+			writes: []request{{"PUT", "/api/notifications/%d/retry", nil}},
+		},
+		{
+			name: "dunning records",
+			seed: func(mem *repository.Memory, residentID int) int {
+				return mem.AddDunningRecord(models.DunningRecord{ResidentID: residentID, Level: 1, Channel: "sms", Status: 1})
+			},
+			list: "/api/dunning/records",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(t)
+			token := s.login("admin", "secret")
+			id := tc.seed(s.mem, s.createResident(token, "101"))
+			eastToken := s.switchCommunity(token, "east")
+
+			if count := listCount(s.expect(http.StatusOK, "GET", tc.list, token, nil)); count != 1 {
+				t.Fatalf("期望默认社区有1条记录，实际%d条", count)
+			}
+			if count := listCount(s.expect(http.StatusOK, "GET", tc.list, eastToken, nil)); count != 0 {
+				t.Fatalf("期望东区没有记录，实际%d条", count)
+			}
+			for _, path := range tc.reads {
+				s.expect(http.StatusNotFound, "GET", fmt.Sprintf(path, id), eastToken, nil)
+			}
+			for _, w := range tc.writes {
+				s.expect(http.StatusNotFound, w.method, fmt.Sprintf(w.path, id), eastToken, w.body)
+			}
+		})
+	}
+}
+
+// createdID 返回创建接口响应中的ID
+func createdID(t *testing.T, resp map[string]interface{}) int {
+	t.Helper()
+
+	data, _ := resp["data"].(map[string]interface{})
+	id, _ := data["id"].(float64)
+	if id == 0 {
+		t.Fatalf("创建接口未返回ID: %v", resp)
+	}
+	return int(id)
+}
+
+func TestPeriodClosingAndJournal(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login("admin", "secret")
+	residentID := s.createResident(token, "101")
+
+	payment := func(date string) gin.H {
+		return gin.H{"resident_id": residentID, "amount": 300, "payment_date": date, "payment_method": "现金"}
+	}
+	s.expect(http.StatusCreated, "POST", "/api/property-fees", token, payment("2024-03-01"))
+	feeID := createdID(t, s.expect(http.StatusCreated, "POST", "/api/property-fees", token, payment("2024-03-02")))
+	bill := gin.H{"resident_id": residentID, "year": 2024, "month": 3, "amount": 250, "due_date": "2024-03-31"}
+	billID := createdID(t, s.expect(http.StatusCreated, "POST", "/api/bills", token, bill))
+
+	entries := s.mem.JournalEntries()
+	if len(entries) != 3 {
+		t.Fatalf("期望3张凭证，实际%d张", len(entries))
+	}
+	if last := entries[2]; last.SourceType != models.JournalSourceBill || last.VoucherNo != 3 || last.CommunityID != models.DefaultCommunityID {
+		t.Fatalf("账单凭证不正确: %+v", last)
+	}
+
+	s.expect(http.StatusOK, "POST", "/api/periods/close", token, gin.H{"year": 2024, "month": 3})
+	if count := listCount(s.expect(http.StatusOK, "GET", "/api/periods?year=2024", token, nil)); count != 1 {
+		t.Fatalf("期望1个账期，实际%d个", count)
+	}
+
+	// 已结账账期内不能登记缴费、退款、出账、删除账单和删除住户
+	refundPath := "/api/property-fees/" + strconv.Itoa(feeID) + "/refund"
+	s.expect(http.StatusConflict, "POST", "/api/property-fees", token, payment("2024-03-15"))
+	s.expect(http.StatusConflict, "POST", refundPath, token, gin.H{"refund_date": "2024-03-20", "reason": "重复缴费"})
+	s.expect(http.StatusConflict, "POST", "/api/bills", token, gin.H{"resident_id": residentID, "fee_type_id": 1, "year": 2024, "month": 2, "amount": 250, "due_date": "2024-02-29"})
+	s.expect(http.StatusConflict, "DELETE", "/api/bills/"+strconv.Itoa(billID), token, nil)
+	s.expect(http.StatusConflict, "DELETE", "/api/residents/"+strconv.Itoa(residentID), token, nil)
+	if entries := s.mem.JournalEntries(); len(entries) != 3 {
+		t.Fatalf("结账后期望仍为3张凭证，实际%d张", len(entries))
+	}
+
+	// 退款日期在未结账的月份时可以退款，并冲回收款
+	s.expect(http.StatusOK, "POST", refundPath, token, gin.H{"refund_date": "2024-04-02", "reason": "重复缴费"})
+	entries = s.mem.JournalEntries()
+	if refund := entries[len(entries)-1]; refund.SourceType != models.JournalSourceRefund || refund.SourceID != feeID || refund.VoucherNo != 1 {
+		t.Fatalf("退款凭证不正确: %+v", refund)
+	}
+
+	s.expect(http.StatusOK, "POST", "/api/periods/reopen", token, gin.H{"year": 2024, "month": 3, "reason": "补录缴费"})
+	s.expect(http.StatusCreated, "POST", "/api/property-fees", token, payment("2024-03-15"))
+
+	if total := listTotal(s.expect(http.StatusOK, "GET", "/api/audit-logs?entity=accounting_period", token, nil)); total != 2 {
+		t.Fatalf("期望2条账期审计日志，实际%d条", total)
+	}
+}
+
+func TestBillsDashboardAndFeeTypes(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login("admin", "secret")
+	paidID := s.createResident(token, "101")
+	s.createResident(token, "102")
+
+	feeTypeID := createdID(t, s.expect(http.StatusCreated, "POST", "/api/fee-types", token, gin.H{
+		"code":                "clean",
+		"name":                "保洁费",
+		"pricing_rule":        models.PricingFixed,
+		"unit_price":          20,
+		"accounting_category": "保洁收入",
+		"enabled":             true,
+	}))
+	resp := s.expect(http.StatusOK, "GET", "/api/fee-types", token, nil)
+	if types, _ := resp["data"].([]interface{}); len(types) != 2 {
+		t.Fatalf("期望2个收费项目，实际%d个", len(types))
+	}
+
+	generate := gin.H{"fee_type_id": feeTypeID, "year": 2024, "month": 5, "due_date": "2024-05-31"}
+	resp = s.expect(http.StatusOK, "POST", "/api/bills/generate", token, generate)
+	if data, _ := resp["data"].(map[string]interface{}); data["created"] != float64(2) {
+		t.Fatalf("期望生成2张账单: %v", resp)
+	}
+	resp = s.expect(http.StatusOK, "POST", "/api/bills/generate", token, generate)
+	if data, _ := resp["data"].(map[string]interface{}); data["created"] != float64(0) {
+		t.Fatalf("重复生成不应新增账单: %v", resp)
+	}
+	if total := listTotal(s.expect(http.StatusOK, "GET", "/api/bills?fee_type_id="+strconv.Itoa(feeTypeID), token, nil)); total != 2 {
+		t.Fatalf("期望2张账单，实际%d张", total)
+	}
+
+	s.expect(http.StatusCreated, "POST", "/api/property-fees", token, gin.H{
+		"resident_id":    paidID,
+		"fee_type_id":    feeTypeID,
+		"amount":         20,
+		"payment_date":   "2024-05-10",
+		"payment_method": "微信",
+	})
+
+	resp = s.expect(http.StatusOK, "GET", "/api/dashboard/collection?year=2024&month=5&asOf=2024-06-30", token, nil)
+	data, _ := resp["data"].(map[string]interface{})
+	total, _ := data["total"].(map[string]interface{})
+	if total["amountDue"] != float64(40) || total["amountCollected"] != float64(20) || total["paidHouseholds"] != float64(1) {
+		t.Fatalf("收缴率不正确: %v", total)
+	}
+
+	resp = s.expect(http.StatusOK, "GET", "/api/bills/overdue?asOf=2024-06-30", token, nil)
+	if listTotal(resp) != 1 {
+		t.Fatalf("期望1户欠费: %v", resp)
+	}
+
+	resp = s.expect(http.StatusOK, "GET", "/api/dashboard/income?year=2024&type=clean", token, nil)
+	data, _ = resp["data"].(map[string]interface{})
+	actual, _ := data["actual"].([]interface{})
+	planned, _ := data["planned"].([]interface{})
+	if len(actual) != 12 || actual[4] != float64(20) || planned[4] != float64(40) {
+		t.Fatalf("5月收入趋势不正确: %v", data)
+	}
+
+	resp = s.expect(http.StatusOK, "GET", "/api/dashboard", token, nil)
+	data, _ = resp["data"].(map[string]interface{})
+	if stats, _ := data["stats"].(map[string]interface{}); stats["residentCount"] != float64(2) {
+		t.Fatalf("住户数不正确: %v", stats)
+	}
+
+	resp = s.expect(http.StatusOK, "GET", "/api/bills", token, nil)
+	data, _ = resp["data"].(map[string]interface{})
+	bills, _ := data["list"].([]interface{})
+	first, _ := bills[0].(map[string]interface{})
+	s.expect(http.StatusOK, "DELETE", fmt.Sprintf("/api/bills/%.0f", first["id"]), token, nil)
+	entries := s.mem.JournalEntries()
+	if void := entries[len(entries)-1]; void.SourceType != models.JournalSourceBillVoid {
+		t.Fatalf("删除账单应生成冲销凭证: %+v", void)
+	}
+}
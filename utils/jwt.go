@@ -18,8 +18,8 @@ func NewJWTUtil(secret []byte) *JWTUtil {
 	}
 }
 
-// GenerateToken 生成JWT令牌
-func (j *JWTUtil) GenerateToken(userID uint, username string, remember bool) (string, error) {
+// GenerateToken 生成JWT令牌，communityID为用户当前所在的社区
+func (j *JWTUtil) GenerateToken(userID uint, username, role string, communityID int, remember bool) (string, error) {
 	// 设置过期时间
 	expirationTime := time.Now().Add(24 * time.Hour)
 	if remember {
@@ -28,9 +28,11 @@ func (j *JWTUtil) GenerateToken(userID uint, username string, remember bool) (st
 
 	// 创建claims
 	claims := jwt.MapClaims{
-		"user_id":  userID,
-		"username": username,
-		"exp":      expirationTime.Unix(),
+		"user_id":      userID,
+		"username":     username,
+		"role":         role,
+		"community_id": communityID,
+		"exp":          expirationTime.Unix(),
 	}
 
 	// 创建token
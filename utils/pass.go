@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPassCode 通行码格式错误或签名不匹配
+var ErrInvalidPassCode = errors.New("无效的通行码")
+
+// PassSigner 生成和校验访客通行码（二维码内容）
+// 通行码格式为 V1.<通行证ID>.<失效时间Unix秒>.<签名>，签名为HMAC-SHA256的前16字节
+type PassSigner struct {
+	Secret []byte
+}
+
+// NewPassSigner 创建新的PassSigner
+func NewPassSigner(secret []byte) *PassSigner {
+	return &PassSigner{
+		Secret: secret,
+	}
+}
+
+// Sign 为通行证生成通行码
+func (s *PassSigner) Sign(passID int, validUntil time.Time) string {
+	payload := "V1." + strconv.Itoa(passID) + "." + strconv.FormatInt(validUntil.Unix(), 10)
+	return payload + "." + s.signature(payload)
+}
+
+// Parse 校验通行码签名，返回通行证ID和签发时的失效时间
+func (s *PassSigner) Parse(code string) (int, time.Time, error) {
+	code = strings.TrimSpace(code)
+	i := strings.LastIndex(code, ".")
+	if i < 0 {
+		return 0, time.Time{}, ErrInvalidPassCode
+	}
+	payload, sig := code[:i], code[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.signature(payload))) {
+		return 0, time.Time{}, ErrInvalidPassCode
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 || parts[0] != "V1" {
+		return 0, time.Time{}, ErrInvalidPassCode
+	}
+	passID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, time.Time{}, ErrInvalidPassCode
+	}
+	until, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, ErrInvalidPassCode
+	}
+
+	return passID, time.Unix(until, 0), nil
+}
+
+// signature 计算载荷的签名
+func (s *PassSigner) signature(payload string) string {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// MaxImageSize 上传图片和附件的最大字节数
+const MaxImageSize = 10 << 20
+
+// imageExtensions 允许上传的图片类型及保存时使用的扩展名
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// attachmentExtensions 允许上传的附件类型，在图片之外增加PDF（检验报告、合格证等）
+var attachmentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+var (
+	// ErrInvalidImage 上传的文件不是允许的图片类型或超过大小限制
+	ErrInvalidImage = errors.New("只能上传10MB以内的jpg、png、gif或webp图片")
+	// ErrInvalidAttachment 上传的附件不是图片或PDF，或超过大小限制
+	ErrInvalidAttachment = errors.New("只能上传10MB以内的图片或PDF文件")
+)
+
+// SaveImage 将上传的图片保存到baseDir/subDir下，文件名随机生成
+// 按文件内容判断类型，返回相对baseDir的路径和内容类型
+func SaveImage(file *multipart.FileHeader, baseDir, subDir string) (string, string, error) {
+	return saveUpload(file, baseDir, subDir, imageExtensions, ErrInvalidImage)
+}
+
+// SaveAttachment 将上传的图片或PDF附件保存到baseDir/subDir下，规则同SaveImage
+func SaveAttachment(file *multipart.FileHeader, baseDir, subDir string) (string, string, error) {
+	return saveUpload(file, baseDir, subDir, attachmentExtensions, ErrInvalidAttachment)
+}
+
+// saveUpload 按allowed中的类型校验并保存上传文件，类型不符或超过大小限制时返回errInvalid
+func saveUpload(file *multipart.FileHeader, baseDir, subDir string, allowed map[string]string, errInvalid error) (string, string, error) {
+	if file.Size > MaxImageSize {
+		return "", "", errInvalid
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", "", err
+	}
+	defer src.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", "", err
+	}
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := allowed[contentType]
+	if !ok {
+		return "", "", errInvalid
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", "", err
+	}
+	relPath := filepath.Join(subDir, hex.EncodeToString(name)+ext)
+
+	fullPath := filepath.Join(baseDir, relPath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return "", "", fmt.Errorf("创建上传目录失败: %w", err)
+	}
+
+	dst, err := os.Create(fullPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		os.Remove(fullPath)
+		return "", "", err
+	}
+
+	return relPath, contentType, nil
+}